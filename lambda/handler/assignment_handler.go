@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// handleAssign processes requests to assign a technician to a labor line.
+func (h *LaborLineHandler) handleAssign(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.LaborLineAssignmentInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	// Validate input
+	if err := h.validationService.ValidateAssignmentInput(input); err != nil {
+		return validationErrorResponse(err), nil
+	}
+
+	laborLine, err := h.dynamoDBService.AssignLaborLine(ctx, input, event.Actor())
+	if err != nil {
+		return writeErrorResponse(err, "failed to assign labor line"), nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: laborLine,
+	}, nil
+}
+
+// handleUnassign processes requests to unassign a technician from a labor line.
+func (h *LaborLineHandler) handleUnassign(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.LaborLineAssignmentInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	// Validate input
+	if err := h.validationService.ValidateAssignmentInput(input); err != nil {
+		return validationErrorResponse(err), nil
+	}
+
+	laborLine, err := h.dynamoDBService.UnassignLaborLine(ctx, input, event.Actor())
+	if err != nil {
+		return writeErrorResponse(err, "failed to unassign labor line"), nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: laborLine,
+	}, nil
+}
+
+// handleListByTechnician processes requests for a page of the labor lines a
+// technician is assigned to, so that their queue of work can be reviewed.
+func (h *LaborLineHandler) handleListByTechnician(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.ListLaborLinesByTechnicianInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+	if input.TechnicianID == "" {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "technicianId is required",
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+	for _, status := range input.Statuses {
+		if !status.IsValid() {
+			return &models.AppSyncResponse{
+				Error: &models.AppSyncError{
+					Message: fmt.Sprintf("invalid status: %s", status),
+					Type:    "ValidationError",
+				},
+			}, nil
+		}
+	}
+	if input.AssignedFrom != nil && input.AssignedTo != nil && *input.AssignedFrom > *input.AssignedTo {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "assignedFrom must not be after assignedTo",
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	connection, err := h.dynamoDBService.ListLaborLinesByTechnician(ctx, input)
+	if errors.Is(err, services.ErrInvalidNextToken) {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "invalid nextToken",
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+	if err != nil {
+		log.Printf("Error listing labor lines by technician: %v", err)
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "failed to list labor lines",
+				Type:    "InternalError",
+			},
+		}, nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: connection,
+	}, nil
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+func TestLaborLineHandler_HandleAppSyncEvent_AssignLaborLine(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	updated := &models.LaborLine{AssignedTechnicianIDs: []string{"tech-1"}}
+	validationService.On("ValidateAssignmentInput", mock.Anything).Return(nil)
+	dynamoDBService.On("AssignLaborLine", mock.Anything, mock.MatchedBy(func(input models.LaborLineAssignmentInput) bool {
+		return input.TechnicianID == "tech-1"
+	}), "user-123").Return(updated, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), noteEvent("assignLaborLine", map[string]interface{}{
+		"technicianId": "tech-1",
+	}, "service-advisors"))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+	assert.Equal(t, updated, response.Data)
+
+	dynamoDBService.AssertExpectations(t)
+	validationService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_AssignLaborLine_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		fieldName    string
+		err          error
+		expectedType string
+	}{
+		{"already assigned", "assignLaborLine", services.ErrTechnicianAlreadyAssigned, "ValidationError"},
+		{"too many technicians", "assignLaborLine", services.ErrTooManyTechnicians, "ValidationError"},
+		{"not assigned", "unassignLaborLine", services.ErrTechnicianNotAssigned, "NotFound"},
+		{"labor line not found", "unassignLaborLine", services.ErrLaborLineNotFound, "NotFound"},
+		{"read only", "assignLaborLine", services.ErrLaborLineReadOnly, "ReadOnly"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamoDBService := &MockDynamoDBService{}
+			validationService := &MockValidationService{}
+			handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+			validationService.On("ValidateAssignmentInput", mock.Anything).Return(nil)
+			method := "AssignLaborLine"
+			if tt.fieldName == "unassignLaborLine" {
+				method = "UnassignLaborLine"
+			}
+			dynamoDBService.On(method, mock.Anything, mock.Anything, "user-123").Return((*models.LaborLine)(nil), tt.err)
+
+			response, err := handler.HandleAppSyncEvent(context.Background(), noteEvent(tt.fieldName, map[string]interface{}{
+				"technicianId": "tech-1",
+			}))
+
+			require.NoError(t, err)
+			require.NotNil(t, response.Error)
+			assert.Equal(t, tt.expectedType, response.Error.Type)
+		})
+	}
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_AssignLaborLine_TechnicianDenied(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), noteEvent("assignLaborLine", map[string]interface{}{
+		"technicianId": "tech-1",
+	}, "technicians"))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "Unauthorized", response.Error.Type)
+
+	dynamoDBService.AssertNotCalled(t, "AssignLaborLine", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_ListLaborLinesByTechnician(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	connection := &models.LaborLineConnection{Items: []*models.LaborLine{{LaborLineID: uuid.New().String()}}}
+	dynamoDBService.On("ListLaborLinesByTechnician", mock.Anything, mock.MatchedBy(func(input models.ListLaborLinesByTechnicianInput) bool {
+		return input.AccountID == accountID && input.TechnicianID == "tech-1" &&
+			len(input.Statuses) == 1 && input.Statuses[0] == models.StatusInProgress &&
+			input.AssignedFrom != nil && *input.AssignedFrom == 1700000000
+	})).Return(connection, nil)
+
+	// Technicians may review queues
+	response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("listLaborLinesByTechnician", map[string]interface{}{
+		"accountId":    accountID,
+		"technicianId": "tech-1",
+		"statuses":     []interface{}{"IN_PROGRESS"},
+		"assignedFrom": 1700000000,
+	}, "technicians"))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+	assert.Equal(t, connection, response.Data)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_ListLaborLinesByTechnician_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name            string
+		input           map[string]interface{}
+		expectedMessage string
+	}{
+		{
+			name:            "missing technician",
+			input:           map[string]interface{}{},
+			expectedMessage: "technicianId is required",
+		},
+		{
+			name:            "invalid status",
+			input:           map[string]interface{}{"technicianId": "tech-1", "statuses": []interface{}{"DONE"}},
+			expectedMessage: "invalid status: DONE",
+		},
+		{
+			name:            "inverted range",
+			input:           map[string]interface{}{"technicianId": "tech-1", "assignedFrom": 1800000000, "assignedTo": 1700000000},
+			expectedMessage: "assignedFrom must not be after assignedTo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamoDBService := &MockDynamoDBService{}
+			handler := NewLaborLineHandler(dynamoDBService, &MockValidationService{})
+
+			tt.input["accountId"] = uuid.New().String()
+			response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("listLaborLinesByTechnician", tt.input, adminGroup))
+
+			require.NoError(t, err)
+			require.NotNil(t, response.Error)
+			assert.Equal(t, "ValidationError", response.Error.Type)
+			assert.Equal(t, tt.expectedMessage, response.Error.Message)
+
+			dynamoDBService.AssertNotCalled(t, "ListLaborLinesByTechnician", mock.Anything, mock.Anything)
+		})
+	}
+}
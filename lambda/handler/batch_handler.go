@@ -0,0 +1,325 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// handleBatchCreate processes requests to create several labor lines at once.
+func (h *LaborLineHandler) handleBatchCreate(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.BatchCreateLaborLinesInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+	mode := input.Mode.OrDefault()
+	if response := validateBatch(mode, len(input.Items)); response != nil {
+		return response, nil
+	}
+
+	rateCards := newRateCardCache(h)
+	customFields := newCustomFieldCache(h)
+	laborLines := make([]*models.LaborLine, len(input.Items))
+	prepare := func(i int) *models.AppSyncError {
+		item := input.Items[i]
+		definitions, err := customFields.get(ctx, item.AccountID)
+		if err != nil {
+			log.Printf("Error listing custom fields: %v", err)
+			return &models.AppSyncError{
+				Message: "failed to create labor line",
+				Type:    "InternalError",
+			}
+		}
+		if err := h.validationService.ValidateCreateInput(item, definitions); err != nil {
+			return validationErrorResponse(err).Error
+		}
+		if item.OperationCode != "" {
+			var appErr *models.AppSyncError
+			if item, appErr = h.applyLaborOperation(ctx, item, "failed to create labor line"); appErr != nil {
+				return appErr
+			}
+		}
+
+		rateCard, err := rateCards.get(ctx, item.AccountID, item.NeedsRateCard())
+		if err != nil {
+			log.Printf("Error getting rate card: %v", err)
+			return &models.AppSyncError{
+				Message: "failed to create labor line",
+				Type:    "InternalError",
+			}
+		}
+		laborLines[i] = models.NewLaborLine(item, rateCard)
+		laborLines[i].RecalculateLaborCost()
+		if len(laborLines[i].Parts) > 0 {
+			return h.reserveParts(ctx, item.AccountID, laborLines[i].LaborLineID, laborLines[i].Parts, "failed to create labor line")
+		}
+		return nil
+	}
+	write := func(indexes []int) []services.BatchWriteResult {
+		batch := make([]*models.LaborLine, len(indexes))
+		for j, i := range indexes {
+			batch[j] = laborLines[i]
+		}
+		return h.dynamoDBService.BatchCreateLaborLines(ctx, batch, mode, event.Actor())
+	}
+
+	result := executeBatch(mode, len(input.Items), prepare, write, "failed to create labor line")
+
+	// Release the parts reserved for labor lines that were not created
+	for i, item := range result.Items {
+		if !item.Success && laborLines[i] != nil && len(laborLines[i].Parts) > 0 {
+			h.releaseParts(ctx, laborLines[i].AccountID, laborLines[i].LaborLineID)
+		}
+	}
+
+	return &models.AppSyncResponse{
+		Data: result,
+	}, nil
+}
+
+// handleBatchUpdate processes requests to update several labor lines at once.
+func (h *LaborLineHandler) handleBatchUpdate(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.BatchUpdateLaborLinesInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+	mode := input.Mode.OrDefault()
+	if response := validateBatch(mode, len(input.Items)); response != nil {
+		return response, nil
+	}
+
+	rateCards := newRateCardCache(h)
+	customFields := newCustomFieldCache(h)
+	updates := make([]services.LaborLineUpdate, len(input.Items))
+	existing := make([]*models.LaborLine, len(input.Items))
+	prepare := func(i int) *models.AppSyncError {
+		item := input.Items[i]
+		var definitions []*models.CustomFieldDefinition
+		if item.CustomFields.Set {
+			var err error
+			if definitions, err = customFields.get(ctx, item.AccountID); err != nil {
+				log.Printf("Error listing custom fields: %v", err)
+				return &models.AppSyncError{
+					Message: "failed to update labor line",
+					Type:    "InternalError",
+				}
+			}
+		}
+		if err := h.validationService.ValidateUpdateInput(item, definitions); err != nil {
+			return validationErrorResponse(err).Error
+		}
+
+		rateCard, err := rateCards.get(ctx, item.AccountID, item.NeedsRateCard())
+		if err != nil {
+			log.Printf("Error getting rate card: %v", err)
+			return &models.AppSyncError{
+				Message: "failed to update labor line",
+				Type:    "InternalError",
+			}
+		}
+		if item.Parts.Set {
+			var appErr *models.AppSyncError
+			if existing[i], appErr = h.reserveUpdatedParts(ctx, item, "failed to update labor line"); appErr != nil {
+				return appErr
+			}
+		}
+		updates[i] = services.LaborLineUpdate{Input: item, RateCard: rateCard}
+		return nil
+	}
+	write := func(indexes []int) []services.BatchWriteResult {
+		batch := make([]services.LaborLineUpdate, len(indexes))
+		for j, i := range indexes {
+			batch[j] = updates[i]
+		}
+		return h.dynamoDBService.BatchUpdateLaborLines(ctx, batch, mode, event.Actor())
+	}
+
+	result := executeBatch(mode, len(input.Items), prepare, write, "failed to update labor line")
+
+	// Put back the reservations replaced for labor lines that were not updated
+	for i, item := range result.Items {
+		if !item.Success {
+			h.restoreParts(ctx, existing[i])
+		}
+	}
+
+	return &models.AppSyncResponse{
+		Data: result,
+	}, nil
+}
+
+// handleBatchDelete processes requests to delete several labor lines at once.
+func (h *LaborLineHandler) handleBatchDelete(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.BatchDeleteLaborLinesInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+	mode := input.Mode.OrDefault()
+	if response := validateBatch(mode, len(input.Items)); response != nil {
+		return response, nil
+	}
+
+	prepare := func(int) *models.AppSyncError { return nil }
+	write := func(indexes []int) []services.BatchWriteResult {
+		batch := make([]models.DeleteLaborLineInput, len(indexes))
+		for j, i := range indexes {
+			batch[j] = input.Items[i]
+		}
+		return h.dynamoDBService.BatchDeleteLaborLines(ctx, batch, mode, event.Actor())
+	}
+
+	result := executeBatch(mode, len(input.Items), prepare, write, "failed to delete labor line")
+
+	// Release the parts of the labor lines that were deleted
+	for i, item := range result.Items {
+		if item.Success {
+			h.releaseParts(ctx, input.Items[i].AccountID, input.Items[i].LaborLineID)
+		}
+	}
+
+	return &models.AppSyncResponse{
+		Data: result,
+	}, nil
+}
+
+// validateBatch checks the mode and size of a batch mutation, returning an
+// error response for the whole request if they are invalid.
+func validateBatch(mode models.BatchMode, count int) *models.AppSyncResponse {
+	if !mode.IsValid() {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid batch mode: %s", mode),
+				Type:    "ValidationError",
+			},
+		}
+	}
+	if count == 0 || count > models.MaxBatchSize {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("a batch must contain between 1 and %d items", models.MaxBatchSize),
+				Type:    "ValidationError",
+			},
+		}
+	}
+	return nil
+}
+
+// executeBatch prepares each of count items, writes those that were prepared,
+// and assembles the per-item results in input order. In an atomic batch nothing
+// is written if any item fails to prepare.
+func executeBatch(mode models.BatchMode, count int, prepare func(i int) *models.AppSyncError, write func(indexes []int) []services.BatchWriteResult, message string) *models.BatchResult {
+	result := &models.BatchResult{
+		Mode:  mode,
+		Items: make([]*models.BatchItemResult, count),
+	}
+
+	var indexes []int
+	for i := range result.Items {
+		result.Items[i] = &models.BatchItemResult{Index: i}
+		if appErr := prepare(i); appErr != nil {
+			result.Items[i].Error = appErr
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+
+	if mode == models.BatchModeAtomic && len(indexes) < count {
+		for _, i := range indexes {
+			result.Items[i].Error = writeErrorResponse(services.ErrBatchAborted, message).Error
+		}
+		indexes = nil
+	}
+
+	if len(indexes) > 0 {
+		for j, written := range write(indexes) {
+			item := result.Items[indexes[j]]
+			if written.Err != nil {
+				item.Error = writeErrorResponse(written.Err, message).Error
+				continue
+			}
+			item.Success = true
+			item.LaborLine = written.LaborLine
+		}
+	}
+
+	for _, item := range result.Items {
+		if item.Success {
+			result.SuccessCount++
+		} else {
+			result.FailureCount++
+		}
+	}
+
+	return result
+}
+
+// rateCardCache loads each account's rate card at most once per batch.
+type rateCardCache struct {
+	handler *LaborLineHandler
+	cards   map[string]*models.RateCard
+}
+
+// newRateCardCache creates an empty rate card cache for the handler.
+func newRateCardCache(h *LaborLineHandler) *rateCardCache {
+	return &rateCardCache{handler: h, cards: map[string]*models.RateCard{}}
+}
+
+// get returns the rate card rateCardFor would load, from the cache if possible.
+func (c *rateCardCache) get(ctx context.Context, accountID string, needed bool) (*models.RateCard, error) {
+	if !needed {
+		return nil, nil
+	}
+	if card, ok := c.cards[accountID]; ok {
+		return card, nil
+	}
+
+	card, err := c.handler.rateCardFor(ctx, accountID, needed)
+	if err != nil {
+		return nil, err
+	}
+	c.cards[accountID] = card
+	return card, nil
+}
+
+// customFieldCache loads each account's custom field definitions at most once per batch.
+type customFieldCache struct {
+	handler     *LaborLineHandler
+	definitions map[string][]*models.CustomFieldDefinition
+}
+
+// newCustomFieldCache creates an empty custom field cache for the handler.
+func newCustomFieldCache(h *LaborLineHandler) *customFieldCache {
+	return &customFieldCache{handler: h, definitions: map[string][]*models.CustomFieldDefinition{}}
+}
+
+// get returns the account's custom field definitions, from the cache if possible.
+func (c *customFieldCache) get(ctx context.Context, accountID string) ([]*models.CustomFieldDefinition, error) {
+	if definitions, ok := c.definitions[accountID]; ok {
+		return definitions, nil
+	}
+
+	definitions, err := c.handler.dynamoDBService.ListCustomFieldDefinitions(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	c.definitions[accountID] = definitions
+	return definitions, nil
+}
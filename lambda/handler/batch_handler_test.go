@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// batchEvent builds a batch mutation event for the given field.
+func batchEvent(fieldName string, input map[string]interface{}) models.AppSyncEvent {
+	return authorized(models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: fieldName,
+		},
+		Arguments: map[string]interface{}{
+			"input": input,
+		},
+		Identity: map[string]interface{}{"sub": "user-123"},
+	})
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_BatchCreateLaborLines(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	rateCard := models.UpdateRateCardInput{
+		AccountID:       accountID,
+		DefaultRateType: models.RateTypeHourly,
+		Rates: []models.RateCardEntry{
+			{RateType: models.RateTypeHourly, RatePerHour: models.MustParseDecimal("100")},
+		},
+	}.ToRateCard()
+
+	validationService.On("ValidateCreateInput", mock.Anything, mock.Anything).Return(nil)
+	// The rate card and custom fields are loaded once for the whole batch
+	dynamoDBService.On("GetRateCard", mock.Anything, accountID).Return(rateCard, nil).Once()
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, accountID).Return(([]*models.CustomFieldDefinition)(nil), nil).Once()
+	dynamoDBService.On("BatchCreateLaborLines", mock.Anything, mock.MatchedBy(func(laborLines []*models.LaborLine) bool {
+		return len(laborLines) == 2 &&
+			laborLines[0].Description == "Replace pads" && laborLines[1].Description == "Bleed brakes" &&
+			laborLines[0].RatePerHour.Equal(models.MustParseDecimal("100"))
+	}), models.BatchModeAtomic, "user-123").
+		Return([]services.BatchWriteResult{
+			{LaborLine: &models.LaborLine{LaborLineID: "line-1"}},
+			{LaborLine: &models.LaborLine{LaborLineID: "line-2"}},
+		})
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), batchEvent("batchCreateLaborLines", map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"accountId": accountID, "taskId": taskID, "description": "Replace pads"},
+			map[string]interface{}{"accountId": accountID, "taskId": taskID, "description": "Bleed brakes"},
+		},
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+
+	result := response.Data.(*models.BatchResult)
+	assert.Equal(t, models.BatchModeAtomic, result.Mode)
+	assert.Equal(t, 2, result.SuccessCount)
+	assert.Equal(t, 0, result.FailureCount)
+	require.Len(t, result.Items, 2)
+	assert.Equal(t, "line-2", result.Items[1].LaborLine.LaborLineID)
+	assert.Equal(t, 1, result.Items[1].Index)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_BatchUpdateLaborLines_BestEffort(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	ids := []string{uuid.New().String(), uuid.New().String(), uuid.New().String()}
+
+	validationService.On("ValidateUpdateInput", mock.MatchedBy(func(input models.UpdateLaborLineInput) bool {
+		return input.LaborLineID != ids[1]
+	}), mock.Anything).Return(nil)
+	validationService.On("ValidateUpdateInput", mock.Anything, mock.Anything).Return(errors.New("description is too long"))
+
+	// Only the valid items are written; the results map back to their input positions
+	dynamoDBService.On("BatchUpdateLaborLines", mock.Anything, mock.MatchedBy(func(updates []services.LaborLineUpdate) bool {
+		return len(updates) == 2 &&
+			updates[0].Input.LaborLineID == ids[0] &&
+			updates[1].Input.LaborLineID == ids[2] &&
+			*updates[1].Input.ExpectedVersion == 4
+	}), models.BatchModeBestEffort, "user-123").
+		Return([]services.BatchWriteResult{
+			{LaborLine: &models.LaborLine{LaborLineID: ids[0]}},
+			{Err: &services.ConflictError{CurrentVersion: 5}},
+		})
+
+	rate := map[string]interface{}{"rateType": "HOURLY", "ratePerHour": "90"}
+	item := func(id string) map[string]interface{} {
+		input := map[string]interface{}{"accountId": accountID, "taskId": taskID, "laborLineId": id}
+		for k, v := range rate {
+			input[k] = v
+		}
+		return input
+	}
+	last := item(ids[2])
+	last["expectedVersion"] = 4
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), batchEvent("batchUpdateLaborLines", map[string]interface{}{
+		"mode":  "BEST_EFFORT",
+		"items": []interface{}{item(ids[0]), item(ids[1]), last},
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+
+	result := response.Data.(*models.BatchResult)
+	assert.Equal(t, 1, result.SuccessCount)
+	assert.Equal(t, 2, result.FailureCount)
+	assert.True(t, result.Items[0].Success)
+	assert.Equal(t, "ValidationError", result.Items[1].Error.Type)
+	assert.Equal(t, "ConflictError", result.Items[2].Error.Type)
+	assert.Equal(t, int64(5), result.Items[2].Error.ErrorInfo["currentVersion"])
+
+	// Both items supplied a rate and left custom fields unchanged, so neither was loaded
+	dynamoDBService.AssertNotCalled(t, "GetRateCard", mock.Anything, mock.Anything)
+	dynamoDBService.AssertNotCalled(t, "ListCustomFieldDefinitions", mock.Anything, mock.Anything)
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_BatchCreateLaborLines_AtomicValidationFailure(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, mock.Anything).Return(([]*models.CustomFieldDefinition)(nil), nil)
+	validationService.On("ValidateCreateInput", mock.MatchedBy(func(input models.CreateLaborLineInput) bool {
+		return input.TaskID != ""
+	}), mock.Anything).Return(nil)
+	validationService.On("ValidateCreateInput", mock.Anything, mock.Anything).Return(errors.New("taskId is required"))
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), batchEvent("batchCreateLaborLines", map[string]interface{}{
+		"mode": "ATOMIC",
+		"items": []interface{}{
+			map[string]interface{}{"accountId": uuid.New().String(), "taskId": uuid.New().String(), "rateType": "HOURLY", "ratePerHour": "90"},
+			map[string]interface{}{"accountId": uuid.New().String()},
+		},
+	}))
+
+	require.NoError(t, err)
+	result := response.Data.(*models.BatchResult)
+	assert.Equal(t, 0, result.SuccessCount)
+	assert.Equal(t, "BatchAborted", result.Items[0].Error.Type)
+	assert.Equal(t, "ValidationError", result.Items[1].Error.Type)
+
+	dynamoDBService.AssertNotCalled(t, "BatchCreateLaborLines", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_BatchDeleteLaborLines(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	inputs := []models.DeleteLaborLineInput{
+		{AccountID: accountID, TaskID: uuid.New().String(), LaborLineID: uuid.New().String()},
+		{AccountID: accountID, TaskID: uuid.New().String(), LaborLineID: uuid.New().String()},
+	}
+	dynamoDBService.On("BatchDeleteLaborLines", mock.Anything, inputs, models.BatchModeBestEffort, "user-123").
+		Return([]services.BatchWriteResult{
+			{LaborLine: &models.LaborLine{LaborLineID: inputs[0].LaborLineID}},
+			{Err: services.ErrLaborLineNotFound},
+		})
+
+	items := make([]interface{}, len(inputs))
+	for i, input := range inputs {
+		items[i] = laborLineKeyInput(input.AccountID, input.TaskID, input.LaborLineID)
+	}
+	response, err := handler.HandleAppSyncEvent(context.Background(), batchEvent("batchDeleteLaborLines", map[string]interface{}{
+		"mode":  "BEST_EFFORT",
+		"items": items,
+	}))
+
+	require.NoError(t, err)
+	result := response.Data.(*models.BatchResult)
+	assert.Equal(t, 1, result.SuccessCount)
+	assert.True(t, result.Items[0].Success)
+	assert.Equal(t, "NotFound", result.Items[1].Error.Type)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_BatchInvalidRequest(t *testing.T) {
+	tooMany := make([]interface{}, models.MaxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = map[string]interface{}{"accountId": "a", "taskId": "t", "laborLineId": "l"}
+	}
+
+	tests := []struct {
+		name  string
+		input map[string]interface{}
+	}{
+		{name: "Unknown mode", input: map[string]interface{}{"mode": "SOMETIMES", "items": tooMany[:1]}},
+		{name: "No items", input: map[string]interface{}{"items": []interface{}{}}},
+		{name: "Too many items", input: map[string]interface{}{"items": tooMany}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamoDBService := &MockDynamoDBService{}
+			validationService := &MockValidationService{}
+			handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+			response, err := handler.HandleAppSyncEvent(context.Background(), batchEvent("batchDeleteLaborLines", tt.input))
+
+			require.NoError(t, err)
+			require.NotNil(t, response.Error)
+			assert.Equal(t, "ValidationError", response.Error.Type)
+			dynamoDBService.AssertNotCalled(t, "BatchDeleteLaborLines", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
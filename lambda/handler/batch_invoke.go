@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// batchInvokeConcurrency bounds how many events of an AppSync batch are
+// handled at the same time.
+const batchInvokeConcurrency = 10
+
+// HandleAppSyncBatch processes the events of an AppSync BatchInvoke request and
+// returns one response per event, in the same order. getLaborLine events are
+// coalesced into a single batch read; the rest are handled concurrently.
+func (h *LaborLineHandler) HandleAppSyncBatch(ctx context.Context, events []models.AppSyncEvent) []*models.AppSyncResponse {
+	responses := make([]*models.AppSyncResponse, len(events))
+
+	var gets []int
+	var others []int
+	for i, event := range events {
+		if event.Info.FieldName == "getLaborLine" {
+			gets = append(gets, i)
+		} else {
+			others = append(others, i)
+		}
+	}
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, batchInvokeConcurrency)
+	for _, i := range others {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			responses[i] = h.handleBatchedEvent(ctx, events[i])
+		}()
+	}
+
+	h.handleBatchedGets(ctx, events, gets, responses)
+	wg.Wait()
+
+	return responses
+}
+
+// handleBatchedEvent handles one event of a batch, turning an unexpected error
+// into an error response for that event alone.
+func (h *LaborLineHandler) handleBatchedEvent(ctx context.Context, event models.AppSyncEvent) *models.AppSyncResponse {
+	response, err := h.HandleAppSyncEvent(ctx, event)
+	if err != nil {
+		log.Printf("Error handling batched %s event: %v", event.Info.FieldName, err)
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("failed to handle %s", event.Info.FieldName),
+				Type:    "InternalError",
+			},
+		}
+	}
+	return response
+}
+
+// handleBatchedGets answers the getLaborLine events at the given indexes with a
+// single BatchGetLaborLines call, writing each response to its index.
+func (h *LaborLineHandler) handleBatchedGets(ctx context.Context, events []models.AppSyncEvent, indexes []int, responses []*models.AppSyncResponse) {
+	inputs := make([]models.GetLaborLineInput, 0, len(indexes))
+	valid := make([]int, 0, len(indexes))
+	for _, i := range indexes {
+		if response := h.authorize(ctx, events[i]); response != nil {
+			responses[i] = response
+			continue
+		}
+
+		var input models.GetLaborLineInput
+		if err := events[i].GetInputArgument(&input); err != nil {
+			responses[i] = &models.AppSyncResponse{
+				Error: &models.AppSyncError{
+					Message: fmt.Sprintf("invalid input: %v", err),
+					Type:    "ValidationError",
+				},
+			}
+			continue
+		}
+		inputs = append(inputs, input)
+		valid = append(valid, i)
+	}
+	if len(inputs) == 0 {
+		return
+	}
+
+	laborLines, err := h.dynamoDBService.BatchGetLaborLines(ctx, inputs)
+	if err != nil {
+		log.Printf("Error batch getting labor lines: %v", err)
+		for _, i := range valid {
+			responses[i] = &models.AppSyncResponse{
+				Error: &models.AppSyncError{
+					Message: "failed to get labor line",
+					Type:    "InternalError",
+				},
+			}
+		}
+		return
+	}
+
+	for j, i := range valid {
+		if laborLines[j] == nil {
+			responses[i] = &models.AppSyncResponse{
+				Error: &models.AppSyncError{
+					Message: "labor line not found",
+					Type:    "NotFound",
+				},
+			}
+			continue
+		}
+		responses[i] = &models.AppSyncResponse{
+			Data: laborLines[j],
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// getEvent builds a getLaborLine event.
+func getEvent(input interface{}) models.AppSyncEvent {
+	return authorized(models.AppSyncEvent{
+		Info:      models.AppSyncInfo{FieldName: "getLaborLine", ParentTypeName: "Task"},
+		Arguments: map[string]interface{}{"input": input},
+	})
+}
+
+func TestLaborLineHandler_HandleAppSyncBatch(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	found := &models.LaborLine{LaborLineID: uuid.New().String(), AccountID: accountID, TaskID: taskID}
+	missingID := uuid.New().String()
+	connection := &models.LaborLineConnection{Items: []*models.LaborLine{found}}
+
+	// Both gets are answered by a single batch read
+	dynamoDBService.On("BatchGetLaborLines", mock.Anything, []models.GetLaborLineInput{
+		{AccountID: accountID, TaskID: taskID, LaborLineID: found.LaborLineID},
+		{AccountID: accountID, TaskID: taskID, LaborLineID: missingID},
+	}).Return([]*models.LaborLine{found, nil}, nil).Once()
+	dynamoDBService.On("ListLaborLines", mock.Anything, models.ListLaborLinesInput{AccountID: accountID}).
+		Return(connection, nil).Once()
+
+	responses := handler.HandleAppSyncBatch(context.Background(), []models.AppSyncEvent{
+		getEvent(laborLineKeyInput(accountID, taskID, found.LaborLineID)),
+		authorized(models.AppSyncEvent{
+			Info:      models.AppSyncInfo{FieldName: "listLaborLines"},
+			Arguments: map[string]interface{}{"input": map[string]interface{}{"accountId": accountID}},
+		}),
+		getEvent("not an object"),
+		getEvent(laborLineKeyInput(accountID, taskID, missingID)),
+		{Info: models.AppSyncInfo{FieldName: "unknownField"}},
+	})
+
+	require.Len(t, responses, 5)
+	assert.Equal(t, found, responses[0].Data)
+	assert.Equal(t, connection, responses[1].Data)
+	assert.Equal(t, "ValidationError", responses[2].Error.Type)
+	assert.Equal(t, "NotFound", responses[3].Error.Type)
+	assert.Equal(t, "UnsupportedOperation", responses[4].Error.Type)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncBatch_BatchGetFailure(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	dynamoDBService.On("BatchGetLaborLines", mock.Anything, mock.Anything).
+		Return(([]*models.LaborLine)(nil), errors.New("dynamodb unavailable"))
+
+	responses := handler.HandleAppSyncBatch(context.Background(), []models.AppSyncEvent{
+		getEvent(laborLineKeyInput(uuid.New().String(), uuid.New().String(), uuid.New().String())),
+		getEvent(laborLineKeyInput(uuid.New().String(), uuid.New().String(), uuid.New().String())),
+	})
+
+	require.Len(t, responses, 2)
+	for _, response := range responses {
+		require.NotNil(t, response.Error)
+		assert.Equal(t, "InternalError", response.Error.Type)
+	}
+}
+
+func TestLaborLineHandler_HandleAppSyncBatch_BoundedConcurrency(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountIDs := make([]string, 3*batchInvokeConcurrency)
+	events := make([]models.AppSyncEvent, len(accountIDs))
+	for i := range accountIDs {
+		accountIDs[i] = uuid.New().String()
+		events[i] = authorized(models.AppSyncEvent{
+			Info:      models.AppSyncInfo{FieldName: "listLaborLines"},
+			Arguments: map[string]interface{}{"input": map[string]interface{}{"accountId": accountIDs[i]}},
+		})
+		dynamoDBService.On("ListLaborLines", mock.Anything, models.ListLaborLinesInput{AccountID: accountIDs[i]}).
+			Return(&models.LaborLineConnection{Items: []*models.LaborLine{{AccountID: accountIDs[i]}}}, nil)
+	}
+
+	responses := handler.HandleAppSyncBatch(context.Background(), events)
+
+	// Every response is in the position of its event
+	require.Len(t, responses, len(events))
+	for i, response := range responses {
+		require.Nil(t, response.Error)
+		assert.Equal(t, accountIDs[i], response.Data.(*models.LaborLineConnection).Items[0].AccountID)
+	}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// handleDefineCustomField processes requests to create or replace one of an
+// account's custom field definitions.
+func (h *LaborLineHandler) handleDefineCustomField(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.DefineCustomFieldInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	// Validate input
+	if err := h.validationService.ValidateDefineCustomFieldInput(input); err != nil {
+		return validationErrorResponse(err), nil
+	}
+
+	definition := input.ToCustomFieldDefinition()
+	if err := h.dynamoDBService.PutCustomFieldDefinition(ctx, definition); err != nil {
+		log.Printf("Error defining custom field: %v", err)
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "failed to define custom field",
+				Type:    "InternalError",
+			},
+		}, nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: definition,
+	}, nil
+}
+
+// handleListCustomFields processes requests for an account's custom field definitions.
+func (h *LaborLineHandler) handleListCustomFields(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.ListCustomFieldsInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	definitions, err := h.dynamoDBService.ListCustomFieldDefinitions(ctx, input.AccountID)
+	if err != nil {
+		log.Printf("Error listing custom fields: %v", err)
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "failed to list custom fields",
+				Type:    "InternalError",
+			},
+		}, nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: definitions,
+	}, nil
+}
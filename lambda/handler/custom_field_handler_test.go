@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+func TestLaborLineHandler_HandleAppSyncEvent_DefineCustomField(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{FieldName: "defineCustomField"},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"accountId": accountID,
+				"name":      "shift",
+				"type":      "STRING",
+				"enum":      []interface{}{"DAY", "NIGHT"},
+			},
+		},
+	}
+
+	validationService.On("ValidateDefineCustomFieldInput", mock.Anything).Return(nil)
+	dynamoDBService.On("PutCustomFieldDefinition", mock.Anything, mock.MatchedBy(func(definition *models.CustomFieldDefinition) bool {
+		return definition.AccountID == accountID && definition.Name == "shift" && definition.SK == "CUSTOMFIELD#shift"
+	})).Return(nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+	definition, ok := response.Data.(*models.CustomFieldDefinition)
+	require.True(t, ok)
+	assert.Equal(t, []string{"DAY", "NIGHT"}, definition.Enum)
+
+	dynamoDBService.AssertExpectations(t)
+	validationService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_DefineCustomField_AdvisorDenied(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("defineCustomField", map[string]interface{}{
+		"accountId": uuid.New().String(),
+		"name":      "bayNumber",
+		"type":      "INTEGER",
+	}, "service-advisors"))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "Unauthorized", response.Error.Type)
+	assert.Equal(t, DenyReasonOperationNotAllowed, response.Error.ErrorInfo["reason"])
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_ListCustomFields(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	definitions := []*models.CustomFieldDefinition{
+		{AccountID: accountID, Name: "bayNumber", Type: models.CustomFieldTypeInteger},
+	}
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, accountID).Return(definitions, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("listCustomFields", map[string]interface{}{
+		"accountId": accountID,
+	}, "technicians"))
+
+	require.NoError(t, err)
+	assert.Nil(t, response.Error)
+	assert.Equal(t, definitions, response.Data)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine_CustomFields(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	definitions := []*models.CustomFieldDefinition{
+		{AccountID: accountID, Name: "bayNumber", Type: models.CustomFieldTypeInteger, Required: true},
+	}
+	fields := []services.FieldError{
+		{Field: "customFields.bayNumber", Rule: "required", Message: "bayNumber is required"},
+	}
+
+	// The account's definitions are passed to validation
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, accountID).Return(definitions, nil)
+	validationService.On("ValidateCreateInput", mock.Anything, definitions).Return(&services.ValidationError{Fields: fields})
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(models.AppSyncEvent{
+		Info: models.AppSyncInfo{FieldName: "createLaborLine"},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"accountId":    accountID,
+				"taskId":       uuid.New().String(),
+				"customFields": map[string]interface{}{"shift": "DAY"},
+			},
+		},
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ValidationError", response.Error.Type)
+	assert.Equal(t, fields, response.Error.ErrorInfo["fields"])
+	dynamoDBService.AssertNotCalled(t, "CreateLaborLine", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// handleRestore processes requests to restore a soft-deleted labor line.
+func (h *LaborLineHandler) handleRestore(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.RestoreLaborLineInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	laborLine, err := h.dynamoDBService.RestoreLaborLine(ctx, input, event.Actor())
+	if err != nil {
+		return writeErrorResponse(err, "failed to restore labor line"), nil
+	}
+
+	// The parts released when the labor line was deleted are reserved again
+	if len(laborLine.Parts) > 0 {
+		h.restoreParts(ctx, laborLine)
+	}
+
+	return &models.AppSyncResponse{
+		Data: laborLine,
+	}, nil
+}
+
+// handlePurge processes requests to permanently remove a soft-deleted labor
+// line. The permission policy limits purging to admins.
+func (h *LaborLineHandler) handlePurge(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.PurgeLaborLineInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	if err := h.dynamoDBService.PurgeLaborLine(ctx, input); err != nil {
+		return writeErrorResponse(err, "failed to purge labor line"), nil
+	}
+	h.releaseParts(ctx, input.AccountID, input.LaborLineID)
+
+	// The history is purged with the labor line, so the log is the only record
+	log.Printf("Labor line %s purged by %s", input.LaborLineID, event.Actor())
+
+	return &models.AppSyncResponse{
+		Data: map[string]interface{}{
+			"success": true,
+			"message": "labor line purged successfully",
+		},
+	}, nil
+}
+
+// handleListDeleted processes requests for a page of soft-deleted labor lines.
+func (h *LaborLineHandler) handleListDeleted(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.ListLaborLinesInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	connection, err := h.dynamoDBService.ListDeletedLaborLines(ctx, input)
+	if errors.Is(err, services.ErrInvalidNextToken) {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "invalid nextToken",
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+	if err != nil {
+		log.Printf("Error listing deleted labor lines: %v", err)
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "failed to list deleted labor lines",
+				Type:    "InternalError",
+			},
+		}, nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: connection,
+	}, nil
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// laborLineKeyInput builds the input arguments identifying a labor line.
+func laborLineKeyInput(accountID, taskID, laborLineID string) map[string]interface{} {
+	return map[string]interface{}{
+		"accountId":   accountID,
+		"taskId":      taskID,
+		"laborLineId": laborLineID,
+	}
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_RestoreLaborLine(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	input := models.RestoreLaborLineInput{
+		AccountID:   uuid.New().String(),
+		TaskID:      uuid.New().String(),
+		LaborLineID: uuid.New().String(),
+	}
+	restored := &models.LaborLine{
+		LaborLineID: input.LaborLineID,
+		AccountID:   input.AccountID,
+		TaskID:      input.TaskID,
+		Version:     3,
+	}
+	dynamoDBService.On("RestoreLaborLine", mock.Anything, input, "user-123").Return(restored, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(models.AppSyncEvent{
+		Info:      models.AppSyncInfo{FieldName: "restoreLaborLine"},
+		Arguments: map[string]interface{}{"input": laborLineKeyInput(input.AccountID, input.TaskID, input.LaborLineID)},
+		Identity:  map[string]interface{}{"sub": "user-123"},
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+	assert.Equal(t, restored, response.Data)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_RestoreLaborLine_NotDeleted(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	dynamoDBService.On("RestoreLaborLine", mock.Anything, mock.Anything, mock.Anything).
+		Return((*models.LaborLine)(nil), services.ErrLaborLineNotDeleted)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(models.AppSyncEvent{
+		Info:      models.AppSyncInfo{FieldName: "restoreLaborLine"},
+		Arguments: map[string]interface{}{"input": laborLineKeyInput(uuid.New().String(), uuid.New().String(), uuid.New().String())},
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ValidationError", response.Error.Type)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_PurgeLaborLine(t *testing.T) {
+	input := models.PurgeLaborLineInput{
+		AccountID:   uuid.New().String(),
+		TaskID:      uuid.New().String(),
+		LaborLineID: uuid.New().String(),
+	}
+
+	tests := []struct {
+		name         string
+		identity     map[string]interface{}
+		purgeErr     error
+		expectPurge  bool
+		expectedType string
+	}{
+		{
+			name:        "Admin purges",
+			identity:    map[string]interface{}{"sub": "admin-1", "groups": []interface{}{"admin"}},
+			expectPurge: true,
+		},
+		{
+			name:         "Non-admin is rejected",
+			identity:     map[string]interface{}{"sub": "user-123", "groups": []interface{}{"technicians"}},
+			expectedType: "Unauthorized",
+		},
+		{
+			name:         "Caller without a role is rejected",
+			identity:     map[string]interface{}{"sub": "user-123", "groups": []interface{}{}},
+			expectedType: "Unauthorized",
+		},
+		{
+			name:         "Labor line not deleted",
+			identity:     map[string]interface{}{"sub": "admin-1", "groups": []interface{}{"admin"}},
+			purgeErr:     services.ErrLaborLineNotDeleted,
+			expectPurge:  true,
+			expectedType: "ValidationError",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamoDBService := &MockDynamoDBService{}
+			validationService := &MockValidationService{}
+			handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+			if tt.expectPurge {
+				dynamoDBService.On("PurgeLaborLine", mock.Anything, input).Return(tt.purgeErr)
+			}
+
+			response, err := handler.HandleAppSyncEvent(context.Background(), authorized(models.AppSyncEvent{
+				Info:      models.AppSyncInfo{FieldName: "purgeLaborLine"},
+				Arguments: map[string]interface{}{"input": laborLineKeyInput(input.AccountID, input.TaskID, input.LaborLineID)},
+				Identity:  tt.identity,
+			}))
+
+			require.NoError(t, err)
+			require.NotNil(t, response)
+			if tt.expectedType == "" {
+				assert.Nil(t, response.Error)
+			} else {
+				require.NotNil(t, response.Error)
+				assert.Equal(t, tt.expectedType, response.Error.Type)
+			}
+
+			if !tt.expectPurge {
+				dynamoDBService.AssertNotCalled(t, "PurgeLaborLine", mock.Anything, mock.Anything)
+			}
+			dynamoDBService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_ListDeletedLaborLines(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	deletedAt := int64(1700000000)
+	connection := &models.LaborLineConnection{
+		Items: []*models.LaborLine{
+			{LaborLineID: uuid.New().String(), AccountID: accountID, DeletedAt: &deletedAt},
+		},
+	}
+	dynamoDBService.On("ListDeletedLaborLines", mock.Anything, models.ListLaborLinesInput{AccountID: accountID, Limit: 5}).
+		Return(connection, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(models.AppSyncEvent{
+		Info: models.AppSyncInfo{FieldName: "listDeletedLaborLines"},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{"accountId": accountID, "limit": 5},
+		},
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+	assert.Equal(t, connection, response.Data)
+
+	dynamoDBService.AssertExpectations(t)
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// WithLaborLineExporter sets the exporter behind the exportLaborLines
+// operation. Without it the operation reports that exports are not configured.
+func WithLaborLineExporter(exporter *services.LaborLineExporter) LaborLineHandlerOption {
+	return func(h *LaborLineHandler) {
+		h.exporter = exporter
+	}
+}
+
+// handleExportLaborLines processes requests to export an account's labor
+// lines to a file, returning a URL to download it from.
+func (h *LaborLineHandler) handleExportLaborLines(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.ExportLaborLinesInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	if input.Format != "" && !input.Format.IsValid() {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid format: %s", input.Format),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+	if len(input.Columns) > 0 && input.Format == models.ExportFormatNDJSON {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "columns can only be chosen for CSV exports",
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+	for _, column := range input.Columns {
+		if !models.IsExportColumn(column) {
+			return &models.AppSyncResponse{
+				Error: &models.AppSyncError{
+					Message: fmt.Sprintf("unknown column: %s", column),
+					Type:    "ValidationError",
+				},
+			}, nil
+		}
+	}
+	if input.CreatedFrom != nil && input.CreatedTo != nil && *input.CreatedFrom > *input.CreatedTo {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "createdFrom must not be after createdTo",
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	if h.exporter == nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "labor line exports are not configured",
+				Type:    "ConfigurationError",
+			},
+		}, nil
+	}
+
+	export, err := h.exporter.Export(ctx, input)
+	if err != nil {
+		log.Printf("Error exporting labor lines: %v", err)
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "failed to export labor lines",
+				Type:    "InternalError",
+			},
+		}, nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: export,
+	}, nil
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// exportEvent builds an exportLaborLines event.
+func exportEvent(input map[string]interface{}) models.AppSyncEvent {
+	return authorized(models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "exportLaborLines",
+		},
+		Arguments: map[string]interface{}{
+			"input": input,
+		},
+	})
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_ExportLaborLines(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	store := services.NewMemoryExportStore()
+	handler := NewLaborLineHandler(dynamoDBService, validationService,
+		WithLaborLineExporter(services.NewLaborLineExporter(dynamoDBService, store)))
+
+	accountID := uuid.New().String()
+	from, to := int64(1759276800), int64(1761955199)
+	laborLines := []*models.LaborLine{
+		{LaborLineID: "ll-1", AccountID: accountID, Status: models.StatusCompleted, Description: "Brakes, front"},
+		{LaborLineID: "ll-2", AccountID: accountID, Status: models.StatusPending},
+	}
+	dynamoDBService.On("ForEachLaborLine", mock.Anything, models.ExportLaborLinesInput{
+		AccountID:   accountID,
+		CreatedFrom: &from,
+		CreatedTo:   &to,
+		Columns:     []string{"laborLineId", "description", "status"},
+	}, mock.Anything).Run(func(args mock.Arguments) {
+		fn := args.Get(2).(func(*models.LaborLine) error)
+		for _, laborLine := range laborLines {
+			require.NoError(t, fn(laborLine))
+		}
+	}).Return(nil).Once()
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), exportEvent(map[string]interface{}{
+		"accountId":   accountID,
+		"createdFrom": from,
+		"createdTo":   to,
+		"columns":     []interface{}{"laborLineId", "description", "status"},
+	}))
+
+	require.NoError(t, err)
+	require.Nil(t, response.Error)
+	export := response.Data.(*models.LaborLineExport)
+	assert.Equal(t, 2, export.RowCount)
+	assert.Equal(t, "memory://"+export.Key, export.URL)
+
+	body, _, ok := store.Get(export.Key)
+	require.True(t, ok)
+	assert.Equal(t, "laborLineId,description,status\r\nll-1,\"Brakes, front\",COMPLETED\r\nll-2,,PENDING\r\n", string(body))
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_ExportLaborLines_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    map[string]interface{}
+		expected string
+	}{
+		{"unknown format", map[string]interface{}{"format": "XLSX"}, "invalid format: XLSX"},
+		{"unknown column", map[string]interface{}{"columns": []interface{}{"laborLineId", "PK"}}, "unknown column: PK"},
+		{"columns for NDJSON", map[string]interface{}{"format": "NDJSON", "columns": []interface{}{"laborLineId"}}, "columns can only be chosen for CSV exports"},
+		{"reversed range", map[string]interface{}{"createdFrom": 1761955199, "createdTo": 1759276800}, "createdFrom must not be after createdTo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamoDBService := &MockDynamoDBService{}
+			handler := NewLaborLineHandler(dynamoDBService, &MockValidationService{},
+				WithLaborLineExporter(services.NewLaborLineExporter(dynamoDBService, services.NewMemoryExportStore())))
+
+			tt.input["accountId"] = uuid.New().String()
+			response, err := handler.HandleAppSyncEvent(context.Background(), exportEvent(tt.input))
+
+			require.NoError(t, err)
+			require.NotNil(t, response.Error)
+			assert.Equal(t, "ValidationError", response.Error.Type)
+			assert.Equal(t, tt.expected, response.Error.Message)
+			dynamoDBService.AssertNotCalled(t, "ForEachLaborLine", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_ExportLaborLines_NotConfigured(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	handler := NewLaborLineHandler(dynamoDBService, &MockValidationService{})
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), exportEvent(map[string]interface{}{
+		"accountId": uuid.New().String(),
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ConfigurationError", response.Error.Type)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_ExportLaborLines_Error(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	handler := NewLaborLineHandler(dynamoDBService, &MockValidationService{},
+		WithLaborLineExporter(services.NewLaborLineExporter(dynamoDBService, services.NewMemoryExportStore())))
+
+	dynamoDBService.On("ForEachLaborLine", mock.Anything, mock.Anything, mock.Anything).
+		Return(errors.New("throttled")).Once()
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), exportEvent(map[string]interface{}{
+		"accountId": uuid.New().String(),
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "InternalError", response.Error.Type)
+	assert.Equal(t, "failed to export labor lines", response.Error.Message)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// handleGetHistory processes requests for a page of a labor line's change history.
+func (h *LaborLineHandler) handleGetHistory(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.GetLaborLineHistoryInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	connection, err := h.dynamoDBService.GetLaborLineHistory(ctx, input)
+	if errors.Is(err, services.ErrInvalidNextToken) {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "invalid nextToken",
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+	if err != nil {
+		log.Printf("Error getting labor line history: %v", err)
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "failed to get labor line history",
+				Type:    "InternalError",
+			},
+		}, nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: connection,
+	}, nil
+}
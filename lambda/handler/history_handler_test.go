@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// historyEvent builds a getLaborLineHistory event.
+func historyEvent(input map[string]interface{}) models.AppSyncEvent {
+	return authorized(models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "getLaborLineHistory",
+		},
+		Arguments: map[string]interface{}{
+			"input": input,
+		},
+	})
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_GetLaborLineHistory(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	input := models.GetLaborLineHistoryInput{
+		AccountID:   uuid.New().String(),
+		TaskID:      uuid.New().String(),
+		LaborLineID: uuid.New().String(),
+		Limit:       10,
+		NextToken:   "token",
+	}
+	nextToken := "next"
+	connection := &models.HistoryConnection{
+		Items: []*models.HistoryRecord{
+			{LaborLineID: input.LaborLineID, Version: 2, Action: models.ActionUpdate, Actor: "user-123"},
+		},
+		NextToken: &nextToken,
+	}
+
+	dynamoDBService.On("GetLaborLineHistory", mock.Anything, input).Return(connection, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), historyEvent(map[string]interface{}{
+		"accountId":   input.AccountID,
+		"taskId":      input.TaskID,
+		"laborLineId": input.LaborLineID,
+		"limit":       10,
+		"nextToken":   "token",
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+	assert.Equal(t, connection, response.Data)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_GetLaborLineHistory_InvalidNextToken(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	dynamoDBService.On("GetLaborLineHistory", mock.Anything, mock.Anything).
+		Return((*models.HistoryConnection)(nil), fmt.Errorf("querying: %w", services.ErrInvalidNextToken))
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), historyEvent(map[string]interface{}{
+		"accountId":   uuid.New().String(),
+		"taskId":      uuid.New().String(),
+		"laborLineId": uuid.New().String(),
+		"nextToken":   "tampered",
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ValidationError", response.Error.Type)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_GetLaborLineHistory_Error(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	dynamoDBService.On("GetLaborLineHistory", mock.Anything, mock.Anything).
+		Return((*models.HistoryConnection)(nil), fmt.Errorf("dynamodb unavailable"))
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), historyEvent(map[string]interface{}{
+		"accountId":   uuid.New().String(),
+		"taskId":      uuid.New().String(),
+		"laborLineId": uuid.New().String(),
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "InternalError", response.Error.Type)
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// Import files are uploaded under importPrefix, as
+// imports/{accountId}/{file} or imports/{accountId}/dry-run/{file} to only
+// validate them. The report of each is written under reportPrefix with the
+// same path and a .json extension.
+const (
+	importPrefix = "imports/"
+	reportPrefix = "reports/"
+	dryRunFolder = "dry-run"
+)
+
+// ImportHandler imports the labor line files uploaded to an S3 bucket.
+type ImportHandler struct {
+	importer *services.LaborLineImporter
+	store    services.ImportStore
+}
+
+// NewImportHandler creates a new import handler.
+func NewImportHandler(importer *services.LaborLineImporter, store services.ImportStore) *ImportHandler {
+	return &ImportHandler{
+		importer: importer,
+		store:    store,
+	}
+}
+
+// HandleS3Event imports each uploaded file and writes its report next to the
+// bucket's other reports. Uploads whose key does not follow the import layout
+// are logged and skipped. An error is returned if a file could not be
+// imported or its report written, so that Lambda retries the event; rows
+// imported by the failed attempt are skipped by the retry.
+func (h *ImportHandler) HandleS3Event(ctx context.Context, event events.S3Event) error {
+	for _, record := range event.Records {
+		bucket, key := record.S3.Bucket.Name, record.S3.Object.URLDecodedKey
+		input, ok := importInputFromKey(key)
+		if !ok {
+			log.Printf("Skipping %s: not an import file", key)
+			continue
+		}
+
+		result, err := h.importObject(ctx, bucket, key, input)
+		if err != nil {
+			return fmt.Errorf("importing %s: %w", key, err)
+		}
+		log.Printf("Imported %s: %d rows, %d created, %d skipped, %d failed (dry run: %t)",
+			key, result.RowCount, result.CreatedCount, result.SkippedCount, result.FailedCount, result.DryRun)
+	}
+	return nil
+}
+
+// importObject imports one file and writes its report.
+func (h *ImportHandler) importObject(ctx context.Context, bucket, key string, input models.ImportLaborLinesInput) (*models.ImportResult, error) {
+	body, err := h.store.Get(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	result, err := h.importer.Import(ctx, body, input)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding import report: %w", err)
+	}
+	reportKey := reportPrefix + strings.TrimPrefix(key, importPrefix) + ".json"
+	if err := h.store.Put(ctx, bucket, reportKey, bytes.NewReader(report), "application/json"); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// importInputFromKey returns the import of the file uploaded under the key,
+// and whether the key follows the import layout and names a file in a format
+// labor lines can be imported from.
+func importInputFromKey(key string) (models.ImportLaborLinesInput, bool) {
+	if !strings.HasPrefix(key, importPrefix) {
+		return models.ImportLaborLinesInput{}, false
+	}
+	parts := strings.Split(strings.TrimPrefix(key, importPrefix), "/")
+
+	dryRun := len(parts) == 3 && parts[1] == dryRunFolder
+	if (len(parts) != 2 && !dryRun) || parts[0] == "" {
+		return models.ImportLaborLinesInput{}, false
+	}
+	format, ok := models.ImportFormatOf(key)
+	if !ok {
+		return models.ImportLaborLinesInput{}, false
+	}
+
+	return models.ImportLaborLinesInput{
+		AccountID: parts[0],
+		Source:    path.Base(key),
+		Format:    format,
+		DryRun:    dryRun,
+	}, true
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// s3Event builds the event of objects uploaded to the imports bucket.
+func s3Event(keys ...string) events.S3Event {
+	event := events.S3Event{}
+	for _, key := range keys {
+		event.Records = append(event.Records, events.S3EventRecord{
+			S3: events.S3Entity{
+				Bucket: events.S3Bucket{Name: "imports-bucket"},
+				Object: events.S3Object{Key: key, URLDecodedKey: key},
+			},
+		})
+	}
+	return event
+}
+
+// putImportFile stores an import file of one row in the store.
+func putImportFile(t *testing.T, store *services.MemoryImportStore, key string) {
+	t.Helper()
+
+	file := "taskId,rateType,ratePerHour\n" + uuid.New().String() + ",HOURLY,95\n"
+	require.NoError(t, store.Put(context.Background(), "imports-bucket", key, strings.NewReader(file), "text/csv"))
+}
+
+func TestImportHandler_HandleS3Event(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	store := services.NewMemoryImportStore()
+	handler := NewImportHandler(services.NewLaborLineImporter(dynamoDBService, validationService), store)
+
+	accountID := uuid.New().String()
+	putImportFile(t, store, "imports/"+accountID+"/fleet.csv")
+	putImportFile(t, store, "imports/"+accountID+"/dry-run/fleet.csv")
+
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, accountID).Return([]*models.CustomFieldDefinition{}, nil).Twice()
+	validationService.On("ValidateCreateInput", mock.Anything, mock.Anything).Return(nil).Twice()
+	dynamoDBService.On("BatchCreateLaborLines", mock.Anything, mock.Anything, models.BatchModeBestEffort, models.DefaultImportActor).
+		Return([]services.BatchWriteResult{{LaborLine: &models.LaborLine{}}}).Once()
+
+	err := handler.HandleS3Event(context.Background(), s3Event(
+		"imports/"+accountID+"/fleet.csv",
+		"imports/"+accountID+"/dry-run/fleet.csv",
+		"imports/"+accountID+"/fleet.xlsx",
+		"reports/"+accountID+"/fleet.csv.json",
+	))
+	require.NoError(t, err)
+
+	body, ok := store.Object("imports-bucket", "reports/"+accountID+"/fleet.csv.json")
+	require.True(t, ok)
+	var report models.ImportResult
+	require.NoError(t, json.Unmarshal(body, &report))
+	assert.Equal(t, "fleet.csv", report.Source)
+	assert.Equal(t, accountID, report.AccountID)
+	assert.Equal(t, 1, report.CreatedCount)
+
+	body, ok = store.Object("imports-bucket", "reports/"+accountID+"/dry-run/fleet.csv.json")
+	require.True(t, ok)
+	require.NoError(t, json.Unmarshal(body, &report))
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.ValidCount)
+	assert.Zero(t, report.CreatedCount)
+
+	dynamoDBService.AssertExpectations(t)
+	validationService.AssertExpectations(t)
+}
+
+func TestImportHandler_HandleS3Event_Error(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	store := services.NewMemoryImportStore()
+	handler := NewImportHandler(services.NewLaborLineImporter(dynamoDBService, &MockValidationService{}), store)
+
+	accountID := uuid.New().String()
+	putImportFile(t, store, "imports/"+accountID+"/fleet.csv")
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, accountID).
+		Return([]*models.CustomFieldDefinition(nil), errors.New("throttled")).Once()
+
+	// The event fails so that Lambda retries it
+	err := handler.HandleS3Event(context.Background(), s3Event("imports/"+accountID+"/fleet.csv"))
+	assert.EqualError(t, err, "importing imports/"+accountID+"/fleet.csv: throttled")
+
+	_, ok := store.Object("imports-bucket", "reports/"+accountID+"/fleet.csv.json")
+	assert.False(t, ok)
+}
+
+func TestImportInputFromKey(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected models.ImportLaborLinesInput
+		ok       bool
+	}{
+		{"imports/acct/fleet.csv", models.ImportLaborLinesInput{AccountID: "acct", Source: "fleet.csv", Format: models.ImportFormatCSV}, true},
+		{"imports/acct/dry-run/fleet.ndjson", models.ImportLaborLinesInput{AccountID: "acct", Source: "fleet.ndjson", Format: models.ImportFormatNDJSON, DryRun: true}, true},
+		{"imports/acct/other/fleet.csv", models.ImportLaborLinesInput{}, false},
+		{"imports//fleet.csv", models.ImportLaborLinesInput{}, false},
+		{"imports/fleet.csv", models.ImportLaborLinesInput{}, false},
+		{"imports/acct/fleet.txt", models.ImportLaborLinesInput{}, false},
+		{"uploads/acct/fleet.csv", models.ImportLaborLinesInput{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			input, ok := importInputFromKey(tt.key)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, input)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 
@@ -14,14 +15,60 @@ import (
 type LaborLineHandler struct {
 	dynamoDBService   services.DynamoDBService
 	validationService services.ValidationService
+	accountIDsClaim   string
+	trustedIAMRoles   map[string]bool
+	permissionPolicy  *PermissionPolicy
+	partsCatalog      services.PartsCatalog
+	exporter          *services.LaborLineExporter
+}
+
+// LaborLineHandlerOption configures optional behaviour of the labor line handler.
+type LaborLineHandlerOption func(*LaborLineHandler)
+
+// WithAccountIDsClaim sets the identity claim listing the accounts a caller may
+// access. Without it models.DefaultAccountIDsClaim is used.
+func WithAccountIDsClaim(claim string) LaborLineHandlerOption {
+	return func(h *LaborLineHandler) {
+		h.accountIDsClaim = claim
+	}
+}
+
+// WithTrustedIAMRoles trusts callers that sign their requests with one of the
+// given IAM roles, such as the account's own back-end services. They carry no
+// claims, so they may access every account and perform every operation.
+// Without it IAM callers are refused. Sessions of a role are matched by the
+// role's account and name, as models.IAMRoleARN describes.
+func WithTrustedIAMRoles(roleARNs ...string) LaborLineHandlerOption {
+	return func(h *LaborLineHandler) {
+		for _, arn := range roleARNs {
+			if role := models.IAMRoleARN(arn); role != "" {
+				h.trustedIAMRoles[role] = true
+			}
+		}
+	}
+}
+
+// WithPermissionPolicy sets the policy deciding which operations each caller
+// may perform. Without it the policy embedded in the function is used.
+func WithPermissionPolicy(policy *PermissionPolicy) LaborLineHandlerOption {
+	return func(h *LaborLineHandler) {
+		h.permissionPolicy = policy
+	}
 }
 
 // NewLaborLineHandler creates a new labor line handler.
-func NewLaborLineHandler(dynamoDBService services.DynamoDBService, validationService services.ValidationService) *LaborLineHandler {
-	return &LaborLineHandler{
+func NewLaborLineHandler(dynamoDBService services.DynamoDBService, validationService services.ValidationService, opts ...LaborLineHandlerOption) *LaborLineHandler {
+	h := &LaborLineHandler{
 		dynamoDBService:   dynamoDBService,
 		validationService: validationService,
+		accountIDsClaim:   models.DefaultAccountIDsClaim,
+		trustedIAMRoles:   map[string]bool{},
+		permissionPolicy:  defaultPermissionPolicy,
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
 }
 
 // HandleAppSyncEvent processes AppSync events and routes them to appropriate handlers.
@@ -32,24 +79,111 @@ func (h *LaborLineHandler) HandleAppSyncEvent(ctx context.Context, event models.
 
 	log.Printf("Processing AppSync event: %s.%s", typeName, fieldName)
 
+	operation, ok := h.operation(fieldName)
+	if !ok {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("unsupported operation: %s", fieldName),
+				Type:    "UnsupportedOperation",
+			},
+		}, nil
+	}
+
+	if response := h.authorize(ctx, event); response != nil {
+		return response, nil
+	}
+
+	return operation(ctx, event)
+}
+
+// authorize checks that the caller may perform the event's operation: every
+// account it refers to must be one the caller's identity grants, and the
+// permission policy must allow it for the caller's roles. Trusted IAM callers
+// may perform any operation. It returns the error
+// response to send if not.
+func (h *LaborLineHandler) authorize(ctx context.Context, event models.AppSyncEvent) *models.AppSyncResponse {
+	if h.isTrustedIAMCaller(event.CallerIdentity()) {
+		return nil
+	}
+	if response := h.authorizeAccounts(event); response != nil {
+		return response
+	}
+	return h.authorizeOperation(ctx, event)
+}
+
+// operation returns the handler for the given GraphQL field.
+func (h *LaborLineHandler) operation(fieldName string) (func(context.Context, models.AppSyncEvent) (*models.AppSyncResponse, error), bool) {
 	switch fieldName {
 	case "createLaborLine":
-		return h.handleCreate(ctx, event)
+		return h.handleCreate, true
 	case "updateLaborLine":
-		return h.handleUpdate(ctx, event)
+		return h.handleUpdate, true
 	case "deleteLaborLine":
-		return h.handleDelete(ctx, event)
+		return h.handleDelete, true
+	case "batchCreateLaborLines":
+		return h.handleBatchCreate, true
+	case "batchUpdateLaborLines":
+		return h.handleBatchUpdate, true
+	case "batchDeleteLaborLines":
+		return h.handleBatchDelete, true
+	case "restoreLaborLine":
+		return h.handleRestore, true
+	case "purgeLaborLine":
+		return h.handlePurge, true
 	case "getLaborLine":
-		return h.handleGet(ctx, event)
+		return h.handleGet, true
 	case "listLaborLines":
-		return h.handleList(ctx, event)
+		return h.handleList, true
+	case "listDeletedLaborLines":
+		return h.handleListDeleted, true
+	case "listLaborLinesByTask":
+		return h.handleListByTask, true
+	case "getTaskLaborSummary":
+		return h.handleGetTaskSummary, true
+	case "exportLaborLines":
+		return h.handleExportLaborLines, true
+	case "getLaborLineHistory":
+		return h.handleGetHistory, true
+	case "startLaborTimer":
+		return h.handleStartTimer, true
+	case "stopLaborTimer":
+		return h.handleStopTimer, true
+	case "addTimeEntry":
+		return h.handleAddTimeEntry, true
+	case "transitionLaborLineStatus":
+		return h.handleTransitionStatus, true
+	case "addLaborLineNote":
+		return h.handleAddNote, true
+	case "editLaborLineNote":
+		return h.handleEditNote, true
+	case "deleteLaborLineNote":
+		return h.handleDeleteNote, true
+	case "assignLaborLine":
+		return h.handleAssign, true
+	case "unassignLaborLine":
+		return h.handleUnassign, true
+	case "listLaborLinesByTechnician":
+		return h.handleListByTechnician, true
+	case "getRateCard":
+		return h.handleGetRateCard, true
+	case "updateRateCard":
+		return h.handleUpdateRateCard, true
+	case "defineCustomField":
+		return h.handleDefineCustomField, true
+	case "listCustomFields":
+		return h.handleListCustomFields, true
+	case "createLaborOperation":
+		return h.handleCreateLaborOperation, true
+	case "getLaborOperation":
+		return h.handleGetLaborOperation, true
+	case "updateLaborOperation":
+		return h.handleUpdateLaborOperation, true
+	case "deleteLaborOperation":
+		return h.handleDeleteLaborOperation, true
+	case "searchLaborOperations":
+		return h.handleSearchLaborOperations, true
 	default:
-		return &models.AppSyncResponse{
-			Error: &models.AppSyncError{
-				Message: fmt.Sprintf("unsupported operation: %s", fieldName),
-				Type:    "UnsupportedOperation",
-			},
-		}, nil
+		return nil, false
 	}
 }
 
@@ -65,19 +199,53 @@ func (h *LaborLineHandler) handleCreate(ctx context.Context, event models.AppSyn
 		}, nil
 	}
 
-	// Validate input
-	if err := h.validationService.ValidateCreateInput(input); err != nil {
+	// Validate input, including the account's custom fields
+	customFields, err := h.dynamoDBService.ListCustomFieldDefinitions(ctx, input.AccountID)
+	if err != nil {
+		log.Printf("Error listing custom fields: %v", err)
 		return &models.AppSyncResponse{
 			Error: &models.AppSyncError{
-				Message: fmt.Sprintf("validation failed: %v", err),
-				Type:    "ValidationError",
+				Message: "failed to create labor line",
+				Type:    "InternalError",
+			},
+		}, nil
+	}
+	if err := h.validationService.ValidateCreateInput(input, customFields); err != nil {
+		return validationErrorResponse(err), nil
+	}
+
+	// Fill in what the input leaves out from the labor operation it references
+	if input.OperationCode != "" {
+		var appErr *models.AppSyncError
+		if input, appErr = h.applyLaborOperation(ctx, input, "failed to create labor line"); appErr != nil {
+			return &models.AppSyncResponse{Error: appErr}, nil
+		}
+	}
+
+	// Default the rate from the account's rate card and compute the cost
+	rateCard, err := h.rateCardFor(ctx, input.AccountID, input.NeedsRateCard())
+	if err != nil {
+		log.Printf("Error getting rate card: %v", err)
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "failed to create labor line",
+				Type:    "InternalError",
 			},
 		}, nil
 	}
+	laborLine := models.NewLaborLine(input, rateCard)
+	laborLine.RecalculateLaborCost()
+
+	// Reserve the parts, releasing them again if the labor line is not created
+	if len(laborLine.Parts) > 0 {
+		if appErr := h.reserveParts(ctx, laborLine.AccountID, laborLine.LaborLineID, laborLine.Parts, "failed to create labor line"); appErr != nil {
+			return &models.AppSyncResponse{Error: appErr}, nil
+		}
+	}
 
 	// Create labor line
-	laborLine := models.NewLaborLine(input)
-	if err := h.dynamoDBService.CreateLaborLine(ctx, laborLine); err != nil {
+	if err := h.dynamoDBService.CreateLaborLine(ctx, laborLine, event.Actor()); err != nil {
+		h.releaseParts(ctx, laborLine.AccountID, laborLine.LaborLineID)
 		log.Printf("Error creating labor line: %v", err)
 		return &models.AppSyncResponse{
 			Error: &models.AppSyncError{
@@ -104,20 +272,28 @@ func (h *LaborLineHandler) handleUpdate(ctx context.Context, event models.AppSyn
 		}, nil
 	}
 
-	// Validate input
-	if err := h.validationService.ValidateUpdateInput(input); err != nil {
-		return &models.AppSyncResponse{
-			Error: &models.AppSyncError{
-				Message: fmt.Sprintf("validation failed: %v", err),
-				Type:    "ValidationError",
-			},
-		}, nil
+	// Validate the fields being changed, including custom fields if they are replaced
+	var customFields []*models.CustomFieldDefinition
+	if input.CustomFields.Set {
+		var err error
+		if customFields, err = h.dynamoDBService.ListCustomFieldDefinitions(ctx, input.AccountID); err != nil {
+			log.Printf("Error listing custom fields: %v", err)
+			return &models.AppSyncResponse{
+				Error: &models.AppSyncError{
+					Message: "failed to update labor line",
+					Type:    "InternalError",
+				},
+			}, nil
+		}
+	}
+	if err := h.validationService.ValidateUpdateInput(input, customFields); err != nil {
+		return validationErrorResponse(err), nil
 	}
 
-	// Update labor line
-	laborLine := input.ToLaborLine()
-	if err := h.dynamoDBService.UpdateLaborLine(ctx, laborLine); err != nil {
-		log.Printf("Error updating labor line: %v", err)
+	// Load the account's rate card if a rate is reset to its default
+	rateCard, err := h.rateCardFor(ctx, input.AccountID, input.NeedsRateCard())
+	if err != nil {
+		log.Printf("Error getting rate card: %v", err)
 		return &models.AppSyncResponse{
 			Error: &models.AppSyncError{
 				Message: "failed to update labor line",
@@ -126,20 +302,21 @@ func (h *LaborLineHandler) handleUpdate(ctx context.Context, event models.AppSyn
 		}, nil
 	}
 
-	// Return the updated labor line
-	updatedLaborLine, err := h.dynamoDBService.GetLaborLine(ctx, models.GetLaborLineInput{
-		AccountID:   input.AccountID,
-		TaskID:      input.TaskID,
-		LaborLineID: input.LaborLineID,
-	})
+	// Reserve the new parts in place of the labor line's previous reservation,
+	// which is put back if the update is not written
+	var existing *models.LaborLine
+	if input.Parts.Set {
+		var appErr *models.AppSyncError
+		if existing, appErr = h.reserveUpdatedParts(ctx, input, "failed to update labor line"); appErr != nil {
+			return &models.AppSyncResponse{Error: appErr}, nil
+		}
+	}
+
+	// Update labor line
+	updatedLaborLine, err := h.dynamoDBService.UpdateLaborLine(ctx, services.LaborLineUpdate{Input: input, RateCard: rateCard}, event.Actor())
 	if err != nil {
-		log.Printf("Error retrieving updated labor line: %v", err)
-		return &models.AppSyncResponse{
-			Error: &models.AppSyncError{
-				Message: "failed to retrieve updated labor line",
-				Type:    "InternalError",
-			},
-		}, nil
+		h.restoreParts(ctx, existing)
+		return writeErrorResponse(err, "failed to update labor line"), nil
 	}
 
 	return &models.AppSyncResponse{
@@ -159,16 +336,11 @@ func (h *LaborLineHandler) handleDelete(ctx context.Context, event models.AppSyn
 		}, nil
 	}
 
-	// Delete labor line
-	if err := h.dynamoDBService.DeleteLaborLine(ctx, input); err != nil {
-		log.Printf("Error deleting labor line: %v", err)
-		return &models.AppSyncResponse{
-			Error: &models.AppSyncError{
-				Message: "failed to delete labor line",
-				Type:    "InternalError",
-			},
-		}, nil
+	// Delete labor line, releasing its parts until it is restored
+	if err := h.dynamoDBService.DeleteLaborLine(ctx, input, event.Actor()); err != nil {
+		return writeErrorResponse(err, "failed to delete labor line"), nil
 	}
+	h.releaseParts(ctx, input.AccountID, input.LaborLineID)
 
 	return &models.AppSyncResponse{
 		Data: map[string]interface{}{
@@ -229,7 +401,15 @@ func (h *LaborLineHandler) handleList(ctx context.Context, event models.AppSyncE
 	}
 
 	// List labor lines
-	laborLines, err := h.dynamoDBService.ListLaborLines(ctx, input)
+	connection, err := h.dynamoDBService.ListLaborLines(ctx, input)
+	if errors.Is(err, services.ErrInvalidNextToken) {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "invalid nextToken",
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
 	if err != nil {
 		log.Printf("Error listing labor lines: %v", err)
 		return &models.AppSyncResponse{
@@ -241,6 +421,216 @@ func (h *LaborLineHandler) handleList(ctx context.Context, event models.AppSyncE
 	}
 
 	return &models.AppSyncResponse{
-		Data: laborLines,
+		Data: connection,
+	}, nil
+}
+
+// handleListByTask processes requests for a page of a task's labor lines across
+// every account the caller is authorized for.
+func (h *LaborLineHandler) handleListByTask(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.ListLaborLinesByTaskInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+	if input.TaskID == "" {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "taskId is required",
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	// Only labor lines in the caller's own accounts are returned
+	connection, err := h.dynamoDBService.ListLaborLinesByTask(ctx, input, event.CallerIdentity().AccountIDs(h.accountIDsClaim))
+	if errors.Is(err, services.ErrInvalidNextToken) {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "invalid nextToken",
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+	if errors.Is(err, services.ErrTooManyAccounts) {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: err.Error(),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+	if err != nil {
+		log.Printf("Error listing labor lines by task: %v", err)
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "failed to list labor lines",
+				Type:    "InternalError",
+			},
+		}, nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: connection,
 	}, nil
 }
+
+// validationErrorResponse reports invalid input. A ValidationError's invalid
+// fields are listed in the error info so clients can point each one out.
+func validationErrorResponse(err error) *models.AppSyncResponse {
+	response := &models.AppSyncResponse{
+		Error: &models.AppSyncError{
+			Message: fmt.Sprintf("validation failed: %v", err),
+			Type:    "ValidationError",
+		},
+	}
+
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		response.Error.ErrorInfo = map[string]interface{}{"fields": validationErr.Fields}
+	}
+	return response
+}
+
+// writeErrorResponse converts an error from a labor line write into an AppSync
+// error. Conflicts carry the current version so clients can prompt a reload,
+// rejected status transitions carry the allowed moves and stock shortages carry
+// the quantity available; unexpected errors are logged and reported with the
+// given message.
+func writeErrorResponse(err error, message string) *models.AppSyncResponse {
+	var conflictErr *services.ConflictError
+	var transitionErr *services.InvalidStateTransitionError
+	var stockErr *services.InsufficientStockError
+	switch {
+	case errors.As(err, &conflictErr):
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "labor line was modified by another request",
+				Type:    "ConflictError",
+				ErrorInfo: map[string]interface{}{
+					"currentVersion": conflictErr.CurrentVersion,
+				},
+			},
+		}
+	case errors.As(err, &transitionErr):
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: err.Error(),
+				Type:    "InvalidStateTransition",
+				ErrorInfo: map[string]interface{}{
+					"currentStatus":      transitionErr.From,
+					"requestedStatus":    transitionErr.To,
+					"allowedTransitions": models.AllowedTransitions(transitionErr.From),
+				},
+			},
+		}
+	case errors.As(err, &stockErr):
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: err.Error(),
+				Type:    "InsufficientStock",
+				ErrorInfo: map[string]interface{}{
+					"partId":    stockErr.PartID,
+					"requested": stockErr.Requested,
+					"available": stockErr.Available,
+				},
+			},
+		}
+	case errors.Is(err, services.ErrLaborLineReadOnly):
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: err.Error(),
+				Type:    "ReadOnly",
+			},
+		}
+	case errors.Is(err, services.ErrLaborLineNotFound):
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "labor line not found",
+				Type:    "NotFound",
+			},
+		}
+	case errors.Is(err, services.ErrNoteNotFound):
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "note not found",
+				Type:    "NotFound",
+			},
+		}
+	case errors.Is(err, services.ErrTechnicianNotAssigned):
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: err.Error(),
+				Type:    "NotFound",
+			},
+		}
+	case errors.Is(err, services.ErrTechnicianAlreadyAssigned), errors.Is(err, services.ErrTooManyTechnicians):
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: err.Error(),
+				Type:    "ValidationError",
+			},
+		}
+	case errors.Is(err, services.ErrLaborOperationNotFound):
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: err.Error(),
+				Type:    "NotFound",
+			},
+		}
+	case errors.Is(err, services.ErrLaborOperationExists):
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: err.Error(),
+				Type:    "ValidationError",
+			},
+		}
+	case errors.Is(err, services.ErrLaborLineNotDeleted):
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: err.Error(),
+				Type:    "ValidationError",
+			},
+		}
+	case errors.Is(err, services.ErrBatchAborted):
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: err.Error(),
+				Type:    "BatchAborted",
+			},
+		}
+	case errors.Is(err, services.ErrDuplicateBatchItem):
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: err.Error(),
+				Type:    "ValidationError",
+			},
+		}
+	case errors.Is(err, services.ErrTimerNotRunning):
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: err.Error(),
+				Type:    "NotFound",
+			},
+		}
+	case errors.Is(err, services.ErrTimeEntryOverlap):
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: err.Error(),
+				Type:    "ValidationError",
+			},
+		}
+	default:
+		log.Printf("Error: %s: %v", message, err)
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: message,
+				Type:    "InternalError",
+			},
+		}
+	}
+}
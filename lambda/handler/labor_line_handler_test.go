@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -11,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
 )
 
 // MockDynamoDBService is a mock implementation of DynamoDBService.
@@ -18,8 +20,8 @@ type MockDynamoDBService struct {
 	mock.Mock
 }
 
-func (m *MockDynamoDBService) CreateLaborLine(ctx context.Context, laborLine *models.LaborLine) error {
-	args := m.Called(ctx, laborLine)
+func (m *MockDynamoDBService) CreateLaborLine(ctx context.Context, laborLine *models.LaborLine, actor string) error {
+	args := m.Called(ctx, laborLine, actor)
 	return args.Error(0)
 }
 
@@ -28,19 +30,179 @@ func (m *MockDynamoDBService) GetLaborLine(ctx context.Context, input models.Get
 	return args.Get(0).(*models.LaborLine), args.Error(1)
 }
 
-func (m *MockDynamoDBService) UpdateLaborLine(ctx context.Context, laborLine *models.LaborLine) error {
-	args := m.Called(ctx, laborLine)
+func (m *MockDynamoDBService) BatchGetLaborLines(ctx context.Context, inputs []models.GetLaborLineInput) ([]*models.LaborLine, error) {
+	args := m.Called(ctx, inputs)
+	return args.Get(0).([]*models.LaborLine), args.Error(1)
+}
+
+func (m *MockDynamoDBService) UpdateLaborLine(ctx context.Context, update services.LaborLineUpdate, actor string) (*models.LaborLine, error) {
+	args := m.Called(ctx, update, actor)
+	return args.Get(0).(*models.LaborLine), args.Error(1)
+}
+
+func (m *MockDynamoDBService) DeleteLaborLine(ctx context.Context, input models.DeleteLaborLineInput, actor string) error {
+	args := m.Called(ctx, input, actor)
+	return args.Error(0)
+}
+
+func (m *MockDynamoDBService) RestoreLaborLine(ctx context.Context, input models.RestoreLaborLineInput, actor string) (*models.LaborLine, error) {
+	args := m.Called(ctx, input, actor)
+	return args.Get(0).(*models.LaborLine), args.Error(1)
+}
+
+func (m *MockDynamoDBService) PurgeLaborLine(ctx context.Context, input models.PurgeLaborLineInput) error {
+	args := m.Called(ctx, input)
+	return args.Error(0)
+}
+
+func (m *MockDynamoDBService) PurgeLaborLineRecords(ctx context.Context, accountID, taskID, laborLineID string) error {
+	args := m.Called(ctx, accountID, taskID, laborLineID)
 	return args.Error(0)
 }
 
-func (m *MockDynamoDBService) DeleteLaborLine(ctx context.Context, input models.DeleteLaborLineInput) error {
+func (m *MockDynamoDBService) ListDeletedLaborLines(ctx context.Context, input models.ListLaborLinesInput) (*models.LaborLineConnection, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*models.LaborLineConnection), args.Error(1)
+}
+
+func (m *MockDynamoDBService) ListLaborLines(ctx context.Context, input models.ListLaborLinesInput) (*models.LaborLineConnection, error) {
 	args := m.Called(ctx, input)
+	return args.Get(0).(*models.LaborLineConnection), args.Error(1)
+}
+
+func (m *MockDynamoDBService) ListLaborLinesByTask(ctx context.Context, input models.ListLaborLinesByTaskInput, accountIDs []string) (*models.LaborLineConnection, error) {
+	args := m.Called(ctx, input, accountIDs)
+	return args.Get(0).(*models.LaborLineConnection), args.Error(1)
+}
+
+func (m *MockDynamoDBService) ForEachLaborLine(ctx context.Context, input models.ExportLaborLinesInput, fn func(*models.LaborLine) error) error {
+	args := m.Called(ctx, input, fn)
 	return args.Error(0)
 }
 
-func (m *MockDynamoDBService) ListLaborLines(ctx context.Context, input models.ListLaborLinesInput) ([]*models.LaborLine, error) {
+func (m *MockDynamoDBService) GetTaskLaborSummary(ctx context.Context, input models.GetTaskLaborSummaryInput) (*models.TaskLaborSummary, error) {
 	args := m.Called(ctx, input)
-	return args.Get(0).([]*models.LaborLine), args.Error(1)
+	return args.Get(0).(*models.TaskLaborSummary), args.Error(1)
+}
+
+func (m *MockDynamoDBService) BatchCreateLaborLines(ctx context.Context, laborLines []*models.LaborLine, mode models.BatchMode, actor string) []services.BatchWriteResult {
+	args := m.Called(ctx, laborLines, mode, actor)
+	return args.Get(0).([]services.BatchWriteResult)
+}
+
+func (m *MockDynamoDBService) BatchUpdateLaborLines(ctx context.Context, updates []services.LaborLineUpdate, mode models.BatchMode, actor string) []services.BatchWriteResult {
+	args := m.Called(ctx, updates, mode, actor)
+	return args.Get(0).([]services.BatchWriteResult)
+}
+
+func (m *MockDynamoDBService) BatchDeleteLaborLines(ctx context.Context, inputs []models.DeleteLaborLineInput, mode models.BatchMode, actor string) []services.BatchWriteResult {
+	args := m.Called(ctx, inputs, mode, actor)
+	return args.Get(0).([]services.BatchWriteResult)
+}
+
+func (m *MockDynamoDBService) ListTimeEntries(ctx context.Context, input models.GetLaborLineInput) ([]*models.TimeEntry, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).([]*models.TimeEntry), args.Error(1)
+}
+
+func (m *MockDynamoDBService) SaveTimeEntry(ctx context.Context, entry *models.TimeEntry, actor string) (*models.LaborLine, error) {
+	args := m.Called(ctx, entry, actor)
+	return args.Get(0).(*models.LaborLine), args.Error(1)
+}
+
+func (m *MockDynamoDBService) GetRateCard(ctx context.Context, accountID string) (*models.RateCard, error) {
+	args := m.Called(ctx, accountID)
+	return args.Get(0).(*models.RateCard), args.Error(1)
+}
+
+func (m *MockDynamoDBService) PutRateCard(ctx context.Context, rateCard *models.RateCard) error {
+	args := m.Called(ctx, rateCard)
+	return args.Error(0)
+}
+
+func (m *MockDynamoDBService) ListCustomFieldDefinitions(ctx context.Context, accountID string) ([]*models.CustomFieldDefinition, error) {
+	args := m.Called(ctx, accountID)
+	return args.Get(0).([]*models.CustomFieldDefinition), args.Error(1)
+}
+
+func (m *MockDynamoDBService) PutCustomFieldDefinition(ctx context.Context, definition *models.CustomFieldDefinition) error {
+	args := m.Called(ctx, definition)
+	return args.Error(0)
+}
+
+func (m *MockDynamoDBService) CreateLaborOperation(ctx context.Context, operation *models.LaborOperation) error {
+	args := m.Called(ctx, operation)
+	return args.Error(0)
+}
+
+func (m *MockDynamoDBService) GetLaborOperation(ctx context.Context, accountID, code string) (*models.LaborOperation, error) {
+	args := m.Called(ctx, accountID, code)
+	return args.Get(0).(*models.LaborOperation), args.Error(1)
+}
+
+func (m *MockDynamoDBService) UpdateLaborOperation(ctx context.Context, input models.UpdateLaborOperationInput) (*models.LaborOperation, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*models.LaborOperation), args.Error(1)
+}
+
+func (m *MockDynamoDBService) DeleteLaborOperation(ctx context.Context, input models.DeleteLaborOperationInput) error {
+	args := m.Called(ctx, input)
+	return args.Error(0)
+}
+
+func (m *MockDynamoDBService) SearchLaborOperations(ctx context.Context, input models.SearchLaborOperationsInput) (*models.LaborOperationConnection, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*models.LaborOperationConnection), args.Error(1)
+}
+
+func (m *MockDynamoDBService) GetLaborLineHistory(ctx context.Context, input models.GetLaborLineHistoryInput) (*models.HistoryConnection, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*models.HistoryConnection), args.Error(1)
+}
+
+func (m *MockDynamoDBService) RelayOutbox(ctx context.Context, minAge time.Duration) (int, error) {
+	args := m.Called(ctx, minAge)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockDynamoDBService) ApplyLaborLineChange(ctx context.Context, before, after *models.LaborLine) (*models.TaskLaborTotals, error) {
+	args := m.Called(ctx, before, after)
+	return args.Get(0).(*models.TaskLaborTotals), args.Error(1)
+}
+
+func (m *MockDynamoDBService) TransitionLaborLineStatus(ctx context.Context, input models.TransitionLaborLineStatusInput, actor string) (*models.LaborLine, error) {
+	args := m.Called(ctx, input, actor)
+	return args.Get(0).(*models.LaborLine), args.Error(1)
+}
+
+func (m *MockDynamoDBService) AddLaborLineNote(ctx context.Context, input models.AddLaborLineNoteInput, actor string) (*models.LaborLine, error) {
+	args := m.Called(ctx, input, actor)
+	return args.Get(0).(*models.LaborLine), args.Error(1)
+}
+
+func (m *MockDynamoDBService) EditLaborLineNote(ctx context.Context, input models.EditLaborLineNoteInput, actor string) (*models.LaborLine, error) {
+	args := m.Called(ctx, input, actor)
+	return args.Get(0).(*models.LaborLine), args.Error(1)
+}
+
+func (m *MockDynamoDBService) DeleteLaborLineNote(ctx context.Context, input models.DeleteLaborLineNoteInput, actor string) (*models.LaborLine, error) {
+	args := m.Called(ctx, input, actor)
+	return args.Get(0).(*models.LaborLine), args.Error(1)
+}
+
+func (m *MockDynamoDBService) AssignLaborLine(ctx context.Context, input models.LaborLineAssignmentInput, actor string) (*models.LaborLine, error) {
+	args := m.Called(ctx, input, actor)
+	return args.Get(0).(*models.LaborLine), args.Error(1)
+}
+
+func (m *MockDynamoDBService) UnassignLaborLine(ctx context.Context, input models.LaborLineAssignmentInput, actor string) (*models.LaborLine, error) {
+	args := m.Called(ctx, input, actor)
+	return args.Get(0).(*models.LaborLine), args.Error(1)
+}
+
+func (m *MockDynamoDBService) ListLaborLinesByTechnician(ctx context.Context, input models.ListLaborLinesByTechnicianInput) (*models.LaborLineConnection, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*models.LaborLineConnection), args.Error(1)
 }
 
 // MockValidationService is a mock implementation of ValidationService.
@@ -48,16 +210,81 @@ type MockValidationService struct {
 	mock.Mock
 }
 
-func (m *MockValidationService) ValidateCreateInput(input models.CreateLaborLineInput) error {
+func (m *MockValidationService) ValidateCreateInput(input models.CreateLaborLineInput, customFields []*models.CustomFieldDefinition) error {
+	args := m.Called(input, customFields)
+	return args.Error(0)
+}
+
+func (m *MockValidationService) ValidateUpdateInput(input models.UpdateLaborLineInput, customFields []*models.CustomFieldDefinition) error {
+	args := m.Called(input, customFields)
+	return args.Error(0)
+}
+
+func (m *MockValidationService) ValidateStartTimerInput(input models.StartLaborTimerInput) error {
+	args := m.Called(input)
+	return args.Error(0)
+}
+
+func (m *MockValidationService) ValidateStopTimerInput(input models.StopLaborTimerInput) error {
+	args := m.Called(input)
+	return args.Error(0)
+}
+
+func (m *MockValidationService) ValidateAddTimeEntryInput(input models.AddTimeEntryInput) error {
+	args := m.Called(input)
+	return args.Error(0)
+}
+
+func (m *MockValidationService) ValidateRateCardInput(input models.UpdateRateCardInput) error {
+	args := m.Called(input)
+	return args.Error(0)
+}
+
+func (m *MockValidationService) ValidateTransitionStatusInput(input models.TransitionLaborLineStatusInput) error {
+	args := m.Called(input)
+	return args.Error(0)
+}
+
+func (m *MockValidationService) ValidateDefineCustomFieldInput(input models.DefineCustomFieldInput) error {
 	args := m.Called(input)
 	return args.Error(0)
 }
 
-func (m *MockValidationService) ValidateUpdateInput(input models.UpdateLaborLineInput) error {
+func (m *MockValidationService) ValidateAddNoteInput(input models.AddLaborLineNoteInput) error {
 	args := m.Called(input)
 	return args.Error(0)
 }
 
+func (m *MockValidationService) ValidateEditNoteInput(input models.EditLaborLineNoteInput) error {
+	args := m.Called(input)
+	return args.Error(0)
+}
+
+func (m *MockValidationService) ValidateDeleteNoteInput(input models.DeleteLaborLineNoteInput) error {
+	args := m.Called(input)
+	return args.Error(0)
+}
+
+func (m *MockValidationService) ValidateAssignmentInput(input models.LaborLineAssignmentInput) error {
+	args := m.Called(input)
+	return args.Error(0)
+}
+
+func (m *MockValidationService) ValidateCreateLaborOperationInput(input models.CreateLaborOperationInput) error {
+	args := m.Called(input)
+	return args.Error(0)
+}
+
+func (m *MockValidationService) ValidateUpdateLaborOperationInput(input models.UpdateLaborOperationInput) error {
+	args := m.Called(input)
+	return args.Error(0)
+}
+
+func (m *MockValidationService) ValidateLaborLine(laborLine *models.LaborLine) error {
+	args := m.Called(laborLine)
+	return args.Error(0)
+}
+
 func TestNewLaborLineHandler(t *testing.T) {
 	dynamoDBService := &MockDynamoDBService{}
 	validationService := &MockValidationService{}
@@ -88,15 +315,17 @@ func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine(t *testing.T) {
 		},
 	}
 
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, mock.Anything).Return(([]*models.CustomFieldDefinition)(nil), nil)
 	validationService.On("ValidateCreateInput", mock.MatchedBy(func(i models.CreateLaborLineInput) bool {
 		return i.AccountID == input.AccountID && i.TaskID == input.TaskID
-	})).Return(nil)
+	}), mock.Anything).Return(nil)
 
+	dynamoDBService.On("GetRateCard", mock.Anything, input.AccountID).Return((*models.RateCard)(nil), nil)
 	dynamoDBService.On("CreateLaborLine", mock.Anything, mock.MatchedBy(func(ll *models.LaborLine) bool {
 		return ll.AccountID == input.AccountID && ll.TaskID == input.TaskID
-	})).Return(nil)
+	}), "anonymous").Return(nil)
 
-	response, err := handler.HandleAppSyncEvent(context.Background(), event)
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
 
 	require.NoError(t, err)
 	require.NotNil(t, response)
@@ -113,6 +342,126 @@ func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine(t *testing.T) {
 	validationService.AssertExpectations(t)
 }
 
+func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine_DefaultsRateFromRateCard(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "createLaborLine",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"accountId":      accountID,
+				"taskId":         uuid.New().String(),
+				"estimatedHours": 1.5,
+				"rateType":       "FLAT_RATE",
+			},
+		},
+	}
+
+	rateCard := &models.RateCard{
+		AccountID:       accountID,
+		DefaultRateType: models.RateTypeHourly,
+		Rates: []models.RateCardEntry{
+			{RateType: models.RateTypeHourly, RatePerHour: models.MustParseDecimal("95")},
+			{RateType: models.RateTypeFlatRate, RatePerHour: models.MustParseDecimal("120.50")},
+		},
+	}
+
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, mock.Anything).Return(([]*models.CustomFieldDefinition)(nil), nil)
+	validationService.On("ValidateCreateInput", mock.Anything, mock.Anything).Return(nil)
+	dynamoDBService.On("GetRateCard", mock.Anything, accountID).Return(rateCard, nil)
+	dynamoDBService.On("CreateLaborLine", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+
+	laborLine, ok := response.Data.(*models.LaborLine)
+	require.True(t, ok)
+	assert.Equal(t, models.RateTypeFlatRate, laborLine.RateType)
+	assert.Equal(t, "120.50", laborLine.RatePerHour.StringFixed(2))
+	assert.Equal(t, "180.75", laborLine.LaborCost.StringFixed(2))
+
+	dynamoDBService.AssertExpectations(t)
+	validationService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine_ExplicitRateSkipsRateCard(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "createLaborLine",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"accountId":      uuid.New().String(),
+				"taskId":         uuid.New().String(),
+				"estimatedHours": 2,
+				"rateType":       "WARRANTY",
+				"ratePerHour":    "88.25",
+			},
+		},
+	}
+
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, mock.Anything).Return(([]*models.CustomFieldDefinition)(nil), nil)
+	validationService.On("ValidateCreateInput", mock.Anything, mock.Anything).Return(nil)
+	dynamoDBService.On("CreateLaborLine", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+
+	laborLine, ok := response.Data.(*models.LaborLine)
+	require.True(t, ok)
+	assert.Equal(t, "176.50", laborLine.LaborCost.StringFixed(2))
+
+	dynamoDBService.AssertNotCalled(t, "GetRateCard", mock.Anything, mock.Anything)
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine_RateCardError(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "createLaborLine",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"accountId": uuid.New().String(),
+				"taskId":    uuid.New().String(),
+			},
+		},
+	}
+
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, mock.Anything).Return(([]*models.CustomFieldDefinition)(nil), nil)
+	validationService.On("ValidateCreateInput", mock.Anything, mock.Anything).Return(nil)
+	dynamoDBService.On("GetRateCard", mock.Anything, mock.Anything).Return((*models.RateCard)(nil), fmt.Errorf("dynamodb unavailable"))
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "InternalError", response.Error.Type)
+
+	dynamoDBService.AssertNotCalled(t, "CreateLaborLine", mock.Anything, mock.Anything, mock.Anything)
+	dynamoDBService.AssertExpectations(t)
+}
+
 func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine_ValidationError(t *testing.T) {
 	dynamoDBService := &MockDynamoDBService{}
 	validationService := &MockValidationService{}
@@ -130,9 +479,10 @@ func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine_ValidationError(t *
 		},
 	}
 
-	validationService.On("ValidateCreateInput", mock.Anything).Return(fmt.Errorf("validation failed"))
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, mock.Anything).Return(([]*models.CustomFieldDefinition)(nil), nil)
+	validationService.On("ValidateCreateInput", mock.Anything, mock.Anything).Return(fmt.Errorf("validation failed"))
 
-	response, err := handler.HandleAppSyncEvent(context.Background(), event)
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
 
 	require.NoError(t, err)
 	require.NotNil(t, response)
@@ -143,6 +493,33 @@ func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine_ValidationError(t *
 	validationService.AssertExpectations(t)
 }
 
+func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine_FieldErrors(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	fields := []services.FieldError{
+		{Field: "description", Rule: "string_lte", Message: "String length must be less than or equal to 1000", Value: "..."},
+		{Field: "notes.0.body", Rule: "string_gte", Message: "String length must be greater than or equal to 1", Value: ""},
+	}
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, mock.Anything).Return(([]*models.CustomFieldDefinition)(nil), nil)
+	validationService.On("ValidateCreateInput", mock.Anything, mock.Anything).Return(&services.ValidationError{Fields: fields})
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(models.AppSyncEvent{
+		Info: models.AppSyncInfo{FieldName: "createLaborLine"},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{"accountId": uuid.New().String(), "taskId": uuid.New().String()},
+		},
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ValidationError", response.Error.Type)
+	assert.Equal(t, fields, response.Error.ErrorInfo["fields"])
+
+	dynamoDBService.AssertNotCalled(t, "CreateLaborLine", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestLaborLineHandler_HandleAppSyncEvent_GetLaborLine(t *testing.T) {
 	dynamoDBService := &MockDynamoDBService{}
 	validationService := &MockValidationService{}
@@ -177,7 +554,7 @@ func TestLaborLineHandler_HandleAppSyncEvent_GetLaborLine(t *testing.T) {
 		LaborLineID: laborLineID,
 	}).Return(expectedLaborLine, nil)
 
-	response, err := handler.HandleAppSyncEvent(context.Background(), event)
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
 
 	require.NoError(t, err)
 	require.NotNil(t, response)
@@ -207,7 +584,7 @@ func TestLaborLineHandler_HandleAppSyncEvent_GetLaborLine_NotFound(t *testing.T)
 
 	dynamoDBService.On("GetLaborLine", mock.Anything, mock.Anything).Return((*models.LaborLine)(nil), nil)
 
-	response, err := handler.HandleAppSyncEvent(context.Background(), event)
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
 
 	require.NoError(t, err)
 	require.NotNil(t, response)
@@ -248,27 +625,134 @@ func TestLaborLineHandler_HandleAppSyncEvent_UpdateLaborLine(t *testing.T) {
 		},
 	}
 
-	validationService.On("ValidateUpdateInput", mock.Anything).Return(nil)
-	dynamoDBService.On("UpdateLaborLine", mock.Anything, mock.Anything).Return(nil)
-	dynamoDBService.On("GetLaborLine", mock.Anything, mock.Anything).Return(updatedLaborLine, nil)
+	validationService.On("ValidateUpdateInput", mock.Anything, mock.Anything).Return(nil)
+	dynamoDBService.On("UpdateLaborLine", mock.Anything, mock.MatchedBy(func(update services.LaborLineUpdate) bool {
+		return update.Input.LaborLineID == input.LaborLineID && update.Input.ExpectedVersion == nil && update.RateCard == nil
+	}), mock.Anything).Return(updatedLaborLine, nil)
 
-	response, err := handler.HandleAppSyncEvent(context.Background(), event)
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
 
 	require.NoError(t, err)
 	require.NotNil(t, response)
 	assert.Nil(t, response.Error)
 	assert.Equal(t, updatedLaborLine, response.Data)
 
+	// The update returns the labor line as written, and neither custom fields
+	// nor the rate card are needed when the input leaves them out
+	dynamoDBService.AssertNotCalled(t, "GetLaborLine", mock.Anything, mock.Anything)
+	dynamoDBService.AssertNotCalled(t, "ListCustomFieldDefinitions", mock.Anything, mock.Anything)
+	dynamoDBService.AssertNotCalled(t, "GetRateCard", mock.Anything, mock.Anything)
+	dynamoDBService.AssertExpectations(t)
+	validationService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_UpdateLaborLine_Conflict(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "updateLaborLine",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"laborLineId":     uuid.New().String(),
+				"accountId":       uuid.New().String(),
+				"taskId":          uuid.New().String(),
+				"expectedVersion": 3,
+			},
+		},
+	}
+
+	validationService.On("ValidateUpdateInput", mock.Anything, mock.Anything).Return(nil)
+	dynamoDBService.On("UpdateLaborLine", mock.Anything, mock.MatchedBy(func(update services.LaborLineUpdate) bool {
+		return update.Input.ExpectedVersion != nil && *update.Input.ExpectedVersion == 3
+	}), mock.Anything).Return((*models.LaborLine)(nil), &services.ConflictError{CurrentVersion: 4})
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ConflictError", response.Error.Type)
+	assert.Equal(t, int64(4), response.Error.ErrorInfo["currentVersion"])
+
 	dynamoDBService.AssertExpectations(t)
 	validationService.AssertExpectations(t)
 }
 
+func TestLaborLineHandler_HandleAppSyncEvent_UpdateLaborLine_NotFound(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "updateLaborLine",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"laborLineId": uuid.New().String(),
+				"accountId":   uuid.New().String(),
+				"taskId":      uuid.New().String(),
+			},
+		},
+	}
+
+	validationService.On("ValidateUpdateInput", mock.Anything, mock.Anything).Return(nil)
+	dynamoDBService.On("UpdateLaborLine", mock.Anything, mock.Anything, mock.Anything).Return((*models.LaborLine)(nil), services.ErrLaborLineNotFound)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "NotFound", response.Error.Type)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_UpdateLaborLine_ReadOnly(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "updateLaborLine",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"laborLineId": uuid.New().String(),
+				"accountId":   uuid.New().String(),
+				"taskId":      uuid.New().String(),
+			},
+		},
+	}
+
+	validationService.On("ValidateUpdateInput", mock.Anything, mock.Anything).Return(nil)
+	dynamoDBService.On("UpdateLaborLine", mock.Anything, mock.Anything, mock.Anything).Return((*models.LaborLine)(nil), services.ErrLaborLineReadOnly)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ReadOnly", response.Error.Type)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
 func TestLaborLineHandler_HandleAppSyncEvent_DeleteLaborLine(t *testing.T) {
 	dynamoDBService := &MockDynamoDBService{}
 	validationService := &MockValidationService{}
 	handler := NewLaborLineHandler(dynamoDBService, validationService)
 
 	event := models.AppSyncEvent{
+		Identity: map[string]interface{}{
+			"sub": "user-123",
+		},
 		Info: models.AppSyncInfo{
 			FieldName: "deleteLaborLine",
 		},
@@ -281,9 +765,9 @@ func TestLaborLineHandler_HandleAppSyncEvent_DeleteLaborLine(t *testing.T) {
 		},
 	}
 
-	dynamoDBService.On("DeleteLaborLine", mock.Anything, mock.Anything).Return(nil)
+	dynamoDBService.On("DeleteLaborLine", mock.Anything, mock.Anything, "user-123").Return(nil)
 
-	response, err := handler.HandleAppSyncEvent(context.Background(), event)
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
 
 	require.NoError(t, err)
 	require.NotNil(t, response)
@@ -324,22 +808,126 @@ func TestLaborLineHandler_HandleAppSyncEvent_ListLaborLines(t *testing.T) {
 		Arguments: map[string]interface{}{
 			"input": map[string]interface{}{
 				"accountId": accountID,
+				"limit":     2,
+			},
+		},
+	}
+
+	nextToken := "next-page-token"
+	expectedConnection := &models.LaborLineConnection{
+		Items:     expectedLaborLines,
+		NextToken: &nextToken,
+	}
+
+	dynamoDBService.On("ListLaborLines", mock.Anything, models.ListLaborLinesInput{
+		AccountID: accountID,
+		Limit:     2,
+	}).Return(expectedConnection, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+	assert.Equal(t, expectedConnection, response.Data)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_ListLaborLines_InvalidNextToken(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "listLaborLines",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"accountId": uuid.New().String(),
+				"nextToken": "forged-token",
 			},
 		},
 	}
 
-	dynamoDBService.On("ListLaborLines", mock.Anything, mock.Anything).Return(expectedLaborLines, nil)
+	dynamoDBService.On("ListLaborLines", mock.Anything, mock.Anything).
+		Return((*models.LaborLineConnection)(nil), fmt.Errorf("querying labor lines from DynamoDB: %w", services.ErrInvalidNextToken))
 
-	response, err := handler.HandleAppSyncEvent(context.Background(), event)
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ValidationError", response.Error.Type)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_ListLaborLinesByTask(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	taskID := uuid.New().String()
+	expectedConnection := &models.LaborLineConnection{
+		Items: []*models.LaborLine{
+			{LaborLineID: uuid.New().String(), AccountID: "acct-1", TaskID: taskID},
+			{LaborLineID: uuid.New().String(), AccountID: "acct-2", TaskID: taskID},
+		},
+	}
+
+	// The caller's accounts come from the identity, not the input
+	dynamoDBService.On("ListLaborLinesByTask", mock.Anything, models.ListLaborLinesByTaskInput{
+		TaskID: taskID,
+		Limit:  10,
+	}, []string{"acct-1", "acct-2"}).Return(expectedConnection, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "listLaborLinesByTask",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"taskId": taskID,
+				"limit":  10,
+			},
+		},
+		Identity: map[string]interface{}{
+			"sub":    "service-1",
+			"groups": []interface{}{"technicians"},
+			"claims": map[string]interface{}{"custom:accountIds": "acct-1,acct-2"},
+		},
+	})
 
 	require.NoError(t, err)
 	require.NotNil(t, response)
 	assert.Nil(t, response.Error)
-	assert.Equal(t, expectedLaborLines, response.Data)
+	assert.Equal(t, expectedConnection, response.Data)
 
 	dynamoDBService.AssertExpectations(t)
 }
 
+func TestLaborLineHandler_HandleAppSyncEvent_ListLaborLinesByTask_MissingTaskID(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "listLaborLinesByTask",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{},
+		},
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ValidationError", response.Error.Type)
+	dynamoDBService.AssertNotCalled(t, "ListLaborLinesByTask", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestLaborLineHandler_HandleAppSyncEvent_UnsupportedOperation(t *testing.T) {
 	dynamoDBService := &MockDynamoDBService{}
 	validationService := &MockValidationService{}
@@ -352,7 +940,7 @@ func TestLaborLineHandler_HandleAppSyncEvent_UnsupportedOperation(t *testing.T)
 		Arguments: map[string]interface{}{},
 	}
 
-	response, err := handler.HandleAppSyncEvent(context.Background(), event)
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
 
 	require.NoError(t, err)
 	require.NotNil(t, response)
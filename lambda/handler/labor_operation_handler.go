@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// handleCreateLaborOperation processes requests to add an operation to an
+// account's labor operation catalog.
+func (h *LaborLineHandler) handleCreateLaborOperation(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.CreateLaborOperationInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	// Validate input
+	if err := h.validationService.ValidateCreateLaborOperationInput(input); err != nil {
+		return validationErrorResponse(err), nil
+	}
+
+	operation := input.ToLaborOperation()
+	if err := h.dynamoDBService.CreateLaborOperation(ctx, operation); err != nil {
+		return writeErrorResponse(err, "failed to create labor operation"), nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: operation,
+	}, nil
+}
+
+// handleGetLaborOperation processes requests for one of an account's labor operations.
+func (h *LaborLineHandler) handleGetLaborOperation(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.GetLaborOperationInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	operation, err := h.dynamoDBService.GetLaborOperation(ctx, input.AccountID, input.Code)
+	if err != nil {
+		return writeErrorResponse(err, "failed to get labor operation"), nil
+	}
+	if operation == nil {
+		return writeErrorResponse(services.ErrLaborOperationNotFound, "failed to get labor operation"), nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: operation,
+	}, nil
+}
+
+// handleUpdateLaborOperation processes requests to change one of an account's labor operations.
+func (h *LaborLineHandler) handleUpdateLaborOperation(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.UpdateLaborOperationInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	// Validate the fields being changed
+	if err := h.validationService.ValidateUpdateLaborOperationInput(input); err != nil {
+		return validationErrorResponse(err), nil
+	}
+
+	operation, err := h.dynamoDBService.UpdateLaborOperation(ctx, input)
+	if err != nil {
+		return writeErrorResponse(err, "failed to update labor operation"), nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: operation,
+	}, nil
+}
+
+// handleDeleteLaborOperation processes requests to remove an operation from an
+// account's labor operation catalog.
+func (h *LaborLineHandler) handleDeleteLaborOperation(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.DeleteLaborOperationInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	if err := h.dynamoDBService.DeleteLaborOperation(ctx, input); err != nil {
+		return writeErrorResponse(err, "failed to delete labor operation"), nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: map[string]interface{}{
+			"success": true,
+			"message": "labor operation deleted successfully",
+		},
+	}, nil
+}
+
+// handleSearchLaborOperations processes requests for a page of an account's
+// labor operations matching a code prefix or keyword.
+func (h *LaborLineHandler) handleSearchLaborOperations(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.SearchLaborOperationsInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	connection, err := h.dynamoDBService.SearchLaborOperations(ctx, input)
+	if errors.Is(err, services.ErrInvalidNextToken) {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "invalid nextToken",
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+	if err != nil {
+		log.Printf("Error searching labor operations: %v", err)
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "failed to search labor operations",
+				Type:    "InternalError",
+			},
+		}, nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: connection,
+	}, nil
+}
+
+// applyLaborOperation fills in what a create input leaves out from the labor
+// operation it references. It returns the error to report if the operation is
+// not in the account's catalog or is inactive.
+func (h *LaborLineHandler) applyLaborOperation(ctx context.Context, input models.CreateLaborLineInput, message string) (models.CreateLaborLineInput, *models.AppSyncError) {
+	operation, err := h.dynamoDBService.GetLaborOperation(ctx, input.AccountID, input.OperationCode)
+	if err != nil {
+		log.Printf("Error getting labor operation: %v", err)
+		return input, &models.AppSyncError{
+			Message: message,
+			Type:    "InternalError",
+		}
+	}
+
+	var fieldErr *services.FieldError
+	switch {
+	case operation == nil:
+		fieldErr = &services.FieldError{Field: "operationCode", Rule: "exists", Message: "labor operation not found", Value: input.OperationCode}
+	case !operation.Active:
+		fieldErr = &services.FieldError{Field: "operationCode", Rule: "active", Message: "labor operation is inactive", Value: input.OperationCode}
+	}
+	if fieldErr != nil {
+		return input, validationErrorResponse(&services.ValidationError{Fields: []services.FieldError{*fieldErr}}).Error
+	}
+
+	return input.WithOperation(operation), nil
+}
@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// brakeJob is a labor operation that sets a flat rate.
+func brakeJob(accountID string) *models.LaborOperation {
+	ratePerHour := models.MustParseDecimal("120")
+	return &models.LaborOperation{
+		AccountID:   accountID,
+		Code:        "013-001-001",
+		Description: "Replace front brake pads",
+		BookHours:   models.MustParseDecimal("1.5"),
+		RateType:    models.RateTypeFlatRate,
+		RatePerHour: &ratePerHour,
+		Active:      true,
+	}
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine_FromOperation(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, mock.Anything).Return(([]*models.CustomFieldDefinition)(nil), nil)
+	validationService.On("ValidateCreateInput", mock.Anything, mock.Anything).Return(nil)
+	dynamoDBService.On("GetLaborOperation", mock.Anything, accountID, "013-001-001").Return(brakeJob(accountID), nil)
+	dynamoDBService.On("CreateLaborLine", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	// The description given overrides the operation's; the rest is pre-filled
+	response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("createLaborLine", map[string]interface{}{
+		"accountId":     accountID,
+		"taskId":        uuid.New().String(),
+		"operationCode": "013-001-001",
+		"description":   "Replace front brake pads and shims",
+	}, "service-advisors"))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+
+	laborLine, ok := response.Data.(*models.LaborLine)
+	require.True(t, ok)
+	assert.Equal(t, "013-001-001", laborLine.OperationCode)
+	assert.Equal(t, "Replace front brake pads and shims", laborLine.Description)
+	assert.Equal(t, "1.50", laborLine.EstimatedHours.StringFixed(2))
+	assert.Equal(t, models.RateTypeFlatRate, laborLine.RateType)
+	assert.Equal(t, "180.00", laborLine.LaborCost.StringFixed(2))
+
+	// The operation sets the rate, so the rate card is not needed
+	dynamoDBService.AssertNotCalled(t, "GetRateCard", mock.Anything, mock.Anything)
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine_UnusableOperation(t *testing.T) {
+	inactive := brakeJob("")
+	inactive.Active = false
+
+	tests := []struct {
+		name         string
+		operation    *models.LaborOperation
+		expectedRule string
+	}{
+		{"unknown operation", nil, "exists"},
+		{"inactive operation", inactive, "active"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamoDBService := &MockDynamoDBService{}
+			validationService := &MockValidationService{}
+			handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+			dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, mock.Anything).Return(([]*models.CustomFieldDefinition)(nil), nil)
+			validationService.On("ValidateCreateInput", mock.Anything, mock.Anything).Return(nil)
+			dynamoDBService.On("GetLaborOperation", mock.Anything, mock.Anything, "013-001-001").Return(tt.operation, nil)
+
+			response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("createLaborLine", map[string]interface{}{
+				"accountId":     uuid.New().String(),
+				"taskId":        uuid.New().String(),
+				"operationCode": "013-001-001",
+			}, adminGroup))
+
+			require.NoError(t, err)
+			require.NotNil(t, response.Error)
+			assert.Equal(t, "ValidationError", response.Error.Type)
+			fields := response.Error.ErrorInfo["fields"].([]services.FieldError)
+			require.Len(t, fields, 1)
+			assert.Equal(t, "operationCode", fields[0].Field)
+			assert.Equal(t, tt.expectedRule, fields[0].Rule)
+
+			dynamoDBService.AssertNotCalled(t, "CreateLaborLine", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborOperation(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	validationService.On("ValidateCreateLaborOperationInput", mock.Anything).Return(nil)
+	dynamoDBService.On("CreateLaborOperation", mock.Anything, mock.MatchedBy(func(operation *models.LaborOperation) bool {
+		return operation.AccountID == accountID && operation.Code == "013-001-001" && operation.Active
+	})).Return(nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("createLaborOperation", map[string]interface{}{
+		"accountId":   accountID,
+		"code":        "013-001-001",
+		"description": "Replace front brake pads",
+		"bookHours":   1.5,
+	}, "service-advisors"))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+
+	dynamoDBService.AssertExpectations(t)
+	validationService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborOperation_Exists(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	validationService.On("ValidateCreateLaborOperationInput", mock.Anything).Return(nil)
+	dynamoDBService.On("CreateLaborOperation", mock.Anything, mock.Anything).Return(services.ErrLaborOperationExists)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("createLaborOperation", map[string]interface{}{
+		"accountId":   uuid.New().String(),
+		"code":        "013-001-001",
+		"description": "Replace front brake pads",
+		"bookHours":   1.5,
+	}, adminGroup))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ValidationError", response.Error.Type)
+	assert.Equal(t, "labor operation already exists", response.Error.Message)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_GetLaborOperation_NotFound(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	handler := NewLaborLineHandler(dynamoDBService, &MockValidationService{})
+
+	dynamoDBService.On("GetLaborOperation", mock.Anything, mock.Anything, "013-001-001").Return((*models.LaborOperation)(nil), nil)
+
+	// Technicians may look up operations
+	response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("getLaborOperation", map[string]interface{}{
+		"accountId": uuid.New().String(),
+		"code":      "013-001-001",
+	}, "technicians"))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "NotFound", response.Error.Type)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_DeleteLaborOperation_AdvisorDenied(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	handler := NewLaborLineHandler(dynamoDBService, &MockValidationService{})
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("deleteLaborOperation", map[string]interface{}{
+		"accountId": uuid.New().String(),
+		"code":      "013-001-001",
+	}, "service-advisors"))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "Unauthorized", response.Error.Type)
+
+	dynamoDBService.AssertNotCalled(t, "DeleteLaborOperation", mock.Anything, mock.Anything)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_SearchLaborOperations(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	handler := NewLaborLineHandler(dynamoDBService, &MockValidationService{})
+
+	accountID := uuid.New().String()
+	connection := &models.LaborOperationConnection{Items: []*models.LaborOperation{brakeJob(accountID)}}
+	dynamoDBService.On("SearchLaborOperations", mock.Anything, models.SearchLaborOperationsInput{
+		AccountID:  accountID,
+		CodePrefix: "013",
+		Keyword:    "brake",
+		Limit:      20,
+	}).Return(connection, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("searchLaborOperations", map[string]interface{}{
+		"accountId":  accountID,
+		"codePrefix": "013",
+		"keyword":    "brake",
+		"limit":      20,
+	}, "technicians"))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+	assert.Equal(t, connection, response.Data)
+
+	dynamoDBService.AssertExpectations(t)
+}
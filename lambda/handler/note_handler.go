@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// handleAddNote processes requests to add a note to a labor line. The caller
+// is recorded as the note's author.
+func (h *LaborLineHandler) handleAddNote(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.AddLaborLineNoteInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	// Validate input
+	if err := h.validationService.ValidateAddNoteInput(input); err != nil {
+		return validationErrorResponse(err), nil
+	}
+
+	laborLine, err := h.dynamoDBService.AddLaborLineNote(ctx, input, event.Actor())
+	if err != nil {
+		return writeErrorResponse(err, "failed to add labor line note"), nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: laborLine,
+	}, nil
+}
+
+// handleEditNote processes requests to change the body or visibility of a labor line note.
+func (h *LaborLineHandler) handleEditNote(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.EditLaborLineNoteInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	// Validate input
+	if err := h.validationService.ValidateEditNoteInput(input); err != nil {
+		return validationErrorResponse(err), nil
+	}
+
+	laborLine, err := h.dynamoDBService.EditLaborLineNote(ctx, input, event.Actor())
+	if err != nil {
+		return writeErrorResponse(err, "failed to edit labor line note"), nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: laborLine,
+	}, nil
+}
+
+// handleDeleteNote processes requests to remove a note from a labor line.
+func (h *LaborLineHandler) handleDeleteNote(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.DeleteLaborLineNoteInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	// Validate input
+	if err := h.validationService.ValidateDeleteNoteInput(input); err != nil {
+		return validationErrorResponse(err), nil
+	}
+
+	laborLine, err := h.dynamoDBService.DeleteLaborLineNote(ctx, input, event.Actor())
+	if err != nil {
+		return writeErrorResponse(err, "failed to delete labor line note"), nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: laborLine,
+	}, nil
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// noteEvent builds a note operation event with the given input fields added to a labor line key.
+func noteEvent(fieldName string, fields map[string]interface{}, groups ...string) models.AppSyncEvent {
+	input := map[string]interface{}{
+		"laborLineId": uuid.New().String(),
+		"accountId":   uuid.New().String(),
+		"taskId":      uuid.New().String(),
+	}
+	for k, v := range fields {
+		input[k] = v
+	}
+	if len(groups) == 0 {
+		groups = []string{adminGroup}
+	}
+	return roleEvent(fieldName, input, groups...)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_AddLaborLineNote(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	updated := &models.LaborLine{Notes: []models.Note{models.NewNote("user-123", models.NoteVisibilityCustomer, "Replace worn brake pads")}}
+	validationService.On("ValidateAddNoteInput", mock.Anything).Return(nil)
+	dynamoDBService.On("AddLaborLineNote", mock.Anything, mock.MatchedBy(func(input models.AddLaborLineNoteInput) bool {
+		return input.Body == "Replace worn brake pads" && input.Visibility == models.NoteVisibilityCustomer
+	}), "user-123").Return(updated, nil)
+
+	// Technicians may add notes to the labor lines they are assigned to
+	dynamoDBService.On("BatchGetLaborLines", mock.Anything, mock.Anything).
+		Return([]*models.LaborLine{{CreatedBy: "advisor-1", AssignedTechnicianIDs: []string{"user-123"}}}, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), noteEvent("addLaborLineNote", map[string]interface{}{
+		"visibility": "CUSTOMER",
+		"body":       "Replace worn brake pads",
+	}, "technicians"))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+	assert.Equal(t, updated, response.Data)
+
+	dynamoDBService.AssertExpectations(t)
+	validationService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_EditLaborLineNote_NotFound(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	validationService.On("ValidateEditNoteInput", mock.Anything).Return(nil)
+	dynamoDBService.On("EditLaborLineNote", mock.Anything, mock.Anything, "user-123").Return((*models.LaborLine)(nil), services.ErrNoteNotFound)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), noteEvent("editLaborLineNote", map[string]interface{}{
+		"noteId": uuid.New().String(),
+		"body":   "Replace pads and rotors",
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "NotFound", response.Error.Type)
+	assert.Equal(t, "note not found", response.Error.Message)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_DeleteLaborLineNote(t *testing.T) {
+	tests := []struct {
+		name    string
+		groups  []string
+		allowed bool
+	}{
+		{name: "Advisor", groups: []string{"service-advisors"}, allowed: true},
+		{name: "Technician", groups: []string{"technicians"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamoDBService := &MockDynamoDBService{}
+			validationService := &MockValidationService{}
+			handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+			noteID := uuid.New().String()
+			if tt.allowed {
+				validationService.On("ValidateDeleteNoteInput", mock.Anything).Return(nil)
+				dynamoDBService.On("DeleteLaborLineNote", mock.Anything, mock.MatchedBy(func(input models.DeleteLaborLineNoteInput) bool {
+					return input.NoteID == noteID
+				}), "user-123").Return(&models.LaborLine{}, nil)
+			}
+
+			response, err := handler.HandleAppSyncEvent(context.Background(), noteEvent("deleteLaborLineNote", map[string]interface{}{
+				"noteId": noteID,
+			}, tt.groups...))
+
+			require.NoError(t, err)
+			if tt.allowed {
+				assert.Nil(t, response.Error)
+			} else {
+				require.NotNil(t, response.Error)
+				assert.Equal(t, "Unauthorized", response.Error.Type)
+			}
+			dynamoDBService.AssertExpectations(t)
+			validationService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// WithPartsCatalog sets the parts inventory that the parts of labor lines are
+// checked against and reserved in. Without it parts are recorded unchecked.
+func WithPartsCatalog(catalog services.PartsCatalog) LaborLineHandlerOption {
+	return func(h *LaborLineHandler) {
+		h.partsCatalog = catalog
+	}
+}
+
+// reserveParts checks that a labor line's parts are in the parts catalog and
+// reserves their stock, replacing what was reserved for the labor line before.
+// It returns the error to report if a part is unknown or short.
+func (h *LaborLineHandler) reserveParts(ctx context.Context, accountID, laborLineID string, parts []models.PartLineItem, message string) *models.AppSyncError {
+	if h.partsCatalog == nil {
+		return nil
+	}
+
+	var fields []services.FieldError
+	for i, part := range parts {
+		_, err := h.partsCatalog.GetPart(ctx, accountID, part.PartID)
+		if errors.Is(err, services.ErrPartNotFound) {
+			fields = append(fields, services.FieldError{Field: fmt.Sprintf("parts.%d.partId", i), Rule: "exists", Message: "part not found in the parts catalog", Value: part.PartID})
+			continue
+		}
+		if err != nil {
+			log.Printf("Error getting part: %v", err)
+			return &models.AppSyncError{
+				Message: message,
+				Type:    "InternalError",
+			}
+		}
+	}
+	if len(fields) > 0 {
+		return validationErrorResponse(&services.ValidationError{Fields: fields}).Error
+	}
+
+	if err := h.partsCatalog.ReserveParts(ctx, accountID, laborLineID, parts); err != nil {
+		return writeErrorResponse(err, message).Error
+	}
+	return nil
+}
+
+// reserveUpdatedParts checks that the labor line an update names exists and
+// accepts the update, then reserves the update's parts in place of the labor
+// line's previous reservation. It returns the labor line as it was read, for
+// restoreParts to put its reservation back if the update is not written.
+func (h *LaborLineHandler) reserveUpdatedParts(ctx context.Context, input models.UpdateLaborLineInput, message string) (*models.LaborLine, *models.AppSyncError) {
+	if h.partsCatalog == nil {
+		return nil, nil
+	}
+
+	existing, err := h.dynamoDBService.GetLaborLine(ctx, models.GetLaborLineInput{
+		AccountID:   input.AccountID,
+		TaskID:      input.TaskID,
+		LaborLineID: input.LaborLineID,
+	})
+	switch {
+	case err != nil:
+	case existing == nil:
+		err = services.ErrLaborLineNotFound
+	case input.ExpectedVersion != nil && *input.ExpectedVersion != existing.Version:
+		err = &services.ConflictError{CurrentVersion: existing.Version}
+	case existing.IsReadOnly():
+		err = services.ErrLaborLineReadOnly
+	}
+	if err != nil {
+		return nil, writeErrorResponse(err, message).Error
+	}
+
+	if appErr := h.reserveParts(ctx, input.AccountID, input.LaborLineID, input.Parts.Value, message); appErr != nil {
+		return nil, appErr
+	}
+	return existing, nil
+}
+
+// restoreParts puts back the reservation a labor line held before an update
+// that was not written replaced it, or reserves the parts of a labor line that
+// has been restored. Failures are logged, as the labor line itself is unaffected.
+func (h *LaborLineHandler) restoreParts(ctx context.Context, laborLine *models.LaborLine) {
+	if h.partsCatalog == nil || laborLine == nil {
+		return
+	}
+	if len(laborLine.Parts) == 0 {
+		h.releaseParts(ctx, laborLine.AccountID, laborLine.LaborLineID)
+		return
+	}
+	if err := h.partsCatalog.ReserveParts(ctx, laborLine.AccountID, laborLine.LaborLineID, laborLine.Parts); err != nil {
+		log.Printf("Error reserving parts of labor line %s: %v", laborLine.LaborLineID, err)
+	}
+}
+
+// releaseParts releases the stock reserved for a labor line that was not
+// written or has been deleted. Failures are logged, as the labor line itself
+// is unaffected.
+func (h *LaborLineHandler) releaseParts(ctx context.Context, accountID, laborLineID string) {
+	if h.partsCatalog == nil {
+		return
+	}
+	if err := h.partsCatalog.ReleaseParts(ctx, accountID, laborLineID); err != nil {
+		log.Printf("Error releasing parts of labor line %s: %v", laborLineID, err)
+	}
+}
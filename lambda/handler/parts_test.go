@@ -0,0 +1,343 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// partsCatalog returns an in-memory catalog holding quantity of one part for the account.
+func partsCatalog(accountID, partID string, quantity int64) *services.MemoryPartsCatalog {
+	return services.NewMemoryPartsCatalog(models.CatalogPart{
+		AccountID:      accountID,
+		PartID:         partID,
+		UnitOfMeasure:  "EA",
+		QuantityOnHand: models.NewDecimalFromInt(quantity),
+	})
+}
+
+// createWithPartsEvent builds a create event for a labor line using quantity of a part.
+func createWithPartsEvent(accountID, partID string, quantity int64) models.AppSyncEvent {
+	return roleEvent("createLaborLine", map[string]interface{}{
+		"accountId":      accountID,
+		"taskId":         uuid.New().String(),
+		"rateType":       "HOURLY",
+		"ratePerHour":    100,
+		"estimatedHours": 1,
+		"parts": []interface{}{
+			map[string]interface{}{"partId": partID, "quantity": quantity},
+		},
+	}, adminGroup)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine_ReservesParts(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	accountID := uuid.New().String()
+	partID := uuid.New().String()
+	catalog := partsCatalog(accountID, partID, 5)
+	handler := NewLaborLineHandler(dynamoDBService, validationService, WithPartsCatalog(catalog))
+
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, accountID).Return(([]*models.CustomFieldDefinition)(nil), nil)
+	validationService.On("ValidateCreateInput", mock.Anything, mock.Anything).Return(nil)
+	dynamoDBService.On("CreateLaborLine", mock.Anything, mock.Anything, "user-123").Return(nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), createWithPartsEvent(accountID, partID, 3))
+
+	require.NoError(t, err)
+	require.Nil(t, response.Error)
+	laborLine := response.Data.(*models.LaborLine)
+	require.Len(t, laborLine.Parts, 1)
+	assert.Equal(t, models.PartStatusRequested, laborLine.Parts[0].Status)
+	assert.True(t, models.NewDecimalFromInt(3).Equal(catalog.Reserved(accountID, partID)))
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine_UnknownPart(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	accountID := uuid.New().String()
+	handler := NewLaborLineHandler(dynamoDBService, validationService, WithPartsCatalog(partsCatalog(accountID, uuid.New().String(), 5)))
+
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, accountID).Return(([]*models.CustomFieldDefinition)(nil), nil)
+	validationService.On("ValidateCreateInput", mock.Anything, mock.Anything).Return(nil)
+
+	unknownPartID := uuid.New().String()
+	response, err := handler.HandleAppSyncEvent(context.Background(), createWithPartsEvent(accountID, unknownPartID, 1))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ValidationError", response.Error.Type)
+	assert.Equal(t, []services.FieldError{{Field: "parts.0.partId", Rule: "exists", Message: "part not found in the parts catalog", Value: unknownPartID}}, response.Error.ErrorInfo["fields"])
+	dynamoDBService.AssertNotCalled(t, "CreateLaborLine", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine_InsufficientStock(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	accountID := uuid.New().String()
+	partID := uuid.New().String()
+	handler := NewLaborLineHandler(dynamoDBService, validationService, WithPartsCatalog(partsCatalog(accountID, partID, 2)))
+
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, accountID).Return(([]*models.CustomFieldDefinition)(nil), nil)
+	validationService.On("ValidateCreateInput", mock.Anything, mock.Anything).Return(nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), createWithPartsEvent(accountID, partID, 3))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "InsufficientStock", response.Error.Type)
+	assert.Equal(t, partID, response.Error.ErrorInfo["partId"])
+	assert.True(t, models.NewDecimalFromInt(2).Equal(response.Error.ErrorInfo["available"].(models.Decimal)))
+	dynamoDBService.AssertNotCalled(t, "CreateLaborLine", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_CreateLaborLine_ReleasesPartsOnFailure(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	accountID := uuid.New().String()
+	partID := uuid.New().String()
+	catalog := partsCatalog(accountID, partID, 5)
+	handler := NewLaborLineHandler(dynamoDBService, validationService, WithPartsCatalog(catalog))
+
+	dynamoDBService.On("ListCustomFieldDefinitions", mock.Anything, accountID).Return(([]*models.CustomFieldDefinition)(nil), nil)
+	validationService.On("ValidateCreateInput", mock.Anything, mock.Anything).Return(nil)
+	dynamoDBService.On("CreateLaborLine", mock.Anything, mock.Anything, "user-123").Return(errors.New("throttled"))
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), createWithPartsEvent(accountID, partID, 3))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "InternalError", response.Error.Type)
+	assert.True(t, catalog.Reserved(accountID, partID).IsZero())
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_UpdateLaborLine_ReservesParts(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	accountID := uuid.New().String()
+	partID := uuid.New().String()
+	laborLineID := uuid.New().String()
+	catalog := partsCatalog(accountID, partID, 5)
+	handler := NewLaborLineHandler(dynamoDBService, validationService, WithPartsCatalog(catalog))
+	require.NoError(t, catalog.ReserveParts(context.Background(), accountID, laborLineID, []models.PartLineItem{{PartID: partID, Quantity: models.NewDecimalFromInt(4)}}))
+
+	updated := &models.LaborLine{LaborLineID: laborLineID, AccountID: accountID}
+	validationService.On("ValidateUpdateInput", mock.Anything, mock.Anything).Return(nil)
+	dynamoDBService.On("GetLaborLine", mock.Anything, mock.Anything).Return(&models.LaborLine{LaborLineID: laborLineID, AccountID: accountID}, nil)
+	dynamoDBService.On("UpdateLaborLine", mock.Anything, mock.Anything, "user-123").Return(updated, nil)
+
+	// The labor line's own reservation does not count against the new quantity
+	response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("updateLaborLine", map[string]interface{}{
+		"laborLineId": laborLineID,
+		"accountId":   accountID,
+		"taskId":      uuid.New().String(),
+		"parts": []interface{}{
+			map[string]interface{}{"partId": partID, "quantity": 5, "status": "ISSUED"},
+		},
+	}, adminGroup))
+
+	require.NoError(t, err)
+	require.Nil(t, response.Error)
+	assert.True(t, models.NewDecimalFromInt(5).Equal(catalog.Reserved(accountID, partID)))
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+// updateWithPartsEvent builds an update event setting a labor line's parts to quantity of a part.
+func updateWithPartsEvent(accountID, laborLineID, partID string, quantity int64) models.AppSyncEvent {
+	return roleEvent("updateLaborLine", map[string]interface{}{
+		"laborLineId": laborLineID,
+		"accountId":   accountID,
+		"taskId":      uuid.New().String(),
+		"parts": []interface{}{
+			map[string]interface{}{"partId": partID, "quantity": quantity},
+		},
+	}, adminGroup)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_UpdateLaborLine_NotFoundReservesNothing(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	accountID := uuid.New().String()
+	partID := uuid.New().String()
+	catalog := partsCatalog(accountID, partID, 5)
+	handler := NewLaborLineHandler(dynamoDBService, validationService, WithPartsCatalog(catalog))
+
+	validationService.On("ValidateUpdateInput", mock.Anything, mock.Anything).Return(nil)
+	dynamoDBService.On("GetLaborLine", mock.Anything, mock.Anything).Return((*models.LaborLine)(nil), nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), updateWithPartsEvent(accountID, uuid.New().String(), partID, 3))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "NotFound", response.Error.Type)
+	assert.True(t, catalog.Reserved(accountID, partID).IsZero())
+	dynamoDBService.AssertNotCalled(t, "UpdateLaborLine", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_UpdateLaborLine_ReadOnlyReservesNothing(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	accountID := uuid.New().String()
+	partID := uuid.New().String()
+	laborLineID := uuid.New().String()
+	catalog := partsCatalog(accountID, partID, 5)
+	handler := NewLaborLineHandler(dynamoDBService, validationService, WithPartsCatalog(catalog))
+
+	validationService.On("ValidateUpdateInput", mock.Anything, mock.Anything).Return(nil)
+	dynamoDBService.On("GetLaborLine", mock.Anything, mock.Anything).
+		Return(&models.LaborLine{LaborLineID: laborLineID, AccountID: accountID, Status: models.StatusCompleted}, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), updateWithPartsEvent(accountID, laborLineID, partID, 3))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.True(t, catalog.Reserved(accountID, partID).IsZero())
+	dynamoDBService.AssertNotCalled(t, "UpdateLaborLine", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_UpdateLaborLine_RestoresPartsOnFailure(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	accountID := uuid.New().String()
+	partID := uuid.New().String()
+	laborLineID := uuid.New().String()
+	catalog := partsCatalog(accountID, partID, 5)
+	handler := NewLaborLineHandler(dynamoDBService, validationService, WithPartsCatalog(catalog))
+
+	parts := []models.PartLineItem{{PartID: partID, Quantity: models.NewDecimalFromInt(2)}}
+	require.NoError(t, catalog.ReserveParts(context.Background(), accountID, laborLineID, parts))
+
+	validationService.On("ValidateUpdateInput", mock.Anything, mock.Anything).Return(nil)
+	dynamoDBService.On("GetLaborLine", mock.Anything, mock.Anything).
+		Return(&models.LaborLine{LaborLineID: laborLineID, AccountID: accountID, Parts: parts, Version: 4}, nil)
+	dynamoDBService.On("UpdateLaborLine", mock.Anything, mock.Anything, "user-123").
+		Return((*models.LaborLine)(nil), &services.ConflictError{CurrentVersion: 5})
+
+	// The labor line changed between the read and the write
+	response, err := handler.HandleAppSyncEvent(context.Background(), updateWithPartsEvent(accountID, laborLineID, partID, 4))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ConflictError", response.Error.Type)
+	assert.True(t, models.NewDecimalFromInt(2).Equal(catalog.Reserved(accountID, partID)))
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_DeleteLaborLine_ReleasesParts(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	accountID := uuid.New().String()
+	partID := uuid.New().String()
+	laborLineID := uuid.New().String()
+	catalog := partsCatalog(accountID, partID, 5)
+	handler := NewLaborLineHandler(dynamoDBService, validationService, WithPartsCatalog(catalog))
+	require.NoError(t, catalog.ReserveParts(context.Background(), accountID, laborLineID, []models.PartLineItem{{PartID: partID, Quantity: models.NewDecimalFromInt(3)}}))
+
+	dynamoDBService.On("DeleteLaborLine", mock.Anything, mock.Anything, "user-123").Return(nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("deleteLaborLine",
+		laborLineKeyInput(accountID, uuid.New().String(), laborLineID), adminGroup))
+
+	require.NoError(t, err)
+	require.Nil(t, response.Error)
+	assert.True(t, catalog.Reserved(accountID, partID).IsZero())
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_RestoreLaborLine_ReservesParts(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	accountID := uuid.New().String()
+	partID := uuid.New().String()
+	laborLineID := uuid.New().String()
+	catalog := partsCatalog(accountID, partID, 5)
+	handler := NewLaborLineHandler(dynamoDBService, validationService, WithPartsCatalog(catalog))
+
+	restored := &models.LaborLine{
+		LaborLineID: laborLineID,
+		AccountID:   accountID,
+		Parts:       []models.PartLineItem{{PartID: partID, Quantity: models.NewDecimalFromInt(3)}},
+	}
+	dynamoDBService.On("RestoreLaborLine", mock.Anything, mock.Anything, "user-123").Return(restored, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("restoreLaborLine",
+		laborLineKeyInput(accountID, uuid.New().String(), laborLineID), adminGroup))
+
+	require.NoError(t, err)
+	require.Nil(t, response.Error)
+	assert.True(t, models.NewDecimalFromInt(3).Equal(catalog.Reserved(accountID, partID)))
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_BatchParts(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	accountID := uuid.New().String()
+	partID := uuid.New().String()
+	catalog := partsCatalog(accountID, partID, 10)
+	handler := NewLaborLineHandler(dynamoDBService, validationService, WithPartsCatalog(catalog))
+
+	parts := []models.PartLineItem{{PartID: partID, Quantity: models.NewDecimalFromInt(1)}}
+	laborLineIDs := []string{uuid.New().String(), uuid.New().String()}
+	for _, laborLineID := range laborLineIDs {
+		require.NoError(t, catalog.ReserveParts(context.Background(), accountID, laborLineID, parts))
+	}
+
+	validationService.On("ValidateUpdateInput", mock.Anything, mock.Anything).Return(nil)
+	for _, laborLineID := range laborLineIDs {
+		dynamoDBService.On("GetLaborLine", mock.Anything, mock.MatchedBy(func(input models.GetLaborLineInput) bool {
+			return input.LaborLineID == laborLineID
+		})).Return(&models.LaborLine{LaborLineID: laborLineID, AccountID: accountID, Parts: parts}, nil)
+	}
+	dynamoDBService.On("BatchUpdateLaborLines", mock.Anything, mock.Anything, models.BatchModeBestEffort, "user-123").
+		Return([]services.BatchWriteResult{
+			{LaborLine: &models.LaborLine{LaborLineID: laborLineIDs[0]}},
+			{Err: &services.ConflictError{CurrentVersion: 2}},
+		})
+
+	// Only the update that was written keeps its new reservation
+	items := make([]interface{}, len(laborLineIDs))
+	for i, laborLineID := range laborLineIDs {
+		items[i] = map[string]interface{}{
+			"laborLineId": laborLineID,
+			"accountId":   accountID,
+			"taskId":      uuid.New().String(),
+			"parts":       []interface{}{map[string]interface{}{"partId": partID, "quantity": 4}},
+		}
+	}
+	response, err := handler.HandleAppSyncEvent(context.Background(), batchEvent("batchUpdateLaborLines", map[string]interface{}{
+		"mode":  "BEST_EFFORT",
+		"items": items,
+	}))
+
+	require.NoError(t, err)
+	require.Nil(t, response.Error)
+	assert.True(t, models.NewDecimalFromInt(5).Equal(catalog.Reserved(accountID, partID)))
+
+	// Only the labor line that was deleted releases its parts
+	dynamoDBService.On("BatchDeleteLaborLines", mock.Anything, mock.Anything, models.BatchModeBestEffort, "user-123").
+		Return([]services.BatchWriteResult{
+			{LaborLine: &models.LaborLine{LaborLineID: laborLineIDs[0]}},
+			{Err: services.ErrLaborLineNotFound},
+		})
+
+	for i, laborLineID := range laborLineIDs {
+		items[i] = laborLineKeyInput(accountID, uuid.New().String(), laborLineID)
+	}
+	response, err = handler.HandleAppSyncEvent(context.Background(), batchEvent("batchDeleteLaborLines", map[string]interface{}{
+		"mode":  "BEST_EFFORT",
+		"items": items,
+	}))
+
+	require.NoError(t, err)
+	require.Nil(t, response.Error)
+	assert.True(t, models.NewDecimalFromInt(1).Equal(catalog.Reserved(accountID, partID)))
+}
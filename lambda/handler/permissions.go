@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// permissionPolicyDocument is the permission policy built into the function.
+//
+//go:embed permissions.json
+var permissionPolicyDocument []byte
+
+// allOperations is the operation key of a rule that applies to every operation.
+const allOperations = "*"
+
+// Reasons a permission policy denies an operation, reported in the error info.
+const (
+	DenyReasonNoRole              = "NO_ROLE"
+	DenyReasonOperationNotAllowed = "OPERATION_NOT_ALLOWED"
+	DenyReasonFieldNotWritable    = "FIELD_NOT_WRITABLE"
+	DenyReasonStatusNotAllowed    = "STATUS_NOT_ALLOWED"
+	DenyReasonNotOwner            = "NOT_OWNER"
+)
+
+// PermissionPolicy maps callers to roles and roles to the operations they may
+// perform.
+type PermissionPolicy struct {
+	// RoleClaim names the identity claim listing roles granted directly,
+	// in addition to those granted through groups. Only administrators may be
+	// able to set it: a Cognito custom attribute that users can write to, such
+	// as custom:roles, would let them grant themselves any role. The embedded
+	// policy uses cognito:groups, so a group named after a role grants it.
+	RoleClaim string                    `json:"roleClaim,omitempty"`
+	Roles     map[string]PermissionRole `json:"roles"`
+}
+
+// PermissionRole is a named set of operation rules granted to the members of
+// its groups.
+type PermissionRole struct {
+	Groups     []string                  `json:"groups,omitempty"`
+	Operations map[string]PermissionRule `json:"operations"`
+}
+
+// PermissionRule allows an operation, optionally only on labor lines the caller
+// created or is assigned to, without writing some fields, or only to reach some
+// statuses.
+type PermissionRule struct {
+	OwnOnly         bool                     `json:"ownOnly,omitempty"`
+	DeniedFields    []string                 `json:"deniedFields,omitempty"`
+	AllowedStatuses []models.LaborLineStatus `json:"allowedStatuses,omitempty"`
+}
+
+// PermissionDenial explains why the policy refused an operation.
+type PermissionDenial struct {
+	Reason string
+	Field  string
+}
+
+// defaultPermissionPolicy is parsed from the embedded document at start-up.
+var defaultPermissionPolicy = mustLoadPermissionPolicy(permissionPolicyDocument)
+
+// LoadPermissionPolicy parses a permission policy document, rejecting unknown
+// keys so that a misspelt rule cannot silently grant more than intended.
+func LoadPermissionPolicy(document []byte) (*PermissionPolicy, error) {
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.DisallowUnknownFields()
+
+	var policy PermissionPolicy
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("parsing permission policy: %w", err)
+	}
+	if len(policy.Roles) == 0 {
+		return nil, fmt.Errorf("permission policy defines no roles")
+	}
+	for name, role := range policy.Roles {
+		for operation, rule := range role.Operations {
+			for _, status := range rule.AllowedStatuses {
+				if !status.IsValid() {
+					return nil, fmt.Errorf("role %s: %s allows unknown status %q", name, operation, status)
+				}
+			}
+		}
+	}
+
+	return &policy, nil
+}
+
+// mustLoadPermissionPolicy parses a permission policy that is known to be valid.
+func mustLoadPermissionPolicy(document []byte) *PermissionPolicy {
+	policy, err := LoadPermissionPolicy(document)
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}
+
+// RolesFor returns the names of the roles granted to the caller, sorted.
+func (p *PermissionPolicy) RolesFor(identity models.Identity) []string {
+	granted := map[string]bool{}
+	if p.RoleClaim != "" {
+		for _, name := range identity.ClaimValues(p.RoleClaim) {
+			if _, ok := p.Roles[name]; ok {
+				granted[name] = true
+			}
+		}
+	}
+	for name, role := range p.Roles {
+		for _, group := range role.Groups {
+			if identity.InGroup(group) {
+				granted[name] = true
+			}
+		}
+	}
+
+	roles := make([]string, 0, len(granted))
+	for name := range granted {
+		roles = append(roles, name)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// rulesFor returns the rules of the given roles that allow the operation.
+func (p *PermissionPolicy) rulesFor(roles []string, operation string) []PermissionRule {
+	var rules []PermissionRule
+	for _, name := range roles {
+		role := p.Roles[name]
+		if rule, ok := role.Operations[operation]; ok {
+			rules = append(rules, rule)
+		} else if rule, ok := role.Operations[allOperations]; ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// check reports why the rule does not allow writing input, or nil if it does.
+// A denied field may not appear in the input at all, since a null value resets
+// the field. owned is only consulted by rules limited to the caller's own
+// labor lines.
+func (r PermissionRule) check(input map[string]interface{}, owned bool) *PermissionDenial {
+	for _, field := range r.DeniedFields {
+		if _, ok := input[field]; ok {
+			return &PermissionDenial{Reason: DenyReasonFieldNotWritable, Field: field}
+		}
+	}
+	if len(r.AllowedStatuses) > 0 {
+		status, _ := input["status"].(string)
+		allowed := false
+		for _, s := range r.AllowedStatuses {
+			if string(s) == status {
+				allowed = true
+			}
+		}
+		if !allowed {
+			return &PermissionDenial{Reason: DenyReasonStatusNotAllowed, Field: "status"}
+		}
+	}
+	if r.OwnOnly && !owned {
+		return &PermissionDenial{Reason: DenyReasonNotOwner}
+	}
+	return nil
+}
+
+// authorizeOperation checks the event's operation, and each item of a batch,
+// against the permission policy, returning the error response to send if the
+// caller's roles do not allow it.
+func (h *LaborLineHandler) authorizeOperation(ctx context.Context, event models.AppSyncEvent) *models.AppSyncResponse {
+	identity := event.CallerIdentity()
+	roles := h.permissionPolicy.RolesFor(identity)
+
+	operation := event.Info.FieldName
+	var input map[string]interface{}
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}
+	}
+	items := []map[string]interface{}{input}
+	itemOperation, isBatch := batchOperations[operation]
+	if isBatch {
+		var batch struct {
+			Items []map[string]interface{} `json:"items"`
+		}
+		if err := event.GetInputArgument(&batch); err != nil {
+			return &models.AppSyncResponse{
+				Error: &models.AppSyncError{
+					Message: fmt.Sprintf("invalid input: %v", err),
+					Type:    "ValidationError",
+				},
+			}
+		}
+		operation, items = itemOperation, batch.Items
+	}
+
+	if len(roles) == 0 {
+		return permissionDeniedResponse(operation, roles, &PermissionDenial{Reason: DenyReasonNoRole}, -1)
+	}
+	rules := h.permissionPolicy.rulesFor(roles, operation)
+	if len(rules) == 0 {
+		return permissionDeniedResponse(operation, roles, &PermissionDenial{Reason: DenyReasonOperationNotAllowed}, -1)
+	}
+
+	owned, err := h.ownedItems(ctx, rules, items, identity.Actor())
+	if err != nil {
+		log.Printf("Error checking labor line ownership: %v", err)
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "failed to check permissions",
+				Type:    "InternalError",
+			},
+		}
+	}
+
+	for i, item := range items {
+		var denial *PermissionDenial
+		for _, rule := range rules {
+			if denial = rule.check(item, owned[i]); denial == nil {
+				break
+			}
+		}
+		if denial != nil {
+			index := -1
+			if isBatch {
+				index = i
+			}
+			return permissionDeniedResponse(operation, roles, denial, index)
+		}
+	}
+
+	return nil
+}
+
+// ownedItems reports, for each item, whether the labor line it names was
+// created by actor or has actor assigned to it. The labor lines are only read if a rule depends on
+// ownership; one that does not exist counts as owned, so that the operation
+// reports it as not found.
+func (h *LaborLineHandler) ownedItems(ctx context.Context, rules []PermissionRule, items []map[string]interface{}, actor string) ([]bool, error) {
+	owned := make([]bool, len(items))
+	needed := false
+	for _, rule := range rules {
+		needed = needed || rule.OwnOnly
+	}
+	if !needed {
+		return owned, nil
+	}
+
+	keys := make([]models.GetLaborLineInput, len(items))
+	for i, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &keys[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	laborLines, err := h.dynamoDBService.BatchGetLaborLines(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("reading labor lines: %w", err)
+	}
+	for i, laborLine := range laborLines {
+		owned[i] = laborLine == nil || laborLine.IsOwnedBy(actor) || laborLine.IsAssigned(actor)
+	}
+	return owned, nil
+}
+
+// permissionDeniedResponse reports a denied operation with the reason, the
+// caller's roles and, for a batch, the index of the refused item.
+func permissionDeniedResponse(operation string, roles []string, denial *PermissionDenial, index int) *models.AppSyncResponse {
+	errorInfo := map[string]interface{}{
+		"reason":    denial.Reason,
+		"operation": operation,
+		"roles":     roles,
+	}
+	message := fmt.Sprintf("not permitted to %s", operation)
+	if denial.Field != "" {
+		errorInfo["field"] = denial.Field
+		message = fmt.Sprintf("not permitted to set %s in %s", denial.Field, operation)
+	}
+	if index >= 0 {
+		errorInfo["index"] = index
+	}
+
+	return &models.AppSyncResponse{
+		Error: &models.AppSyncError{
+			Message:   message,
+			Type:      "Unauthorized",
+			ErrorInfo: errorInfo,
+		},
+	}
+}
@@ -0,0 +1,357 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// adminGroup is the group the embedded permission policy grants the admin role.
+const adminGroup = "admin"
+
+// roleEvent builds an event for the given field whose caller belongs to the
+// given groups and may access every account the input refers to.
+func roleEvent(fieldName string, input map[string]interface{}, groups ...string) models.AppSyncEvent {
+	memberOf := make([]interface{}, len(groups))
+	for i, group := range groups {
+		memberOf[i] = group
+	}
+	return authorized(models.AppSyncEvent{
+		Info:      models.AppSyncInfo{FieldName: fieldName},
+		Arguments: map[string]interface{}{"input": input},
+		Identity:  map[string]interface{}{"sub": "user-123", "groups": memberOf},
+	})
+}
+
+func TestDefaultPermissionPolicy(t *testing.T) {
+	h := NewLaborLineHandler(&MockDynamoDBService{}, &MockValidationService{})
+
+	// Every rule in the embedded policy names a real operation
+	for name, role := range defaultPermissionPolicy.Roles {
+		for operation := range role.Operations {
+			if operation == allOperations {
+				continue
+			}
+			_, ok := h.operation(operation)
+			assert.True(t, ok, "role %s names unknown operation %s", name, operation)
+		}
+	}
+}
+
+func TestLoadPermissionPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		wantErr  string
+	}{
+		{
+			name:     "Valid policy",
+			document: `{"roles": {"viewer": {"groups": ["viewers"], "operations": {"getLaborLine": {}}}}}`,
+		},
+		{
+			name:     "Misspelt rule",
+			document: `{"roles": {"viewer": {"operations": {"getLaborLine": {"ownsOnly": true}}}}}`,
+			wantErr:  "unknown field",
+		},
+		{
+			name:     "Unknown status",
+			document: `{"roles": {"viewer": {"operations": {"transitionLaborLineStatus": {"allowedStatuses": ["DONE"]}}}}}`,
+			wantErr:  "unknown status",
+		},
+		{
+			name:     "No roles",
+			document: `{"roles": {}}`,
+			wantErr:  "defines no roles",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := LoadPermissionPolicy([]byte(tt.document))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, policy.Roles, "viewer")
+		})
+	}
+}
+
+func TestPermissionPolicy_RolesFor(t *testing.T) {
+	identity := models.ParseIdentity(map[string]interface{}{
+		"sub":      "user-123",
+		"username": "jdoe",
+		"groups":   []interface{}{"technicians", "unrelated"},
+		"claims":   map[string]interface{}{"cognito:groups": []interface{}{"advisor", "unknown-role"}},
+	})
+
+	assert.Equal(t, []string{"advisor", "technician"}, defaultPermissionPolicy.RolesFor(identity))
+	assert.Empty(t, defaultPermissionPolicy.RolesFor(models.ParseIdentity(nil)))
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_PermissionDenied(t *testing.T) {
+	accountID := uuid.New().String()
+	key := laborLineKeyInput(accountID, uuid.New().String(), uuid.New().String())
+	withFields := func(fields map[string]interface{}) map[string]interface{} {
+		input := map[string]interface{}{}
+		for k, v := range key {
+			input[k] = v
+		}
+		for k, v := range fields {
+			input[k] = v
+		}
+		return input
+	}
+
+	tests := []struct {
+		name           string
+		event          models.AppSyncEvent
+		expectedReason string
+		expectedField  string
+	}{
+		{
+			name:           "Technician may not delete",
+			event:          roleEvent("deleteLaborLine", key, "technicians"),
+			expectedReason: DenyReasonOperationNotAllowed,
+		},
+		{
+			name:           "Advisor may not delete",
+			event:          roleEvent("deleteLaborLine", key, "service-advisors"),
+			expectedReason: DenyReasonOperationNotAllowed,
+		},
+		{
+			name:           "Technician may not set the rate",
+			event:          roleEvent("createLaborLine", withFields(map[string]interface{}{"ratePerHour": "150"}), "technicians"),
+			expectedReason: DenyReasonFieldNotWritable,
+			expectedField:  "ratePerHour",
+		},
+		{
+			name:           "Technician may not pick the labor operation",
+			event:          roleEvent("createLaborLine", withFields(map[string]interface{}{"operationCode": "BRK-01"}), "technicians"),
+			expectedReason: DenyReasonFieldNotWritable,
+			expectedField:  "operationCode",
+		},
+		{
+			name:           "Technician may not complete",
+			event:          roleEvent("transitionLaborLineStatus", withFields(map[string]interface{}{"status": "COMPLETED"}), "technicians"),
+			expectedReason: DenyReasonStatusNotAllowed,
+			expectedField:  "status",
+		},
+		{
+			name:           "Technician may not approve",
+			event:          roleEvent("transitionLaborLineStatus", withFields(map[string]interface{}{"status": "APPROVED"}), "technicians"),
+			expectedReason: DenyReasonStatusNotAllowed,
+			expectedField:  "status",
+		},
+		{
+			name:           "Technician may not log time for another technician",
+			event:          roleEvent("addTimeEntry", withFields(map[string]interface{}{"technicianId": "tech-2"}), "technicians"),
+			expectedReason: DenyReasonFieldNotWritable,
+			expectedField:  "technicianId",
+		},
+		{
+			name:           "Caller without a role",
+			event:          roleEvent("getLaborLine", key),
+			expectedReason: DenyReasonNoRole,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamoDBService := &MockDynamoDBService{}
+			validationService := &MockValidationService{}
+			handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+			// Rules limited to the caller's own labor lines read them first
+			dynamoDBService.On("BatchGetLaborLines", mock.Anything, mock.Anything).
+				Return([]*models.LaborLine{{CreatedBy: "user-123"}}, nil).Maybe()
+
+			response, err := handler.HandleAppSyncEvent(context.Background(), tt.event)
+
+			require.NoError(t, err)
+			require.NotNil(t, response.Error)
+			assert.Equal(t, "Unauthorized", response.Error.Type)
+			assert.Equal(t, tt.expectedReason, response.Error.ErrorInfo["reason"])
+			if tt.expectedField != "" {
+				assert.Equal(t, tt.expectedField, response.Error.ErrorInfo["field"])
+			}
+
+			// Nothing is written for a denied operation
+			dynamoDBService.AssertExpectations(t)
+			validationService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_AdvisorApproves(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	input := laborLineKeyInput(uuid.New().String(), uuid.New().String(), uuid.New().String())
+	input["status"] = "APPROVED"
+
+	validationService.On("ValidateTransitionStatusInput", mock.Anything).Return(nil)
+	dynamoDBService.On("TransitionLaborLineStatus", mock.Anything, mock.Anything, "user-123").
+		Return(&models.LaborLine{Status: models.StatusApproved}, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("transitionLaborLineStatus", input, "service-advisors"))
+
+	require.NoError(t, err)
+	assert.Nil(t, response.Error)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_TechnicianUpdatesOwnLaborLine(t *testing.T) {
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+	key := models.GetLaborLineInput{AccountID: accountID, TaskID: taskID, LaborLineID: laborLineID}
+
+	tests := []struct {
+		name      string
+		createdBy string
+		allowed   bool
+	}{
+		{name: "Own labor line", createdBy: "user-123", allowed: true},
+		{name: "Another technician's labor line", createdBy: "user-456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamoDBService := &MockDynamoDBService{}
+			validationService := &MockValidationService{}
+			handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+			stored := &models.LaborLine{LaborLineID: laborLineID, AccountID: accountID, TaskID: taskID, CreatedBy: tt.createdBy}
+			dynamoDBService.On("BatchGetLaborLines", mock.Anything, []models.GetLaborLineInput{key}).
+				Return([]*models.LaborLine{stored}, nil).Once()
+			if tt.allowed {
+				validationService.On("ValidateUpdateInput", mock.Anything, mock.Anything).Return(nil)
+				dynamoDBService.On("UpdateLaborLine", mock.Anything, mock.Anything, "user-123").Return(stored, nil)
+			}
+
+			response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("updateLaborLine", map[string]interface{}{
+				"accountId":   accountID,
+				"taskId":      taskID,
+				"laborLineId": laborLineID,
+				"description": "Replace pads",
+			}, "technicians"))
+
+			require.NoError(t, err)
+			if tt.allowed {
+				assert.Nil(t, response.Error)
+			} else {
+				require.NotNil(t, response.Error)
+				assert.Equal(t, "Unauthorized", response.Error.Type)
+				assert.Equal(t, DenyReasonNotOwner, response.Error.ErrorInfo["reason"])
+			}
+			dynamoDBService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_TechnicianLogsTimeOnAssignedLaborLine(t *testing.T) {
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+	key := models.GetLaborLineInput{AccountID: accountID, TaskID: taskID, LaborLineID: laborLineID}
+
+	tests := []struct {
+		name     string
+		assigned []string
+		allowed  bool
+	}{
+		{name: "Assigned labor line", assigned: []string{"user-456", "user-123"}, allowed: true},
+		{name: "Labor line assigned to others", assigned: []string{"user-456"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamoDBService := &MockDynamoDBService{}
+			validationService := &MockValidationService{}
+			handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+			stored := &models.LaborLine{
+				LaborLineID:           laborLineID,
+				AccountID:             accountID,
+				TaskID:                taskID,
+				CreatedBy:             "advisor-1",
+				AssignedTechnicianIDs: tt.assigned,
+			}
+			dynamoDBService.On("BatchGetLaborLines", mock.Anything, []models.GetLaborLineInput{key}).
+				Return([]*models.LaborLine{stored}, nil).Once()
+			if tt.allowed {
+				validationService.On("ValidateStartTimerInput", mock.Anything).Return(nil)
+				dynamoDBService.On("SaveTimeEntry", mock.Anything, mock.Anything, "user-123").Return(stored, nil)
+			}
+
+			response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("startLaborTimer", map[string]interface{}{
+				"accountId":   accountID,
+				"taskId":      taskID,
+				"laborLineId": laborLineID,
+			}, "technicians"))
+
+			require.NoError(t, err)
+			if tt.allowed {
+				assert.Nil(t, response.Error)
+			} else {
+				require.NotNil(t, response.Error)
+				assert.Equal(t, DenyReasonNotOwner, response.Error.ErrorInfo["reason"])
+			}
+			dynamoDBService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_PermissionDenied_BatchItem(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("batchCreateLaborLines", map[string]interface{}{
+		"mode": "BEST_EFFORT",
+		"items": []interface{}{
+			map[string]interface{}{"accountId": accountID, "taskId": taskID, "description": "Replace pads"},
+			map[string]interface{}{"accountId": accountID, "taskId": taskID, "rateType": "WARRANTY"},
+		},
+	}, "technicians"))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "Unauthorized", response.Error.Type)
+	assert.Equal(t, DenyReasonFieldNotWritable, response.Error.ErrorInfo["reason"])
+	assert.Equal(t, "createLaborLine", response.Error.ErrorInfo["operation"])
+	assert.Equal(t, "rateType", response.Error.ErrorInfo["field"])
+	assert.Equal(t, 1, response.Error.ErrorInfo["index"])
+	dynamoDBService.AssertNotCalled(t, "BatchCreateLaborLines", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_PermissionCheckFailure(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	dynamoDBService.On("BatchGetLaborLines", mock.Anything, mock.Anything).
+		Return(([]*models.LaborLine)(nil), errors.New("dynamodb unavailable"))
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), roleEvent("updateLaborLine",
+		laborLineKeyInput(uuid.New().String(), uuid.New().String(), uuid.New().String()), "technicians"))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "InternalError", response.Error.Type)
+}
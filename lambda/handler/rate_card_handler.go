@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// handleGetRateCard processes requests for an account's rate card. Accounts
+// without a rate card get an empty one with the default rate type.
+func (h *LaborLineHandler) handleGetRateCard(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.GetRateCardInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	rateCard, err := h.dynamoDBService.GetRateCard(ctx, input.AccountID)
+	if err != nil {
+		log.Printf("Error getting rate card: %v", err)
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "failed to get rate card",
+				Type:    "InternalError",
+			},
+		}, nil
+	}
+
+	if rateCard == nil {
+		rateCard = &models.RateCard{
+			AccountID:       input.AccountID,
+			DefaultRateType: models.DefaultRateType,
+			Rates:           []models.RateCardEntry{},
+		}
+	}
+
+	return &models.AppSyncResponse{
+		Data: rateCard,
+	}, nil
+}
+
+// handleUpdateRateCard processes requests to replace an account's rate card.
+func (h *LaborLineHandler) handleUpdateRateCard(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.UpdateRateCardInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	// Validate input
+	if err := h.validationService.ValidateRateCardInput(input); err != nil {
+		return validationErrorResponse(err), nil
+	}
+
+	rateCard := input.ToRateCard()
+	if err := h.dynamoDBService.PutRateCard(ctx, rateCard); err != nil {
+		log.Printf("Error updating rate card: %v", err)
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "failed to update rate card",
+				Type:    "InternalError",
+			},
+		}, nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: rateCard,
+	}, nil
+}
+
+// rateCardFor loads the account's rate card when needed, that is when the
+// input leaves the rate type or rate per hour to be defaulted. It returns nil
+// when no defaults are needed or the account has no rate card.
+func (h *LaborLineHandler) rateCardFor(ctx context.Context, accountID string, needed bool) (*models.RateCard, error) {
+	if !needed {
+		return nil, nil
+	}
+	return h.dynamoDBService.GetRateCard(ctx, accountID)
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+func TestLaborLineHandler_HandleAppSyncEvent_GetRateCard(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	rateCard := &models.RateCard{
+		AccountID:       accountID,
+		DefaultRateType: models.RateTypeFlatRate,
+		Rates: []models.RateCardEntry{
+			{RateType: models.RateTypeFlatRate, RatePerHour: models.MustParseDecimal("120")},
+		},
+	}
+
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "getRateCard",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"accountId": accountID,
+			},
+		},
+	}
+
+	dynamoDBService.On("GetRateCard", mock.Anything, accountID).Return(rateCard, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+	assert.Equal(t, rateCard, response.Data)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_GetRateCard_NotConfigured(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "getRateCard",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"accountId": accountID,
+			},
+		},
+	}
+
+	dynamoDBService.On("GetRateCard", mock.Anything, accountID).Return((*models.RateCard)(nil), nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+
+	rateCard, ok := response.Data.(*models.RateCard)
+	require.True(t, ok)
+	assert.Equal(t, accountID, rateCard.AccountID)
+	assert.Equal(t, models.DefaultRateType, rateCard.DefaultRateType)
+	assert.Empty(t, rateCard.Rates)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_UpdateRateCard(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "updateRateCard",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"accountId":       accountID,
+				"defaultRateType": "HOURLY",
+				"rates": []interface{}{
+					map[string]interface{}{"rateType": "HOURLY", "ratePerHour": 95.5},
+					map[string]interface{}{"rateType": "WARRANTY", "ratePerHour": "80"},
+				},
+			},
+		},
+	}
+
+	validationService.On("ValidateRateCardInput", mock.Anything).Return(nil)
+	dynamoDBService.On("PutRateCard", mock.Anything, mock.MatchedBy(func(rc *models.RateCard) bool {
+		return rc.AccountID == accountID && rc.PK == accountID && len(rc.Rates) == 2
+	})).Return(nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+
+	rateCard, ok := response.Data.(*models.RateCard)
+	require.True(t, ok)
+	rate, found := rateCard.RateFor(models.RateTypeHourly)
+	require.True(t, found)
+	assert.Equal(t, "95.50", rate.StringFixed(2))
+
+	dynamoDBService.AssertExpectations(t)
+	validationService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_UpdateRateCard_ValidationError(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "updateRateCard",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"accountId": uuid.New().String(),
+				"rates":     []interface{}{},
+			},
+		},
+	}
+
+	validationService.On("ValidateRateCardInput", mock.Anything).Return(fmt.Errorf("duplicate rate"))
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ValidationError", response.Error.Type)
+
+	dynamoDBService.AssertNotCalled(t, "PutRateCard", mock.Anything, mock.Anything)
+	validationService.AssertExpectations(t)
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// handleTransitionStatus processes requests to move a labor line to a new status.
+// The caller's identity is recorded with the transition.
+func (h *LaborLineHandler) handleTransitionStatus(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.TransitionLaborLineStatusInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	// Validate input
+	if err := h.validationService.ValidateTransitionStatusInput(input); err != nil {
+		return validationErrorResponse(err), nil
+	}
+
+	laborLine, err := h.dynamoDBService.TransitionLaborLineStatus(ctx, input, event.Actor())
+	if err != nil {
+		return writeErrorResponse(err, "failed to transition labor line status"), nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: laborLine,
+	}, nil
+}
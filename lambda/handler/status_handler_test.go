@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// transitionEvent builds a transitionLaborLineStatus event for the given status.
+func transitionEvent(status string) models.AppSyncEvent {
+	return authorized(models.AppSyncEvent{
+		Identity: map[string]interface{}{
+			"sub": "user-123",
+		},
+		Info: models.AppSyncInfo{
+			FieldName: "transitionLaborLineStatus",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"laborLineId": uuid.New().String(),
+				"accountId":   uuid.New().String(),
+				"taskId":      uuid.New().String(),
+				"status":      status,
+				"reason":      "waiting on parts",
+			},
+		},
+	})
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_TransitionStatus(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	transitioned := &models.LaborLine{
+		Status: models.StatusOnHold,
+		StatusHistory: []models.StatusTransition{
+			{From: models.StatusInProgress, To: models.StatusOnHold, ChangedBy: "user-123"},
+		},
+	}
+
+	validationService.On("ValidateTransitionStatusInput", mock.Anything).Return(nil)
+	dynamoDBService.On("TransitionLaborLineStatus", mock.Anything, mock.MatchedBy(func(input models.TransitionLaborLineStatusInput) bool {
+		return input.Status == models.StatusOnHold && input.Reason == "waiting on parts"
+	}), "user-123").Return(transitioned, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), transitionEvent("ON_HOLD"))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+	assert.Equal(t, transitioned, response.Data)
+
+	dynamoDBService.AssertExpectations(t)
+	validationService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_TransitionStatus_Invalid(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	validationService.On("ValidateTransitionStatusInput", mock.Anything).Return(nil)
+	dynamoDBService.On("TransitionLaborLineStatus", mock.Anything, mock.Anything, mock.Anything).
+		Return((*models.LaborLine)(nil), &services.InvalidStateTransitionError{From: models.StatusPending, To: models.StatusInvoiced})
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), transitionEvent("INVOICED"))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "InvalidStateTransition", response.Error.Type)
+	assert.Equal(t, models.StatusPending, response.Error.ErrorInfo["currentStatus"])
+	assert.Equal(t, models.StatusInvoiced, response.Error.ErrorInfo["requestedStatus"])
+	assert.ElementsMatch(t, []models.LaborLineStatus{models.StatusInProgress, models.StatusOnHold}, response.Error.ErrorInfo["allowedTransitions"])
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_TransitionStatus_ValidationError(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	validationService.On("ValidateTransitionStatusInput", mock.Anything).Return(fmt.Errorf("status must be one of the enum values"))
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), transitionEvent("CANCELLED"))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ValidationError", response.Error.Type)
+
+	dynamoDBService.AssertNotCalled(t, "TransitionLaborLineStatus", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// StreamHandler maintains the data derived from labor lines, such as each
+// account's status counts, from the changes delivered by the table's DynamoDB
+// stream, and announces the task totals those changes move.
+type StreamHandler struct {
+	dynamoDBService services.DynamoDBService
+	totalsPublisher services.TaskTotalsPublisher
+	partsCatalog    services.PartsCatalog
+}
+
+// StreamHandlerOption configures optional behaviour of the stream handler.
+type StreamHandlerOption func(*StreamHandler)
+
+// WithTaskTotalsPublisher announces each change to a task's totals. Without it
+// the totals are only kept in the task's summary.
+func WithTaskTotalsPublisher(publisher services.TaskTotalsPublisher) StreamHandlerOption {
+	return func(h *StreamHandler) {
+		h.totalsPublisher = publisher
+	}
+}
+
+// WithStreamPartsCatalog releases the parts of labor lines removed from the
+// table, such as soft-deleted labor lines whose retention period has expired.
+func WithStreamPartsCatalog(catalog services.PartsCatalog) StreamHandlerOption {
+	return func(h *StreamHandler) {
+		h.partsCatalog = catalog
+	}
+}
+
+// NewStreamHandler creates a new stream handler.
+func NewStreamHandler(dynamoDBService services.DynamoDBService, opts ...StreamHandlerOption) *StreamHandler {
+	h := &StreamHandler{
+		dynamoDBService: dynamoDBService,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// HandleDynamoDBEvent processes a batch of stream records in order. Records of
+// items other than labor lines, including the derived items it writes itself,
+// are skipped. At the first record that cannot be processed it stops and
+// reports that record as failed, so Lambda retries the batch from it and the
+// changes to each labor line are still applied in order. Records processed
+// before are not applied twice when they are delivered again.
+func (h *StreamHandler) HandleDynamoDBEvent(ctx context.Context, event events.DynamoDBEvent) events.DynamoDBEventResponse {
+	for _, record := range event.Records {
+		if err := h.handleRecord(ctx, record); err != nil {
+			log.Printf("Error processing stream record %s: %v", record.EventID, err)
+			return events.DynamoDBEventResponse{
+				BatchItemFailures: []events.DynamoDBBatchItemFailure{
+					{ItemIdentifier: record.Change.SequenceNumber},
+				},
+			}
+		}
+	}
+
+	return events.DynamoDBEventResponse{BatchItemFailures: []events.DynamoDBBatchItemFailure{}}
+}
+
+// handleRecord applies one stream record to the derived data and announces
+// the task totals it changes. When a labor line is removed from the table its
+// related records are removed and its parts are released.
+func (h *StreamHandler) handleRecord(ctx context.Context, record events.DynamoDBEventRecord) error {
+	before, err := streamLaborLine(record.Change.OldImage)
+	if err != nil {
+		return fmt.Errorf("decoding old image: %w", err)
+	}
+	after, err := streamLaborLine(record.Change.NewImage)
+	if err != nil {
+		return fmt.Errorf("decoding new image: %w", err)
+	}
+	if before == nil && after == nil {
+		return nil
+	}
+
+	totals, err := h.dynamoDBService.ApplyLaborLineChange(ctx, before, after)
+	if err != nil {
+		return err
+	}
+
+	if totals != nil && h.totalsPublisher != nil {
+		if err := h.totalsPublisher.PublishTaskTotals(ctx, []*models.TaskLaborTotals{totals}); err != nil {
+			return fmt.Errorf("publishing task totals: %w", err)
+		}
+	}
+
+	if after == nil {
+		return h.removeLaborLine(ctx, before)
+	}
+	return nil
+}
+
+// removeLaborLine removes what is kept for a labor line that was removed from
+// the table, as one that expired is by its TTL alone. Both are repeated
+// harmlessly for purged labor lines, whose records were removed and parts
+// released when they were purged.
+func (h *StreamHandler) removeLaborLine(ctx context.Context, laborLine *models.LaborLine) error {
+	if err := h.dynamoDBService.PurgeLaborLineRecords(ctx, laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID); err != nil {
+		return fmt.Errorf("removing labor line records: %w", err)
+	}
+
+	if h.partsCatalog != nil {
+		if err := h.partsCatalog.ReleaseParts(ctx, laborLine.AccountID, laborLine.LaborLineID); err != nil {
+			return fmt.Errorf("releasing parts: %w", err)
+		}
+	}
+	return nil
+}
+
+// streamLaborLine decodes a stream image into a labor line. It returns nil if
+// there is no image, as for the old image of a new item, or if the item is
+// not a labor line.
+func streamLaborLine(image map[string]events.DynamoDBAttributeValue) (*models.LaborLine, error) {
+	if len(image) == 0 {
+		return nil, nil
+	}
+	// Labor lines are the only items without a record type
+	if _, ok := image["recordType"]; ok {
+		return nil, nil
+	}
+
+	item, err := streamItem(image)
+	if err != nil {
+		return nil, err
+	}
+
+	var laborLine models.LaborLine
+	if err := attributevalue.UnmarshalMap(item, &laborLine); err != nil {
+		return nil, fmt.Errorf("unmarshaling labor line: %w", err)
+	}
+	if laborLine.LaborLineID == "" {
+		return nil, nil
+	}
+	return &laborLine, nil
+}
+
+// streamItem converts a stream image into the attribute values the DynamoDB
+// client uses, so it can be unmarshaled like any other item.
+func streamItem(image map[string]events.DynamoDBAttributeValue) (map[string]types.AttributeValue, error) {
+	item := make(map[string]types.AttributeValue, len(image))
+	for name, value := range image {
+		av, err := streamAttributeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", name, err)
+		}
+		item[name] = av
+	}
+	return item, nil
+}
+
+// streamAttributeValue converts a stream attribute value into the DynamoDB
+// client's attribute value.
+func streamAttributeValue(value events.DynamoDBAttributeValue) (types.AttributeValue, error) {
+	switch value.DataType() {
+	case events.DataTypeString:
+		return &types.AttributeValueMemberS{Value: value.String()}, nil
+	case events.DataTypeNumber:
+		return &types.AttributeValueMemberN{Value: value.Number()}, nil
+	case events.DataTypeBinary:
+		return &types.AttributeValueMemberB{Value: value.Binary()}, nil
+	case events.DataTypeBoolean:
+		return &types.AttributeValueMemberBOOL{Value: value.Boolean()}, nil
+	case events.DataTypeNull:
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case events.DataTypeStringSet:
+		return &types.AttributeValueMemberSS{Value: value.StringSet()}, nil
+	case events.DataTypeNumberSet:
+		return &types.AttributeValueMemberNS{Value: value.NumberSet()}, nil
+	case events.DataTypeBinarySet:
+		return &types.AttributeValueMemberBS{Value: value.BinarySet()}, nil
+	case events.DataTypeList:
+		list := make([]types.AttributeValue, 0, len(value.List()))
+		for _, element := range value.List() {
+			av, err := streamAttributeValue(element)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, av)
+		}
+		return &types.AttributeValueMemberL{Value: list}, nil
+	case events.DataTypeMap:
+		m, err := streamItem(value.Map())
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberM{Value: m}, nil
+	default:
+		return nil, fmt.Errorf("unsupported stream attribute type %d", value.DataType())
+	}
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// loadStreamEvent reads a recorded stream event from testdata/stream.
+func loadStreamEvent(t *testing.T, name string) events.DynamoDBEvent {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "stream", name))
+	require.NoError(t, err)
+
+	var event events.DynamoDBEvent
+	require.NoError(t, json.Unmarshal(data, &event))
+	return event
+}
+
+func TestStreamHandler_HandleDynamoDBEvent_Insert(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	publisher := services.NewMemoryEventPublisher()
+	handler := NewStreamHandler(dynamoDBService, WithTaskTotalsPublisher(publisher))
+
+	totals := &models.TaskLaborTotals{
+		AccountID:       "0b6a3c57-3f0e-4a55-9f54-0f7e3c9a1d21",
+		TaskID:          "5f1d2c7e-8a43-4d1b-9b0e-2d6f5c3a7e10",
+		LaborLineTotals: models.LaborLineTotals{LaborLineCount: 1, EstimatedHours: models.MustParseDecimal("1.5")},
+	}
+	dynamoDBService.On("ApplyLaborLineChange", mock.Anything, (*models.LaborLine)(nil), mock.MatchedBy(func(after *models.LaborLine) bool {
+		return after.LaborLineID == "c2a9e4b1-6d3f-4e8a-a1b7-9f0c2d4e6a83" &&
+			after.Status == models.StatusPending &&
+			after.EstimatedHours.StringFixed(2) == "1.50" &&
+			after.LaborCost.StringFixed(2) == "180.00" &&
+			len(after.Parts) == 1 && after.Parts[0].UnitCost.StringFixed(2) == "45.50"
+	})).Return(totals, nil).Once()
+
+	// The history record written with the labor line is skipped
+	response := handler.HandleDynamoDBEvent(context.Background(), loadStreamEvent(t, "insert.json"))
+
+	assert.Empty(t, response.BatchItemFailures)
+	assert.Equal(t, []*models.TaskLaborTotals{totals}, publisher.TaskTotals())
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestStreamHandler_HandleDynamoDBEvent_Modify(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	handler := NewStreamHandler(dynamoDBService)
+
+	dynamoDBService.On("ApplyLaborLineChange", mock.Anything,
+		mock.MatchedBy(func(before *models.LaborLine) bool {
+			return before.Status == models.StatusPending && before.Version == 2
+		}),
+		mock.MatchedBy(func(after *models.LaborLine) bool {
+			return after.Status == models.StatusInProgress && after.Version == 3 &&
+				after.ActualHours.StringFixed(2) == "1.25" &&
+				len(after.StatusHistory) == 1 && after.StatusHistory[0].ChangedBy == "tech-1" &&
+				assert.ObjectsAreEqual([]string{"tech-1"}, after.AssignedTechnicianIDs)
+		}),
+	).Return(&models.TaskLaborTotals{}, nil).Once()
+
+	response := handler.HandleDynamoDBEvent(context.Background(), loadStreamEvent(t, "modify.json"))
+
+	assert.Empty(t, response.BatchItemFailures)
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestStreamHandler_HandleDynamoDBEvent_Remove(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	publisher := services.NewMemoryEventPublisher()
+	handler := NewStreamHandler(dynamoDBService, WithTaskTotalsPublisher(publisher))
+
+	// The labor line expired through TTL after being soft deleted
+	dynamoDBService.On("ApplyLaborLineChange", mock.Anything,
+		mock.MatchedBy(func(before *models.LaborLine) bool {
+			return before.DeletedAt != nil && before.Version == 6
+		}),
+		(*models.LaborLine)(nil),
+	).Return((*models.TaskLaborTotals)(nil), nil).Once()
+
+	// Its time entries, time slots, assignments and history go with it
+	event := loadStreamEvent(t, "remove.json")
+	before, err := streamLaborLine(event.Records[0].Change.OldImage)
+	require.NoError(t, err)
+	dynamoDBService.On("PurgeLaborLineRecords", mock.Anything, before.AccountID, before.TaskID, before.LaborLineID).Return(nil).Once()
+
+	response := handler.HandleDynamoDBEvent(context.Background(), event)
+
+	assert.Empty(t, response.BatchItemFailures)
+	assert.Empty(t, publisher.TaskTotals())
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestStreamHandler_HandleDynamoDBEvent_RemoveRecordsFailure(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	handler := NewStreamHandler(dynamoDBService)
+
+	dynamoDBService.On("ApplyLaborLineChange", mock.Anything, mock.Anything, (*models.LaborLine)(nil)).
+		Return((*models.TaskLaborTotals)(nil), nil).Once()
+	dynamoDBService.On("PurgeLaborLineRecords", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(errors.New("throttled")).Once()
+
+	// The removal is retried until its records are gone
+	response := handler.HandleDynamoDBEvent(context.Background(), loadStreamEvent(t, "remove.json"))
+
+	require.Len(t, response.BatchItemFailures, 1)
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestStreamHandler_HandleDynamoDBEvent_RemoveReleasesParts(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	event := loadStreamEvent(t, "remove.json")
+	before, err := streamLaborLine(event.Records[0].Change.OldImage)
+	require.NoError(t, err)
+
+	partID := uuid.New().String()
+	catalog := partsCatalog(before.AccountID, partID, 5)
+	require.NoError(t, catalog.ReserveParts(context.Background(), before.AccountID, before.LaborLineID, []models.PartLineItem{{PartID: partID, Quantity: models.NewDecimalFromInt(2)}}))
+	handler := NewStreamHandler(dynamoDBService, WithStreamPartsCatalog(catalog))
+
+	dynamoDBService.On("ApplyLaborLineChange", mock.Anything, mock.Anything, (*models.LaborLine)(nil)).
+		Return((*models.TaskLaborTotals)(nil), nil).Once()
+	dynamoDBService.On("PurgeLaborLineRecords", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	response := handler.HandleDynamoDBEvent(context.Background(), event)
+
+	assert.Empty(t, response.BatchItemFailures)
+	assert.True(t, catalog.Reserved(before.AccountID, partID).IsZero())
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestStreamHandler_HandleDynamoDBEvent_ReportsFirstFailure(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	handler := NewStreamHandler(dynamoDBService)
+
+	// A batch of the creation, the status change and the removal
+	event := loadStreamEvent(t, "insert.json")
+	event.Records = append(event.Records, loadStreamEvent(t, "modify.json").Records...)
+	event.Records = append(event.Records, loadStreamEvent(t, "remove.json").Records...)
+
+	dynamoDBService.On("ApplyLaborLineChange", mock.Anything, (*models.LaborLine)(nil), mock.Anything).
+		Return((*models.TaskLaborTotals)(nil), nil).Once()
+	dynamoDBService.On("ApplyLaborLineChange", mock.Anything, mock.MatchedBy(func(before *models.LaborLine) bool {
+		return before.Version == 2
+	}), mock.Anything).Return((*models.TaskLaborTotals)(nil), errors.New("throttled")).Once()
+
+	response := handler.HandleDynamoDBEvent(context.Background(), event)
+
+	// Processing stops at the failed record, so the removal is left for the retry
+	require.Len(t, response.BatchItemFailures, 1)
+	assert.Equal(t, "111300000000012345678903", response.BatchItemFailures[0].ItemIdentifier)
+	dynamoDBService.AssertNumberOfCalls(t, "ApplyLaborLineChange", 2)
+}
+
+func TestStreamHandler_HandleDynamoDBEvent_PublishFailure(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	publisher := services.NewMemoryEventPublisher()
+	publisher.Err = errors.New("event bus unavailable")
+	handler := NewStreamHandler(dynamoDBService, WithTaskTotalsPublisher(publisher))
+
+	dynamoDBService.On("ApplyLaborLineChange", mock.Anything, mock.Anything, mock.Anything).
+		Return(&models.TaskLaborTotals{}, nil).Once()
+
+	// The record is retried; the totals are not changed twice, but are published again
+	response := handler.HandleDynamoDBEvent(context.Background(), loadStreamEvent(t, "insert.json"))
+
+	require.Len(t, response.BatchItemFailures, 1)
+	assert.Equal(t, "111100000000012345678901", response.BatchItemFailures[0].ItemIdentifier)
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// handleGetTaskSummary processes requests for the totals of a task's labor
+// lines, broken down by status and technician.
+func (h *LaborLineHandler) handleGetTaskSummary(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.GetTaskLaborSummaryInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+	if input.TaskID == "" {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "taskId is required",
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	summary, err := h.dynamoDBService.GetTaskLaborSummary(ctx, input)
+	if err != nil {
+		log.Printf("Error getting task labor summary: %v", err)
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "failed to get task labor summary",
+				Type:    "InternalError",
+			},
+		}, nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: summary,
+	}, nil
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// taskSummaryEvent builds a getTaskLaborSummary event.
+func taskSummaryEvent(input map[string]interface{}) models.AppSyncEvent {
+	return authorized(models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "getTaskLaborSummary",
+		},
+		Arguments: map[string]interface{}{
+			"input": input,
+		},
+	})
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_GetTaskLaborSummary(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	input := models.GetTaskLaborSummaryInput{
+		AccountID: uuid.New().String(),
+		TaskID:    uuid.New().String(),
+		Recompute: true,
+	}
+	summary := &models.TaskLaborSummary{
+		AccountID:       input.AccountID,
+		TaskID:          input.TaskID,
+		LaborLineTotals: models.LaborLineTotals{LaborLineCount: 2, EstimatedHours: models.MustParseDecimal("3.5")},
+		ByStatus: []models.StatusLaborTotals{
+			{Status: models.StatusPending, LaborLineTotals: models.LaborLineTotals{LaborLineCount: 2}},
+		},
+		ByTechnician: []models.TechnicianLaborTotals{},
+		Recomputed:   true,
+	}
+
+	dynamoDBService.On("GetTaskLaborSummary", mock.Anything, input).Return(summary, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), taskSummaryEvent(map[string]interface{}{
+		"accountId": input.AccountID,
+		"taskId":    input.TaskID,
+		"recompute": true,
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+	assert.Equal(t, summary, response.Data)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_GetTaskLaborSummary_MissingTask(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), taskSummaryEvent(map[string]interface{}{
+		"accountId": uuid.New().String(),
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ValidationError", response.Error.Type)
+	dynamoDBService.AssertNotCalled(t, "GetTaskLaborSummary", mock.Anything, mock.Anything)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_GetTaskLaborSummary_Error(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	dynamoDBService.On("GetTaskLaborSummary", mock.Anything, mock.Anything).
+		Return((*models.TaskLaborSummary)(nil), errors.New("throttled"))
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), taskSummaryEvent(map[string]interface{}{
+		"accountId": uuid.New().String(),
+		"taskId":    uuid.New().String(),
+	}))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "InternalError", response.Error.Type)
+	assert.Equal(t, "failed to get task labor summary", response.Error.Message)
+}
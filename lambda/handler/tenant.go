@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"fmt"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// crossAccountOperations take no accountId; they are scoped to the caller's
+// accounts by the operation itself.
+var crossAccountOperations = map[string]bool{
+	"listLaborLinesByTask": true,
+}
+
+// batchOperations take a list of items, each naming its own account. They map
+// to the operation performed on each item.
+var batchOperations = map[string]string{
+	"batchCreateLaborLines": "createLaborLine",
+	"batchUpdateLaborLines": "updateLaborLine",
+	"batchDeleteLaborLines": "deleteLaborLine",
+}
+
+// accountScopedInput holds the account references of an operation's input:
+// the accountId of a single-item operation or of each item in a batch.
+type accountScopedInput struct {
+	AccountID string `json:"accountId"`
+	Items     []struct {
+		AccountID string `json:"accountId"`
+	} `json:"items"`
+}
+
+// authorizeAccounts checks that every account the event's input refers to is
+// one the caller's identity grants access to, returning the error response to
+// send if not.
+func (h *LaborLineHandler) authorizeAccounts(event models.AppSyncEvent) *models.AppSyncResponse {
+	if crossAccountOperations[event.Info.FieldName] {
+		return nil
+	}
+
+	var input accountScopedInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}
+	}
+
+	accountIDs := []string{input.AccountID}
+	if _, ok := batchOperations[event.Info.FieldName]; ok {
+		accountIDs = make([]string, len(input.Items))
+		for i, item := range input.Items {
+			accountIDs[i] = item.AccountID
+		}
+	}
+
+	identity := event.CallerIdentity()
+	for _, accountID := range accountIDs {
+		if accountID == "" {
+			return &models.AppSyncResponse{
+				Error: &models.AppSyncError{
+					Message: "accountId is required",
+					Type:    "ValidationError",
+				},
+			}
+		}
+		if !identity.CanAccessAccount(h.accountIDsClaim, accountID) {
+			return unauthorizedAccountResponse(accountID)
+		}
+	}
+
+	return nil
+}
+
+// isTrustedIAMCaller reports whether the caller signed the request with one of
+// the trusted IAM roles. Other IAM callers carry no account claims, so
+// authorizeAccounts refuses them.
+func (h *LaborLineHandler) isTrustedIAMCaller(identity models.Identity) bool {
+	role := identity.IAMRole()
+	return role != "" && h.trustedIAMRoles[role]
+}
+
+// unauthorizedAccountResponse reports that the caller may not access accountID.
+func unauthorizedAccountResponse(accountID string) *models.AppSyncResponse {
+	return &models.AppSyncResponse{
+		Error: &models.AppSyncError{
+			Message:   "not authorized for this account",
+			Type:      "Unauthorized",
+			ErrorInfo: map[string]interface{}{"accountId": accountID},
+		},
+	}
+}
@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// authorized returns the event with its caller granted access to every account
+// its input refers to, keeping any identity fields already set. Callers not
+// given groups by the test are admins, so every operation is permitted.
+func authorized(event models.AppSyncEvent) models.AppSyncEvent {
+	var input accountScopedInput
+	_ = event.GetInputArgument(&input)
+
+	accountIDs := []string{input.AccountID}
+	for _, item := range input.Items {
+		accountIDs = append(accountIDs, item.AccountID)
+	}
+
+	identity := map[string]interface{}{}
+	for key, value := range event.Identity {
+		identity[key] = value
+	}
+	identity["claims"] = map[string]interface{}{models.DefaultAccountIDsClaim: strings.Join(accountIDs, ",")}
+	if _, ok := identity["groups"]; !ok {
+		identity["groups"] = []interface{}{adminGroup}
+	}
+	event.Identity = identity
+	return event
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_TenantIsolation(t *testing.T) {
+	accountID := uuid.New().String()
+
+	tests := []struct {
+		name         string
+		identity     map[string]interface{}
+		expectedType string
+	}{
+		{
+			name: "Cognito user with the account",
+			identity: map[string]interface{}{
+				"sub":      "user-123",
+				"username": "jdoe",
+				"issuer":   "https://cognito-idp.us-east-1.amazonaws.com/us-east-1_example",
+				"groups":   []interface{}{"technicians"},
+				"claims":   map[string]interface{}{models.DefaultAccountIDsClaim: "other-account," + accountID},
+			},
+		},
+		{
+			name: "Cognito user without the account",
+			identity: map[string]interface{}{
+				"sub":      "user-123",
+				"username": "jdoe",
+				"claims":   map[string]interface{}{models.DefaultAccountIDsClaim: "other-account"},
+			},
+			expectedType: "Unauthorized",
+		},
+		{
+			name: "OIDC user with the account",
+			identity: map[string]interface{}{
+				"sub":    "oidc|42",
+				"issuer": "https://login.example.com/",
+				"claims": map[string]interface{}{
+					models.DefaultAccountIDsClaim: []interface{}{accountID},
+					"cognito:groups":              "technician",
+				},
+			},
+		},
+		{
+			name: "Lambda authorizer granting the account",
+			identity: map[string]interface{}{
+				"resolverContext": map[string]interface{}{
+					models.DefaultAccountIDsClaim: accountID,
+					"cognito:groups":              "technician",
+				},
+			},
+		},
+		{
+			name: "Lambda authorizer granting another account",
+			identity: map[string]interface{}{
+				"resolverContext": map[string]interface{}{models.DefaultAccountIDsClaim: "other-account"},
+			},
+			expectedType: "Unauthorized",
+		},
+		{
+			name: "IAM caller",
+			identity: map[string]interface{}{
+				"accountId": accountID,
+				"userArn":   "arn:aws:iam::123456789012:role/service",
+			},
+			expectedType: "Unauthorized",
+		},
+		{
+			name:         "No identity",
+			expectedType: "Unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamoDBService := &MockDynamoDBService{}
+			validationService := &MockValidationService{}
+			handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+			if tt.expectedType == "" {
+				dynamoDBService.On("GetLaborLine", mock.Anything, mock.Anything).
+					Return(&models.LaborLine{AccountID: accountID}, nil)
+			}
+
+			response, err := handler.HandleAppSyncEvent(context.Background(), models.AppSyncEvent{
+				Info:      models.AppSyncInfo{FieldName: "getLaborLine"},
+				Arguments: map[string]interface{}{"input": laborLineKeyInput(accountID, uuid.New().String(), uuid.New().String())},
+				Identity:  tt.identity,
+			})
+
+			require.NoError(t, err)
+			require.NotNil(t, response)
+			if tt.expectedType == "" {
+				assert.Nil(t, response.Error)
+			} else {
+				require.NotNil(t, response.Error)
+				assert.Equal(t, tt.expectedType, response.Error.Type)
+				assert.Equal(t, accountID, response.Error.ErrorInfo["accountId"])
+				dynamoDBService.AssertNotCalled(t, "GetLaborLine", mock.Anything, mock.Anything)
+			}
+			dynamoDBService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_TenantIsolation_ConfiguredClaim(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService, WithAccountIDsClaim("tenants"))
+
+	accountID := uuid.New().String()
+	dynamoDBService.On("ListLaborLines", mock.Anything, models.ListLaborLinesInput{AccountID: accountID}).
+		Return(&models.LaborLineConnection{}, nil).Once()
+
+	newEvent := func(claims map[string]interface{}) models.AppSyncEvent {
+		return models.AppSyncEvent{
+			Info:      models.AppSyncInfo{FieldName: "listLaborLines"},
+			Arguments: map[string]interface{}{"input": map[string]interface{}{"accountId": accountID}},
+			Identity:  map[string]interface{}{"sub": "user-123", "groups": []interface{}{"technicians"}, "claims": claims},
+		}
+	}
+
+	// The default claim is ignored once another is configured
+	response, err := handler.HandleAppSyncEvent(context.Background(), newEvent(map[string]interface{}{models.DefaultAccountIDsClaim: accountID}))
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "Unauthorized", response.Error.Type)
+
+	response, err = handler.HandleAppSyncEvent(context.Background(), newEvent(map[string]interface{}{"tenants": accountID}))
+	require.NoError(t, err)
+	assert.Nil(t, response.Error)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_TrustedIAMRole(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService,
+		WithTrustedIAMRoles("arn:aws:iam::123456789012:role/services/labor-sync"))
+
+	accountID := uuid.New().String()
+	dynamoDBService.On("GetLaborLine", mock.Anything, mock.Anything).
+		Return(&models.LaborLine{AccountID: accountID}, nil).Once()
+
+	newEvent := func(userArn string) models.AppSyncEvent {
+		return models.AppSyncEvent{
+			Info:      models.AppSyncInfo{FieldName: "getLaborLine"},
+			Arguments: map[string]interface{}{"input": laborLineKeyInput(accountID, uuid.New().String(), uuid.New().String())},
+			Identity:  map[string]interface{}{"accountId": "123456789012", "userArn": userArn},
+		}
+	}
+
+	// A session of the trusted role needs no account claim or group
+	response, err := handler.HandleAppSyncEvent(context.Background(), newEvent("arn:aws:sts::123456789012:assumed-role/labor-sync/sync-1"))
+	require.NoError(t, err)
+	assert.Nil(t, response.Error)
+
+	// Roles of the same name in other AWS accounts are not trusted
+	response, err = handler.HandleAppSyncEvent(context.Background(), newEvent("arn:aws:sts::210987654321:assumed-role/labor-sync/sync-1"))
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "Unauthorized", response.Error.Type)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_TenantIsolation_BatchItems(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	otherAccountID := uuid.New().String()
+
+	// One foreign item rejects the whole batch before anything is written
+	response, err := handler.HandleAppSyncEvent(context.Background(), models.AppSyncEvent{
+		Info: models.AppSyncInfo{FieldName: "batchDeleteLaborLines"},
+		Arguments: map[string]interface{}{"input": map[string]interface{}{
+			"mode": "BEST_EFFORT",
+			"items": []interface{}{
+				laborLineKeyInput(accountID, uuid.New().String(), uuid.New().String()),
+				laborLineKeyInput(otherAccountID, uuid.New().String(), uuid.New().String()),
+			},
+		}},
+		Identity: map[string]interface{}{
+			"sub":    "user-123",
+			"claims": map[string]interface{}{models.DefaultAccountIDsClaim: accountID},
+		},
+	})
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "Unauthorized", response.Error.Type)
+	assert.Equal(t, otherAccountID, response.Error.ErrorInfo["accountId"])
+	dynamoDBService.AssertNotCalled(t, "BatchDeleteLaborLines", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_TenantIsolation_MissingAccountID(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), models.AppSyncEvent{
+		Info:      models.AppSyncInfo{FieldName: "getRateCard"},
+		Arguments: map[string]interface{}{"input": map[string]interface{}{}},
+		Identity: map[string]interface{}{
+			"sub":    "user-123",
+			"claims": map[string]interface{}{models.DefaultAccountIDsClaim: "acct-1"},
+		},
+	})
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ValidationError", response.Error.Type)
+	assert.Equal(t, "accountId is required", response.Error.Message)
+}
+
+func TestLaborLineHandler_HandleAppSyncBatch_TenantIsolation(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	found := &models.LaborLine{LaborLineID: uuid.New().String(), AccountID: accountID, TaskID: taskID}
+
+	// Only the authorized get reaches the batch read
+	dynamoDBService.On("BatchGetLaborLines", mock.Anything, []models.GetLaborLineInput{
+		{AccountID: accountID, TaskID: taskID, LaborLineID: found.LaborLineID},
+	}).Return([]*models.LaborLine{found}, nil).Once()
+
+	foreign := getEvent(laborLineKeyInput(uuid.New().String(), taskID, uuid.New().String()))
+	foreign.Identity = map[string]interface{}{
+		"sub":    "user-123",
+		"claims": map[string]interface{}{models.DefaultAccountIDsClaim: accountID},
+	}
+
+	responses := handler.HandleAppSyncBatch(context.Background(), []models.AppSyncEvent{
+		getEvent(laborLineKeyInput(accountID, taskID, found.LaborLineID)),
+		foreign,
+	})
+
+	require.Len(t, responses, 2)
+	assert.Equal(t, found, responses[0].Data)
+	require.NotNil(t, responses[1].Error)
+	assert.Equal(t, "Unauthorized", responses[1].Error.Type)
+
+	dynamoDBService.AssertExpectations(t)
+}
@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// handleStartTimer processes requests to clock a technician in on a labor line.
+func (h *LaborLineHandler) handleStartTimer(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.StartLaborTimerInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	// Technicians log their own time unless the policy lets the caller name another
+	if input.TechnicianID == "" {
+		input.TechnicianID = event.Actor()
+	}
+
+	// Validate input
+	if err := h.validationService.ValidateStartTimerInput(input); err != nil {
+		return validationErrorResponse(err), nil
+	}
+
+	startTime := time.Now().Unix()
+	if input.StartTime != nil {
+		startTime = *input.StartTime
+	}
+
+	// A running entry overlaps any later entry, so a technician cannot start two timers
+	entry := models.NewTimeEntry(input.AccountID, input.TaskID, input.LaborLineID, input.TechnicianID, startTime)
+	laborLine, err := h.dynamoDBService.SaveTimeEntry(ctx, entry, event.Actor())
+	if err != nil {
+		return writeErrorResponse(err, "failed to start labor timer"), nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: &models.TimeEntryResult{
+			TimeEntry: entry,
+			LaborLine: laborLine,
+		},
+	}, nil
+}
+
+// handleStopTimer processes requests to clock a technician out of a labor line.
+func (h *LaborLineHandler) handleStopTimer(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.StopLaborTimerInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	// Technicians log their own time unless the policy lets the caller name another
+	if input.TechnicianID == "" {
+		input.TechnicianID = event.Actor()
+	}
+
+	// Validate input
+	if err := h.validationService.ValidateStopTimerInput(input); err != nil {
+		return validationErrorResponse(err), nil
+	}
+
+	// Find the technician's running entry on this labor line
+	entries, err := h.dynamoDBService.ListTimeEntries(ctx, models.GetLaborLineInput{
+		AccountID:   input.AccountID,
+		TaskID:      input.TaskID,
+		LaborLineID: input.LaborLineID,
+	})
+	if err != nil {
+		return writeErrorResponse(err, "failed to stop labor timer"), nil
+	}
+
+	var running *models.TimeEntry
+	for _, entry := range entries {
+		if entry.TechnicianID == input.TechnicianID && entry.IsRunning() {
+			running = entry
+			break
+		}
+	}
+	if running == nil {
+		return writeErrorResponse(services.ErrTimerNotRunning, "failed to stop labor timer"), nil
+	}
+
+	endTime := time.Now().Unix()
+	if input.EndTime != nil {
+		endTime = *input.EndTime
+	}
+	if err := services.ValidateTimeRange(running.StartTime, endTime, input.BreakMinutes); err != nil {
+		return validationErrorResponse(err), nil
+	}
+
+	running.Stop(endTime, input.BreakMinutes)
+	laborLine, err := h.dynamoDBService.SaveTimeEntry(ctx, running, event.Actor())
+	if err != nil {
+		return writeErrorResponse(err, "failed to stop labor timer"), nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: &models.TimeEntryResult{
+			TimeEntry: running,
+			LaborLine: laborLine,
+		},
+	}, nil
+}
+
+// handleAddTimeEntry processes requests to record a completed time entry.
+func (h *LaborLineHandler) handleAddTimeEntry(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+	var input models.AddTimeEntryInput
+	if err := event.GetInputArgument(&input); err != nil {
+		return &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: fmt.Sprintf("invalid input: %v", err),
+				Type:    "ValidationError",
+			},
+		}, nil
+	}
+
+	// Technicians log their own time unless the policy lets the caller name another
+	if input.TechnicianID == "" {
+		input.TechnicianID = event.Actor()
+	}
+
+	// Validate input
+	if err := h.validationService.ValidateAddTimeEntryInput(input); err != nil {
+		return validationErrorResponse(err), nil
+	}
+
+	entry := input.ToTimeEntry()
+	laborLine, err := h.dynamoDBService.SaveTimeEntry(ctx, entry, event.Actor())
+	if err != nil {
+		return writeErrorResponse(err, "failed to add time entry"), nil
+	}
+
+	return &models.AppSyncResponse{
+		Data: &models.TimeEntryResult{
+			TimeEntry: entry,
+			LaborLine: laborLine,
+		},
+	}, nil
+}
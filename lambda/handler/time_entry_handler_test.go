@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+func TestLaborLineHandler_HandleAppSyncEvent_StartLaborTimer(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "startLaborTimer",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"accountId":    accountID,
+				"taskId":       taskID,
+				"laborLineId":  laborLineID,
+				"technicianId": "tech-1",
+				"startTime":    1700000000,
+			},
+		},
+	}
+
+	laborLine := &models.LaborLine{LaborLineID: laborLineID, AccountID: accountID, TaskID: taskID}
+
+	validationService.On("ValidateStartTimerInput", mock.Anything).Return(nil)
+	dynamoDBService.On("SaveTimeEntry", mock.Anything, mock.MatchedBy(func(e *models.TimeEntry) bool {
+		return e.TechnicianID == "tech-1" && e.StartTime == 1700000000 && e.IsRunning()
+	}), mock.Anything).Return(laborLine, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	require.NotNil(t, response)
+	assert.Nil(t, response.Error)
+
+	result, ok := response.Data.(*models.TimeEntryResult)
+	require.True(t, ok)
+	assert.Equal(t, laborLine, result.LaborLine)
+	assert.Equal(t, laborLineID, result.TimeEntry.LaborLineID)
+
+	dynamoDBService.AssertExpectations(t)
+	validationService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_StartLaborTimer_DefaultsToCaller(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "startLaborTimer",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"accountId":   uuid.New().String(),
+				"taskId":      uuid.New().String(),
+				"laborLineId": uuid.New().String(),
+			},
+		},
+		Identity: map[string]interface{}{"sub": "user-123"},
+	}
+
+	// Without a technicianId the caller clocks themselves in
+	validationService.On("ValidateStartTimerInput", mock.MatchedBy(func(input models.StartLaborTimerInput) bool {
+		return input.TechnicianID == "user-123"
+	})).Return(nil)
+	dynamoDBService.On("SaveTimeEntry", mock.Anything, mock.MatchedBy(func(e *models.TimeEntry) bool {
+		return e.TechnicianID == "user-123"
+	}), "user-123").Return(&models.LaborLine{}, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	assert.Nil(t, response.Error)
+
+	dynamoDBService.AssertExpectations(t)
+	validationService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_StartLaborTimer_Overlap(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "startLaborTimer",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"accountId":    uuid.New().String(),
+				"taskId":       uuid.New().String(),
+				"laborLineId":  uuid.New().String(),
+				"technicianId": "tech-1",
+			},
+		},
+	}
+
+	validationService.On("ValidateStartTimerInput", mock.Anything).Return(nil)
+	dynamoDBService.On("SaveTimeEntry", mock.Anything, mock.Anything, mock.Anything).Return((*models.LaborLine)(nil), services.ErrTimeEntryOverlap)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, "ValidationError", response.Error.Type)
+
+	dynamoDBService.AssertExpectations(t)
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_StopLaborTimer(t *testing.T) {
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	newEvent := func(technicianID string) models.AppSyncEvent {
+		return authorized(models.AppSyncEvent{
+			Info: models.AppSyncInfo{
+				FieldName: "stopLaborTimer",
+			},
+			Arguments: map[string]interface{}{
+				"input": map[string]interface{}{
+					"accountId":    accountID,
+					"taskId":       taskID,
+					"laborLineId":  laborLineID,
+					"technicianId": technicianID,
+					"endTime":      1700003600,
+					"breakMinutes": 10,
+				},
+			},
+		})
+	}
+
+	t.Run("Stops the technician's running entry", func(t *testing.T) {
+		dynamoDBService := &MockDynamoDBService{}
+		validationService := &MockValidationService{}
+		handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+		otherTech := models.NewTimeEntry(accountID, taskID, laborLineID, "tech-2", 1700000000)
+		running := models.NewTimeEntry(accountID, taskID, laborLineID, "tech-1", 1700000000)
+		laborLine := &models.LaborLine{LaborLineID: laborLineID, ActualHours: models.MustParseDecimal("0.83")}
+
+		validationService.On("ValidateStopTimerInput", mock.Anything).Return(nil)
+		dynamoDBService.On("ListTimeEntries", mock.Anything, models.GetLaborLineInput{
+			AccountID:   accountID,
+			TaskID:      taskID,
+			LaborLineID: laborLineID,
+		}).Return([]*models.TimeEntry{otherTech, running}, nil)
+		dynamoDBService.On("SaveTimeEntry", mock.Anything, mock.MatchedBy(func(e *models.TimeEntry) bool {
+			return e.TimeEntryID == running.TimeEntryID && !e.IsRunning() && e.DurationMinutes == 50
+		}), mock.Anything).Return(laborLine, nil)
+
+		response, err := handler.HandleAppSyncEvent(context.Background(), newEvent("tech-1"))
+
+		require.NoError(t, err)
+		assert.Nil(t, response.Error)
+		result, ok := response.Data.(*models.TimeEntryResult)
+		require.True(t, ok)
+		assert.Equal(t, running.TimeEntryID, result.TimeEntry.TimeEntryID)
+		assert.Equal(t, laborLine, result.LaborLine)
+
+		dynamoDBService.AssertExpectations(t)
+	})
+
+	t.Run("No running timer", func(t *testing.T) {
+		dynamoDBService := &MockDynamoDBService{}
+		validationService := &MockValidationService{}
+		handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+		validationService.On("ValidateStopTimerInput", mock.Anything).Return(nil)
+		dynamoDBService.On("ListTimeEntries", mock.Anything, mock.Anything).Return([]*models.TimeEntry{}, nil)
+
+		response, err := handler.HandleAppSyncEvent(context.Background(), newEvent("tech-3"))
+
+		require.NoError(t, err)
+		require.NotNil(t, response.Error)
+		assert.Equal(t, "NotFound", response.Error.Type)
+
+		dynamoDBService.AssertExpectations(t)
+	})
+}
+
+func TestLaborLineHandler_HandleAppSyncEvent_AddTimeEntry(t *testing.T) {
+	dynamoDBService := &MockDynamoDBService{}
+	validationService := &MockValidationService{}
+	handler := NewLaborLineHandler(dynamoDBService, validationService)
+
+	laborLineID := uuid.New().String()
+	event := models.AppSyncEvent{
+		Info: models.AppSyncInfo{
+			FieldName: "addTimeEntry",
+		},
+		Arguments: map[string]interface{}{
+			"input": map[string]interface{}{
+				"accountId":    uuid.New().String(),
+				"taskId":       uuid.New().String(),
+				"laborLineId":  laborLineID,
+				"technicianId": "tech-1",
+				"startTime":    1700000000,
+				"endTime":      1700007200,
+			},
+		},
+	}
+
+	validationService.On("ValidateAddTimeEntryInput", mock.Anything).Return(nil)
+	dynamoDBService.On("SaveTimeEntry", mock.Anything, mock.MatchedBy(func(e *models.TimeEntry) bool {
+		return e.DurationMinutes == 120
+	}), mock.Anything).Return(&models.LaborLine{LaborLineID: laborLineID}, nil)
+
+	response, err := handler.HandleAppSyncEvent(context.Background(), authorized(event))
+
+	require.NoError(t, err)
+	assert.Nil(t, response.Error)
+	_, ok := response.Data.(*models.TimeEntryResult)
+	assert.True(t, ok)
+
+	dynamoDBService.AssertExpectations(t)
+	validationService.AssertExpectations(t)
+}
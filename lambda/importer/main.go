@@ -0,0 +1,153 @@
+// Package main contains the entry point of the labor line importer. Deployed
+// as a Lambda function it imports the files uploaded to the imports bucket;
+// run anywhere else it is a command that imports a local file:
+//
+//	DYNAMODB_TABLE_NAME=labor-lines importer -account <accountId> -file lines.csv [-dry-run]
+//
+// The command prints the import report as JSON and exits with status 1 if any
+// row was not imported. If EVENT_BUS_NAME is set, a LaborLineCreated event is
+// published for every imported labor line, as for those created through the API.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"steverhoton-labor-lines/lambda/handler"
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// ImportLambdaHandler is the import Lambda function handler.
+func ImportLambdaHandler(ctx context.Context, event events.S3Event) error {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	importer, err := newImporter(cfg, services.DefaultImportConcurrency)
+	if err != nil {
+		return err
+	}
+
+	importHandler := handler.NewImportHandler(importer, services.NewS3ImportStore(s3.NewFromConfig(cfg)))
+	return importHandler.HandleS3Event(ctx, event)
+}
+
+// newImporter creates the importer from the environment.
+func newImporter(cfg aws.Config, concurrency int) (*services.LaborLineImporter, error) {
+	tableName := os.Getenv("DYNAMODB_TABLE_NAME")
+	if tableName == "" {
+		return nil, errors.New("DYNAMODB_TABLE_NAME environment variable not set")
+	}
+
+	validationService, err := services.NewValidationServiceWithEmbeddedSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create validation service: %w", err)
+	}
+
+	// Imported labor lines are announced, and kept in the outbox, like any other write
+	var serviceOpts []services.DynamoDBServiceOption
+	if eventBusName := os.Getenv("EVENT_BUS_NAME"); eventBusName != "" {
+		publisher := services.NewEventBridgePublisher(eventbridge.NewFromConfig(cfg), eventBusName)
+		serviceOpts = append(serviceOpts, services.WithEventPublisher(publisher))
+	}
+
+	dynamoDBService := services.NewDynamoDBService(dynamodb.NewFromConfig(cfg), tableName, serviceOpts...)
+	return services.NewLaborLineImporter(dynamoDBService, validationService, services.WithImportConcurrency(concurrency)), nil
+}
+
+// runCommand imports a local file, or standard input, and prints the report.
+func runCommand(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer) (bool, error) {
+	flags := flag.NewFlagSet("importer", flag.ContinueOnError)
+	file := flags.String("file", "-", "file to import, or - for standard input")
+	accountID := flags.String("account", "", "account to import the labor lines into (required)")
+	format := flags.String("format", "", "CSV or NDJSON; defaults from the file extension, or CSV")
+	dryRun := flags.Bool("dry-run", false, "validate the rows without writing them")
+	concurrency := flags.Int("concurrency", services.DefaultImportConcurrency, "batches to write at once")
+	actor := flags.String("actor", models.DefaultImportActor, "recorded as the creator of the labor lines")
+	if err := flags.Parse(args); err != nil {
+		return false, err
+	}
+	if *accountID == "" {
+		return false, errors.New("-account is required")
+	}
+
+	input := models.ImportLaborLinesInput{
+		AccountID: *accountID,
+		Source:    *file,
+		Format:    models.ImportFormat(*format),
+		DryRun:    *dryRun,
+		Actor:     *actor,
+	}
+	if input.Format == "" {
+		input.Format, _ = models.ImportFormatOf(*file)
+	}
+	if input.Format != "" && !input.Format.IsValid() {
+		return false, fmt.Errorf("invalid format: %s", input.Format)
+	}
+
+	body := stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return false, err
+		}
+		defer f.Close()
+		body = f
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	importer, err := newImporter(cfg, *concurrency)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := importer.Import(ctx, body, input)
+	if err != nil {
+		return false, err
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return false, err
+	}
+	return result.FailedCount == 0, nil
+}
+
+func main() {
+	// The Lambda runtime sets AWS_LAMBDA_RUNTIME_API for the functions it runs
+	if os.Getenv("AWS_LAMBDA_RUNTIME_API") != "" {
+		lambda.Start(ImportLambdaHandler)
+		return
+	}
+
+	ok, err := runCommand(context.Background(), os.Args[1:], os.Stdin, os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
@@ -2,63 +2,270 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 
 	"steverhoton-labor-lines/lambda/handler"
 	"steverhoton-labor-lines/lambda/models"
 	"steverhoton-labor-lines/lambda/services"
 )
 
-// LambdaHandler is the main Lambda function handler.
-func LambdaHandler(ctx context.Context, event models.AppSyncEvent) (*models.AppSyncResponse, error) {
+// outboxRelayMinAge is how old an outbox entry must be before the scheduled
+// relay publishes it, leaving the write that added it time to publish it.
+const outboxRelayMinAge = time.Minute
+
+// LambdaHandler is the main Lambda function handler. AppSync sends a single
+// event, or an array of events when batching is enabled on the resolver, in
+// which case one response is returned per event in the same order. The
+// EventBridge schedule that relays the outbox also invokes it.
+func LambdaHandler(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	if isScheduledEvent(payload) {
+		return relayOutbox(ctx)
+	}
+
+	if isBatchPayload(payload) {
+		var events []models.AppSyncEvent
+		if err := json.Unmarshal(payload, &events); err != nil {
+			return nil, fmt.Errorf("decoding AppSync batch: %w", err)
+		}
+
+		laborLineHandler, errResponse := newLaborLineHandler(ctx)
+		if errResponse != nil {
+			responses := make([]*models.AppSyncResponse, len(events))
+			for i := range responses {
+				responses[i] = errResponse
+			}
+			return responses, nil
+		}
+
+		return laborLineHandler.HandleAppSyncBatch(ctx, events), nil
+	}
+
+	var event models.AppSyncEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("decoding AppSync event: %w", err)
+	}
+
+	laborLineHandler, errResponse := newLaborLineHandler(ctx)
+	if errResponse != nil {
+		return errResponse, nil
+	}
+
+	// Process the event
+	return laborLineHandler.HandleAppSyncEvent(ctx, event)
+}
+
+// isBatchPayload reports whether the invocation payload is an array of events.
+func isBatchPayload(payload json.RawMessage) bool {
+	trimmed := bytes.TrimLeft(payload, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// isScheduledEvent reports whether the invocation payload is an EventBridge
+// scheduled event. AppSync events also have a source, but it is the parent
+// object of the field being resolved.
+func isScheduledEvent(payload json.RawMessage) bool {
+	var event struct {
+		Source     json.RawMessage `json:"source"`
+		DetailType string          `json:"detail-type"`
+	}
+	if isBatchPayload(payload) || json.Unmarshal(payload, &event) != nil {
+		return false
+	}
+	return string(event.Source) == `"aws.events"` && event.DetailType == "Scheduled Event"
+}
+
+// relayOutbox publishes the labor line events whose publish failed when they
+// were written.
+func relayOutbox(ctx context.Context) (interface{}, error) {
+	dynamoDBService, _, errResponse := newServices(ctx)
+	if errResponse != nil {
+		return nil, errors.New(errResponse.Error.Message)
+	}
+
+	published, err := dynamoDBService.RelayOutbox(ctx, outboxRelayMinAge)
+	log.Printf("Relayed %d labor line events from the outbox", published)
+	if err != nil {
+		return nil, fmt.Errorf("relaying outbox: %w", err)
+	}
+
+	return map[string]interface{}{"published": published}, nil
+}
+
+// newLaborLineHandler creates the labor line handler from the environment. If
+// the configuration is invalid it returns the error response to send instead.
+func newLaborLineHandler(ctx context.Context) (*handler.LaborLineHandler, *models.AppSyncResponse) {
+	dynamoDBService, validationService, errResponse := newServices(ctx)
+	if errResponse != nil {
+		return nil, errResponse
+	}
+
+	// Callers are limited to the accounts listed in this identity claim
+	var handlerOpts []handler.LaborLineHandlerOption
+	if claim := os.Getenv("ACCOUNT_IDS_CLAIM"); claim != "" {
+		handlerOpts = append(handlerOpts, handler.WithAccountIDsClaim(claim))
+	}
+
+	// IAM callers are refused unless they sign with one of these roles
+	if roles := os.Getenv("TRUSTED_IAM_ROLE_ARNS"); roles != "" {
+		roleARNs := strings.Split(roles, ",")
+		for i, arn := range roleARNs {
+			roleARNs[i] = strings.TrimSpace(arn)
+			if models.IAMRoleARN(roleARNs[i]) == "" {
+				return nil, &models.AppSyncResponse{
+					Error: &models.AppSyncError{
+						Message: fmt.Sprintf("invalid trusted IAM role ARN: %q", arn),
+						Type:    "ConfigurationError",
+					},
+				}
+			}
+		}
+		handlerOpts = append(handlerOpts, handler.WithTrustedIAMRoles(roleARNs...))
+	}
+
+	// Parts are checked against a catalog file when one is supplied for local
+	// runs; their reservations are kept in the table
+	if path := os.Getenv("PARTS_CATALOG_FILE"); path != "" {
+		catalog, err := newPartsCatalog(ctx, path)
+		if err != nil {
+			return nil, &models.AppSyncResponse{
+				Error: &models.AppSyncError{
+					Message: fmt.Sprintf("failed to load parts catalog: %v", err),
+					Type:    "ConfigurationError",
+				},
+			}
+		}
+		handlerOpts = append(handlerOpts, handler.WithPartsCatalog(catalog))
+	}
+
+	// Labor lines can be exported when a bucket to keep the exports in is configured
+	if bucket := os.Getenv("EXPORT_BUCKET_NAME"); bucket != "" {
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, &models.AppSyncResponse{
+				Error: &models.AppSyncError{
+					Message: fmt.Sprintf("failed to load AWS config: %v", err),
+					Type:    "ConfigurationError",
+				},
+			}
+		}
+		s3Client := s3.NewFromConfig(cfg)
+		store := services.NewS3ExportStore(s3Client, s3.NewPresignClient(s3Client), bucket)
+		handlerOpts = append(handlerOpts, handler.WithLaborLineExporter(services.NewLaborLineExporter(dynamoDBService, store)))
+	}
+
+	// Create handler
+	return handler.NewLaborLineHandler(dynamoDBService, validationService, handlerOpts...), nil
+}
+
+// newPartsCatalog creates a parts catalog holding the parts listed in a JSON
+// file, which keeps its reservations in the labor lines table.
+func newPartsCatalog(ctx context.Context, path string) (services.PartsCatalog, error) {
+	parts, err := services.LoadMemoryPartsCatalog(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return services.NewDynamoDBPartsCatalog(dynamodb.NewFromConfig(cfg), os.Getenv("DYNAMODB_TABLE_NAME"), parts), nil
+}
+
+// newServices creates the DynamoDB and validation services from the
+// environment. If the configuration is invalid it returns the error response
+// to send instead.
+func newServices(ctx context.Context) (services.DynamoDBService, services.ValidationService, *models.AppSyncResponse) {
 	// Get table name from environment variable
 	tableName := os.Getenv("DYNAMODB_TABLE_NAME")
 	if tableName == "" {
-		return &models.AppSyncResponse{
+		return nil, nil, &models.AppSyncResponse{
 			Error: &models.AppSyncError{
 				Message: "DYNAMODB_TABLE_NAME environment variable not set",
 				Type:    "ConfigurationError",
 			},
-		}, nil
+		}
+	}
+
+	// Pagination tokens must verify across Lambda instances, so the signing key is required
+	pageTokenSecret := os.Getenv("PAGINATION_TOKEN_SECRET")
+	if pageTokenSecret == "" {
+		return nil, nil, &models.AppSyncResponse{
+			Error: &models.AppSyncError{
+				Message: "PAGINATION_TOKEN_SECRET environment variable not set",
+				Type:    "ConfigurationError",
+			},
+		}
+	}
+
+	// Soft-deleted labor lines are kept indefinitely unless a retention period is set
+	var deletedRetention time.Duration
+	if days := os.Getenv("DELETED_RETENTION_DAYS"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return nil, nil, &models.AppSyncResponse{
+				Error: &models.AppSyncError{
+					Message: fmt.Sprintf("invalid DELETED_RETENTION_DAYS: %q", days),
+					Type:    "ConfigurationError",
+				},
+			}
+		}
+		deletedRetention = time.Duration(n) * 24 * time.Hour
 	}
 
 	// Initialize AWS config
 	cfg, err := config.LoadDefaultConfig(ctx)
 	if err != nil {
-		return &models.AppSyncResponse{
+		return nil, nil, &models.AppSyncResponse{
 			Error: &models.AppSyncError{
 				Message: fmt.Sprintf("failed to load AWS config: %v", err),
 				Type:    "ConfigurationError",
 			},
-		}, nil
+		}
 	}
 
 	// Create DynamoDB client
 	dynamoClient := dynamodb.NewFromConfig(cfg)
 
 	// Create services
-	dynamoDBService := services.NewDynamoDBService(dynamoClient, tableName)
 	validationService, err := services.NewValidationServiceWithEmbeddedSchema()
 	if err != nil {
-		return &models.AppSyncResponse{
+		return nil, nil, &models.AppSyncResponse{
 			Error: &models.AppSyncError{
 				Message: fmt.Sprintf("failed to create validation service: %v", err),
 				Type:    "InternalError",
 			},
-		}, nil
+		}
+	}
+	serviceOpts := []services.DynamoDBServiceOption{
+		services.WithPageTokenSecret([]byte(pageTokenSecret)),
+		services.WithDeletedRetention(deletedRetention),
+		services.WithLaborLineValidator(validationService),
 	}
 
-	// Create handler
-	laborLineHandler := handler.NewLaborLineHandler(dynamoDBService, validationService)
+	// Labor line changes are published as events when an event bus is configured
+	if eventBusName := os.Getenv("EVENT_BUS_NAME"); eventBusName != "" {
+		publisher := services.NewEventBridgePublisher(eventbridge.NewFromConfig(cfg), eventBusName)
+		serviceOpts = append(serviceOpts, services.WithEventPublisher(publisher))
+	}
 
-	// Process the event
-	return laborLineHandler.HandleAppSyncEvent(ctx, event)
+	return services.NewDynamoDBService(dynamoClient, tableName, serviceOpts...), validationService, nil
 }
 
 func main() {
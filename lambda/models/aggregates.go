@@ -0,0 +1,142 @@
+package models
+
+// RecordTypeStatusCount identifies the items counting an account's labor lines in each status.
+const RecordTypeStatusCount = "STATUS_COUNT"
+
+// RecordTypeAggregateMarker identifies the items recording which version of a
+// labor line the aggregates include.
+const RecordTypeAggregateMarker = "AGGREGATE_MARKER"
+
+// Sort key prefixes of the aggregate items in an account's partition. They
+// are kept outside the labor lines' key space so purging a labor line leaves
+// them in place.
+const (
+	statusCountSKPrefix     = "COUNT#"
+	aggregateMarkerSKPrefix = "AGGREGATED#"
+)
+
+// LaborLineTotals are the amounts a set of labor lines adds up to.
+type LaborLineTotals struct {
+	LaborLineCount int64   `json:"laborLineCount" dynamodbav:"laborLineCount"`
+	EstimatedHours Decimal `json:"estimatedHours" dynamodbav:"estimatedHours"`
+	ActualHours    Decimal `json:"actualHours" dynamodbav:"actualHours"`
+	LaborCost      Decimal `json:"laborCost" dynamodbav:"laborCost"`
+}
+
+// TotalsOf returns what a labor line adds to its task's totals. Soft-deleted
+// labor lines, and a labor line that does not exist (nil), add nothing.
+func TotalsOf(laborLine *LaborLine) LaborLineTotals {
+	if laborLine == nil || laborLine.DeletedAt != nil {
+		return LaborLineTotals{}
+	}
+	return LaborLineTotals{
+		LaborLineCount: 1,
+		EstimatedHours: laborLine.EstimatedHours,
+		ActualHours:    laborLine.ActualHours,
+		LaborCost:      laborLine.LaborCost,
+	}
+}
+
+// Add returns the sum of the totals and other.
+func (t LaborLineTotals) Add(other LaborLineTotals) LaborLineTotals {
+	return LaborLineTotals{
+		LaborLineCount: t.LaborLineCount + other.LaborLineCount,
+		EstimatedHours: t.EstimatedHours.Add(other.EstimatedHours),
+		ActualHours:    t.ActualHours.Add(other.ActualHours),
+		LaborCost:      t.LaborCost.Add(other.LaborCost),
+	}
+}
+
+// Sub returns the difference between the totals and other.
+func (t LaborLineTotals) Sub(other LaborLineTotals) LaborLineTotals {
+	return LaborLineTotals{
+		LaborLineCount: t.LaborLineCount - other.LaborLineCount,
+		EstimatedHours: t.EstimatedHours.Sub(other.EstimatedHours),
+		ActualHours:    t.ActualHours.Sub(other.ActualHours),
+		LaborCost:      t.LaborCost.Sub(other.LaborCost),
+	}
+}
+
+// IsZero reports whether every total is zero.
+func (t LaborLineTotals) IsZero() bool {
+	return t.LaborLineCount == 0 && t.EstimatedHours.IsZero() && t.ActualHours.IsZero() && t.LaborCost.IsZero()
+}
+
+// amounts returns the totals by attribute name.
+func (t LaborLineTotals) amounts() map[string]Decimal {
+	return map[string]Decimal{
+		"laborLineCount": NewDecimalFromInt(t.LaborLineCount),
+		"estimatedHours": t.EstimatedHours,
+		"actualHours":    t.ActualHours,
+		"laborCost":      t.LaborCost,
+	}
+}
+
+// setAmount sets the total with the given attribute name, if there is one.
+func (t *LaborLineTotals) setAmount(name string, amount Decimal) {
+	switch name {
+	case "laborLineCount":
+		t.LaborLineCount = amount.IntPart()
+	case "estimatedHours":
+		t.EstimatedHours = amount
+	case "actualHours":
+		t.ActualHours = amount
+	case "laborCost":
+		t.LaborCost = amount
+	}
+}
+
+// TaskLaborTotals holds the totals of a task's labor lines that are not
+// deleted, as announced when a change moves them. They are read from the
+// task's summary.
+type TaskLaborTotals struct {
+	AccountID string `json:"accountId"`
+	TaskID    string `json:"taskId"`
+	LaborLineTotals
+	UpdatedAt int64 `json:"updatedAt"`
+}
+
+// LaborLineStatusCount holds how many of an account's labor lines that are
+// not deleted are in a status. It is derived from the table's stream.
+type LaborLineStatusCount struct {
+	AccountID string          `json:"accountId" dynamodbav:"accountId"`
+	Status    LaborLineStatus `json:"status" dynamodbav:"status"`
+	Count     int64           `json:"count" dynamodbav:"count"`
+	UpdatedAt int64           `json:"updatedAt" dynamodbav:"updatedAt"`
+
+	// DynamoDB keys
+	RecordType string `json:"-" dynamodbav:"recordType"`
+	PK         string `json:"-" dynamodbav:"PK"` // accountId
+	SK         string `json:"-" dynamodbav:"SK"` // COUNT#{status}
+}
+
+// StatusCountChanges returns how a change from before to after moves an
+// account's labor line counts, by status. Statuses whose count does not change
+// are left out.
+func StatusCountChanges(before, after *LaborLine) map[LaborLineStatus]int64 {
+	changes := map[LaborLineStatus]int64{}
+	if before != nil && before.DeletedAt == nil {
+		changes[before.Status]--
+	}
+	if after != nil && after.DeletedAt == nil {
+		changes[after.Status]++
+	}
+	for status, change := range changes {
+		if change == 0 {
+			delete(changes, status)
+		}
+	}
+	return changes
+}
+
+// StatusCountKey returns the partition and sort key of an account's count of
+// labor lines in a status.
+func StatusCountKey(accountID string, status LaborLineStatus) (string, string) {
+	return accountID, statusCountSKPrefix + string(status)
+}
+
+// AggregateMarkerKey returns the partition and sort key of the item recording
+// which version of a labor line the aggregates include.
+func AggregateMarkerKey(accountID, taskID, laborLineID string) (string, string) {
+	return accountID, aggregateMarkerSKPrefix + taskID + "#" + laborLineID
+}
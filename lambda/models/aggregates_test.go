@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTotalsOf(t *testing.T) {
+	laborLine := &LaborLine{
+		EstimatedHours: MustParseDecimal("1.5"),
+		ActualHours:    MustParseDecimal("2"),
+		LaborCost:      MustParseDecimal("240"),
+	}
+
+	totals := TotalsOf(laborLine)
+	assert.Equal(t, int64(1), totals.LaborLineCount)
+	assert.Equal(t, "240.00", totals.LaborCost.StringFixed(2))
+
+	// Missing and soft-deleted labor lines add nothing
+	assert.True(t, TotalsOf(nil).IsZero())
+	deletedAt := int64(1760000000)
+	deleted := *laborLine
+	deleted.DeletedAt = &deletedAt
+	assert.True(t, TotalsOf(&deleted).IsZero())
+
+	// Deleting the labor line takes all of it back off the totals
+	change := TotalsOf(&deleted).Sub(totals)
+	assert.Equal(t, int64(-1), change.LaborLineCount)
+	assert.Equal(t, "-1.50", change.EstimatedHours.StringFixed(2))
+	assert.False(t, change.IsZero())
+}
+
+func TestStatusCountChanges(t *testing.T) {
+	pending := &LaborLine{Status: StatusPending}
+	inProgress := &LaborLine{Status: StatusInProgress}
+	deletedAt := int64(1760000000)
+	deleted := &LaborLine{Status: StatusInProgress, DeletedAt: &deletedAt}
+
+	tests := []struct {
+		name          string
+		before, after *LaborLine
+		expected      map[LaborLineStatus]int64
+	}{
+		{"created", nil, pending, map[LaborLineStatus]int64{StatusPending: 1}},
+		{"status changed", pending, inProgress, map[LaborLineStatus]int64{StatusPending: -1, StatusInProgress: 1}},
+		{"status unchanged", inProgress, inProgress, map[LaborLineStatus]int64{}},
+		{"soft deleted", inProgress, deleted, map[LaborLineStatus]int64{StatusInProgress: -1}},
+		{"removed after soft delete", deleted, nil, map[LaborLineStatus]int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, StatusCountChanges(tt.before, tt.after))
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package models
 
-import "encoding/json"
+import (
+	"encoding/json"
+)
 
 // AppSyncEvent represents the structure of an AWS AppSync event.
 type AppSyncEvent struct {
@@ -41,6 +43,20 @@ type AppSyncError struct {
 	ErrorInfo map[string]interface{} `json:"errorInfo,omitempty"`
 }
 
+// anonymousActor identifies callers without an identity, such as API key requests.
+const anonymousActor = "anonymous"
+
+// CallerIdentity parses the event's identity into a typed Identity.
+func (e *AppSyncEvent) CallerIdentity() Identity {
+	return ParseIdentity(e.Identity)
+}
+
+// Actor returns an identifier for the caller: the Cognito or OIDC subject,
+// the username, or the IAM user ARN, in that order of preference.
+func (e *AppSyncEvent) Actor() string {
+	return e.CallerIdentity().Actor()
+}
+
 // GetArgumentAs extracts and unmarshals an argument from the AppSync event.
 func (e *AppSyncEvent) GetArgumentAs(key string, target interface{}) error {
 	if arg, exists := e.Arguments[key]; exists {
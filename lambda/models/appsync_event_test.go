@@ -84,7 +84,7 @@ func TestAppSyncEvent_GetInputArgument(t *testing.T) {
 			"input": map[string]interface{}{
 				"accountId": accountID,
 				"taskId":    taskID,
-				"notes":     []interface{}{"Test note"},
+				"notes":     []interface{}{map[string]interface{}{"body": "Test note", "visibility": "CUSTOMER"}},
 			},
 		},
 	}
@@ -95,7 +95,7 @@ func TestAppSyncEvent_GetInputArgument(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, accountID, input.AccountID)
 	assert.Equal(t, taskID, input.TaskID)
-	assert.Equal(t, []string{"Test note"}, input.Notes)
+	assert.Equal(t, []NoteInput{{Visibility: NoteVisibilityCustomer, Body: "Test note"}}, input.Notes)
 }
 
 func TestAppSyncEvent_GetInputArgument_NoInput(t *testing.T) {
@@ -111,3 +111,39 @@ func TestAppSyncEvent_GetInputArgument_NoInput(t *testing.T) {
 	assert.Empty(t, input.AccountID)
 	assert.Empty(t, input.TaskID)
 }
+
+func TestAppSyncEvent_Actor(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "Cognito subject",
+			identity: map[string]interface{}{"sub": "abc-123", "username": "jdoe"},
+			expected: "abc-123",
+		},
+		{
+			name:     "Username without subject",
+			identity: map[string]interface{}{"username": "jdoe"},
+			expected: "jdoe",
+		},
+		{
+			name:     "IAM user",
+			identity: map[string]interface{}{"userArn": "arn:aws:iam::123456789012:user/jdoe", "accountId": "123456789012"},
+			expected: "arn:aws:iam::123456789012:user/jdoe",
+		},
+		{
+			name:     "No identity",
+			identity: nil,
+			expected: "anonymous",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := AppSyncEvent{Identity: tt.identity}
+			assert.Equal(t, tt.expected, event.Actor())
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// RecordTypeAssignment identifies technician assignment items stored alongside labor lines.
+const RecordTypeAssignment = "ASSIGNMENT"
+
+// MaxAssignedTechnicians is the most technicians a labor line can be assigned to.
+const MaxAssignedTechnicians = 10
+
+// Assignment records that a technician is assigned to a labor line. There is
+// one item per technician, stored under the labor line's key space, which
+// also carries the TechnicianIndex key so each technician's queue can be
+// queried. The labor line's status is copied onto it and kept in step so that
+// queues can be filtered by status.
+type Assignment struct {
+	AccountID    string          `json:"accountId" dynamodbav:"accountId"`
+	TaskID       string          `json:"taskId" dynamodbav:"taskId"`
+	LaborLineID  string          `json:"laborLineId" dynamodbav:"laborLineId"`
+	TechnicianID string          `json:"technicianId" dynamodbav:"technicianId"`
+	Status       LaborLineStatus `json:"status" dynamodbav:"status"`
+	AssignedBy   string          `json:"assignedBy" dynamodbav:"assignedBy"`
+	AssignedAt   int64           `json:"assignedAt" dynamodbav:"assignedAt"` // Epoch seconds; TechnicianIndex sort key
+
+	// TechnicianKey is the TechnicianIndex partition key. Only assignments have
+	// it, which keeps the index sparse; time entries also have a technicianId
+	TechnicianKey string `json:"-" dynamodbav:"technicianKey"` // {accountId}#{technicianId}
+
+	// DynamoDB keys
+	RecordType string `json:"-" dynamodbav:"recordType"`
+	PK         string `json:"-" dynamodbav:"PK"` // accountId
+	SK         string `json:"-" dynamodbav:"SK"` // {taskId}#{laborLineId}#ASSIGN#{technicianId}
+}
+
+// LaborLineAssignmentInput represents the input for assigning a technician to
+// a labor line or unassigning them.
+type LaborLineAssignmentInput struct {
+	AccountID       string `json:"accountId"`
+	TaskID          string `json:"taskId"`
+	LaborLineID     string `json:"laborLineId"`
+	TechnicianID    string `json:"technicianId"`
+	ExpectedVersion *int64 `json:"expectedVersion,omitempty"` // Optional optimistic concurrency check
+}
+
+// ListLaborLinesByTechnicianInput represents the input for listing the labor
+// lines a technician is assigned to, most recently assigned first.
+type ListLaborLinesByTechnicianInput struct {
+	AccountID    string            `json:"accountId"`
+	TechnicianID string            `json:"technicianId"`
+	Statuses     []LaborLineStatus `json:"statuses,omitempty"`     // Optional filter by status
+	AssignedFrom *int64            `json:"assignedFrom,omitempty"` // Optional start of the assignment date range (epoch seconds, inclusive)
+	AssignedTo   *int64            `json:"assignedTo,omitempty"`   // Optional end of the assignment date range (epoch seconds, inclusive)
+	Limit        int32             `json:"limit,omitempty"`        // Optional page size
+	NextToken    string            `json:"nextToken,omitempty"`    // Opaque token from a previous page
+}
+
+// NewAssignment creates the assignment of a technician to a labor line, made by actor now.
+func NewAssignment(laborLine *LaborLine, technicianID, actor string) *Assignment {
+	pk, sk := AssignmentKey(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID, technicianID)
+
+	return &Assignment{
+		AccountID:     laborLine.AccountID,
+		TaskID:        laborLine.TaskID,
+		LaborLineID:   laborLine.LaborLineID,
+		TechnicianID:  technicianID,
+		Status:        laborLine.CurrentStatus(),
+		AssignedBy:    actor,
+		AssignedAt:    time.Now().Unix(),
+		TechnicianKey: TechnicianKey(laborLine.AccountID, technicianID),
+		RecordType:    RecordTypeAssignment,
+		PK:            pk,
+		SK:            sk,
+	}
+}
+
+// AssignmentKey returns the partition and sort key of a technician's assignment to a labor line.
+func AssignmentKey(accountID, taskID, laborLineID, technicianID string) (string, string) {
+	return accountID, taskID + "#" + laborLineID + "#ASSIGN#" + technicianID
+}
+
+// TechnicianKey returns the TechnicianIndex partition key of an account's technician.
+func TechnicianKey(accountID, technicianID string) string {
+	return accountID + "#" + technicianID
+}
+
+// IsAssigned reports whether the technician is assigned to the labor line.
+func (ll *LaborLine) IsAssigned(technicianID string) bool {
+	for _, assigned := range ll.AssignedTechnicianIDs {
+		if assigned == technicianID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAssignment(t *testing.T) {
+	laborLine := &LaborLine{
+		LaborLineID: "550e8400-e29b-41d4-a716-446655440000",
+		AccountID:   "acct-1",
+		TaskID:      "660e8400-e29b-41d4-a716-446655440000",
+		Status:      StatusOnHold,
+	}
+
+	assignment := NewAssignment(laborLine, "tech-1", "user-123")
+
+	assert.Equal(t, "acct-1", assignment.PK)
+	assert.Equal(t, "660e8400-e29b-41d4-a716-446655440000#550e8400-e29b-41d4-a716-446655440000#ASSIGN#tech-1", assignment.SK)
+	assert.Equal(t, "acct-1#tech-1", assignment.TechnicianKey)
+	assert.Equal(t, RecordTypeAssignment, assignment.RecordType)
+	assert.Equal(t, StatusOnHold, assignment.Status)
+	assert.Equal(t, "user-123", assignment.AssignedBy)
+	assert.NotZero(t, assignment.AssignedAt)
+}
+
+func TestLaborLine_IsAssigned(t *testing.T) {
+	laborLine := &LaborLine{AssignedTechnicianIDs: []string{"tech-1", "tech-2"}}
+
+	assert.True(t, laborLine.IsAssigned("tech-2"))
+	assert.False(t, laborLine.IsAssigned("tech-3"))
+	assert.False(t, (&LaborLine{}).IsAssigned("tech-1"))
+}
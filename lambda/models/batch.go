@@ -0,0 +1,68 @@
+package models
+
+// MaxBatchSize is the most labor lines a single batch mutation may contain.
+// An all-or-nothing batch writes each labor line, its history record, its
+// outbox entry and the summary of its task in one transaction, so this keeps
+// it within DynamoDB's 100 item transaction limit.
+const MaxBatchSize = 25
+
+// BatchMode selects how a batch mutation handles items that cannot be written.
+type BatchMode string
+
+const (
+	// BatchModeAtomic writes every item or none of them.
+	BatchModeAtomic BatchMode = "ATOMIC"
+	// BatchModeBestEffort writes every item it can and reports the rest.
+	BatchModeBestEffort BatchMode = "BEST_EFFORT"
+)
+
+// DefaultBatchMode is used when a batch mutation does not specify a mode.
+const DefaultBatchMode = BatchModeAtomic
+
+// IsValid returns true if the batch mode is one of the known modes.
+func (m BatchMode) IsValid() bool {
+	return m == BatchModeAtomic || m == BatchModeBestEffort
+}
+
+// OrDefault returns the batch mode, or DefaultBatchMode if it is unset.
+func (m BatchMode) OrDefault() BatchMode {
+	if m == "" {
+		return DefaultBatchMode
+	}
+	return m
+}
+
+// BatchCreateLaborLinesInput represents the input for creating several labor lines at once.
+type BatchCreateLaborLinesInput struct {
+	Mode  BatchMode              `json:"mode,omitempty"` // Defaults to DefaultBatchMode
+	Items []CreateLaborLineInput `json:"items"`
+}
+
+// BatchUpdateLaborLinesInput represents the input for updating several labor lines at once.
+type BatchUpdateLaborLinesInput struct {
+	Mode  BatchMode              `json:"mode,omitempty"` // Defaults to DefaultBatchMode
+	Items []UpdateLaborLineInput `json:"items"`
+}
+
+// BatchDeleteLaborLinesInput represents the input for deleting several labor lines at once.
+type BatchDeleteLaborLinesInput struct {
+	Mode  BatchMode              `json:"mode,omitempty"` // Defaults to DefaultBatchMode
+	Items []DeleteLaborLineInput `json:"items"`
+}
+
+// BatchItemResult is the outcome of one item of a batch mutation.
+type BatchItemResult struct {
+	Index     int           `json:"index"` // Position of the item in the input
+	Success   bool          `json:"success"`
+	LaborLine *LaborLine    `json:"laborLine,omitempty"` // The labor line as written, on success
+	Error     *AppSyncError `json:"error,omitempty"`
+}
+
+// BatchResult is the outcome of a batch mutation, with one result per input
+// item in input order.
+type BatchResult struct {
+	Mode         BatchMode          `json:"mode"`
+	Items        []*BatchItemResult `json:"items"`
+	SuccessCount int                `json:"successCount"`
+	FailureCount int                `json:"failureCount"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchMode(t *testing.T) {
+	assert.Equal(t, BatchModeAtomic, BatchMode("").OrDefault())
+	assert.Equal(t, BatchModeBestEffort, BatchModeBestEffort.OrDefault())
+
+	assert.True(t, BatchModeAtomic.IsValid())
+	assert.True(t, BatchModeBestEffort.IsValid())
+	assert.False(t, BatchMode("SOMETIMES").IsValid())
+	assert.False(t, BatchMode("").IsValid())
+}
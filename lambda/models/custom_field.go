@@ -0,0 +1,103 @@
+package models
+
+import "time"
+
+// RecordTypeCustomField identifies custom field definition items stored alongside labor lines.
+const RecordTypeCustomField = "CUSTOM_FIELD"
+
+// customFieldSKPrefix prefixes the sort key of each of an account's custom field definitions.
+const customFieldSKPrefix = "CUSTOMFIELD#"
+
+// CustomFieldType is the type of value a custom field holds.
+type CustomFieldType string
+
+const (
+	// CustomFieldTypeString holds text, optionally limited to an enum or pattern.
+	CustomFieldTypeString CustomFieldType = "STRING"
+	// CustomFieldTypeNumber holds any number.
+	CustomFieldTypeNumber CustomFieldType = "NUMBER"
+	// CustomFieldTypeInteger holds a whole number.
+	CustomFieldTypeInteger CustomFieldType = "INTEGER"
+	// CustomFieldTypeBoolean holds true or false.
+	CustomFieldTypeBoolean CustomFieldType = "BOOLEAN"
+)
+
+// JSONType returns the JSON schema type of the custom field's values.
+func (t CustomFieldType) JSONType() string {
+	switch t {
+	case CustomFieldTypeNumber:
+		return "number"
+	case CustomFieldTypeInteger:
+		return "integer"
+	case CustomFieldTypeBoolean:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+// CustomFieldDefinition describes an extra attribute an account records on its
+// labor lines, such as a bay number. Definitions apply to labor lines as they
+// are created or updated; stored labor lines are not revalidated when a
+// definition changes.
+type CustomFieldDefinition struct {
+	AccountID   string          `json:"accountId" dynamodbav:"accountId"`
+	Name        string          `json:"name" dynamodbav:"name"`
+	Type        CustomFieldType `json:"type" dynamodbav:"type"`
+	Required    bool            `json:"required" dynamodbav:"required"`
+	Enum        []string        `json:"enum,omitempty" dynamodbav:"enum,omitempty"`       // Allowed values of a STRING field
+	Pattern     string          `json:"pattern,omitempty" dynamodbav:"pattern,omitempty"` // Regular expression a STRING field must match
+	Description string          `json:"description,omitempty" dynamodbav:"description,omitempty"`
+	UpdatedAt   int64           `json:"updatedAt" dynamodbav:"updatedAt"`
+
+	// DynamoDB keys
+	RecordType string `json:"-" dynamodbav:"recordType"`
+	PK         string `json:"-" dynamodbav:"PK"` // accountId
+	SK         string `json:"-" dynamodbav:"SK"` // CUSTOMFIELD#{name}
+}
+
+// DefineCustomFieldInput represents the input for creating or replacing a custom field definition.
+type DefineCustomFieldInput struct {
+	AccountID   string          `json:"accountId"`
+	Name        string          `json:"name"`
+	Type        CustomFieldType `json:"type"`
+	Required    bool            `json:"required,omitempty"`
+	Enum        []string        `json:"enum,omitempty"`
+	Pattern     string          `json:"pattern,omitempty"`
+	Description string          `json:"description,omitempty"`
+}
+
+// ListCustomFieldsInput represents the input for listing an account's custom field definitions.
+type ListCustomFieldsInput struct {
+	AccountID string `json:"accountId"`
+}
+
+// ToCustomFieldDefinition converts DefineCustomFieldInput to a CustomFieldDefinition.
+func (input DefineCustomFieldInput) ToCustomFieldDefinition() *CustomFieldDefinition {
+	pk, sk := CustomFieldKey(input.AccountID, input.Name)
+
+	return &CustomFieldDefinition{
+		AccountID:   input.AccountID,
+		Name:        input.Name,
+		Type:        input.Type,
+		Required:    input.Required,
+		Enum:        input.Enum,
+		Pattern:     input.Pattern,
+		Description: input.Description,
+		UpdatedAt:   time.Now().Unix(),
+		RecordType:  RecordTypeCustomField,
+		PK:          pk,
+		SK:          sk,
+	}
+}
+
+// CustomFieldKey returns the partition and sort key of an account's custom field definition.
+func CustomFieldKey(accountID, name string) (string, string) {
+	return accountID, customFieldSKPrefix + name
+}
+
+// CustomFieldKeyPrefix returns the partition key and sort key prefix shared by
+// all of an account's custom field definitions.
+func CustomFieldKeyPrefix(accountID string) (string, string) {
+	return accountID, customFieldSKPrefix
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefineCustomFieldInput_ToCustomFieldDefinition(t *testing.T) {
+	accountID := uuid.New().String()
+
+	definition := DefineCustomFieldInput{
+		AccountID: accountID,
+		Name:      "bayNumber",
+		Type:      CustomFieldTypeInteger,
+		Required:  true,
+	}.ToCustomFieldDefinition()
+
+	assert.Equal(t, accountID, definition.PK)
+	assert.Equal(t, "CUSTOMFIELD#bayNumber", definition.SK)
+	assert.Equal(t, RecordTypeCustomField, definition.RecordType)
+	assert.True(t, definition.Required)
+	assert.NotZero(t, definition.UpdatedAt)
+
+	// Every definition of the account shares the key prefix
+	pk, prefix := CustomFieldKeyPrefix(accountID)
+	assert.Equal(t, accountID, pk)
+	assert.Contains(t, definition.SK, prefix)
+}
+
+func TestCustomFieldType_JSONType(t *testing.T) {
+	assert.Equal(t, "string", CustomFieldTypeString.JSONType())
+	assert.Equal(t, "number", CustomFieldTypeNumber.JSONType())
+	assert.Equal(t, "integer", CustomFieldTypeInteger.JSONType())
+	assert.Equal(t, "boolean", CustomFieldTypeBoolean.JSONType())
+}
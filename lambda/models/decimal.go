@@ -0,0 +1,152 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/shopspring/decimal"
+)
+
+// Decimal is an exact decimal number used for hours, rates and amounts so that
+// arithmetic never goes through float64. It is encoded as a JSON number and a
+// DynamoDB number.
+type Decimal struct {
+	value decimal.Decimal
+}
+
+// NewDecimalFromInt creates a Decimal from an integer.
+func NewDecimalFromInt(i int64) Decimal {
+	return Decimal{value: decimal.NewFromInt(i)}
+}
+
+// ParseDecimal parses a decimal string such as "12.50".
+func ParseDecimal(s string) (Decimal, error) {
+	value, err := decimal.NewFromString(s)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("parsing decimal %q: %w", s, err)
+	}
+	return Decimal{value: value}, nil
+}
+
+// MustParseDecimal parses a decimal string and panics if it is invalid. It is
+// intended for constants and tests.
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// HoursFromMinutes converts a number of minutes into hours rounded to two decimal places.
+func HoursFromMinutes(minutes int64) Decimal {
+	return Decimal{value: decimal.NewFromInt(minutes).DivRound(decimal.NewFromInt(60), 2)}
+}
+
+// decimalOrZero dereferences an optional decimal, treating nil as zero.
+func decimalOrZero(d *Decimal) Decimal {
+	if d == nil {
+		return Decimal{}
+	}
+	return *d
+}
+
+// Add returns d + other.
+func (d Decimal) Add(other Decimal) Decimal {
+	return Decimal{value: d.value.Add(other.value)}
+}
+
+// IntPart returns the integer part of d.
+func (d Decimal) IntPart() int64 {
+	return d.value.IntPart()
+}
+
+// Sub returns d - other.
+func (d Decimal) Sub(other Decimal) Decimal {
+	return Decimal{value: d.value.Sub(other.value)}
+}
+
+// Mul returns d * other.
+func (d Decimal) Mul(other Decimal) Decimal {
+	return Decimal{value: d.value.Mul(other.value)}
+}
+
+// Round rounds half away from zero to the given number of decimal places.
+func (d Decimal) Round(places int32) Decimal {
+	return Decimal{value: d.value.Round(places)}
+}
+
+// Cmp compares d and other, returning -1, 0 or +1.
+func (d Decimal) Cmp(other Decimal) int {
+	return d.value.Cmp(other.value)
+}
+
+// Equal reports whether d and other represent the same number.
+func (d Decimal) Equal(other Decimal) bool {
+	return d.value.Equal(other.value)
+}
+
+// IsZero reports whether d is zero.
+func (d Decimal) IsZero() bool {
+	return d.value.IsZero()
+}
+
+// IsNegative reports whether d is less than zero.
+func (d Decimal) IsNegative() bool {
+	return d.value.IsNegative()
+}
+
+// String returns the decimal without trailing zeros, e.g. "12.5".
+func (d Decimal) String() string {
+	return d.value.String()
+}
+
+// StringFixed returns the decimal with exactly the given number of decimal places.
+func (d Decimal) StringFixed(places int32) string {
+	return d.value.StringFixed(places)
+}
+
+// MarshalJSON encodes the decimal as a JSON number.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.value.String()), nil
+}
+
+// UnmarshalJSON accepts either a JSON number or a numeric string.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*d = Decimal{}
+		return nil
+	}
+	return d.value.UnmarshalJSON(data)
+}
+
+// MarshalDynamoDBAttributeValue encodes the decimal as a DynamoDB number.
+func (d Decimal) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
+	return &types.AttributeValueMemberN{Value: d.value.String()}, nil
+}
+
+// UnmarshalDynamoDBAttributeValue decodes a DynamoDB number or string.
+func (d *Decimal) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberN:
+		return d.parse(v.Value)
+	case *types.AttributeValueMemberS:
+		return d.parse(v.Value)
+	case *types.AttributeValueMemberNULL:
+		*d = Decimal{}
+		return nil
+	default:
+		return fmt.Errorf("cannot decode %T as decimal", av)
+	}
+}
+
+// parse replaces the value of d with the parsed string.
+func (d *Decimal) parse(s string) error {
+	parsed, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
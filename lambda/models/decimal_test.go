@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHoursFromMinutes(t *testing.T) {
+	tests := []struct {
+		name     string
+		minutes  int64
+		expected string
+	}{
+		{name: "Zero", minutes: 0, expected: "0.00"},
+		{name: "Whole hours", minutes: 120, expected: "2.00"},
+		{name: "Quarter hour", minutes: 15, expected: "0.25"},
+		{name: "Repeating fraction rounds down", minutes: 20, expected: "0.33"},
+		{name: "Repeating fraction rounds up", minutes: 40, expected: "0.67"},
+		{name: "Single minute rounds up", minutes: 1, expected: "0.02"},
+		{name: "Exact fraction", minutes: 3, expected: "0.05"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, HoursFromMinutes(tt.minutes).StringFixed(2))
+		})
+	}
+}
+
+func TestDecimal_JSON(t *testing.T) {
+	d := MustParseDecimal("12.50")
+
+	data, err := json.Marshal(struct {
+		Value Decimal `json:"value"`
+	}{Value: d})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"value": 12.5}`, string(data))
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Number", input: `12.5`, expected: "12.5"},
+		{name: "String", input: `"7.25"`, expected: "7.25"},
+		{name: "Null", input: `null`, expected: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decoded Decimal
+			require.NoError(t, json.Unmarshal([]byte(tt.input), &decoded))
+			assert.Equal(t, tt.expected, decoded.String())
+		})
+	}
+}
+
+func TestDecimal_DynamoDBAttributeValue(t *testing.T) {
+	d := MustParseDecimal("3.75")
+
+	av, err := d.MarshalDynamoDBAttributeValue()
+	require.NoError(t, err)
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "3.75"}, av)
+
+	var decoded Decimal
+	require.NoError(t, decoded.UnmarshalDynamoDBAttributeValue(av))
+	assert.True(t, d.Equal(decoded))
+
+	err = decoded.UnmarshalDynamoDBAttributeValue(&types.AttributeValueMemberBOOL{Value: true})
+	assert.Error(t, err)
+}
+
+func TestParseDecimal_Invalid(t *testing.T) {
+	_, err := ParseDecimal("not-a-number")
+	assert.Error(t, err)
+}
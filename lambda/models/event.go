@@ -0,0 +1,192 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecordTypeOutbox identifies outbox items stored alongside labor lines.
+const RecordTypeOutbox = "OUTBOX"
+
+// OutboxPending marks an outbox entry whose event has not been published yet.
+const OutboxPending = "PENDING"
+
+// OutboxFailed marks an outbox entry whose event could not be published after
+// MaxOutboxAttempts tries, or could not be read at all. The relay leaves it alone; setting its status back
+// to OutboxPending has it tried again.
+const OutboxFailed = "FAILED"
+
+// MaxOutboxAttempts is how many times the relay tries to publish an outbox
+// entry's event before marking it failed, so that an event that will never be
+// accepted does not hold up the rest.
+const MaxOutboxAttempts = 10
+
+// MaxEventDetailSize bounds the size of a labor line event as JSON. It is kept
+// well below EventBridge's 256 KB entry limit, and below DynamoDB's 400 KB item
+// limit for the outbox entry that holds the event.
+const MaxEventDetailSize = 200 * 1024
+
+// outboxSKPrefix prefixes the sort key of each entry in an account's outbox.
+const outboxSKPrefix = "OUTBOX#"
+
+// EventType names the kind of change a labor line event announces.
+type EventType string
+
+const (
+	// EventLaborLineCreated announces a new labor line.
+	EventLaborLineCreated EventType = "LaborLineCreated"
+	// EventLaborLineUpdated announces any change to a labor line that is not
+	// its creation, deletion or a status change, such as a field update, a
+	// note, an assignment or a restore.
+	EventLaborLineUpdated EventType = "LaborLineUpdated"
+	// EventLaborLineDeleted announces a labor line being soft deleted.
+	EventLaborLineDeleted EventType = "LaborLineDeleted"
+	// EventLaborLineStatusChanged announces a labor line moving to a new status.
+	EventLaborLineStatusChanged EventType = "LaborLineStatusChanged"
+)
+
+// EventTaskLaborTotalsChanged announces a change to the totals of a task's
+// labor lines. Unlike the labor line events it is derived from the table's
+// stream, and carries the task's totals after the change.
+const EventTaskLaborTotalsChanged EventType = "TaskLaborTotalsChanged"
+
+// EventTypeFor returns the type of event that announces a change recorded
+// with the given action.
+func EventTypeFor(action ChangeAction) EventType {
+	switch action {
+	case ActionCreate:
+		return EventLaborLineCreated
+	case ActionDelete:
+		return EventLaborLineDeleted
+	case ActionStatusChange:
+		return EventLaborLineStatusChanged
+	default:
+		return EventLaborLineUpdated
+	}
+}
+
+// LaborLineEvent announces a committed change to a labor line to other
+// services. It carries the labor line as it was before the change, which is
+// nil for a creation, and as it is after.
+type LaborLineEvent struct {
+	EventID     string       `json:"eventId"`
+	Type        EventType    `json:"type"`
+	Action      ChangeAction `json:"action"`
+	AccountID   string       `json:"accountId"`
+	TaskID      string       `json:"taskId"`
+	LaborLineID string       `json:"laborLineId"`
+	Version     int64        `json:"version"` // Labor line version after the change
+	Actor       string       `json:"actor"`
+	OccurredAt  int64        `json:"occurredAt"`
+	Before      *LaborLine   `json:"before"`
+	After       *LaborLine   `json:"after"`
+
+	// SnapshotsOmitted is set when Before, and if need be After, were left out
+	// to keep the event within MaxEventDetailSize; consumers read the labor
+	// line instead
+	SnapshotsOmitted bool `json:"snapshotsOmitted,omitempty"`
+}
+
+// NewLaborLineEvent creates the event announcing a change from before to after.
+func NewLaborLineEvent(action ChangeAction, actor string, before, after *LaborLine) *LaborLineEvent {
+	return &LaborLineEvent{
+		EventID:     uuid.New().String(),
+		Type:        EventTypeFor(action),
+		Action:      action,
+		AccountID:   after.AccountID,
+		TaskID:      after.TaskID,
+		LaborLineID: after.LaborLineID,
+		Version:     after.Version,
+		Actor:       actor,
+		OccurredAt:  time.Now().Unix(),
+		Before:      before,
+		After:       after,
+	}
+}
+
+// MarshalDetail returns the event as JSON. If the labor line snapshots would
+// make it larger than MaxEventDetailSize, the snapshot before the change and
+// then the one after are left out of the event, which records that they were.
+func (e *LaborLineEvent) MarshalDetail() ([]byte, error) {
+	detail, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling labor line event: %w", err)
+	}
+
+	for _, omit := range []**LaborLine{&e.Before, &e.After} {
+		if len(detail) <= MaxEventDetailSize {
+			break
+		}
+		*omit = nil
+		e.SnapshotsOmitted = true
+		if detail, err = json.Marshal(e); err != nil {
+			return nil, fmt.Errorf("marshaling labor line event: %w", err)
+		}
+	}
+	return detail, nil
+}
+
+// OutboxEntry holds a labor line event that has yet to be published. It is
+// written in the same transaction as the change it announces, so the event
+// survives even if publishing it afterwards fails, and is removed once the
+// event is published. It is keyed outside the labor line's key space so that
+// purging the labor line does not drop it.
+type OutboxEntry struct {
+	EventID   string    `json:"eventId" dynamodbav:"eventId"`
+	EventType EventType `json:"eventType" dynamodbav:"eventType"`
+	Detail    string    `json:"detail" dynamodbav:"detail"` // The event as JSON
+
+	// OutboxStatus and CreatedAt are the OutboxIndex keys, which the relay
+	// queries for entries that were not published when they were written
+	OutboxStatus string `json:"-" dynamodbav:"outboxStatus"`
+	CreatedAt    int64  `json:"-" dynamodbav:"createdAt"`
+
+	// Attempts counts the relay's failed tries at publishing the event, the
+	// last of which failed with LastError
+	Attempts  int    `json:"-" dynamodbav:"attempts,omitempty"`
+	LastError string `json:"-" dynamodbav:"lastError,omitempty"`
+
+	// DynamoDB keys
+	RecordType string `json:"-" dynamodbav:"recordType"`
+	PK         string `json:"-" dynamodbav:"PK"` // accountId
+	SK         string `json:"-" dynamodbav:"SK"` // OUTBOX#{eventId}
+}
+
+// NewOutboxEntry creates the outbox entry holding an event until it is
+// published. Snapshots that would make the event too large are left out of
+// it, as MarshalDetail describes.
+func NewOutboxEntry(event *LaborLineEvent) (*OutboxEntry, error) {
+	detail, err := event.MarshalDetail()
+	if err != nil {
+		return nil, err
+	}
+
+	pk, sk := OutboxKey(event.AccountID, event.EventID)
+	return &OutboxEntry{
+		EventID:      event.EventID,
+		EventType:    event.Type,
+		Detail:       string(detail),
+		OutboxStatus: OutboxPending,
+		CreatedAt:    event.OccurredAt,
+		RecordType:   RecordTypeOutbox,
+		PK:           pk,
+		SK:           sk,
+	}, nil
+}
+
+// Event decodes the event the outbox entry holds.
+func (e *OutboxEntry) Event() (*LaborLineEvent, error) {
+	var event LaborLineEvent
+	if err := json.Unmarshal([]byte(e.Detail), &event); err != nil {
+		return nil, fmt.Errorf("unmarshaling labor line event %s: %w", e.EventID, err)
+	}
+	return &event, nil
+}
+
+// OutboxKey returns the partition and sort key of an event in an account's outbox.
+func OutboxKey(accountID, eventID string) (string, string) {
+	return accountID, outboxSKPrefix + eventID
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventTypeFor(t *testing.T) {
+	tests := []struct {
+		action   ChangeAction
+		expected EventType
+	}{
+		{ActionCreate, EventLaborLineCreated},
+		{ActionUpdate, EventLaborLineUpdated},
+		{ActionDelete, EventLaborLineDeleted},
+		{ActionRestore, EventLaborLineUpdated},
+		{ActionStatusChange, EventLaborLineStatusChanged},
+		{ActionNoteAdd, EventLaborLineUpdated},
+		{ActionAssign, EventLaborLineUpdated},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.action), func(t *testing.T) {
+			assert.Equal(t, tt.expected, EventTypeFor(tt.action))
+		})
+	}
+}
+
+func TestNewOutboxEntry(t *testing.T) {
+	before := NewLaborLine(CreateLaborLineInput{AccountID: "acct-1", TaskID: "task-1", Description: "Inspect brakes"}, nil)
+	after := *before
+	after.Description = "Replace brake pads"
+	after.Version++
+
+	event := NewLaborLineEvent(ActionUpdate, "user-123", before, &after)
+	assert.Equal(t, EventLaborLineUpdated, event.Type)
+	assert.Equal(t, after.Version, event.Version)
+	assert.NotEmpty(t, event.EventID)
+
+	entry, err := NewOutboxEntry(event)
+	require.NoError(t, err)
+	assert.Equal(t, "acct-1", entry.PK)
+	assert.Equal(t, "OUTBOX#"+event.EventID, entry.SK)
+	assert.Equal(t, RecordTypeOutbox, entry.RecordType)
+	assert.Equal(t, OutboxPending, entry.OutboxStatus)
+	assert.Equal(t, event.OccurredAt, entry.CreatedAt)
+
+	// The entry holds the whole event, snapshots included
+	decoded, err := entry.Event()
+	require.NoError(t, err)
+	assert.Equal(t, event.EventID, decoded.EventID)
+	assert.Equal(t, "Inspect brakes", decoded.Before.Description)
+	assert.Equal(t, "Replace brake pads", decoded.After.Description)
+}
+
+func TestNewOutboxEntry_OmitsLargeSnapshots(t *testing.T) {
+	before := NewLaborLine(CreateLaborLineInput{AccountID: "acct-1", TaskID: "task-1", Description: strings.Repeat("a", 120*1024)}, nil)
+	after := *before
+	after.Version++
+
+	// Both snapshots are too large together, but the one after the change fits
+	event := NewLaborLineEvent(ActionUpdate, "user-123", before, &after)
+	entry, err := NewOutboxEntry(event)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(entry.Detail), MaxEventDetailSize)
+
+	decoded, err := entry.Event()
+	require.NoError(t, err)
+	assert.True(t, decoded.SnapshotsOmitted)
+	assert.Nil(t, decoded.Before)
+	require.NotNil(t, decoded.After)
+	assert.Equal(t, after.Version, decoded.After.Version)
+
+	// The event published with the entry matches it
+	assert.True(t, event.SnapshotsOmitted)
+	assert.Nil(t, event.Before)
+}
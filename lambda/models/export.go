@@ -0,0 +1,124 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportFormat is the file format labor lines are exported in.
+type ExportFormat string
+
+const (
+	// ExportFormatCSV exports one row per labor line with the chosen columns.
+	ExportFormatCSV ExportFormat = "CSV"
+	// ExportFormatNDJSON exports one labor line JSON object per line.
+	ExportFormatNDJSON ExportFormat = "NDJSON"
+)
+
+// DefaultExportFormat is used when an export does not specify a format.
+const DefaultExportFormat = ExportFormatCSV
+
+// IsValid reports whether the format is one labor lines can be exported in.
+func (f ExportFormat) IsValid() bool {
+	return f == ExportFormatCSV || f == ExportFormatNDJSON
+}
+
+// ContentType returns the media type of an export in the format.
+func (f ExportFormat) ContentType() string {
+	if f == ExportFormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv"
+}
+
+// Extension returns the file extension of an export in the format.
+func (f ExportFormat) Extension() string {
+	if f == ExportFormatNDJSON {
+		return "ndjson"
+	}
+	return "csv"
+}
+
+// ExportLaborLinesInput represents the input for exporting an account's labor
+// lines to a file.
+type ExportLaborLinesInput struct {
+	AccountID   string       `json:"accountId"`
+	TaskID      string       `json:"taskId,omitempty"`      // Optional filter by task
+	CreatedFrom *int64       `json:"createdFrom,omitempty"` // Optional start of the creation date range (epoch seconds, inclusive)
+	CreatedTo   *int64       `json:"createdTo,omitempty"`   // Optional end of the creation date range (epoch seconds, inclusive)
+	Format      ExportFormat `json:"format,omitempty"`      // Defaults to CSV
+	Columns     []string     `json:"columns,omitempty"`     // CSV columns in order; defaults to DefaultExportColumns
+}
+
+// LaborLineExport is a finished export, downloadable from URL until ExpiresAt.
+type LaborLineExport struct {
+	URL       string       `json:"url"`
+	Key       string       `json:"key"`
+	Format    ExportFormat `json:"format"`
+	RowCount  int          `json:"rowCount"`
+	ExpiresAt int64        `json:"expiresAt"` // Epoch seconds
+}
+
+// exportColumns renders each CSV export column from a labor line. Timestamps
+// are written in RFC 3339 so spreadsheets read them as dates.
+var exportColumns = map[string]func(*LaborLine) string{
+	"laborLineId":    func(ll *LaborLine) string { return ll.LaborLineID },
+	"accountId":      func(ll *LaborLine) string { return ll.AccountID },
+	"taskId":         func(ll *LaborLine) string { return ll.TaskID },
+	"description":    func(ll *LaborLine) string { return ll.Description },
+	"operationCode":  func(ll *LaborLine) string { return ll.OperationCode },
+	"status":         func(ll *LaborLine) string { return string(ll.CurrentStatus()) },
+	"estimatedHours": func(ll *LaborLine) string { return ll.EstimatedHours.String() },
+	"actualHours":    func(ll *LaborLine) string { return ll.ActualHours.String() },
+	"rateType":       func(ll *LaborLine) string { return string(ll.RateType) },
+	"ratePerHour":    func(ll *LaborLine) string { return ll.RatePerHour.StringFixed(costPlaces) },
+	"laborCost":      func(ll *LaborLine) string { return ll.LaborCost.StringFixed(costPlaces) },
+	"partCount":      func(ll *LaborLine) string { return strconv.Itoa(len(ll.Parts)) },
+	"technicianIds":  func(ll *LaborLine) string { return strings.Join(ll.AssignedTechnicianIDs, ";") },
+	"notes":          exportNotes,
+	"createdBy":      func(ll *LaborLine) string { return ll.CreatedBy },
+	"createdAt":      func(ll *LaborLine) string { return exportTime(ll.CreatedAt) },
+	"updatedAt":      func(ll *LaborLine) string { return exportTime(ll.UpdatedAt) },
+}
+
+// DefaultExportColumns are the CSV columns of an export that does not choose its own.
+var DefaultExportColumns = []string{
+	"laborLineId", "taskId", "description", "status",
+	"estimatedHours", "actualHours", "rateType", "ratePerHour", "laborCost",
+	"technicianIds", "notes", "createdAt", "updatedAt",
+}
+
+// IsExportColumn reports whether labor lines can be exported with the named column.
+func IsExportColumn(name string) bool {
+	_, ok := exportColumns[name]
+	return ok
+}
+
+// ExportRow renders a labor line as a CSV row of the named columns, which
+// must all be export columns.
+func ExportRow(laborLine *LaborLine, columns []string) []string {
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		row[i] = exportColumns[column](laborLine)
+	}
+	return row
+}
+
+// exportNotes joins the bodies of a labor line's notes, one per line. The CSV
+// writer quotes the field, so the line breaks stay within the cell.
+func exportNotes(laborLine *LaborLine) string {
+	bodies := make([]string, len(laborLine.Notes))
+	for i, note := range laborLine.Notes {
+		bodies[i] = note.Body
+	}
+	return strings.Join(bodies, "\n")
+}
+
+// exportTime formats an epoch seconds timestamp in RFC 3339, or "" if unset.
+func exportTime(epoch int64) string {
+	if epoch == 0 {
+		return ""
+	}
+	return time.Unix(epoch, 0).UTC().Format(time.RFC3339)
+}
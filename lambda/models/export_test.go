@@ -0,0 +1,37 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportFormat(t *testing.T) {
+	assert.True(t, ExportFormatCSV.IsValid())
+	assert.True(t, ExportFormatNDJSON.IsValid())
+	assert.False(t, ExportFormat("XLSX").IsValid())
+
+	assert.Equal(t, "csv", DefaultExportFormat.Extension())
+	assert.Equal(t, "application/x-ndjson", ExportFormatNDJSON.ContentType())
+}
+
+func TestExportRow(t *testing.T) {
+	laborLine := &LaborLine{
+		LaborLineID:           "ll-1",
+		Status:                StatusInProgress,
+		EstimatedHours:        MustParseDecimal("1.5"),
+		RatePerHour:           MustParseDecimal("120"),
+		AssignedTechnicianIDs: []string{"tech-1", "tech-2"},
+		Notes:                 []Note{{Body: "Ordered pads"}, {Body: "Pads arrived"}},
+		CreatedAt:             1760000000,
+	}
+
+	row := ExportRow(laborLine, []string{"laborLineId", "status", "estimatedHours", "ratePerHour", "technicianIds", "notes", "createdAt", "updatedAt"})
+	assert.Equal(t, []string{"ll-1", "IN_PROGRESS", "1.5", "120.00", "tech-1;tech-2", "Ordered pads\nPads arrived", "2025-10-09T08:53:20Z", ""}, row)
+
+	// Every default column can be rendered
+	for _, column := range DefaultExportColumns {
+		assert.True(t, IsExportColumn(column), column)
+	}
+	assert.False(t, IsExportColumn("PK"))
+}
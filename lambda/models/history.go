@@ -0,0 +1,181 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RecordTypeHistory identifies history items, which are kept in their own
+// partition of each account so that queries of its labor lines never read them.
+const RecordTypeHistory = "HISTORY"
+
+// ChangeAction describes the kind of write recorded in a history record.
+type ChangeAction string
+
+const (
+	// ActionCreate records the creation of a labor line.
+	ActionCreate ChangeAction = "CREATE"
+	// ActionUpdate records a change to a labor line's fields.
+	ActionUpdate ChangeAction = "UPDATE"
+	// ActionDelete records a labor line being soft deleted.
+	ActionDelete ChangeAction = "DELETE"
+	// ActionRestore records a soft-deleted labor line being restored.
+	ActionRestore ChangeAction = "RESTORE"
+	// ActionStatusChange records a labor line moving to a new status.
+	ActionStatusChange ChangeAction = "STATUS_CHANGE"
+	// ActionNoteAdd records a note being added to a labor line.
+	ActionNoteAdd ChangeAction = "NOTE_ADD"
+	// ActionNoteEdit records a change to one of a labor line's notes.
+	ActionNoteEdit ChangeAction = "NOTE_EDIT"
+	// ActionNoteDelete records a note being removed from a labor line.
+	ActionNoteDelete ChangeAction = "NOTE_DELETE"
+	// ActionAssign records a technician being assigned to a labor line.
+	ActionAssign ChangeAction = "ASSIGN"
+	// ActionUnassign records a technician being unassigned from a labor line.
+	ActionUnassign ChangeAction = "UNASSIGN"
+	// ActionTimeEntry records a timer being started or stopped, or time being
+	// logged, on a labor line.
+	ActionTimeEntry ChangeAction = "TIME_ENTRY"
+)
+
+// historyIgnoredFields are not included in diffs: they change on every write,
+// or, for the status history, are already captured by the status field.
+var historyIgnoredFields = map[string]bool{
+	"updatedAt":     true,
+	"version":       true,
+	"statusHistory": true,
+}
+
+// HistoryRecord is an immutable record of a single write to a labor line. It
+// is keyed by the labor line version the write produced, so records sort in
+// the order the changes were made.
+type HistoryRecord struct {
+	LaborLineID string        `json:"laborLineId" dynamodbav:"laborLineId"`
+	AccountID   string        `json:"accountId" dynamodbav:"accountId"`
+	TaskID      string        `json:"taskId" dynamodbav:"taskId"`
+	Version     int64         `json:"version" dynamodbav:"version"` // Labor line version after the change
+	Action      ChangeAction  `json:"action" dynamodbav:"action"`
+	Actor       string        `json:"actor" dynamodbav:"actor"`
+	ChangedAt   int64         `json:"changedAt" dynamodbav:"changedAt"`
+	Changes     []FieldChange `json:"changes" dynamodbav:"changes"`
+
+	// DynamoDB keys
+	RecordType string `json:"-" dynamodbav:"recordType"`
+	PK         string `json:"-" dynamodbav:"PK"` // HISTORY#{accountId}
+	SK         string `json:"-" dynamodbav:"SK"` // {taskId}#{laborLineId}#HISTORY#{version}
+}
+
+// FieldChange is the before and after value of a single labor line field,
+// each encoded as JSON. A value is empty when the field was not set.
+type FieldChange struct {
+	Field  string `json:"field" dynamodbav:"field"`
+	Before string `json:"before,omitempty" dynamodbav:"before,omitempty"`
+	After  string `json:"after,omitempty" dynamodbav:"after,omitempty"`
+}
+
+// GetLaborLineHistoryInput represents the input for listing a labor line's change history.
+type GetLaborLineHistoryInput struct {
+	AccountID   string `json:"accountId"`
+	TaskID      string `json:"taskId"`
+	LaborLineID string `json:"laborLineId"`
+	Limit       int32  `json:"limit,omitempty"`     // Optional page size
+	NextToken   string `json:"nextToken,omitempty"` // Opaque token from a previous page
+}
+
+// HistoryConnection represents a page of history records in the GraphQL connection shape.
+type HistoryConnection struct {
+	Items     []*HistoryRecord `json:"items"`
+	NextToken *string          `json:"nextToken"` // nil when there are no more pages
+}
+
+// NewHistoryRecord records a write that changed a labor line from before to
+// after. Before is nil for a newly created labor line.
+func NewHistoryRecord(action ChangeAction, actor string, before, after *LaborLine) (*HistoryRecord, error) {
+	changes, err := DiffLaborLines(before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HistoryRecord{
+		LaborLineID: after.LaborLineID,
+		AccountID:   after.AccountID,
+		TaskID:      after.TaskID,
+		Version:     after.Version,
+		Action:      action,
+		Actor:       actor,
+		ChangedAt:   time.Now().Unix(),
+		Changes:     changes,
+		RecordType:  RecordTypeHistory,
+		PK:          HistoryPK(after.AccountID),
+		SK:          fmt.Sprintf("%s%020d", HistorySKPrefix(after.TaskID, after.LaborLineID), after.Version),
+	}, nil
+}
+
+// HistoryPK returns the partition key holding the history records of an account's labor lines.
+func HistoryPK(accountID string) string {
+	return "HISTORY#" + accountID
+}
+
+// HistorySKPrefix returns the sort key prefix shared by all history records of a labor line.
+func HistorySKPrefix(taskID, laborLineID string) string {
+	return taskID + "#" + laborLineID + "#HISTORY#"
+}
+
+// DiffLaborLines returns the fields that differ between two versions of a
+// labor line, sorted by field name. Before may be nil.
+func DiffLaborLines(before, after *LaborLine) ([]FieldChange, error) {
+	beforeFields, err := laborLineFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := laborLineFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(afterFields))
+	for name := range beforeFields {
+		names = append(names, name)
+	}
+	for name := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := []FieldChange{}
+	for _, name := range names {
+		if historyIgnoredFields[name] || bytes.Equal(beforeFields[name], afterFields[name]) {
+			continue
+		}
+		changes = append(changes, FieldChange{
+			Field:  name,
+			Before: string(beforeFields[name]),
+			After:  string(afterFields[name]),
+		})
+	}
+
+	return changes, nil
+}
+
+// laborLineFields returns the JSON encoding of each field of a labor line.
+func laborLineFields(laborLine *LaborLine) (map[string]json.RawMessage, error) {
+	fields := map[string]json.RawMessage{}
+	if laborLine == nil {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(laborLine)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling labor line for diff: %w", err)
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("unmarshaling labor line for diff: %w", err)
+	}
+
+	return fields, nil
+}
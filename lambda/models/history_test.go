@@ -0,0 +1,91 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLaborLines(t *testing.T) {
+	before := &LaborLine{
+		AccountID:   "account-1",
+		TaskID:      "task-1",
+		LaborLineID: "line-1",
+		Description: "Brake inspection",
+		Status:      StatusPending,
+		UpdatedAt:   100,
+		Version:     1,
+	}
+	after := *before
+	after.Description = "Brake replacement"
+	after.Status = StatusInProgress
+	after.StatusHistory = []StatusTransition{{From: StatusPending, To: StatusInProgress}}
+	after.UpdatedAt = 200
+	after.Version = 2
+
+	changes, err := DiffLaborLines(before, &after)
+	require.NoError(t, err)
+
+	// updatedAt, version and statusHistory are not reported
+	require.Len(t, changes, 2)
+	assert.Equal(t, FieldChange{
+		Field:  "description",
+		Before: `"Brake inspection"`,
+		After:  `"Brake replacement"`,
+	}, changes[0])
+	assert.Equal(t, FieldChange{Field: "status", Before: `"PENDING"`, After: `"IN_PROGRESS"`}, changes[1])
+}
+
+func TestDiffLaborLines_Create(t *testing.T) {
+	after := &LaborLine{
+		AccountID:   "account-1",
+		TaskID:      "task-1",
+		LaborLineID: "line-1",
+		Version:     1,
+	}
+
+	changes, err := DiffLaborLines(nil, after)
+	require.NoError(t, err)
+
+	fields := make([]string, 0, len(changes))
+	for _, change := range changes {
+		assert.Empty(t, change.Before)
+		assert.NotEmpty(t, change.After)
+		fields = append(fields, change.Field)
+	}
+	assert.IsIncreasing(t, fields)
+	assert.Contains(t, fields, "laborLineId")
+	assert.NotContains(t, fields, "version")
+}
+
+func TestDiffLaborLines_NoChanges(t *testing.T) {
+	laborLine := &LaborLine{AccountID: "account-1", TaskID: "task-1", LaborLineID: "line-1", Version: 1}
+	next := *laborLine
+	next.Version = 2
+
+	changes, err := DiffLaborLines(laborLine, &next)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestNewHistoryRecord(t *testing.T) {
+	laborLine := &LaborLine{
+		AccountID:   "account-1",
+		TaskID:      "task-1",
+		LaborLineID: "line-1",
+		Version:     12,
+	}
+
+	record, err := NewHistoryRecord(ActionUpdate, "user-123", laborLine, laborLine)
+	require.NoError(t, err)
+
+	assert.Equal(t, "HISTORY#account-1", record.PK)
+	assert.Equal(t, "task-1#line-1#HISTORY#00000000000000000012", record.SK)
+	assert.Equal(t, RecordTypeHistory, record.RecordType)
+	assert.Equal(t, ActionUpdate, record.Action)
+	assert.Equal(t, "user-123", record.Actor)
+	assert.Equal(t, int64(12), record.Version)
+	assert.NotZero(t, record.ChangedAt)
+	assert.Empty(t, record.Changes)
+}
@@ -0,0 +1,212 @@
+package models
+
+import (
+	"strings"
+)
+
+// IdentityType identifies how AppSync authenticated the caller.
+type IdentityType string
+
+const (
+	// IdentityTypeCognito is a Cognito user pool user.
+	IdentityTypeCognito IdentityType = "COGNITO"
+	// IdentityTypeOIDC is a caller authenticated by an OpenID Connect provider.
+	IdentityTypeOIDC IdentityType = "OIDC"
+	// IdentityTypeIAM is a caller that signed the request with IAM credentials.
+	IdentityTypeIAM IdentityType = "IAM"
+	// IdentityTypeLambda is a caller admitted by a Lambda authorizer.
+	IdentityTypeLambda IdentityType = "LAMBDA"
+	// IdentityTypeNone is a caller without an identity, such as an API key request.
+	IdentityTypeNone IdentityType = "NONE"
+)
+
+// DefaultAccountIDsClaim is the identity claim listing the accounts a caller
+// may access, as a comma-separated string or a list, unless another claim is
+// configured.
+const DefaultAccountIDsClaim = "custom:accountIds"
+
+// cognitoIssuerHost appears in the issuer of tokens from Cognito user pools.
+const cognitoIssuerHost = "cognito-idp."
+
+// Identity is the caller of an AppSync request, parsed from the event's
+// identity for each of the API's authorization modes.
+type Identity struct {
+	Type     IdentityType
+	Subject  string
+	Username string
+	Issuer   string
+	Groups   []string
+
+	// Claims holds the token claims of Cognito and OIDC callers.
+	Claims map[string]interface{}
+	// ResolverContext holds the context returned by a Lambda authorizer.
+	ResolverContext map[string]interface{}
+
+	// UserArn and AWSAccountID identify IAM callers.
+	UserArn      string
+	AWSAccountID string
+
+	SourceIP []string
+}
+
+// ParseIdentity builds an Identity from the identity of an AppSync event. A
+// Lambda authorizer is recognized by its resolver context, IAM by the user
+// ARN, and Cognito and OIDC by their claims, told apart by the issuer.
+func ParseIdentity(raw map[string]interface{}) Identity {
+	identity := Identity{
+		Type:            IdentityTypeNone,
+		Subject:         stringValue(raw["sub"]),
+		Username:        stringValue(raw["username"]),
+		Issuer:          stringValue(raw["issuer"]),
+		Groups:          stringList(raw["groups"]),
+		Claims:          mapValue(raw["claims"]),
+		ResolverContext: mapValue(raw["resolverContext"]),
+		UserArn:         stringValue(raw["userArn"]),
+		AWSAccountID:    stringValue(raw["accountId"]),
+		SourceIP:        stringList(raw["sourceIp"]),
+	}
+
+	switch {
+	case identity.ResolverContext != nil:
+		identity.Type = IdentityTypeLambda
+		if identity.Subject == "" {
+			identity.Subject = stringValue(identity.ResolverContext["sub"])
+		}
+	case identity.UserArn != "":
+		identity.Type = IdentityTypeIAM
+	case identity.Claims != nil || identity.Subject != "":
+		identity.Type = IdentityTypeOIDC
+		if identity.Username != "" || strings.Contains(identity.Issuer, cognitoIssuerHost) {
+			identity.Type = IdentityTypeCognito
+		}
+	}
+
+	return identity
+}
+
+// Actor returns an identifier for the caller: the subject, the username, or
+// the IAM user ARN, in that order of preference.
+func (i Identity) Actor() string {
+	for _, value := range []string{i.Subject, i.Username, i.UserArn} {
+		if value != "" {
+			return value
+		}
+	}
+	return anonymousActor
+}
+
+// InGroup reports whether the caller belongs to the given group.
+func (i Identity) InGroup(group string) bool {
+	for _, g := range i.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// ClaimValues returns the values of the named claim of a Cognito or OIDC
+// token, or of the same key of a Lambda authorizer's resolver context, read as
+// a comma-separated string or a list. IAM callers and callers without an
+// identity carry no claims.
+func (i Identity) ClaimValues(claim string) []string {
+	switch i.Type {
+	case IdentityTypeCognito, IdentityTypeOIDC:
+		return stringList(i.Claims[claim])
+	case IdentityTypeLambda:
+		return stringList(i.ResolverContext[claim])
+	default:
+		return nil
+	}
+}
+
+// AccountIDs returns the accounts the caller may access, listed in the named
+// claim. IAM callers and callers without an identity may access no accounts.
+func (i Identity) AccountIDs(claim string) []string {
+	return i.ClaimValues(claim)
+}
+
+// CanAccessAccount reports whether accountID is one of the accounts listed in
+// the named claim.
+func (i Identity) CanAccessAccount(claim, accountID string) bool {
+	for _, allowed := range i.AccountIDs(claim) {
+		if allowed == accountID {
+			return true
+		}
+	}
+	return false
+}
+
+// IAMRole returns the ARN of the IAM role an IAM caller signed the request
+// with, in the form IAMRoleARN returns, or "" if the caller did not assume a
+// role.
+func (i Identity) IAMRole() string {
+	if i.Type != IdentityTypeIAM {
+		return ""
+	}
+	return IAMRoleARN(i.UserArn)
+}
+
+// IAMRoleARN returns the ARN of the IAM role named by arn, which may be the
+// ARN of the role or of a session assuming it, as
+// arn:{partition}:iam::{account}:role/{name}. Session ARNs carry no role path,
+// so the path of a role ARN is left out too. It returns "" if arn does not
+// name a role.
+func IAMRoleARN(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[4] == "" {
+		return ""
+	}
+	partition, service, account, resource := parts[1], parts[2], parts[4], parts[5]
+
+	var name string
+	switch {
+	case service == "iam" && strings.HasPrefix(resource, "role/"):
+		name = resource[strings.LastIndex(resource, "/")+1:]
+	case service == "sts" && strings.HasPrefix(resource, "assumed-role/"):
+		name, _, _ = strings.Cut(strings.TrimPrefix(resource, "assumed-role/"), "/")
+	}
+	if name == "" {
+		return ""
+	}
+	return "arn:" + partition + ":iam::" + account + ":role/" + name
+}
+
+// stringValue returns value if it is a string, or "" otherwise.
+func stringValue(value interface{}) string {
+	s, _ := value.(string)
+	return s
+}
+
+// mapValue returns value if it is a JSON object, or nil otherwise.
+func mapValue(value interface{}) map[string]interface{} {
+	m, _ := value.(map[string]interface{})
+	return m
+}
+
+// stringList reads a comma-separated string or a list of strings, dropping
+// empty entries.
+func stringList(value interface{}) []string {
+	var list []string
+	switch value := value.(type) {
+	case string:
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			if s, ok := item.(string); ok && s != "" {
+				list = append(list, s)
+			}
+		}
+	case []string:
+		for _, s := range value {
+			if s != "" {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
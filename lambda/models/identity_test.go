@@ -0,0 +1,151 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIdentity(t *testing.T) {
+	tests := []struct {
+		name             string
+		identity         map[string]interface{}
+		expectedType     IdentityType
+		expectedActor    string
+		expectedAccounts []string
+	}{
+		{
+			name: "Cognito user pool",
+			identity: map[string]interface{}{
+				"sub":      "abc-123",
+				"username": "jdoe",
+				"issuer":   "https://cognito-idp.us-east-1.amazonaws.com/us-east-1_example",
+				"groups":   []interface{}{"technicians"},
+				"claims":   map[string]interface{}{"custom:accountIds": "acct-1, acct-2,,"},
+				"sourceIp": []interface{}{"10.0.0.1"},
+			},
+			expectedType:     IdentityTypeCognito,
+			expectedActor:    "abc-123",
+			expectedAccounts: []string{"acct-1", "acct-2"},
+		},
+		{
+			name: "OIDC provider",
+			identity: map[string]interface{}{
+				"sub":    "oidc|42",
+				"issuer": "https://login.example.com/",
+				"claims": map[string]interface{}{"custom:accountIds": []interface{}{"acct-1", "", "acct-3"}},
+			},
+			expectedType:     IdentityTypeOIDC,
+			expectedActor:    "oidc|42",
+			expectedAccounts: []string{"acct-1", "acct-3"},
+		},
+		{
+			name: "IAM",
+			identity: map[string]interface{}{
+				"accountId": "123456789012",
+				"userArn":   "arn:aws:iam::123456789012:user/jdoe",
+				"username":  "AIDAEXAMPLE",
+				"sourceIp":  []interface{}{"10.0.0.1"},
+			},
+			expectedType:     IdentityTypeIAM,
+			expectedActor:    "AIDAEXAMPLE",
+			expectedAccounts: nil,
+		},
+		{
+			name: "Lambda authorizer",
+			identity: map[string]interface{}{
+				"resolverContext": map[string]interface{}{
+					"sub":               "svc-7",
+					"custom:accountIds": "acct-9",
+				},
+			},
+			expectedType:     IdentityTypeLambda,
+			expectedActor:    "svc-7",
+			expectedAccounts: []string{"acct-9"},
+		},
+		{
+			name:             "No identity",
+			identity:         nil,
+			expectedType:     IdentityTypeNone,
+			expectedActor:    "anonymous",
+			expectedAccounts: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity := ParseIdentity(tt.identity)
+
+			assert.Equal(t, tt.expectedType, identity.Type)
+			assert.Equal(t, tt.expectedActor, identity.Actor())
+			assert.Equal(t, tt.expectedAccounts, identity.AccountIDs(DefaultAccountIDsClaim))
+		})
+	}
+}
+
+func TestIdentity_AccountIDs_ConfiguredClaim(t *testing.T) {
+	identity := ParseIdentity(map[string]interface{}{
+		"sub":      "abc-123",
+		"username": "jdoe",
+		"claims": map[string]interface{}{
+			"custom:accountIds": "acct-1",
+			"tenants":           "acct-2",
+		},
+	})
+
+	assert.Equal(t, []string{"acct-2"}, identity.AccountIDs("tenants"))
+	assert.True(t, identity.CanAccessAccount("tenants", "acct-2"))
+	assert.False(t, identity.CanAccessAccount("tenants", "acct-1"))
+	assert.Nil(t, identity.AccountIDs("missing"))
+}
+
+func TestIdentity_AccountIDs_IgnoresIAMClaims(t *testing.T) {
+	// An IAM caller's own AWS account is not a labor line account
+	identity := ParseIdentity(map[string]interface{}{
+		"accountId": "123456789012",
+		"userArn":   "arn:aws:iam::123456789012:role/service",
+	})
+
+	assert.False(t, identity.CanAccessAccount(DefaultAccountIDsClaim, "123456789012"))
+}
+
+func TestIdentity_IAMRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "Assumed role session",
+			identity: map[string]interface{}{"userArn": "arn:aws:sts::123456789012:assumed-role/labor-sync/session-1"},
+			expected: "arn:aws:iam::123456789012:role/labor-sync",
+		},
+		{
+			name:     "Role",
+			identity: map[string]interface{}{"userArn": "arn:aws:iam::123456789012:role/service"},
+			expected: "arn:aws:iam::123456789012:role/service",
+		},
+		{
+			name:     "IAM user",
+			identity: map[string]interface{}{"userArn": "arn:aws:iam::123456789012:user/jdoe"},
+		},
+		{
+			name:     "Cognito user",
+			identity: map[string]interface{}{"sub": "user-123", "claims": map[string]interface{}{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseIdentity(tt.identity).IAMRole())
+		})
+	}
+}
+
+func TestIAMRoleARN(t *testing.T) {
+	// A role's path is dropped, as session ARNs do not carry it
+	assert.Equal(t, "arn:aws:iam::123456789012:role/labor-sync", IAMRoleARN("arn:aws:iam::123456789012:role/services/labor-sync"))
+	assert.Equal(t, "arn:aws-us-gov:iam::123456789012:role/labor-sync", IAMRoleARN("arn:aws-us-gov:sts::123456789012:assumed-role/labor-sync/session-1"))
+	assert.Empty(t, IAMRoleARN("labor-sync"))
+	assert.Empty(t, IAMRoleARN("arn:aws:iam::123456789012:role/"))
+}
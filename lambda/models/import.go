@@ -0,0 +1,97 @@
+package models
+
+import (
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ImportFormat is the file format labor lines are imported from.
+type ImportFormat string
+
+const (
+	// ImportFormatCSV imports a labor line from each row after the header row.
+	ImportFormatCSV ImportFormat = "CSV"
+	// ImportFormatNDJSON imports a labor line from each line holding a JSON object.
+	ImportFormatNDJSON ImportFormat = "NDJSON"
+)
+
+// IsValid reports whether the format is one labor lines can be imported from.
+func (f ImportFormat) IsValid() bool {
+	return f == ImportFormatCSV || f == ImportFormatNDJSON
+}
+
+// ImportFormatOf returns the format of a file named name, judged by its
+// extension, and whether it is one labor lines can be imported from.
+func ImportFormatOf(name string) (ImportFormat, bool) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csv":
+		return ImportFormatCSV, true
+	case ".ndjson", ".jsonl":
+		return ImportFormatNDJSON, true
+	}
+	return "", false
+}
+
+// DefaultImportActor is recorded as the creator of imported labor lines when
+// the import does not name one.
+const DefaultImportActor = "import"
+
+// ImportLaborLinesInput describes a file of labor lines to import into an account.
+type ImportLaborLinesInput struct {
+	AccountID string       `json:"accountId"` // Rows may leave it out, but must not name another account
+	Source    string       `json:"source"`    // Name of the file, for the report
+	Format    ImportFormat `json:"format"`
+	DryRun    bool         `json:"dryRun,omitempty"` // Validate the rows without writing them
+	Actor     string       `json:"actor,omitempty"`  // Defaults to DefaultImportActor
+}
+
+// ImportRow is a labor line read from an import file.
+type ImportRow struct {
+	CreateLaborLineInput
+
+	// ImportKey identifies the row in the system it was exported from, so that
+	// importing it again does not create it twice. Rows without one are
+	// identified by their content.
+	ImportKey string `json:"importKey,omitempty"`
+}
+
+// ImportRowError describes why a row of an import file was not imported.
+type ImportRowError struct {
+	// Row is the position of the row in the file: its line in an NDJSON
+	// file, or its row as a spreadsheet numbers them in a CSV file, where the
+	// header is row 1
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportResult reports what an import did with each row of its file.
+type ImportResult struct {
+	Source       string           `json:"source"`
+	AccountID    string           `json:"accountId"`
+	DryRun       bool             `json:"dryRun"`
+	RowCount     int              `json:"rowCount"`
+	ValidCount   int              `json:"validCount"`   // Rows that passed validation
+	CreatedCount int              `json:"createdCount"` // Labor lines created; none in a dry run
+	SkippedCount int              `json:"skippedCount"` // Rows imported by an earlier run
+	FailedCount  int              `json:"failedCount"`  // Rows that are invalid or could not be written
+	Errors       []ImportRowError `json:"errors"`
+}
+
+// importNamespace derives the IDs of imported labor lines from their import
+// keys, so importing a row again yields the same labor line ID.
+var importNamespace = uuid.MustParse("3d9c2f6e-8b1a-4c57-a0e4-7f25b6d9c184")
+
+// NewImportedLaborLine creates the labor line imported from a row with the
+// given import key, with its cost calculated. Its ID is derived from the
+// account and key rather than random, so a row imported twice maps to the
+// same labor line.
+func NewImportedLaborLine(input CreateLaborLineInput, rateCard *RateCard, importKey string) *LaborLine {
+	laborLine := NewLaborLine(input, rateCard)
+	laborLine.LaborLineID = uuid.NewSHA1(importNamespace, []byte(input.AccountID+"#"+importKey)).String()
+	laborLine.SK = laborLine.TaskID + "#" + laborLine.LaborLineID
+	laborLine.RecalculateLaborCost()
+	return laborLine
+}
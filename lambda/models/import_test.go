@@ -0,0 +1,56 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportFormatOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		format ImportFormat
+		ok     bool
+	}{
+		{"imports/account/lines.csv", ImportFormatCSV, true},
+		{"LINES.CSV", ImportFormatCSV, true},
+		{"lines.ndjson", ImportFormatNDJSON, true},
+		{"lines.jsonl", ImportFormatNDJSON, true},
+		{"lines.xlsx", "", false},
+		{"lines", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, ok := ImportFormatOf(tt.name)
+			assert.Equal(t, tt.format, format)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func TestNewImportedLaborLine(t *testing.T) {
+	hours := MustParseDecimal("2")
+	rate := MustParseDecimal("95")
+	input := CreateLaborLineInput{
+		AccountID:      uuid.New().String(),
+		TaskID:         uuid.New().String(),
+		EstimatedHours: &hours,
+		RateType:       RateTypeFlatRate,
+		RatePerHour:    &rate,
+	}
+
+	first := NewImportedLaborLine(input, nil, "wo-1")
+	again := NewImportedLaborLine(input, nil, "wo-1")
+	other := NewImportedLaborLine(input, nil, "wo-2")
+
+	assert.Equal(t, first.LaborLineID, again.LaborLineID)
+	assert.NotEqual(t, first.LaborLineID, other.LaborLineID)
+	assert.Equal(t, input.TaskID+"#"+first.LaborLineID, first.SK)
+	assert.Equal(t, MustParseDecimal("190").String(), first.LaborCost.String())
+
+	// The same key in another account is another labor line
+	input.AccountID = uuid.New().String()
+	assert.NotEqual(t, first.LaborLineID, NewImportedLaborLine(input, nil, "wo-1").LaborLineID)
+}
@@ -16,15 +16,58 @@ type LaborLine struct {
 	TaskID      string `json:"taskId" dynamodbav:"taskId"`
 
 	// Optional fields from schema
-	PartID      []string `json:"partId,omitempty" dynamodbav:"partId,omitempty"`
-	Notes       []string `json:"notes,omitempty" dynamodbav:"notes,omitempty"`
-	Description string   `json:"description,omitempty" dynamodbav:"description,omitempty"`
+	Parts       []PartLineItem `json:"parts,omitempty" dynamodbav:"parts,omitempty"`
+	Description string         `json:"description,omitempty" dynamodbav:"description,omitempty"`
+
+	// OperationCode is the labor operation the labor line was created from, if any
+	OperationCode string `json:"operationCode,omitempty" dynamodbav:"operationCode,omitempty"`
+
+	// LegacyPartIDs holds the part IDs stored before parts were line items,
+	// until MigrateParts converts them
+	LegacyPartIDs []string `json:"-" dynamodbav:"partId,omitempty"`
+
+	// Notes are added, edited and deleted one at a time, each recording its author
+	Notes []Note `json:"notes,omitempty" dynamodbav:"notes,omitempty"`
+
+	// CustomFields holds the values of the account's custom fields by name
+	CustomFields map[string]interface{} `json:"customFields,omitempty" dynamodbav:"customFields,omitempty"`
+
+	// Labor hours: estimated (book) hours are entered, actual hours are rolled
+	// up from completed time entries
+	EstimatedHours Decimal `json:"estimatedHours" dynamodbav:"estimatedHours"`
+	ActualHours    Decimal `json:"actualHours" dynamodbav:"actualHours"`
+
+	// Billing: LaborCost is computed from the hours, rate type and rate
+	RateType    RateType `json:"rateType" dynamodbav:"rateType"`
+	RatePerHour Decimal  `json:"ratePerHour" dynamodbav:"ratePerHour"`
+	LaborCost   Decimal  `json:"laborCost" dynamodbav:"laborCost"`
+
+	// Lifecycle status and the record of every status change
+	Status        LaborLineStatus    `json:"status" dynamodbav:"status"`
+	StatusHistory []StatusTransition `json:"statusHistory,omitempty" dynamodbav:"statusHistory,omitempty"`
+
+	// AssignedTechnicianIDs are the technicians doing the work, changed with the
+	// assignment operations
+	AssignedTechnicianIDs []string `json:"assignedTechnicianIds,omitempty" dynamodbav:"assignedTechnicianIds,omitempty"`
+
+	// CreatedBy is the caller who created the labor line and owns it
+	CreatedBy string `json:"createdBy,omitempty" dynamodbav:"createdBy,omitempty"`
 
 	// Audit timestamps (epoch seconds)
 	CreatedAt int64  `json:"createdAt" dynamodbav:"createdAt"`
 	UpdatedAt int64  `json:"updatedAt" dynamodbav:"updatedAt"`
 	DeletedAt *int64 `json:"deletedAt,omitempty" dynamodbav:"deletedAt,omitempty"`
 
+	// ExpiresAt is the DynamoDB TTL of a soft-deleted labor line (epoch seconds)
+	ExpiresAt *int64 `json:"expiresAt,omitempty" dynamodbav:"expiresAt,omitempty"`
+
+	// Version is incremented on every write and used for optimistic concurrency
+	Version int64 `json:"version" dynamodbav:"version"`
+
+	// SchemaVersion is the version of the JSON schema the item was written
+	// with; zero for items written before it was recorded
+	SchemaVersion int `json:"schemaVersion,omitempty" dynamodbav:"schemaVersion,omitempty"`
+
 	// DynamoDB keys
 	PK string `json:"-" dynamodbav:"PK"` // accountId
 	SK string `json:"-" dynamodbav:"SK"` // {taskId}#{laborLineId}
@@ -32,21 +75,32 @@ type LaborLine struct {
 
 // CreateLaborLineInput represents the input for creating a new labor line.
 type CreateLaborLineInput struct {
-	AccountID   string   `json:"accountId"`
-	TaskID      string   `json:"taskId"`
-	PartID      []string `json:"partId,omitempty"`
-	Notes       []string `json:"notes,omitempty"`
-	Description string   `json:"description,omitempty"`
+	AccountID      string                 `json:"accountId"`
+	TaskID         string                 `json:"taskId"`
+	Parts          []PartLineItem         `json:"parts,omitempty"`
+	Notes          []NoteInput            `json:"notes,omitempty"`
+	Description    string                 `json:"description,omitempty"`
+	OperationCode  string                 `json:"operationCode,omitempty"` // Labor operation that fills in what the input leaves out
+	CustomFields   map[string]interface{} `json:"customFields,omitempty"`
+	EstimatedHours *Decimal               `json:"estimatedHours,omitempty"`
+	RateType       RateType               `json:"rateType,omitempty"`    // Defaults from the account's rate card
+	RatePerHour    *Decimal               `json:"ratePerHour,omitempty"` // Defaults from the account's rate card
 }
 
-// UpdateLaborLineInput represents the input for updating an existing labor line.
+// UpdateLaborLineInput represents a partial update of an existing labor line.
+// Fields left out of the input are unchanged and fields set to null are
+// cleared. Notes are changed with the note operations instead.
 type UpdateLaborLineInput struct {
-	LaborLineID string   `json:"laborLineId"`
-	AccountID   string   `json:"accountId"`
-	TaskID      string   `json:"taskId"`
-	PartID      []string `json:"partId,omitempty"`
-	Notes       []string `json:"notes,omitempty"`
-	Description string   `json:"description,omitempty"`
+	LaborLineID     string                           `json:"laborLineId"`
+	AccountID       string                           `json:"accountId"`
+	TaskID          string                           `json:"taskId"`
+	Parts           Nullable[[]PartLineItem]         `json:"parts,omitzero"`
+	Description     Nullable[string]                 `json:"description,omitzero"`
+	CustomFields    Nullable[map[string]interface{}] `json:"customFields,omitzero"`
+	EstimatedHours  Nullable[Decimal]                `json:"estimatedHours,omitzero"`
+	RateType        Nullable[RateType]               `json:"rateType,omitzero"`         // Null resets it to the account's rate card default
+	RatePerHour     Nullable[Decimal]                `json:"ratePerHour,omitzero"`      // Null resets it to the rate card's rate
+	ExpectedVersion *int64                           `json:"expectedVersion,omitempty"` // Optional optimistic concurrency check
 }
 
 // GetLaborLineInput represents the input for retrieving a labor line.
@@ -59,48 +113,147 @@ type GetLaborLineInput struct {
 // ListLaborLinesInput represents the input for listing labor lines.
 type ListLaborLinesInput struct {
 	AccountID string `json:"accountId"`
-	TaskID    string `json:"taskId,omitempty"` // Optional filter by task
+	TaskID    string `json:"taskId,omitempty"`    // Optional filter by task
+	Limit     int32  `json:"limit,omitempty"`     // Optional page size
+	NextToken string `json:"nextToken,omitempty"` // Opaque token from a previous page
+}
+
+// ListLaborLinesByTaskInput represents the input for listing a task's labor
+// lines across every account the caller is authorized for.
+type ListLaborLinesByTaskInput struct {
+	TaskID    string `json:"taskId"`
+	Limit     int32  `json:"limit,omitempty"`     // Optional page size
+	NextToken string `json:"nextToken,omitempty"` // Opaque token from a previous page
+}
+
+// LaborLineConnection represents a page of labor lines in the GraphQL connection shape.
+type LaborLineConnection struct {
+	Items     []*LaborLine `json:"items"`
+	NextToken *string      `json:"nextToken"` // nil when there are no more pages
 }
 
 // DeleteLaborLineInput represents the input for deleting a labor line.
 type DeleteLaborLineInput struct {
+	AccountID       string `json:"accountId"`
+	TaskID          string `json:"taskId"`
+	LaborLineID     string `json:"laborLineId"`
+	ExpectedVersion *int64 `json:"expectedVersion,omitempty"` // Optional optimistic concurrency check
+}
+
+// RestoreLaborLineInput represents the input for restoring a soft-deleted labor line.
+type RestoreLaborLineInput struct {
+	AccountID       string `json:"accountId"`
+	TaskID          string `json:"taskId"`
+	LaborLineID     string `json:"laborLineId"`
+	ExpectedVersion *int64 `json:"expectedVersion,omitempty"` // Optional optimistic concurrency check
+}
+
+// PurgeLaborLineInput represents the input for permanently removing a soft-deleted labor line.
+type PurgeLaborLineInput struct {
 	AccountID   string `json:"accountId"`
 	TaskID      string `json:"taskId"`
 	LaborLineID string `json:"laborLineId"`
 }
 
-// NewLaborLine creates a new LaborLine from CreateLaborLineInput.
-func NewLaborLine(input CreateLaborLineInput) *LaborLine {
+// NewLaborLine creates a new LaborLine from CreateLaborLineInput. A rate type
+// or rate per hour missing from the input is taken from the account's rate
+// card, which may be nil.
+func NewLaborLine(input CreateLaborLineInput, rateCard *RateCard) *LaborLine {
 	now := time.Now().Unix()
 	laborLineID := uuid.New().String()
+	rateType, ratePerHour := resolveRate(input.RateType, input.RatePerHour, rateCard)
 
 	return &LaborLine{
-		LaborLineID: laborLineID,
-		AccountID:   input.AccountID,
-		TaskID:      input.TaskID,
-		PartID:      input.PartID,
-		Notes:       input.Notes,
-		Description: input.Description,
-		CreatedAt:   now,
-		UpdatedAt:   now,
-		PK:          input.AccountID,
-		SK:          input.TaskID + "#" + laborLineID,
+		LaborLineID:    laborLineID,
+		AccountID:      input.AccountID,
+		TaskID:         input.TaskID,
+		Parts:          newPartLineItems(input.Parts),
+		Notes:          newNotes(input.Notes, now),
+		Description:    input.Description,
+		OperationCode:  input.OperationCode,
+		CustomFields:   input.CustomFields,
+		EstimatedHours: decimalOrZero(input.EstimatedHours),
+		RateType:       rateType,
+		RatePerHour:    ratePerHour,
+		Status:         StatusPending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Version:        1,
+		PK:             input.AccountID,
+		SK:             input.TaskID + "#" + laborLineID,
 	}
 }
 
-// ToLaborLine converts UpdateLaborLineInput to LaborLine for updates.
-func (input UpdateLaborLineInput) ToLaborLine() *LaborLine {
-	return &LaborLine{
-		LaborLineID: input.LaborLineID,
-		AccountID:   input.AccountID,
-		TaskID:      input.TaskID,
-		PartID:      input.PartID,
-		Notes:       input.Notes,
-		Description: input.Description,
-		UpdatedAt:   time.Now().Unix(),
-		PK:          input.AccountID,
-		SK:          input.TaskID + "#" + input.LaborLineID,
+// newNotes creates the notes a labor line is created with. Their author is
+// set with the creator by AssignCreator.
+func newNotes(inputs []NoteInput, now int64) []Note {
+	if inputs == nil {
+		return nil
+	}
+	notes := make([]Note, len(inputs))
+	for i, input := range inputs {
+		notes[i] = NewNote("", input.Visibility, input.Body)
+		notes[i].CreatedAt = now
 	}
+	return notes
+}
+
+// NeedsRateCard reports whether NewLaborLine takes the rate type or rate per
+// hour from the account's rate card.
+func (input CreateLaborLineInput) NeedsRateCard() bool {
+	return input.RateType == "" || input.RatePerHour == nil
+}
+
+// ApplyTo returns the labor line that results from applying the update to the
+// stored labor line, at the next version and with its cost recalculated. A
+// rate type that is reset, and a rate per hour that is reset or left out when
+// the rate type changes, are taken from the account's rate card, which may be
+// nil.
+func (input UpdateLaborLineInput) ApplyTo(existing *LaborLine, rateCard *RateCard) *LaborLine {
+	updated := *existing
+
+	if input.Parts.Set {
+		updated.Parts = newPartLineItems(input.Parts.Value)
+	}
+	if input.Description.Set {
+		updated.Description = input.Description.Value
+	}
+	if input.CustomFields.Set {
+		updated.CustomFields = input.CustomFields.Value
+	}
+	if input.EstimatedHours.Set {
+		updated.EstimatedHours = input.EstimatedHours.Value
+	}
+	if input.RateType.Set || input.RatePerHour.Set {
+		rateType := existing.RateType
+		if input.RateType.Set {
+			rateType = input.RateType.Value
+		}
+		var ratePerHour *Decimal
+		if input.RatePerHour.HasValue() {
+			ratePerHour = &input.RatePerHour.Value
+		}
+		updated.RateType, updated.RatePerHour = resolveRate(rateType, ratePerHour, rateCard)
+	}
+
+	updated.UpdatedAt = time.Now().Unix()
+	updated.Version = existing.Version + 1
+	updated.RecalculateLaborCost()
+	return &updated
+}
+
+// NeedsRateCard reports whether ApplyTo takes the rate type or rate per hour
+// from the account's rate card.
+func (input UpdateLaborLineInput) NeedsRateCard() bool {
+	if !input.RateType.Set && !input.RatePerHour.Set {
+		return false
+	}
+	return input.RateType.Null || !input.RatePerHour.HasValue()
+}
+
+// IsOwnedBy reports whether the labor line was created by the given actor.
+func (ll *LaborLine) IsOwnedBy(actor string) bool {
+	return ll.CreatedBy != "" && ll.CreatedBy == actor
 }
 
 // IsDeleted returns true if the labor line has been soft deleted.
@@ -114,3 +267,23 @@ func (ll *LaborLine) SoftDelete() {
 	ll.DeletedAt = &now
 	ll.UpdatedAt = now
 }
+
+// ExpireAfter schedules a soft-deleted labor line to be removed by DynamoDB TTL
+// once the retention period since its deletion has passed. A zero retention
+// keeps it indefinitely.
+func (ll *LaborLine) ExpireAfter(retention time.Duration) {
+	if ll.DeletedAt == nil || retention <= 0 {
+		ll.ExpiresAt = nil
+		return
+	}
+
+	expiresAt := *ll.DeletedAt + int64(retention/time.Second)
+	ll.ExpiresAt = &expiresAt
+}
+
+// Restore clears the soft delete and any scheduled expiry.
+func (ll *LaborLine) Restore() {
+	ll.DeletedAt = nil
+	ll.ExpiresAt = nil
+	ll.UpdatedAt = time.Now().Unix()
+}
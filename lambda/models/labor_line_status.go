@@ -0,0 +1,102 @@
+package models
+
+import "time"
+
+// LaborLineStatus is the lifecycle state of a labor line.
+type LaborLineStatus string
+
+const (
+	// StatusPending is the initial state of a new labor line.
+	StatusPending LaborLineStatus = "PENDING"
+	// StatusInProgress means a technician is working on the labor line.
+	StatusInProgress LaborLineStatus = "IN_PROGRESS"
+	// StatusOnHold means work has been paused, for example waiting on parts.
+	StatusOnHold LaborLineStatus = "ON_HOLD"
+	// StatusCompleted means the work is done and awaiting approval or invoicing.
+	StatusCompleted LaborLineStatus = "COMPLETED"
+	// StatusApproved means the completed work has been approved for billing.
+	StatusApproved LaborLineStatus = "APPROVED"
+	// StatusInvoiced means the labor line has been billed. It is a final state.
+	StatusInvoiced LaborLineStatus = "INVOICED"
+)
+
+// statusTransitions lists the statuses each status may move to.
+var statusTransitions = map[LaborLineStatus][]LaborLineStatus{
+	StatusPending:    {StatusInProgress, StatusOnHold},
+	StatusInProgress: {StatusOnHold, StatusCompleted},
+	StatusOnHold:     {StatusInProgress, StatusCompleted},
+	StatusCompleted:  {StatusInProgress, StatusApproved, StatusInvoiced}, // Reopening returns it to IN_PROGRESS
+	StatusApproved:   {StatusInvoiced},
+	StatusInvoiced:   {},
+}
+
+// StatusTransition records a single change of a labor line's status.
+type StatusTransition struct {
+	From      LaborLineStatus `json:"from" dynamodbav:"from"`
+	To        LaborLineStatus `json:"to" dynamodbav:"to"`
+	ChangedBy string          `json:"changedBy" dynamodbav:"changedBy"`
+	ChangedAt int64           `json:"changedAt" dynamodbav:"changedAt"`
+	Reason    string          `json:"reason,omitempty" dynamodbav:"reason,omitempty"`
+}
+
+// TransitionLaborLineStatusInput represents the input for moving a labor line to a new status.
+type TransitionLaborLineStatusInput struct {
+	AccountID       string          `json:"accountId"`
+	TaskID          string          `json:"taskId"`
+	LaborLineID     string          `json:"laborLineId"`
+	Status          LaborLineStatus `json:"status"`
+	Reason          string          `json:"reason,omitempty"`
+	ExpectedVersion *int64          `json:"expectedVersion,omitempty"` // Optional optimistic concurrency check
+}
+
+// IsValid returns true if the status is one of the known statuses.
+func (s LaborLineStatus) IsValid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+// AllowedTransitions returns the statuses the given status may move to.
+func AllowedTransitions(from LaborLineStatus) []LaborLineStatus {
+	return statusTransitions[from]
+}
+
+// CanTransition reports whether a labor line may move from one status to another.
+func CanTransition(from, to LaborLineStatus) bool {
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentStatus returns the labor line's status. Labor lines created before
+// statuses were introduced have none and are treated as pending.
+func (ll *LaborLine) CurrentStatus() LaborLineStatus {
+	if ll.Status == "" {
+		return StatusPending
+	}
+	return ll.Status
+}
+
+// IsReadOnly returns true once the labor line's work has been completed; its
+// fields can then only change by reopening it.
+func (ll *LaborLine) IsReadOnly() bool {
+	switch ll.CurrentStatus() {
+	case StatusCompleted, StatusApproved, StatusInvoiced:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewStatusTransition creates the record of a labor line moving to a new status.
+func (ll *LaborLine) NewStatusTransition(to LaborLineStatus, changedBy, reason string) StatusTransition {
+	return StatusTransition{
+		From:      ll.CurrentStatus(),
+		To:        to,
+		ChangedBy: changedBy,
+		ChangedAt: time.Now().Unix(),
+		Reason:    reason,
+	}
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from     LaborLineStatus
+		to       LaborLineStatus
+		expected bool
+	}{
+		{from: StatusPending, to: StatusInProgress, expected: true},
+		{from: StatusPending, to: StatusOnHold, expected: true},
+		{from: StatusPending, to: StatusCompleted, expected: false},
+		{from: StatusPending, to: StatusInvoiced, expected: false},
+		{from: StatusInProgress, to: StatusOnHold, expected: true},
+		{from: StatusInProgress, to: StatusCompleted, expected: true},
+		{from: StatusInProgress, to: StatusPending, expected: false},
+		{from: StatusOnHold, to: StatusInProgress, expected: true},
+		{from: StatusOnHold, to: StatusCompleted, expected: true},
+		{from: StatusOnHold, to: StatusApproved, expected: false},
+		{from: StatusCompleted, to: StatusApproved, expected: true},
+		{from: StatusCompleted, to: StatusInvoiced, expected: true},
+		{from: StatusCompleted, to: StatusInProgress, expected: true},
+		{from: StatusCompleted, to: StatusOnHold, expected: false},
+		{from: StatusApproved, to: StatusInvoiced, expected: true},
+		{from: StatusApproved, to: StatusInProgress, expected: false},
+		{from: StatusInvoiced, to: StatusInProgress, expected: false},
+		{from: StatusInvoiced, to: StatusApproved, expected: false},
+		{from: StatusInProgress, to: StatusInProgress, expected: false},
+		{from: StatusPending, to: "CANCELLED", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			assert.Equal(t, tt.expected, CanTransition(tt.from, tt.to))
+		})
+	}
+}
+
+func TestAllowedTransitions(t *testing.T) {
+	assert.ElementsMatch(t, []LaborLineStatus{StatusOnHold, StatusCompleted}, AllowedTransitions(StatusInProgress))
+	assert.Empty(t, AllowedTransitions(StatusInvoiced))
+	assert.Empty(t, AllowedTransitions("UNKNOWN"))
+}
+
+func TestLaborLine_CurrentStatus(t *testing.T) {
+	assert.Equal(t, StatusPending, (&LaborLine{}).CurrentStatus(), "legacy labor lines are pending")
+	assert.Equal(t, StatusOnHold, (&LaborLine{Status: StatusOnHold}).CurrentStatus())
+}
+
+func TestLaborLine_IsReadOnly(t *testing.T) {
+	tests := []struct {
+		status   LaborLineStatus
+		expected bool
+	}{
+		{status: "", expected: false},
+		{status: StatusPending, expected: false},
+		{status: StatusInProgress, expected: false},
+		{status: StatusOnHold, expected: false},
+		{status: StatusCompleted, expected: true},
+		{status: StatusApproved, expected: true},
+		{status: StatusInvoiced, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			laborLine := &LaborLine{Status: tt.status}
+			assert.Equal(t, tt.expected, laborLine.IsReadOnly())
+		})
+	}
+}
+
+func TestLaborLine_NewStatusTransition(t *testing.T) {
+	laborLine := &LaborLine{Status: StatusInProgress}
+
+	startTime := time.Now().Unix()
+	transition := laborLine.NewStatusTransition(StatusOnHold, "user-123", "waiting on parts")
+
+	assert.Equal(t, StatusInProgress, transition.From)
+	assert.Equal(t, StatusOnHold, transition.To)
+	assert.Equal(t, "user-123", transition.ChangedBy)
+	assert.Equal(t, "waiting on parts", transition.Reason)
+	assert.GreaterOrEqual(t, transition.ChangedAt, startTime)
+
+	// Creating the record does not change the labor line itself
+	assert.Equal(t, StatusInProgress, laborLine.Status)
+}
@@ -17,11 +17,17 @@ func TestNewLaborLine(t *testing.T) {
 		{
 			name: "Valid input with all fields",
 			input: CreateLaborLineInput{
-				AccountID:   uuid.New().String(),
-				TaskID:      uuid.New().String(),
-				PartID:      []string{uuid.New().String(), uuid.New().String()},
-				Notes:       []string{"First note", "Second note"},
-				Description: "Complete brake system maintenance",
+				AccountID: uuid.New().String(),
+				TaskID:    uuid.New().String(),
+				Parts: []PartLineItem{
+					{PartID: uuid.New().String(), Quantity: NewDecimalFromInt(2)},
+					{PartID: uuid.New().String(), Quantity: MustParseDecimal("0.5"), UnitOfMeasure: "QT", Status: PartStatusIssued},
+				},
+				Notes:          []NoteInput{{Body: "First note"}, {Visibility: NoteVisibilityCustomer, Body: "Second note"}},
+				Description:    "Complete brake system maintenance",
+				EstimatedHours: func() *Decimal { d := MustParseDecimal("1.5"); return &d }(),
+				RateType:       RateTypeFlatRate,
+				RatePerHour:    func() *Decimal { d := MustParseDecimal("110"); return &d }(),
 			},
 		},
 		{
@@ -36,7 +42,7 @@ func TestNewLaborLine(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			startTime := time.Now().Unix()
-			laborLine := NewLaborLine(tt.input)
+			laborLine := NewLaborLine(tt.input, nil)
 
 			// Verify required fields
 			assert.NotEmpty(t, laborLine.LaborLineID)
@@ -44,16 +50,36 @@ func TestNewLaborLine(t *testing.T) {
 			assert.Equal(t, tt.input.TaskID, laborLine.TaskID)
 
 			// Verify optional fields
-			assert.Equal(t, tt.input.PartID, laborLine.PartID)
-			assert.Equal(t, tt.input.Notes, laborLine.Notes)
+			require.Len(t, laborLine.Parts, len(tt.input.Parts))
+			for i, part := range laborLine.Parts {
+				assert.Equal(t, tt.input.Parts[i].PartID, part.PartID)
+				assert.True(t, tt.input.Parts[i].Quantity.Equal(part.Quantity))
+			}
+			require.Len(t, laborLine.Notes, len(tt.input.Notes))
+			for i, note := range laborLine.Notes {
+				assert.NotEmpty(t, note.NoteID)
+				assert.Equal(t, tt.input.Notes[i].Body, note.Body)
+				assert.Equal(t, laborLine.CreatedAt, note.CreatedAt)
+			}
 			assert.Equal(t, tt.input.Description, laborLine.Description)
 
+			// Verify billing fields; cost is computed separately
+			assert.True(t, decimalOrZero(tt.input.EstimatedHours).Equal(laborLine.EstimatedHours))
+			assert.True(t, decimalOrZero(tt.input.RatePerHour).Equal(laborLine.RatePerHour))
+			assert.NotEmpty(t, laborLine.RateType)
+			assert.True(t, laborLine.LaborCost.IsZero())
+
 			// Verify timestamps
 			assert.GreaterOrEqual(t, laborLine.CreatedAt, startTime)
 			assert.GreaterOrEqual(t, laborLine.UpdatedAt, startTime)
 			assert.Equal(t, laborLine.CreatedAt, laborLine.UpdatedAt)
 			assert.Nil(t, laborLine.DeletedAt)
 
+			// Verify new labor lines start pending at version 1
+			assert.Equal(t, StatusPending, laborLine.Status)
+			assert.Empty(t, laborLine.StatusHistory)
+			assert.Equal(t, int64(1), laborLine.Version)
+
 			// Verify DynamoDB keys
 			assert.Equal(t, tt.input.AccountID, laborLine.PK)
 			assert.Equal(t, tt.input.TaskID+"#"+laborLine.LaborLineID, laborLine.SK)
@@ -65,37 +91,109 @@ func TestNewLaborLine(t *testing.T) {
 	}
 }
 
-func TestUpdateLaborLineInput_ToLaborLine(t *testing.T) {
-	input := UpdateLaborLineInput{
-		LaborLineID: uuid.New().String(),
+func TestUpdateLaborLineInput_ApplyTo(t *testing.T) {
+	existing := NewLaborLine(CreateLaborLineInput{
 		AccountID:   uuid.New().String(),
 		TaskID:      uuid.New().String(),
-		PartID:      []string{uuid.New().String()},
-		Notes:       []string{"Updated note"},
-		Description: "Updated brake system maintenance task",
+		Parts:       []PartLineItem{{PartID: uuid.New().String(), Quantity: NewDecimalFromInt(1)}},
+		Description: "Brake system maintenance",
+	}, nil)
+	existing.Version = 3
+	existing.ActualHours = NewDecimalFromInt(2)
+
+	input := UpdateLaborLineInput{
+		LaborLineID: existing.LaborLineID,
+		AccountID:   existing.AccountID,
+		TaskID:      existing.TaskID,
+		Description: NullValue[string](),
+		RatePerHour: NewNullable(NewDecimalFromInt(100)),
 	}
 
 	startTime := time.Now().Unix()
-	laborLine := input.ToLaborLine()
+	updated := input.ApplyTo(existing, nil)
 
-	// Verify all fields are set correctly
-	assert.Equal(t, input.LaborLineID, laborLine.LaborLineID)
-	assert.Equal(t, input.AccountID, laborLine.AccountID)
-	assert.Equal(t, input.TaskID, laborLine.TaskID)
-	assert.Equal(t, input.PartID, laborLine.PartID)
-	assert.Equal(t, input.Notes, laborLine.Notes)
-	assert.Equal(t, input.Description, laborLine.Description)
+	// Fields left out of the input are unchanged
+	assert.Equal(t, existing.Parts, updated.Parts)
+	assert.Equal(t, existing.CreatedAt, updated.CreatedAt)
+	assert.Equal(t, existing.PK, updated.PK)
+	assert.Equal(t, existing.SK, updated.SK)
 
-	// Verify DynamoDB keys
-	assert.Equal(t, input.AccountID, laborLine.PK)
-	assert.Equal(t, input.TaskID+"#"+input.LaborLineID, laborLine.SK)
+	// Null clears a field
+	assert.Empty(t, updated.Description)
+	assert.Equal(t, "Brake system maintenance", existing.Description)
 
-	// Verify UpdatedAt is set
-	assert.GreaterOrEqual(t, laborLine.UpdatedAt, startTime)
+	// The rate type is kept and the cost recalculated with the new rate
+	assert.Equal(t, existing.RateType, updated.RateType)
+	assert.Equal(t, "100", updated.RatePerHour.String())
+	assert.Equal(t, "200", updated.LaborCost.String())
+
+	assert.Equal(t, int64(4), updated.Version)
+	assert.GreaterOrEqual(t, updated.UpdatedAt, startTime)
+}
+
+func TestUpdateLaborLineInput_ApplyTo_ResetsRateFromRateCard(t *testing.T) {
+	existing := NewLaborLine(CreateLaborLineInput{
+		AccountID:   uuid.New().String(),
+		TaskID:      uuid.New().String(),
+		RateType:    RateTypeWarranty,
+		RatePerHour: &Decimal{},
+	}, nil)
+	card := &RateCard{
+		DefaultRateType: RateTypeFlatRate,
+		Rates:           []RateCardEntry{{RateType: RateTypeFlatRate, RatePerHour: NewDecimalFromInt(90)}},
+	}
+
+	input := UpdateLaborLineInput{RateType: NullValue[RateType](), RatePerHour: NullValue[Decimal]()}
+	assert.True(t, input.NeedsRateCard())
+
+	updated := input.ApplyTo(existing, card)
+	assert.Equal(t, RateTypeFlatRate, updated.RateType)
+	assert.Equal(t, "90", updated.RatePerHour.String())
+}
+
+func TestUpdateLaborLineInput_NeedsRateCard(t *testing.T) {
+	assert.False(t, UpdateLaborLineInput{}.NeedsRateCard())
+	assert.False(t, UpdateLaborLineInput{RatePerHour: NewNullable(NewDecimalFromInt(80))}.NeedsRateCard())
+	assert.True(t, UpdateLaborLineInput{RateType: NewNullable(RateTypeFlatRate)}.NeedsRateCard())
+	assert.True(t, UpdateLaborLineInput{RateType: NullValue[RateType](), RatePerHour: NewNullable(NewDecimalFromInt(80))}.NeedsRateCard())
+}
+
+func TestLaborLine_IsOwnedBy(t *testing.T) {
+	tests := []struct {
+		name      string
+		createdBy string
+		actor     string
+		expected  bool
+	}{
+		{
+			name:      "Creator",
+			createdBy: "user-123",
+			actor:     "user-123",
+			expected:  true,
+		},
+		{
+			name:      "Someone else",
+			createdBy: "user-123",
+			actor:     "user-456",
+			expected:  false,
+		},
+		{
+			name:      "Created before owners were recorded",
+			createdBy: "",
+			actor:     "",
+			expected:  false,
+		},
+	}
 
-	// Verify CreatedAt and DeletedAt are not set (will be set during update)
-	assert.Zero(t, laborLine.CreatedAt)
-	assert.Nil(t, laborLine.DeletedAt)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			laborLine := &LaborLine{
+				CreatedBy: tt.createdBy,
+			}
+
+			assert.Equal(t, tt.expected, laborLine.IsOwnedBy(tt.actor))
+		})
+	}
 }
 
 func TestLaborLine_IsDeleted(t *testing.T) {
@@ -147,3 +245,32 @@ func TestLaborLine_SoftDelete(t *testing.T) {
 	// Verify IsDeleted returns true
 	assert.True(t, laborLine.IsDeleted())
 }
+
+func TestLaborLine_ExpireAfter(t *testing.T) {
+	deletedAt := int64(1700000000)
+
+	laborLine := &LaborLine{DeletedAt: &deletedAt}
+	laborLine.ExpireAfter(30 * 24 * time.Hour)
+	require.NotNil(t, laborLine.ExpiresAt)
+	assert.Equal(t, deletedAt+30*24*60*60, *laborLine.ExpiresAt)
+
+	// No retention keeps the labor line indefinitely
+	laborLine.ExpireAfter(0)
+	assert.Nil(t, laborLine.ExpiresAt)
+
+	// Live labor lines never expire
+	live := &LaborLine{}
+	live.ExpireAfter(time.Hour)
+	assert.Nil(t, live.ExpiresAt)
+}
+
+func TestLaborLine_Restore(t *testing.T) {
+	laborLine := &LaborLine{LaborLineID: uuid.New().String()}
+	laborLine.SoftDelete()
+	laborLine.ExpireAfter(time.Hour)
+
+	laborLine.Restore()
+
+	assert.False(t, laborLine.IsDeleted())
+	assert.Nil(t, laborLine.ExpiresAt)
+}
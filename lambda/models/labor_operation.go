@@ -0,0 +1,185 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// RecordTypeLaborOperation identifies labor operation items stored alongside labor lines.
+const RecordTypeLaborOperation = "LABOR_OPERATION"
+
+// laborOperationSKPrefix prefixes the sort key of each operation in an account's labor operation catalog.
+const laborOperationSKPrefix = "LABOROP#"
+
+// LaborOperation is a standard repair in an account's labor operation catalog,
+// such as a VMRS or flat-rate code, with the book hours it takes. A labor line
+// created from an operation copies its description, hours and rate, so later
+// changes to the operation do not affect existing labor lines.
+type LaborOperation struct {
+	AccountID   string   `json:"accountId" dynamodbav:"accountId"`
+	Code        string   `json:"code" dynamodbav:"code"`
+	Description string   `json:"description" dynamodbav:"description"`
+	BookHours   Decimal  `json:"bookHours" dynamodbav:"bookHours"`
+	RateType    RateType `json:"rateType,omitempty" dynamodbav:"rateType,omitempty"`       // Optional; labor lines otherwise take it from the rate card
+	RatePerHour *Decimal `json:"ratePerHour,omitempty" dynamodbav:"ratePerHour,omitempty"` // Optional; labor lines otherwise take it from the rate card
+	Active      bool     `json:"active" dynamodbav:"active"`                               // Only active operations may be referenced by new labor lines
+	CreatedAt   int64    `json:"createdAt" dynamodbav:"createdAt"`
+	UpdatedAt   int64    `json:"updatedAt" dynamodbav:"updatedAt"`
+
+	// SearchText is the lowercased code and description that keyword searches
+	// match, as DynamoDB's contains is case sensitive
+	SearchText string `json:"-" dynamodbav:"searchText"`
+
+	// DynamoDB keys
+	RecordType string `json:"-" dynamodbav:"recordType"`
+	PK         string `json:"-" dynamodbav:"PK"` // accountId
+	SK         string `json:"-" dynamodbav:"SK"` // LABOROP#{code}
+}
+
+// CreateLaborOperationInput represents the input for adding an operation to an account's catalog.
+type CreateLaborOperationInput struct {
+	AccountID   string   `json:"accountId"`
+	Code        string   `json:"code"`
+	Description string   `json:"description"`
+	BookHours   Decimal  `json:"bookHours"`
+	RateType    RateType `json:"rateType,omitempty"`
+	RatePerHour *Decimal `json:"ratePerHour,omitempty"`
+	Active      *bool    `json:"active,omitempty"` // Defaults to true
+}
+
+// UpdateLaborOperationInput represents a partial update of a labor operation.
+// Fields left out of the input are unchanged; the rate type and rate per hour
+// are cleared when set to null.
+type UpdateLaborOperationInput struct {
+	AccountID   string             `json:"accountId"`
+	Code        string             `json:"code"`
+	Description Nullable[string]   `json:"description,omitzero"`
+	BookHours   Nullable[Decimal]  `json:"bookHours,omitzero"`
+	RateType    Nullable[RateType] `json:"rateType,omitzero"`
+	RatePerHour Nullable[Decimal]  `json:"ratePerHour,omitzero"`
+	Active      Nullable[bool]     `json:"active,omitzero"`
+}
+
+// GetLaborOperationInput represents the input for retrieving a labor operation.
+type GetLaborOperationInput struct {
+	AccountID string `json:"accountId"`
+	Code      string `json:"code"`
+}
+
+// DeleteLaborOperationInput represents the input for removing an operation from an account's catalog.
+type DeleteLaborOperationInput struct {
+	AccountID string `json:"accountId"`
+	Code      string `json:"code"`
+}
+
+// SearchLaborOperationsInput represents the input for searching an account's
+// labor operations, ordered by code. Without a code prefix or keyword every
+// operation matches.
+type SearchLaborOperationsInput struct {
+	AccountID       string `json:"accountId"`
+	CodePrefix      string `json:"codePrefix,omitempty"`      // Optional start of the code
+	Keyword         string `json:"keyword,omitempty"`         // Optional text the code or description contains, ignoring case
+	IncludeInactive bool   `json:"includeInactive,omitempty"` // Whether inactive operations are returned
+	Limit           int32  `json:"limit,omitempty"`           // Optional page size
+	NextToken       string `json:"nextToken,omitempty"`       // Opaque token from a previous page
+}
+
+// LaborOperationConnection represents a page of labor operations in the GraphQL connection shape.
+type LaborOperationConnection struct {
+	Items     []*LaborOperation `json:"items"`
+	NextToken *string           `json:"nextToken"` // nil when there are no more pages
+}
+
+// ToLaborOperation converts CreateLaborOperationInput to a LaborOperation.
+func (input CreateLaborOperationInput) ToLaborOperation() *LaborOperation {
+	now := time.Now().Unix()
+	pk, sk := LaborOperationKey(input.AccountID, input.Code)
+	active := input.Active == nil || *input.Active
+
+	operation := &LaborOperation{
+		AccountID:   input.AccountID,
+		Code:        input.Code,
+		Description: input.Description,
+		BookHours:   input.BookHours,
+		RateType:    input.RateType,
+		RatePerHour: input.RatePerHour,
+		Active:      active,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		RecordType:  RecordTypeLaborOperation,
+		PK:          pk,
+		SK:          sk,
+	}
+	operation.SearchText = operation.searchText()
+	return operation
+}
+
+// HasChanges reports whether the update sets any field of the labor operation.
+func (input UpdateLaborOperationInput) HasChanges() bool {
+	return input.Description.Set || input.BookHours.Set || input.RateType.Set || input.RatePerHour.Set || input.Active.Set
+}
+
+// ApplyTo returns the labor operation that results from applying the update to the stored one.
+func (input UpdateLaborOperationInput) ApplyTo(existing *LaborOperation) *LaborOperation {
+	updated := *existing
+
+	if input.Description.HasValue() {
+		updated.Description = input.Description.Value
+	}
+	if input.BookHours.HasValue() {
+		updated.BookHours = input.BookHours.Value
+	}
+	if input.RateType.Set {
+		updated.RateType = input.RateType.Value
+	}
+	if input.RatePerHour.Set {
+		updated.RatePerHour = nil
+		if input.RatePerHour.HasValue() {
+			ratePerHour := input.RatePerHour.Value
+			updated.RatePerHour = &ratePerHour
+		}
+	}
+	if input.Active.HasValue() {
+		updated.Active = input.Active.Value
+	}
+
+	updated.SearchText = updated.searchText()
+	updated.UpdatedAt = time.Now().Unix()
+	return &updated
+}
+
+// searchText returns the text keyword searches of the operation match.
+func (op *LaborOperation) searchText() string {
+	return strings.ToLower(op.Code + " " + op.Description)
+}
+
+// WithOperation returns the input with the description, estimated hours, rate
+// type and rate per hour it leaves out filled in from the labor operation.
+func (input CreateLaborLineInput) WithOperation(op *LaborOperation) CreateLaborLineInput {
+	if input.Description == "" {
+		input.Description = op.Description
+	}
+	if input.EstimatedHours == nil {
+		bookHours := op.BookHours
+		input.EstimatedHours = &bookHours
+	}
+	if input.RateType == "" {
+		input.RateType = op.RateType
+	}
+	if input.RatePerHour == nil && op.RatePerHour != nil {
+		ratePerHour := *op.RatePerHour
+		input.RatePerHour = &ratePerHour
+	}
+	return input
+}
+
+// LaborOperationKey returns the partition and sort key of an account's labor operation.
+func LaborOperationKey(accountID, code string) (string, string) {
+	return accountID, laborOperationSKPrefix + code
+}
+
+// LaborOperationKeyPrefix returns the partition key and the sort key prefix
+// shared by an account's labor operations whose code starts with codePrefix.
+func LaborOperationKeyPrefix(accountID, codePrefix string) (string, string) {
+	return accountID, laborOperationSKPrefix + codePrefix
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateLaborOperationInput_ToLaborOperation(t *testing.T) {
+	operation := CreateLaborOperationInput{
+		AccountID:   "acct-1",
+		Code:        "013-001-001",
+		Description: "Replace Front Brake Pads",
+		BookHours:   MustParseDecimal("1.5"),
+	}.ToLaborOperation()
+
+	assert.Equal(t, "acct-1", operation.PK)
+	assert.Equal(t, "LABOROP#013-001-001", operation.SK)
+	assert.Equal(t, RecordTypeLaborOperation, operation.RecordType)
+	assert.Equal(t, "013-001-001 replace front brake pads", operation.SearchText)
+	// Operations are active unless created otherwise
+	assert.True(t, operation.Active)
+}
+
+func TestUpdateLaborOperationInput_ApplyTo(t *testing.T) {
+	ratePerHour := MustParseDecimal("120")
+	existing := &LaborOperation{
+		Code:        "013-001-001",
+		Description: "Replace front brake pads",
+		BookHours:   MustParseDecimal("1.5"),
+		RateType:    RateTypeFlatRate,
+		RatePerHour: &ratePerHour,
+		Active:      true,
+	}
+
+	updated := UpdateLaborOperationInput{
+		BookHours:   NewNullable(MustParseDecimal("2")),
+		RateType:    NullValue[RateType](),
+		RatePerHour: NullValue[Decimal](),
+	}.ApplyTo(existing)
+
+	assert.Equal(t, "2.00", updated.BookHours.StringFixed(2))
+	assert.Empty(t, updated.RateType)
+	assert.Nil(t, updated.RatePerHour)
+	assert.Equal(t, existing.Description, updated.Description)
+	assert.True(t, updated.Active)
+	// The stored operation is unchanged
+	assert.Equal(t, RateTypeFlatRate, existing.RateType)
+}
+
+func TestCreateLaborLineInput_WithOperation(t *testing.T) {
+	ratePerHour := MustParseDecimal("120")
+	operation := &LaborOperation{
+		Code:        "013-001-001",
+		Description: "Replace front brake pads",
+		BookHours:   MustParseDecimal("1.5"),
+		RateType:    RateTypeFlatRate,
+		RatePerHour: &ratePerHour,
+	}
+
+	filled := CreateLaborLineInput{OperationCode: "013-001-001"}.WithOperation(operation)
+	assert.Equal(t, "Replace front brake pads", filled.Description)
+	assert.Equal(t, "1.50", filled.EstimatedHours.StringFixed(2))
+	assert.Equal(t, RateTypeFlatRate, filled.RateType)
+	assert.Equal(t, "120.00", filled.RatePerHour.StringFixed(2))
+	assert.False(t, filled.NeedsRateCard())
+
+	// What the input gives is kept
+	estimatedHours := MustParseDecimal("3")
+	kept := CreateLaborLineInput{
+		Description:    "Replace pads and rotors",
+		EstimatedHours: &estimatedHours,
+		RateType:       RateTypeWarranty,
+	}.WithOperation(operation)
+	assert.Equal(t, "Replace pads and rotors", kept.Description)
+	assert.Equal(t, "3.00", kept.EstimatedHours.StringFixed(2))
+	assert.Equal(t, RateTypeWarranty, kept.RateType)
+}
@@ -0,0 +1,134 @@
+package models
+
+import "time"
+
+// RecordTypeRateCard identifies rate card items stored alongside labor lines.
+const RecordTypeRateCard = "RATE_CARD"
+
+// rateCardSK is the sort key of an account's rate card.
+const rateCardSK = "RATECARD"
+
+// RateType determines how a labor line is billed.
+type RateType string
+
+const (
+	// RateTypeFlatRate bills the book (estimated) hours regardless of time worked.
+	RateTypeFlatRate RateType = "FLAT_RATE"
+	// RateTypeHourly bills the actual hours clocked against the labor line.
+	RateTypeHourly RateType = "HOURLY"
+	// RateTypeWarranty bills the book hours at the warranty rate.
+	RateTypeWarranty RateType = "WARRANTY"
+	// RateTypeInternal costs the actual hours at the internal rate.
+	RateTypeInternal RateType = "INTERNAL"
+)
+
+// DefaultRateType is used when neither the input nor the account's rate card specify one.
+const DefaultRateType = RateTypeHourly
+
+// costPlaces is the number of decimal places labor cost is rounded to.
+const costPlaces = 2
+
+// RateCard holds an account's default labor rates. There is one rate card per
+// account, stored in the labor lines table.
+type RateCard struct {
+	AccountID       string          `json:"accountId" dynamodbav:"accountId"`
+	DefaultRateType RateType        `json:"defaultRateType" dynamodbav:"defaultRateType"`
+	Rates           []RateCardEntry `json:"rates" dynamodbav:"rates"`
+	UpdatedAt       int64           `json:"updatedAt" dynamodbav:"updatedAt"`
+
+	// DynamoDB keys
+	RecordType string `json:"-" dynamodbav:"recordType"`
+	PK         string `json:"-" dynamodbav:"PK"` // accountId
+	SK         string `json:"-" dynamodbav:"SK"` // RATECARD
+}
+
+// RateCardEntry is the hourly rate for a single rate type.
+type RateCardEntry struct {
+	RateType    RateType `json:"rateType" dynamodbav:"rateType"`
+	RatePerHour Decimal  `json:"ratePerHour" dynamodbav:"ratePerHour"`
+}
+
+// GetRateCardInput represents the input for retrieving an account's rate card.
+type GetRateCardInput struct {
+	AccountID string `json:"accountId"`
+}
+
+// UpdateRateCardInput represents the input for replacing an account's rate card.
+type UpdateRateCardInput struct {
+	AccountID       string          `json:"accountId"`
+	DefaultRateType RateType        `json:"defaultRateType,omitempty"`
+	Rates           []RateCardEntry `json:"rates"`
+}
+
+// ToRateCard converts UpdateRateCardInput to a RateCard.
+func (input UpdateRateCardInput) ToRateCard() *RateCard {
+	defaultRateType := input.DefaultRateType
+	if defaultRateType == "" {
+		defaultRateType = DefaultRateType
+	}
+
+	return &RateCard{
+		AccountID:       input.AccountID,
+		DefaultRateType: defaultRateType,
+		Rates:           input.Rates,
+		UpdatedAt:       time.Now().Unix(),
+		RecordType:      RecordTypeRateCard,
+		PK:              input.AccountID,
+		SK:              rateCardSK,
+	}
+}
+
+// RateCardKey returns the partition and sort key of an account's rate card.
+func RateCardKey(accountID string) (string, string) {
+	return accountID, rateCardSK
+}
+
+// RateFor returns the card's rate for the given rate type.
+func (c *RateCard) RateFor(rateType RateType) (Decimal, bool) {
+	for _, entry := range c.Rates {
+		if entry.RateType == rateType {
+			return entry.RatePerHour, true
+		}
+	}
+	return Decimal{}, false
+}
+
+// resolveRate fills in the rate type and rate per hour from the rate card when
+// they are not given explicitly. The card may be nil.
+func resolveRate(rateType RateType, ratePerHour *Decimal, card *RateCard) (RateType, Decimal) {
+	if rateType == "" {
+		rateType = DefaultRateType
+		if card != nil && card.DefaultRateType != "" {
+			rateType = card.DefaultRateType
+		}
+	}
+
+	if ratePerHour != nil {
+		return rateType, *ratePerHour
+	}
+	if card != nil {
+		if rate, ok := card.RateFor(rateType); ok {
+			return rateType, rate
+		}
+	}
+	return rateType, Decimal{}
+}
+
+// BillableHours returns the hours the labor line is charged for: the book
+// (estimated) hours for flat-rate and warranty work, and the actual hours
+// clocked for hourly and internal work.
+func (ll *LaborLine) BillableHours() Decimal {
+	switch ll.RateType {
+	case RateTypeFlatRate, RateTypeWarranty:
+		return ll.EstimatedHours
+	default:
+		return ll.ActualHours
+	}
+}
+
+// RecalculateLaborCost sets LaborCost to the billable hours multiplied by the
+// rate per hour, rounded half away from zero to whole cents. It must be called
+// whenever hours, rate type or rate change.
+func (ll *LaborLine) RecalculateLaborCost() {
+	ll.LaborCost = ll.BillableHours().Mul(ll.RatePerHour).Round(costPlaces)
+}
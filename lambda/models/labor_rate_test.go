@@ -0,0 +1,125 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLaborLine_RecalculateLaborCost(t *testing.T) {
+	tests := []struct {
+		name           string
+		rateType       RateType
+		estimatedHours string
+		actualHours    string
+		ratePerHour    string
+		expected       string
+	}{
+		{name: "Hourly bills actual hours", rateType: RateTypeHourly, estimatedHours: "2", actualHours: "1.5", ratePerHour: "100", expected: "150.00"},
+		{name: "Internal bills actual hours", rateType: RateTypeInternal, estimatedHours: "2", actualHours: "0.75", ratePerHour: "40", expected: "30.00"},
+		{name: "Flat rate bills estimated hours", rateType: RateTypeFlatRate, estimatedHours: "2", actualHours: "3.5", ratePerHour: "100", expected: "200.00"},
+		{name: "Warranty bills estimated hours", rateType: RateTypeWarranty, estimatedHours: "1.2", actualHours: "0", ratePerHour: "85.50", expected: "102.60"},
+		{name: "Unknown rate type bills actual hours", rateType: "", estimatedHours: "4", actualHours: "1", ratePerHour: "10", expected: "10.00"},
+		{name: "Rounds down below half a cent", rateType: RateTypeHourly, actualHours: "0.33", ratePerHour: "10.01", expected: "3.30"},
+		{name: "Rounds half a cent up", rateType: RateTypeHourly, actualHours: "0.5", ratePerHour: "0.01", expected: "0.01"},
+		{name: "Rounds above half a cent up", rateType: RateTypeHourly, actualHours: "1.17", ratePerHour: "89.99", expected: "105.29"},
+		{name: "Exact where float64 is not", rateType: RateTypeFlatRate, estimatedHours: "0.1", ratePerHour: "0.3", expected: "0.03"},
+		{name: "Sub-cent rate", rateType: RateTypeHourly, actualHours: "3", ratePerHour: "33.335", expected: "100.01"},
+		{name: "Large values keep precision", rateType: RateTypeFlatRate, estimatedHours: "9999.99", ratePerHour: "99999.99", expected: "999998900.00"},
+		{name: "Zero hours", rateType: RateTypeHourly, actualHours: "0", ratePerHour: "125", expected: "0.00"},
+		{name: "Zero rate", rateType: RateTypeInternal, actualHours: "8", ratePerHour: "0", expected: "0.00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			laborLine := &LaborLine{
+				RateType:    tt.rateType,
+				RatePerHour: MustParseDecimal(tt.ratePerHour),
+			}
+			if tt.estimatedHours != "" {
+				laborLine.EstimatedHours = MustParseDecimal(tt.estimatedHours)
+			}
+			if tt.actualHours != "" {
+				laborLine.ActualHours = MustParseDecimal(tt.actualHours)
+			}
+
+			laborLine.RecalculateLaborCost()
+
+			assert.Equal(t, tt.expected, laborLine.LaborCost.StringFixed(2))
+			assert.True(t, laborLine.LaborCost.Equal(MustParseDecimal(tt.expected)))
+		})
+	}
+}
+
+func TestNewLaborLine_RateDefaults(t *testing.T) {
+	rateCard := &RateCard{
+		DefaultRateType: RateTypeFlatRate,
+		Rates: []RateCardEntry{
+			{RateType: RateTypeFlatRate, RatePerHour: MustParseDecimal("120")},
+			{RateType: RateTypeWarranty, RatePerHour: MustParseDecimal("90")},
+		},
+	}
+	explicitRate := MustParseDecimal("75.25")
+
+	tests := []struct {
+		name         string
+		rateType     RateType
+		ratePerHour  *Decimal
+		rateCard     *RateCard
+		expectedType RateType
+		expectedRate string
+	}{
+		{name: "Both from rate card", rateCard: rateCard, expectedType: RateTypeFlatRate, expectedRate: "120"},
+		{name: "Rate for given type from rate card", rateType: RateTypeWarranty, rateCard: rateCard, expectedType: RateTypeWarranty, expectedRate: "90"},
+		{name: "Type missing from rate card", rateType: RateTypeInternal, rateCard: rateCard, expectedType: RateTypeInternal, expectedRate: "0"},
+		{name: "Explicit rate wins", rateType: RateTypeWarranty, ratePerHour: &explicitRate, rateCard: rateCard, expectedType: RateTypeWarranty, expectedRate: "75.25"},
+		{name: "No rate card", expectedType: DefaultRateType, expectedRate: "0"},
+		{name: "No rate card with explicit rate", ratePerHour: &explicitRate, expectedType: DefaultRateType, expectedRate: "75.25"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			laborLine := NewLaborLine(CreateLaborLineInput{
+				AccountID:   uuid.New().String(),
+				TaskID:      uuid.New().String(),
+				RateType:    tt.rateType,
+				RatePerHour: tt.ratePerHour,
+			}, tt.rateCard)
+
+			assert.Equal(t, tt.expectedType, laborLine.RateType)
+			assert.True(t, laborLine.RatePerHour.Equal(MustParseDecimal(tt.expectedRate)), "got %s", laborLine.RatePerHour)
+		})
+	}
+}
+
+func TestUpdateRateCardInput_ToRateCard(t *testing.T) {
+	accountID := uuid.New().String()
+	input := UpdateRateCardInput{
+		AccountID: accountID,
+		Rates: []RateCardEntry{
+			{RateType: RateTypeHourly, RatePerHour: MustParseDecimal("95")},
+		},
+	}
+
+	startTime := time.Now().Unix()
+	rateCard := input.ToRateCard()
+
+	assert.Equal(t, accountID, rateCard.AccountID)
+	assert.Equal(t, DefaultRateType, rateCard.DefaultRateType)
+	assert.Equal(t, RecordTypeRateCard, rateCard.RecordType)
+	assert.GreaterOrEqual(t, rateCard.UpdatedAt, startTime)
+
+	pk, sk := RateCardKey(accountID)
+	assert.Equal(t, pk, rateCard.PK)
+	assert.Equal(t, sk, rateCard.SK)
+
+	rate, found := rateCard.RateFor(RateTypeHourly)
+	require.True(t, found)
+	assert.Equal(t, "95", rate.String())
+
+	_, found = rateCard.RateFor(RateTypeWarranty)
+	assert.False(t, found)
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+)
+
+// NoteVisibility controls who a labor line note is shown to.
+type NoteVisibility string
+
+const (
+	// NoteVisibilityInternal notes are only for the shop.
+	NoteVisibilityInternal NoteVisibility = "INTERNAL"
+	// NoteVisibilityCustomer notes may be shown to the customer, such as on an invoice.
+	NoteVisibilityCustomer NoteVisibility = "CUSTOMER"
+)
+
+// DefaultNoteVisibility is the visibility of a note that does not specify one.
+const DefaultNoteVisibility = NoteVisibilityInternal
+
+// legacyNoteNamespace derives the IDs of notes stored as plain strings, so a
+// migrated note keeps the same ID on every read.
+var legacyNoteNamespace = uuid.MustParse("6f1c7a52-3f4e-4b8e-9a0d-2c5b8e1f7d43")
+
+// Note is a note on a labor line, recorded with who wrote it and when.
+type Note struct {
+	NoteID     string         `json:"noteId" dynamodbav:"noteId"`
+	Author     string         `json:"author,omitempty" dynamodbav:"author,omitempty"`
+	CreatedAt  int64          `json:"createdAt" dynamodbav:"createdAt"`
+	UpdatedAt  int64          `json:"updatedAt,omitempty" dynamodbav:"updatedAt,omitempty"` // Set once the note is edited
+	Visibility NoteVisibility `json:"visibility" dynamodbav:"visibility"`
+	Body       string         `json:"body" dynamodbav:"body"`
+}
+
+// NoteInput is a note given when creating a labor line.
+type NoteInput struct {
+	Visibility NoteVisibility `json:"visibility,omitempty"` // Defaults to INTERNAL
+	Body       string         `json:"body"`
+}
+
+// AddLaborLineNoteInput represents the input for adding a note to a labor line.
+type AddLaborLineNoteInput struct {
+	AccountID       string         `json:"accountId"`
+	TaskID          string         `json:"taskId"`
+	LaborLineID     string         `json:"laborLineId"`
+	Visibility      NoteVisibility `json:"visibility,omitempty"` // Defaults to INTERNAL
+	Body            string         `json:"body"`
+	ExpectedVersion *int64         `json:"expectedVersion,omitempty"` // Optional optimistic concurrency check
+}
+
+// EditLaborLineNoteInput represents the input for changing the body or
+// visibility of a labor line note.
+type EditLaborLineNoteInput struct {
+	AccountID       string         `json:"accountId"`
+	TaskID          string         `json:"taskId"`
+	LaborLineID     string         `json:"laborLineId"`
+	NoteID          string         `json:"noteId"`
+	Visibility      NoteVisibility `json:"visibility,omitempty"` // Unchanged if empty
+	Body            string         `json:"body,omitempty"`       // Unchanged if empty
+	ExpectedVersion *int64         `json:"expectedVersion,omitempty"`
+}
+
+// DeleteLaborLineNoteInput represents the input for removing a note from a labor line.
+type DeleteLaborLineNoteInput struct {
+	AccountID       string `json:"accountId"`
+	TaskID          string `json:"taskId"`
+	LaborLineID     string `json:"laborLineId"`
+	NoteID          string `json:"noteId"`
+	ExpectedVersion *int64 `json:"expectedVersion,omitempty"`
+}
+
+// NewNote creates a note written by author now.
+func NewNote(author string, visibility NoteVisibility, body string) Note {
+	if visibility == "" {
+		visibility = DefaultNoteVisibility
+	}
+	return Note{
+		NoteID:     uuid.New().String(),
+		Author:     author,
+		CreatedAt:  time.Now().Unix(),
+		Visibility: visibility,
+		Body:       body,
+	}
+}
+
+// UnmarshalDynamoDBAttributeValue decodes a note, accepting the plain strings
+// notes were stored as before they were structured. A plain string becomes an
+// internal note without an ID; MigrateNotes fills in the rest.
+func (n *Note) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error {
+	if s, ok := av.(*types.AttributeValueMemberS); ok {
+		*n = Note{Visibility: DefaultNoteVisibility, Body: s.Value}
+		return nil
+	}
+
+	// Decode the map form without recursing into this method
+	type note Note
+	return attributevalue.Unmarshal(av, (*note)(n))
+}
+
+// MigrateNotes completes notes read from the plain strings they were once
+// stored as. They are attributed to the labor line's creator at its creation
+// time, and given an ID derived from their position, which stays the same
+// until the notes are next written.
+func (ll *LaborLine) MigrateNotes() {
+	for i := range ll.Notes {
+		note := &ll.Notes[i]
+		if note.NoteID != "" {
+			continue
+		}
+		note.NoteID = uuid.NewSHA1(legacyNoteNamespace, []byte(fmt.Sprintf("%s#%d", ll.LaborLineID, i))).String()
+		note.Author = ll.CreatedBy
+		note.CreatedAt = ll.CreatedAt
+	}
+}
+
+// AssignCreator records actor as the creator of a new labor line and the
+// author of the notes it was created with.
+func (ll *LaborLine) AssignCreator(actor string) {
+	ll.CreatedBy = actor
+	for i := range ll.Notes {
+		ll.Notes[i].Author = actor
+	}
+}
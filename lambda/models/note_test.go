@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNote_UnmarshalDynamoDBAttributeValue(t *testing.T) {
+	note := NewNote("user-123", NoteVisibilityCustomer, "Replace worn brake pads")
+	stored, err := attributevalue.Marshal(note)
+	require.NoError(t, err)
+
+	var notes []Note
+	require.NoError(t, attributevalue.Unmarshal(&types.AttributeValueMemberL{Value: []types.AttributeValue{
+		stored,
+		&types.AttributeValueMemberS{Value: "Check brake fluid level"},
+	}}, &notes))
+
+	require.Len(t, notes, 2)
+	assert.Equal(t, note, notes[0])
+	// A note stored as a plain string is read as an internal note
+	assert.Equal(t, Note{Visibility: NoteVisibilityInternal, Body: "Check brake fluid level"}, notes[1])
+}
+
+func TestLaborLine_MigrateNotes(t *testing.T) {
+	newLaborLine := func() *LaborLine {
+		return &LaborLine{
+			LaborLineID: "550e8400-e29b-41d4-a716-446655440000",
+			CreatedBy:   "user-123",
+			CreatedAt:   1718000000,
+			Notes: []Note{
+				{Visibility: NoteVisibilityInternal, Body: "Replace worn brake pads"},
+				NewNote("user-456", NoteVisibilityCustomer, "Check brake fluid level"),
+			},
+		}
+	}
+
+	laborLine := newLaborLine()
+	written := laborLine.Notes[1]
+	laborLine.MigrateNotes()
+
+	migrated := laborLine.Notes[0]
+	_, err := uuid.Parse(migrated.NoteID)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-123", migrated.Author)
+	assert.Equal(t, int64(1718000000), migrated.CreatedAt)
+	assert.Equal(t, written, laborLine.Notes[1])
+
+	// The migrated note gets the same ID on every read
+	again := newLaborLine()
+	again.MigrateNotes()
+	assert.Equal(t, migrated.NoteID, again.Notes[0].NoteID)
+}
+
+func TestLaborLine_AssignCreator(t *testing.T) {
+	laborLine := NewLaborLine(CreateLaborLineInput{
+		AccountID: uuid.New().String(),
+		TaskID:    uuid.New().String(),
+		Notes:     []NoteInput{{Body: "Replace worn brake pads"}},
+	}, nil)
+
+	laborLine.AssignCreator("user-123")
+
+	assert.Equal(t, "user-123", laborLine.CreatedBy)
+	assert.Equal(t, "user-123", laborLine.Notes[0].Author)
+	assert.Equal(t, NoteVisibilityInternal, laborLine.Notes[0].Visibility)
+}
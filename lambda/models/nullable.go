@@ -0,0 +1,48 @@
+package models
+
+import "encoding/json"
+
+// Nullable is an optional input field that tells a field left out of the input
+// apart from one explicitly set to null, so that a partial update can leave the
+// first unchanged and clear the second.
+type Nullable[T any] struct {
+	Value T
+	// Set reports whether the field was present in the input, even as null
+	Set bool
+	// Null reports whether the field was explicitly null
+	Null bool
+}
+
+// NewNullable returns a field set to value.
+func NewNullable[T any](value T) Nullable[T] {
+	return Nullable[T]{Value: value, Set: true}
+}
+
+// NullValue returns a field explicitly set to null.
+func NullValue[T any]() Nullable[T] {
+	return Nullable[T]{Set: true, Null: true}
+}
+
+// HasValue reports whether the field was set to a value other than null.
+func (n Nullable[T]) HasValue() bool {
+	return n.Set && !n.Null
+}
+
+// UnmarshalJSON records that the field was present and whether it was null.
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	var zero T
+	n.Value, n.Set, n.Null = zero, true, string(data) == "null"
+	if n.Null {
+		return nil
+	}
+	return json.Unmarshal(data, &n.Value)
+}
+
+// MarshalJSON writes the value, or null if the field is null. A field that was
+// not set should be left out with the omitzero option.
+func (n Nullable[T]) MarshalJSON() ([]byte, error) {
+	if !n.HasValue() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.Value)
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNullable_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Nullable[string]
+	}{
+		{
+			name:     "Absent",
+			input:    `{}`,
+			expected: Nullable[string]{},
+		},
+		{
+			name:     "Null",
+			input:    `{"description": null}`,
+			expected: NullValue[string](),
+		},
+		{
+			name:     "Value",
+			input:    `{"description": "Brake pads"}`,
+			expected: NewNullable("Brake pads"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input struct {
+				Description Nullable[string] `json:"description"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(tt.input), &input))
+			assert.Equal(t, tt.expected, input.Description)
+		})
+	}
+}
+
+func TestNullable_MarshalJSON(t *testing.T) {
+	type input struct {
+		Notes Nullable[[]string] `json:"notes,omitzero"`
+	}
+
+	data, err := json.Marshal(input{})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{}`, string(data))
+
+	data, err = json.Marshal(input{Notes: NullValue[[]string]()})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"notes": null}`, string(data))
+
+	data, err = json.Marshal(input{Notes: NewNullable([]string{"Check rotors"})})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"notes": ["Check rotors"]}`, string(data))
+}
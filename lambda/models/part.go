@@ -0,0 +1,127 @@
+package models
+
+// PartStatus tracks a part from request through issue to return.
+type PartStatus string
+
+const (
+	// PartStatusRequested parts are needed for the work and reserved in the parts inventory.
+	PartStatusRequested PartStatus = "REQUESTED"
+	// PartStatusIssued parts have been handed to the technician.
+	PartStatusIssued PartStatus = "ISSUED"
+	// PartStatusReturned parts were not used and went back to stock.
+	PartStatusReturned PartStatus = "RETURNED"
+)
+
+// DefaultUnitOfMeasure is the unit of measure of a part that does not specify one.
+const DefaultUnitOfMeasure = "EA"
+
+// PartLineItem is a part used for a labor line, with how many are needed and
+// what they cost.
+type PartLineItem struct {
+	PartID        string     `json:"partId" dynamodbav:"partId"`
+	Quantity      Decimal    `json:"quantity" dynamodbav:"quantity"`
+	UnitOfMeasure string     `json:"unitOfMeasure,omitempty" dynamodbav:"unitOfMeasure"` // Defaults to EA
+	UnitCost      *Decimal   `json:"unitCost,omitempty" dynamodbav:"unitCost,omitempty"` // Nil until the cost is known
+	Status        PartStatus `json:"status,omitempty" dynamodbav:"status"`               // Defaults to REQUESTED
+}
+
+// HoldsStock reports whether the part is taken out of the parts inventory's
+// available stock, which is the case until it is returned.
+func (p PartLineItem) HoldsStock() bool {
+	return p.Status != PartStatusReturned
+}
+
+// newPartLineItems returns the parts with their unit of measure and status
+// defaulted.
+func newPartLineItems(parts []PartLineItem) []PartLineItem {
+	if parts == nil {
+		return nil
+	}
+	items := make([]PartLineItem, len(parts))
+	for i, part := range parts {
+		if part.UnitOfMeasure == "" {
+			part.UnitOfMeasure = DefaultUnitOfMeasure
+		}
+		if part.Status == "" {
+			part.Status = PartStatusRequested
+		}
+		items[i] = part
+	}
+	return items
+}
+
+// MigrateParts converts the part IDs labor lines once recorded into part line
+// items, each a single requested unit of the part.
+func (ll *LaborLine) MigrateParts() {
+	if len(ll.LegacyPartIDs) > 0 && ll.Parts == nil {
+		ll.Parts = make([]PartLineItem, len(ll.LegacyPartIDs))
+		for i, partID := range ll.LegacyPartIDs {
+			ll.Parts[i] = PartLineItem{
+				PartID:        partID,
+				Quantity:      NewDecimalFromInt(1),
+				UnitOfMeasure: DefaultUnitOfMeasure,
+				Status:        PartStatusRequested,
+			}
+		}
+	}
+	ll.LegacyPartIDs = nil
+}
+
+// CatalogPart is a part as the parts inventory knows it.
+type CatalogPart struct {
+	AccountID      string  `json:"accountId"`
+	PartID         string  `json:"partId"`
+	Description    string  `json:"description,omitempty"`
+	UnitOfMeasure  string  `json:"unitOfMeasure"`
+	QuantityOnHand Decimal `json:"quantityOnHand"`
+}
+
+// StockHeld returns the quantity of each part, by part ID, that the parts hold
+// out of the parts inventory's available stock.
+func StockHeld(parts []PartLineItem) map[string]Decimal {
+	held := map[string]Decimal{}
+	for _, part := range parts {
+		if part.HoldsStock() {
+			held[part.PartID] = held[part.PartID].Add(part.Quantity)
+		}
+	}
+	return held
+}
+
+// RecordTypePartReservation identifies the items holding the stock reserved
+// for each labor line.
+const RecordTypePartReservation = "PART_RESERVATION"
+
+// RecordTypePartReserved identifies the items holding how much of each part
+// is reserved across an account's labor lines.
+const RecordTypePartReserved = "PART_RESERVED"
+
+// PartReservation is the stock of each part, by part ID, reserved for a labor line.
+type PartReservation struct {
+	AccountID   string             `dynamodbav:"accountId"`
+	LaborLineID string             `dynamodbav:"laborLineId"`
+	Quantities  map[string]Decimal `dynamodbav:"quantities"`
+	Version     int64              `dynamodbav:"version"`
+
+	// DynamoDB keys
+	RecordType string `dynamodbav:"recordType"`
+	PK         string `dynamodbav:"PK"` // PARTS#{accountId}
+	SK         string `dynamodbav:"SK"` // RESERVATION#{laborLineId}
+}
+
+// PartsPK returns the partition key of an account's part reservations. They
+// are kept out of the account's own partition so purging its labor lines, or
+// listing them, does not see them.
+func PartsPK(accountID string) string {
+	return "PARTS#" + accountID
+}
+
+// PartReservationSK returns the sort key of the stock reserved for a labor line.
+func PartReservationSK(laborLineID string) string {
+	return "RESERVATION#" + laborLineID
+}
+
+// PartReservedSK returns the sort key of how much of a part is reserved.
+func PartReservedSK(partID string) string {
+	return "RESERVED#" + partID
+}
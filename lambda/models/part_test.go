@@ -0,0 +1,56 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLaborLine_PartDefaults(t *testing.T) {
+	laborLine := NewLaborLine(CreateLaborLineInput{
+		AccountID: uuid.New().String(),
+		TaskID:    uuid.New().String(),
+		Parts: []PartLineItem{
+			{PartID: uuid.New().String(), Quantity: NewDecimalFromInt(2)},
+			{PartID: uuid.New().String(), Quantity: NewDecimalFromInt(4), UnitOfMeasure: "QT", Status: PartStatusIssued},
+		},
+	}, nil)
+
+	require.Len(t, laborLine.Parts, 2)
+	assert.Equal(t, DefaultUnitOfMeasure, laborLine.Parts[0].UnitOfMeasure)
+	assert.Equal(t, PartStatusRequested, laborLine.Parts[0].Status)
+	assert.Equal(t, "QT", laborLine.Parts[1].UnitOfMeasure)
+	assert.Equal(t, PartStatusIssued, laborLine.Parts[1].Status)
+}
+
+func TestPartLineItem_HoldsStock(t *testing.T) {
+	assert.True(t, PartLineItem{Status: PartStatusRequested}.HoldsStock())
+	assert.True(t, PartLineItem{Status: PartStatusIssued}.HoldsStock())
+	assert.False(t, PartLineItem{Status: PartStatusReturned}.HoldsStock())
+}
+
+func TestLaborLine_MigrateParts(t *testing.T) {
+	partIDs := []string{uuid.New().String(), uuid.New().String()}
+	laborLine := &LaborLine{LegacyPartIDs: partIDs}
+
+	laborLine.MigrateParts()
+
+	assert.Nil(t, laborLine.LegacyPartIDs)
+	require.Len(t, laborLine.Parts, 2)
+	for i, part := range laborLine.Parts {
+		assert.Equal(t, partIDs[i], part.PartID)
+		assert.True(t, NewDecimalFromInt(1).Equal(part.Quantity))
+		assert.Equal(t, DefaultUnitOfMeasure, part.UnitOfMeasure)
+		assert.Equal(t, PartStatusRequested, part.Status)
+		assert.Nil(t, part.UnitCost)
+	}
+
+	// Part line items written since take precedence over leftover part IDs
+	parts := []PartLineItem{{PartID: uuid.New().String(), Quantity: NewDecimalFromInt(3)}}
+	laborLine = &LaborLine{Parts: parts, LegacyPartIDs: partIDs}
+	laborLine.MigrateParts()
+	assert.Equal(t, parts, laborLine.Parts)
+	assert.Nil(t, laborLine.LegacyPartIDs)
+}
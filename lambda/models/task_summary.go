@@ -0,0 +1,244 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// RecordTypeTaskSummary identifies the items summarizing each task's labor lines.
+const RecordTypeTaskSummary = "TASK_SUMMARY"
+
+// taskSummarySKPrefix is the sort key prefix of the task summary items.
+const taskSummarySKPrefix = "SUMMARY#"
+
+// The summary item keeps each breakdown in top-level attributes named
+// {breakdown}#{key}#{total}, such as status#PENDING#laborLineCount, because
+// DynamoDB can only add to a nested attribute whose parent map already exists.
+const (
+	statusBreakdownPrefix     = "status#"
+	technicianBreakdownPrefix = "technician#"
+)
+
+// TaskLaborSummary holds the totals of a task's labor lines that are not
+// deleted, broken down by status and by technician. A labor line with several
+// technicians counts in full towards each of them.
+type TaskLaborSummary struct {
+	AccountID string `json:"accountId"`
+	TaskID    string `json:"taskId"`
+	LaborLineTotals
+	ByStatus     []StatusLaborTotals     `json:"byStatus"`
+	ByTechnician []TechnicianLaborTotals `json:"byTechnician"`
+	UpdatedAt    int64                   `json:"updatedAt"`
+
+	// Recomputed is set when the summary was computed from the labor lines
+	// rather than read from the maintained summary item
+	Recomputed bool `json:"recomputed"`
+
+	// Revision counts the writes to the summary item, so a recomputed summary
+	// only replaces it if no labor line was written in the meantime
+	Revision int64 `json:"-"`
+
+	// Initialized is set once the summary item has been recomputed from the
+	// labor lines. An item created by a labor line write holds only the
+	// changes made since, which miss any labor lines that predate it
+	Initialized bool `json:"-"`
+}
+
+// StatusLaborTotals are the totals of a task's labor lines in a status.
+type StatusLaborTotals struct {
+	Status LaborLineStatus `json:"status"`
+	LaborLineTotals
+}
+
+// TechnicianLaborTotals are the totals of a task's labor lines assigned to a technician.
+type TechnicianLaborTotals struct {
+	TechnicianID string `json:"technicianId"`
+	LaborLineTotals
+}
+
+// GetTaskLaborSummaryInput represents the input for summarizing a task's labor lines.
+type GetTaskLaborSummaryInput struct {
+	AccountID string `json:"accountId"`
+	TaskID    string `json:"taskId"`
+	Recompute bool   `json:"recompute,omitempty"` // Rebuild the summary from the labor lines
+}
+
+// TaskSummaryChange is how a set of labor line writes to one task moves its summary.
+type TaskSummaryChange struct {
+	AccountID    string
+	TaskID       string
+	Totals       LaborLineTotals
+	ByStatus     map[LaborLineStatus]LaborLineTotals
+	ByTechnician map[string]LaborLineTotals
+}
+
+// NewTaskSummaryChange creates a change to a task's summary that does not move it yet.
+func NewTaskSummaryChange(accountID, taskID string) *TaskSummaryChange {
+	return &TaskSummaryChange{
+		AccountID:    accountID,
+		TaskID:       taskID,
+		ByStatus:     map[LaborLineStatus]LaborLineTotals{},
+		ByTechnician: map[string]LaborLineTotals{},
+	}
+}
+
+// Add folds a write of one of the task's labor lines, from before to after,
+// into the change. before is nil for a new labor line.
+func (c *TaskSummaryChange) Add(before, after *LaborLine) {
+	c.add(before, LaborLineTotals.Sub)
+	c.add(after, LaborLineTotals.Add)
+}
+
+// add combines what a labor line adds to the summary into the change.
+func (c *TaskSummaryChange) add(laborLine *LaborLine, combine func(LaborLineTotals, LaborLineTotals) LaborLineTotals) {
+	totals := TotalsOf(laborLine)
+	if totals.IsZero() {
+		return
+	}
+
+	c.Totals = combine(c.Totals, totals)
+	c.ByStatus[laborLine.Status] = combine(c.ByStatus[laborLine.Status], totals)
+	for _, technicianID := range laborLine.AssignedTechnicianIDs {
+		c.ByTechnician[technicianID] = combine(c.ByTechnician[technicianID], totals)
+	}
+}
+
+// IsZero reports whether the change leaves the summary as it was.
+func (c *TaskSummaryChange) IsZero() bool {
+	return len(c.Amounts()) == 0
+}
+
+// Amounts returns the amount the change adds to each attribute of the summary
+// item, leaving out those it does not move.
+func (c *TaskSummaryChange) Amounts() map[string]Decimal {
+	amounts := map[string]Decimal{}
+	addAmounts(amounts, "", c.Totals)
+	for status, totals := range c.ByStatus {
+		addAmounts(amounts, statusBreakdownPrefix+string(status)+"#", totals)
+	}
+	for technicianID, totals := range c.ByTechnician {
+		addAmounts(amounts, technicianBreakdownPrefix+technicianID+"#", totals)
+	}
+	return amounts
+}
+
+// addAmounts adds the non-zero totals to amounts, naming each with the prefix.
+func addAmounts(amounts map[string]Decimal, prefix string, totals LaborLineTotals) {
+	for name, amount := range totals.amounts() {
+		if !amount.IsZero() {
+			amounts[prefix+name] = amount
+		}
+	}
+}
+
+// Summary returns the summary of a task whose labor lines all went into the change.
+func (c *TaskSummaryChange) Summary() *TaskLaborSummary {
+	summary := &TaskLaborSummary{
+		AccountID:       c.AccountID,
+		TaskID:          c.TaskID,
+		LaborLineTotals: c.Totals,
+		ByStatus:        []StatusLaborTotals{},
+		ByTechnician:    []TechnicianLaborTotals{},
+	}
+
+	// Statuses and technicians whose labor lines are all gone are left out
+	for status, totals := range c.ByStatus {
+		if totals.LaborLineCount > 0 {
+			summary.ByStatus = append(summary.ByStatus, StatusLaborTotals{Status: status, LaborLineTotals: totals})
+		}
+	}
+	slices.SortFunc(summary.ByStatus, func(a, b StatusLaborTotals) int {
+		return strings.Compare(string(a.Status), string(b.Status))
+	})
+
+	for technicianID, totals := range c.ByTechnician {
+		if totals.LaborLineCount > 0 {
+			summary.ByTechnician = append(summary.ByTechnician, TechnicianLaborTotals{TechnicianID: technicianID, LaborLineTotals: totals})
+		}
+	}
+	slices.SortFunc(summary.ByTechnician, func(a, b TechnicianLaborTotals) int {
+		return strings.Compare(a.TechnicianID, b.TechnicianID)
+	})
+
+	return summary
+}
+
+// TaskSummaryFromItem decodes a task summary item.
+func TaskSummaryFromItem(item map[string]types.AttributeValue) (*TaskLaborSummary, error) {
+	change := NewTaskSummaryChange(stringAttribute(item, "accountId"), stringAttribute(item, "taskId"))
+	var updatedAt, revision int64
+
+	// Every amount is a number; the other attributes are skipped
+	for name, value := range item {
+		n, ok := value.(*types.AttributeValueMemberN)
+		if !ok {
+			continue
+		}
+		amount, err := ParseDecimal(n.Value)
+		if err != nil {
+			return nil, fmt.Errorf("decoding task summary attribute %s: %w", name, err)
+		}
+
+		switch name {
+		case "updatedAt":
+			updatedAt = amount.IntPart()
+		case "revision":
+			revision = amount.IntPart()
+		default:
+			change.setAmount(name, amount)
+		}
+	}
+
+	summary := change.Summary()
+	summary.UpdatedAt = updatedAt
+	summary.Revision = revision
+	if initialized, ok := item["initialized"].(*types.AttributeValueMemberBOOL); ok {
+		summary.Initialized = initialized.Value
+	}
+	return summary, nil
+}
+
+// setAmount sets the amount held by the summary item attribute with the given
+// name. Attributes that hold none of the summary's amounts are ignored.
+func (c *TaskSummaryChange) setAmount(name string, amount Decimal) {
+	breakdown, rest, found := strings.Cut(name, "#")
+	if !found {
+		c.Totals.setAmount(name, amount)
+		return
+	}
+
+	// Technician IDs may contain the separator, so the total is split off the end
+	sep := strings.LastIndex(rest, "#")
+	if sep < 0 {
+		return
+	}
+	key, total := rest[:sep], rest[sep+1:]
+
+	switch breakdown + "#" {
+	case statusBreakdownPrefix:
+		totals := c.ByStatus[LaborLineStatus(key)]
+		totals.setAmount(total, amount)
+		c.ByStatus[LaborLineStatus(key)] = totals
+	case technicianBreakdownPrefix:
+		totals := c.ByTechnician[key]
+		totals.setAmount(total, amount)
+		c.ByTechnician[key] = totals
+	}
+}
+
+// stringAttribute returns the value of a string attribute of an item, or ""
+// if it is missing or not a string.
+func stringAttribute(item map[string]types.AttributeValue, name string) string {
+	if s, ok := item[name].(*types.AttributeValueMemberS); ok {
+		return s.Value
+	}
+	return ""
+}
+
+// TaskSummaryKey returns the partition and sort key of a task's labor summary.
+func TaskSummaryKey(accountID, taskID string) (string, string) {
+	return accountID, taskSummarySKPrefix + taskID
+}
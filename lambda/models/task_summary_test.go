@@ -0,0 +1,103 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskSummaryChange_Add(t *testing.T) {
+	before := &LaborLine{
+		TaskID:                "task-1",
+		Status:                StatusPending,
+		EstimatedHours:        MustParseDecimal("2"),
+		AssignedTechnicianIDs: []string{"tech-1"},
+	}
+	after := *before
+	after.Status = StatusInProgress
+	after.ActualHours = MustParseDecimal("0.5")
+	after.AssignedTechnicianIDs = []string{"tech-1", "tech-2"}
+
+	change := NewTaskSummaryChange("account-1", "task-1")
+	change.Add(before, &after)
+
+	// The labor line moves between statuses and gains a technician, but the
+	// task still has one labor line
+	assert.Equal(t, map[string]Decimal{
+		"actualHours":                       MustParseDecimal("0.5"),
+		"status#PENDING#laborLineCount":     NewDecimalFromInt(-1),
+		"status#PENDING#estimatedHours":     MustParseDecimal("-2"),
+		"status#IN_PROGRESS#laborLineCount": NewDecimalFromInt(1),
+		"status#IN_PROGRESS#estimatedHours": MustParseDecimal("2"),
+		"status#IN_PROGRESS#actualHours":    MustParseDecimal("0.5"),
+		"technician#tech-1#actualHours":     MustParseDecimal("0.5"),
+		"technician#tech-2#laborLineCount":  NewDecimalFromInt(1),
+		"technician#tech-2#estimatedHours":  MustParseDecimal("2"),
+		"technician#tech-2#actualHours":     MustParseDecimal("0.5"),
+	}, change.Amounts())
+	assert.False(t, change.IsZero())
+}
+
+func TestTaskSummaryChange_AddWithoutAmounts(t *testing.T) {
+	laborLine := &LaborLine{Status: StatusPending, Description: "Rotate tires"}
+	edited := *laborLine
+	edited.Description = "Rotate and balance tires"
+
+	change := NewTaskSummaryChange("account-1", "task-1")
+	change.Add(laborLine, &edited)
+	assert.True(t, change.IsZero())
+
+	// A labor line that was already deleted is not taken off again
+	deletedAt := int64(1760000000)
+	deleted := *laborLine
+	deleted.DeletedAt = &deletedAt
+	change.Add(&deleted, &deleted)
+	assert.True(t, change.IsZero())
+}
+
+func TestTaskSummaryFromItem(t *testing.T) {
+	item := map[string]types.AttributeValue{
+		"accountId":                        &types.AttributeValueMemberS{Value: "account-1"},
+		"taskId":                           &types.AttributeValueMemberS{Value: "task-1"},
+		"recordType":                       &types.AttributeValueMemberS{Value: RecordTypeTaskSummary},
+		"updatedAt":                        &types.AttributeValueMemberN{Value: "1760000000"},
+		"revision":                         &types.AttributeValueMemberN{Value: "3"},
+		"initialized":                      &types.AttributeValueMemberBOOL{Value: true},
+		"laborLineCount":                   &types.AttributeValueMemberN{Value: "2"},
+		"laborCost":                        &types.AttributeValueMemberN{Value: "240.5"},
+		"status#COMPLETED#laborLineCount":  &types.AttributeValueMemberN{Value: "2"},
+		"status#COMPLETED#laborCost":       &types.AttributeValueMemberN{Value: "240.5"},
+		"technician#crew#7#laborLineCount": &types.AttributeValueMemberN{Value: "1"},
+		"technician#crew#7#laborCost":      &types.AttributeValueMemberN{Value: "100"},
+		"technician#tech-9#laborLineCount": &types.AttributeValueMemberN{Value: "0"},
+	}
+
+	summary, err := TaskSummaryFromItem(item)
+	require.NoError(t, err)
+
+	assert.Equal(t, "account-1", summary.AccountID)
+	assert.Equal(t, "task-1", summary.TaskID)
+	assert.Equal(t, int64(1760000000), summary.UpdatedAt)
+	assert.Equal(t, int64(3), summary.Revision)
+	assert.True(t, summary.Initialized)
+	assert.Equal(t, int64(2), summary.LaborLineCount)
+	assert.Equal(t, "240.50", summary.LaborCost.StringFixed(2))
+	assert.Equal(t, []StatusLaborTotals{
+		{Status: StatusCompleted, LaborLineTotals: LaborLineTotals{LaborLineCount: 2, LaborCost: MustParseDecimal("240.5")}},
+	}, summary.ByStatus)
+
+	// Technician IDs may contain the separator; technicians left with no
+	// labor lines are dropped
+	assert.Equal(t, []TechnicianLaborTotals{
+		{TechnicianID: "crew#7", LaborLineTotals: LaborLineTotals{LaborLineCount: 1, LaborCost: MustParseDecimal("100")}},
+	}, summary.ByTechnician)
+}
+
+func TestTaskSummaryFromItem_InvalidAmount(t *testing.T) {
+	_, err := TaskSummaryFromItem(map[string]types.AttributeValue{
+		"laborCost": &types.AttributeValueMemberN{Value: "not-a-number"},
+	})
+	assert.Error(t, err)
+}
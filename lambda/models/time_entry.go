@@ -0,0 +1,223 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecordTypeTimeEntry identifies time entry items stored alongside labor lines.
+const RecordTypeTimeEntry = "TIME_ENTRY"
+
+// Record types of the items in a technician's time partition.
+const (
+	RecordTypeTimeSlot        = "TIME_SLOT"
+	RecordTypeTechnicianClock = "TECHNICIAN_CLOCK"
+)
+
+// TechnicianClockSK is the sort key of the item in a technician's time
+// partition whose version moves with every time entry the technician saves.
+const TechnicianClockSK = "CLOCK"
+
+// TimeSlotSKPrefix is the sort key prefix shared by the time slots in a
+// technician's time partition.
+const TimeSlotSKPrefix = "TIME#"
+
+// TimeEntry records a period of work by a technician on a labor line.
+// Entries are stored under the labor line's key space.
+type TimeEntry struct {
+	TimeEntryID  string `json:"timeEntryId" dynamodbav:"timeEntryId"`
+	LaborLineID  string `json:"laborLineId" dynamodbav:"laborLineId"`
+	AccountID    string `json:"accountId" dynamodbav:"accountId"`
+	TaskID       string `json:"taskId" dynamodbav:"taskId"`
+	TechnicianID string `json:"technicianId" dynamodbav:"technicianId"`
+
+	// Clock times (epoch seconds); EndTime is nil while the timer is running
+	StartTime int64  `json:"startTime" dynamodbav:"startTime"`
+	EndTime   *int64 `json:"endTime,omitempty" dynamodbav:"endTime,omitempty"`
+
+	// Worked time in minutes, net of breaks; set once the entry is stopped
+	BreakMinutes    int64 `json:"breakMinutes" dynamodbav:"breakMinutes"`
+	DurationMinutes int64 `json:"durationMinutes" dynamodbav:"durationMinutes"`
+
+	// Audit timestamps (epoch seconds)
+	CreatedAt int64 `json:"createdAt" dynamodbav:"createdAt"`
+	UpdatedAt int64 `json:"updatedAt" dynamodbav:"updatedAt"`
+
+	// DynamoDB keys
+	RecordType string `json:"-" dynamodbav:"recordType"`
+	PK         string `json:"-" dynamodbav:"PK"` // accountId
+	SK         string `json:"-" dynamodbav:"SK"` // {taskId}#{laborLineId}#TIME#{timeEntryId}
+}
+
+// TimeSlot is the period of a time entry, copied into the technician's time
+// partition so that the technician's entries on every labor line can be read
+// together and checked for overlaps.
+type TimeSlot struct {
+	TimeEntryID  string `json:"timeEntryId" dynamodbav:"timeEntryId"`
+	LaborLineID  string `json:"laborLineId" dynamodbav:"laborLineId"`
+	AccountID    string `json:"accountId" dynamodbav:"accountId"`
+	TaskID       string `json:"taskId" dynamodbav:"taskId"`
+	TechnicianID string `json:"technicianId" dynamodbav:"technicianId"`
+
+	// Clock times (epoch seconds), as on the time entry
+	StartTime int64  `json:"startTime" dynamodbav:"startTime"`
+	EndTime   *int64 `json:"endTime,omitempty" dynamodbav:"endTime,omitempty"`
+
+	// DynamoDB keys
+	RecordType string `json:"-" dynamodbav:"recordType"`
+	PK         string `json:"-" dynamodbav:"PK"` // TECHNICIAN#{accountId}#{technicianId}
+	SK         string `json:"-" dynamodbav:"SK"` // TIME#{startTime}#{timeEntryId}
+}
+
+// StartLaborTimerInput represents the input for clocking a technician in on a labor line.
+type StartLaborTimerInput struct {
+	AccountID    string `json:"accountId"`
+	TaskID       string `json:"taskId"`
+	LaborLineID  string `json:"laborLineId"`
+	TechnicianID string `json:"technicianId"`
+	StartTime    *int64 `json:"startTime,omitempty"` // Defaults to now
+}
+
+// StopLaborTimerInput represents the input for clocking a technician out of a labor line.
+type StopLaborTimerInput struct {
+	AccountID    string `json:"accountId"`
+	TaskID       string `json:"taskId"`
+	LaborLineID  string `json:"laborLineId"`
+	TechnicianID string `json:"technicianId"`
+	EndTime      *int64 `json:"endTime,omitempty"` // Defaults to now
+	BreakMinutes int64  `json:"breakMinutes,omitempty"`
+}
+
+// AddTimeEntryInput represents the input for recording a completed time entry after the fact.
+type AddTimeEntryInput struct {
+	AccountID    string `json:"accountId"`
+	TaskID       string `json:"taskId"`
+	LaborLineID  string `json:"laborLineId"`
+	TechnicianID string `json:"technicianId"`
+	StartTime    int64  `json:"startTime"`
+	EndTime      int64  `json:"endTime"`
+	BreakMinutes int64  `json:"breakMinutes,omitempty"`
+}
+
+// TimeEntryResult is returned by time entry mutations and includes the labor
+// line with its recalculated actual hours.
+type TimeEntryResult struct {
+	TimeEntry *TimeEntry `json:"timeEntry"`
+	LaborLine *LaborLine `json:"laborLine"`
+}
+
+// NewTimeEntry creates a running time entry for a technician on a labor line.
+func NewTimeEntry(accountID, taskID, laborLineID, technicianID string, startTime int64) *TimeEntry {
+	now := time.Now().Unix()
+	timeEntryID := uuid.New().String()
+
+	return &TimeEntry{
+		TimeEntryID:  timeEntryID,
+		LaborLineID:  laborLineID,
+		AccountID:    accountID,
+		TaskID:       taskID,
+		TechnicianID: technicianID,
+		StartTime:    startTime,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		RecordType:   RecordTypeTimeEntry,
+		PK:           accountID,
+		SK:           TimeEntrySKPrefix(taskID, laborLineID) + timeEntryID,
+	}
+}
+
+// ToTimeEntry converts AddTimeEntryInput to a completed TimeEntry.
+func (input AddTimeEntryInput) ToTimeEntry() *TimeEntry {
+	entry := NewTimeEntry(input.AccountID, input.TaskID, input.LaborLineID, input.TechnicianID, input.StartTime)
+	entry.Stop(input.EndTime, input.BreakMinutes)
+	return entry
+}
+
+// TimeEntrySKPrefix returns the sort key prefix shared by all time entries of a labor line.
+func TimeEntrySKPrefix(taskID, laborLineID string) string {
+	return taskID + "#" + laborLineID + "#TIME#"
+}
+
+// TechnicianTimePK returns the partition key of a technician's time partition,
+// which holds a time slot for each of the technician's time entries.
+func TechnicianTimePK(accountID, technicianID string) string {
+	return "TECHNICIAN#" + accountID + "#" + technicianID
+}
+
+// TimeSlotSK returns the sort key of a time slot. Slots sort by start time, so
+// the slots starting before a given time can be read as a key range.
+func TimeSlotSK(startTime int64, timeEntryID string) string {
+	return fmt.Sprintf("%s%020d#%s", TimeSlotSKPrefix, startTime, timeEntryID)
+}
+
+// Slot returns the time slot recording the entry's period in the technician's
+// time partition.
+func (e *TimeEntry) Slot() *TimeSlot {
+	return &TimeSlot{
+		TimeEntryID:  e.TimeEntryID,
+		LaborLineID:  e.LaborLineID,
+		AccountID:    e.AccountID,
+		TaskID:       e.TaskID,
+		TechnicianID: e.TechnicianID,
+		StartTime:    e.StartTime,
+		EndTime:      e.EndTime,
+		RecordType:   RecordTypeTimeSlot,
+		PK:           TechnicianTimePK(e.AccountID, e.TechnicianID),
+		SK:           TimeSlotSK(e.StartTime, e.TimeEntryID),
+	}
+}
+
+// IsRunning returns true if the timer has not been stopped yet.
+func (e *TimeEntry) IsRunning() bool {
+	return e.EndTime == nil
+}
+
+// Stop completes the entry and computes the worked duration, rounded to the
+// nearest minute and net of breaks.
+func (e *TimeEntry) Stop(endTime, breakMinutes int64) {
+	e.EndTime = &endTime
+	e.BreakMinutes = breakMinutes
+	e.DurationMinutes = (endTime-e.StartTime+30)/60 - breakMinutes
+	if e.DurationMinutes < 0 {
+		e.DurationMinutes = 0
+	}
+	e.UpdatedAt = time.Now().Unix()
+}
+
+// Overlaps returns true if the two entries share any period of time. A running
+// entry is treated as extending indefinitely.
+func (e *TimeEntry) Overlaps(other *TimeEntry) bool {
+	return e.StartTime < other.End() && other.StartTime < e.End()
+}
+
+// OverlapsSlot returns true if the entry shares any period of time with a time
+// slot of another entry.
+func (e *TimeEntry) OverlapsSlot(slot *TimeSlot) bool {
+	return e.StartTime < periodEnd(slot.EndTime) && slot.StartTime < e.End()
+}
+
+// End returns the end time of the entry, or the maximum time if it is still running.
+func (e *TimeEntry) End() int64 {
+	return periodEnd(e.EndTime)
+}
+
+// periodEnd returns the end time of a period, or the maximum time if it has none.
+func periodEnd(endTime *int64) int64 {
+	if endTime == nil {
+		return 1<<63 - 1
+	}
+	return *endTime
+}
+
+// TotalActualHours sums the worked time of all completed entries, in hours.
+func TotalActualHours(entries []*TimeEntry) Decimal {
+	var minutes int64
+	for _, entry := range entries {
+		if !entry.IsRunning() {
+			minutes += entry.DurationMinutes
+		}
+	}
+	return HoursFromMinutes(minutes)
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTimeEntry(t *testing.T) {
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	entry := NewTimeEntry(accountID, taskID, laborLineID, "tech-1", 1000)
+
+	_, err := uuid.Parse(entry.TimeEntryID)
+	require.NoError(t, err)
+	assert.Equal(t, "tech-1", entry.TechnicianID)
+	assert.Equal(t, int64(1000), entry.StartTime)
+	assert.True(t, entry.IsRunning())
+	assert.Equal(t, RecordTypeTimeEntry, entry.RecordType)
+	assert.Equal(t, accountID, entry.PK)
+	assert.Equal(t, taskID+"#"+laborLineID+"#TIME#"+entry.TimeEntryID, entry.SK)
+}
+
+func TestTimeEntry_Stop(t *testing.T) {
+	tests := []struct {
+		name         string
+		start        int64
+		end          int64
+		breakMinutes int64
+		expected     int64
+	}{
+		{name: "Exact minutes", start: 0, end: 3600, expected: 60},
+		{name: "Rounds to nearest minute", start: 0, end: 3629, expected: 60},
+		{name: "Rounds half up", start: 0, end: 3630, expected: 61},
+		{name: "Deducts breaks", start: 0, end: 7200, breakMinutes: 30, expected: 90},
+		{name: "Never negative", start: 0, end: 60, breakMinutes: 5, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := NewTimeEntry(uuid.New().String(), uuid.New().String(), uuid.New().String(), "tech-1", tt.start)
+			entry.Stop(tt.end, tt.breakMinutes)
+
+			assert.False(t, entry.IsRunning())
+			require.NotNil(t, entry.EndTime)
+			assert.Equal(t, tt.end, *entry.EndTime)
+			assert.Equal(t, tt.expected, entry.DurationMinutes)
+		})
+	}
+}
+
+func TestTimeEntry_Overlaps(t *testing.T) {
+	completed := func(start, end int64) *TimeEntry {
+		return &TimeEntry{StartTime: start, EndTime: &end}
+	}
+	running := func(start int64) *TimeEntry {
+		return &TimeEntry{StartTime: start}
+	}
+
+	tests := []struct {
+		name     string
+		a        *TimeEntry
+		b        *TimeEntry
+		expected bool
+	}{
+		{name: "Disjoint", a: completed(0, 100), b: completed(200, 300), expected: false},
+		{name: "Adjacent", a: completed(0, 100), b: completed(100, 200), expected: false},
+		{name: "Partial overlap", a: completed(0, 150), b: completed(100, 200), expected: true},
+		{name: "Contained", a: completed(0, 300), b: completed(100, 200), expected: true},
+		{name: "Running after completed", a: running(150), b: completed(100, 200), expected: true},
+		{name: "Running starts after completed ends", a: running(200), b: completed(100, 200), expected: false},
+		{name: "Two running", a: running(0), b: running(500), expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.a.Overlaps(tt.b))
+			assert.Equal(t, tt.expected, tt.b.Overlaps(tt.a))
+		})
+	}
+}
+
+func TestTotalActualHours(t *testing.T) {
+	end := int64(3600)
+	entries := []*TimeEntry{
+		{StartTime: 0, EndTime: &end, DurationMinutes: 50},
+		{StartTime: 0, EndTime: &end, DurationMinutes: 40},
+		{StartTime: 5000, DurationMinutes: 0}, // running entries are ignored
+	}
+
+	assert.Equal(t, "1.50", TotalActualHours(entries).StringFixed(2))
+	assert.True(t, TotalActualHours(nil).IsZero())
+}
+
+func TestAddTimeEntryInput_ToTimeEntry(t *testing.T) {
+	input := AddTimeEntryInput{
+		AccountID:    uuid.New().String(),
+		TaskID:       uuid.New().String(),
+		LaborLineID:  uuid.New().String(),
+		TechnicianID: "tech-1",
+		StartTime:    0,
+		EndTime:      5400,
+		BreakMinutes: 15,
+	}
+
+	entry := input.ToTimeEntry()
+
+	assert.Equal(t, input.LaborLineID, entry.LaborLineID)
+	assert.False(t, entry.IsRunning())
+	assert.Equal(t, int64(75), entry.DurationMinutes)
+	assert.Equal(t, int64(15), entry.BreakMinutes)
+}
+
+func TestTimeEntry_Slot(t *testing.T) {
+	entry := NewTimeEntry("acct-1", "task-1", "line-1", "tech-1", 1000)
+	entry.Stop(4600, 0)
+
+	slot := entry.Slot()
+
+	assert.Equal(t, "TECHNICIAN#acct-1#tech-1", slot.PK)
+	assert.Equal(t, "TIME#00000000000000001000#"+entry.TimeEntryID, slot.SK)
+	assert.Equal(t, RecordTypeTimeSlot, slot.RecordType)
+	assert.Equal(t, "line-1", slot.LaborLineID)
+
+	// Slots sort by start time
+	assert.Less(t, TimeSlotSK(999, "z"), slot.SK)
+	assert.Less(t, slot.SK, TimeSlotSK(1001, ""))
+
+	assert.True(t, NewTimeEntry("acct-1", "task-1", "line-2", "tech-1", 2000).OverlapsSlot(slot))
+	assert.False(t, NewTimeEntry("acct-1", "task-1", "line-2", "tech-1", 4600).OverlapsSlot(slot))
+}
@@ -0,0 +1,57 @@
+// Package schemas embeds the JSON schemas of labor line records, one file per
+// schema version, so that the function validates against exactly the schema
+// files in the repository.
+//
+// Adding an optional property is compatible with records already written and
+// is made to the current version. Any other change adds a new version file
+// and raises CurrentVersion, keeping the older versions to validate the
+// records written with them.
+package schemas
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+)
+
+// CurrentVersion is the schema version labor lines are written with.
+const CurrentVersion = 3
+
+// UnversionedVersion is the schema version of labor lines written before the
+// version was recorded on each item.
+const UnversionedVersion = 1
+
+// laborLineSchemaPattern names the file of each labor line schema version.
+const laborLineSchemaPattern = "labor-line.v%d.schema.json"
+
+//go:embed labor-line.v*.schema.json
+var files embed.FS
+
+// LaborLine returns the labor line schema document of the given version.
+func LaborLine(version int) ([]byte, error) {
+	document, err := files.ReadFile(fmt.Sprintf(laborLineSchemaPattern, version))
+	if err != nil {
+		return nil, fmt.Errorf("labor line schema version %d: %w", version, err)
+	}
+	return document, nil
+}
+
+// Versions returns the embedded labor line schema versions in ascending order.
+func Versions() []int {
+	names, err := fs.Glob(files, "labor-line.v*.schema.json")
+	if err != nil {
+		// The pattern is constant and well-formed
+		panic(err)
+	}
+
+	versions := make([]int, 0, len(names))
+	for _, name := range names {
+		var version int
+		if _, err := fmt.Sscanf(name, laborLineSchemaPattern, &version); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	sort.Ints(versions)
+	return versions
+}
@@ -0,0 +1,35 @@
+package schemas
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersions(t *testing.T) {
+	versions := Versions()
+
+	require.NotEmpty(t, versions)
+	assert.Contains(t, versions, CurrentVersion)
+	assert.Contains(t, versions, UnversionedVersion)
+	for i := 1; i < len(versions); i++ {
+		assert.Less(t, versions[i-1], versions[i])
+	}
+}
+
+func TestLaborLine(t *testing.T) {
+	for _, version := range Versions() {
+		document, err := LaborLine(version)
+		require.NoError(t, err)
+
+		var schema map[string]interface{}
+		require.NoError(t, json.Unmarshal(document, &schema), "version %d", version)
+		assert.Equal(t, "Labor Line", schema["title"])
+		assert.Contains(t, schema["definitions"], "timeEntry")
+	}
+
+	_, err := LaborLine(0)
+	assert.Error(t, err)
+}
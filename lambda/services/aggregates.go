@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// ApplyLaborLineChange folds a change to a labor line, as delivered by the
+// table's stream, into its account's status counts. before is nil for a new
+// labor line and after is nil for a removed one.
+//
+// Stream records can be delivered more than once, so each labor line has a
+// marker item holding the version the counts include, which is advanced in
+// the same transaction as the counts. A change the marker shows was already
+// applied is skipped.
+//
+// Task totals are not kept here: every labor line write updates its task's
+// summary in its own transaction. If the change moves the task's totals it
+// returns them as the task summary now holds them; otherwise it returns nil.
+func (s *dynamoDBService) ApplyLaborLineChange(ctx context.Context, before, after *models.LaborLine) (*models.TaskLaborTotals, error) {
+	laborLine := after
+	if laborLine == nil {
+		laborLine = before
+	}
+	if laborLine == nil {
+		return nil, nil
+	}
+
+	statusChanges := models.StatusCountChanges(before, after)
+
+	// A removed labor line was soft deleted first, so it is usually only its
+	// marker that is left to remove
+	var transactItems []types.TransactWriteItem
+	switch {
+	case after == nil:
+		transactItems = append(transactItems, s.removeMarkerTransactItem(laborLine))
+	case len(statusChanges) > 0:
+		transactItems = append(transactItems, s.advanceMarkerTransactItem(laborLine))
+	}
+
+	if len(transactItems) > 0 {
+		transactItems = append(transactItems, s.statusCountTransactItems(laborLine.AccountID, statusChanges)...)
+		_, err := s.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+			TransactItems: transactItems,
+		})
+		if _, alreadyApplied := transactionConditionReason(err, 0); err != nil && !alreadyApplied {
+			return nil, fmt.Errorf("applying labor line change to aggregates in DynamoDB: %w", err)
+		}
+	}
+
+	if models.TotalsOf(after).Sub(models.TotalsOf(before)).IsZero() {
+		return nil, nil
+	}
+	return s.taskLaborTotals(ctx, laborLine.AccountID, laborLine.TaskID)
+}
+
+// advanceMarkerTransactItem builds the transaction item that records a labor
+// line's version as included in the aggregates, unless it or a later version
+// already is.
+func (s *dynamoDBService) advanceMarkerTransactItem(laborLine *models.LaborLine) types.TransactWriteItem {
+	pk, sk := models.AggregateMarkerKey(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID)
+	return types.TransactWriteItem{
+		Update: &types.Update{
+			TableName: aws.String(s.tableName),
+			Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: pk},
+				"SK": &types.AttributeValueMemberS{Value: sk},
+			},
+			UpdateExpression:    aws.String("SET appliedVersion = :version, recordType = :recordType"),
+			ConditionExpression: aws.String("attribute_not_exists(appliedVersion) OR appliedVersion < :version"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":version":    &types.AttributeValueMemberN{Value: strconv.FormatInt(laborLine.Version, 10)},
+				":recordType": &types.AttributeValueMemberS{Value: models.RecordTypeAggregateMarker},
+			},
+		},
+	}
+}
+
+// removeMarkerTransactItem builds the transaction item that removes a removed
+// labor line's marker. The marker must exist, so the removal is only applied
+// once.
+func (s *dynamoDBService) removeMarkerTransactItem(laborLine *models.LaborLine) types.TransactWriteItem {
+	pk, sk := models.AggregateMarkerKey(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID)
+	return types.TransactWriteItem{
+		Delete: &types.Delete{
+			TableName: aws.String(s.tableName),
+			Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: pk},
+				"SK": &types.AttributeValueMemberS{Value: sk},
+			},
+			ConditionExpression: aws.String("appliedVersion <= :version"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":version": &types.AttributeValueMemberN{Value: strconv.FormatInt(laborLine.Version, 10)},
+			},
+		},
+	}
+}
+
+// statusCountTransactItems builds the transaction items that move an
+// account's labor line counts by status, in status order.
+func (s *dynamoDBService) statusCountTransactItems(accountID string, changes map[models.LaborLineStatus]int64) []types.TransactWriteItem {
+	statuses := make([]models.LaborLineStatus, 0, len(changes))
+	for status := range changes {
+		statuses = append(statuses, status)
+	}
+	slices.Sort(statuses)
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	items := make([]types.TransactWriteItem, 0, len(statuses))
+	for _, status := range statuses {
+		pk, sk := models.StatusCountKey(accountID, status)
+		items = append(items, types.TransactWriteItem{
+			Update: &types.Update{
+				TableName: aws.String(s.tableName),
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: pk},
+					"SK": &types.AttributeValueMemberS{Value: sk},
+				},
+				// status and count are reserved words
+				UpdateExpression:         aws.String("SET accountId = :accountId, #status = :status, recordType = :recordType, updatedAt = :updatedAt ADD #count :change"),
+				ExpressionAttributeNames: map[string]string{"#status": "status", "#count": "count"},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":accountId":  &types.AttributeValueMemberS{Value: accountID},
+					":status":     &types.AttributeValueMemberS{Value: string(status)},
+					":recordType": &types.AttributeValueMemberS{Value: models.RecordTypeStatusCount},
+					":updatedAt":  &types.AttributeValueMemberN{Value: now},
+					":change":     &types.AttributeValueMemberN{Value: strconv.FormatInt(changes[status], 10)},
+				},
+			},
+		})
+	}
+	return items
+}
+
+// taskLaborTotals reads a task's totals from its summary, recomputing the
+// summary if the task does not have one yet.
+func (s *dynamoDBService) taskLaborTotals(ctx context.Context, accountID, taskID string) (*models.TaskLaborTotals, error) {
+	summary, err := s.GetTaskLaborSummary(ctx, models.GetTaskLaborSummaryInput{AccountID: accountID, TaskID: taskID})
+	if err != nil {
+		return nil, err
+	}
+	return &models.TaskLaborTotals{
+		AccountID:       summary.AccountID,
+		TaskID:          summary.TaskID,
+		LaborLineTotals: summary.LaborLineTotals,
+		UpdatedAt:       summary.UpdatedAt,
+	}, nil
+}
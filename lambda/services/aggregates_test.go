@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// taskSummaryItem returns the summary item of a labor line's task, holding
+// laborLineCount labor lines.
+func taskSummaryItem(laborLine *models.LaborLine, laborLineCount string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"accountId":      &types.AttributeValueMemberS{Value: laborLine.AccountID},
+		"taskId":         &types.AttributeValueMemberS{Value: laborLine.TaskID},
+		"laborLineCount": &types.AttributeValueMemberN{Value: laborLineCount},
+		"revision":       &types.AttributeValueMemberN{Value: "1"},
+		"initialized":    &types.AttributeValueMemberBOOL{Value: true},
+	}
+}
+
+// aggregatedLaborLine returns a labor line with hours and cost to aggregate.
+func aggregatedLaborLine() *models.LaborLine {
+	laborLine := newTestLaborLine()
+	laborLine.Status = models.StatusPending
+	laborLine.EstimatedHours = models.MustParseDecimal("2")
+	laborLine.RatePerHour = models.MustParseDecimal("100")
+	laborLine.LaborCost = models.MustParseDecimal("200")
+	return laborLine
+}
+
+func TestDynamoDBService_ApplyLaborLineChange_Create(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	after := aggregatedLaborLine()
+
+	var written *dynamodb.TransactWriteItemsInput
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { written = args.Get(1).(*dynamodb.TransactWriteItemsInput) }).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+	onGetTaskSummary(client, after.TaskID, taskSummaryItem(after, "1"))
+
+	totals, err := service.ApplyLaborLineChange(context.Background(), nil, after)
+	require.NoError(t, err)
+	require.NotNil(t, totals)
+	assert.Equal(t, after.TaskID, totals.TaskID)
+	assert.Equal(t, int64(1), totals.LaborLineCount)
+
+	// The marker and the status count change together; the task's totals are
+	// read from the summary the labor line's write updated
+	require.Len(t, written.TransactItems, 2)
+	marker := written.TransactItems[0].Update
+	assert.Equal(t, "AGGREGATED#"+after.TaskID+"#"+after.LaborLineID, marker.Key["SK"].(*types.AttributeValueMemberS).Value)
+	assert.Equal(t, "attribute_not_exists(appliedVersion) OR appliedVersion < :version", *marker.ConditionExpression)
+	assert.Equal(t, "3", marker.ExpressionAttributeValues[":version"].(*types.AttributeValueMemberN).Value)
+
+	count := written.TransactItems[1].Update
+	assert.Equal(t, "COUNT#PENDING", count.Key["SK"].(*types.AttributeValueMemberS).Value)
+	assert.Equal(t, "1", count.ExpressionAttributeValues[":change"].(*types.AttributeValueMemberN).Value)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_ApplyLaborLineChange_AlreadyApplied(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	after := aggregatedLaborLine()
+
+	// A replayed record fails the marker's condition
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Return((*dynamodb.TransactWriteItemsOutput)(nil), &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("ConditionalCheckFailed")},
+				{Code: aws.String("None")},
+			},
+		}).Once()
+	onGetTaskSummary(client, after.TaskID, taskSummaryItem(after, "1"))
+
+	totals, err := service.ApplyLaborLineChange(context.Background(), nil, after)
+	require.NoError(t, err)
+	assert.NotNil(t, totals)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_ApplyLaborLineChange_StatusChange(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	before := aggregatedLaborLine()
+	after := *before
+	after.Status = models.StatusInProgress
+	after.Version++
+
+	var written *dynamodb.TransactWriteItemsInput
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { written = args.Get(1).(*dynamodb.TransactWriteItemsInput) }).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	// The totals do not move, so they are not read back
+	totals, err := service.ApplyLaborLineChange(context.Background(), before, &after)
+	require.NoError(t, err)
+	assert.Nil(t, totals)
+
+	require.Len(t, written.TransactItems, 3)
+	inProgress := written.TransactItems[1].Update
+	assert.Equal(t, "COUNT#IN_PROGRESS", inProgress.Key["SK"].(*types.AttributeValueMemberS).Value)
+	assert.Equal(t, "1", inProgress.ExpressionAttributeValues[":change"].(*types.AttributeValueMemberN).Value)
+	pending := written.TransactItems[2].Update
+	assert.Equal(t, "COUNT#PENDING", pending.Key["SK"].(*types.AttributeValueMemberS).Value)
+	assert.Equal(t, "-1", pending.ExpressionAttributeValues[":change"].(*types.AttributeValueMemberN).Value)
+
+	client.AssertNotCalled(t, "GetItem", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_ApplyLaborLineChange_HoursChange(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	before := aggregatedLaborLine()
+	after := *before
+	after.ActualHours = models.MustParseDecimal("1.5")
+	after.Version++
+	onGetTaskSummary(client, after.TaskID, taskSummaryItem(&after, "1"))
+
+	// The status counts do not move, so nothing is written
+	totals, err := service.ApplyLaborLineChange(context.Background(), before, &after)
+	require.NoError(t, err)
+	require.NotNil(t, totals)
+	assert.Equal(t, int64(1), totals.LaborLineCount)
+
+	client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_ApplyLaborLineChange_NoAggregateChange(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	before := aggregatedLaborLine()
+	after := *before
+	after.Description = "Inspect brakes"
+	after.Version++
+
+	totals, err := service.ApplyLaborLineChange(context.Background(), before, &after)
+	require.NoError(t, err)
+	assert.Nil(t, totals)
+
+	client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_ApplyLaborLineChange_RemoveDeleted(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	before := aggregatedLaborLine()
+	deletedAt := int64(1760000000)
+	before.DeletedAt = &deletedAt
+
+	var written *dynamodb.TransactWriteItemsInput
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { written = args.Get(1).(*dynamodb.TransactWriteItemsInput) }).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	// The soft delete already took it out of the aggregates; only the marker goes
+	totals, err := service.ApplyLaborLineChange(context.Background(), before, nil)
+	require.NoError(t, err)
+	assert.Nil(t, totals)
+
+	require.Len(t, written.TransactItems, 1)
+	marker := written.TransactItems[0].Delete
+	require.NotNil(t, marker)
+	assert.Equal(t, "appliedVersion <= :version", *marker.ConditionExpression)
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// technicianIndexName is the sparse global secondary index of assignments,
+// keyed on technicianKey and sorted by assignedAt.
+const technicianIndexName = "TechnicianIndex"
+
+// AssignLaborLine assigns a technician to a labor line. The labor line records
+// the technician and an assignment item puts the labor line in the
+// technician's queue, both in one transaction conditioned on the version that
+// was read. Assigning a technician twice is rejected with
+// ErrTechnicianAlreadyAssigned.
+func (s *dynamoDBService) AssignLaborLine(ctx context.Context, input models.LaborLineAssignmentInput, actor string) (*models.LaborLine, error) {
+	existing, err := s.assignableLaborLine(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if existing.IsAssigned(input.TechnicianID) {
+		return nil, ErrTechnicianAlreadyAssigned
+	}
+	if len(existing.AssignedTechnicianIDs) >= models.MaxAssignedTechnicians {
+		return nil, ErrTooManyTechnicians
+	}
+
+	item, err := attributevalue.MarshalMap(models.NewAssignment(existing, input.TechnicianID, actor))
+	if err != nil {
+		return nil, fmt.Errorf("marshaling assignment: %w", err)
+	}
+	assignment := types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:           aws.String(s.tableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(PK)"),
+		},
+	}
+
+	technicianIDs := append(slices.Clone(existing.AssignedTechnicianIDs), input.TechnicianID)
+	return s.writeAssignment(ctx, existing, technicianIDs, models.ActionAssign, actor, assignment)
+}
+
+// UnassignLaborLine removes a technician from a labor line and from the
+// technician's queue. Unassigning a technician who is not assigned is rejected
+// with ErrTechnicianNotAssigned.
+func (s *dynamoDBService) UnassignLaborLine(ctx context.Context, input models.LaborLineAssignmentInput, actor string) (*models.LaborLine, error) {
+	existing, err := s.assignableLaborLine(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if !existing.IsAssigned(input.TechnicianID) {
+		return nil, ErrTechnicianNotAssigned
+	}
+
+	pk, sk := models.AssignmentKey(input.AccountID, input.TaskID, input.LaborLineID, input.TechnicianID)
+	assignment := types.TransactWriteItem{
+		Delete: &types.Delete{
+			TableName: aws.String(s.tableName),
+			Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: pk},
+				"SK": &types.AttributeValueMemberS{Value: sk},
+			},
+		},
+	}
+
+	technicianIDs := slices.DeleteFunc(slices.Clone(existing.AssignedTechnicianIDs), func(id string) bool { return id == input.TechnicianID })
+	if len(technicianIDs) == 0 {
+		technicianIDs = nil
+	}
+	return s.writeAssignment(ctx, existing, technicianIDs, models.ActionUnassign, actor, assignment)
+}
+
+// assignableLaborLine reads the labor line an assignment input refers to and
+// checks that its assignments may be changed.
+func (s *dynamoDBService) assignableLaborLine(ctx context.Context, input models.LaborLineAssignmentInput) (*models.LaborLine, error) {
+	existing, err := s.GetLaborLine(ctx, models.GetLaborLineInput{
+		AccountID:   input.AccountID,
+		TaskID:      input.TaskID,
+		LaborLineID: input.LaborLineID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("checking existing labor line: %w", err)
+	}
+	if existing == nil {
+		return nil, ErrLaborLineNotFound
+	}
+	if input.ExpectedVersion != nil && *input.ExpectedVersion != existing.Version {
+		return nil, &ConflictError{CurrentVersion: existing.Version}
+	}
+	if existing.IsReadOnly() {
+		return nil, ErrLaborLineReadOnly
+	}
+	return existing, nil
+}
+
+// writeAssignment writes the labor line with its new technicians together with
+// the change to the assignment item, recording it in the history.
+func (s *dynamoDBService) writeAssignment(ctx context.Context, existing *models.LaborLine, technicianIDs []string, action models.ChangeAction, actor string, assignment types.TransactWriteItem) (*models.LaborLine, error) {
+	updated := *existing
+	updated.AssignedTechnicianIDs = technicianIDs
+	updated.UpdatedAt = time.Now().Unix()
+	updated.Version = existing.Version + 1
+
+	err := s.writeWithHistory(ctx, versionedWrite(&updated, existing.Version), action, actor, existing, assignment)
+	if err != nil {
+		if condErr := transactionConditionFailure(err, 0); condErr != nil {
+			return nil, condErr
+		}
+		return nil, fmt.Errorf("writing labor line assignment to DynamoDB: %w", err)
+	}
+
+	return &updated, nil
+}
+
+// assignmentStatusUpdates returns the writes that copy the labor line's status
+// onto each of its assignments.
+func (s *dynamoDBService) assignmentStatusUpdates(laborLine *models.LaborLine) []types.TransactWriteItem {
+	updates := make([]types.TransactWriteItem, 0, len(laborLine.AssignedTechnicianIDs))
+	for _, technicianID := range laborLine.AssignedTechnicianIDs {
+		pk, sk := models.AssignmentKey(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID, technicianID)
+		updates = append(updates, types.TransactWriteItem{
+			Update: &types.Update{
+				TableName: aws.String(s.tableName),
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: pk},
+					"SK": &types.AttributeValueMemberS{Value: sk},
+				},
+				UpdateExpression: aws.String("SET #status = :status"),
+				// Never create an assignment the labor line does not list
+				ConditionExpression:      aws.String("attribute_exists(PK)"),
+				ExpressionAttributeNames: map[string]string{"#status": "status"},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":status": &types.AttributeValueMemberS{Value: string(laborLine.CurrentStatus())},
+				},
+			},
+		})
+	}
+	return updates
+}
+
+// ListLaborLinesByTechnician retrieves a page of the labor lines a technician
+// is assigned to from the TechnicianIndex, most recently assigned first,
+// optionally limited to some statuses and to a range of assignment times.
+// Soft-deleted labor lines are left out, so a page may hold fewer labor lines
+// than its limit.
+func (s *dynamoDBService) ListLaborLinesByTechnician(ctx context.Context, input models.ListLaborLinesByTechnicianInput) (*models.LaborLineConnection, error) {
+	technicianKey := models.TechnicianKey(input.AccountID, input.TechnicianID)
+	keyCondition := "technicianKey = :technicianKey"
+	values := map[string]types.AttributeValue{
+		":technicianKey": &types.AttributeValueMemberS{Value: technicianKey},
+	}
+
+	switch {
+	case input.AssignedFrom != nil && input.AssignedTo != nil:
+		keyCondition += " AND assignedAt BETWEEN :from AND :to"
+	case input.AssignedFrom != nil:
+		keyCondition += " AND assignedAt >= :from"
+	case input.AssignedTo != nil:
+		keyCondition += " AND assignedAt <= :to"
+	}
+	if input.AssignedFrom != nil {
+		values[":from"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(*input.AssignedFrom, 10)}
+	}
+	if input.AssignedTo != nil {
+		values[":to"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(*input.AssignedTo, 10)}
+	}
+
+	queryInput := &dynamodb.QueryInput{
+		TableName:                 aws.String(s.tableName),
+		IndexName:                 aws.String(technicianIndexName),
+		KeyConditionExpression:    aws.String(keyCondition),
+		ExpressionAttributeValues: values,
+		ScanIndexForward:          aws.Bool(false),
+	}
+	if len(input.Statuses) > 0 {
+		placeholders := make([]string, len(input.Statuses))
+		for i, status := range input.Statuses {
+			placeholders[i] = ":status" + strconv.Itoa(i)
+			values[placeholders[i]] = &types.AttributeValueMemberS{Value: string(status)}
+		}
+		queryInput.FilterExpression = aws.String("#status IN (" + strings.Join(placeholders, ", ") + ")")
+		queryInput.ExpressionAttributeNames = map[string]string{"#status": "status"}
+	}
+
+	items, nextToken, err := s.queryPage(ctx, queryInput, "technician#"+technicianKey, input.Limit, input.NextToken)
+	if err != nil {
+		return nil, fmt.Errorf("querying labor lines by technician from DynamoDB: %w", err)
+	}
+
+	keys := make([]models.GetLaborLineInput, len(items))
+	for i, item := range items {
+		var assignment models.Assignment
+		if err := attributevalue.UnmarshalMap(item, &assignment); err != nil {
+			return nil, fmt.Errorf("unmarshaling assignment: %w", err)
+		}
+		keys[i] = models.GetLaborLineInput{AccountID: assignment.AccountID, TaskID: assignment.TaskID, LaborLineID: assignment.LaborLineID}
+	}
+
+	found, err := s.BatchGetLaborLines(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	laborLines := make([]*models.LaborLine, 0, len(found))
+	for _, laborLine := range found {
+		if laborLine != nil {
+			laborLines = append(laborLines, laborLine)
+		}
+	}
+
+	return &models.LaborLineConnection{
+		Items:     laborLines,
+		NextToken: nextToken,
+	}, nil
+}
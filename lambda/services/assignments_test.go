@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// assignmentInput builds an assignment request for the given labor line.
+func assignmentInput(laborLine *models.LaborLine, technicianID string) models.LaborLineAssignmentInput {
+	return models.LaborLineAssignmentInput{
+		AccountID:    laborLine.AccountID,
+		TaskID:       laborLine.TaskID,
+		LaborLineID:  laborLine.LaborLineID,
+		TechnicianID: technicianID,
+	}
+}
+
+func TestDynamoDBService_AssignLaborLine(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	stored := newBatchLaborLine(uuid.New().String())
+	stored.AssignedTechnicianIDs = []string{"tech-1"}
+	onGetLaborLine(client, stored)
+
+	var written *dynamodb.TransactWriteItemsInput
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { written = args.Get(1).(*dynamodb.TransactWriteItemsInput) }).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	updated, err := service.AssignLaborLine(context.Background(), assignmentInput(stored, "tech-2"), "user-123")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tech-1", "tech-2"}, updated.AssignedTechnicianIDs)
+	assert.Equal(t, stored.Version+1, updated.Version)
+
+	// The labor line, its history, the assignment and the task's summary are written together
+	require.NotNil(t, written)
+	require.Len(t, written.TransactItems, 4)
+	var record models.HistoryRecord
+	require.NoError(t, attributevalue.UnmarshalMap(historyPut(written).Item, &record))
+	assert.Equal(t, models.ActionAssign, record.Action)
+
+	put := written.TransactItems[2].Put
+	require.NotNil(t, put)
+	assert.Equal(t, "attribute_not_exists(PK)", *put.ConditionExpression)
+	var assignment models.Assignment
+	require.NoError(t, attributevalue.UnmarshalMap(put.Item, &assignment))
+	assert.Equal(t, "tech-2", assignment.TechnicianID)
+	assert.Equal(t, stored.AccountID+"#tech-2", assignment.TechnicianKey)
+
+	summary := written.TransactItems[3].Update
+	require.NotNil(t, summary)
+	assert.Equal(t, map[string]string{"#a0": "technician#tech-2#laborLineCount"}, summary.ExpressionAttributeNames)
+	assert.Equal(t, models.StatusPending, assignment.Status)
+	assert.Equal(t, "user-123", assignment.AssignedBy)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_AssignLaborLine_AlreadyAssigned(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	stored := newBatchLaborLine(uuid.New().String())
+	stored.AssignedTechnicianIDs = []string{"tech-1"}
+	onGetLaborLine(client, stored)
+
+	_, err := service.AssignLaborLine(context.Background(), assignmentInput(stored, "tech-1"), "user-123")
+	assert.ErrorIs(t, err, ErrTechnicianAlreadyAssigned)
+
+	client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_AssignLaborLine_TooManyTechnicians(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	stored := newBatchLaborLine(uuid.New().String())
+	for i := range models.MaxAssignedTechnicians {
+		stored.AssignedTechnicianIDs = append(stored.AssignedTechnicianIDs, fmt.Sprintf("tech-%d", i))
+	}
+	onGetLaborLine(client, stored)
+
+	_, err := service.AssignLaborLine(context.Background(), assignmentInput(stored, "tech-new"), "user-123")
+	assert.ErrorIs(t, err, ErrTooManyTechnicians)
+}
+
+func TestDynamoDBService_UnassignLaborLine(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	stored := newBatchLaborLine(uuid.New().String())
+	stored.AssignedTechnicianIDs = []string{"tech-1"}
+	onGetLaborLine(client, stored)
+
+	var written *dynamodb.TransactWriteItemsInput
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { written = args.Get(1).(*dynamodb.TransactWriteItemsInput) }).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	updated, err := service.UnassignLaborLine(context.Background(), assignmentInput(stored, "tech-1"), "user-123")
+	require.NoError(t, err)
+	assert.Empty(t, updated.AssignedTechnicianIDs)
+
+	require.Len(t, written.TransactItems, 4)
+	deleted := written.TransactItems[2].Delete
+	require.NotNil(t, deleted)
+	assert.Equal(t, stored.TaskID+"#"+stored.LaborLineID+"#ASSIGN#tech-1", deleted.Key["SK"].(*types.AttributeValueMemberS).Value)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_UnassignLaborLine_NotAssigned(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	stored := newBatchLaborLine(uuid.New().String())
+	onGetLaborLine(client, stored)
+
+	_, err := service.UnassignLaborLine(context.Background(), assignmentInput(stored, "tech-1"), "user-123")
+	assert.ErrorIs(t, err, ErrTechnicianNotAssigned)
+
+	client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_ListLaborLinesByTechnician(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table", WithPageTokenSecret([]byte("test-secret")))
+
+	laborLine := newBatchLaborLine("acct-1")
+	laborLine.AssignedTechnicianIDs = []string{"tech-1"}
+	assignment, err := attributevalue.MarshalMap(models.NewAssignment(laborLine, "tech-1", "user-1"))
+	require.NoError(t, err)
+	stored, err := attributevalue.MarshalMap(laborLine)
+	require.NoError(t, err)
+
+	from, to := int64(1700000000), int64(1800000000)
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		technicianKey := input.ExpressionAttributeValues[":technicianKey"].(*types.AttributeValueMemberS)
+		status0 := input.ExpressionAttributeValues[":status0"].(*types.AttributeValueMemberS)
+		return *input.IndexName == "TechnicianIndex" &&
+			*input.KeyConditionExpression == "technicianKey = :technicianKey AND assignedAt BETWEEN :from AND :to" &&
+			technicianKey.Value == "acct-1#tech-1" &&
+			*input.FilterExpression == "#status IN (:status0, :status1)" &&
+			status0.Value == "IN_PROGRESS" &&
+			!*input.ScanIndexForward
+	})).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{assignment},
+	}, nil).Once()
+	client.On("BatchGetItem", mock.Anything, mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]types.AttributeValue{"test-table": {stored}},
+	}, nil).Once()
+
+	page, err := service.ListLaborLinesByTechnician(context.Background(), models.ListLaborLinesByTechnicianInput{
+		AccountID:    "acct-1",
+		TechnicianID: "tech-1",
+		Statuses:     []models.LaborLineStatus{models.StatusInProgress, models.StatusOnHold},
+		AssignedFrom: &from,
+		AssignedTo:   &to,
+	})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, laborLine.LaborLineID, page.Items[0].LaborLineID)
+	assert.Nil(t, page.NextToken)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_ListLaborLinesByTechnician_Pages(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table", WithPageTokenSecret([]byte("test-secret")))
+
+	laborLines := []*models.LaborLine{newBatchLaborLine("acct-1"), newBatchLaborLine("acct-1")}
+	assignments := make([]map[string]types.AttributeValue, len(laborLines))
+	stored := make([]map[string]types.AttributeValue, len(laborLines))
+	for i, laborLine := range laborLines {
+		laborLine.AssignedTechnicianIDs = []string{"tech-1"}
+		var err error
+		assignments[i], err = attributevalue.MarshalMap(models.NewAssignment(laborLine, "tech-1", "user-1"))
+		require.NoError(t, err)
+		stored[i], err = attributevalue.MarshalMap(laborLine)
+		require.NoError(t, err)
+	}
+
+	// The TechnicianIndex's last evaluated key includes its numeric range key
+	lastKey := map[string]types.AttributeValue{
+		"PK":            assignments[0]["PK"],
+		"SK":            assignments[0]["SK"],
+		"technicianKey": &types.AttributeValueMemberS{Value: "acct-1#tech-1"},
+		"assignedAt":    &types.AttributeValueMemberN{Value: "1760000000"},
+	}
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return input.ExclusiveStartKey == nil
+	})).Return(&dynamodb.QueryOutput{
+		Items:            assignments[:1],
+		LastEvaluatedKey: lastKey,
+	}, nil).Once()
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return assert.ObjectsAreEqual(lastKey, input.ExclusiveStartKey)
+	})).Return(&dynamodb.QueryOutput{
+		Items: assignments[1:],
+	}, nil).Once()
+	client.On("BatchGetItem", mock.Anything, mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]types.AttributeValue{"test-table": stored[:1]},
+	}, nil).Once()
+	client.On("BatchGetItem", mock.Anything, mock.Anything).Return(&dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]types.AttributeValue{"test-table": stored[1:]},
+	}, nil).Once()
+
+	input := models.ListLaborLinesByTechnicianInput{AccountID: "acct-1", TechnicianID: "tech-1", Limit: 1}
+	first, err := service.ListLaborLinesByTechnician(context.Background(), input)
+	require.NoError(t, err)
+	require.Len(t, first.Items, 1)
+	require.NotNil(t, first.NextToken)
+
+	input.NextToken = *first.NextToken
+	second, err := service.ListLaborLinesByTechnician(context.Background(), input)
+	require.NoError(t, err)
+	require.Len(t, second.Items, 1)
+	assert.Equal(t, laborLines[1].LaborLineID, second.Items[0].LaborLineID)
+	assert.Nil(t, second.NextToken)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_TransitionLaborLineStatus_UpdatesAssignments(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newBatchLaborLine(uuid.New().String())
+	laborLine.AssignedTechnicianIDs = []string{"tech-1", "tech-2"}
+	onGetLaborLine(client, laborLine)
+
+	var written *dynamodb.TransactWriteItemsInput
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { written = args.Get(1).(*dynamodb.TransactWriteItemsInput) }).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	_, err := service.TransitionLaborLineStatus(context.Background(), transitionInput(laborLine, models.StatusInProgress), "user-123")
+	require.NoError(t, err)
+
+	// Each assignment gets the new status in the same transaction, followed by
+	// the task's summary
+	require.Len(t, written.TransactItems, 5)
+	for i, technicianID := range laborLine.AssignedTechnicianIDs {
+		update := written.TransactItems[2+i].Update
+		require.NotNil(t, update)
+		assert.Equal(t, laborLine.TaskID+"#"+laborLine.LaborLineID+"#ASSIGN#"+technicianID, update.Key["SK"].(*types.AttributeValueMemberS).Value)
+		assert.Equal(t, "IN_PROGRESS", update.ExpressionAttributeValues[":status"].(*types.AttributeValueMemberS).Value)
+	}
+}
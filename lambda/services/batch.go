@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// ErrBatchAborted is returned for items of an all-or-nothing batch that were not
+// written because another item in the batch failed.
+var ErrBatchAborted = errors.New("not written because another item in the batch failed")
+
+// ErrDuplicateBatchItem is returned when an all-or-nothing batch writes the same
+// labor line more than once.
+var ErrDuplicateBatchItem = errors.New("labor line appears more than once in the batch")
+
+// LaborLineUpdate is a partial update of a labor line.
+type LaborLineUpdate struct {
+	Input    models.UpdateLaborLineInput
+	RateCard *models.RateCard // The account's rate card, if the update resets a rate field to its default
+}
+
+// BatchWriteResult is the outcome of one item of a batch write.
+type BatchWriteResult struct {
+	LaborLine *models.LaborLine // The labor line as written; nil on failure
+	Err       error
+}
+
+// prepareFunc builds the write of item i of a batch and returns it with the
+// labor line as it was before, which is nil for a new labor line.
+type prepareFunc func(ctx context.Context, i int) (laborLineWrite, *models.LaborLine, error)
+
+// BatchCreateLaborLines creates several labor lines, recording each creation in
+// its history. Results are returned in input order.
+func (s *dynamoDBService) BatchCreateLaborLines(ctx context.Context, laborLines []*models.LaborLine, mode models.BatchMode, actor string) []BatchWriteResult {
+	return s.writeBatch(ctx, len(laborLines), mode, models.ActionCreate, actor, func(_ context.Context, i int) (laborLineWrite, *models.LaborLine, error) {
+		laborLines[i].AssignCreator(actor)
+		return createWrite(laborLines[i]), nil, nil
+	})
+}
+
+// BatchUpdateLaborLines updates several labor lines with the same checks as
+// UpdateLaborLine, recording each change in its history. Results are returned
+// in input order.
+func (s *dynamoDBService) BatchUpdateLaborLines(ctx context.Context, updates []LaborLineUpdate, mode models.BatchMode, actor string) []BatchWriteResult {
+	return s.writeBatch(ctx, len(updates), mode, models.ActionUpdate, actor, func(ctx context.Context, i int) (laborLineWrite, *models.LaborLine, error) {
+		return s.prepareUpdate(ctx, updates[i])
+	})
+}
+
+// BatchDeleteLaborLines soft deletes several labor lines, recording each
+// deletion in its history. Results are returned in input order.
+func (s *dynamoDBService) BatchDeleteLaborLines(ctx context.Context, inputs []models.DeleteLaborLineInput, mode models.BatchMode, actor string) []BatchWriteResult {
+	return s.writeBatch(ctx, len(inputs), mode, models.ActionDelete, actor, func(ctx context.Context, i int) (laborLineWrite, *models.LaborLine, error) {
+		return s.prepareDelete(ctx, inputs[i])
+	})
+}
+
+// writeBatch writes count prepared items. An atomic batch is written as a
+// single transaction; a best-effort batch writes each item in its own
+// transaction so one failure does not affect the others. Either way every
+// labor line write keeps its conditions and its history record, and writes
+// that lose a transaction conflict are prepared again and retried with backoff.
+func (s *dynamoDBService) writeBatch(ctx context.Context, count int, mode models.BatchMode, action models.ChangeAction, actor string, prepare prepareFunc) []BatchWriteResult {
+	if mode == models.BatchModeAtomic {
+		var results []BatchWriteResult
+		_ = withConflictRetry(ctx, func() error {
+			var err error
+			results, err = s.writeAtomicBatch(ctx, count, action, actor, prepare)
+			return err
+		})
+		return results
+	}
+
+	results := make([]BatchWriteResult, count)
+	for i := range results {
+		_ = withConflictRetry(ctx, func() error {
+			var err error
+			results[i], err = s.writeBatchItem(ctx, i, action, actor, prepare)
+			return err
+		})
+	}
+	return results
+}
+
+// writeBatchItem prepares and writes a single item of a best-effort batch. It
+// also returns the raw write error so the caller can decide whether to retry,
+// preparing the item again from the labor line as it now is.
+func (s *dynamoDBService) writeBatchItem(ctx context.Context, i int, action models.ChangeAction, actor string, prepare prepareFunc) (BatchWriteResult, error) {
+	write, before, err := prepare(ctx, i)
+	if err != nil {
+		return BatchWriteResult{Err: err}, nil
+	}
+
+	err = s.transactWithHistory(ctx, write, action, actor, before)
+	if err != nil {
+		if condErr := transactionConditionFailure(err, 0); condErr != nil {
+			return BatchWriteResult{Err: condErr}, err
+		}
+		return BatchWriteResult{Err: fmt.Errorf("writing labor line in DynamoDB: %w", err)}, err
+	}
+
+	return BatchWriteResult{LaborLine: write.laborLine}, nil
+}
+
+// writeAtomicBatch prepares every item of an all-or-nothing batch and writes
+// them in one transaction, together with one update of the summary of each
+// task they belong to. If any item cannot be prepared or written, the items
+// that were not at fault fail with ErrBatchAborted. It also returns the raw
+// write error so the caller can decide whether to retry.
+func (s *dynamoDBService) writeAtomicBatch(ctx context.Context, count int, action models.ChangeAction, actor string, prepare prepareFunc) ([]BatchWriteResult, error) {
+	results := make([]BatchWriteResult, count)
+	writes := make([]laborLineWrite, count)
+	transactItems := make([]types.TransactWriteItem, 0, 3*count)
+	offsets := make([]int, count)
+	events := make([]*models.LaborLineEvent, 0, count)
+	summaries := taskSummaryChanges{}
+	seen := make(map[string]bool, count)
+	failed := false
+
+	for i := range results {
+		write, before, err := prepare(ctx, i)
+		if err == nil {
+			key := write.laborLine.PK + "#" + write.laborLine.SK
+			if seen[key] {
+				err = ErrDuplicateBatchItem
+			}
+			seen[key] = true
+		}
+		var items []types.TransactWriteItem
+		var event *models.LaborLineEvent
+		if err == nil {
+			items, event, err = s.historyTransactItems(write, action, actor, before)
+		}
+		if err != nil {
+			results[i].Err = err
+			failed = true
+			continue
+		}
+
+		writes[i] = write
+		offsets[i] = len(transactItems)
+		transactItems = append(transactItems, items...)
+		if event != nil {
+			events = append(events, event)
+		}
+		summaries.add(before, write.laborLine)
+	}
+	if failed {
+		return abortBatch(results), nil
+	}
+	transactItems = append(transactItems, s.summaryTransactItems(summaries)...)
+
+	_, err := s.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+	if err != nil {
+		// Each item contributed its labor line write followed by its history
+		// record and outbox entry
+		for i := range results {
+			if condErr := transactionConditionFailure(err, offsets[i]); condErr != nil {
+				results[i].Err = condErr
+				failed = true
+			}
+		}
+		if failed {
+			return abortBatch(results), err
+		}
+
+		for i := range results {
+			results[i].Err = fmt.Errorf("writing labor line batch in DynamoDB: %w", err)
+		}
+		return results, err
+	}
+
+	s.publishEvents(ctx, events)
+	for i := range results {
+		results[i].LaborLine = writes[i].laborLine
+	}
+	return results, nil
+}
+
+// abortBatch marks every item of a failed all-or-nothing batch that has no
+// error of its own with ErrBatchAborted.
+func abortBatch(results []BatchWriteResult) []BatchWriteResult {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Err = ErrBatchAborted
+		}
+	}
+	return results
+}
+
+// withConflictRetry runs fn until it returns an error other than a transaction
+// conflict or maxBatchWriteAttempts is reached, backing off between attempts.
+func withConflictRetry(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !isTransactionConflict(err) || attempt == maxBatchWriteAttempts {
+			return err
+		}
+		if err := sleepBackoff(ctx, attempt); err != nil {
+			return err
+		}
+	}
+}
+
+// isTransactionConflict reports whether a write failed only because it raced
+// another transaction on the same item, in which case it is safe to retry.
+func isTransactionConflict(err error) bool {
+	var conflictErr *types.TransactionConflictException
+	if errors.As(err, &conflictErr) {
+		return true
+	}
+
+	var txErr *types.TransactionCanceledException
+	if !errors.As(err, &txErr) {
+		return false
+	}
+	for _, reason := range txErr.CancellationReasons {
+		if aws.ToString(reason.Code) == "TransactionConflict" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// maxBatchGetItems is the most keys DynamoDB accepts in one BatchGetItem call.
+const maxBatchGetItems = 100
+
+// BatchGetLaborLines retrieves several labor lines with as few BatchGetItem
+// calls as possible. The result has one entry per input, in input order, which
+// is nil if the labor line does not exist or has been soft deleted.
+func (s *dynamoDBService) BatchGetLaborLines(ctx context.Context, inputs []models.GetLaborLineInput) ([]*models.LaborLine, error) {
+	// Each distinct labor line is requested once, however many inputs name it
+	var keys []map[string]types.AttributeValue
+	requested := map[string]bool{}
+	for _, input := range inputs {
+		id := input.AccountID + "#" + input.TaskID + "#" + input.LaborLineID
+		if requested[id] {
+			continue
+		}
+		requested[id] = true
+		keys = append(keys, map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: input.AccountID},
+			"SK": &types.AttributeValueMemberS{Value: input.TaskID + "#" + input.LaborLineID},
+		})
+	}
+
+	found := map[string]*models.LaborLine{}
+	for start := 0; start < len(keys); start += maxBatchGetItems {
+		end := min(start+maxBatchGetItems, len(keys))
+
+		items, err := s.batchGet(ctx, keys[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("batch getting labor lines from DynamoDB: %w", err)
+		}
+		for _, item := range items {
+			laborLine, err := s.unmarshalLaborLine(item)
+			if err != nil {
+				return nil, err
+			}
+
+			// Don't return soft-deleted items
+			if !laborLine.IsDeleted() {
+				found[laborLine.PK+"#"+laborLine.SK] = laborLine
+			}
+		}
+	}
+
+	laborLines := make([]*models.LaborLine, len(inputs))
+	for i, input := range inputs {
+		laborLines[i] = found[input.AccountID+"#"+input.TaskID+"#"+input.LaborLineID]
+	}
+	return laborLines, nil
+}
+
+// batchGet reads the items with the given keys, retrying any that DynamoDB
+// leaves unprocessed.
+func (s *dynamoDBService) batchGet(ctx context.Context, keys []map[string]types.AttributeValue) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+
+	pending := map[string]types.KeysAndAttributes{s.tableName: {Keys: keys}}
+	for attempt := 0; len(pending[s.tableName].Keys) > 0; attempt++ {
+		if attempt == maxBatchWriteAttempts {
+			return nil, fmt.Errorf("%d keys still unprocessed after %d attempts", len(pending[s.tableName].Keys), attempt)
+		}
+		if attempt > 0 {
+			if err := sleepBackoff(ctx, attempt); err != nil {
+				return nil, err
+			}
+		}
+
+		result, err := s.client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: pending})
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, result.Responses[s.tableName]...)
+		pending = result.UnprocessedKeys
+	}
+
+	return items, nil
+}
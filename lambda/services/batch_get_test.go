@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+func TestDynamoDBService_BatchGetLaborLines(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID := uuid.New().String()
+	live := newBatchLaborLine(accountID)
+	deleted := newBatchLaborLine(accountID)
+	deleted.SoftDelete()
+	retried := newBatchLaborLine(accountID)
+	missing := newBatchLaborLine(accountID)
+
+	marshal := func(laborLine *models.LaborLine) map[string]types.AttributeValue {
+		item, err := attributevalue.MarshalMap(laborLine)
+		require.NoError(t, err)
+		return item
+	}
+	keyOf := func(laborLine *models.LaborLine) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: laborLine.PK},
+			"SK": &types.AttributeValueMemberS{Value: laborLine.SK},
+		}
+	}
+
+	// The first call leaves one key unprocessed, which is retried on its own
+	client.On("BatchGetItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.BatchGetItemInput) bool {
+		return len(input.RequestItems["test-table"].Keys) == 4
+	})).Return(&dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]types.AttributeValue{
+			"test-table": {marshal(live), marshal(deleted)},
+		},
+		UnprocessedKeys: map[string]types.KeysAndAttributes{
+			"test-table": {Keys: []map[string]types.AttributeValue{keyOf(retried)}},
+		},
+	}, nil).Once()
+	client.On("BatchGetItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.BatchGetItemInput) bool {
+		return len(input.RequestItems["test-table"].Keys) == 1
+	})).Return(&dynamodb.BatchGetItemOutput{
+		Responses: map[string][]map[string]types.AttributeValue{
+			"test-table": {marshal(retried)},
+		},
+	}, nil).Once()
+
+	inputFor := func(laborLine *models.LaborLine) models.GetLaborLineInput {
+		return models.GetLaborLineInput{AccountID: laborLine.AccountID, TaskID: laborLine.TaskID, LaborLineID: laborLine.LaborLineID}
+	}
+
+	// The same labor line requested twice is read once
+	laborLines, err := service.BatchGetLaborLines(context.Background(), []models.GetLaborLineInput{
+		inputFor(retried), inputFor(live), inputFor(deleted), inputFor(missing), inputFor(live),
+	})
+	require.NoError(t, err)
+	require.Len(t, laborLines, 5)
+	assert.Equal(t, retried.LaborLineID, laborLines[0].LaborLineID)
+	assert.Equal(t, live.LaborLineID, laborLines[1].LaborLineID)
+	assert.Nil(t, laborLines[2])
+	assert.Nil(t, laborLines[3])
+	assert.Equal(t, live.LaborLineID, laborLines[4].LaborLineID)
+
+	client.AssertExpectations(t)
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// newBatchLaborLine returns a labor line with fresh identifiers in the given account.
+func newBatchLaborLine(accountID string) *models.LaborLine {
+	return models.NewLaborLine(models.CreateLaborLineInput{
+		AccountID: accountID,
+		TaskID:    uuid.New().String(),
+	}, nil)
+}
+
+// onGetLaborLine mocks the read of a stored labor line by its sort key.
+func onGetLaborLine(client *MockDynamoDBClient, laborLine *models.LaborLine) {
+	sk := laborLine.TaskID + "#" + laborLine.LaborLineID
+	var item map[string]types.AttributeValue
+	if laborLine.Version > 0 {
+		item, _ = attributevalue.MarshalMap(laborLine)
+	}
+	client.On("GetItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.GetItemInput) bool {
+		return input.Key["SK"].(*types.AttributeValueMemberS).Value == sk
+	})).Return(&dynamodb.GetItemOutput{Item: item}, nil)
+}
+
+func TestDynamoDBService_BatchCreateLaborLines_Atomic(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID := uuid.New().String()
+	laborLines := []*models.LaborLine{newBatchLaborLine(accountID), newBatchLaborLine(accountID)}
+
+	// One transaction with a labor line write and a history record per item,
+	// followed by the summary of each item's task
+	client.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		return len(input.TransactItems) == 6 &&
+			input.TransactItems[4].Update != nil && input.TransactItems[5].Update != nil &&
+			input.TransactItems[0].Put.Item["laborLineId"].(*types.AttributeValueMemberS).Value == laborLines[0].LaborLineID &&
+			input.TransactItems[2].Put.Item["laborLineId"].(*types.AttributeValueMemberS).Value == laborLines[1].LaborLineID &&
+			input.TransactItems[3].Put.Item["recordType"].(*types.AttributeValueMemberS).Value == models.RecordTypeHistory
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	results := service.BatchCreateLaborLines(context.Background(), laborLines, models.BatchModeAtomic, "user-123")
+
+	require.Len(t, results, 2)
+	for i, result := range results {
+		require.NoError(t, result.Err)
+		assert.Equal(t, laborLines[i], result.LaborLine)
+	}
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_BatchUpdateLaborLines_AtomicConditionFailure(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID := uuid.New().String()
+	stored := []*models.LaborLine{newBatchLaborLine(accountID), newBatchLaborLine(accountID)}
+	for _, laborLine := range stored {
+		onGetLaborLine(client, laborLine)
+	}
+
+	// The second labor line was modified after it was read
+	concurrentItem, err := attributevalue.MarshalMap(&models.LaborLine{LaborLineID: stored[1].LaborLineID, Version: 5})
+	require.NoError(t, err)
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Return((*dynamodb.TransactWriteItemsOutput)(nil), &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("None")},
+				{Code: aws.String("None")},
+				{Code: aws.String("ConditionalCheckFailed"), Item: concurrentItem},
+				{Code: aws.String("None")},
+			},
+		}).Once()
+
+	updates := make([]LaborLineUpdate, len(stored))
+	for i, laborLine := range stored {
+		updates[i] = LaborLineUpdate{Input: models.UpdateLaborLineInput{
+			LaborLineID: laborLine.LaborLineID,
+			AccountID:   laborLine.AccountID,
+			TaskID:      laborLine.TaskID,
+			Description: models.NewNullable("updated"),
+		}}
+	}
+
+	results := service.BatchUpdateLaborLines(context.Background(), updates, models.BatchModeAtomic, "user-123")
+
+	require.Len(t, results, 2)
+	assert.ErrorIs(t, results[0].Err, ErrBatchAborted)
+	var conflictErr *ConflictError
+	require.ErrorAs(t, results[1].Err, &conflictErr)
+	assert.Equal(t, int64(5), conflictErr.CurrentVersion)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_BatchDeleteLaborLines_AtomicPrepareFailure(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID := uuid.New().String()
+	existing := newBatchLaborLine(accountID)
+	missing := newBatchLaborLine(accountID)
+	missing.Version = 0
+	onGetLaborLine(client, existing)
+	onGetLaborLine(client, missing)
+
+	results := service.BatchDeleteLaborLines(context.Background(), []models.DeleteLaborLineInput{
+		{AccountID: accountID, TaskID: existing.TaskID, LaborLineID: existing.LaborLineID},
+		{AccountID: accountID, TaskID: missing.TaskID, LaborLineID: missing.LaborLineID},
+	}, models.BatchModeAtomic, "user-123")
+
+	require.Len(t, results, 2)
+	assert.ErrorIs(t, results[0].Err, ErrBatchAborted)
+	assert.ErrorIs(t, results[1].Err, ErrLaborLineNotFound)
+	client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_BatchDeleteLaborLines_AtomicDuplicate(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newBatchLaborLine(uuid.New().String())
+	onGetLaborLine(client, laborLine)
+
+	input := models.DeleteLaborLineInput{AccountID: laborLine.AccountID, TaskID: laborLine.TaskID, LaborLineID: laborLine.LaborLineID}
+	results := service.BatchDeleteLaborLines(context.Background(), []models.DeleteLaborLineInput{input, input}, models.BatchModeAtomic, "user-123")
+
+	require.Len(t, results, 2)
+	assert.ErrorIs(t, results[0].Err, ErrBatchAborted)
+	assert.ErrorIs(t, results[1].Err, ErrDuplicateBatchItem)
+	client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_BatchDeleteLaborLines_BestEffort(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID := uuid.New().String()
+	existing := newBatchLaborLine(accountID)
+	missing := newBatchLaborLine(accountID)
+	missing.Version = 0
+	onGetLaborLine(client, existing)
+	onGetLaborLine(client, missing)
+
+	// Each item is written in its own transaction, which takes it off its task's summary
+	client.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		_, deleted := laborLinePut(input).Item["deletedAt"]
+		return len(input.TransactItems) == 3 && deleted
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	results := service.BatchDeleteLaborLines(context.Background(), []models.DeleteLaborLineInput{
+		{AccountID: accountID, TaskID: existing.TaskID, LaborLineID: existing.LaborLineID},
+		{AccountID: accountID, TaskID: missing.TaskID, LaborLineID: missing.LaborLineID},
+	}, models.BatchModeBestEffort, "user-123")
+
+	require.Len(t, results, 2)
+	require.NoError(t, results[0].Err)
+	assert.True(t, results[0].LaborLine.IsDeleted())
+	assert.ErrorIs(t, results[1].Err, ErrLaborLineNotFound)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_BatchCreateLaborLines_RetriesTransactionConflict(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	conflict := &types.TransactionCanceledException{
+		CancellationReasons: []types.CancellationReason{
+			{Code: aws.String("TransactionConflict")},
+			{Code: aws.String("None")},
+		},
+	}
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Return((*dynamodb.TransactWriteItemsOutput)(nil), conflict).Once()
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	laborLine := newBatchLaborLine(uuid.New().String())
+	results := service.BatchCreateLaborLines(context.Background(), []*models.LaborLine{laborLine}, models.BatchModeBestEffort, "user-123")
+
+	require.Len(t, results, 1)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, laborLine, results[0].LaborLine)
+	client.AssertNumberOfCalls(t, "TransactWriteItems", 2)
+}
+
+func TestDynamoDBService_BatchCreateLaborLines_GivesUpAfterRepeatedConflicts(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	original := batchWriteBackoff
+	batchWriteBackoff = 0
+	t.Cleanup(func() { batchWriteBackoff = original })
+
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Return((*dynamodb.TransactWriteItemsOutput)(nil), &types.TransactionConflictException{})
+
+	results := service.BatchCreateLaborLines(context.Background(), []*models.LaborLine{
+		newBatchLaborLine(uuid.New().String()),
+		newBatchLaborLine(uuid.New().String()),
+	}, models.BatchModeAtomic, "user-123")
+
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.Error(t, result.Err)
+		assert.Nil(t, result.LaborLine)
+	}
+	client.AssertNumberOfCalls(t, "TransactWriteItems", maxBatchWriteAttempts)
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// ListCustomFieldDefinitions retrieves all of an account's custom field
+// definitions, ordered by name.
+func (s *dynamoDBService) ListCustomFieldDefinitions(ctx context.Context, accountID string) ([]*models.CustomFieldDefinition, error) {
+	pk, skPrefix := models.CustomFieldKeyPrefix(accountID)
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(s.tableName),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :skPrefix)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":       &types.AttributeValueMemberS{Value: pk},
+			":skPrefix": &types.AttributeValueMemberS{Value: skPrefix},
+		},
+	}
+
+	definitions := []*models.CustomFieldDefinition{}
+	for {
+		result, err := s.client.Query(ctx, queryInput)
+		if err != nil {
+			return nil, fmt.Errorf("querying custom fields from DynamoDB: %w", err)
+		}
+
+		for _, item := range result.Items {
+			var definition models.CustomFieldDefinition
+			if err := attributevalue.UnmarshalMap(item, &definition); err != nil {
+				return nil, fmt.Errorf("unmarshaling custom field: %w", err)
+			}
+			definitions = append(definitions, &definition)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		queryInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	return definitions, nil
+}
+
+// PutCustomFieldDefinition creates or replaces one of an account's custom field
+// definitions. Existing labor lines keep the values they were written with.
+func (s *dynamoDBService) PutCustomFieldDefinition(ctx context.Context, definition *models.CustomFieldDefinition) error {
+	item, err := attributevalue.MarshalMap(definition)
+	if err != nil {
+		return fmt.Errorf("marshaling custom field: %w", err)
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("putting custom field in DynamoDB: %w", err)
+	}
+
+	return nil
+}
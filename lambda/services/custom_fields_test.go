@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+func TestDynamoDBService_ListCustomFieldDefinitions(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID := uuid.New().String()
+	bayItem, err := attributevalue.MarshalMap(models.DefineCustomFieldInput{
+		AccountID: accountID,
+		Name:      "bayNumber",
+		Type:      models.CustomFieldTypeInteger,
+		Required:  true,
+	}.ToCustomFieldDefinition())
+	require.NoError(t, err)
+	vmrsItem, err := attributevalue.MarshalMap(models.DefineCustomFieldInput{
+		AccountID: accountID,
+		Name:      "vmrsCode",
+		Type:      models.CustomFieldTypeString,
+		Pattern:   "^[0-9]{3}-[0-9]{3}-[0-9]{3}$",
+	}.ToCustomFieldDefinition())
+	require.NoError(t, err)
+	lastKey := map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: accountID}}
+
+	// Definitions are read from every page of the account's CUSTOMFIELD# items
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		prefix := input.ExpressionAttributeValues[":skPrefix"].(*types.AttributeValueMemberS)
+		return prefix.Value == "CUSTOMFIELD#" && input.ExclusiveStartKey == nil
+	})).Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{bayItem}, LastEvaluatedKey: lastKey}, nil).Once()
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return input.ExclusiveStartKey != nil
+	})).Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{vmrsItem}}, nil).Once()
+
+	definitions, err := service.ListCustomFieldDefinitions(context.Background(), accountID)
+	require.NoError(t, err)
+	require.Len(t, definitions, 2)
+	assert.Equal(t, "bayNumber", definitions[0].Name)
+	assert.True(t, definitions[0].Required)
+	assert.Equal(t, models.CustomFieldTypeString, definitions[1].Type)
+	assert.Equal(t, "^[0-9]{3}-[0-9]{3}-[0-9]{3}$", definitions[1].Pattern)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_PutCustomFieldDefinition(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	definition := models.DefineCustomFieldInput{
+		AccountID: uuid.New().String(),
+		Name:      "bayNumber",
+		Type:      models.CustomFieldTypeInteger,
+	}.ToCustomFieldDefinition()
+
+	client.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+		recordType, ok := input.Item["recordType"].(*types.AttributeValueMemberS)
+		sk := input.Item["SK"].(*types.AttributeValueMemberS)
+		return ok && recordType.Value == models.RecordTypeCustomField && sk.Value == "CUSTOMFIELD#bayNumber"
+	})).Return(&dynamodb.PutItemOutput{}, nil)
+
+	err := service.PutCustomFieldDefinition(context.Background(), definition)
+	assert.NoError(t, err)
+
+	client.AssertExpectations(t)
+}
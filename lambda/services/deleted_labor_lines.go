@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// maxBatchWriteItems is the most requests DynamoDB accepts in one BatchWriteItem call.
+const maxBatchWriteItems = 25
+
+// maxBatchWriteAttempts bounds the retries of batch reads and writes that
+// DynamoDB leaves unprocessed or cancels because of a transaction conflict.
+const maxBatchWriteAttempts = 5
+
+// batchWriteBackoff is the delay before the first retry of unprocessed items;
+// it doubles with each further attempt.
+var batchWriteBackoff = 50 * time.Millisecond
+
+// RestoreLaborLine undoes the soft delete of a labor line and records the
+// restore in its history. The write is conditioned on the labor line still
+// being deleted and at the version that was read.
+func (s *dynamoDBService) RestoreLaborLine(ctx context.Context, input models.RestoreLaborLineInput, actor string) (*models.LaborLine, error) {
+	existing, err := s.getLaborLineItem(ctx, models.GetLaborLineInput{
+		AccountID:   input.AccountID,
+		TaskID:      input.TaskID,
+		LaborLineID: input.LaborLineID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("checking existing labor line: %w", err)
+	}
+	if existing == nil {
+		return nil, ErrLaborLineNotFound
+	}
+	if !existing.IsDeleted() {
+		return nil, ErrLaborLineNotDeleted
+	}
+	if input.ExpectedVersion != nil && *input.ExpectedVersion != existing.Version {
+		return nil, &ConflictError{CurrentVersion: existing.Version}
+	}
+
+	before := *existing
+	existing.Restore()
+	existing.Version = before.Version + 1
+
+	versionExpr, versionValues := versionCondition(before.Version)
+	write := laborLineWrite{
+		laborLine: existing,
+		condition: "attribute_exists(PK) AND attribute_exists(deletedAt) AND " + versionExpr,
+		names:     map[string]string{"#version": "version"},
+		values:    versionValues,
+	}
+
+	err = s.writeWithHistory(ctx, write, models.ActionRestore, actor, &before)
+	if err != nil {
+		if reason, ok := transactionConditionReason(err, 0); ok {
+			return nil, restoreConditionFailure(reason.Item)
+		}
+		return nil, fmt.Errorf("restoring labor line in DynamoDB: %w", err)
+	}
+
+	return existing, nil
+}
+
+// restoreConditionFailure translates the stored item returned by a failed
+// restore condition into ErrLaborLineNotFound, ErrLaborLineNotDeleted if it was
+// restored concurrently, or a ConflictError carrying the stored version.
+func restoreConditionFailure(item map[string]types.AttributeValue) error {
+	if len(item) == 0 {
+		return ErrLaborLineNotFound
+	}
+
+	var current models.LaborLine
+	if err := attributevalue.UnmarshalMap(item, &current); err != nil {
+		return fmt.Errorf("unmarshaling conflicting labor line: %w", err)
+	}
+	if !current.IsDeleted() {
+		return ErrLaborLineNotDeleted
+	}
+
+	return &ConflictError{CurrentVersion: current.Version}
+}
+
+// PurgeLaborLine permanently removes a soft-deleted labor line together with
+// its time entries, their time slots and history. The related records are
+// removed first so that a failed purge can be retried.
+func (s *dynamoDBService) PurgeLaborLine(ctx context.Context, input models.PurgeLaborLineInput) error {
+	existing, err := s.getLaborLineItem(ctx, models.GetLaborLineInput{
+		AccountID:   input.AccountID,
+		TaskID:      input.TaskID,
+		LaborLineID: input.LaborLineID,
+	})
+	if err != nil {
+		return fmt.Errorf("checking existing labor line: %w", err)
+	}
+	if existing == nil {
+		return ErrLaborLineNotFound
+	}
+	if !existing.IsDeleted() {
+		return ErrLaborLineNotDeleted
+	}
+
+	if err := s.PurgeLaborLineRecords(ctx, input.AccountID, input.TaskID, input.LaborLineID); err != nil {
+		return err
+	}
+
+	_, err = s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: input.AccountID},
+			"SK": &types.AttributeValueMemberS{Value: input.TaskID + "#" + input.LaborLineID},
+		},
+		ConditionExpression:                 aws.String("attribute_exists(PK) AND attribute_exists(deletedAt)"),
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var condErr *types.ConditionalCheckFailedException
+	if errors.As(err, &condErr) {
+		// The labor line was removed or restored after it was read
+		if len(condErr.Item) == 0 {
+			return ErrLaborLineNotFound
+		}
+		return ErrLaborLineNotDeleted
+	}
+	if err != nil {
+		return fmt.Errorf("purging labor line from DynamoDB: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeLaborLineRecords removes the records kept for a labor line apart from
+// the labor line itself: its time entries and their time slots, its
+// assignments and its history. PurgeLaborLine removes them before the labor
+// line; a labor line that expires is removed by its TTL alone, so the stream
+// removes them afterwards. Removing them again is harmless.
+func (s *dynamoDBService) PurgeLaborLineRecords(ctx context.Context, accountID, taskID, laborLineID string) error {
+	keys, err := s.relatedRecordKeys(ctx, accountID, taskID+"#"+laborLineID+"#")
+	if err != nil {
+		return fmt.Errorf("querying labor line records from DynamoDB: %w", err)
+	}
+	historyKeys, err := s.relatedRecordKeys(ctx, models.HistoryPK(accountID), models.HistorySKPrefix(taskID, laborLineID))
+	if err != nil {
+		return fmt.Errorf("querying labor line history from DynamoDB: %w", err)
+	}
+	keys = append(keys, historyKeys...)
+
+	// The time entries' slots in their technicians' time partitions go too,
+	// freeing that time to be logged again
+	entries, err := s.ListTimeEntries(ctx, models.GetLaborLineInput{
+		AccountID:   accountID,
+		TaskID:      taskID,
+		LaborLineID: laborLineID,
+	})
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		slot := entry.Slot()
+		keys = append(keys, map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: slot.PK},
+			"SK": &types.AttributeValueMemberS{Value: slot.SK},
+		})
+	}
+	if err := s.batchDelete(ctx, keys); err != nil {
+		return fmt.Errorf("purging labor line records from DynamoDB: %w", err)
+	}
+	return nil
+}
+
+// relatedRecordKeys returns the keys of every item in the partition whose sort
+// key starts with the given prefix.
+func (s *dynamoDBService) relatedRecordKeys(ctx context.Context, pk, skPrefix string) ([]map[string]types.AttributeValue, error) {
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(s.tableName),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :skPrefix)"),
+		ProjectionExpression:   aws.String("PK, SK"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":       &types.AttributeValueMemberS{Value: pk},
+			":skPrefix": &types.AttributeValueMemberS{Value: skPrefix},
+		},
+	}
+
+	var keys []map[string]types.AttributeValue
+	for {
+		result, err := s.client.Query(ctx, queryInput)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, result.Items...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return keys, nil
+		}
+		queryInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// batchDelete deletes the items with the given keys, retrying any that
+// DynamoDB leaves unprocessed.
+func (s *dynamoDBService) batchDelete(ctx context.Context, keys []map[string]types.AttributeValue) error {
+	for start := 0; start < len(keys); start += maxBatchWriteItems {
+		end := min(start+maxBatchWriteItems, len(keys))
+
+		requests := make([]types.WriteRequest, 0, end-start)
+		for _, key := range keys[start:end] {
+			requests = append(requests, types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}})
+		}
+
+		pending := map[string][]types.WriteRequest{s.tableName: requests}
+		for attempt := 0; len(pending[s.tableName]) > 0; attempt++ {
+			if attempt == maxBatchWriteAttempts {
+				return fmt.Errorf("%d items still unprocessed after %d attempts", len(pending[s.tableName]), attempt)
+			}
+			if attempt > 0 {
+				if err := sleepBackoff(ctx, attempt); err != nil {
+					return err
+				}
+			}
+
+			result, err := s.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{RequestItems: pending})
+			if err != nil {
+				return err
+			}
+			pending = result.UnprocessedItems
+		}
+	}
+
+	return nil
+}
+
+// sleepBackoff waits before the given retry attempt, doubling the delay with
+// each attempt, and returns early with the context's error if it is cancelled.
+func sleepBackoff(ctx context.Context, attempt int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(batchWriteBackoff << (attempt - 1)):
+		return nil
+	}
+}
+
+// ListDeletedLaborLines retrieves a page of the soft-deleted labor lines for an
+// account, optionally filtered by task.
+func (s *dynamoDBService) ListDeletedLaborLines(ctx context.Context, input models.ListLaborLinesInput) (*models.LaborLineConnection, error) {
+	return s.listLaborLines(ctx, input, true)
+}
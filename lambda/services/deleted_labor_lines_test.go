@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// deletedLaborLineItem returns a stored soft-deleted labor line at the given version.
+func deletedLaborLineItem(t *testing.T, accountID, taskID, laborLineID string, version int64) map[string]types.AttributeValue {
+	t.Helper()
+
+	deletedAt := time.Now().Unix() - 60
+	expiresAt := deletedAt + 3600
+	item, err := attributevalue.MarshalMap(&models.LaborLine{
+		LaborLineID: laborLineID,
+		AccountID:   accountID,
+		TaskID:      taskID,
+		DeletedAt:   &deletedAt,
+		ExpiresAt:   &expiresAt,
+		Version:     version,
+		PK:          accountID,
+		SK:          taskID + "#" + laborLineID,
+	})
+	require.NoError(t, err)
+	return item
+}
+
+func TestDynamoDBService_DeleteLaborLine_SetsExpiry(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table", WithDeletedRetention(30*24*time.Hour))
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	existingItem, err := attributevalue.MarshalMap(&models.LaborLine{
+		LaborLineID: laborLineID,
+		AccountID:   accountID,
+		TaskID:      taskID,
+		Version:     1,
+	})
+	require.NoError(t, err)
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: existingItem}, nil)
+
+	var written models.LaborLine
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			input := args.Get(1).(*dynamodb.TransactWriteItemsInput)
+			require.NoError(t, attributevalue.UnmarshalMap(laborLinePut(input).Item, &written))
+		}).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	err = service.DeleteLaborLine(context.Background(), models.DeleteLaborLineInput{
+		AccountID:   accountID,
+		TaskID:      taskID,
+		LaborLineID: laborLineID,
+	}, "user-123")
+	require.NoError(t, err)
+
+	require.NotNil(t, written.DeletedAt)
+	require.NotNil(t, written.ExpiresAt)
+	assert.Equal(t, *written.DeletedAt+30*24*60*60, *written.ExpiresAt)
+}
+
+func TestDynamoDBService_RestoreLaborLine(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	client.On("GetItem", mock.Anything, mock.Anything).
+		Return(&dynamodb.GetItemOutput{Item: deletedLaborLineItem(t, accountID, taskID, laborLineID, 2)}, nil)
+	client.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		put := laborLinePut(input)
+		_, deleted := put.Item["deletedAt"]
+		_, expires := put.Item["expiresAt"]
+		action := historyPut(input).Item["action"].(*types.AttributeValueMemberS)
+		return !deleted && !expires &&
+			*put.ConditionExpression == "attribute_exists(PK) AND attribute_exists(deletedAt) AND #version = :expectedVersion" &&
+			action.Value == "RESTORE"
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	restored, err := service.RestoreLaborLine(context.Background(), models.RestoreLaborLineInput{
+		AccountID:   accountID,
+		TaskID:      taskID,
+		LaborLineID: laborLineID,
+	}, "user-123")
+	require.NoError(t, err)
+	assert.False(t, restored.IsDeleted())
+	assert.Equal(t, int64(3), restored.Version)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_RestoreLaborLine_Errors(t *testing.T) {
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	liveItem, err := attributevalue.MarshalMap(&models.LaborLine{
+		LaborLineID: laborLineID,
+		AccountID:   accountID,
+		TaskID:      taskID,
+		Version:     3,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name                    string
+		storedItem              map[string]types.AttributeValue
+		expectedVersion         *int64
+		writeErr                error
+		expectedErr             error
+		expectedVersionConflict int64
+	}{
+		{
+			name:        "Not found",
+			storedItem:  nil,
+			expectedErr: ErrLaborLineNotFound,
+		},
+		{
+			name:        "Not deleted",
+			storedItem:  liveItem,
+			expectedErr: ErrLaborLineNotDeleted,
+		},
+		{
+			name:                    "Stale expected version",
+			storedItem:              deletedLaborLineItem(t, accountID, taskID, laborLineID, 2),
+			expectedVersion:         aws.Int64(1),
+			expectedVersionConflict: 2,
+		},
+		{
+			name:       "Restored concurrently",
+			storedItem: deletedLaborLineItem(t, accountID, taskID, laborLineID, 2),
+			writeErr: &types.TransactionCanceledException{
+				CancellationReasons: []types.CancellationReason{
+					{Code: aws.String("ConditionalCheckFailed"), Item: liveItem},
+					{Code: aws.String("None")},
+				},
+			},
+			expectedErr: ErrLaborLineNotDeleted,
+		},
+		{
+			name:       "Deleted again concurrently",
+			storedItem: deletedLaborLineItem(t, accountID, taskID, laborLineID, 2),
+			writeErr: &types.TransactionCanceledException{
+				CancellationReasons: []types.CancellationReason{
+					{Code: aws.String("ConditionalCheckFailed"), Item: deletedLaborLineItem(t, accountID, taskID, laborLineID, 4)},
+					{Code: aws.String("None")},
+				},
+			},
+			expectedVersionConflict: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &MockDynamoDBClient{}
+			service := NewDynamoDBService(client, "test-table")
+
+			client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: tt.storedItem}, nil)
+			if tt.writeErr != nil {
+				client.On("TransactWriteItems", mock.Anything, mock.Anything).
+					Return((*dynamodb.TransactWriteItemsOutput)(nil), tt.writeErr)
+			}
+
+			_, err := service.RestoreLaborLine(context.Background(), models.RestoreLaborLineInput{
+				AccountID:       accountID,
+				TaskID:          taskID,
+				LaborLineID:     laborLineID,
+				ExpectedVersion: tt.expectedVersion,
+			}, "user-123")
+
+			if tt.expectedVersionConflict != 0 {
+				var conflictErr *ConflictError
+				require.ErrorAs(t, err, &conflictErr)
+				assert.Equal(t, tt.expectedVersionConflict, conflictErr.CurrentVersion)
+			} else {
+				assert.ErrorIs(t, err, tt.expectedErr)
+			}
+			if tt.writeErr == nil {
+				client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestDynamoDBService_PurgeLaborLine(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+	prefix := taskID + "#" + laborLineID + "#"
+
+	client.On("GetItem", mock.Anything, mock.Anything).
+		Return(&dynamodb.GetItemOutput{Item: deletedLaborLineItem(t, accountID, taskID, laborLineID, 2)}, nil)
+
+	// 30 related records across two query pages
+	keys := make([]map[string]types.AttributeValue, 30)
+	for i := range keys {
+		keys[i] = map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: accountID},
+			"SK": &types.AttributeValueMemberS{Value: prefix + "TIME#" + uuid.New().String()},
+		}
+	}
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		pk := input.ExpressionAttributeValues[":pk"].(*types.AttributeValueMemberS)
+		skPrefix := input.ExpressionAttributeValues[":skPrefix"].(*types.AttributeValueMemberS)
+		return pk.Value == accountID && skPrefix.Value == prefix && input.ExclusiveStartKey == nil
+	})).Return(&dynamodb.QueryOutput{Items: keys[:20], LastEvaluatedKey: keys[19]}, nil).Once()
+
+	// The history is kept in its own partition
+	historyKeys := make([]map[string]types.AttributeValue, 2)
+	for i := range historyKeys {
+		historyKeys[i] = map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: models.HistoryPK(accountID)},
+			"SK": &types.AttributeValueMemberS{Value: fmt.Sprintf("%s%020d", models.HistorySKPrefix(taskID, laborLineID), i+1)},
+		}
+	}
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		pk := input.ExpressionAttributeValues[":pk"].(*types.AttributeValueMemberS)
+		skPrefix := input.ExpressionAttributeValues[":skPrefix"].(*types.AttributeValueMemberS)
+		return pk.Value == models.HistoryPK(accountID) && skPrefix.Value == models.HistorySKPrefix(taskID, laborLineID)
+	})).Return(&dynamodb.QueryOutput{Items: historyKeys}, nil).Once()
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return input.ExclusiveStartKey != nil
+	})).Return(&dynamodb.QueryOutput{Items: keys[20:]}, nil).Once()
+
+	// The slot of the labor line's time entry is removed from the technician's time partition
+	entry := models.NewTimeEntry(accountID, taskID, laborLineID, "tech-1", 1000)
+	entry.Stop(4600, 0)
+	entryItem, err := attributevalue.MarshalMap(entry)
+	require.NoError(t, err)
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		skPrefix := input.ExpressionAttributeValues[":skPrefix"].(*types.AttributeValueMemberS)
+		return skPrefix.Value == models.TimeEntrySKPrefix(taskID, laborLineID)
+	})).Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{entryItem}}, nil).Once()
+
+	// The first batch is full; one item of it is left unprocessed and retried
+	unprocessed := map[string][]types.WriteRequest{
+		"test-table": {{DeleteRequest: &types.DeleteRequest{Key: keys[0]}}},
+	}
+	client.On("BatchWriteItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.BatchWriteItemInput) bool {
+		return len(input.RequestItems["test-table"]) == 25
+	})).Return(&dynamodb.BatchWriteItemOutput{UnprocessedItems: unprocessed}, nil).Once()
+	client.On("BatchWriteItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.BatchWriteItemInput) bool {
+		return len(input.RequestItems["test-table"]) == 1
+	})).Return(&dynamodb.BatchWriteItemOutput{}, nil).Once()
+	client.On("BatchWriteItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.BatchWriteItemInput) bool {
+		requests := input.RequestItems["test-table"]
+		if len(requests) != 8 {
+			return false
+		}
+		historyKey := requests[5].DeleteRequest.Key
+		slotKey := requests[7].DeleteRequest.Key
+		if historyKey["PK"].(*types.AttributeValueMemberS).Value != models.HistoryPK(accountID) {
+			return false
+		}
+		return slotKey["PK"].(*types.AttributeValueMemberS).Value == models.TechnicianTimePK(accountID, "tech-1") &&
+			slotKey["SK"].(*types.AttributeValueMemberS).Value == models.TimeSlotSK(1000, entry.TimeEntryID)
+	})).Return(&dynamodb.BatchWriteItemOutput{}, nil).Once()
+
+	client.On("DeleteItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.DeleteItemInput) bool {
+		sk := input.Key["SK"].(*types.AttributeValueMemberS)
+		return sk.Value == taskID+"#"+laborLineID && *input.ConditionExpression == "attribute_exists(PK) AND attribute_exists(deletedAt)"
+	})).Return(&dynamodb.DeleteItemOutput{}, nil)
+
+	err = service.PurgeLaborLine(context.Background(), models.PurgeLaborLineInput{
+		AccountID:   accountID,
+		TaskID:      taskID,
+		LaborLineID: laborLineID,
+	})
+	require.NoError(t, err)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_PurgeLaborLine_NotDeleted(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	liveItem, err := attributevalue.MarshalMap(&models.LaborLine{LaborLineID: uuid.New().String(), Version: 1})
+	require.NoError(t, err)
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: liveItem}, nil)
+
+	err = service.PurgeLaborLine(context.Background(), models.PurgeLaborLineInput{
+		AccountID:   uuid.New().String(),
+		TaskID:      uuid.New().String(),
+		LaborLineID: uuid.New().String(),
+	})
+	assert.ErrorIs(t, err, ErrLaborLineNotDeleted)
+
+	client.AssertNotCalled(t, "Query", mock.Anything, mock.Anything)
+	client.AssertNotCalled(t, "DeleteItem", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_PurgeLaborLineRecords_AlreadyRemoved(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	// The stream removes the records of a purged labor line again and finds none
+	client.On("Query", mock.Anything, mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Times(3)
+
+	err := service.PurgeLaborLineRecords(context.Background(), uuid.New().String(), uuid.New().String(), uuid.New().String())
+	require.NoError(t, err)
+
+	client.AssertExpectations(t)
+	client.AssertNotCalled(t, "BatchWriteItem", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_ListDeletedLaborLines(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return *input.FilterExpression == "attribute_exists(deletedAt) AND attribute_not_exists(recordType)"
+	})).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{
+			deletedLaborLineItem(t, accountID, taskID, uuid.New().String(), 2),
+		},
+	}, nil)
+
+	page, err := service.ListDeletedLaborLines(context.Background(), models.ListLaborLinesInput{
+		AccountID: accountID,
+		TaskID:    taskID,
+	})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.True(t, page.Items[0].IsDeleted())
+	assert.Nil(t, page.NextToken)
+
+	client.AssertExpectations(t)
+}
@@ -3,7 +3,10 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
@@ -15,11 +18,43 @@ import (
 
 // DynamoDBService defines the interface for DynamoDB operations.
 type DynamoDBService interface {
-	CreateLaborLine(ctx context.Context, laborLine *models.LaborLine) error
+	CreateLaborLine(ctx context.Context, laborLine *models.LaborLine, actor string) error
 	GetLaborLine(ctx context.Context, input models.GetLaborLineInput) (*models.LaborLine, error)
-	UpdateLaborLine(ctx context.Context, laborLine *models.LaborLine) error
-	DeleteLaborLine(ctx context.Context, input models.DeleteLaborLineInput) error
-	ListLaborLines(ctx context.Context, input models.ListLaborLinesInput) ([]*models.LaborLine, error)
+	BatchGetLaborLines(ctx context.Context, inputs []models.GetLaborLineInput) ([]*models.LaborLine, error)
+	UpdateLaborLine(ctx context.Context, update LaborLineUpdate, actor string) (*models.LaborLine, error)
+	DeleteLaborLine(ctx context.Context, input models.DeleteLaborLineInput, actor string) error
+	RestoreLaborLine(ctx context.Context, input models.RestoreLaborLineInput, actor string) (*models.LaborLine, error)
+	PurgeLaborLine(ctx context.Context, input models.PurgeLaborLineInput) error
+	PurgeLaborLineRecords(ctx context.Context, accountID, taskID, laborLineID string) error
+	BatchCreateLaborLines(ctx context.Context, laborLines []*models.LaborLine, mode models.BatchMode, actor string) []BatchWriteResult
+	BatchUpdateLaborLines(ctx context.Context, updates []LaborLineUpdate, mode models.BatchMode, actor string) []BatchWriteResult
+	BatchDeleteLaborLines(ctx context.Context, inputs []models.DeleteLaborLineInput, mode models.BatchMode, actor string) []BatchWriteResult
+	ListLaborLines(ctx context.Context, input models.ListLaborLinesInput) (*models.LaborLineConnection, error)
+	ListDeletedLaborLines(ctx context.Context, input models.ListLaborLinesInput) (*models.LaborLineConnection, error)
+	ListLaborLinesByTask(ctx context.Context, input models.ListLaborLinesByTaskInput, accountIDs []string) (*models.LaborLineConnection, error)
+	ForEachLaborLine(ctx context.Context, input models.ExportLaborLinesInput, fn func(*models.LaborLine) error) error
+	GetTaskLaborSummary(ctx context.Context, input models.GetTaskLaborSummaryInput) (*models.TaskLaborSummary, error)
+	ListTimeEntries(ctx context.Context, input models.GetLaborLineInput) ([]*models.TimeEntry, error)
+	SaveTimeEntry(ctx context.Context, entry *models.TimeEntry, actor string) (*models.LaborLine, error)
+	GetRateCard(ctx context.Context, accountID string) (*models.RateCard, error)
+	PutRateCard(ctx context.Context, rateCard *models.RateCard) error
+	ListCustomFieldDefinitions(ctx context.Context, accountID string) ([]*models.CustomFieldDefinition, error)
+	PutCustomFieldDefinition(ctx context.Context, definition *models.CustomFieldDefinition) error
+	CreateLaborOperation(ctx context.Context, operation *models.LaborOperation) error
+	GetLaborOperation(ctx context.Context, accountID, code string) (*models.LaborOperation, error)
+	UpdateLaborOperation(ctx context.Context, input models.UpdateLaborOperationInput) (*models.LaborOperation, error)
+	DeleteLaborOperation(ctx context.Context, input models.DeleteLaborOperationInput) error
+	SearchLaborOperations(ctx context.Context, input models.SearchLaborOperationsInput) (*models.LaborOperationConnection, error)
+	TransitionLaborLineStatus(ctx context.Context, input models.TransitionLaborLineStatusInput, actor string) (*models.LaborLine, error)
+	AddLaborLineNote(ctx context.Context, input models.AddLaborLineNoteInput, actor string) (*models.LaborLine, error)
+	EditLaborLineNote(ctx context.Context, input models.EditLaborLineNoteInput, actor string) (*models.LaborLine, error)
+	DeleteLaborLineNote(ctx context.Context, input models.DeleteLaborLineNoteInput, actor string) (*models.LaborLine, error)
+	AssignLaborLine(ctx context.Context, input models.LaborLineAssignmentInput, actor string) (*models.LaborLine, error)
+	UnassignLaborLine(ctx context.Context, input models.LaborLineAssignmentInput, actor string) (*models.LaborLine, error)
+	ListLaborLinesByTechnician(ctx context.Context, input models.ListLaborLinesByTechnicianInput) (*models.LaborLineConnection, error)
+	GetLaborLineHistory(ctx context.Context, input models.GetLaborLineHistoryInput) (*models.HistoryConnection, error)
+	RelayOutbox(ctx context.Context, minAge time.Duration) (int, error)
+	ApplyLaborLineChange(ctx context.Context, before, after *models.LaborLine) (*models.TaskLaborTotals, error)
 }
 
 // DynamoDBClient defines the interface for DynamoDB client operations we use.
@@ -27,38 +62,95 @@ type DynamoDBClient interface {
 	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
 	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
 	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
 	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
 }
 
+// laborLineFilter excludes soft-deleted labor lines and the other record types
+// (such as time entries) that share the labor lines' partition.
+const laborLineFilter = "attribute_not_exists(deletedAt) AND attribute_not_exists(recordType)"
+
+// deletedLaborLineFilter selects only soft-deleted labor lines.
+const deletedLaborLineFilter = "attribute_exists(deletedAt) AND attribute_not_exists(recordType)"
+
 // dynamoDBService implements DynamoDBService.
 type dynamoDBService struct {
-	client    DynamoDBClient
-	tableName string
+	client          DynamoDBClient
+	tableName       string
+	pageTokenSecret []byte
+	pageTokens      *pageTokenCodec
+
+	// deletedRetention is how long soft-deleted labor lines are kept before
+	// DynamoDB TTL removes them; zero keeps them indefinitely
+	deletedRetention time.Duration
+
+	// laborLineValidator, if set, validates every labor line read from the table
+	laborLineValidator LaborLineValidator
+
+	// eventPublisher, if set, is sent an event for every labor line write,
+	// which is also added to the outbox in the write's transaction
+	eventPublisher EventPublisher
 }
 
-// NewDynamoDBService creates a new DynamoDB service instance.
-func NewDynamoDBService(client DynamoDBClient, tableName string) DynamoDBService {
-	return &dynamoDBService{
-		client:    client,
-		tableName: tableName,
+// DynamoDBServiceOption configures optional behaviour of the DynamoDB service.
+type DynamoDBServiceOption func(*dynamoDBService)
+
+// WithPageTokenSecret sets the key used to sign pagination tokens. Without it a
+// random per-process key is used.
+func WithPageTokenSecret(secret []byte) DynamoDBServiceOption {
+	return func(s *dynamoDBService) {
+		s.pageTokenSecret = secret
 	}
 }
 
-// CreateLaborLine creates a new labor line in DynamoDB.
-func (s *dynamoDBService) CreateLaborLine(ctx context.Context, laborLine *models.LaborLine) error {
-	item, err := attributevalue.MarshalMap(laborLine)
-	if err != nil {
-		return fmt.Errorf("marshaling labor line: %w", err)
+// WithDeletedRetention sets how long soft-deleted labor lines are kept before
+// they expire through DynamoDB TTL. Without it they are kept until purged.
+func WithDeletedRetention(retention time.Duration) DynamoDBServiceOption {
+	return func(s *dynamoDBService) {
+		s.deletedRetention = retention
 	}
+}
 
-	input := &dynamodb.PutItemInput{
-		TableName:           aws.String(s.tableName),
-		Item:                item,
-		ConditionExpression: aws.String("attribute_not_exists(PK) AND attribute_not_exists(SK)"),
+// WithLaborLineValidator validates every labor line read from the table, so
+// that an item that does not conform to the schema it was written with fails
+// the read instead of being returned.
+func WithLaborLineValidator(validator LaborLineValidator) DynamoDBServiceOption {
+	return func(s *dynamoDBService) {
+		s.laborLineValidator = validator
 	}
+}
 
-	_, err = s.client.PutItem(ctx, input)
-	if err != nil {
+// WithEventPublisher publishes an event for every labor line write. Each event
+// is added to the outbox in the same transaction as the write, so an event
+// that fails to publish is kept for RelayOutbox instead of being lost.
+func WithEventPublisher(publisher EventPublisher) DynamoDBServiceOption {
+	return func(s *dynamoDBService) {
+		s.eventPublisher = publisher
+	}
+}
+
+// NewDynamoDBService creates a new DynamoDB service instance.
+func NewDynamoDBService(client DynamoDBClient, tableName string, opts ...DynamoDBServiceOption) DynamoDBService {
+	s := &dynamoDBService{
+		client:    client,
+		tableName: tableName,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.pageTokens = newPageTokenCodec(s.pageTokenSecret)
+
+	return s
+}
+
+// CreateLaborLine creates a new labor line in DynamoDB, owned by the given
+// actor, and records its creation in the labor line's history.
+func (s *dynamoDBService) CreateLaborLine(ctx context.Context, laborLine *models.LaborLine, actor string) error {
+	laborLine.AssignCreator(actor)
+	if err := s.writeWithHistory(ctx, createWrite(laborLine), models.ActionCreate, actor, nil); err != nil {
 		return fmt.Errorf("creating labor line in DynamoDB: %w", err)
 	}
 
@@ -67,6 +159,22 @@ func (s *dynamoDBService) CreateLaborLine(ctx context.Context, laborLine *models
 
 // GetLaborLine retrieves a labor line from DynamoDB.
 func (s *dynamoDBService) GetLaborLine(ctx context.Context, input models.GetLaborLineInput) (*models.LaborLine, error) {
+	laborLine, err := s.getLaborLineItem(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't return soft-deleted items
+	if laborLine == nil || laborLine.IsDeleted() {
+		return nil, nil
+	}
+
+	return laborLine, nil
+}
+
+// getLaborLineItem retrieves a labor line from DynamoDB whether or not it has
+// been soft deleted.
+func (s *dynamoDBService) getLaborLineItem(ctx context.Context, input models.GetLaborLineInput) (*models.LaborLine, error) {
 	pk := input.AccountID
 	sk := input.TaskID + "#" + input.LaborLineID
 
@@ -87,59 +195,112 @@ func (s *dynamoDBService) GetLaborLine(ctx context.Context, input models.GetLabo
 		return nil, nil // Not found
 	}
 
+	return s.unmarshalLaborLine(result.Item)
+}
+
+// unmarshalLaborLine decodes a labor line item read from the table and
+// validates it if a labor line validator is configured.
+func (s *dynamoDBService) unmarshalLaborLine(item map[string]types.AttributeValue) (*models.LaborLine, error) {
 	var laborLine models.LaborLine
-	err = attributevalue.UnmarshalMap(result.Item, &laborLine)
-	if err != nil {
+	if err := attributevalue.UnmarshalMap(item, &laborLine); err != nil {
 		return nil, fmt.Errorf("unmarshaling labor line: %w", err)
 	}
+	laborLine.MigrateNotes()
+	laborLine.MigrateParts()
 
-	// Don't return soft-deleted items
-	if laborLine.IsDeleted() {
-		return nil, nil
+	if s.laborLineValidator != nil {
+		if err := s.laborLineValidator.ValidateLaborLine(&laborLine); err != nil {
+			return nil, fmt.Errorf("labor line %s does not match its schema: %w", laborLine.LaborLineID, err)
+		}
 	}
 
 	return &laborLine, nil
 }
 
-// UpdateLaborLine updates an existing labor line in DynamoDB.
-func (s *dynamoDBService) UpdateLaborLine(ctx context.Context, laborLine *models.LaborLine) error {
-	// First, get the existing item to preserve createdAt and ensure it exists
+// UpdateLaborLine applies a partial update to an existing labor line in
+// DynamoDB, changing only the fields the input sets, and returns the labor
+// line as updated. If the input's ExpectedVersion is set the write is rejected
+// with a ConflictError unless the stored item is at that version; in all cases
+// the write fails if another writer modified the item after it was read.
+// Completed labor lines are read-only and rejected with ErrLaborLineReadOnly.
+// The change is recorded in the labor line's history.
+func (s *dynamoDBService) UpdateLaborLine(ctx context.Context, update LaborLineUpdate, actor string) (*models.LaborLine, error) {
+	write, existing, err := s.prepareUpdate(ctx, update)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.writeWithHistory(ctx, write, models.ActionUpdate, actor, existing)
+	if err != nil {
+		if condErr := transactionConditionFailure(err, 0); condErr != nil {
+			return nil, condErr
+		}
+		return nil, fmt.Errorf("updating labor line in DynamoDB: %w", err)
+	}
+
+	return write.laborLine, nil
+}
+
+// createWrite builds the write of a new labor line, which fails if the key is taken.
+func createWrite(laborLine *models.LaborLine) laborLineWrite {
+	return laborLineWrite{
+		laborLine: laborLine,
+		condition: "attribute_not_exists(PK) AND attribute_not_exists(SK)",
+	}
+}
+
+// prepareUpdate reads the stored labor line, checks that the update is allowed,
+// and builds the write applying the update to it. It returns the write and the
+// stored labor line.
+func (s *dynamoDBService) prepareUpdate(ctx context.Context, update LaborLineUpdate) (laborLineWrite, *models.LaborLine, error) {
+	input := update.Input
 	existing, err := s.GetLaborLine(ctx, models.GetLaborLineInput{
-		AccountID:   laborLine.AccountID,
-		TaskID:      laborLine.TaskID,
-		LaborLineID: laborLine.LaborLineID,
+		AccountID:   input.AccountID,
+		TaskID:      input.TaskID,
+		LaborLineID: input.LaborLineID,
 	})
 	if err != nil {
-		return fmt.Errorf("checking existing labor line: %w", err)
+		return laborLineWrite{}, nil, fmt.Errorf("checking existing labor line: %w", err)
 	}
 	if existing == nil {
-		return fmt.Errorf("labor line not found")
+		return laborLineWrite{}, nil, ErrLaborLineNotFound
+	}
+	if input.ExpectedVersion != nil && *input.ExpectedVersion != existing.Version {
+		return laborLineWrite{}, nil, &ConflictError{CurrentVersion: existing.Version}
+	}
+	if existing.IsReadOnly() {
+		return laborLineWrite{}, nil, ErrLaborLineReadOnly
 	}
 
-	// Preserve the original createdAt timestamp
-	laborLine.CreatedAt = existing.CreatedAt
-
-	item, err := attributevalue.MarshalMap(laborLine)
+	write, err := patchWrite(input, input.ApplyTo(existing, update.RateCard), existing.Version)
 	if err != nil {
-		return fmt.Errorf("marshaling labor line: %w", err)
+		return laborLineWrite{}, nil, fmt.Errorf("building labor line update: %w", err)
 	}
+	return write, existing, nil
+}
 
-	input := &dynamodb.PutItemInput{
-		TableName:           aws.String(s.tableName),
-		Item:                item,
-		ConditionExpression: aws.String("attribute_exists(PK) AND attribute_exists(SK) AND attribute_not_exists(deletedAt)"),
+// DeleteLaborLine soft deletes a labor line in DynamoDB and records the deletion
+// in the labor line's history.
+func (s *dynamoDBService) DeleteLaborLine(ctx context.Context, input models.DeleteLaborLineInput, actor string) error {
+	write, before, err := s.prepareDelete(ctx, input)
+	if err != nil {
+		return err
 	}
 
-	_, err = s.client.PutItem(ctx, input)
+	err = s.writeWithHistory(ctx, write, models.ActionDelete, actor, before)
 	if err != nil {
-		return fmt.Errorf("updating labor line in DynamoDB: %w", err)
+		if condErr := transactionConditionFailure(err, 0); condErr != nil {
+			return condErr
+		}
+		return fmt.Errorf("soft deleting labor line in DynamoDB: %w", err)
 	}
 
 	return nil
 }
 
-// DeleteLaborLine soft deletes a labor line in DynamoDB.
-func (s *dynamoDBService) DeleteLaborLine(ctx context.Context, input models.DeleteLaborLineInput) error {
+// prepareDelete reads the stored labor line and builds the versioned write that
+// soft deletes it. It returns the write and the labor line as it was before.
+func (s *dynamoDBService) prepareDelete(ctx context.Context, input models.DeleteLaborLineInput) (laborLineWrite, *models.LaborLine, error) {
 	// First get the existing item
 	existing, err := s.GetLaborLine(ctx, models.GetLaborLineInput{
 		AccountID:   input.AccountID,
@@ -147,36 +308,95 @@ func (s *dynamoDBService) DeleteLaborLine(ctx context.Context, input models.Dele
 		LaborLineID: input.LaborLineID,
 	})
 	if err != nil {
-		return fmt.Errorf("checking existing labor line: %w", err)
+		return laborLineWrite{}, nil, fmt.Errorf("checking existing labor line: %w", err)
 	}
 	if existing == nil {
-		return fmt.Errorf("labor line not found")
+		return laborLineWrite{}, nil, ErrLaborLineNotFound
+	}
+	if input.ExpectedVersion != nil && *input.ExpectedVersion != existing.Version {
+		return laborLineWrite{}, nil, &ConflictError{CurrentVersion: existing.Version}
 	}
 
 	// Soft delete the item
+	before := *existing
 	existing.SoftDelete()
+	existing.ExpireAfter(s.deletedRetention)
+	existing.Version = before.Version + 1
 
-	item, err := attributevalue.MarshalMap(existing)
-	if err != nil {
-		return fmt.Errorf("marshaling labor line for deletion: %w", err)
+	return versionedWrite(existing, before.Version), &before, nil
+}
+
+// versionCondition builds a condition that the stored item is still at the given
+// version. Items written before versioning was introduced have no version attribute.
+func versionCondition(version int64) (string, map[string]types.AttributeValue) {
+	if version == 0 {
+		return "attribute_not_exists(#version)", nil
 	}
 
-	updateInput := &dynamodb.PutItemInput{
-		TableName:           aws.String(s.tableName),
-		Item:                item,
-		ConditionExpression: aws.String("attribute_exists(PK) AND attribute_exists(SK)"),
+	return "#version = :expectedVersion", map[string]types.AttributeValue{
+		":expectedVersion": &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)},
 	}
+}
 
-	_, err = s.client.PutItem(ctx, updateInput)
-	if err != nil {
-		return fmt.Errorf("soft deleting labor line in DynamoDB: %w", err)
+// transactionConditionFailure translates a cancelled transaction whose labor line
+// write at the given index failed its condition into ErrLaborLineNotFound or a
+// ConflictError. It returns nil for any other error.
+func transactionConditionFailure(err error, index int) error {
+	reason, ok := transactionConditionReason(err, index)
+	if !ok {
+		return nil
 	}
 
-	return nil
+	return conditionFailure(&types.ConditionalCheckFailedException{Item: reason.Item})
 }
 
-// ListLaborLines retrieves labor lines for an account, optionally filtered by task.
-func (s *dynamoDBService) ListLaborLines(ctx context.Context, input models.ListLaborLinesInput) ([]*models.LaborLine, error) {
+// transactionConditionReason returns the cancellation reason for the write at
+// the given index if the transaction was cancelled because its condition failed.
+func transactionConditionReason(err error, index int) (types.CancellationReason, bool) {
+	var txErr *types.TransactionCanceledException
+	if !errors.As(err, &txErr) || index >= len(txErr.CancellationReasons) {
+		return types.CancellationReason{}, false
+	}
+
+	reason := txErr.CancellationReasons[index]
+	return reason, aws.ToString(reason.Code) == "ConditionalCheckFailed"
+}
+
+// conditionFailure translates a failed write condition into ErrLaborLineNotFound
+// or a ConflictError carrying the stored version. It returns nil for any other error.
+func conditionFailure(err error) error {
+	var condErr *types.ConditionalCheckFailedException
+	if !errors.As(err, &condErr) {
+		return nil
+	}
+	if len(condErr.Item) == 0 {
+		return ErrLaborLineNotFound
+	}
+
+	var current models.LaborLine
+	if err := attributevalue.UnmarshalMap(condErr.Item, &current); err != nil {
+		return fmt.Errorf("unmarshaling conflicting labor line: %w", err)
+	}
+	if current.IsDeleted() {
+		return ErrLaborLineNotFound
+	}
+
+	return &ConflictError{CurrentVersion: current.Version}
+}
+
+// ListLaborLines retrieves a page of labor lines for an account, optionally filtered by task.
+func (s *dynamoDBService) ListLaborLines(ctx context.Context, input models.ListLaborLinesInput) (*models.LaborLineConnection, error) {
+	return s.listLaborLines(ctx, input, false)
+}
+
+// listLaborLines retrieves a page of either the live or the soft-deleted labor
+// lines for an account, optionally filtered by task.
+func (s *dynamoDBService) listLaborLines(ctx context.Context, input models.ListLaborLinesInput, deleted bool) (*models.LaborLineConnection, error) {
+	filter := laborLineFilter
+	if deleted {
+		filter = deletedLaborLineFilter
+	}
+
 	var queryInput *dynamodb.QueryInput
 
 	if input.TaskID != "" {
@@ -184,6 +404,7 @@ func (s *dynamoDBService) ListLaborLines(ctx context.Context, input models.ListL
 		queryInput = &dynamodb.QueryInput{
 			TableName:              aws.String(s.tableName),
 			KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :skPrefix)"),
+			FilterExpression:       aws.String(filter),
 			ExpressionAttributeValues: map[string]types.AttributeValue{
 				":pk":       &types.AttributeValueMemberS{Value: input.AccountID},
 				":skPrefix": &types.AttributeValueMemberS{Value: input.TaskID + "#"},
@@ -194,30 +415,75 @@ func (s *dynamoDBService) ListLaborLines(ctx context.Context, input models.ListL
 		queryInput = &dynamodb.QueryInput{
 			TableName:              aws.String(s.tableName),
 			KeyConditionExpression: aws.String("PK = :pk"),
+			FilterExpression:       aws.String(filter),
 			ExpressionAttributeValues: map[string]types.AttributeValue{
 				":pk": &types.AttributeValueMemberS{Value: input.AccountID},
 			},
 		}
 	}
 
-	result, err := s.client.Query(ctx, queryInput)
+	items, nextToken, err := s.queryPage(ctx, queryInput, input.AccountID, input.Limit, input.NextToken)
 	if err != nil {
 		return nil, fmt.Errorf("querying labor lines from DynamoDB: %w", err)
 	}
 
-	var laborLines []*models.LaborLine
-	for _, item := range result.Items {
-		var laborLine models.LaborLine
-		err = attributevalue.UnmarshalMap(item, &laborLine)
+	laborLines := make([]*models.LaborLine, 0, len(items))
+	for _, item := range items {
+		laborLine, err := s.unmarshalLaborLine(item)
 		if err != nil {
-			return nil, fmt.Errorf("unmarshaling labor line: %w", err)
+			return nil, err
 		}
 
-		// Skip soft-deleted items
-		if !laborLine.IsDeleted() {
-			laborLines = append(laborLines, &laborLine)
+		// Skip items on the other side of the soft delete
+		if laborLine.IsDeleted() == deleted {
+			laborLines = append(laborLines, laborLine)
 		}
 	}
 
-	return laborLines, nil
+	return &models.LaborLineConnection{
+		Items:     laborLines,
+		NextToken: nextToken,
+	}, nil
+}
+
+// queryPage runs a query until it has collected up to limit items or the
+// results are exhausted, resuming from nextToken if one is given. Because a
+// filter expression may discard items after DynamoDB applies Limit, the query is
+// repeated with the remaining count so the returned token never skips items.
+// The scope binds the token to the partition being queried.
+func (s *dynamoDBService) queryPage(ctx context.Context, queryInput *dynamodb.QueryInput, scope string, limit int32, nextToken string) ([]map[string]types.AttributeValue, *string, error) {
+	startKey, err := s.pageTokens.decode(scope, nextToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pageSize := normalizePageSize(limit)
+	var items []map[string]types.AttributeValue
+
+	for {
+		queryInput.ExclusiveStartKey = startKey
+		queryInput.Limit = aws.Int32(pageSize - int32(len(items)))
+
+		result, err := s.client.Query(ctx, queryInput)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		items = append(items, result.Items...)
+		startKey = result.LastEvaluatedKey
+
+		if len(startKey) == 0 || int32(len(items)) >= pageSize {
+			break
+		}
+	}
+
+	token, err := s.pageTokens.encode(scope, startKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if token == "" {
+		return items, nil, nil
+	}
+
+	return items, &token, nil
 }
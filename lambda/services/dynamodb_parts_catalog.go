@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// PartsSource looks up the parts of the parts inventory and their stock on hand.
+type PartsSource interface {
+	// GetPart returns one of an account's parts, or ErrPartNotFound.
+	GetPart(ctx context.Context, accountID, partID string) (*models.CatalogPart, error)
+}
+
+// errPartReservationChanged is returned when a labor line's reservation was
+// changed by another write after it was read.
+var errPartReservationChanged = errors.New("part reservation changed")
+
+// DynamoDBPartsCatalog is a PartsCatalog that looks parts up in a PartsSource
+// and keeps their reservations in the labor lines table, so every function and
+// container that writes labor lines, or removes them, sees the same ones.
+//
+// Each labor line's reservation is an item of its own, and how much of each
+// part is reserved across the account's labor lines is a counter item. A
+// reservation and the counters it moves are written in one transaction, which
+// fails if a counter would exceed the part's stock on hand.
+type DynamoDBPartsCatalog struct {
+	client    DynamoDBClient
+	tableName string
+	parts     PartsSource
+}
+
+// NewDynamoDBPartsCatalog creates a parts catalog that keeps reservations in
+// the table and looks parts up in parts.
+func NewDynamoDBPartsCatalog(client DynamoDBClient, tableName string, parts PartsSource) *DynamoDBPartsCatalog {
+	return &DynamoDBPartsCatalog{
+		client:    client,
+		tableName: tableName,
+		parts:     parts,
+	}
+}
+
+// GetPart returns one of an account's parts, or ErrPartNotFound.
+func (c *DynamoDBPartsCatalog) GetPart(ctx context.Context, accountID, partID string) (*models.CatalogPart, error) {
+	return c.parts.GetPart(ctx, accountID, partID)
+}
+
+// ReserveParts sets the stock held for a labor line to the quantities of its
+// parts that have not been returned. A reservation changed by another write
+// while this one was made is read again.
+func (c *DynamoDBPartsCatalog) ReserveParts(ctx context.Context, accountID, laborLineID string, parts []models.PartLineItem) error {
+	return c.retry(ctx, func() error {
+		return c.reserveParts(ctx, accountID, laborLineID, models.StockHeld(parts))
+	})
+}
+
+// ReleaseParts releases all stock held for a labor line.
+func (c *DynamoDBPartsCatalog) ReleaseParts(ctx context.Context, accountID, laborLineID string) error {
+	return c.retry(ctx, func() error {
+		return c.reserveParts(ctx, accountID, laborLineID, nil)
+	})
+}
+
+// retry makes attempts at a reservation until it is not lost to another write.
+func (c *DynamoDBPartsCatalog) retry(ctx context.Context, attempt func() error) error {
+	for n := 1; ; n++ {
+		err := attempt()
+		if !errors.Is(err, errPartReservationChanged) {
+			return err
+		}
+		if n == maxBatchWriteAttempts {
+			return fmt.Errorf("reserving parts in DynamoDB: %w", err)
+		}
+		if err := sleepBackoff(ctx, n); err != nil {
+			return err
+		}
+	}
+}
+
+// reserveParts makes a single attempt at replacing a labor line's reservation
+// with the requested quantities, by part ID.
+func (c *DynamoDBPartsCatalog) reserveParts(ctx context.Context, accountID, laborLineID string, requested map[string]models.Decimal) error {
+	reservation, err := c.getReservation(ctx, accountID, laborLineID)
+	if err != nil {
+		return err
+	}
+	previous := map[string]models.Decimal{}
+	if reservation != nil {
+		previous = reservation.Quantities
+	}
+
+	// Every requested part must exist, and its stock on hand bounds the counter
+	partIDs := slices.Sorted(maps.Keys(requested))
+	onHand := make(map[string]models.Decimal, len(partIDs))
+	for _, partID := range partIDs {
+		part, err := c.parts.GetPart(ctx, accountID, partID)
+		if err != nil {
+			return err
+		}
+		onHand[partID] = part.QuantityOnHand
+	}
+
+	changes := map[string]models.Decimal{}
+	for partID, quantity := range requested {
+		changes[partID] = quantity.Sub(previous[partID])
+	}
+	for partID, quantity := range previous {
+		if _, ok := requested[partID]; !ok {
+			changes[partID] = models.Decimal{}.Sub(quantity)
+		}
+	}
+
+	var transactItems []types.TransactWriteItem
+	var changedPartIDs []string
+	for _, partID := range slices.Sorted(maps.Keys(changes)) {
+		if changes[partID].IsZero() {
+			continue
+		}
+		transactItems = append(transactItems, c.reservedTransactItem(accountID, partID, changes[partID], onHand[partID]))
+		changedPartIDs = append(changedPartIDs, partID)
+	}
+	if len(transactItems) == 0 && (reservation != nil) == (len(requested) > 0) {
+		return nil
+	}
+
+	item, err := c.reservationTransactItem(accountID, laborLineID, requested, reservation)
+	if err != nil {
+		return err
+	}
+	transactItems = append(transactItems, item)
+
+	_, err = c.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
+	if err == nil {
+		return nil
+	}
+	if _, failed := transactionConditionReason(err, len(transactItems)-1); failed {
+		return errPartReservationChanged
+	}
+	for i, partID := range changedPartIDs {
+		if _, failed := transactionConditionReason(err, i); failed {
+			return c.insufficientStock(ctx, accountID, partID, requested[partID], previous[partID], onHand[partID])
+		}
+	}
+	return fmt.Errorf("reserving parts in DynamoDB: %w", err)
+}
+
+// reservedTransactItem builds the transaction item that moves how much of a
+// part is reserved. An increase is conditioned on the part's stock on hand
+// covering the new amount reserved.
+func (c *DynamoDBPartsCatalog) reservedTransactItem(accountID, partID string, change, onHand models.Decimal) types.TransactWriteItem {
+	update := &types.Update{
+		TableName: aws.String(c.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: models.PartsPK(accountID)},
+			"SK": &types.AttributeValueMemberS{Value: models.PartReservedSK(partID)},
+		},
+		UpdateExpression: aws.String("SET accountId = :accountId, partId = :partId, recordType = :recordType ADD reserved :change"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":accountId":  &types.AttributeValueMemberS{Value: accountID},
+			":partId":     &types.AttributeValueMemberS{Value: partID},
+			":recordType": &types.AttributeValueMemberS{Value: models.RecordTypePartReserved},
+			":change":     &types.AttributeValueMemberN{Value: change.String()},
+		},
+	}
+
+	// Conditions cannot add, so the change is taken off the stock on hand instead
+	if !change.IsNegative() {
+		limit := onHand.Sub(change)
+		update.ConditionExpression = aws.String("reserved <= :limit")
+		if !limit.IsNegative() {
+			update.ConditionExpression = aws.String("attribute_not_exists(reserved) OR reserved <= :limit")
+		}
+		update.ExpressionAttributeValues[":limit"] = &types.AttributeValueMemberN{Value: limit.String()}
+	}
+
+	return types.TransactWriteItem{Update: update}
+}
+
+// reservationTransactItem builds the transaction item that replaces a labor
+// line's reservation, or deletes it if nothing is requested, conditioned on it
+// being as it was read.
+func (c *DynamoDBPartsCatalog) reservationTransactItem(accountID, laborLineID string, requested map[string]models.Decimal, read *models.PartReservation) (types.TransactWriteItem, error) {
+	key := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: models.PartsPK(accountID)},
+		"SK": &types.AttributeValueMemberS{Value: models.PartReservationSK(laborLineID)},
+	}
+	condition := "attribute_not_exists(PK)"
+	values := map[string]types.AttributeValue{}
+	var version int64
+	if read != nil {
+		version = read.Version
+		condition = "#version = :readVersion"
+		values[":readVersion"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)}
+	}
+
+	if len(requested) == 0 {
+		return types.TransactWriteItem{
+			Delete: &types.Delete{
+				TableName:                 aws.String(c.tableName),
+				Key:                       key,
+				ConditionExpression:       aws.String(condition),
+				ExpressionAttributeNames:  map[string]string{"#version": "version"},
+				ExpressionAttributeValues: values,
+			},
+		}, nil
+	}
+
+	item, err := attributevalue.MarshalMap(models.PartReservation{
+		AccountID:   accountID,
+		LaborLineID: laborLineID,
+		Quantities:  requested,
+		Version:     version + 1,
+		RecordType:  models.RecordTypePartReservation,
+		PK:          models.PartsPK(accountID),
+		SK:          models.PartReservationSK(laborLineID),
+	})
+	if err != nil {
+		return types.TransactWriteItem{}, fmt.Errorf("marshaling part reservation: %w", err)
+	}
+
+	put := &types.Put{
+		TableName:           aws.String(c.tableName),
+		Item:                item,
+		ConditionExpression: aws.String(condition),
+	}
+	if read != nil {
+		put.ExpressionAttributeNames = map[string]string{"#version": "version"}
+		put.ExpressionAttributeValues = values
+	}
+	return types.TransactWriteItem{Put: put}, nil
+}
+
+// getReservation reads a labor line's reservation, returning nil if it has none.
+func (c *DynamoDBPartsCatalog) getReservation(ctx context.Context, accountID, laborLineID string) (*models.PartReservation, error) {
+	result, err := c.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(c.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: models.PartsPK(accountID)},
+			"SK": &types.AttributeValueMemberS{Value: models.PartReservationSK(laborLineID)},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting part reservation from DynamoDB: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var reservation models.PartReservation
+	if err := attributevalue.UnmarshalMap(result.Item, &reservation); err != nil {
+		return nil, fmt.Errorf("unmarshaling part reservation: %w", err)
+	}
+	return &reservation, nil
+}
+
+// insufficientStock reports a part that cannot cover the quantity requested,
+// with how much of it is available to the labor line: the stock on hand less
+// what is reserved for other labor lines.
+func (c *DynamoDBPartsCatalog) insufficientStock(ctx context.Context, accountID, partID string, requested, previous, onHand models.Decimal) error {
+	result, err := c.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(c.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: models.PartsPK(accountID)},
+			"SK": &types.AttributeValueMemberS{Value: models.PartReservedSK(partID)},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return fmt.Errorf("getting reserved parts from DynamoDB: %w", err)
+	}
+
+	var counter struct {
+		Reserved models.Decimal `dynamodbav:"reserved"`
+	}
+	if err := attributevalue.UnmarshalMap(result.Item, &counter); err != nil {
+		return fmt.Errorf("unmarshaling reserved parts: %w", err)
+	}
+
+	return &InsufficientStockError{
+		PartID:    partID,
+		Requested: requested,
+		Available: onHand.Sub(counter.Reserved.Sub(previous)),
+	}
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// onGetPartsItem mocks the consistent read of one of the account's parts items.
+func onGetPartsItem(client *MockDynamoDBClient, sk string, item map[string]types.AttributeValue) {
+	client.On("GetItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.GetItemInput) bool {
+		return input.Key["PK"].(*types.AttributeValueMemberS).Value == "PARTS#acct-1" &&
+			input.Key["SK"].(*types.AttributeValueMemberS).Value == sk &&
+			aws.ToBool(input.ConsistentRead)
+	})).Return(&dynamodb.GetItemOutput{Item: item}, nil).Once()
+}
+
+// reservationItem returns a labor line's reservation item holding quantity of part-1.
+func reservationItem(quantity string, version string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"PK":          &types.AttributeValueMemberS{Value: "PARTS#acct-1"},
+		"SK":          &types.AttributeValueMemberS{Value: "RESERVATION#line-1"},
+		"accountId":   &types.AttributeValueMemberS{Value: "acct-1"},
+		"laborLineId": &types.AttributeValueMemberS{Value: "line-1"},
+		"quantities": &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"part-1": &types.AttributeValueMemberN{Value: quantity},
+		}},
+		"version": &types.AttributeValueMemberN{Value: version},
+	}
+}
+
+// reservedParts returns quantity of part-1, which the parts source holds 5 of.
+func reservedParts(quantity int64) []models.PartLineItem {
+	return []models.PartLineItem{{PartID: "part-1", Quantity: models.NewDecimalFromInt(quantity)}}
+}
+
+func newTestPartsCatalog(client *MockDynamoDBClient) *DynamoDBPartsCatalog {
+	parts := NewMemoryPartsCatalog(models.CatalogPart{AccountID: "acct-1", PartID: "part-1", QuantityOnHand: models.NewDecimalFromInt(5)})
+	return NewDynamoDBPartsCatalog(client, "test-table", parts)
+}
+
+func TestDynamoDBPartsCatalog_ReserveParts(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	catalog := newTestPartsCatalog(client)
+
+	onGetPartsItem(client, "RESERVATION#line-1", nil)
+	var written *dynamodb.TransactWriteItemsInput
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { written = args.Get(1).(*dynamodb.TransactWriteItemsInput) }).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	require.NoError(t, catalog.ReserveParts(context.Background(), "acct-1", "line-1", reservedParts(3)))
+
+	// The counter may only rise while at most 2 others are reserved
+	require.Len(t, written.TransactItems, 2)
+	counter := written.TransactItems[0].Update
+	assert.Equal(t, "RESERVED#part-1", counter.Key["SK"].(*types.AttributeValueMemberS).Value)
+	assert.Equal(t, "attribute_not_exists(reserved) OR reserved <= :limit", *counter.ConditionExpression)
+	assert.Equal(t, "3", counter.ExpressionAttributeValues[":change"].(*types.AttributeValueMemberN).Value)
+	assert.Equal(t, "2", counter.ExpressionAttributeValues[":limit"].(*types.AttributeValueMemberN).Value)
+
+	reservation := written.TransactItems[1].Put
+	assert.Equal(t, "RESERVATION#line-1", reservation.Item["SK"].(*types.AttributeValueMemberS).Value)
+	assert.Equal(t, "attribute_not_exists(PK)", *reservation.ConditionExpression)
+	assert.Equal(t, models.RecordTypePartReservation, reservation.Item["recordType"].(*types.AttributeValueMemberS).Value)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBPartsCatalog_ReserveParts_Unchanged(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	catalog := newTestPartsCatalog(client)
+
+	onGetPartsItem(client, "RESERVATION#line-1", reservationItem("3", "2"))
+
+	require.NoError(t, catalog.ReserveParts(context.Background(), "acct-1", "line-1", reservedParts(3)))
+
+	client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBPartsCatalog_ReserveParts_InsufficientStock(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	catalog := newTestPartsCatalog(client)
+
+	onGetPartsItem(client, "RESERVATION#line-1", reservationItem("1", "2"))
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Return((*dynamodb.TransactWriteItemsOutput)(nil), &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("ConditionalCheckFailed")},
+				{Code: aws.String("None")},
+			},
+		}).Once()
+	onGetPartsItem(client, "RESERVED#part-1", map[string]types.AttributeValue{
+		"reserved": &types.AttributeValueMemberN{Value: "4"},
+	})
+
+	// Other labor lines hold 3, leaving 2 of the 5 on hand for this one
+	err := catalog.ReserveParts(context.Background(), "acct-1", "line-1", reservedParts(3))
+
+	var stockErr *InsufficientStockError
+	require.ErrorAs(t, err, &stockErr)
+	assert.Equal(t, "part-1", stockErr.PartID)
+	assert.True(t, models.NewDecimalFromInt(3).Equal(stockErr.Requested))
+	assert.True(t, models.NewDecimalFromInt(2).Equal(stockErr.Available))
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBPartsCatalog_ReserveParts_UnknownPart(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	catalog := newTestPartsCatalog(client)
+
+	onGetPartsItem(client, "RESERVATION#line-1", nil)
+
+	err := catalog.ReserveParts(context.Background(), "acct-1", "line-1", []models.PartLineItem{{PartID: "part-2", Quantity: models.NewDecimalFromInt(1)}})
+
+	assert.ErrorIs(t, err, ErrPartNotFound)
+	client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBPartsCatalog_ReleaseParts(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	catalog := newTestPartsCatalog(client)
+
+	// The first release loses to another write of the reservation and reads it again
+	onGetPartsItem(client, "RESERVATION#line-1", reservationItem("3", "2"))
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Return((*dynamodb.TransactWriteItemsOutput)(nil), &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("None")},
+				{Code: aws.String("ConditionalCheckFailed")},
+			},
+		}).Once()
+	onGetPartsItem(client, "RESERVATION#line-1", reservationItem("4", "3"))
+	var written *dynamodb.TransactWriteItemsInput
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { written = args.Get(1).(*dynamodb.TransactWriteItemsInput) }).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	require.NoError(t, catalog.ReleaseParts(context.Background(), "acct-1", "line-1"))
+
+	require.Len(t, written.TransactItems, 2)
+	counter := written.TransactItems[0].Update
+	assert.Equal(t, "-4", counter.ExpressionAttributeValues[":change"].(*types.AttributeValueMemberN).Value)
+	assert.Nil(t, counter.ConditionExpression)
+
+	reservation := written.TransactItems[1].Delete
+	require.NotNil(t, reservation)
+	assert.Equal(t, "#version = :readVersion", *reservation.ConditionExpression)
+	assert.Equal(t, "3", reservation.ExpressionAttributeValues[":readVersion"].(*types.AttributeValueMemberN).Value)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBPartsCatalog_ReleaseParts_NothingReserved(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	catalog := newTestPartsCatalog(client)
+
+	onGetPartsItem(client, "RESERVATION#line-1", nil)
+
+	require.NoError(t, catalog.ReleaseParts(context.Background(), "acct-1", "line-1"))
+	client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+}
@@ -2,9 +2,11 @@ package services
 
 import (
 	"context"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -14,6 +16,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/schemas"
 )
 
 // MockDynamoDBClient is a mock implementation of DynamoDBClient.
@@ -36,11 +39,46 @@ func (m *MockDynamoDBClient) UpdateItem(ctx context.Context, params *dynamodb.Up
 	return args.Get(0).(*dynamodb.UpdateItemOutput), args.Error(1)
 }
 
+func (m *MockDynamoDBClient) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*dynamodb.DeleteItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*dynamodb.BatchGetItemOutput), args.Error(1)
+}
+
+func (m *MockDynamoDBClient) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*dynamodb.BatchWriteItemOutput), args.Error(1)
+}
+
 func (m *MockDynamoDBClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
 	args := m.Called(ctx, params)
 	return args.Get(0).(*dynamodb.QueryOutput), args.Error(1)
 }
 
+func (m *MockDynamoDBClient) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*dynamodb.TransactWriteItemsOutput), args.Error(1)
+}
+
+// laborLinePut returns the labor line write of a transaction built by writeWithHistory.
+func laborLinePut(input *dynamodb.TransactWriteItemsInput) *types.Put {
+	return input.TransactItems[0].Put
+}
+
+// laborLineUpdate returns the labor line update of a transaction built by writeWithHistory.
+func laborLineUpdate(input *dynamodb.TransactWriteItemsInput) *types.Update {
+	return input.TransactItems[0].Update
+}
+
+// historyPut returns the history record write of a transaction built by writeWithHistory.
+func historyPut(input *dynamodb.TransactWriteItemsInput) *types.Put {
+	return input.TransactItems[1].Put
+}
+
 func TestNewDynamoDBService(t *testing.T) {
 	client := &MockDynamoDBClient{}
 	tableName := "test-table"
@@ -64,12 +102,16 @@ func TestDynamoDBService_CreateLaborLine(t *testing.T) {
 		SK:          uuid.New().String() + "#" + uuid.New().String(),
 	}
 
-	client.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
-		return *input.TableName == tableName && input.ConditionExpression != nil
-	})).Return(&dynamodb.PutItemOutput{}, nil)
+	client.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		put := laborLinePut(input)
+		action := historyPut(input).Item["action"].(*types.AttributeValueMemberS)
+		return *put.TableName == tableName && put.ConditionExpression != nil && action.Value == "CREATE"
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
 
-	err := service.CreateLaborLine(context.Background(), laborLine)
+	err := service.CreateLaborLine(context.Background(), laborLine, "user-123")
 	assert.NoError(t, err)
+	assert.Equal(t, "user-123", laborLine.CreatedBy)
+	assert.Equal(t, schemas.CurrentVersion, laborLine.SchemaVersion)
 
 	client.AssertExpectations(t)
 }
@@ -115,6 +157,40 @@ func TestDynamoDBService_GetLaborLine(t *testing.T) {
 	client.AssertExpectations(t)
 }
 
+func TestDynamoDBService_GetLaborLine_FailsSchemaValidation(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	validationService, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+	service := NewDynamoDBService(client, "test-table", WithLaborLineValidator(validationService))
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	// Written with the current version but holding a status it does not allow
+	item, _ := attributevalue.MarshalMap(&models.LaborLine{
+		LaborLineID:   laborLineID,
+		AccountID:     accountID,
+		TaskID:        taskID,
+		Status:        "DONE",
+		SchemaVersion: schemas.CurrentVersion,
+		PK:            accountID,
+		SK:            taskID + "#" + laborLineID,
+	})
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	result, err := service.GetLaborLine(context.Background(), models.GetLaborLineInput{
+		AccountID:   accountID,
+		TaskID:      taskID,
+		LaborLineID: laborLineID,
+	})
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), laborLineID)
+}
+
 func TestDynamoDBService_GetLaborLine_NotFound(t *testing.T) {
 	client := &MockDynamoDBClient{}
 	tableName := "test-table"
@@ -181,42 +257,403 @@ func TestDynamoDBService_UpdateLaborLine(t *testing.T) {
 	accountID := uuid.New().String()
 	taskID := uuid.New().String()
 	laborLineID := uuid.New().String()
+	partID := uuid.New().String()
 
 	existingLaborLine := &models.LaborLine{
 		LaborLineID: laborLineID,
 		AccountID:   accountID,
 		TaskID:      taskID,
+		Description: "Brake inspection",
+		CreatedBy:   "user-1",
 		CreatedAt:   time.Now().Unix() - 100,
 		UpdatedAt:   time.Now().Unix() - 50,
 		PK:          accountID,
 		SK:          taskID + "#" + laborLineID,
 	}
 
-	updateLaborLine := &models.LaborLine{
+	existingItem, _ := attributevalue.MarshalMap(existingLaborLine)
+
+	// Mock GetItem call for checking existing item
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: existingItem}, nil)
+
+	// Mock the update and history transaction
+	client.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		update := laborLineUpdate(input)
+		return update != nil && *update.TableName == tableName && update.ConditionExpression != nil
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	updated, err := service.UpdateLaborLine(context.Background(), LaborLineUpdate{Input: models.UpdateLaborLineInput{
 		LaborLineID: laborLineID,
 		AccountID:   accountID,
 		TaskID:      taskID,
-		UpdatedAt:   time.Now().Unix(),
+		Parts:       models.NewNullable([]models.PartLineItem{{PartID: partID, Quantity: models.NewDecimalFromInt(4)}}),
+	}}, "user-123")
+	require.NoError(t, err)
+	// Fields left out of the input are unchanged and the labor line stays owned by its creator
+	require.Len(t, updated.Parts, 1)
+	assert.Equal(t, partID, updated.Parts[0].PartID)
+	assert.Equal(t, models.PartStatusRequested, updated.Parts[0].Status)
+	assert.Equal(t, "Brake inspection", updated.Description)
+	assert.Equal(t, "user-1", updated.CreatedBy)
+	assert.Equal(t, existingLaborLine.CreatedAt, updated.CreatedAt)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_UpdateLaborLine_UpdateExpression(t *testing.T) {
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	existingItem, _ := attributevalue.MarshalMap(&models.LaborLine{
+		LaborLineID:   laborLineID,
+		AccountID:     accountID,
+		TaskID:        taskID,
+		Parts:         []models.PartLineItem{{PartID: uuid.New().String(), Quantity: models.NewDecimalFromInt(1)}},
+		Description:   "Brake inspection",
+		Version:       2,
+		SchemaVersion: schemas.CurrentVersion,
+		PK:            accountID,
+		SK:            taskID + "#" + laborLineID,
+	})
+
+	tests := []struct {
+		name       string
+		input      models.UpdateLaborLineInput
+		expression string
+		values     []string
+	}{
+		{
+			name:       "Set",
+			input:      models.UpdateLaborLineInput{Description: models.NewNullable("Brake replacement")},
+			expression: "SET #description = :description, #laborCost = :laborCost, #updatedAt = :updatedAt, #version = :version",
+			values:     []string{":description"},
+		},
+		{
+			name:       "Null removes the attribute",
+			input:      models.UpdateLaborLineInput{Description: models.NullValue[string](), Parts: models.NullValue[[]models.PartLineItem]()},
+			expression: "SET #laborCost = :laborCost, #updatedAt = :updatedAt, #version = :version REMOVE #parts, #partId, #description",
+		},
+		{
+			name:       "Rate fields are written together",
+			input:      models.UpdateLaborLineInput{RatePerHour: models.NewNullable(models.MustParseDecimal("95"))},
+			expression: "SET #rateType = :rateType, #ratePerHour = :ratePerHour, #laborCost = :laborCost, #updatedAt = :updatedAt, #version = :version",
+			values:     []string{":rateType", ":ratePerHour"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &MockDynamoDBClient{}
+			service := NewDynamoDBService(client, "test-table")
+
+			client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: existingItem}, nil)
+			var written *dynamodb.TransactWriteItemsInput
+			client.On("TransactWriteItems", mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) { written = args.Get(1).(*dynamodb.TransactWriteItemsInput) }).
+				Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+			input := tt.input
+			input.AccountID, input.TaskID, input.LaborLineID = accountID, taskID, laborLineID
+			_, err := service.UpdateLaborLine(context.Background(), LaborLineUpdate{Input: input}, "user-123")
+			require.NoError(t, err)
+
+			require.NotNil(t, written)
+			update := laborLineUpdate(written)
+			require.NotNil(t, update)
+			assert.Nil(t, written.TransactItems[0].Put)
+			assert.Equal(t, tt.expression, *update.UpdateExpression)
+			assert.Equal(t, &types.AttributeValueMemberS{Value: accountID}, update.Key["PK"])
+			assert.Equal(t, &types.AttributeValueMemberS{Value: taskID + "#" + laborLineID}, update.Key["SK"])
+			assert.Equal(t, &types.AttributeValueMemberN{Value: "2"}, update.ExpressionAttributeValues[":expectedVersion"])
+			assert.Equal(t, &types.AttributeValueMemberN{Value: "3"}, update.ExpressionAttributeValues[":version"])
+			for _, value := range tt.values {
+				assert.Contains(t, update.ExpressionAttributeValues, value)
+			}
+		})
+	}
+}
+
+func TestDynamoDBService_UpdateLaborLine_MigratesLegacyItem(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+	partID := uuid.New().String()
+
+	// A version 2 item, with its parts stored as part IDs and a version 1 note
+	existingItem, err := attributevalue.MarshalMap(&models.LaborLine{
+		LaborLineID:   laborLineID,
+		AccountID:     accountID,
+		TaskID:        taskID,
+		LegacyPartIDs: []string{partID},
+		Description:   "Brake inspection",
+		Version:       2,
+		SchemaVersion: 2,
+		PK:            accountID,
+		SK:            taskID + "#" + laborLineID,
+	})
+	require.NoError(t, err)
+	existingItem["notes"] = &types.AttributeValueMemberL{Value: []types.AttributeValue{
+		&types.AttributeValueMemberS{Value: "Check pads"},
+	}}
+
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: existingItem}, nil)
+	var written *dynamodb.TransactWriteItemsInput
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { written = args.Get(1).(*dynamodb.TransactWriteItemsInput) }).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	updated, err := service.UpdateLaborLine(context.Background(), LaborLineUpdate{Input: models.UpdateLaborLineInput{
+		LaborLineID: laborLineID,
+		AccountID:   accountID,
+		TaskID:      taskID,
+		Description: models.NewNullable("Brake replacement"),
+	}}, "user-123")
+	require.NoError(t, err)
+	assert.Equal(t, schemas.CurrentVersion, updated.SchemaVersion)
+
+	// The item is written back in the current schema's form
+	update := laborLineUpdate(written)
+	require.NotNil(t, update)
+	assert.Equal(t, "SET #schemaVersion = :schemaVersion, #notes = :notes, #parts = :parts, #description = :description, "+
+		"#laborCost = :laborCost, #updatedAt = :updatedAt, #version = :version REMOVE #partId", *update.UpdateExpression)
+	assert.Equal(t, &types.AttributeValueMemberN{Value: strconv.Itoa(schemas.CurrentVersion)}, update.ExpressionAttributeValues[":schemaVersion"])
+
+	var parts []models.PartLineItem
+	require.NoError(t, attributevalue.Unmarshal(update.ExpressionAttributeValues[":parts"], &parts))
+	require.Len(t, parts, 1)
+	assert.Equal(t, partID, parts[0].PartID)
+
+	var notes []models.Note
+	require.NoError(t, attributevalue.Unmarshal(update.ExpressionAttributeValues[":notes"], &notes))
+	require.Len(t, notes, 1)
+	assert.NotEmpty(t, notes[0].NoteID)
+	assert.Equal(t, "Check pads", notes[0].Body)
+}
+
+func TestDynamoDBService_UpdateLaborLine_IncrementsVersion(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	existingItem, _ := attributevalue.MarshalMap(&models.LaborLine{
+		LaborLineID: laborLineID,
+		AccountID:   accountID,
+		TaskID:      taskID,
+		Version:     2,
 		PK:          accountID,
 		SK:          taskID + "#" + laborLineID,
-	}
+	})
 
-	existingItem, _ := attributevalue.MarshalMap(existingLaborLine)
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: existingItem}, nil)
+	client.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		update := laborLineUpdate(input)
+		expected, ok := update.ExpressionAttributeValues[":expectedVersion"].(*types.AttributeValueMemberN)
+		written, _ := update.ExpressionAttributeValues[":version"].(*types.AttributeValueMemberN)
+		return ok && expected.Value == "2" && written != nil && written.Value == "3"
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	expectedVersion := int64(2)
+	updated, err := service.UpdateLaborLine(context.Background(), LaborLineUpdate{Input: models.UpdateLaborLineInput{
+		LaborLineID:     laborLineID,
+		AccountID:       accountID,
+		TaskID:          taskID,
+		ExpectedVersion: &expectedVersion,
+	}}, "user-123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), updated.Version)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_UpdateLaborLine_RecalculatesCostFromStoredHours(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	existingItem, _ := attributevalue.MarshalMap(&models.LaborLine{
+		LaborLineID: laborLineID,
+		AccountID:   accountID,
+		TaskID:      taskID,
+		ActualHours: models.MustParseDecimal("2.25"),
+		RateType:    models.RateTypeHourly,
+		Version:     1,
+		PK:          accountID,
+		SK:          taskID + "#" + laborLineID,
+	})
 
-	// Mock GetItem call for checking existing item
 	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: existingItem}, nil)
+	client.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		cost, ok := laborLineUpdate(input).ExpressionAttributeValues[":laborCost"].(*types.AttributeValueMemberN)
+		return ok && cost.Value == "225"
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	// The update input carries no actual hours; they are owned by the time entries
+	updated, err := service.UpdateLaborLine(context.Background(), LaborLineUpdate{Input: models.UpdateLaborLineInput{
+		LaborLineID: laborLineID,
+		AccountID:   accountID,
+		TaskID:      taskID,
+		RatePerHour: models.NewNullable(models.MustParseDecimal("100")),
+	}}, "user-123")
+	require.NoError(t, err)
+	assert.Equal(t, "225.00", updated.LaborCost.StringFixed(2))
 
-	// Mock PutItem call for update
-	client.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
-		return *input.TableName == tableName && input.ConditionExpression != nil
-	})).Return(&dynamodb.PutItemOutput{}, nil)
+	client.AssertExpectations(t)
+}
 
-	err := service.UpdateLaborLine(context.Background(), updateLaborLine)
-	assert.NoError(t, err)
+func TestDynamoDBService_UpdateLaborLine_PreservesStatus(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	existingItem, _ := attributevalue.MarshalMap(&models.LaborLine{
+		LaborLineID: laborLineID,
+		AccountID:   accountID,
+		TaskID:      taskID,
+		Status:      models.StatusInProgress,
+		StatusHistory: []models.StatusTransition{
+			{From: models.StatusPending, To: models.StatusInProgress, ChangedBy: "user-123", ChangedAt: 1700000000},
+		},
+		Version: 2,
+		PK:      accountID,
+		SK:      taskID + "#" + laborLineID,
+	})
+
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: existingItem}, nil)
+	client.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		// Status only changes through transitions, so an update never writes it
+		_, ok := laborLineUpdate(input).ExpressionAttributeNames["#status"]
+		return !ok
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	updated, err := service.UpdateLaborLine(context.Background(), LaborLineUpdate{Input: models.UpdateLaborLineInput{
+		LaborLineID: laborLineID,
+		AccountID:   accountID,
+		TaskID:      taskID,
+		Description: models.NewNullable("Brake replacement"),
+	}}, "user-123")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusInProgress, updated.Status)
+	assert.Len(t, updated.StatusHistory, 1)
 
 	client.AssertExpectations(t)
 }
 
+func TestDynamoDBService_UpdateLaborLine_ReadOnly(t *testing.T) {
+	for _, status := range []models.LaborLineStatus{models.StatusCompleted, models.StatusApproved, models.StatusInvoiced} {
+		t.Run(string(status), func(t *testing.T) {
+			client := &MockDynamoDBClient{}
+			service := NewDynamoDBService(client, "test-table")
+
+			accountID := uuid.New().String()
+			taskID := uuid.New().String()
+			laborLineID := uuid.New().String()
+
+			existingItem, _ := attributevalue.MarshalMap(&models.LaborLine{
+				LaborLineID: laborLineID,
+				AccountID:   accountID,
+				TaskID:      taskID,
+				Status:      status,
+				Version:     5,
+				PK:          accountID,
+				SK:          taskID + "#" + laborLineID,
+			})
+			client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: existingItem}, nil)
+
+			_, err := service.UpdateLaborLine(context.Background(), LaborLineUpdate{Input: models.UpdateLaborLineInput{
+				LaborLineID: laborLineID,
+				AccountID:   accountID,
+				TaskID:      taskID,
+			}}, "user-123")
+			assert.ErrorIs(t, err, ErrLaborLineReadOnly)
+			client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestDynamoDBService_UpdateLaborLine_VersionConflict(t *testing.T) {
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	existingItem, _ := attributevalue.MarshalMap(&models.LaborLine{
+		LaborLineID: laborLineID,
+		AccountID:   accountID,
+		TaskID:      taskID,
+		Version:     5,
+		PK:          accountID,
+		SK:          taskID + "#" + laborLineID,
+	})
+	concurrentItem, _ := attributevalue.MarshalMap(&models.LaborLine{
+		LaborLineID: laborLineID,
+		AccountID:   accountID,
+		TaskID:      taskID,
+		Version:     6,
+		PK:          accountID,
+		SK:          taskID + "#" + laborLineID,
+	})
+
+	tests := []struct {
+		name            string
+		expectedVersion *int64
+		putErr          error
+		currentVersion  int64
+	}{
+		{
+			name:            "Stale expected version",
+			expectedVersion: func() *int64 { v := int64(4); return &v }(),
+			currentVersion:  5,
+		},
+		{
+			name: "Concurrent write between read and put",
+			putErr: &types.TransactionCanceledException{
+				CancellationReasons: []types.CancellationReason{
+					{Code: aws.String("ConditionalCheckFailed"), Item: concurrentItem},
+					{Code: aws.String("None")},
+				},
+			},
+			currentVersion: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &MockDynamoDBClient{}
+			service := NewDynamoDBService(client, "test-table")
+
+			client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: existingItem}, nil)
+			if tt.putErr != nil {
+				client.On("TransactWriteItems", mock.Anything, mock.Anything).Return((*dynamodb.TransactWriteItemsOutput)(nil), tt.putErr)
+			}
+
+			_, err := service.UpdateLaborLine(context.Background(), LaborLineUpdate{Input: models.UpdateLaborLineInput{
+				LaborLineID:     laborLineID,
+				AccountID:       accountID,
+				TaskID:          taskID,
+				ExpectedVersion: tt.expectedVersion,
+			}}, "user-123")
+
+			var conflictErr *ConflictError
+			require.ErrorAs(t, err, &conflictErr)
+			assert.Equal(t, tt.currentVersion, conflictErr.CurrentVersion)
+
+			client.AssertExpectations(t)
+		})
+	}
+}
+
 func TestDynamoDBService_DeleteLaborLine(t *testing.T) {
 	client := &MockDynamoDBClient{}
 	tableName := "test-table"
@@ -241,10 +678,13 @@ func TestDynamoDBService_DeleteLaborLine(t *testing.T) {
 	// Mock GetItem call for checking existing item
 	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: existingItem}, nil)
 
-	// Mock PutItem call for soft delete
-	client.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
-		return *input.TableName == tableName && input.ConditionExpression != nil
-	})).Return(&dynamodb.PutItemOutput{}, nil)
+	// Mock the soft delete and history transaction
+	client.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		put := laborLinePut(input)
+		_, deleted := put.Item["deletedAt"]
+		action := historyPut(input).Item["action"].(*types.AttributeValueMemberS)
+		return *put.TableName == tableName && put.ConditionExpression != nil && deleted && action.Value == "DELETE"
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
 
 	input := models.DeleteLaborLineInput{
 		AccountID:   accountID,
@@ -252,7 +692,7 @@ func TestDynamoDBService_DeleteLaborLine(t *testing.T) {
 		LaborLineID: laborLineID,
 	}
 
-	err := service.DeleteLaborLine(context.Background(), input)
+	err := service.DeleteLaborLine(context.Background(), input, "user-123")
 	assert.NoError(t, err)
 
 	client.AssertExpectations(t)
@@ -318,9 +758,112 @@ func TestDynamoDBService_ListLaborLines(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			result, err := service.ListLaborLines(context.Background(), tt.input)
 			require.NoError(t, err)
-			assert.Len(t, result, 2)
+			assert.Len(t, result.Items, 2)
+			assert.Nil(t, result.NextToken)
+		})
+	}
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_ListLaborLines_Pagination(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	tableName := "test-table"
+	service := NewDynamoDBService(client, tableName, WithPageTokenSecret([]byte("test-secret")))
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+
+	newItem := func() map[string]types.AttributeValue {
+		laborLineID := uuid.New().String()
+		item, _ := attributevalue.MarshalMap(&models.LaborLine{
+			LaborLineID: laborLineID,
+			AccountID:   accountID,
+			TaskID:      taskID,
+			PK:          accountID,
+			SK:          taskID + "#" + laborLineID,
 		})
+		return item
+	}
+
+	firstKey := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: accountID},
+		"SK": &types.AttributeValueMemberS{Value: taskID + "#first"},
 	}
+	secondKey := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: accountID},
+		"SK": &types.AttributeValueMemberS{Value: taskID + "#second"},
+	}
+
+	// The first query only returns one item (the rest were filtered), so the
+	// service must query again for the remaining item before issuing a token.
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return input.ExclusiveStartKey == nil && *input.Limit == 2
+	})).Return(&dynamodb.QueryOutput{
+		Items:            []map[string]types.AttributeValue{newItem()},
+		LastEvaluatedKey: firstKey,
+	}, nil).Once()
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return input.ExclusiveStartKey != nil && *input.Limit == 1
+	})).Return(&dynamodb.QueryOutput{
+		Items:            []map[string]types.AttributeValue{newItem()},
+		LastEvaluatedKey: secondKey,
+	}, nil).Once()
+
+	page, err := service.ListLaborLines(context.Background(), models.ListLaborLinesInput{
+		AccountID: accountID,
+		Limit:     2,
+	})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+	require.NotNil(t, page.NextToken)
+
+	// The token resumes from the last evaluated key
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		sk, ok := input.ExclusiveStartKey["SK"].(*types.AttributeValueMemberS)
+		return ok && sk.Value == taskID+"#second"
+	})).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{newItem()},
+	}, nil).Once()
+
+	page, err = service.ListLaborLines(context.Background(), models.ListLaborLinesInput{
+		AccountID: accountID,
+		Limit:     2,
+		NextToken: *page.NextToken,
+	})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Nil(t, page.NextToken)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_ListLaborLines_TokenFromOtherAccount(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table", WithPageTokenSecret([]byte("test-secret")))
+
+	otherAccountID := uuid.New().String()
+	item, _ := attributevalue.MarshalMap(&models.LaborLine{
+		LaborLineID: uuid.New().String(),
+		AccountID:   otherAccountID,
+	})
+	client.On("Query", mock.Anything, mock.Anything).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{item},
+		LastEvaluatedKey: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: otherAccountID},
+			"SK": &types.AttributeValueMemberS{Value: "task#line"},
+		},
+	}, nil).Once()
+
+	page, err := service.ListLaborLines(context.Background(), models.ListLaborLinesInput{AccountID: otherAccountID, Limit: 1})
+	require.NoError(t, err)
+	require.NotNil(t, page.NextToken)
+
+	_, err = service.ListLaborLines(context.Background(), models.ListLaborLinesInput{
+		AccountID: uuid.New().String(),
+		NextToken: *page.NextToken,
+	})
+	assert.ErrorIs(t, err, ErrInvalidNextToken)
 
 	client.AssertExpectations(t)
 }
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// ErrLaborLineNotFound is returned when a labor line does not exist or has been soft deleted.
+var ErrLaborLineNotFound = errors.New("labor line not found")
+
+// ErrTimeEntryOverlap is returned when a time entry overlaps another entry for the same technician.
+var ErrTimeEntryOverlap = errors.New("time entry overlaps an existing entry for the technician")
+
+// ErrTimerNotRunning is returned when stopping a timer that is not running.
+var ErrTimerNotRunning = errors.New("no running timer for technician")
+
+// ErrLaborLineReadOnly is returned when modifying a labor line whose work has been completed.
+var ErrLaborLineReadOnly = errors.New("labor line is read-only once completed")
+
+// ErrLaborLineNotDeleted is returned when restoring or purging a labor line that has not been soft deleted.
+var ErrLaborLineNotDeleted = errors.New("labor line is not deleted")
+
+// ErrNoteNotFound is returned when a labor line has no note with the given ID.
+var ErrNoteNotFound = errors.New("note not found")
+
+// ErrTechnicianAlreadyAssigned is returned when assigning a technician who is already assigned to the labor line.
+var ErrTechnicianAlreadyAssigned = errors.New("technician is already assigned to the labor line")
+
+// ErrTechnicianNotAssigned is returned when unassigning a technician who is not assigned to the labor line.
+var ErrTechnicianNotAssigned = errors.New("technician is not assigned to the labor line")
+
+// ErrTooManyTechnicians is returned when assigning a technician to a labor line that has as many as it can hold.
+var ErrTooManyTechnicians = fmt.Errorf("a labor line can be assigned to at most %d technicians", models.MaxAssignedTechnicians)
+
+// ErrPartNotFound is returned when a part is not in the parts catalog.
+var ErrPartNotFound = errors.New("part not found")
+
+// ErrLaborOperationNotFound is returned when an account's labor operation catalog has no operation with the given code.
+var ErrLaborOperationNotFound = errors.New("labor operation not found")
+
+// ErrLaborOperationExists is returned when adding an operation whose code is already in the account's catalog.
+var ErrLaborOperationExists = errors.New("labor operation already exists")
+
+// ErrNoEventPublisher is returned when relaying the outbox of a service that does not publish events.
+var ErrNoEventPublisher = errors.New("no event publisher configured")
+
+// ErrUnknownSchemaVersion is returned when a labor line was written with a schema version this function does not know.
+var ErrUnknownSchemaVersion = errors.New("unknown schema version")
+
+// ConflictError is returned when a write is rejected because the labor line was
+// modified since the caller last read it.
+type ConflictError struct {
+	CurrentVersion int64
+}
+
+// Error implements the error interface.
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("version conflict: labor line is at version %d", e.CurrentVersion)
+}
+
+// InsufficientStockError is returned when the parts inventory does not have
+// enough of a part to reserve the quantity a labor line needs.
+type InsufficientStockError struct {
+	PartID    string
+	Requested models.Decimal
+	Available models.Decimal
+}
+
+// Error implements the error interface.
+func (e *InsufficientStockError) Error() string {
+	return fmt.Sprintf("insufficient stock of part %s: %s requested, %s available", e.PartID, e.Requested, e.Available)
+}
+
+// InvalidStateTransitionError is returned when a labor line cannot move from its
+// current status to the requested one.
+type InvalidStateTransitionError struct {
+	From models.LaborLineStatus
+	To   models.LaborLineStatus
+}
+
+// Error implements the error interface.
+func (e *InvalidStateTransitionError) Error() string {
+	return fmt.Sprintf("cannot transition labor line from %s to %s", e.From, e.To)
+}
+
+// FieldError describes why one field of an input is invalid.
+type FieldError struct {
+	// Field is the path of the field, with array indexes as path elements (parts.0.partId)
+	Field string `json:"field"`
+	// Rule is the schema keyword the value breaks, such as required or format
+	Rule    string      `json:"rule"`
+	Message string      `json:"message"`
+	Value   interface{} `json:"value,omitempty"`
+}
+
+// ValidationError is returned when an input is invalid, listing every invalid
+// field so that clients can point each one out.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + ": " + field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// newValidationError returns a ValidationError for a single invalid field.
+func newValidationError(field, rule, message string, value interface{}) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Rule: rule, Message: message, Value: value}}}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// EventSource is the source of the labor line events put on EventBridge. Their
+// detail type is the event type, such as LaborLineCreated.
+const EventSource = "steverhoton.labor-lines"
+
+// maxPutEventsEntries is the most events EventBridge accepts in one PutEvents call.
+const maxPutEventsEntries = 10
+
+// EventBridgeClient defines the EventBridge client operations we use.
+type EventBridgeClient interface {
+	PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error)
+}
+
+// EventBridgePublisher is an EventPublisher and TaskTotalsPublisher that puts
+// events on an EventBridge event bus.
+type EventBridgePublisher struct {
+	client       EventBridgeClient
+	eventBusName string
+}
+
+// NewEventBridgePublisher creates a publisher that puts events on the named
+// event bus, or on the account's default bus if the name is empty.
+func NewEventBridgePublisher(client EventBridgeClient, eventBusName string) *EventBridgePublisher {
+	return &EventBridgePublisher{
+		client:       client,
+		eventBusName: eventBusName,
+	}
+}
+
+// Publish puts the events on the event bus. If EventBridge rejects any of
+// them, Publish returns an error even though the others were accepted, so
+// retrying publishes those again.
+func (p *EventBridgePublisher) Publish(ctx context.Context, events []*models.LaborLineEvent) error {
+	entries := make([]ebtypes.PutEventsRequestEntry, 0, len(events))
+	for _, event := range events {
+		detail, err := event.MarshalDetail()
+		if err != nil {
+			return err
+		}
+		entries = append(entries, p.requestEntry(event.Type, event.OccurredAt, detail))
+	}
+	return p.putEntries(ctx, entries)
+}
+
+// PublishTaskTotals puts a TaskLaborTotalsChanged event on the event bus for
+// each task's totals. Like Publish, it fails if any event is rejected.
+func (p *EventBridgePublisher) PublishTaskTotals(ctx context.Context, totals []*models.TaskLaborTotals) error {
+	entries := make([]ebtypes.PutEventsRequestEntry, 0, len(totals))
+	for _, taskTotals := range totals {
+		detail, err := json.Marshal(taskTotals)
+		if err != nil {
+			return fmt.Errorf("marshaling %s event: %w", models.EventTaskLaborTotalsChanged, err)
+		}
+		entries = append(entries, p.requestEntry(models.EventTaskLaborTotalsChanged, taskTotals.UpdatedAt, detail))
+	}
+	return p.putEntries(ctx, entries)
+}
+
+// putEntries puts the entries on the event bus, as many per call as EventBridge allows.
+func (p *EventBridgePublisher) putEntries(ctx context.Context, entries []ebtypes.PutEventsRequestEntry) error {
+	for start := 0; start < len(entries); start += maxPutEventsEntries {
+		end := min(start+maxPutEventsEntries, len(entries))
+
+		result, err := p.client.PutEvents(ctx, &eventbridge.PutEventsInput{Entries: entries[start:end]})
+		if err != nil {
+			return fmt.Errorf("putting events on EventBridge: %w", err)
+		}
+		if result.FailedEntryCount > 0 {
+			return fmt.Errorf("EventBridge rejected %d of %d events: %s", result.FailedEntryCount, end-start, firstEntryError(result.Entries))
+		}
+	}
+
+	return nil
+}
+
+// requestEntry builds the PutEvents entry of an event with the given type,
+// time (epoch seconds) and JSON detail.
+func (p *EventBridgePublisher) requestEntry(eventType models.EventType, occurredAt int64, detail []byte) ebtypes.PutEventsRequestEntry {
+	entry := ebtypes.PutEventsRequestEntry{
+		Source:     aws.String(EventSource),
+		DetailType: aws.String(string(eventType)),
+		Detail:     aws.String(string(detail)),
+		Time:       aws.Time(time.Unix(occurredAt, 0)),
+	}
+	if p.eventBusName != "" {
+		entry.EventBusName = aws.String(p.eventBusName)
+	}
+	return entry
+}
+
+// firstEntryError describes the first rejected entry of a PutEvents result.
+func firstEntryError(entries []ebtypes.PutEventsResultEntry) string {
+	for _, entry := range entries {
+		if entry.ErrorCode != nil {
+			return aws.ToString(entry.ErrorCode) + ": " + aws.ToString(entry.ErrorMessage)
+		}
+	}
+	return "unknown error"
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	ebtypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// MockEventBridgeClient is a mock implementation of EventBridgeClient.
+type MockEventBridgeClient struct {
+	mock.Mock
+}
+
+func (m *MockEventBridgeClient) PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*eventbridge.PutEventsOutput), args.Error(1)
+}
+
+// newTestEvents creates n events announcing changes to new labor lines.
+func newTestEvents(n int) []*models.LaborLineEvent {
+	events := make([]*models.LaborLineEvent, n)
+	for i := range events {
+		events[i] = models.NewLaborLineEvent(models.ActionCreate, "user-123", nil, newBatchLaborLine(uuid.New().String()))
+	}
+	return events
+}
+
+func TestEventBridgePublisher_Publish(t *testing.T) {
+	client := &MockEventBridgeClient{}
+	publisher := NewEventBridgePublisher(client, "labor-lines")
+
+	var calls []*eventbridge.PutEventsInput
+	client.On("PutEvents", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { calls = append(calls, args.Get(1).(*eventbridge.PutEventsInput)) }).
+		Return(&eventbridge.PutEventsOutput{}, nil)
+
+	events := newTestEvents(12)
+	require.NoError(t, publisher.Publish(context.Background(), events))
+
+	// EventBridge takes at most ten entries per call
+	require.Len(t, calls, 2)
+	assert.Len(t, calls[0].Entries, 10)
+	assert.Len(t, calls[1].Entries, 2)
+
+	entry := calls[0].Entries[0]
+	assert.Equal(t, "steverhoton.labor-lines", aws.ToString(entry.Source))
+	assert.Equal(t, "LaborLineCreated", aws.ToString(entry.DetailType))
+	assert.Equal(t, "labor-lines", aws.ToString(entry.EventBusName))
+
+	var detail models.LaborLineEvent
+	require.NoError(t, json.Unmarshal([]byte(aws.ToString(entry.Detail)), &detail))
+	assert.Equal(t, events[0].EventID, detail.EventID)
+	assert.Equal(t, events[0].LaborLineID, detail.After.LaborLineID)
+}
+
+func TestEventBridgePublisher_Publish_RejectedEntries(t *testing.T) {
+	client := &MockEventBridgeClient{}
+	publisher := NewEventBridgePublisher(client, "")
+
+	client.On("PutEvents", mock.Anything, mock.MatchedBy(func(input *eventbridge.PutEventsInput) bool {
+		// Without a bus name events go to the default bus
+		return input.Entries[0].EventBusName == nil
+	})).Return(&eventbridge.PutEventsOutput{
+		FailedEntryCount: 1,
+		Entries: []ebtypes.PutEventsResultEntry{
+			{EventId: aws.String("1")},
+			{ErrorCode: aws.String("ThrottlingException"), ErrorMessage: aws.String("Rate exceeded")},
+		},
+	}, nil)
+
+	err := publisher.Publish(context.Background(), newTestEvents(2))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ThrottlingException: Rate exceeded")
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// outboxIndexName is the sparse index over outbox entries that have not been
+// published, oldest first.
+const outboxIndexName = "OutboxIndex"
+
+// EventPublisher delivers labor line events to the services that react to
+// labor line changes. Events are delivered at least once, so consumers should
+// use the event ID to ignore repeats.
+type EventPublisher interface {
+	// Publish delivers the events, returning an error if any of them may not
+	// have been delivered.
+	Publish(ctx context.Context, events []*models.LaborLineEvent) error
+}
+
+// TaskTotalsPublisher announces changes to the totals of tasks' labor lines,
+// carrying each task's totals after the change.
+type TaskTotalsPublisher interface {
+	PublishTaskTotals(ctx context.Context, totals []*models.TaskLaborTotals) error
+}
+
+// MemoryEventPublisher is an EventPublisher and TaskTotalsPublisher that keeps
+// what it is given in memory, for tests and local runs.
+type MemoryEventPublisher struct {
+	mu         sync.Mutex
+	events     []*models.LaborLineEvent
+	taskTotals []*models.TaskLaborTotals
+
+	// Err, if set, is returned by Publish and PublishTaskTotals instead of
+	// keeping what they are given
+	Err error
+}
+
+// NewMemoryEventPublisher creates an in-memory event publisher with no events.
+func NewMemoryEventPublisher() *MemoryEventPublisher {
+	return &MemoryEventPublisher{}
+}
+
+// Publish keeps the events, or returns p.Err if it is set.
+func (p *MemoryEventPublisher) Publish(_ context.Context, events []*models.LaborLineEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Err != nil {
+		return p.Err
+	}
+	p.events = append(p.events, events...)
+	return nil
+}
+
+// Events returns the events published so far, in the order they were published.
+func (p *MemoryEventPublisher) Events() []*models.LaborLineEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]*models.LaborLineEvent(nil), p.events...)
+}
+
+// PublishTaskTotals keeps the task totals, or returns p.Err if it is set.
+func (p *MemoryEventPublisher) PublishTaskTotals(_ context.Context, totals []*models.TaskLaborTotals) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Err != nil {
+		return p.Err
+	}
+	p.taskTotals = append(p.taskTotals, totals...)
+	return nil
+}
+
+// TaskTotals returns the task totals published so far, in the order they were published.
+func (p *MemoryEventPublisher) TaskTotals() []*models.TaskLaborTotals {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]*models.TaskLaborTotals(nil), p.taskTotals...)
+}
+
+// outboxTransactItem builds the transaction item that adds the event
+// announcing a labor line write to the outbox.
+func (s *dynamoDBService) outboxTransactItem(event *models.LaborLineEvent) (types.TransactWriteItem, error) {
+	entry, err := models.NewOutboxEntry(event)
+	if err != nil {
+		return types.TransactWriteItem{}, err
+	}
+	item, err := attributevalue.MarshalMap(entry)
+	if err != nil {
+		return types.TransactWriteItem{}, fmt.Errorf("marshaling outbox entry: %w", err)
+	}
+
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:           aws.String(s.tableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(PK)"),
+		},
+	}, nil
+}
+
+// publishEvents publishes the events of a committed write and removes them
+// from the outbox. The write has already succeeded, so a failure is only
+// logged: the events stay in the outbox and RelayOutbox publishes them later.
+func (s *dynamoDBService) publishEvents(ctx context.Context, events []*models.LaborLineEvent) {
+	if s.eventPublisher == nil || len(events) == 0 {
+		return
+	}
+
+	if err := s.eventPublisher.Publish(ctx, events); err != nil {
+		log.Printf("Error publishing %d labor line events, leaving them in the outbox: %v", len(events), err)
+		return
+	}
+	if err := s.batchDelete(ctx, outboxKeys(events)); err != nil {
+		// The relay will publish them again, which consumers tolerate
+		log.Printf("Error removing published labor line events from the outbox: %v", err)
+	}
+}
+
+// RelayOutbox publishes the events left in the outbox by writes whose own
+// publish failed, and removes them once published. Entries written less than
+// minAge ago are skipped, as the write that added them may still be
+// publishing them. It returns the number of events published.
+//
+// Each page of entries is published together, and one at a time if that
+// fails, so an event that is rejected does not hold up the others. A failed
+// entry has its attempts counted and after models.MaxOutboxAttempts it is
+// marked failed and no longer relayed. An entry whose event cannot be decoded
+// is marked failed at once. If any event failed to publish, RelayOutbox
+// returns an error once the rest of the outbox has been relayed.
+func (s *dynamoDBService) RelayOutbox(ctx context.Context, minAge time.Duration) (int, error) {
+	if s.eventPublisher == nil {
+		return 0, ErrNoEventPublisher
+	}
+
+	cutoff := time.Now().Add(-minAge).Unix()
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(s.tableName),
+		IndexName:              aws.String(outboxIndexName),
+		KeyConditionExpression: aws.String("outboxStatus = :pending AND createdAt <= :cutoff"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pending": &types.AttributeValueMemberS{Value: models.OutboxPending},
+			":cutoff":  &types.AttributeValueMemberN{Value: strconv.FormatInt(cutoff, 10)},
+		},
+	}
+
+	published, failed := 0, 0
+	for {
+		result, err := s.client.Query(ctx, queryInput)
+		if err != nil {
+			return published, fmt.Errorf("querying outbox from DynamoDB: %w", err)
+		}
+
+		entries := make([]*models.OutboxEntry, 0, len(result.Items))
+		events := make([]*models.LaborLineEvent, 0, len(result.Items))
+		for _, item := range result.Items {
+			var entry models.OutboxEntry
+			event, err := decodeOutboxEntry(item, &entry)
+			if err != nil {
+				failed++
+				if err := s.recordOutboxFailure(ctx, &entry, err, true); err != nil {
+					return published, err
+				}
+				continue
+			}
+			entries = append(entries, &entry)
+			events = append(events, event)
+		}
+
+		n, failures, err := s.relayEvents(ctx, entries, events)
+		published += n
+		failed += failures
+		if err != nil {
+			return published, err
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		queryInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	if failed > 0 {
+		return published, fmt.Errorf("publishing outbox events: %d of %d failed", failed, published+failed)
+	}
+	return published, nil
+}
+
+// decodeOutboxEntry decodes an outbox item into entry and returns the event it
+// holds. The entry's keys are decoded even if the rest of it cannot be.
+func decodeOutboxEntry(item map[string]types.AttributeValue, entry *models.OutboxEntry) (*models.LaborLineEvent, error) {
+	if err := attributevalue.UnmarshalMap(item, entry); err != nil {
+		_ = attributevalue.UnmarshalMap(map[string]types.AttributeValue{"PK": item["PK"], "SK": item["SK"]}, entry)
+		return nil, fmt.Errorf("unmarshaling outbox entry: %w", err)
+	}
+	return entry.Event()
+}
+
+// relayEvents publishes the events of a page of outbox entries and removes
+// the entries of those published. If publishing them together fails, each is
+// published on its own and the failures are recorded against their entries.
+// It returns how many were published and how many failed.
+func (s *dynamoDBService) relayEvents(ctx context.Context, entries []*models.OutboxEntry, events []*models.LaborLineEvent) (int, int, error) {
+	if len(events) == 0 {
+		return 0, 0, nil
+	}
+
+	sent := events
+	if err := s.eventPublisher.Publish(ctx, events); err != nil {
+		sent = make([]*models.LaborLineEvent, 0, len(events))
+		for i, event := range events {
+			if err := s.eventPublisher.Publish(ctx, []*models.LaborLineEvent{event}); err != nil {
+				if err := s.recordOutboxFailure(ctx, entries[i], err, false); err != nil {
+					return 0, 0, err
+				}
+				continue
+			}
+			sent = append(sent, event)
+		}
+	}
+
+	if err := s.batchDelete(ctx, outboxKeys(sent)); err != nil {
+		return 0, 0, fmt.Errorf("removing published events from the outbox: %w", err)
+	}
+	return len(sent), len(events) - len(sent), nil
+}
+
+// recordOutboxFailure counts a failed try at publishing an outbox entry's
+// event, marking the entry failed once it has had models.MaxOutboxAttempts, or
+// at once if final is set. An entry that has since been removed is left alone.
+func (s *dynamoDBService) recordOutboxFailure(ctx context.Context, entry *models.OutboxEntry, cause error, final bool) error {
+	log.Printf("Error relaying outbox entry %s (attempt %d): %v", entry.SK, entry.Attempts+1, cause)
+
+	status := models.OutboxPending
+	if final || entry.Attempts+1 >= models.MaxOutboxAttempts {
+		status = models.OutboxFailed
+	}
+
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: entry.PK},
+			"SK": &types.AttributeValueMemberS{Value: entry.SK},
+		},
+		UpdateExpression:    aws.String("SET outboxStatus = :status, lastError = :lastError ADD attempts :one"),
+		ConditionExpression: aws.String("attribute_exists(PK)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":status":    &types.AttributeValueMemberS{Value: status},
+			":lastError": &types.AttributeValueMemberS{Value: cause.Error()},
+			":one":       &types.AttributeValueMemberN{Value: "1"},
+		},
+	})
+	var condErr *types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &condErr) {
+		return fmt.Errorf("recording outbox failure in DynamoDB: %w", err)
+	}
+	if status == models.OutboxFailed {
+		log.Printf("Outbox entry %s marked %s", entry.SK, models.OutboxFailed)
+	}
+	return nil
+}
+
+// outboxKeys returns the keys of the outbox entries holding the events.
+func outboxKeys(events []*models.LaborLineEvent) []map[string]types.AttributeValue {
+	keys := make([]map[string]types.AttributeValue, 0, len(events))
+	for _, event := range events {
+		pk, sk := models.OutboxKey(event.AccountID, event.EventID)
+		keys = append(keys, map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		})
+	}
+	return keys
+}
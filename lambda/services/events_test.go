@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// outboxPut returns the outbox entry put by a single labor line write, which
+// follows its history record and may be followed by its task's summary.
+func outboxPut(t *testing.T, input *dynamodb.TransactWriteItemsInput) *models.OutboxEntry {
+	t.Helper()
+
+	require.GreaterOrEqual(t, len(input.TransactItems), 3)
+	require.NotNil(t, input.TransactItems[2].Put)
+	var entry models.OutboxEntry
+	require.NoError(t, attributevalue.UnmarshalMap(input.TransactItems[2].Put.Item, &entry))
+	return &entry
+}
+
+func TestDynamoDBService_CreateLaborLine_PublishesEvent(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	publisher := NewMemoryEventPublisher()
+	service := NewDynamoDBService(client, "test-table", WithEventPublisher(publisher))
+
+	laborLine := newBatchLaborLine(uuid.New().String())
+
+	var written *dynamodb.TransactWriteItemsInput
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { written = args.Get(1).(*dynamodb.TransactWriteItemsInput) }).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+	var deleted *dynamodb.BatchWriteItemInput
+	client.On("BatchWriteItem", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { deleted = args.Get(1).(*dynamodb.BatchWriteItemInput) }).
+		Return(&dynamodb.BatchWriteItemOutput{}, nil)
+
+	require.NoError(t, service.CreateLaborLine(context.Background(), laborLine, "user-123"))
+
+	// The event is added to the outbox in the labor line's transaction
+	entry := outboxPut(t, written)
+	assert.Equal(t, models.RecordTypeOutbox, entry.RecordType)
+	assert.Equal(t, models.OutboxPending, entry.OutboxStatus)
+	assert.Equal(t, laborLine.AccountID, entry.PK)
+	assert.Equal(t, "attribute_not_exists(PK)", *written.TransactItems[2].Put.ConditionExpression)
+
+	// Then published and removed from the outbox
+	events := publisher.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, entry.EventID, events[0].EventID)
+	assert.Equal(t, models.EventLaborLineCreated, events[0].Type)
+	assert.Equal(t, "user-123", events[0].Actor)
+	assert.Nil(t, events[0].Before)
+	assert.Equal(t, laborLine.LaborLineID, events[0].After.LaborLineID)
+
+	require.NotNil(t, deleted)
+	requests := deleted.RequestItems["test-table"]
+	require.Len(t, requests, 1)
+	assert.Equal(t, entry.SK, requests[0].DeleteRequest.Key["SK"].(*types.AttributeValueMemberS).Value)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_CreateLaborLine_PublishFailureKeepsOutboxEntry(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	publisher := NewMemoryEventPublisher()
+	publisher.Err = errors.New("event bus unavailable")
+	service := NewDynamoDBService(client, "test-table", WithEventPublisher(publisher))
+
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	// The write succeeded, so the failed publish is not the caller's problem
+	err := service.CreateLaborLine(context.Background(), newBatchLaborLine(uuid.New().String()), "user-123")
+	require.NoError(t, err)
+
+	assert.Empty(t, publisher.Events())
+	client.AssertNotCalled(t, "BatchWriteItem", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_CreateLaborLine_WriteFailureDoesNotPublish(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	publisher := NewMemoryEventPublisher()
+	service := NewDynamoDBService(client, "test-table", WithEventPublisher(publisher))
+
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Return((*dynamodb.TransactWriteItemsOutput)(nil), errors.New("throttled"))
+
+	err := service.CreateLaborLine(context.Background(), newBatchLaborLine(uuid.New().String()), "user-123")
+	require.Error(t, err)
+
+	assert.Empty(t, publisher.Events())
+}
+
+func TestDynamoDBService_TransitionLaborLineStatus_PublishesStatusChange(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	publisher := NewMemoryEventPublisher()
+	service := NewDynamoDBService(client, "test-table", WithEventPublisher(publisher))
+
+	laborLine := newBatchLaborLine(uuid.New().String())
+	onGetLaborLine(client, laborLine)
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+	client.On("BatchWriteItem", mock.Anything, mock.Anything).Return(&dynamodb.BatchWriteItemOutput{}, nil)
+
+	_, err := service.TransitionLaborLineStatus(context.Background(), transitionInput(laborLine, models.StatusInProgress), "user-123")
+	require.NoError(t, err)
+
+	events := publisher.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, models.EventLaborLineStatusChanged, events[0].Type)
+	assert.Equal(t, models.StatusPending, events[0].Before.Status)
+	assert.Equal(t, models.StatusInProgress, events[0].After.Status)
+	assert.Equal(t, laborLine.Version+1, events[0].Version)
+}
+
+func TestDynamoDBService_BatchUpdateLaborLines_AtomicConditionFailureWithOutbox(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	publisher := NewMemoryEventPublisher()
+	service := NewDynamoDBService(client, "test-table", WithEventPublisher(publisher))
+
+	accountID := uuid.New().String()
+	stored := []*models.LaborLine{newBatchLaborLine(accountID), newBatchLaborLine(accountID)}
+	for _, laborLine := range stored {
+		onGetLaborLine(client, laborLine)
+	}
+
+	// Each item contributes three transaction items, so the second labor line
+	// write is the fourth
+	concurrentItem, err := attributevalue.MarshalMap(&models.LaborLine{LaborLineID: stored[1].LaborLineID, Version: 5})
+	require.NoError(t, err)
+	client.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		return len(input.TransactItems) == 6
+	})).Return((*dynamodb.TransactWriteItemsOutput)(nil), &types.TransactionCanceledException{
+		CancellationReasons: []types.CancellationReason{
+			{Code: aws.String("None")},
+			{Code: aws.String("None")},
+			{Code: aws.String("None")},
+			{Code: aws.String("ConditionalCheckFailed"), Item: concurrentItem},
+			{Code: aws.String("None")},
+			{Code: aws.String("None")},
+		},
+	}).Once()
+
+	updates := make([]LaborLineUpdate, len(stored))
+	for i, laborLine := range stored {
+		updates[i] = LaborLineUpdate{Input: models.UpdateLaborLineInput{
+			LaborLineID: laborLine.LaborLineID,
+			AccountID:   laborLine.AccountID,
+			TaskID:      laborLine.TaskID,
+			Description: models.NewNullable("updated"),
+		}}
+	}
+
+	results := service.BatchUpdateLaborLines(context.Background(), updates, models.BatchModeAtomic, "user-123")
+
+	require.Len(t, results, 2)
+	assert.ErrorIs(t, results[0].Err, ErrBatchAborted)
+	var conflictErr *ConflictError
+	require.ErrorAs(t, results[1].Err, &conflictErr)
+	assert.Equal(t, int64(5), conflictErr.CurrentVersion)
+	assert.Empty(t, publisher.Events())
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_RelayOutbox(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	publisher := NewMemoryEventPublisher()
+	service := NewDynamoDBService(client, "test-table", WithEventPublisher(publisher))
+
+	laborLine := newBatchLaborLine(uuid.New().String())
+	event := models.NewLaborLineEvent(models.ActionUpdate, "user-123", laborLine, laborLine)
+	entry, err := models.NewOutboxEntry(event)
+	require.NoError(t, err)
+	item, err := attributevalue.MarshalMap(entry)
+	require.NoError(t, err)
+
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		cutoff := input.ExpressionAttributeValues[":cutoff"].(*types.AttributeValueMemberN)
+		return *input.IndexName == "OutboxIndex" &&
+			*input.KeyConditionExpression == "outboxStatus = :pending AND createdAt <= :cutoff" &&
+			cutoff.Value != ""
+	})).Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil).Once()
+	client.On("BatchWriteItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.BatchWriteItemInput) bool {
+		requests := input.RequestItems["test-table"]
+		return len(requests) == 1 && requests[0].DeleteRequest.Key["SK"].(*types.AttributeValueMemberS).Value == entry.SK
+	})).Return(&dynamodb.BatchWriteItemOutput{}, nil).Once()
+
+	published, err := service.RelayOutbox(context.Background(), time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+
+	events := publisher.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, event.EventID, events[0].EventID)
+	assert.Equal(t, laborLine.LaborLineID, events[0].After.LaborLineID)
+
+	client.AssertExpectations(t)
+}
+
+// rejectingPublisher rejects any publish that includes one labor line's
+// events and keeps the rest.
+type rejectingPublisher struct {
+	*MemoryEventPublisher
+	laborLineID string
+}
+
+func (p *rejectingPublisher) Publish(ctx context.Context, events []*models.LaborLineEvent) error {
+	for _, event := range events {
+		if event.LaborLineID == p.laborLineID {
+			return errors.New("event rejected")
+		}
+	}
+	return p.MemoryEventPublisher.Publish(ctx, events)
+}
+
+// outboxItem returns the outbox item holding a labor line's creation event,
+// after the relay has failed to publish it attempts times.
+func outboxItem(t *testing.T, laborLine *models.LaborLine, attempts int) (*models.OutboxEntry, map[string]types.AttributeValue) {
+	t.Helper()
+
+	entry, err := models.NewOutboxEntry(models.NewLaborLineEvent(models.ActionCreate, "user-123", nil, laborLine))
+	require.NoError(t, err)
+	entry.Attempts = attempts
+	item, err := attributevalue.MarshalMap(entry)
+	require.NoError(t, err)
+	return entry, item
+}
+
+// onRecordOutboxFailure mocks recording a failed publish of the outbox entry
+// with the given sort key, which leaves it with the given status.
+func onRecordOutboxFailure(client *MockDynamoDBClient, sk, status string) {
+	client.On("UpdateItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.UpdateItemInput) bool {
+		return input.Key["SK"].(*types.AttributeValueMemberS).Value == sk &&
+			*input.UpdateExpression == "SET outboxStatus = :status, lastError = :lastError ADD attempts :one" &&
+			input.ExpressionAttributeValues[":status"].(*types.AttributeValueMemberS).Value == status
+	})).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+}
+
+func TestDynamoDBService_RelayOutbox_PublishFailure(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	publisher := NewMemoryEventPublisher()
+	publisher.Err = errors.New("event bus unavailable")
+	service := NewDynamoDBService(client, "test-table", WithEventPublisher(publisher))
+
+	entry, item := outboxItem(t, newBatchLaborLine(uuid.New().String()), 0)
+	client.On("Query", mock.Anything, mock.Anything).Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil).Once()
+	onRecordOutboxFailure(client, entry.SK, models.OutboxPending)
+
+	// The entry stays in the outbox for the next run
+	published, err := service.RelayOutbox(context.Background(), time.Minute)
+	require.Error(t, err)
+	assert.Equal(t, 0, published)
+	client.AssertNotCalled(t, "BatchWriteItem", mock.Anything, mock.Anything)
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_RelayOutbox_RejectedEventDoesNotBlockOthers(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	rejected := newBatchLaborLine(uuid.New().String())
+	publisher := &rejectingPublisher{MemoryEventPublisher: NewMemoryEventPublisher(), laborLineID: rejected.LaborLineID}
+	service := NewDynamoDBService(client, "test-table", WithEventPublisher(publisher))
+
+	rejectedEntry, rejectedItem := outboxItem(t, rejected, 2)
+	entry, item := outboxItem(t, newBatchLaborLine(uuid.New().String()), 0)
+	client.On("Query", mock.Anything, mock.Anything).Return(&dynamodb.QueryOutput{
+		Items:            []map[string]types.AttributeValue{rejectedItem},
+		LastEvaluatedKey: map[string]types.AttributeValue{"PK": rejectedItem["PK"]},
+	}, nil).Once()
+	client.On("Query", mock.Anything, mock.Anything).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{item},
+	}, nil).Once()
+	onRecordOutboxFailure(client, rejectedEntry.SK, models.OutboxPending)
+	client.On("BatchWriteItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.BatchWriteItemInput) bool {
+		requests := input.RequestItems["test-table"]
+		return len(requests) == 1 && requests[0].DeleteRequest.Key["SK"].(*types.AttributeValueMemberS).Value == entry.SK
+	})).Return(&dynamodb.BatchWriteItemOutput{}, nil).Once()
+
+	// The rest of the outbox is relayed before the failure is reported
+	published, err := service.RelayOutbox(context.Background(), time.Minute)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 2 failed")
+	assert.Equal(t, 1, published)
+	require.Len(t, publisher.Events(), 1)
+	assert.Equal(t, entry.EventID, publisher.Events()[0].EventID)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_RelayOutbox_SplitsRejectedPage(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	rejected := newBatchLaborLine(uuid.New().String())
+	publisher := &rejectingPublisher{MemoryEventPublisher: NewMemoryEventPublisher(), laborLineID: rejected.LaborLineID}
+	service := NewDynamoDBService(client, "test-table", WithEventPublisher(publisher))
+
+	// The last of its attempts marks the entry failed
+	rejectedEntry, rejectedItem := outboxItem(t, rejected, models.MaxOutboxAttempts-1)
+	entry, item := outboxItem(t, newBatchLaborLine(uuid.New().String()), 0)
+	client.On("Query", mock.Anything, mock.Anything).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{rejectedItem, item},
+	}, nil).Once()
+	onRecordOutboxFailure(client, rejectedEntry.SK, models.OutboxFailed)
+	client.On("BatchWriteItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.BatchWriteItemInput) bool {
+		requests := input.RequestItems["test-table"]
+		return len(requests) == 1 && requests[0].DeleteRequest.Key["SK"].(*types.AttributeValueMemberS).Value == entry.SK
+	})).Return(&dynamodb.BatchWriteItemOutput{}, nil).Once()
+
+	published, err := service.RelayOutbox(context.Background(), time.Minute)
+	require.Error(t, err)
+	assert.Equal(t, 1, published)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_RelayOutbox_UnreadableEntry(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	publisher := NewMemoryEventPublisher()
+	service := NewDynamoDBService(client, "test-table", WithEventPublisher(publisher))
+
+	entry, item := outboxItem(t, newBatchLaborLine(uuid.New().String()), 0)
+	item["detail"] = &types.AttributeValueMemberS{Value: "{not json"}
+	client.On("Query", mock.Anything, mock.Anything).Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil).Once()
+	onRecordOutboxFailure(client, entry.SK, models.OutboxFailed)
+
+	published, err := service.RelayOutbox(context.Background(), time.Minute)
+	require.Error(t, err)
+	assert.Equal(t, 0, published)
+	assert.Empty(t, publisher.Events())
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_RelayOutbox_NoPublisher(t *testing.T) {
+	service := NewDynamoDBService(&MockDynamoDBClient{}, "test-table")
+
+	_, err := service.RelayOutbox(context.Background(), time.Minute)
+	assert.ErrorIs(t, err, ErrNoEventPublisher)
+}
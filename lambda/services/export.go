@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// DefaultExportURLExpiry is how long the download URL of an export stays valid.
+const DefaultExportURLExpiry = 15 * time.Minute
+
+// ForEachLaborLine calls fn with every labor line of an account that matches
+// the export's filters, reading the query one page at a time so an export
+// never holds all of them in memory. It stops at the first error fn returns.
+func (s *dynamoDBService) ForEachLaborLine(ctx context.Context, input models.ExportLaborLinesInput, fn func(*models.LaborLine) error) error {
+	keyCondition := "PK = :pk"
+	filter := laborLineFilter
+	values := map[string]types.AttributeValue{
+		":pk": &types.AttributeValueMemberS{Value: input.AccountID},
+	}
+	if input.TaskID != "" {
+		keyCondition += " AND begins_with(SK, :skPrefix)"
+		values[":skPrefix"] = &types.AttributeValueMemberS{Value: input.TaskID + "#"}
+	}
+
+	switch {
+	case input.CreatedFrom != nil && input.CreatedTo != nil:
+		filter += " AND createdAt BETWEEN :from AND :to"
+	case input.CreatedFrom != nil:
+		filter += " AND createdAt >= :from"
+	case input.CreatedTo != nil:
+		filter += " AND createdAt <= :to"
+	}
+	if input.CreatedFrom != nil {
+		values[":from"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(*input.CreatedFrom, 10)}
+	}
+	if input.CreatedTo != nil {
+		values[":to"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(*input.CreatedTo, 10)}
+	}
+
+	queryInput := &dynamodb.QueryInput{
+		TableName:                 aws.String(s.tableName),
+		KeyConditionExpression:    aws.String(keyCondition),
+		FilterExpression:          aws.String(filter),
+		ExpressionAttributeValues: values,
+	}
+
+	for {
+		result, err := s.client.Query(ctx, queryInput)
+		if err != nil {
+			return fmt.Errorf("querying labor lines from DynamoDB: %w", err)
+		}
+		for _, item := range result.Items {
+			laborLine, err := s.unmarshalLaborLine(item)
+			if err != nil {
+				return err
+			}
+			if err := fn(laborLine); err != nil {
+				return err
+			}
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		queryInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// ExportStore is where finished exports are kept for download.
+type ExportStore interface {
+	// Put stores an export under the key, replacing any stored there before.
+	Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) error
+
+	// PresignGet returns a URL the stored export can be downloaded from
+	// without credentials until it expires.
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// MemoryExportStore is an ExportStore that keeps exports in memory, for tests
+// and local runs.
+type MemoryExportStore struct {
+	mu      sync.Mutex
+	objects map[string]memoryExport
+
+	// Err, if set, is returned by Put instead of keeping the export
+	Err error
+}
+
+// memoryExport is an export kept by a MemoryExportStore.
+type memoryExport struct {
+	body        []byte
+	contentType string
+}
+
+// NewMemoryExportStore creates an in-memory export store with no exports.
+func NewMemoryExportStore() *MemoryExportStore {
+	return &MemoryExportStore{objects: map[string]memoryExport{}}
+}
+
+// Put keeps the export, or returns s.Err if it is set.
+func (s *MemoryExportStore) Put(_ context.Context, key string, body io.ReadSeeker, contentType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("reading export: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+	s.objects[key] = memoryExport{body: data, contentType: contentType}
+	return nil
+}
+
+// PresignGet returns a memory:// URL naming the export.
+func (s *MemoryExportStore) PresignGet(_ context.Context, key string, _ time.Duration) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.objects[key]; !ok {
+		return "", fmt.Errorf("export %s not found", key)
+	}
+	return "memory://" + key, nil
+}
+
+// Get returns the content and content type of a stored export, and whether
+// there is one under the key.
+func (s *MemoryExportStore) Get(key string) ([]byte, string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	object, ok := s.objects[key]
+	return object.body, object.contentType, ok
+}
+
+// LaborLineExporter writes an account's labor lines to a file in an
+// ExportStore and returns where to download it.
+type LaborLineExporter struct {
+	dynamoDBService DynamoDBService
+	store           ExportStore
+	urlExpiry       time.Duration
+}
+
+// NewLaborLineExporter creates an exporter that reads labor lines from the
+// DynamoDB service and keeps the exports in the store.
+func NewLaborLineExporter(dynamoDBService DynamoDBService, store ExportStore) *LaborLineExporter {
+	return &LaborLineExporter{
+		dynamoDBService: dynamoDBService,
+		store:           store,
+		urlExpiry:       DefaultExportURLExpiry,
+	}
+}
+
+// Export renders the labor lines matching the input in its format and
+// stores the file. The input's format and columns must be valid; empty ones
+// take their defaults. The file is built in a temporary file rather than in
+// memory, since an account may have many labor lines.
+func (e *LaborLineExporter) Export(ctx context.Context, input models.ExportLaborLinesInput) (*models.LaborLineExport, error) {
+	format := input.Format
+	if format == "" {
+		format = models.DefaultExportFormat
+	}
+
+	file, err := os.CreateTemp("", "labor-lines-export-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating export file: %w", err)
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+
+	writer, err := newExportWriter(file, format, input.Columns)
+	if err != nil {
+		return nil, err
+	}
+	rowCount := 0
+	err = e.dynamoDBService.ForEachLaborLine(ctx, input, func(laborLine *models.LaborLine) error {
+		rowCount++
+		return writer.write(laborLine)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.flush(); err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("rewinding export file: %w", err)
+	}
+
+	now := time.Now()
+	key := fmt.Sprintf("exports/%s/%s-%s.%s", input.AccountID, now.UTC().Format("20060102T150405Z"), uuid.New().String(), format.Extension())
+	if err := e.store.Put(ctx, key, file, format.ContentType()); err != nil {
+		return nil, err
+	}
+	url, err := e.store.PresignGet(ctx, key, e.urlExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.LaborLineExport{
+		URL:       url,
+		Key:       key,
+		Format:    format,
+		RowCount:  rowCount,
+		ExpiresAt: now.Add(e.urlExpiry).Unix(),
+	}, nil
+}
+
+// exportWriter renders labor lines into an export file.
+type exportWriter interface {
+	write(laborLine *models.LaborLine) error
+	flush() error
+}
+
+// newExportWriter creates the writer of an export in the format, writing the
+// CSV header straight away.
+func newExportWriter(w io.Writer, format models.ExportFormat, columns []string) (exportWriter, error) {
+	if format == models.ExportFormatNDJSON {
+		return &ndjsonExportWriter{encoder: json.NewEncoder(w)}, nil
+	}
+
+	if len(columns) == 0 {
+		columns = models.DefaultExportColumns
+	}
+
+	// RFC 4180 ends records with CRLF; fields holding quotes, commas or line
+	// breaks, such as notes, are quoted by the csv package
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = true
+	if err := writer.Write(columns); err != nil {
+		return nil, fmt.Errorf("writing export header: %w", err)
+	}
+	return &csvExportWriter{writer: writer, columns: columns}, nil
+}
+
+// csvExportWriter writes a row of the chosen columns per labor line.
+type csvExportWriter struct {
+	writer  *csv.Writer
+	columns []string
+}
+
+func (w *csvExportWriter) write(laborLine *models.LaborLine) error {
+	if err := w.writer.Write(models.ExportRow(laborLine, w.columns)); err != nil {
+		return fmt.Errorf("writing export row: %w", err)
+	}
+	return nil
+}
+
+func (w *csvExportWriter) flush() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return fmt.Errorf("writing export rows: %w", err)
+	}
+	return nil
+}
+
+// ndjsonExportWriter writes each labor line as a JSON object on its own line,
+// in the same shape the API returns it.
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *ndjsonExportWriter) write(laborLine *models.LaborLine) error {
+	if err := w.encoder.Encode(laborLine); err != nil {
+		return fmt.Errorf("writing export line: %w", err)
+	}
+	return nil
+}
+
+func (w *ndjsonExportWriter) flush() error {
+	return nil
+}
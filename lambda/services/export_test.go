@@ -0,0 +1,185 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// exportLaborLine returns a labor line with a note that needs CSV escaping.
+func exportLaborLine(t *testing.T) (*models.LaborLine, map[string]types.AttributeValue) {
+	t.Helper()
+
+	laborLine := newTestLaborLine()
+	laborLine.Description = "Replace brake pads"
+	laborLine.Status = models.StatusCompleted
+	laborLine.EstimatedHours = models.MustParseDecimal("1.5")
+	laborLine.LaborCost = models.MustParseDecimal("180")
+	laborLine.CreatedAt = 1760000000
+	laborLine.Notes = []models.Note{
+		{NoteID: "note-1", Body: `Customer said "squealing", front only`},
+		{NoteID: "note-2", Body: "Rotors OK"},
+	}
+
+	item, err := attributevalue.MarshalMap(laborLine)
+	require.NoError(t, err)
+	return laborLine, item
+}
+
+func TestDynamoDBService_ForEachLaborLine(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	first, firstItem := exportLaborLine(t)
+	_, secondItem := exportLaborLine(t)
+	from, to := int64(1759000000), int64(1761000000)
+
+	// Every page is read, with the date range filtered by DynamoDB
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return input.ExclusiveStartKey == nil &&
+			*input.KeyConditionExpression == "PK = :pk AND begins_with(SK, :skPrefix)" &&
+			*input.FilterExpression == laborLineFilter+" AND createdAt BETWEEN :from AND :to" &&
+			input.ExpressionAttributeValues[":from"].(*types.AttributeValueMemberN).Value == "1759000000"
+	})).Return(&dynamodb.QueryOutput{
+		Items:            []map[string]types.AttributeValue{firstItem},
+		LastEvaluatedKey: map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: first.AccountID}},
+	}, nil).Once()
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return input.ExclusiveStartKey != nil
+	})).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{secondItem},
+	}, nil).Once()
+
+	var seen []*models.LaborLine
+	err := service.ForEachLaborLine(context.Background(), models.ExportLaborLinesInput{
+		AccountID:   first.AccountID,
+		TaskID:      first.TaskID,
+		CreatedFrom: &from,
+		CreatedTo:   &to,
+	}, func(laborLine *models.LaborLine) error {
+		seen = append(seen, laborLine)
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Len(t, seen, 2)
+	assert.Equal(t, first.LaborLineID, seen[0].LaborLineID)
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_ForEachLaborLine_StopsAtError(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine, item := exportLaborLine(t)
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return *input.FilterExpression == laborLineFilter+" AND createdAt <= :to"
+	})).Return(&dynamodb.QueryOutput{
+		Items:            []map[string]types.AttributeValue{item, item},
+		LastEvaluatedKey: map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: laborLine.AccountID}},
+	}, nil).Once()
+
+	to := int64(1761000000)
+	calls := 0
+	err := service.ForEachLaborLine(context.Background(), models.ExportLaborLinesInput{AccountID: laborLine.AccountID, CreatedTo: &to}, func(*models.LaborLine) error {
+		calls++
+		return errors.New("disk full")
+	})
+
+	assert.EqualError(t, err, "disk full")
+	assert.Equal(t, 1, calls)
+	client.AssertNumberOfCalls(t, "Query", 1)
+}
+
+// exportFixture mocks an account whose only labor line is the item.
+func exportFixture(client *MockDynamoDBClient, item map[string]types.AttributeValue) {
+	client.On("Query", mock.Anything, mock.Anything).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{item},
+	}, nil).Once()
+}
+
+func TestLaborLineExporter_Export_CSV(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	store := NewMemoryExportStore()
+	exporter := NewLaborLineExporter(NewDynamoDBService(client, "test-table"), store)
+
+	laborLine, item := exportLaborLine(t)
+	exportFixture(client, item)
+
+	export, err := exporter.Export(context.Background(), models.ExportLaborLinesInput{
+		AccountID: laborLine.AccountID,
+		Columns:   []string{"laborLineId", "laborCost", "notes", "createdAt"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, models.ExportFormatCSV, export.Format)
+	assert.Equal(t, 1, export.RowCount)
+	assert.True(t, strings.HasPrefix(export.Key, "exports/"+laborLine.AccountID+"/"))
+	assert.True(t, strings.HasSuffix(export.Key, ".csv"))
+	assert.Equal(t, "memory://"+export.Key, export.URL)
+	assert.Greater(t, export.ExpiresAt, laborLine.CreatedAt)
+
+	body, contentType, ok := store.Get(export.Key)
+	require.True(t, ok)
+	assert.Equal(t, "text/csv", contentType)
+
+	// Records end in CRLF and the notes are quoted with their quotes doubled
+	expected := "laborLineId,laborCost,notes,createdAt\r\n" +
+		laborLine.LaborLineID + `,180.00,"Customer said ""squealing"", front only` + "\r\n" + `Rotors OK",2025-10-09T08:53:20Z` + "\r\n"
+	assert.Equal(t, expected, string(body))
+}
+
+func TestLaborLineExporter_Export_NDJSON(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	store := NewMemoryExportStore()
+	exporter := NewLaborLineExporter(NewDynamoDBService(client, "test-table"), store)
+
+	laborLine, item := exportLaborLine(t)
+	exportFixture(client, item)
+
+	export, err := exporter.Export(context.Background(), models.ExportLaborLinesInput{
+		AccountID: laborLine.AccountID,
+		Format:    models.ExportFormatNDJSON,
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(export.Key, ".ndjson"))
+
+	body, contentType, ok := store.Get(export.Key)
+	require.True(t, ok)
+	assert.Equal(t, "application/x-ndjson", contentType)
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	require.True(t, scanner.Scan())
+	var exported models.LaborLine
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &exported))
+	assert.Equal(t, laborLine.LaborLineID, exported.LaborLineID)
+	assert.Len(t, exported.Notes, 2)
+	assert.False(t, scanner.Scan())
+}
+
+func TestLaborLineExporter_Export_StoreError(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	store := NewMemoryExportStore()
+	store.Err = errors.New("access denied")
+	exporter := NewLaborLineExporter(NewDynamoDBService(client, "test-table"), store)
+
+	laborLine, item := exportLaborLine(t)
+	exportFixture(client, item)
+
+	export, err := exporter.Export(context.Background(), models.ExportLaborLinesInput{AccountID: laborLine.AccountID})
+	assert.Nil(t, export)
+	assert.EqualError(t, err, "access denied")
+}
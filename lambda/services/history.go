@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/schemas"
+)
+
+// laborLineWrite is a conditional write of a labor line item: a put of the
+// whole item or, if update is set, an update expression changing the stored
+// item into laborLine.
+type laborLineWrite struct {
+	laborLine *models.LaborLine
+	update    string
+	condition string
+	names     map[string]string
+	values    map[string]types.AttributeValue
+}
+
+// versionedWrite builds a write of an existing labor line that only succeeds if
+// the item is still live and at the version that was read.
+func versionedWrite(laborLine *models.LaborLine, readVersion int64) laborLineWrite {
+	versionExpr, versionValues := versionCondition(readVersion)
+	return laborLineWrite{
+		laborLine: laborLine,
+		condition: "attribute_exists(PK) AND attribute_exists(SK) AND attribute_not_exists(deletedAt) AND " + versionExpr,
+		names:     map[string]string{"#version": "version"},
+		values:    versionValues,
+	}
+}
+
+// writeWithHistory puts a labor line and records the change from before in the
+// same transaction, so the history can never miss or invent a write. Writes of
+// the labor line's related records, such as its assignments, are added to the
+// transaction after the history record, followed by the update of the task's
+// summary. The labor line write is the first item of the transaction, so a
+// failed condition can be translated with transactionConditionFailure(err, 0).
+// A transaction that conflicts with a concurrent write to the same task is
+// retried. Once the transaction commits, the event announcing the change is
+// published.
+func (s *dynamoDBService) writeWithHistory(ctx context.Context, write laborLineWrite, action models.ChangeAction, actor string, before *models.LaborLine, related ...types.TransactWriteItem) error {
+	return withConflictRetry(ctx, func() error {
+		return s.transactWithHistory(ctx, write, action, actor, before, related...)
+	})
+}
+
+// transactWithHistory makes a single attempt at the transaction of
+// writeWithHistory.
+func (s *dynamoDBService) transactWithHistory(ctx context.Context, write laborLineWrite, action models.ChangeAction, actor string, before *models.LaborLine, related ...types.TransactWriteItem) error {
+	items, event, err := s.historyTransactItems(write, action, actor, before)
+	if err != nil {
+		return err
+	}
+
+	summaries := taskSummaryChanges{}
+	summaries.add(before, write.laborLine)
+	items = append(items, related...)
+	items = append(items, s.summaryTransactItems(summaries)...)
+
+	_, err = s.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	if err != nil {
+		return err
+	}
+
+	if event != nil {
+		s.publishEvents(ctx, []*models.LaborLineEvent{event})
+	}
+	return nil
+}
+
+// historyTransactItems builds the transaction items of writeWithHistory: the
+// labor line write followed by its history record and, if the service
+// publishes events, the outbox entry of the event it returns.
+func (s *dynamoDBService) historyTransactItems(write laborLineWrite, action models.ChangeAction, actor string, before *models.LaborLine) ([]types.TransactWriteItem, *models.LaborLineEvent, error) {
+	if write.update == "" {
+		// The whole item is written, so it now conforms to the current schema
+		write.laborLine.SchemaVersion = schemas.CurrentVersion
+	}
+
+	record, err := models.NewHistoryRecord(action, actor, before, write.laborLine)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	laborLineItem, err := s.laborLineTransactItem(write)
+	if err != nil {
+		return nil, nil, err
+	}
+	historyItem, err := attributevalue.MarshalMap(record)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshaling history record: %w", err)
+	}
+
+	items := []types.TransactWriteItem{
+		laborLineItem,
+		{
+			// History records are immutable
+			Put: &types.Put{
+				TableName:           aws.String(s.tableName),
+				Item:                historyItem,
+				ConditionExpression: aws.String("attribute_not_exists(PK)"),
+			},
+		},
+	}
+	if s.eventPublisher == nil {
+		return items, nil, nil
+	}
+
+	event := models.NewLaborLineEvent(action, actor, before, write.laborLine)
+	outboxItem, err := s.outboxTransactItem(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(items, outboxItem), event, nil
+}
+
+// firstRelatedIndex returns the index in a writeWithHistory transaction of the
+// first write of the labor line's related records, so that their failed
+// conditions can be translated.
+func (s *dynamoDBService) firstRelatedIndex() int {
+	if s.eventPublisher == nil {
+		return 2
+	}
+	return 3
+}
+
+// laborLineTransactItem builds the transaction item of a labor line write.
+// Transactions cannot return the written item, but every write is conditioned
+// on the stored version it was prepared from, so write.laborLine is exactly
+// the item as written.
+func (s *dynamoDBService) laborLineTransactItem(write laborLineWrite) (types.TransactWriteItem, error) {
+	if write.update != "" {
+		return types.TransactWriteItem{
+			Update: &types.Update{
+				TableName: aws.String(s.tableName),
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: write.laborLine.PK},
+					"SK": &types.AttributeValueMemberS{Value: write.laborLine.SK},
+				},
+				UpdateExpression:                    aws.String(write.update),
+				ConditionExpression:                 aws.String(write.condition),
+				ExpressionAttributeNames:            write.names,
+				ExpressionAttributeValues:           write.values,
+				ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+			},
+		}, nil
+	}
+
+	item, err := attributevalue.MarshalMap(write.laborLine)
+	if err != nil {
+		return types.TransactWriteItem{}, fmt.Errorf("marshaling labor line: %w", err)
+	}
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:                           aws.String(s.tableName),
+			Item:                                item,
+			ConditionExpression:                 aws.String(write.condition),
+			ExpressionAttributeNames:            write.names,
+			ExpressionAttributeValues:           write.values,
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+		},
+	}, nil
+}
+
+// GetLaborLineHistory retrieves a page of a labor line's change history, most
+// recent change first. History remains available after the labor line is deleted.
+func (s *dynamoDBService) GetLaborLineHistory(ctx context.Context, input models.GetLaborLineHistoryInput) (*models.HistoryConnection, error) {
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(s.tableName),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :skPrefix)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":       &types.AttributeValueMemberS{Value: models.HistoryPK(input.AccountID)},
+			":skPrefix": &types.AttributeValueMemberS{Value: models.HistorySKPrefix(input.TaskID, input.LaborLineID)},
+		},
+		ScanIndexForward: aws.Bool(false),
+	}
+
+	scope := input.AccountID + "#" + input.LaborLineID
+	items, nextToken, err := s.queryPage(ctx, queryInput, scope, input.Limit, input.NextToken)
+	if err != nil {
+		return nil, fmt.Errorf("querying labor line history from DynamoDB: %w", err)
+	}
+
+	records := make([]*models.HistoryRecord, 0, len(items))
+	for _, item := range items {
+		var record models.HistoryRecord
+		if err := attributevalue.UnmarshalMap(item, &record); err != nil {
+			return nil, fmt.Errorf("unmarshaling history record: %w", err)
+		}
+		records = append(records, &record)
+	}
+
+	return &models.HistoryConnection{
+		Items:     records,
+		NextToken: nextToken,
+	}, nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+func TestDynamoDBService_UpdateLaborLine_RecordsHistory(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	existingItem, err := attributevalue.MarshalMap(&models.LaborLine{
+		LaborLineID: laborLineID,
+		AccountID:   accountID,
+		TaskID:      taskID,
+		Description: "Brake inspection",
+		Version:     4,
+	})
+	require.NoError(t, err)
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: existingItem}, nil)
+
+	var written *dynamodb.TransactWriteItemsInput
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { written = args.Get(1).(*dynamodb.TransactWriteItemsInput) }).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	_, err = service.UpdateLaborLine(context.Background(), LaborLineUpdate{Input: models.UpdateLaborLineInput{
+		LaborLineID: laborLineID,
+		AccountID:   accountID,
+		TaskID:      taskID,
+		Description: models.NewNullable("Brake replacement"),
+	}}, "user-123")
+	require.NoError(t, err)
+	require.NotNil(t, written)
+	require.Len(t, written.TransactItems, 2)
+
+	put := historyPut(written)
+	assert.Equal(t, "attribute_not_exists(PK)", *put.ConditionExpression)
+
+	var record models.HistoryRecord
+	require.NoError(t, attributevalue.UnmarshalMap(put.Item, &record))
+	assert.Equal(t, models.RecordTypeHistory, record.RecordType)
+	assert.Equal(t, "HISTORY#"+accountID, record.PK)
+	assert.Equal(t, taskID+"#"+laborLineID+"#HISTORY#00000000000000000005", record.SK)
+	assert.Equal(t, models.ActionUpdate, record.Action)
+	assert.Equal(t, "user-123", record.Actor)
+	assert.Equal(t, int64(5), record.Version)
+	assert.Contains(t, record.Changes, models.FieldChange{
+		Field:  "description",
+		Before: `"Brake inspection"`,
+		After:  `"Brake replacement"`,
+	})
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_GetLaborLineHistory(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table", WithPageTokenSecret([]byte("test-secret")))
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	newItem := func(version int64, action models.ChangeAction) map[string]types.AttributeValue {
+		record, _ := models.NewHistoryRecord(action, "user-123", nil, &models.LaborLine{
+			LaborLineID: laborLineID,
+			AccountID:   accountID,
+			TaskID:      taskID,
+			Version:     version,
+		})
+		item, _ := attributevalue.MarshalMap(record)
+		return item
+	}
+
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		pk := input.ExpressionAttributeValues[":pk"].(*types.AttributeValueMemberS)
+		prefix := input.ExpressionAttributeValues[":skPrefix"].(*types.AttributeValueMemberS)
+		return pk.Value == "HISTORY#"+accountID && prefix.Value == taskID+"#"+laborLineID+"#HISTORY#" &&
+			input.ScanIndexForward != nil && !*input.ScanIndexForward &&
+			input.FilterExpression == nil && input.ExclusiveStartKey == nil
+	})).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{newItem(2, models.ActionUpdate), newItem(1, models.ActionCreate)},
+		LastEvaluatedKey: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: "HISTORY#" + accountID},
+			"SK": &types.AttributeValueMemberS{Value: taskID + "#" + laborLineID + "#HISTORY#00000000000000000001"},
+		},
+	}, nil).Once()
+
+	page, err := service.GetLaborLineHistory(context.Background(), models.GetLaborLineHistoryInput{
+		AccountID:   accountID,
+		TaskID:      taskID,
+		LaborLineID: laborLineID,
+		Limit:       2,
+	})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, models.ActionUpdate, page.Items[0].Action)
+	assert.Equal(t, int64(2), page.Items[0].Version)
+	require.NotNil(t, page.NextToken)
+
+	// A history token cannot be used to page another labor line's history
+	_, err = service.GetLaborLineHistory(context.Background(), models.GetLaborLineHistoryInput{
+		AccountID:   accountID,
+		TaskID:      taskID,
+		LaborLineID: uuid.New().String(),
+		NextToken:   *page.NextToken,
+	})
+	assert.ErrorIs(t, err, ErrInvalidNextToken)
+
+	client.AssertExpectations(t)
+}
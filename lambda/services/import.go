@@ -0,0 +1,381 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// DefaultImportConcurrency is how many batches of an import are written at once.
+const DefaultImportConcurrency = 4
+
+// LaborLineImporter creates labor lines from the rows of an import file.
+type LaborLineImporter struct {
+	dynamoDBService   DynamoDBService
+	validationService ValidationService
+	concurrency       int
+}
+
+// LaborLineImporterOption configures a LaborLineImporter.
+type LaborLineImporterOption func(*LaborLineImporter)
+
+// WithImportConcurrency sets how many batches an import writes at once.
+// Values below 1 are ignored.
+func WithImportConcurrency(n int) LaborLineImporterOption {
+	return func(i *LaborLineImporter) {
+		if n > 0 {
+			i.concurrency = n
+		}
+	}
+}
+
+// NewLaborLineImporter creates an importer that validates rows like the
+// create operation and writes them with dynamoDBService.
+func NewLaborLineImporter(dynamoDBService DynamoDBService, validationService ValidationService, opts ...LaborLineImporterOption) *LaborLineImporter {
+	importer := &LaborLineImporter{
+		dynamoDBService:   dynamoDBService,
+		validationService: validationService,
+		concurrency:       DefaultImportConcurrency,
+	}
+	for _, opt := range opts {
+		opt(importer)
+	}
+	return importer
+}
+
+// importedRow is a valid row waiting to be written.
+type importedRow struct {
+	number    int
+	laborLine *models.LaborLine
+}
+
+// importRun is the state of one import.
+type importRun struct {
+	importer *LaborLineImporter
+	input    models.ImportLaborLinesInput
+
+	definitions []*models.CustomFieldDefinition
+	rateCard    *models.RateCard
+	rateCardSet bool
+	operations  map[string]*models.LaborOperation
+	keys        map[string]int // Row each import key was first seen in
+
+	mu     sync.Mutex
+	result *models.ImportResult
+}
+
+// Import reads the rows of an import file from body, validates each one as
+// the create operation does and writes the valid ones in batches of
+// models.MaxBatchSize, several at a time. Rows that are invalid or cannot be
+// written are reported in the result rather than stopping the import.
+//
+// Each labor line's ID is derived from the row's import key, so a row already
+// imported by an earlier run is skipped rather than created again. Parts are
+// imported as recorded, without reserving inventory, as imported labor lines
+// describe work that has already been done.
+//
+// An error is returned only if the file cannot be read or the account's
+// settings cannot be loaded; rows written before then stay written, and
+// running the import again picks up where it stopped.
+func (i *LaborLineImporter) Import(ctx context.Context, body io.Reader, input models.ImportLaborLinesInput) (*models.ImportResult, error) {
+	if input.Format == "" {
+		input.Format = models.ImportFormatCSV
+	}
+	if input.Actor == "" {
+		input.Actor = models.DefaultImportActor
+	}
+
+	definitions, err := i.dynamoDBService.ListCustomFieldDefinitions(ctx, input.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := newImportReader(body, input.Format, definitions)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &importRun{
+		importer:    i,
+		input:       input,
+		definitions: definitions,
+		operations:  map[string]*models.LaborOperation{},
+		keys:        map[string]int{},
+		result: &models.ImportResult{
+			Source:    input.Source,
+			AccountID: input.AccountID,
+			DryRun:    input.DryRun,
+			Errors:    []models.ImportRowError{},
+		},
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, i.concurrency)
+	flush := func(batch []importedRow) {
+		if input.DryRun || len(batch) == 0 {
+			return
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			run.write(ctx, batch)
+		}()
+	}
+
+	batch := make([]importedRow, 0, models.MaxBatchSize)
+	for {
+		row, number, rowErrs, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			var laborLine *models.LaborLine
+			if laborLine, err = run.prepare(ctx, row, number, rowErrs); laborLine != nil {
+				batch = append(batch, importedRow{number: number, laborLine: laborLine})
+			}
+		}
+		if err != nil {
+			wg.Wait()
+			return nil, err
+		}
+
+		if len(batch) == models.MaxBatchSize {
+			flush(batch)
+			batch = make([]importedRow, 0, models.MaxBatchSize)
+		}
+	}
+	flush(batch)
+	wg.Wait()
+
+	sort.SliceStable(run.result.Errors, func(a, b int) bool {
+		return run.result.Errors[a].Row < run.result.Errors[b].Row
+	})
+	return run.result, nil
+}
+
+// prepare checks a row read from the file and creates the labor line it
+// describes. It returns nil if the row is invalid, having reported why, and
+// an error only if the account's settings cannot be loaded.
+func (r *importRun) prepare(ctx context.Context, row models.ImportRow, number int, rowErrs []models.ImportRowError) (*models.LaborLine, error) {
+	r.result.RowCount++
+	if len(rowErrs) > 0 {
+		r.reject(rowErrs...)
+		return nil, nil
+	}
+
+	input := row.CreateLaborLineInput
+	if input.AccountID == "" {
+		input.AccountID = r.input.AccountID
+	}
+	if input.AccountID != r.input.AccountID {
+		r.reject(models.ImportRowError{Row: number, Field: "accountId", Message: "row belongs to another account"})
+		return nil, nil
+	}
+
+	key, err := importKey(row.ImportKey, input)
+	if err != nil {
+		return nil, err
+	}
+	if first, ok := r.keys[key]; ok {
+		r.reject(models.ImportRowError{Row: number, Field: "importKey", Message: fmt.Sprintf("duplicate of row %d", first)})
+		return nil, nil
+	}
+	r.keys[key] = number
+
+	if err := r.importer.validationService.ValidateCreateInput(input, r.definitions); err != nil {
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			r.reject(models.ImportRowError{Row: number, Message: err.Error()})
+			return nil, nil
+		}
+		fieldErrs := make([]models.ImportRowError, len(validationErr.Fields))
+		for j, field := range validationErr.Fields {
+			fieldErrs[j] = models.ImportRowError{Row: number, Field: field.Field, Message: field.Message}
+		}
+		r.reject(fieldErrs...)
+		return nil, nil
+	}
+
+	if input.OperationCode != "" {
+		operation, err := r.operation(ctx, input.OperationCode)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case operation == nil:
+			r.reject(models.ImportRowError{Row: number, Field: "operationCode", Message: "labor operation not found"})
+			return nil, nil
+		case !operation.Active:
+			r.reject(models.ImportRowError{Row: number, Field: "operationCode", Message: "labor operation is inactive"})
+			return nil, nil
+		}
+		input = input.WithOperation(operation)
+	}
+
+	var rateCard *models.RateCard
+	if input.NeedsRateCard() {
+		if rateCard, err = r.loadRateCard(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	r.result.ValidCount++
+	return models.NewImportedLaborLine(input, rateCard, key), nil
+}
+
+// operation returns the account's labor operation with the code, loading
+// each code once per import.
+func (r *importRun) operation(ctx context.Context, code string) (*models.LaborOperation, error) {
+	if operation, ok := r.operations[code]; ok {
+		return operation, nil
+	}
+	operation, err := r.importer.dynamoDBService.GetLaborOperation(ctx, r.input.AccountID, code)
+	if err != nil {
+		return nil, err
+	}
+	r.operations[code] = operation
+	return operation, nil
+}
+
+// loadRateCard returns the account's rate card, loading it once per import.
+func (r *importRun) loadRateCard(ctx context.Context) (*models.RateCard, error) {
+	if !r.rateCardSet {
+		rateCard, err := r.importer.dynamoDBService.GetRateCard(ctx, r.input.AccountID)
+		if err != nil {
+			return nil, err
+		}
+		r.rateCard, r.rateCardSet = rateCard, true
+	}
+	return r.rateCard, nil
+}
+
+// write creates a batch of labor lines, each in its own transaction. A labor
+// line that already exists was created by an earlier run of the import.
+func (r *importRun) write(ctx context.Context, batch []importedRow) {
+	laborLines := make([]*models.LaborLine, len(batch))
+	for j, row := range batch {
+		laborLines[j] = row.laborLine
+	}
+	results := r.importer.dynamoDBService.BatchCreateLaborLines(ctx, laborLines, models.BatchModeBestEffort, r.input.Actor)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for j, result := range results {
+		var conflictErr *ConflictError
+		switch {
+		case result.Err == nil:
+			r.result.CreatedCount++
+		case errors.As(result.Err, &conflictErr), errors.Is(result.Err, ErrLaborLineNotFound):
+			// ErrLaborLineNotFound means the labor line was imported and has
+			// since been deleted, which a rerun must not undo
+			r.result.SkippedCount++
+		default:
+			r.addErrors(models.ImportRowError{Row: batch[j].number, Message: fmt.Sprintf("failed to create labor line: %v", result.Err)})
+		}
+	}
+}
+
+// reject reports why a row was not imported.
+func (r *importRun) reject(rowErrs ...models.ImportRowError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addErrors(rowErrs...)
+}
+
+// addErrors counts a row as failed and records its errors. The caller holds r.mu.
+func (r *importRun) addErrors(rowErrs ...models.ImportRowError) {
+	r.result.FailedCount++
+	r.result.Errors = append(r.result.Errors, rowErrs...)
+}
+
+// importKey returns the key identifying a row across runs of an import: the
+// row's own import key if it has one, otherwise a hash of its content.
+func importKey(key string, input models.CreateLaborLineInput) (string, error) {
+	if key != "" {
+		return key, nil
+	}
+	content, err := json.Marshal(input)
+	if err != nil {
+		return "", fmt.Errorf("hashing import row: %w", err)
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ImportStore is where import files are read from and their reports written to.
+type ImportStore interface {
+	// Get opens the object under the key in the bucket. The caller closes it.
+	Get(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+
+	// Put stores an object under the key in the bucket, replacing any stored
+	// there before.
+	Put(ctx context.Context, bucket, key string, body io.ReadSeeker, contentType string) error
+}
+
+// MemoryImportStore is an ImportStore that keeps objects in memory, for tests
+// and local runs.
+type MemoryImportStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+
+	// Err, if set, is returned by Get and Put instead of reading or keeping
+	// the object
+	Err error
+}
+
+// NewMemoryImportStore creates an in-memory import store with no objects.
+func NewMemoryImportStore() *MemoryImportStore {
+	return &MemoryImportStore{objects: map[string][]byte{}}
+}
+
+// Get opens the object, or returns s.Err if it is set.
+func (s *MemoryImportStore) Get(_ context.Context, bucket, key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	body, ok := s.objects[bucket+"/"+key]
+	if !ok {
+		return nil, fmt.Errorf("object %s/%s not found", bucket, key)
+	}
+	return io.NopCloser(bytes.NewReader(body)), nil
+}
+
+// Put keeps the object, or returns s.Err if it is set.
+func (s *MemoryImportStore) Put(_ context.Context, bucket, key string, body io.ReadSeeker, _ string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("reading object: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+	s.objects[bucket+"/"+key] = data
+	return nil
+}
+
+// Object returns the content of a stored object, and whether there is one
+// under the key in the bucket.
+func (s *MemoryImportStore) Object(bucket, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, ok := s.objects[bucket+"/"+key]
+	return body, ok
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// maxImportLineSize is the longest line an NDJSON import file may hold.
+const maxImportLineSize = 1 << 20
+
+// customFieldColumnPrefix starts the CSV import columns holding custom fields,
+// such as customFields.mileage.
+const customFieldColumnPrefix = "customFields."
+
+// importReader reads the rows of an import file one at a time.
+type importReader interface {
+	// next returns the next row and its number. A row that cannot be made
+	// into a labor line input is returned with the errors found in it instead.
+	// It returns io.EOF after the last row, and any other error if the rest
+	// of the file cannot be read.
+	next() (row models.ImportRow, number int, rowErrs []models.ImportRowError, err error)
+}
+
+// newImportReader creates the reader of an import file in the format. CSV
+// custom field values are converted to the types of the account's custom
+// field definitions.
+func newImportReader(r io.Reader, format models.ImportFormat, definitions []*models.CustomFieldDefinition) (importReader, error) {
+	if format == models.ImportFormatNDJSON {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineSize)
+		return &ndjsonImportReader{scanner: scanner}, nil
+	}
+	return newCSVImportReader(r, definitions)
+}
+
+// ndjsonImportReader reads a labor line input from each non-blank line.
+type ndjsonImportReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *ndjsonImportReader) next() (models.ImportRow, int, []models.ImportRowError, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		// Unknown fields are rejected, as a misspelt field would otherwise be lost
+		var row models.ImportRow
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row); err != nil {
+			return models.ImportRow{}, r.line, []models.ImportRowError{{Row: r.line, Message: fmt.Sprintf("invalid JSON: %v", err)}}, nil
+		}
+		return row, r.line, nil, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return models.ImportRow{}, r.line + 1, nil, fmt.Errorf("reading line %d: %w", r.line+1, err)
+	}
+	return models.ImportRow{}, 0, nil, io.EOF
+}
+
+// csvImportReader reads a labor line input from each row after the header
+// row, which names the field each column holds.
+type csvImportReader struct {
+	reader       *csv.Reader
+	columns      []string
+	customFields map[string]models.CustomFieldType
+	row          int
+}
+
+// newCSVImportReader reads the header row of a CSV import file and checks
+// that it names only fields a labor line can be imported with.
+func newCSVImportReader(r io.Reader, definitions []*models.CustomFieldDefinition) (*csvImportReader, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("import file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading header row: %w", err)
+	}
+
+	// Spreadsheets often save CSV files with a byte order mark
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	hasTaskID := false
+	for _, column := range header {
+		if !isImportColumn(column) {
+			return nil, fmt.Errorf("unknown column: %q", column)
+		}
+		hasTaskID = hasTaskID || column == "taskId"
+	}
+	if !hasTaskID {
+		return nil, errors.New("missing column: taskId")
+	}
+
+	customFields := make(map[string]models.CustomFieldType, len(definitions))
+	for _, definition := range definitions {
+		customFields[definition.Name] = definition.Type
+	}
+
+	return &csvImportReader{reader: reader, columns: header, customFields: customFields, row: 1}, nil
+}
+
+// isImportColumn reports whether a CSV import column names a field a labor
+// line can be imported with.
+func isImportColumn(column string) bool {
+	switch column {
+	case "accountId", "taskId", "description", "operationCode", "estimatedHours",
+		"rateType", "ratePerHour", "notes", "importKey":
+		return true
+	}
+	return strings.HasPrefix(column, customFieldColumnPrefix) && len(column) > len(customFieldColumnPrefix)
+}
+
+func (r *csvImportReader) next() (models.ImportRow, int, []models.ImportRowError, error) {
+	record, err := r.reader.Read()
+	if err == io.EOF {
+		return models.ImportRow{}, 0, nil, io.EOF
+	}
+	r.row++
+
+	// A row with the wrong number of fields is reported and skipped; any
+	// other error, such as a stray quote, leaves the rest of the file unreadable
+	if errors.Is(err, csv.ErrFieldCount) {
+		return models.ImportRow{}, r.row, []models.ImportRowError{{
+			Row:     r.row,
+			Message: fmt.Sprintf("row has %d fields but the header has %d", len(record), len(r.columns)),
+		}}, nil
+	}
+	if err != nil {
+		return models.ImportRow{}, r.row, nil, fmt.Errorf("reading row %d: %w", r.row, err)
+	}
+
+	var row models.ImportRow
+	var rowErrs []models.ImportRowError
+	for i, column := range r.columns {
+		value := record[i]
+		if value == "" {
+			continue
+		}
+		if err := r.setField(&row, column, value); err != nil {
+			rowErrs = append(rowErrs, models.ImportRowError{Row: r.row, Field: column, Message: err.Error()})
+		}
+	}
+	return row, r.row, rowErrs, nil
+}
+
+// setField sets the field of the row a CSV import column holds to a value.
+func (r *csvImportReader) setField(row *models.ImportRow, column, value string) error {
+	switch column {
+	case "accountId":
+		row.AccountID = value
+	case "taskId":
+		row.TaskID = value
+	case "description":
+		row.Description = value
+	case "operationCode":
+		row.OperationCode = value
+	case "rateType":
+		row.RateType = models.RateType(value)
+	case "importKey":
+		row.ImportKey = value
+	case "estimatedHours":
+		hours, err := models.ParseDecimal(value)
+		if err != nil {
+			return err
+		}
+		row.EstimatedHours = &hours
+	case "ratePerHour":
+		rate, err := models.ParseDecimal(value)
+		if err != nil {
+			return err
+		}
+		row.RatePerHour = &rate
+	case "notes":
+		// One note per line, as labor lines are exported
+		for _, body := range strings.Split(value, "\n") {
+			if body = strings.TrimSpace(body); body != "" {
+				row.Notes = append(row.Notes, models.NoteInput{Body: body})
+			}
+		}
+	default:
+		name := strings.TrimPrefix(column, customFieldColumnPrefix)
+		if row.CustomFields == nil {
+			row.CustomFields = map[string]interface{}{}
+		}
+		row.CustomFields[name] = r.customFieldValue(name, value)
+	}
+	return nil
+}
+
+// customFieldValue converts a CSV value to the type of the named custom field.
+// A value that does not convert is kept as a string, for validation to report.
+func (r *csvImportReader) customFieldValue(name, value string) interface{} {
+	switch r.customFields[name] {
+	case models.CustomFieldTypeNumber, models.CustomFieldTypeInteger:
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case models.CustomFieldTypeBoolean:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+func TestCSVImportReader(t *testing.T) {
+	definitions := []*models.CustomFieldDefinition{
+		{Name: "mileage", Type: models.CustomFieldTypeInteger},
+		{Name: "warranty", Type: models.CustomFieldTypeBoolean},
+	}
+	file := "\ufefftaskId,description,estimatedHours,ratePerHour,notes,importKey,customFields.mileage,customFields.warranty\r\n" +
+		"task-1,\"Brakes, front\",1.5,,\"Pads worn\nRotors OK\",wo-1,120500,true\r\n" +
+		"task-2,Oil change,lots,,,,12k,\r\n" +
+		"task-3,Too short\r\n"
+
+	reader, err := newImportReader(strings.NewReader(file), models.ImportFormatCSV, definitions)
+	require.NoError(t, err)
+
+	row, number, rowErrs, err := reader.next()
+	require.NoError(t, err)
+	assert.Equal(t, 2, number)
+	assert.Empty(t, rowErrs)
+	assert.Equal(t, "task-1", row.TaskID)
+	assert.Equal(t, "Brakes, front", row.Description)
+	assert.Equal(t, models.MustParseDecimal("1.5").String(), row.EstimatedHours.String())
+	assert.Nil(t, row.RatePerHour)
+	assert.Equal(t, []models.NoteInput{{Body: "Pads worn"}, {Body: "Rotors OK"}}, row.Notes)
+	assert.Equal(t, "wo-1", row.ImportKey)
+	assert.Equal(t, map[string]interface{}{"mileage": float64(120500), "warranty": true}, row.CustomFields)
+
+	// A value that does not convert is kept for validation to report
+	row, number, rowErrs, err = reader.next()
+	require.NoError(t, err)
+	assert.Equal(t, 3, number)
+	require.Len(t, rowErrs, 1)
+	assert.Equal(t, models.ImportRowError{Row: 3, Field: "estimatedHours", Message: rowErrs[0].Message}, rowErrs[0])
+	assert.Equal(t, "12k", row.CustomFields["mileage"])
+
+	_, number, rowErrs, err = reader.next()
+	require.NoError(t, err)
+	assert.Equal(t, 4, number)
+	assert.Equal(t, []models.ImportRowError{{Row: 4, Message: "row has 2 fields but the header has 8"}}, rowErrs)
+
+	_, _, _, err = reader.next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestCSVImportReader_InvalidHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		expected string
+	}{
+		{"empty file", "", "import file is empty"},
+		{"unknown column", "taskId,laborCost\r\n", `unknown column: "laborCost"`},
+		{"no task column", "description\r\n", "missing column: taskId"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newImportReader(strings.NewReader(tt.file), models.ImportFormatCSV, nil)
+			assert.EqualError(t, err, tt.expected)
+		})
+	}
+}
+
+func TestNDJSONImportReader(t *testing.T) {
+	file := `{"taskId":"task-1","estimatedHours":"2","importKey":"wo-1"}` + "\n" +
+		"\n" +
+		`{"taskId":"task-2","laborCost":"90"}` + "\n"
+
+	reader, err := newImportReader(strings.NewReader(file), models.ImportFormatNDJSON, nil)
+	require.NoError(t, err)
+
+	row, number, rowErrs, err := reader.next()
+	require.NoError(t, err)
+	assert.Equal(t, 1, number)
+	assert.Empty(t, rowErrs)
+	assert.Equal(t, "task-1", row.TaskID)
+	assert.Equal(t, "wo-1", row.ImportKey)
+
+	// Blank lines are skipped but still counted
+	_, number, rowErrs, err = reader.next()
+	require.NoError(t, err)
+	assert.Equal(t, 3, number)
+	require.Len(t, rowErrs, 1)
+	assert.Contains(t, rowErrs[0].Message, `unknown field "laborCost"`)
+
+	_, _, _, err = reader.next()
+	assert.Equal(t, io.EOF, err)
+}
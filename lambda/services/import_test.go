@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// newTestImporter creates an importer for an account with no custom fields.
+func newTestImporter(t *testing.T, client *MockDynamoDBClient) *LaborLineImporter {
+	t.Helper()
+
+	validationService, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+	client.On("Query", mock.Anything, mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
+	return NewLaborLineImporter(NewDynamoDBService(client, "test-table"), validationService, WithImportConcurrency(2))
+}
+
+// importCSV builds a CSV import file with a row per task, each billed hourly.
+func importCSV(taskIDs ...string) string {
+	var b strings.Builder
+	b.WriteString("taskId,description,rateType,ratePerHour,importKey\n")
+	for i, taskID := range taskIDs {
+		fmt.Fprintf(&b, "%s,Imported line,HOURLY,95,wo-%d\n", taskID, i)
+	}
+	return b.String()
+}
+
+// writtenLaborLineID returns the ID of the labor line a transaction creates.
+func writtenLaborLineID(input *dynamodb.TransactWriteItemsInput) string {
+	return input.TransactItems[0].Put.Item["laborLineId"].(*types.AttributeValueMemberS).Value
+}
+
+func TestLaborLineImporter_Import(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	importer := newTestImporter(t, client)
+
+	accountID, taskID := uuid.New().String(), uuid.New().String()
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	file := "taskId,accountId,estimatedHours,rateType,ratePerHour\n" +
+		taskID + ",,2,HOURLY,95\n" +
+		taskID + ",,two,HOURLY,95\n" +
+		"not-a-uuid,,1,HOURLY,95\n" +
+		taskID + "," + uuid.New().String() + ",1,HOURLY,95\n"
+
+	result, err := importer.Import(context.Background(), strings.NewReader(file), models.ImportLaborLinesInput{
+		AccountID: accountID,
+		Source:    "lines.csv",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "lines.csv", result.Source)
+	assert.Equal(t, 4, result.RowCount)
+	assert.Equal(t, 1, result.ValidCount)
+	assert.Equal(t, 1, result.CreatedCount)
+	assert.Equal(t, 3, result.FailedCount)
+	require.Len(t, result.Errors, 3)
+	assert.Equal(t, 3, result.Errors[0].Row)
+	assert.Equal(t, "estimatedHours", result.Errors[0].Field)
+	assert.Equal(t, 4, result.Errors[1].Row)
+	assert.Equal(t, "taskId", result.Errors[1].Field)
+	assert.Equal(t, models.ImportRowError{Row: 5, Field: "accountId", Message: "row belongs to another account"}, result.Errors[2])
+	client.AssertExpectations(t)
+}
+
+func TestLaborLineImporter_Import_DryRun(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	importer := newTestImporter(t, client)
+
+	result, err := importer.Import(context.Background(), strings.NewReader(importCSV(uuid.New().String(), uuid.New().String())), models.ImportLaborLinesInput{
+		AccountID: uuid.New().String(),
+		DryRun:    true,
+	})
+	require.NoError(t, err)
+
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 2, result.ValidCount)
+	assert.Zero(t, result.CreatedCount)
+	assert.Empty(t, result.Errors)
+	client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+}
+
+func TestLaborLineImporter_Import_Batches(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	importer := newTestImporter(t, client)
+
+	accountID := uuid.New().String()
+	taskIDs := make([]string, 2*models.MaxBatchSize+3)
+	for i := range taskIDs {
+		taskIDs[i] = uuid.New().String()
+	}
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	result, err := importer.Import(context.Background(), strings.NewReader(importCSV(taskIDs...)), models.ImportLaborLinesInput{AccountID: accountID})
+	require.NoError(t, err)
+
+	assert.Equal(t, len(taskIDs), result.CreatedCount)
+	assert.Zero(t, result.FailedCount)
+	client.AssertNumberOfCalls(t, "TransactWriteItems", len(taskIDs))
+}
+
+func TestLaborLineImporter_Import_Rerun(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	importer := newTestImporter(t, client)
+
+	accountID, taskID := uuid.New().String(), uuid.New().String()
+	file := importCSV(taskID, taskID)
+
+	// Both runs derive the same labor line IDs from the import keys
+	var firstRun []string
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		firstRun = append(firstRun, writtenLaborLineID(args.Get(1).(*dynamodb.TransactWriteItemsInput)))
+	}).Return(&dynamodb.TransactWriteItemsOutput{}, nil).Twice()
+	_, err := importer.Import(context.Background(), strings.NewReader(file), models.ImportLaborLinesInput{AccountID: accountID})
+	require.NoError(t, err)
+
+	// The second run finds the first labor line created and the second one deleted
+	existing, err := attributevalue.MarshalMap(&models.LaborLine{LaborLineID: firstRun[0], Version: 1})
+	require.NoError(t, err)
+	deletedAt := int64(1760000000)
+	deleted, err := attributevalue.MarshalMap(&models.LaborLine{LaborLineID: firstRun[1], Version: 2, DeletedAt: &deletedAt})
+	require.NoError(t, err)
+	var secondRun []string
+	client.On("Query", mock.Anything, mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
+	for _, item := range []map[string]types.AttributeValue{existing, deleted} {
+		client.On("TransactWriteItems", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			secondRun = append(secondRun, writtenLaborLineID(args.Get(1).(*dynamodb.TransactWriteItemsInput)))
+		}).Return((*dynamodb.TransactWriteItemsOutput)(nil), &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{{Code: aws.String("ConditionalCheckFailed"), Item: item}},
+		}).Once()
+	}
+
+	result, err := importer.Import(context.Background(), strings.NewReader(file), models.ImportLaborLinesInput{AccountID: accountID})
+	require.NoError(t, err)
+
+	assert.Equal(t, firstRun, secondRun)
+	assert.Zero(t, result.CreatedCount)
+	assert.Equal(t, 2, result.SkippedCount)
+	assert.Zero(t, result.FailedCount)
+}
+
+func TestLaborLineImporter_Import_DuplicateRows(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	importer := newTestImporter(t, client)
+
+	// Rows without an import key are identified by their content
+	taskID := uuid.New().String()
+	file := "taskId,rateType,ratePerHour\n" +
+		taskID + ",HOURLY,95\n" +
+		taskID + ",HOURLY,95\n"
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	result, err := importer.Import(context.Background(), strings.NewReader(file), models.ImportLaborLinesInput{AccountID: uuid.New().String()})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.CreatedCount)
+	assert.Equal(t, []models.ImportRowError{{Row: 3, Field: "importKey", Message: "duplicate of row 2"}}, result.Errors)
+	client.AssertExpectations(t)
+}
+
+func TestLaborLineImporter_Import_WriteError(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	importer := newTestImporter(t, client)
+
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Return((*dynamodb.TransactWriteItemsOutput)(nil), errors.New("throttled")).Once()
+
+	result, err := importer.Import(context.Background(), strings.NewReader(importCSV(uuid.New().String())), models.ImportLaborLinesInput{AccountID: uuid.New().String()})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, result.FailedCount)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 2, result.Errors[0].Row)
+	assert.Contains(t, result.Errors[0].Message, "failed to create labor line: ")
+}
+
+func TestLaborLineImporter_Import_UnreadableFile(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	importer := newTestImporter(t, client)
+
+	result, err := importer.Import(context.Background(), strings.NewReader("taskId,cost\n"), models.ImportLaborLinesInput{AccountID: uuid.New().String()})
+	assert.Nil(t, result)
+	assert.EqualError(t, err, `unknown column: "cost"`)
+}
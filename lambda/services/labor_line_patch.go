@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/schemas"
+)
+
+// updateExpression collects the SET and REMOVE actions of a DynamoDB update
+// expression, with a placeholder for every attribute name and value.
+type updateExpression struct {
+	sets    []string
+	removes []string
+	names   map[string]string
+	values  map[string]types.AttributeValue
+}
+
+// set adds an action setting the attribute to value.
+func (e *updateExpression) set(attribute string, value interface{}) error {
+	av, err := attributevalue.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("marshaling %s: %w", attribute, err)
+	}
+	e.names["#"+attribute] = attribute
+	e.values[":"+attribute] = av
+	e.sets = append(e.sets, fmt.Sprintf("#%s = :%s", attribute, attribute))
+	return nil
+}
+
+// remove adds an action removing the attribute.
+func (e *updateExpression) remove(attribute string) {
+	e.names["#"+attribute] = attribute
+	e.removes = append(e.removes, "#"+attribute)
+}
+
+// setOrRemove sets the attribute, or removes it if empty is true, matching how
+// a put of the whole item omits empty attributes.
+func (e *updateExpression) setOrRemove(attribute string, value interface{}, empty bool) error {
+	if empty {
+		e.remove(attribute)
+		return nil
+	}
+	return e.set(attribute, value)
+}
+
+// String returns the update expression.
+func (e *updateExpression) String() string {
+	var clauses []string
+	if len(e.sets) > 0 {
+		clauses = append(clauses, "SET "+strings.Join(e.sets, ", "))
+	}
+	if len(e.removes) > 0 {
+		clauses = append(clauses, "REMOVE "+strings.Join(e.removes, ", "))
+	}
+	return strings.Join(clauses, " ")
+}
+
+// patchWrite builds a versioned write that changes only the attributes the
+// input updates, along with the version, timestamp and cost, leaving the rest
+// of the stored item as it is unless it needs migrating to the current schema. updated is the labor line the input produces
+// from the stored one at readVersion.
+func patchWrite(input models.UpdateLaborLineInput, updated *models.LaborLine, readVersion int64) (laborLineWrite, error) {
+	write := versionedWrite(updated, readVersion)
+	if write.values == nil {
+		write.values = map[string]types.AttributeValue{}
+	}
+	expr := &updateExpression{names: write.names, values: write.values}
+
+	var err error
+	set := func(attribute string, value interface{}) {
+		if err == nil {
+			err = expr.set(attribute, value)
+		}
+	}
+	setOrRemove := func(attribute string, value interface{}, empty bool) {
+		if err == nil {
+			err = expr.setOrRemove(attribute, value, empty)
+		}
+	}
+
+	// An item written with an older schema is migrated as it is patched: its
+	// notes and parts, as read, are written back in their current form
+	migrate := updated.SchemaVersion < schemas.CurrentVersion
+	if migrate {
+		updated.SchemaVersion = schemas.CurrentVersion
+		set("schemaVersion", updated.SchemaVersion)
+		setOrRemove("notes", updated.Notes, len(updated.Notes) == 0)
+	}
+	if input.Parts.Set || migrate {
+		setOrRemove("parts", updated.Parts, len(updated.Parts) == 0)
+		// Part IDs stored before parts were line items are replaced too
+		expr.remove("partId")
+	}
+	if input.Description.Set {
+		setOrRemove("description", updated.Description, updated.Description == "")
+	}
+	if input.CustomFields.Set {
+		setOrRemove("customFields", updated.CustomFields, len(updated.CustomFields) == 0)
+	}
+	if input.EstimatedHours.Set {
+		set("estimatedHours", updated.EstimatedHours)
+	}
+	if input.RateType.Set || input.RatePerHour.Set {
+		set("rateType", updated.RateType)
+		set("ratePerHour", updated.RatePerHour)
+	}
+	set("laborCost", updated.LaborCost)
+	set("updatedAt", updated.UpdatedAt)
+	set("version", updated.Version)
+	if err != nil {
+		return laborLineWrite{}, err
+	}
+
+	write.update = expr.String()
+	return write, nil
+}
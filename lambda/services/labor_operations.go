@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// CreateLaborOperation adds an operation to an account's labor operation
+// catalog. A code that is already in the catalog is rejected with
+// ErrLaborOperationExists.
+func (s *dynamoDBService) CreateLaborOperation(ctx context.Context, operation *models.LaborOperation) error {
+	err := s.putLaborOperation(ctx, operation, "attribute_not_exists(PK)")
+	var condErr *types.ConditionalCheckFailedException
+	if errors.As(err, &condErr) {
+		return ErrLaborOperationExists
+	}
+	return err
+}
+
+// GetLaborOperation retrieves one of an account's labor operations, returning
+// nil if the code is not in its catalog.
+func (s *dynamoDBService) GetLaborOperation(ctx context.Context, accountID, code string) (*models.LaborOperation, error) {
+	pk, sk := models.LaborOperationKey(accountID, code)
+
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting labor operation from DynamoDB: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, nil // Not found
+	}
+
+	var operation models.LaborOperation
+	if err := attributevalue.UnmarshalMap(result.Item, &operation); err != nil {
+		return nil, fmt.Errorf("unmarshaling labor operation: %w", err)
+	}
+
+	return &operation, nil
+}
+
+// UpdateLaborOperation applies a partial update to one of an account's labor
+// operations. Labor lines already created from the operation are unaffected.
+func (s *dynamoDBService) UpdateLaborOperation(ctx context.Context, input models.UpdateLaborOperationInput) (*models.LaborOperation, error) {
+	existing, err := s.GetLaborOperation(ctx, input.AccountID, input.Code)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrLaborOperationNotFound
+	}
+
+	updated := input.ApplyTo(existing)
+	err = s.putLaborOperation(ctx, updated, "attribute_exists(PK)")
+	var condErr *types.ConditionalCheckFailedException
+	if errors.As(err, &condErr) {
+		// The operation was deleted after it was read
+		return nil, ErrLaborOperationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// DeleteLaborOperation removes an operation from an account's labor operation
+// catalog. Labor lines created from it keep its code and what they copied.
+func (s *dynamoDBService) DeleteLaborOperation(ctx context.Context, input models.DeleteLaborOperationInput) error {
+	pk, sk := models.LaborOperationKey(input.AccountID, input.Code)
+
+	_, err := s.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+		ConditionExpression: aws.String("attribute_exists(PK)"),
+	})
+	var condErr *types.ConditionalCheckFailedException
+	if errors.As(err, &condErr) {
+		return ErrLaborOperationNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("deleting labor operation from DynamoDB: %w", err)
+	}
+
+	return nil
+}
+
+// SearchLaborOperations retrieves a page of an account's labor operations in
+// code order. A code prefix narrows the key condition; a keyword is matched
+// against the code and description, ignoring case. Inactive operations are
+// left out unless they are asked for.
+func (s *dynamoDBService) SearchLaborOperations(ctx context.Context, input models.SearchLaborOperationsInput) (*models.LaborOperationConnection, error) {
+	pk, skPrefix := models.LaborOperationKeyPrefix(input.AccountID, input.CodePrefix)
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(s.tableName),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :skPrefix)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":       &types.AttributeValueMemberS{Value: pk},
+			":skPrefix": &types.AttributeValueMemberS{Value: skPrefix},
+		},
+	}
+
+	var filters []string
+	if !input.IncludeInactive {
+		filters = append(filters, "active = :active")
+		queryInput.ExpressionAttributeValues[":active"] = &types.AttributeValueMemberBOOL{Value: true}
+	}
+	if input.Keyword != "" {
+		filters = append(filters, "contains(searchText, :keyword)")
+		queryInput.ExpressionAttributeValues[":keyword"] = &types.AttributeValueMemberS{Value: strings.ToLower(input.Keyword)}
+	}
+	if len(filters) > 0 {
+		queryInput.FilterExpression = aws.String(strings.Join(filters, " AND "))
+	}
+
+	// The token is bound to the search, so it cannot resume a different prefix or keyword
+	scope := fmt.Sprintf("operations#%s#%s#%s#%t", pk, skPrefix, strings.ToLower(input.Keyword), input.IncludeInactive)
+	items, nextToken, err := s.queryPage(ctx, queryInput, scope, input.Limit, input.NextToken)
+	if err != nil {
+		return nil, fmt.Errorf("querying labor operations from DynamoDB: %w", err)
+	}
+
+	operations := make([]*models.LaborOperation, 0, len(items))
+	for _, item := range items {
+		var operation models.LaborOperation
+		if err := attributevalue.UnmarshalMap(item, &operation); err != nil {
+			return nil, fmt.Errorf("unmarshaling labor operation: %w", err)
+		}
+		operations = append(operations, &operation)
+	}
+
+	return &models.LaborOperationConnection{
+		Items:     operations,
+		NextToken: nextToken,
+	}, nil
+}
+
+// putLaborOperation writes a labor operation under the given condition.
+func (s *dynamoDBService) putLaborOperation(ctx context.Context, operation *models.LaborOperation, condition string) error {
+	item, err := attributevalue.MarshalMap(operation)
+	if err != nil {
+		return fmt.Errorf("marshaling labor operation: %w", err)
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(s.tableName),
+		Item:                item,
+		ConditionExpression: aws.String(condition),
+	})
+	if err != nil {
+		return fmt.Errorf("putting labor operation in DynamoDB: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// newLaborOperation builds an active labor operation for the account.
+func newLaborOperation(accountID, code, description string) *models.LaborOperation {
+	return models.CreateLaborOperationInput{
+		AccountID:   accountID,
+		Code:        code,
+		Description: description,
+		BookHours:   models.MustParseDecimal("1.5"),
+	}.ToLaborOperation()
+}
+
+func TestDynamoDBService_CreateLaborOperation_Exists(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	client.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+		return *input.ConditionExpression == "attribute_not_exists(PK)" &&
+			input.Item["SK"].(*types.AttributeValueMemberS).Value == "LABOROP#013-001-001"
+	})).Return((*dynamodb.PutItemOutput)(nil), &types.ConditionalCheckFailedException{})
+
+	err := service.CreateLaborOperation(context.Background(), newLaborOperation("acct-1", "013-001-001", "Replace front brake pads"))
+	assert.ErrorIs(t, err, ErrLaborOperationExists)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_UpdateLaborOperation(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	stored := newLaborOperation("acct-1", "013-001-001", "Replace front brake pads")
+	item, err := attributevalue.MarshalMap(stored)
+	require.NoError(t, err)
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	var written *dynamodb.PutItemInput
+	client.On("PutItem", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { written = args.Get(1).(*dynamodb.PutItemInput) }).
+		Return(&dynamodb.PutItemOutput{}, nil)
+
+	updated, err := service.UpdateLaborOperation(context.Background(), models.UpdateLaborOperationInput{
+		AccountID:   "acct-1",
+		Code:        "013-001-001",
+		Description: models.NewNullable("Replace rear brake pads"),
+		Active:      models.NewNullable(false),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Replace rear brake pads", updated.Description)
+	assert.False(t, updated.Active)
+	assert.True(t, stored.BookHours.Equal(updated.BookHours))
+
+	// The search text follows the description
+	require.NotNil(t, written)
+	assert.Equal(t, "attribute_exists(PK)", *written.ConditionExpression)
+	assert.Equal(t, "013-001-001 replace rear brake pads", written.Item["searchText"].(*types.AttributeValueMemberS).Value)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_UpdateLaborOperation_NotFound(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{}, nil)
+
+	_, err := service.UpdateLaborOperation(context.Background(), models.UpdateLaborOperationInput{
+		AccountID: "acct-1",
+		Code:      "013-001-001",
+		Active:    models.NewNullable(false),
+	})
+	assert.ErrorIs(t, err, ErrLaborOperationNotFound)
+
+	client.AssertNotCalled(t, "PutItem", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_DeleteLaborOperation_NotFound(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	client.On("DeleteItem", mock.Anything, mock.Anything).Return((*dynamodb.DeleteItemOutput)(nil), &types.ConditionalCheckFailedException{})
+
+	err := service.DeleteLaborOperation(context.Background(), models.DeleteLaborOperationInput{AccountID: "acct-1", Code: "013-001-001"})
+	assert.ErrorIs(t, err, ErrLaborOperationNotFound)
+}
+
+func TestDynamoDBService_SearchLaborOperations(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          models.SearchLaborOperationsInput
+		expectedPrefix string
+		expectedFilter *string
+	}{
+		{
+			name:           "code prefix",
+			input:          models.SearchLaborOperationsInput{AccountID: "acct-1", CodePrefix: "013-"},
+			expectedPrefix: "LABOROP#013-",
+			expectedFilter: aws.String("active = :active"),
+		},
+		{
+			name:           "keyword ignoring case",
+			input:          models.SearchLaborOperationsInput{AccountID: "acct-1", Keyword: "Brake"},
+			expectedPrefix: "LABOROP#",
+			expectedFilter: aws.String("active = :active AND contains(searchText, :keyword)"),
+		},
+		{
+			name:           "including inactive",
+			input:          models.SearchLaborOperationsInput{AccountID: "acct-1", IncludeInactive: true},
+			expectedPrefix: "LABOROP#",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &MockDynamoDBClient{}
+			service := NewDynamoDBService(client, "test-table")
+
+			item, err := attributevalue.MarshalMap(newLaborOperation("acct-1", "013-001-001", "Replace front brake pads"))
+			require.NoError(t, err)
+			client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+				prefix := input.ExpressionAttributeValues[":skPrefix"].(*types.AttributeValueMemberS)
+				if keyword, ok := input.ExpressionAttributeValues[":keyword"]; ok && keyword.(*types.AttributeValueMemberS).Value != "brake" {
+					return false
+				}
+				return prefix.Value == tt.expectedPrefix && assert.ObjectsAreEqual(tt.expectedFilter, input.FilterExpression)
+			})).Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil)
+
+			page, err := service.SearchLaborOperations(context.Background(), tt.input)
+			require.NoError(t, err)
+			require.Len(t, page.Items, 1)
+			assert.Equal(t, "013-001-001", page.Items[0].Code)
+			assert.Nil(t, page.NextToken)
+
+			client.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// AddLaborLineNote adds a note written by actor to a labor line.
+func (s *dynamoDBService) AddLaborLineNote(ctx context.Context, input models.AddLaborLineNoteInput, actor string) (*models.LaborLine, error) {
+	key := models.GetLaborLineInput{AccountID: input.AccountID, TaskID: input.TaskID, LaborLineID: input.LaborLineID}
+	return s.changeNotes(ctx, key, input.ExpectedVersion, models.ActionNoteAdd, actor, func(notes []models.Note) ([]models.Note, error) {
+		return append(notes, models.NewNote(actor, input.Visibility, input.Body)), nil
+	})
+}
+
+// EditLaborLineNote changes the body or visibility of one of a labor line's
+// notes. The note keeps its author.
+func (s *dynamoDBService) EditLaborLineNote(ctx context.Context, input models.EditLaborLineNoteInput, actor string) (*models.LaborLine, error) {
+	key := models.GetLaborLineInput{AccountID: input.AccountID, TaskID: input.TaskID, LaborLineID: input.LaborLineID}
+	return s.changeNotes(ctx, key, input.ExpectedVersion, models.ActionNoteEdit, actor, func(notes []models.Note) ([]models.Note, error) {
+		i := slices.IndexFunc(notes, func(note models.Note) bool { return note.NoteID == input.NoteID })
+		if i < 0 {
+			return nil, ErrNoteNotFound
+		}
+		if input.Body != "" {
+			notes[i].Body = input.Body
+		}
+		if input.Visibility != "" {
+			notes[i].Visibility = input.Visibility
+		}
+		notes[i].UpdatedAt = time.Now().Unix()
+		return notes, nil
+	})
+}
+
+// DeleteLaborLineNote removes one of a labor line's notes.
+func (s *dynamoDBService) DeleteLaborLineNote(ctx context.Context, input models.DeleteLaborLineNoteInput, actor string) (*models.LaborLine, error) {
+	key := models.GetLaborLineInput{AccountID: input.AccountID, TaskID: input.TaskID, LaborLineID: input.LaborLineID}
+	return s.changeNotes(ctx, key, input.ExpectedVersion, models.ActionNoteDelete, actor, func(notes []models.Note) ([]models.Note, error) {
+		i := slices.IndexFunc(notes, func(note models.Note) bool { return note.NoteID == input.NoteID })
+		if i < 0 {
+			return nil, ErrNoteNotFound
+		}
+		return slices.Delete(notes, i, i+1), nil
+	})
+}
+
+// changeNotes applies change to a copy of the labor line's notes and writes
+// the labor line back, recording the change in its history. The write is
+// conditioned on the version that was read, so concurrent note changes cannot
+// be lost. Notes read in their legacy form are written back structured.
+func (s *dynamoDBService) changeNotes(ctx context.Context, key models.GetLaborLineInput, expectedVersion *int64, action models.ChangeAction, actor string, change func([]models.Note) ([]models.Note, error)) (*models.LaborLine, error) {
+	existing, err := s.GetLaborLine(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("checking existing labor line: %w", err)
+	}
+	if existing == nil {
+		return nil, ErrLaborLineNotFound
+	}
+	if expectedVersion != nil && *expectedVersion != existing.Version {
+		return nil, &ConflictError{CurrentVersion: existing.Version}
+	}
+	if existing.IsReadOnly() {
+		return nil, ErrLaborLineReadOnly
+	}
+
+	notes, err := change(slices.Clone(existing.Notes))
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *existing
+	updated.Notes = notes
+	updated.UpdatedAt = time.Now().Unix()
+	updated.Version = existing.Version + 1
+
+	err = s.writeWithHistory(ctx, versionedWrite(&updated, existing.Version), action, actor, existing)
+	if err != nil {
+		if condErr := transactionConditionFailure(err, 0); condErr != nil {
+			return nil, condErr
+		}
+		return nil, fmt.Errorf("writing labor line notes to DynamoDB: %w", err)
+	}
+
+	return &updated, nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/schemas"
+)
+
+// onGetLegacyLaborLine mocks the read of a labor line written with schema
+// version 1, whose notes are plain strings.
+func onGetLegacyLaborLine(client *MockDynamoDBClient, laborLine *models.LaborLine, notes ...string) {
+	item, _ := attributevalue.MarshalMap(laborLine)
+	list := make([]types.AttributeValue, len(notes))
+	for i, note := range notes {
+		list[i] = &types.AttributeValueMemberS{Value: note}
+	}
+	item["notes"] = &types.AttributeValueMemberL{Value: list}
+	item["schemaVersion"] = &types.AttributeValueMemberN{Value: "1"}
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: item}, nil)
+}
+
+func TestDynamoDBService_AddLaborLineNote(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	validationService, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+	service := NewDynamoDBService(client, "test-table", WithLaborLineValidator(validationService))
+
+	stored := newBatchLaborLine(uuid.New().String())
+	stored.CreatedBy = "user-1"
+	onGetLegacyLaborLine(client, stored, "Replace worn brake pads")
+
+	var written *dynamodb.TransactWriteItemsInput
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { written = args.Get(1).(*dynamodb.TransactWriteItemsInput) }).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	updated, err := service.AddLaborLineNote(context.Background(), models.AddLaborLineNoteInput{
+		AccountID:   stored.AccountID,
+		TaskID:      stored.TaskID,
+		LaborLineID: stored.LaborLineID,
+		Visibility:  models.NoteVisibilityCustomer,
+		Body:        "Rotors are scored",
+	}, "user-123")
+	require.NoError(t, err)
+
+	// The legacy note is migrated and the new note follows it
+	require.Len(t, updated.Notes, 2)
+	assert.Equal(t, "user-1", updated.Notes[0].Author)
+	assert.Equal(t, "Replace worn brake pads", updated.Notes[0].Body)
+	assert.Equal(t, "user-123", updated.Notes[1].Author)
+	assert.Equal(t, models.NoteVisibilityCustomer, updated.Notes[1].Visibility)
+	assert.Equal(t, stored.Version+1, updated.Version)
+
+	// The whole labor line is written back at the current schema version
+	require.NotNil(t, written)
+	var item models.LaborLine
+	require.NoError(t, attributevalue.UnmarshalMap(laborLinePut(written).Item, &item))
+	assert.Equal(t, schemas.CurrentVersion, item.SchemaVersion)
+	assert.Equal(t, updated.Notes, item.Notes)
+
+	var record models.HistoryRecord
+	require.NoError(t, attributevalue.UnmarshalMap(historyPut(written).Item, &record))
+	assert.Equal(t, models.ActionNoteAdd, record.Action)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_EditLaborLineNote(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	stored := newBatchLaborLine(uuid.New().String())
+	note := models.NewNote("user-1", models.NoteVisibilityInternal, "Replace worn brake pads")
+	stored.Notes = []models.Note{note}
+	onGetLaborLine(client, stored)
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	updated, err := service.EditLaborLineNote(context.Background(), models.EditLaborLineNoteInput{
+		AccountID:   stored.AccountID,
+		TaskID:      stored.TaskID,
+		LaborLineID: stored.LaborLineID,
+		NoteID:      note.NoteID,
+		Visibility:  models.NoteVisibilityCustomer,
+	}, "user-123")
+	require.NoError(t, err)
+
+	// Only the visibility changes; the note keeps its author and body
+	require.Len(t, updated.Notes, 1)
+	edited := updated.Notes[0]
+	assert.Equal(t, models.NoteVisibilityCustomer, edited.Visibility)
+	assert.Equal(t, note.Body, edited.Body)
+	assert.Equal(t, "user-1", edited.Author)
+	assert.NotZero(t, edited.UpdatedAt)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_DeleteLaborLineNote(t *testing.T) {
+	stored := newBatchLaborLine(uuid.New().String())
+	kept := models.NewNote("user-1", models.NoteVisibilityInternal, "Replace worn brake pads")
+	deleted := models.NewNote("user-1", models.NoteVisibilityInternal, "Check brake fluid level")
+	stored.Notes = []models.Note{kept, deleted}
+
+	tests := []struct {
+		name    string
+		noteID  string
+		wantErr error
+	}{
+		{name: "Existing note", noteID: deleted.NoteID},
+		{name: "Unknown note", noteID: uuid.New().String(), wantErr: ErrNoteNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &MockDynamoDBClient{}
+			service := NewDynamoDBService(client, "test-table")
+
+			onGetLaborLine(client, stored)
+			if tt.wantErr == nil {
+				client.On("TransactWriteItems", mock.Anything, mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+			}
+
+			updated, err := service.DeleteLaborLineNote(context.Background(), models.DeleteLaborLineNoteInput{
+				AccountID:   stored.AccountID,
+				TaskID:      stored.TaskID,
+				LaborLineID: stored.LaborLineID,
+				NoteID:      tt.noteID,
+			}, "user-123")
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []models.Note{kept}, updated.Notes)
+			client.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// defaultPageSize is used when a list request does not specify a limit.
+	defaultPageSize int32 = 50
+	// maxPageSize caps the number of items returned in a single page.
+	maxPageSize int32 = 100
+)
+
+// ErrInvalidNextToken is returned when a pagination token is malformed, has been
+// tampered with, or was issued for a different query scope.
+var ErrInvalidNextToken = errors.New("invalid next token")
+
+// pageTokenCodec encodes DynamoDB LastEvaluatedKey values into opaque, signed
+// pagination tokens and decodes them back.
+//
+// Tokens are bound to a scope (for example the account being listed) so that a
+// token issued for one account cannot be replayed against another.
+type pageTokenCodec struct {
+	secret []byte
+}
+
+// pageTokenPayload is the signed content of a pagination token.
+type pageTokenPayload struct {
+	Scope string                        `json:"s"`
+	Key   map[string]pageTokenAttribute `json:"k"`
+}
+
+// pageTokenAttribute is a key attribute held in a pagination token. Index
+// keys may be numbers or binary as well as strings, so each keeps its type.
+type pageTokenAttribute struct {
+	Type  string `json:"t"` // S, N or B
+	Value string `json:"v"` // Binary values are base64 encoded
+}
+
+// newPageTokenCodec creates a codec that signs tokens with the given secret.
+// If the secret is empty a random one is generated, which means tokens are
+// only valid for the lifetime of the process.
+func newPageTokenCodec(secret []byte) *pageTokenCodec {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		_, _ = rand.Read(secret) // crypto/rand.Read never returns an error
+	}
+
+	return &pageTokenCodec{secret: secret}
+}
+
+// encode converts a LastEvaluatedKey into a signed token. An empty key yields an
+// empty token, meaning there are no more pages.
+func (c *pageTokenCodec) encode(scope string, key map[string]types.AttributeValue) (string, error) {
+	if len(key) == 0 {
+		return "", nil
+	}
+
+	payload := pageTokenPayload{
+		Scope: scope,
+		Key:   make(map[string]pageTokenAttribute, len(key)),
+	}
+	for name, value := range key {
+		switch v := value.(type) {
+		case *types.AttributeValueMemberS:
+			payload.Key[name] = pageTokenAttribute{Type: "S", Value: v.Value}
+		case *types.AttributeValueMemberN:
+			payload.Key[name] = pageTokenAttribute{Type: "N", Value: v.Value}
+		case *types.AttributeValueMemberB:
+			payload.Key[name] = pageTokenAttribute{Type: "B", Value: base64.StdEncoding.EncodeToString(v.Value)}
+		default:
+			return "", fmt.Errorf("unsupported key attribute type for %s", name)
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("marshaling page token: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(c.sign(encoded)), nil
+}
+
+// decode verifies a token and returns the ExclusiveStartKey it represents. An
+// empty token yields a nil key, meaning the first page.
+func (c *pageTokenCodec) decode(scope string, token string) (map[string]types.AttributeValue, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, ErrInvalidNextToken
+	}
+
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, c.sign(encoded)) {
+		return nil, ErrInvalidNextToken
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidNextToken
+	}
+
+	var payload pageTokenPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, ErrInvalidNextToken
+	}
+	if payload.Scope != scope || len(payload.Key) == 0 {
+		return nil, ErrInvalidNextToken
+	}
+
+	key := make(map[string]types.AttributeValue, len(payload.Key))
+	for name, value := range payload.Key {
+		switch value.Type {
+		case "S":
+			key[name] = &types.AttributeValueMemberS{Value: value.Value}
+		case "N":
+			key[name] = &types.AttributeValueMemberN{Value: value.Value}
+		case "B":
+			b, err := base64.StdEncoding.DecodeString(value.Value)
+			if err != nil {
+				return nil, ErrInvalidNextToken
+			}
+			key[name] = &types.AttributeValueMemberB{Value: b}
+		default:
+			return nil, ErrInvalidNextToken
+		}
+	}
+
+	return key, nil
+}
+
+// sign computes the HMAC-SHA256 signature of the encoded payload.
+func (c *pageTokenCodec) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// normalizePageSize applies the default and maximum page sizes to a requested limit.
+func normalizePageSize(limit int32) int32 {
+	if limit <= 0 {
+		return defaultPageSize
+	}
+	if limit > maxPageSize {
+		return maxPageSize
+	}
+	return limit
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageTokenCodec_RoundTrip(t *testing.T) {
+	codec := newPageTokenCodec([]byte("test-secret"))
+
+	key := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: "account"},
+		"SK": &types.AttributeValueMemberS{Value: "task#line"},
+	}
+
+	token, err := codec.encode("account", key)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	decoded, err := codec.decode("account", token)
+	require.NoError(t, err)
+	assert.Equal(t, key, decoded)
+}
+
+func TestPageTokenCodec_RoundTrip_IndexKeyTypes(t *testing.T) {
+	codec := newPageTokenCodec([]byte("test-secret"))
+
+	// Index keys carry the index's own key attributes, which need not be strings
+	key := map[string]types.AttributeValue{
+		"PK":            &types.AttributeValueMemberS{Value: "account"},
+		"SK":            &types.AttributeValueMemberS{Value: "task#line#ASSIGNMENT#tech-1"},
+		"technicianKey": &types.AttributeValueMemberS{Value: "account#tech-1"},
+		"assignedAt":    &types.AttributeValueMemberN{Value: "1760000000"},
+		"checksum":      &types.AttributeValueMemberB{Value: []byte{0x00, 0xff, 0x10}},
+	}
+
+	token, err := codec.encode("account", key)
+	require.NoError(t, err)
+
+	decoded, err := codec.decode("account", token)
+	require.NoError(t, err)
+	assert.Equal(t, key, decoded)
+}
+
+func TestPageTokenCodec_Encode_UnsupportedType(t *testing.T) {
+	codec := newPageTokenCodec([]byte("test-secret"))
+
+	_, err := codec.encode("account", map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberBOOL{Value: true},
+	})
+	assert.Error(t, err)
+}
+
+func TestPageTokenCodec_EmptyValues(t *testing.T) {
+	codec := newPageTokenCodec(nil)
+
+	token, err := codec.encode("account", nil)
+	require.NoError(t, err)
+	assert.Empty(t, token)
+
+	key, err := codec.decode("account", "")
+	require.NoError(t, err)
+	assert.Nil(t, key)
+}
+
+func TestPageTokenCodec_Decode_Invalid(t *testing.T) {
+	codec := newPageTokenCodec([]byte("test-secret"))
+
+	token, err := codec.encode("account", map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: "account"},
+		"SK": &types.AttributeValueMemberS{Value: "task#line"},
+	})
+	require.NoError(t, err)
+
+	payload, signature, _ := strings.Cut(token, ".")
+
+	tests := []struct {
+		name  string
+		codec *pageTokenCodec
+		scope string
+		token string
+	}{
+		{
+			name:  "Garbage token",
+			codec: codec,
+			scope: "account",
+			token: "not-a-token",
+		},
+		{
+			name:  "Tampered payload",
+			codec: codec,
+			scope: "account",
+			token: payload + "x." + signature,
+		},
+		{
+			name:  "Different scope",
+			codec: codec,
+			scope: "other-account",
+			token: token,
+		},
+		{
+			name:  "Different secret",
+			codec: newPageTokenCodec([]byte("other-secret")),
+			scope: "account",
+			token: token,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.codec.decode(tt.scope, tt.token)
+			assert.ErrorIs(t, err, ErrInvalidNextToken)
+		})
+	}
+}
+
+func TestNormalizePageSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int32
+		expected int32
+	}{
+		{name: "Default when unset", limit: 0, expected: defaultPageSize},
+		{name: "Default when negative", limit: -5, expected: defaultPageSize},
+		{name: "Within range", limit: 25, expected: 25},
+		{name: "Capped at maximum", limit: 1000, expected: maxPageSize},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, normalizePageSize(tt.limit))
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// PartsCatalog is the parts inventory labor lines draw their parts from. It
+// confirms that parts exist and holds stock for the labor lines that use them.
+// Stock is reserved by the API function and released by it and by the stream
+// function, so reservations must be kept where both see them.
+type PartsCatalog interface {
+	// GetPart returns one of an account's parts, or ErrPartNotFound.
+	GetPart(ctx context.Context, accountID, partID string) (*models.CatalogPart, error)
+
+	// ReserveParts sets the stock held for a labor line to the quantities of
+	// its parts that have not been returned, replacing whatever was held for it
+	// before, so reserving the same parts again changes nothing. If a part is
+	// short it returns an *InsufficientStockError and the previous reservation
+	// stands.
+	ReserveParts(ctx context.Context, accountID, laborLineID string, parts []models.PartLineItem) error
+
+	// ReleaseParts releases all stock held for a labor line.
+	ReleaseParts(ctx context.Context, accountID, laborLineID string) error
+}
+
+// MemoryPartsCatalog is a PartsCatalog held in memory. Its reservations are
+// only seen by its own process, so it serves tests, and as the PartsSource of
+// a DynamoDBPartsCatalog it supplies the parts for local runs.
+type MemoryPartsCatalog struct {
+	mu    sync.Mutex
+	parts map[string]models.CatalogPart
+
+	// reservations holds the quantity of each part reserved for each labor line
+	reservations map[string]map[string]models.Decimal
+}
+
+// NewMemoryPartsCatalog creates an in-memory parts catalog stocked with the given parts.
+func NewMemoryPartsCatalog(parts ...models.CatalogPart) *MemoryPartsCatalog {
+	c := &MemoryPartsCatalog{
+		parts:        make(map[string]models.CatalogPart, len(parts)),
+		reservations: map[string]map[string]models.Decimal{},
+	}
+	for _, part := range parts {
+		c.parts[catalogKey(part.AccountID, part.PartID)] = part
+	}
+	return c
+}
+
+// LoadMemoryPartsCatalog creates an in-memory parts catalog stocked with the
+// parts listed in a JSON file.
+func LoadMemoryPartsCatalog(path string) (*MemoryPartsCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading parts catalog: %w", err)
+	}
+
+	var parts []models.CatalogPart
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return nil, fmt.Errorf("decoding parts catalog: %w", err)
+	}
+	return NewMemoryPartsCatalog(parts...), nil
+}
+
+// catalogKey identifies an account's part or labor line within the catalog.
+func catalogKey(accountID, id string) string {
+	return accountID + "#" + id
+}
+
+// GetPart returns one of an account's parts, or ErrPartNotFound.
+func (c *MemoryPartsCatalog) GetPart(_ context.Context, accountID, partID string) (*models.CatalogPart, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	part, ok := c.parts[catalogKey(accountID, partID)]
+	if !ok {
+		return nil, fmt.Errorf("part %s: %w", partID, ErrPartNotFound)
+	}
+	return &part, nil
+}
+
+// ReserveParts sets the stock held for a labor line to the quantities of its
+// parts that have not been returned.
+func (c *MemoryPartsCatalog) ReserveParts(_ context.Context, accountID, laborLineID string, parts []models.PartLineItem) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lineKey := catalogKey(accountID, laborLineID)
+	held := models.StockHeld(parts)
+	partIDs := slices.Sorted(maps.Keys(held))
+
+	requested := make(map[string]models.Decimal, len(held))
+	for _, partID := range partIDs {
+		key := catalogKey(accountID, partID)
+		part, ok := c.parts[key]
+		if !ok {
+			return fmt.Errorf("part %s: %w", partID, ErrPartNotFound)
+		}
+		available := part.QuantityOnHand.Sub(c.reservedLocked(key, lineKey))
+		if held[partID].Cmp(available) > 0 {
+			return &InsufficientStockError{PartID: partID, Requested: held[partID], Available: available}
+		}
+		requested[key] = held[partID]
+	}
+
+	if len(requested) == 0 {
+		delete(c.reservations, lineKey)
+		return nil
+	}
+	c.reservations[lineKey] = requested
+	return nil
+}
+
+// ReleaseParts releases all stock held for a labor line.
+func (c *MemoryPartsCatalog) ReleaseParts(_ context.Context, accountID, laborLineID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.reservations, catalogKey(accountID, laborLineID))
+	return nil
+}
+
+// Reserved returns the quantity of one of an account's parts held across all labor lines.
+func (c *MemoryPartsCatalog) Reserved(accountID, partID string) models.Decimal {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.reservedLocked(catalogKey(accountID, partID), "")
+}
+
+// reservedLocked returns the quantity of a part held for labor lines other
+// than the excluded one. The caller must hold the lock.
+func (c *MemoryPartsCatalog) reservedLocked(partKey, excludedLineKey string) models.Decimal {
+	var reserved models.Decimal
+	for lineKey, quantities := range c.reservations {
+		if lineKey != excludedLineKey {
+			reserved = reserved.Add(quantities[partKey])
+		}
+	}
+	return reserved
+}
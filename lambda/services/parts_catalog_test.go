@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+func TestMemoryPartsCatalog_GetPart(t *testing.T) {
+	accountID := uuid.New().String()
+	partID := uuid.New().String()
+	catalog := NewMemoryPartsCatalog(models.CatalogPart{AccountID: accountID, PartID: partID, UnitOfMeasure: "EA", QuantityOnHand: models.NewDecimalFromInt(5)})
+
+	part, err := catalog.GetPart(context.Background(), accountID, partID)
+	require.NoError(t, err)
+	assert.Equal(t, partID, part.PartID)
+
+	// Parts belong to an account
+	_, err = catalog.GetPart(context.Background(), uuid.New().String(), partID)
+	assert.ErrorIs(t, err, ErrPartNotFound)
+}
+
+func TestMemoryPartsCatalog_ReserveParts(t *testing.T) {
+	ctx := context.Background()
+	accountID := uuid.New().String()
+	partID := uuid.New().String()
+	catalog := NewMemoryPartsCatalog(models.CatalogPart{AccountID: accountID, PartID: partID, UnitOfMeasure: "EA", QuantityOnHand: models.NewDecimalFromInt(5)})
+	firstLine := uuid.New().String()
+	secondLine := uuid.New().String()
+	parts := func(quantity int64, status models.PartStatus) []models.PartLineItem {
+		return []models.PartLineItem{{PartID: partID, Quantity: models.NewDecimalFromInt(quantity), Status: status}}
+	}
+
+	require.NoError(t, catalog.ReserveParts(ctx, accountID, firstLine, parts(3, models.PartStatusRequested)))
+	assert.True(t, models.NewDecimalFromInt(3).Equal(catalog.Reserved(accountID, partID)))
+
+	// Reserving again replaces the labor line's reservation rather than adding to it
+	require.NoError(t, catalog.ReserveParts(ctx, accountID, firstLine, parts(4, models.PartStatusIssued)))
+	assert.True(t, models.NewDecimalFromInt(4).Equal(catalog.Reserved(accountID, partID)))
+
+	// Stock held by other labor lines is not available
+	err := catalog.ReserveParts(ctx, accountID, secondLine, parts(2, models.PartStatusRequested))
+	var stockErr *InsufficientStockError
+	require.ErrorAs(t, err, &stockErr)
+	assert.Equal(t, partID, stockErr.PartID)
+	assert.True(t, models.NewDecimalFromInt(2).Equal(stockErr.Requested))
+	assert.True(t, models.NewDecimalFromInt(1).Equal(stockErr.Available))
+	assert.True(t, models.NewDecimalFromInt(4).Equal(catalog.Reserved(accountID, partID)))
+
+	// Returned parts go back to stock
+	require.NoError(t, catalog.ReserveParts(ctx, accountID, firstLine, parts(4, models.PartStatusReturned)))
+	assert.True(t, catalog.Reserved(accountID, partID).IsZero())
+	require.NoError(t, catalog.ReserveParts(ctx, accountID, secondLine, parts(2, models.PartStatusRequested)))
+
+	require.NoError(t, catalog.ReleaseParts(ctx, accountID, secondLine))
+	assert.True(t, catalog.Reserved(accountID, partID).IsZero())
+
+	err = catalog.ReserveParts(ctx, accountID, firstLine, []models.PartLineItem{{PartID: uuid.New().String(), Quantity: models.NewDecimalFromInt(1)}})
+	assert.ErrorIs(t, err, ErrPartNotFound)
+}
+
+func TestLoadMemoryPartsCatalog(t *testing.T) {
+	accountID := uuid.New().String()
+	partID := uuid.New().String()
+	path := filepath.Join(t.TempDir(), "parts.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"accountId":"`+accountID+`","partId":"`+partID+`","unitOfMeasure":"QT","quantityOnHand":12.5}]`), 0o600))
+
+	catalog, err := LoadMemoryPartsCatalog(path)
+	require.NoError(t, err)
+	part, err := catalog.GetPart(context.Background(), accountID, partID)
+	require.NoError(t, err)
+	assert.True(t, models.MustParseDecimal("12.5").Equal(part.QuantityOnHand))
+
+	_, err = LoadMemoryPartsCatalog(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
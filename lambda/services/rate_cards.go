@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// GetRateCard retrieves an account's rate card. It returns nil if the account
+// has no rate card.
+func (s *dynamoDBService) GetRateCard(ctx context.Context, accountID string) (*models.RateCard, error) {
+	pk, sk := models.RateCardKey(accountID)
+
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting rate card from DynamoDB: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, nil // Not found
+	}
+
+	var rateCard models.RateCard
+	if err := attributevalue.UnmarshalMap(result.Item, &rateCard); err != nil {
+		return nil, fmt.Errorf("unmarshaling rate card: %w", err)
+	}
+
+	return &rateCard, nil
+}
+
+// PutRateCard creates or replaces an account's rate card. Existing labor lines
+// keep the rate they were created with.
+func (s *dynamoDBService) PutRateCard(ctx context.Context, rateCard *models.RateCard) error {
+	item, err := attributevalue.MarshalMap(rateCard)
+	if err != nil {
+		return fmt.Errorf("marshaling rate card: %w", err)
+	}
+
+	_, err = s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("putting rate card in DynamoDB: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+func TestDynamoDBService_GetRateCard(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID := uuid.New().String()
+	rateCard := models.UpdateRateCardInput{
+		AccountID:       accountID,
+		DefaultRateType: models.RateTypeFlatRate,
+		Rates: []models.RateCardEntry{
+			{RateType: models.RateTypeFlatRate, RatePerHour: models.MustParseDecimal("125.50")},
+		},
+	}.ToRateCard()
+	item, err := attributevalue.MarshalMap(rateCard)
+	require.NoError(t, err)
+
+	client.On("GetItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.GetItemInput) bool {
+		pk := input.Key["PK"].(*types.AttributeValueMemberS)
+		sk := input.Key["SK"].(*types.AttributeValueMemberS)
+		return pk.Value == accountID && sk.Value == "RATECARD"
+	})).Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	result, err := service.GetRateCard(context.Background(), accountID)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, models.RateTypeFlatRate, result.DefaultRateType)
+
+	rate, found := result.RateFor(models.RateTypeFlatRate)
+	require.True(t, found)
+	assert.Equal(t, "125.50", rate.StringFixed(2))
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_GetRateCard_NotFound(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: nil}, nil)
+
+	result, err := service.GetRateCard(context.Background(), uuid.New().String())
+	require.NoError(t, err)
+	assert.Nil(t, result)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_PutRateCard(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	rateCard := models.UpdateRateCardInput{
+		AccountID: uuid.New().String(),
+		Rates: []models.RateCardEntry{
+			{RateType: models.RateTypeHourly, RatePerHour: models.MustParseDecimal("95")},
+		},
+	}.ToRateCard()
+
+	client.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+		recordType, ok := input.Item["recordType"].(*types.AttributeValueMemberS)
+		return ok && recordType.Value == models.RecordTypeRateCard
+	})).Return(&dynamodb.PutItemOutput{}, nil)
+
+	err := service.PutRateCard(context.Background(), rateCard)
+	require.NoError(t, err)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_PutRateCard_Error(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	client.On("PutItem", mock.Anything, mock.Anything).Return(&dynamodb.PutItemOutput{}, fmt.Errorf("throttled"))
+
+	err := service.PutRateCard(context.Background(), models.UpdateRateCardInput{AccountID: uuid.New().String()}.ToRateCard())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "throttled")
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Client defines the S3 client operations we use.
+type S3Client interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// S3Presigner defines the S3 presign client operations we use.
+type S3Presigner interface {
+	PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+// S3ExportStore is an ExportStore that keeps exports in an S3 bucket.
+type S3ExportStore struct {
+	client    S3Client
+	presigner S3Presigner
+	bucket    string
+}
+
+// NewS3ExportStore creates an export store keeping exports in the named bucket.
+func NewS3ExportStore(client S3Client, presigner S3Presigner, bucket string) *S3ExportStore {
+	return &S3ExportStore{
+		client:    client,
+		presigner: presigner,
+		bucket:    bucket,
+	}
+}
+
+// Put uploads the export to the bucket, marked to download as a file named
+// after the last element of the key.
+func (s *S3ExportStore) Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:             aws.String(s.bucket),
+		Key:                aws.String(key),
+		Body:               body,
+		ContentType:        aws.String(contentType),
+		ContentDisposition: aws.String(fmt.Sprintf("attachment; filename=%q", path.Base(key))),
+	})
+	if err != nil {
+		return fmt.Errorf("uploading export to S3: %w", err)
+	}
+	return nil
+}
+
+// PresignGet returns a presigned GET URL of the export.
+func (s *S3ExportStore) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	request, err := s.presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", fmt.Errorf("presigning export URL: %w", err)
+	}
+	return request.URL, nil
+}
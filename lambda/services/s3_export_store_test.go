@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockS3Client is a mock implementation of S3Client and S3Presigner.
+type MockS3Client struct {
+	mock.Mock
+}
+
+func (m *MockS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.PutObjectOutput), args.Error(1)
+}
+
+func (m *MockS3Client) PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error) {
+	options := s3.PresignOptions{}
+	for _, fn := range optFns {
+		fn(&options)
+	}
+	args := m.Called(ctx, params, options.Expires)
+	return args.Get(0).(*v4.PresignedHTTPRequest), args.Error(1)
+}
+
+func TestS3ExportStore_Put(t *testing.T) {
+	client := &MockS3Client{}
+	store := NewS3ExportStore(client, client, "exports-bucket")
+
+	client.On("PutObject", mock.Anything, mock.MatchedBy(func(input *s3.PutObjectInput) bool {
+		return aws.ToString(input.Bucket) == "exports-bucket" &&
+			aws.ToString(input.Key) == "exports/account-1/export.csv" &&
+			aws.ToString(input.ContentType) == "text/csv" &&
+			aws.ToString(input.ContentDisposition) == `attachment; filename="export.csv"`
+	})).Return(&s3.PutObjectOutput{}, nil).Once()
+
+	err := store.Put(context.Background(), "exports/account-1/export.csv", strings.NewReader("a,b\r\n"), "text/csv")
+	require.NoError(t, err)
+	client.AssertExpectations(t)
+}
+
+func TestS3ExportStore_Put_Error(t *testing.T) {
+	client := &MockS3Client{}
+	store := NewS3ExportStore(client, client, "exports-bucket")
+
+	client.On("PutObject", mock.Anything, mock.Anything).
+		Return((*s3.PutObjectOutput)(nil), errors.New("access denied")).Once()
+
+	err := store.Put(context.Background(), "exports/account-1/export.csv", strings.NewReader(""), "text/csv")
+	assert.EqualError(t, err, "uploading export to S3: access denied")
+}
+
+func TestS3ExportStore_PresignGet(t *testing.T) {
+	client := &MockS3Client{}
+	store := NewS3ExportStore(client, client, "exports-bucket")
+
+	client.On("PresignGetObject", mock.Anything, mock.MatchedBy(func(input *s3.GetObjectInput) bool {
+		return aws.ToString(input.Bucket) == "exports-bucket" && aws.ToString(input.Key) == "exports/account-1/export.csv"
+	}), 15*time.Minute).Return(&v4.PresignedHTTPRequest{URL: "https://exports-bucket.s3.amazonaws.com/exports/account-1/export.csv?X-Amz-Signature=abc"}, nil).Once()
+
+	url, err := store.PresignGet(context.Background(), "exports/account-1/export.csv", 15*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "https://exports-bucket.s3.amazonaws.com/exports/account-1/export.csv?X-Amz-Signature=abc", url)
+	client.AssertExpectations(t)
+}
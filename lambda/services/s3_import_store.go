@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3ObjectClient defines the S3 client operations an S3ImportStore uses.
+type S3ObjectClient interface {
+	S3Client
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// S3ImportStore is an ImportStore that reads and writes S3 objects.
+type S3ImportStore struct {
+	client S3ObjectClient
+}
+
+// NewS3ImportStore creates an import store reading and writing S3 objects.
+func NewS3ImportStore(client S3ObjectClient) *S3ImportStore {
+	return &S3ImportStore{client: client}
+}
+
+// Get opens the object for reading as it downloads.
+func (s *S3ImportStore) Get(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("downloading %s from S3: %w", key, err)
+	}
+	return output.Body, nil
+}
+
+// Put uploads the object.
+func (s *S3ImportStore) Put(ctx context.Context, bucket, key string, body io.ReadSeeker, contentType string) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("uploading %s to S3: %w", key, err)
+	}
+	return nil
+}
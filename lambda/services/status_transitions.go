@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// TransitionLaborLineStatus moves a labor line to a new status and records who
+// made the change and when. Moves not allowed by the transition table are
+// rejected with an InvalidStateTransitionError. The write is conditioned on
+// the version that was read, so a concurrent transition cannot be lost, and is
+// recorded in the labor line's history.
+func (s *dynamoDBService) TransitionLaborLineStatus(ctx context.Context, input models.TransitionLaborLineStatusInput, actor string) (*models.LaborLine, error) {
+	existing, err := s.GetLaborLine(ctx, models.GetLaborLineInput{
+		AccountID:   input.AccountID,
+		TaskID:      input.TaskID,
+		LaborLineID: input.LaborLineID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("checking existing labor line: %w", err)
+	}
+	if existing == nil {
+		return nil, ErrLaborLineNotFound
+	}
+	if input.ExpectedVersion != nil && *input.ExpectedVersion != existing.Version {
+		return nil, &ConflictError{CurrentVersion: existing.Version}
+	}
+
+	from := existing.CurrentStatus()
+	if !models.CanTransition(from, input.Status) {
+		return nil, &InvalidStateTransitionError{From: from, To: input.Status}
+	}
+
+	before := *existing
+	existing.Status = input.Status
+	existing.StatusHistory = append(existing.StatusHistory, before.NewStatusTransition(input.Status, actor, input.Reason))
+	existing.UpdatedAt = time.Now().Unix()
+	existing.Version = before.Version + 1
+
+	// The technicians' queues are filtered by status, so their assignments move with it
+	err = s.writeWithHistory(ctx, versionedWrite(existing, before.Version), models.ActionStatusChange, actor, &before, s.assignmentStatusUpdates(existing)...)
+	if err != nil {
+		if condErr := transactionConditionFailure(err, 0); condErr != nil {
+			return nil, condErr
+		}
+		return nil, fmt.Errorf("transitioning labor line status in DynamoDB: %w", err)
+	}
+
+	return existing, nil
+}
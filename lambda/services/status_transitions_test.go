@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// transitionInput builds a transition request for the given labor line.
+func transitionInput(laborLine *models.LaborLine, status models.LaborLineStatus) models.TransitionLaborLineStatusInput {
+	return models.TransitionLaborLineStatusInput{
+		AccountID:   laborLine.AccountID,
+		TaskID:      laborLine.TaskID,
+		LaborLineID: laborLine.LaborLineID,
+		Status:      status,
+	}
+}
+
+func TestDynamoDBService_TransitionLaborLineStatus(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newTestLaborLine()
+	laborLine.Status = models.StatusInProgress
+	item, err := attributevalue.MarshalMap(laborLine)
+	require.NoError(t, err)
+
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: item}, nil)
+	client.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		put := laborLinePut(input)
+		status := put.Item["status"].(*types.AttributeValueMemberS)
+		expected := put.ExpressionAttributeValues[":expectedVersion"].(*types.AttributeValueMemberN)
+		action := historyPut(input).Item["action"].(*types.AttributeValueMemberS)
+		actor := historyPut(input).Item["actor"].(*types.AttributeValueMemberS)
+		return status.Value == "ON_HOLD" && expected.Value == "3" && action.Value == "STATUS_CHANGE" && actor.Value == "user-123"
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	input := transitionInput(laborLine, models.StatusOnHold)
+	input.Reason = "waiting on parts"
+
+	updated, err := service.TransitionLaborLineStatus(context.Background(), input, "user-123")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusOnHold, updated.Status)
+	assert.Equal(t, int64(4), updated.Version)
+	require.Len(t, updated.StatusHistory, 1)
+	assert.Equal(t, models.StatusInProgress, updated.StatusHistory[0].From)
+	assert.Equal(t, models.StatusOnHold, updated.StatusHistory[0].To)
+	assert.Equal(t, "user-123", updated.StatusHistory[0].ChangedBy)
+	assert.Equal(t, "waiting on parts", updated.StatusHistory[0].Reason)
+	assert.NotZero(t, updated.StatusHistory[0].ChangedAt)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_TransitionLaborLineStatus_LegacyLineIsPending(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newTestLaborLine() // No status attribute
+	item, err := attributevalue.MarshalMap(laborLine)
+	require.NoError(t, err)
+
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: item}, nil)
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	updated, err := service.TransitionLaborLineStatus(context.Background(), transitionInput(laborLine, models.StatusInProgress), "user-123")
+	require.NoError(t, err)
+	require.Len(t, updated.StatusHistory, 1)
+	assert.Equal(t, models.StatusPending, updated.StatusHistory[0].From)
+}
+
+func TestDynamoDBService_TransitionLaborLineStatus_InvalidTransition(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newTestLaborLine()
+	laborLine.Status = models.StatusInvoiced
+	item, err := attributevalue.MarshalMap(laborLine)
+	require.NoError(t, err)
+
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	_, err = service.TransitionLaborLineStatus(context.Background(), transitionInput(laborLine, models.StatusInProgress), "user-123")
+
+	var transitionErr *InvalidStateTransitionError
+	require.ErrorAs(t, err, &transitionErr)
+	assert.Equal(t, models.StatusInvoiced, transitionErr.From)
+	assert.Equal(t, models.StatusInProgress, transitionErr.To)
+	client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_TransitionLaborLineStatus_VersionConflict(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newTestLaborLine()
+	item, err := attributevalue.MarshalMap(laborLine)
+	require.NoError(t, err)
+
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	input := transitionInput(laborLine, models.StatusInProgress)
+	staleVersion := int64(2)
+	input.ExpectedVersion = &staleVersion
+
+	_, err = service.TransitionLaborLineStatus(context.Background(), input, "user-123")
+
+	var conflictErr *ConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	assert.Equal(t, int64(3), conflictErr.CurrentVersion)
+}
+
+func TestDynamoDBService_TransitionLaborLineStatus_ConcurrentWrite(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newTestLaborLine()
+	item, err := attributevalue.MarshalMap(laborLine)
+	require.NoError(t, err)
+
+	laborLine.Version = 4
+	laborLine.Status = models.StatusOnHold
+	currentItem, err := attributevalue.MarshalMap(laborLine)
+	require.NoError(t, err)
+
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: item}, nil)
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Return(&dynamodb.TransactWriteItemsOutput{}, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("ConditionalCheckFailed"), Item: currentItem},
+				{Code: aws.String("None")},
+			},
+		})
+
+	_, err = service.TransitionLaborLineStatus(context.Background(), transitionInput(laborLine, models.StatusInProgress), "user-123")
+
+	var conflictErr *ConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	assert.Equal(t, int64(4), conflictErr.CurrentVersion)
+}
+
+func TestDynamoDBService_TransitionLaborLineStatus_NotFound(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{Item: nil}, nil)
+
+	_, err := service.TransitionLaborLineStatus(context.Background(), transitionInput(newTestLaborLine(), models.StatusInProgress), "user-123")
+	assert.ErrorIs(t, err, ErrLaborLineNotFound)
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// taskIndexName is the global secondary index keyed on taskId.
+const taskIndexName = "TaskIndex"
+
+// maxTaskQueryAccounts is the most accounts a task query can be limited to,
+// the maximum number of operands DynamoDB accepts in an IN comparison.
+const maxTaskQueryAccounts = 100
+
+// ErrTooManyAccounts is returned when a task query is limited to more accounts
+// than a single filter expression can hold.
+var ErrTooManyAccounts = errors.New("too many authorized accounts for a task query")
+
+// ListLaborLinesByTask retrieves a page of a task's labor lines from the
+// TaskIndex, across every account in accountIDs and no others. Soft-deleted
+// labor lines are excluded. Pagination tokens are bound to the task and the
+// account set, so they cannot be replayed by a caller with different access.
+func (s *dynamoDBService) ListLaborLinesByTask(ctx context.Context, input models.ListLaborLinesByTaskInput, accountIDs []string) (*models.LaborLineConnection, error) {
+	if len(accountIDs) == 0 {
+		return &models.LaborLineConnection{Items: []*models.LaborLine{}}, nil
+	}
+
+	accounts := slices.Clone(accountIDs)
+	slices.Sort(accounts)
+	accounts = slices.Compact(accounts)
+	if len(accounts) > maxTaskQueryAccounts {
+		return nil, ErrTooManyAccounts
+	}
+
+	values := map[string]types.AttributeValue{
+		":taskId": &types.AttributeValueMemberS{Value: input.TaskID},
+	}
+	placeholders := make([]string, len(accounts))
+	for i, accountID := range accounts {
+		placeholders[i] = ":account" + strconv.Itoa(i)
+		values[placeholders[i]] = &types.AttributeValueMemberS{Value: accountID}
+	}
+
+	queryInput := &dynamodb.QueryInput{
+		TableName:                 aws.String(s.tableName),
+		IndexName:                 aws.String(taskIndexName),
+		KeyConditionExpression:    aws.String("taskId = :taskId"),
+		FilterExpression:          aws.String(laborLineFilter + " AND accountId IN (" + strings.Join(placeholders, ", ") + ")"),
+		ExpressionAttributeValues: values,
+	}
+
+	accountsHash := sha256.Sum256([]byte(strings.Join(accounts, ",")))
+	scope := "task#" + input.TaskID + "#" + hex.EncodeToString(accountsHash[:])
+
+	items, nextToken, err := s.queryPage(ctx, queryInput, scope, input.Limit, input.NextToken)
+	if err != nil {
+		return nil, fmt.Errorf("querying labor lines by task from DynamoDB: %w", err)
+	}
+
+	laborLines := make([]*models.LaborLine, 0, len(items))
+	for _, item := range items {
+		laborLine, err := s.unmarshalLaborLine(item)
+		if err != nil {
+			return nil, err
+		}
+
+		// The filter already enforces both; checked again so a bad filter can never leak
+		if !laborLine.IsDeleted() && slices.Contains(accounts, laborLine.AccountID) {
+			laborLines = append(laborLines, laborLine)
+		}
+	}
+
+	return &models.LaborLineConnection{
+		Items:     laborLines,
+		NextToken: nextToken,
+	}, nil
+}
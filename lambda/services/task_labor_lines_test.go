@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+func TestDynamoDBService_ListLaborLinesByTask(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table", WithPageTokenSecret([]byte("test-secret")))
+
+	taskID := uuid.New().String()
+	newItem := func(accountID string) map[string]types.AttributeValue {
+		laborLineID := uuid.New().String()
+		item, _ := attributevalue.MarshalMap(&models.LaborLine{
+			LaborLineID: laborLineID,
+			AccountID:   accountID,
+			TaskID:      taskID,
+			PK:          accountID,
+			SK:          taskID + "#" + laborLineID,
+		})
+		return item
+	}
+
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		taskValue := input.ExpressionAttributeValues[":taskId"].(*types.AttributeValueMemberS)
+		account0 := input.ExpressionAttributeValues[":account0"].(*types.AttributeValueMemberS)
+		account1 := input.ExpressionAttributeValues[":account1"].(*types.AttributeValueMemberS)
+		return *input.IndexName == "TaskIndex" &&
+			*input.KeyConditionExpression == "taskId = :taskId" &&
+			taskValue.Value == taskID &&
+			strings.HasPrefix(*input.FilterExpression, laborLineFilter) &&
+			strings.HasSuffix(*input.FilterExpression, "accountId IN (:account0, :account1)") &&
+			account0.Value == "acct-1" && account1.Value == "acct-2" &&
+			len(input.ExpressionAttributeValues) == 3
+	})).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{newItem("acct-1"), newItem("acct-2"), newItem("acct-other")},
+		LastEvaluatedKey: map[string]types.AttributeValue{
+			"PK":     &types.AttributeValueMemberS{Value: "acct-2"},
+			"SK":     &types.AttributeValueMemberS{Value: taskID + "#last"},
+			"taskId": &types.AttributeValueMemberS{Value: taskID},
+		},
+	}, nil).Once()
+
+	// Duplicate accounts are collapsed into one operand
+	page, err := service.ListLaborLinesByTask(context.Background(), models.ListLaborLinesByTaskInput{
+		TaskID: taskID,
+		Limit:  3,
+	}, []string{"acct-2", "acct-1", "acct-2"})
+	require.NoError(t, err)
+
+	// An item outside the authorized accounts is never returned
+	require.Len(t, page.Items, 2)
+	for _, laborLine := range page.Items {
+		assert.Contains(t, []string{"acct-1", "acct-2"}, laborLine.AccountID)
+	}
+	require.NotNil(t, page.NextToken)
+
+	// The token cannot be replayed by a caller with access to other accounts
+	_, err = service.ListLaborLinesByTask(context.Background(), models.ListLaborLinesByTaskInput{
+		TaskID:    taskID,
+		NextToken: *page.NextToken,
+	}, []string{"acct-1", "acct-2", "acct-3"})
+	assert.ErrorIs(t, err, ErrInvalidNextToken)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_ListLaborLinesByTask_NoAccounts(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	page, err := service.ListLaborLinesByTask(context.Background(), models.ListLaborLinesByTaskInput{
+		TaskID: uuid.New().String(),
+	}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, page.Items)
+	assert.Nil(t, page.NextToken)
+
+	client.AssertNotCalled(t, "Query", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_ListLaborLinesByTask_TooManyAccounts(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountIDs := make([]string, maxTaskQueryAccounts+1)
+	for i := range accountIDs {
+		accountIDs[i] = uuid.New().String()
+	}
+
+	_, err := service.ListLaborLinesByTask(context.Background(), models.ListLaborLinesByTaskInput{
+		TaskID: uuid.New().String(),
+	}, accountIDs)
+	assert.ErrorIs(t, err, ErrTooManyAccounts)
+
+	client.AssertNotCalled(t, "Query", mock.Anything, mock.Anything)
+}
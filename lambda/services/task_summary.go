@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// GetTaskLaborSummary returns the summary of a task's labor lines. It is read
+// from the summary item that every labor line write updates in its
+// transaction. If the task has no summary item, or input.Recompute is set, the
+// summary is recomputed from the labor lines and stored.
+//
+// A summary item created by a labor line write only holds the changes made
+// since, so it is not trusted until it has been recomputed once; this is how
+// the labor lines of a task that predate the summary items are counted.
+func (s *dynamoDBService) GetTaskLaborSummary(ctx context.Context, input models.GetTaskLaborSummaryInput) (*models.TaskLaborSummary, error) {
+	stored, err := s.getTaskSummary(ctx, input.AccountID, input.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil && stored.Initialized && !input.Recompute {
+		return stored, nil
+	}
+
+	return s.recomputeTaskSummary(ctx, input.AccountID, input.TaskID, stored)
+}
+
+// getTaskSummary reads a task's summary item, returning nil if it has none.
+func (s *dynamoDBService) getTaskSummary(ctx context.Context, accountID, taskID string) (*models.TaskLaborSummary, error) {
+	pk, sk := models.TaskSummaryKey(accountID, taskID)
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: sk},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting task summary from DynamoDB: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	return models.TaskSummaryFromItem(result.Item)
+}
+
+// recomputeTaskSummary computes a task's summary from its labor lines and
+// replaces the stored summary with it, unless a labor line was written since
+// stored was read. A task without labor lines is not given a summary item.
+func (s *dynamoDBService) recomputeTaskSummary(ctx context.Context, accountID, taskID string, stored *models.TaskLaborSummary) (*models.TaskLaborSummary, error) {
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(s.tableName),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :skPrefix)"),
+		FilterExpression:       aws.String(laborLineFilter),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":       &types.AttributeValueMemberS{Value: accountID},
+			":skPrefix": &types.AttributeValueMemberS{Value: taskID + "#"},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	change := models.NewTaskSummaryChange(accountID, taskID)
+	for {
+		result, err := s.client.Query(ctx, queryInput)
+		if err != nil {
+			return nil, fmt.Errorf("querying task labor lines from DynamoDB: %w", err)
+		}
+		for _, item := range result.Items {
+			laborLine, err := s.unmarshalLaborLine(item)
+			if err != nil {
+				return nil, err
+			}
+			change.Add(nil, laborLine)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		queryInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	summary := change.Summary()
+	summary.UpdatedAt = time.Now().Unix()
+	summary.Recomputed = true
+	summary.Initialized = true
+	if stored == nil && change.IsZero() {
+		return summary, nil
+	}
+
+	if err := s.putTaskSummary(ctx, change, summary, stored); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// putTaskSummary replaces a task's summary item with a recomputed summary,
+// conditioned on the item still being at the revision it was read at. If a
+// labor line was written in the meantime the recomputed summary is already
+// out of date and the stored one, which that write kept current, is kept.
+func (s *dynamoDBService) putTaskSummary(ctx context.Context, change *models.TaskSummaryChange, summary, stored *models.TaskLaborSummary) error {
+	pk, sk := models.TaskSummaryKey(summary.AccountID, summary.TaskID)
+	item := map[string]types.AttributeValue{
+		"PK":          &types.AttributeValueMemberS{Value: pk},
+		"SK":          &types.AttributeValueMemberS{Value: sk},
+		"accountId":   &types.AttributeValueMemberS{Value: summary.AccountID},
+		"taskId":      &types.AttributeValueMemberS{Value: summary.TaskID},
+		"recordType":  &types.AttributeValueMemberS{Value: models.RecordTypeTaskSummary},
+		"updatedAt":   &types.AttributeValueMemberN{Value: strconv.FormatInt(summary.UpdatedAt, 10)},
+		"initialized": &types.AttributeValueMemberBOOL{Value: true},
+	}
+	for name, amount := range change.Amounts() {
+		av, err := amount.MarshalDynamoDBAttributeValue()
+		if err != nil {
+			return fmt.Errorf("marshaling task summary: %w", err)
+		}
+		item[name] = av
+	}
+
+	putInput := &dynamodb.PutItemInput{
+		TableName:           aws.String(s.tableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(PK)"),
+	}
+	if stored != nil {
+		summary.Revision = stored.Revision + 1
+		putInput.ConditionExpression = aws.String("revision = :revision")
+		putInput.ExpressionAttributeValues = map[string]types.AttributeValue{
+			":revision": &types.AttributeValueMemberN{Value: strconv.FormatInt(stored.Revision, 10)},
+		}
+	} else {
+		summary.Revision = 1
+	}
+	item["revision"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(summary.Revision, 10)}
+
+	_, err := s.client.PutItem(ctx, putInput)
+	var condErr *types.ConditionalCheckFailedException
+	if errors.As(err, &condErr) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("storing task summary in DynamoDB: %w", err)
+	}
+	return nil
+}
+
+// taskSummaryChanges collects how the labor line writes of a transaction move
+// the summaries of their tasks, keyed by account and task.
+type taskSummaryChanges map[[2]string]*models.TaskSummaryChange
+
+// add folds a labor line write, from before to after, into the change to its
+// task's summary.
+func (c taskSummaryChanges) add(before, after *models.LaborLine) {
+	laborLine := after
+	if laborLine == nil {
+		laborLine = before
+	}
+
+	key := [2]string{laborLine.AccountID, laborLine.TaskID}
+	if c[key] == nil {
+		c[key] = models.NewTaskSummaryChange(laborLine.AccountID, laborLine.TaskID)
+	}
+	c[key].Add(before, after)
+}
+
+// summaryTransactItems builds the transaction items that apply the changes to
+// the task summaries, one per task in task order, since a transaction may
+// write each item only once. Tasks whose summary does not move are left out.
+func (s *dynamoDBService) summaryTransactItems(changes taskSummaryChanges) []types.TransactWriteItem {
+	keys := make([][2]string, 0, len(changes))
+	for key, change := range changes {
+		if !change.IsZero() {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b [2]string) int {
+		return slices.Compare(a[:], b[:])
+	})
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	items := make([]types.TransactWriteItem, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, s.summaryTransactItem(changes[key], now))
+	}
+	return items
+}
+
+// summaryTransactItem builds the transaction item that adds a change to its
+// task's summary, creating the summary if it does not exist. A summary created
+// here is not initialized until GetTaskLaborSummary recomputes it.
+func (s *dynamoDBService) summaryTransactItem(change *models.TaskSummaryChange, now string) types.TransactWriteItem {
+	amounts := change.Amounts()
+	attributes := make([]string, 0, len(amounts))
+	for name := range amounts {
+		attributes = append(attributes, name)
+	}
+	slices.Sort(attributes)
+
+	// Breakdown attribute names contain # and must be substituted
+	update := "SET accountId = :accountId, taskId = :taskId, recordType = :recordType, updatedAt = :updatedAt ADD revision :one"
+	names := make(map[string]string, len(attributes))
+	values := map[string]types.AttributeValue{
+		":accountId":  &types.AttributeValueMemberS{Value: change.AccountID},
+		":taskId":     &types.AttributeValueMemberS{Value: change.TaskID},
+		":recordType": &types.AttributeValueMemberS{Value: models.RecordTypeTaskSummary},
+		":updatedAt":  &types.AttributeValueMemberN{Value: now},
+		":one":        &types.AttributeValueMemberN{Value: "1"},
+	}
+	for i, name := range attributes {
+		placeholder := "a" + strconv.Itoa(i)
+		update += ", #" + placeholder + " :" + placeholder
+		names["#"+placeholder] = name
+		values[":"+placeholder] = &types.AttributeValueMemberN{Value: amounts[name].String()}
+	}
+
+	pk, sk := models.TaskSummaryKey(change.AccountID, change.TaskID)
+	return types.TransactWriteItem{
+		Update: &types.Update{
+			TableName: aws.String(s.tableName),
+			Key: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: pk},
+				"SK": &types.AttributeValueMemberS{Value: sk},
+			},
+			UpdateExpression:          aws.String(update),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+		},
+	}
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// onGetTaskSummary mocks the read of a task's summary item; a nil item means
+// the task has none.
+func onGetTaskSummary(client *MockDynamoDBClient, taskID string, item map[string]types.AttributeValue) {
+	client.On("GetItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.GetItemInput) bool {
+		return input.Key["SK"].(*types.AttributeValueMemberS).Value == "SUMMARY#"+taskID && aws.ToBool(input.ConsistentRead)
+	})).Return(&dynamodb.GetItemOutput{Item: item}, nil).Once()
+}
+
+// summaryLaborLine returns a labor line of the task with hours and cost to summarize.
+func summaryLaborLine(t *testing.T, accountID, taskID string, status models.LaborLineStatus, technicianIDs ...string) map[string]types.AttributeValue {
+	t.Helper()
+
+	laborLine := models.NewLaborLine(models.CreateLaborLineInput{AccountID: accountID, TaskID: taskID}, nil)
+	laborLine.Status = status
+	laborLine.EstimatedHours = models.MustParseDecimal("1.5")
+	laborLine.LaborCost = models.MustParseDecimal("150")
+	laborLine.AssignedTechnicianIDs = technicianIDs
+
+	item, err := attributevalue.MarshalMap(laborLine)
+	require.NoError(t, err)
+	return item
+}
+
+func TestDynamoDBService_GetTaskLaborSummary_Stored(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID, taskID := uuid.New().String(), uuid.New().String()
+	onGetTaskSummary(client, taskID, map[string]types.AttributeValue{
+		"PK":                               &types.AttributeValueMemberS{Value: accountID},
+		"SK":                               &types.AttributeValueMemberS{Value: "SUMMARY#" + taskID},
+		"accountId":                        &types.AttributeValueMemberS{Value: accountID},
+		"taskId":                           &types.AttributeValueMemberS{Value: taskID},
+		"recordType":                       &types.AttributeValueMemberS{Value: models.RecordTypeTaskSummary},
+		"updatedAt":                        &types.AttributeValueMemberN{Value: "1760000000"},
+		"revision":                         &types.AttributeValueMemberN{Value: "7"},
+		"initialized":                      &types.AttributeValueMemberBOOL{Value: true},
+		"laborLineCount":                   &types.AttributeValueMemberN{Value: "2"},
+		"estimatedHours":                   &types.AttributeValueMemberN{Value: "3"},
+		"status#PENDING#laborLineCount":    &types.AttributeValueMemberN{Value: "2"},
+		"status#PENDING#estimatedHours":    &types.AttributeValueMemberN{Value: "3"},
+		"status#COMPLETED#laborLineCount":  &types.AttributeValueMemberN{Value: "0"},
+		"technician#tech-1#laborLineCount": &types.AttributeValueMemberN{Value: "1"},
+		"technician#tech-1#estimatedHours": &types.AttributeValueMemberN{Value: "1.5"},
+	})
+
+	summary, err := service.GetTaskLaborSummary(context.Background(), models.GetTaskLaborSummaryInput{AccountID: accountID, TaskID: taskID})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), summary.LaborLineCount)
+	assert.Equal(t, "3.00", summary.EstimatedHours.StringFixed(2))
+	assert.Equal(t, int64(1760000000), summary.UpdatedAt)
+	assert.False(t, summary.Recomputed)
+
+	// The status whose labor lines have all moved on is left out
+	require.Len(t, summary.ByStatus, 1)
+	assert.Equal(t, models.StatusPending, summary.ByStatus[0].Status)
+	require.Len(t, summary.ByTechnician, 1)
+	assert.Equal(t, "tech-1", summary.ByTechnician[0].TechnicianID)
+	assert.Equal(t, "1.50", summary.ByTechnician[0].EstimatedHours.StringFixed(2))
+
+	client.AssertNotCalled(t, "Query", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_GetTaskLaborSummary_RecomputesMissingSummary(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID, taskID := uuid.New().String(), uuid.New().String()
+	onGetTaskSummary(client, taskID, nil)
+
+	// The labor lines are read in pages
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return input.ExclusiveStartKey == nil && aws.ToBool(input.ConsistentRead) &&
+			input.ExpressionAttributeValues[":skPrefix"].(*types.AttributeValueMemberS).Value == taskID+"#"
+	})).Return(&dynamodb.QueryOutput{
+		Items:            []map[string]types.AttributeValue{summaryLaborLine(t, accountID, taskID, models.StatusPending, "tech-1")},
+		LastEvaluatedKey: map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: accountID}},
+	}, nil).Once()
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		return input.ExclusiveStartKey != nil
+	})).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{summaryLaborLine(t, accountID, taskID, models.StatusInProgress, "tech-1", "tech-2")},
+	}, nil).Once()
+
+	var stored *dynamodb.PutItemInput
+	client.On("PutItem", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { stored = args.Get(1).(*dynamodb.PutItemInput) }).
+		Return(&dynamodb.PutItemOutput{}, nil).Once()
+
+	summary, err := service.GetTaskLaborSummary(context.Background(), models.GetTaskLaborSummaryInput{AccountID: accountID, TaskID: taskID})
+	require.NoError(t, err)
+
+	assert.True(t, summary.Recomputed)
+	assert.Equal(t, int64(2), summary.LaborLineCount)
+	assert.Equal(t, "300.00", summary.LaborCost.StringFixed(2))
+	require.Len(t, summary.ByStatus, 2)
+	assert.Equal(t, models.StatusInProgress, summary.ByStatus[0].Status)
+	assert.Equal(t, models.StatusPending, summary.ByStatus[1].Status)
+	require.Len(t, summary.ByTechnician, 2)
+	assert.Equal(t, int64(2), summary.ByTechnician[0].LaborLineCount)
+	assert.Equal(t, int64(1), summary.ByTechnician[1].LaborLineCount)
+
+	// The recomputed summary becomes the task's summary item
+	require.NotNil(t, stored)
+	assert.Equal(t, "attribute_not_exists(PK)", *stored.ConditionExpression)
+	assert.Equal(t, "SUMMARY#"+taskID, stored.Item["SK"].(*types.AttributeValueMemberS).Value)
+	assert.Equal(t, "2", stored.Item["technician#tech-1#laborLineCount"].(*types.AttributeValueMemberN).Value)
+	assert.Equal(t, "1", stored.Item["revision"].(*types.AttributeValueMemberN).Value)
+	assert.True(t, stored.Item["initialized"].(*types.AttributeValueMemberBOOL).Value)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_GetTaskLaborSummary_RecomputesUninitializedSummary(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	// The summary was created by a write to the second of two labor lines, so
+	// it only counts that one
+	accountID, taskID := uuid.New().String(), uuid.New().String()
+	onGetTaskSummary(client, taskID, map[string]types.AttributeValue{
+		"accountId":      &types.AttributeValueMemberS{Value: accountID},
+		"taskId":         &types.AttributeValueMemberS{Value: taskID},
+		"revision":       &types.AttributeValueMemberN{Value: "1"},
+		"laborLineCount": &types.AttributeValueMemberN{Value: "1"},
+	})
+	client.On("Query", mock.Anything, mock.Anything).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{
+			summaryLaborLine(t, accountID, taskID, models.StatusPending),
+			summaryLaborLine(t, accountID, taskID, models.StatusPending),
+		},
+	}, nil).Once()
+	client.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+		return *input.ConditionExpression == "revision = :revision" &&
+			input.Item["laborLineCount"].(*types.AttributeValueMemberN).Value == "2" &&
+			input.Item["initialized"].(*types.AttributeValueMemberBOOL).Value
+	})).Return(&dynamodb.PutItemOutput{}, nil).Once()
+
+	summary, err := service.GetTaskLaborSummary(context.Background(), models.GetTaskLaborSummaryInput{AccountID: accountID, TaskID: taskID})
+	require.NoError(t, err)
+	assert.True(t, summary.Recomputed)
+	assert.Equal(t, int64(2), summary.LaborLineCount)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_GetTaskLaborSummary_RecomputeKeepsConcurrentWrite(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID, taskID := uuid.New().String(), uuid.New().String()
+	onGetTaskSummary(client, taskID, map[string]types.AttributeValue{
+		"revision":       &types.AttributeValueMemberN{Value: "4"},
+		"laborLineCount": &types.AttributeValueMemberN{Value: "5"},
+	})
+	client.On("Query", mock.Anything, mock.Anything).Return(&dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{summaryLaborLine(t, accountID, taskID, models.StatusPending)},
+	}, nil).Once()
+
+	// A labor line was written after the summary was read
+	client.On("PutItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.PutItemInput) bool {
+		return *input.ConditionExpression == "revision = :revision" &&
+			input.ExpressionAttributeValues[":revision"].(*types.AttributeValueMemberN).Value == "4" &&
+			input.Item["revision"].(*types.AttributeValueMemberN).Value == "5"
+	})).Return((*dynamodb.PutItemOutput)(nil), &types.ConditionalCheckFailedException{}).Once()
+
+	summary, err := service.GetTaskLaborSummary(context.Background(), models.GetTaskLaborSummaryInput{
+		AccountID: accountID,
+		TaskID:    taskID,
+		Recompute: true,
+	})
+	require.NoError(t, err)
+	assert.True(t, summary.Recomputed)
+	assert.Equal(t, int64(1), summary.LaborLineCount)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_GetTaskLaborSummary_TaskWithoutLaborLines(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	taskID := uuid.New().String()
+	onGetTaskSummary(client, taskID, nil)
+	client.On("Query", mock.Anything, mock.Anything).Return(&dynamodb.QueryOutput{}, nil).Once()
+
+	summary, err := service.GetTaskLaborSummary(context.Background(), models.GetTaskLaborSummaryInput{AccountID: uuid.New().String(), TaskID: taskID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), summary.LaborLineCount)
+	assert.Empty(t, summary.ByStatus)
+
+	// Reading an unknown task does not create a summary for it
+	client.AssertNotCalled(t, "PutItem", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_BatchCreateLaborLines_SummarizesEachTaskOnce(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	accountID, taskID := uuid.New().String(), uuid.New().String()
+	laborLines := make([]*models.LaborLine, 3)
+	for i := range laborLines {
+		laborLines[i] = models.NewLaborLine(models.CreateLaborLineInput{AccountID: accountID, TaskID: taskID}, nil)
+	}
+
+	var written *dynamodb.TransactWriteItemsInput
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { written = args.Get(1).(*dynamodb.TransactWriteItemsInput) }).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	results := service.BatchCreateLaborLines(context.Background(), laborLines, models.BatchModeAtomic, "user-123")
+	for _, result := range results {
+		require.NoError(t, result.Err)
+	}
+
+	// A transaction may write an item only once, so the task's three labor
+	// lines are added to its summary in a single update
+	require.Len(t, written.TransactItems, 7)
+	summary := written.TransactItems[6].Update
+	require.NotNil(t, summary)
+	assert.Equal(t, "SUMMARY#"+taskID, summary.Key["SK"].(*types.AttributeValueMemberS).Value)
+	assert.Equal(t, "laborLineCount", summary.ExpressionAttributeNames["#a0"])
+	assert.Equal(t, "3", summary.ExpressionAttributeValues[":a0"].(*types.AttributeValueMemberN).Value)
+}
+
+func TestDynamoDBService_CreateLaborLine_RetriesTransactionConflict(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	original := batchWriteBackoff
+	batchWriteBackoff = 0
+	t.Cleanup(func() { batchWriteBackoff = original })
+
+	// Another labor line of the task updated its summary at the same time
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Return((*dynamodb.TransactWriteItemsOutput)(nil), &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("None")},
+				{Code: aws.String("None")},
+				{Code: aws.String("TransactionConflict")},
+			},
+		}).Once()
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).
+		Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	laborLine := newBatchLaborLine(uuid.New().String())
+	require.NoError(t, service.CreateLaborLine(context.Background(), laborLine, "user-123"))
+	client.AssertNumberOfCalls(t, "TransactWriteItems", 2)
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// ListTimeEntries retrieves all time entries recorded against a labor line.
+func (s *dynamoDBService) ListTimeEntries(ctx context.Context, input models.GetLaborLineInput) ([]*models.TimeEntry, error) {
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(s.tableName),
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :skPrefix)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":       &types.AttributeValueMemberS{Value: input.AccountID},
+			":skPrefix": &types.AttributeValueMemberS{Value: models.TimeEntrySKPrefix(input.TaskID, input.LaborLineID)},
+		},
+	}
+
+	var entries []*models.TimeEntry
+	for {
+		result, err := s.client.Query(ctx, queryInput)
+		if err != nil {
+			return nil, fmt.Errorf("querying time entries from DynamoDB: %w", err)
+		}
+
+		for _, item := range result.Items {
+			var entry models.TimeEntry
+			if err := attributevalue.UnmarshalMap(item, &entry); err != nil {
+				return nil, fmt.Errorf("unmarshaling time entry: %w", err)
+			}
+			entries = append(entries, &entry)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			break
+		}
+		queryInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+
+	return entries, nil
+}
+
+// errTechnicianTimeChanged is returned by saveTimeEntry when the technician
+// saved another time entry after their time partition was read.
+var errTechnicianTimeChanged = errors.New("technician time changed")
+
+// SaveTimeEntry creates a time entry or completes a running one, and rolls the
+// total actual hours up onto the labor line in the same transaction, recording
+// the change in the labor line's history. Entries
+// that overlap another entry for the same technician, on any labor line, are
+// rejected with ErrTimeEntryOverlap.
+//
+// Each entry's period is also kept as a time slot in the technician's time
+// partition, which is read consistently to find overlaps. The transaction
+// moves the version of the technician's clock, so two overlapping entries
+// cannot both be accepted; a save that loses that race is checked again.
+func (s *dynamoDBService) SaveTimeEntry(ctx context.Context, entry *models.TimeEntry, actor string) (*models.LaborLine, error) {
+	for attempt := 1; ; attempt++ {
+		laborLine, err := s.saveTimeEntry(ctx, entry, actor)
+		if !errors.Is(err, errTechnicianTimeChanged) {
+			return laborLine, err
+		}
+		if attempt == maxBatchWriteAttempts {
+			return nil, fmt.Errorf("saving time entry in DynamoDB: %w", err)
+		}
+		if err := sleepBackoff(ctx, attempt); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// saveTimeEntry makes a single attempt at SaveTimeEntry.
+func (s *dynamoDBService) saveTimeEntry(ctx context.Context, entry *models.TimeEntry, actor string) (*models.LaborLine, error) {
+	laborLine, err := s.GetLaborLine(ctx, models.GetLaborLineInput{
+		AccountID:   entry.AccountID,
+		TaskID:      entry.TaskID,
+		LaborLineID: entry.LaborLineID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("checking existing labor line: %w", err)
+	}
+	if laborLine == nil {
+		return nil, ErrLaborLineNotFound
+	}
+
+	entries, err := s.ListTimeEntries(ctx, models.GetLaborLineInput{
+		AccountID:   entry.AccountID,
+		TaskID:      entry.TaskID,
+		LaborLineID: entry.LaborLineID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	isNew := true
+	for _, other := range entries {
+		if other.TimeEntryID == entry.TimeEntryID {
+			isNew = false
+			break
+		}
+	}
+
+	// Running timers may still be stopped after completion, but no new time can be logged
+	if isNew && laborLine.IsReadOnly() {
+		return nil, ErrLaborLineReadOnly
+	}
+
+	slots, clockVersion, err := s.technicianTime(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+	for _, slot := range slots {
+		if slot.TimeEntryID != entry.TimeEntryID && entry.OverlapsSlot(slot) {
+			return nil, ErrTimeEntryOverlap
+		}
+	}
+
+	merged := make([]*models.TimeEntry, 0, len(entries)+1)
+	for _, other := range entries {
+		if other.TimeEntryID != entry.TimeEntryID {
+			merged = append(merged, other)
+		}
+	}
+	merged = append(merged, entry)
+
+	// Recalculate the rollup and cost, and bump the labor line version
+	before := *laborLine
+	readVersion := laborLine.Version
+	laborLine.ActualHours = models.TotalActualHours(merged)
+	laborLine.RecalculateLaborCost()
+	laborLine.UpdatedAt = time.Now().Unix()
+	laborLine.Version = readVersion + 1
+
+	entryItem, err := attributevalue.MarshalMap(entry)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling time entry: %w", err)
+	}
+	slotItem, err := attributevalue.MarshalMap(entry.Slot())
+	if err != nil {
+		return nil, fmt.Errorf("marshaling time slot: %w", err)
+	}
+	actualHours, err := attributevalue.Marshal(laborLine.ActualHours)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling actual hours: %w", err)
+	}
+	laborCost, err := attributevalue.Marshal(laborLine.LaborCost)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling labor cost: %w", err)
+	}
+
+	// New entries must not exist yet; completing an entry requires it to still be running
+	entryCondition := "attribute_not_exists(PK)"
+	if !isNew {
+		entryCondition = "attribute_exists(PK) AND attribute_not_exists(endTime)"
+	}
+
+	// The rollup is written as an update, leaving the rest of the item as stored
+	write := versionedWrite(laborLine, readVersion)
+	write.update = "SET actualHours = :actualHours, laborCost = :laborCost, updatedAt = :updatedAt, #version = :newVersion"
+	write.values[":actualHours"] = actualHours
+	write.values[":laborCost"] = laborCost
+	write.values[":updatedAt"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(laborLine.UpdatedAt, 10)}
+	write.values[":newVersion"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(laborLine.Version, 10)}
+
+	// The slot is rewritten as the entry is; the clock fails the transaction if
+	// the technician saved another entry since their slots were read
+	clockCondition := "attribute_not_exists(PK)"
+	clockValues := map[string]types.AttributeValue{
+		":clockVersion": &types.AttributeValueMemberN{Value: strconv.FormatInt(clockVersion+1, 10)},
+		":recordType":   &types.AttributeValueMemberS{Value: models.RecordTypeTechnicianClock},
+	}
+	if clockVersion > 0 {
+		clockCondition = "#version = :readClockVersion"
+		clockValues[":readClockVersion"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(clockVersion, 10)}
+	}
+	related := []types.TransactWriteItem{
+		{
+			Put: &types.Put{
+				TableName:           aws.String(s.tableName),
+				Item:                entryItem,
+				ConditionExpression: aws.String(entryCondition),
+			},
+		},
+		{
+			Put: &types.Put{
+				TableName: aws.String(s.tableName),
+				Item:      slotItem,
+			},
+		},
+		{
+			Update: &types.Update{
+				TableName: aws.String(s.tableName),
+				Key: map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: models.TechnicianTimePK(entry.AccountID, entry.TechnicianID)},
+					"SK": &types.AttributeValueMemberS{Value: models.TechnicianClockSK},
+				},
+				UpdateExpression:          aws.String("SET #version = :clockVersion, recordType = :recordType"),
+				ConditionExpression:       aws.String(clockCondition),
+				ExpressionAttributeNames:  map[string]string{"#version": "version"},
+				ExpressionAttributeValues: clockValues,
+			},
+		},
+	}
+
+	err = s.writeWithHistory(ctx, write, models.ActionTimeEntry, actor, &before, related...)
+	if err != nil {
+		if condErr := transactionConditionFailure(err, 0); condErr != nil {
+			return nil, condErr
+		}
+		entryIndex := s.firstRelatedIndex()
+		if _, failed := transactionConditionReason(err, entryIndex); failed && !isNew {
+			return nil, ErrTimerNotRunning
+		}
+		if _, failed := transactionConditionReason(err, entryIndex+2); failed {
+			return nil, errTechnicianTimeChanged
+		}
+		return nil, fmt.Errorf("saving time entry in DynamoDB: %w", err)
+	}
+
+	return laborLine, nil
+}
+
+// technicianTime reads the technician's time slots, on every labor line, that
+// start before the entry ends and are still running or end after it starts,
+// together with the version of the technician's clock they were read at. Both
+// are read consistently, so no committed entry can be missed.
+func (s *dynamoDBService) technicianTime(ctx context.Context, entry *models.TimeEntry) ([]*models.TimeSlot, int64, error) {
+	pk := models.TechnicianTimePK(entry.AccountID, entry.TechnicianID)
+
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberS{Value: pk},
+			"SK": &types.AttributeValueMemberS{Value: models.TechnicianClockSK},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading technician clock from DynamoDB: %w", err)
+	}
+	var clock struct {
+		Version int64 `dynamodbav:"version"`
+	}
+	if err := attributevalue.UnmarshalMap(result.Item, &clock); err != nil {
+		return nil, 0, fmt.Errorf("unmarshaling technician clock: %w", err)
+	}
+
+	// Slots sort by start time, so those starting at or after the entry's end
+	// are outside the key range
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(s.tableName),
+		KeyConditionExpression: aws.String("PK = :pk AND SK BETWEEN :from AND :to"),
+		FilterExpression:       aws.String("attribute_not_exists(endTime) OR endTime > :startTime"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":        &types.AttributeValueMemberS{Value: pk},
+			":from":      &types.AttributeValueMemberS{Value: models.TimeSlotSKPrefix},
+			":to":        &types.AttributeValueMemberS{Value: models.TimeSlotSK(entry.End(), "")},
+			":startTime": &types.AttributeValueMemberN{Value: strconv.FormatInt(entry.StartTime, 10)},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	var slots []*models.TimeSlot
+	for {
+		result, err := s.client.Query(ctx, queryInput)
+		if err != nil {
+			return nil, 0, fmt.Errorf("querying technician time from DynamoDB: %w", err)
+		}
+
+		for _, item := range result.Items {
+			var slot models.TimeSlot
+			if err := attributevalue.UnmarshalMap(item, &slot); err != nil {
+				return nil, 0, fmt.Errorf("unmarshaling time slot: %w", err)
+			}
+			slots = append(slots, &slot)
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return slots, clock.Version, nil
+		}
+		queryInput.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
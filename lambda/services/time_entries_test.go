@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"steverhoton-labor-lines/lambda/models"
+)
+
+// timeEntryFixture sets up a labor line and existing time entries on a mock
+// client. Entries on other labor lines are only found in their technicians'
+// time partitions.
+func timeEntryFixture(t *testing.T, client *MockDynamoDBClient, laborLine *models.LaborLine, entries ...*models.TimeEntry) {
+	t.Helper()
+
+	laborLineItem, err := attributevalue.MarshalMap(laborLine)
+	require.NoError(t, err)
+	client.On("GetItem", mock.Anything, mock.MatchedBy(func(input *dynamodb.GetItemInput) bool {
+		return input.Key["SK"].(*types.AttributeValueMemberS).Value != models.TechnicianClockSK
+	})).Return(&dynamodb.GetItemOutput{Item: laborLineItem}, nil)
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{}, nil)
+
+	items := make([]map[string]types.AttributeValue, 0, len(entries))
+	slots := map[string][]map[string]types.AttributeValue{}
+	for _, entry := range entries {
+		slot := entry.Slot()
+		slotItem, err := attributevalue.MarshalMap(slot)
+		require.NoError(t, err)
+		slots[slot.PK] = append(slots[slot.PK], slotItem)
+
+		if entry.LaborLineID != laborLine.LaborLineID {
+			continue
+		}
+		item, err := attributevalue.MarshalMap(entry)
+		require.NoError(t, err)
+		items = append(items, item)
+	}
+	client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+		prefix, ok := input.ExpressionAttributeValues[":skPrefix"].(*types.AttributeValueMemberS)
+		return ok && prefix.Value == models.TimeEntrySKPrefix(laborLine.TaskID, laborLine.LaborLineID)
+	})).Return(&dynamodb.QueryOutput{Items: items}, nil)
+
+	for pk, items := range slots {
+		client.On("Query", mock.Anything, mock.MatchedBy(func(input *dynamodb.QueryInput) bool {
+			return input.ExpressionAttributeValues[":pk"].(*types.AttributeValueMemberS).Value == pk
+		})).Return(&dynamodb.QueryOutput{Items: items}, nil)
+	}
+	client.On("Query", mock.Anything, mock.Anything).Return(&dynamodb.QueryOutput{}, nil)
+}
+
+func newTestLaborLine() *models.LaborLine {
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	return &models.LaborLine{
+		LaborLineID: laborLineID,
+		AccountID:   accountID,
+		TaskID:      taskID,
+		Version:     3,
+		PK:          accountID,
+		SK:          taskID + "#" + laborLineID,
+	}
+}
+
+func TestDynamoDBService_ListTimeEntries(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newTestLaborLine()
+	entry := models.NewTimeEntry(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID, "tech-1", 1000)
+	timeEntryFixture(t, client, laborLine, entry)
+
+	entries, err := service.ListTimeEntries(context.Background(), models.GetLaborLineInput{
+		AccountID:   laborLine.AccountID,
+		TaskID:      laborLine.TaskID,
+		LaborLineID: laborLine.LaborLineID,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, entry.TimeEntryID, entries[0].TimeEntryID)
+	assert.True(t, entries[0].IsRunning())
+}
+
+func TestDynamoDBService_SaveTimeEntry_RollsUpHours(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newTestLaborLine()
+	laborLine.ActualHours = models.MustParseDecimal("1")
+	previous := models.NewTimeEntry(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID, "tech-1", 0)
+	previous.Stop(3600, 0)
+	timeEntryFixture(t, client, laborLine, previous)
+
+	client.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		if len(input.TransactItems) != 6 {
+			return false
+		}
+		update := input.TransactItems[0].Update
+		hours := update.ExpressionAttributeValues[":actualHours"].(*types.AttributeValueMemberN)
+		expectedVersion := update.ExpressionAttributeValues[":expectedVersion"].(*types.AttributeValueMemberN)
+		put := input.TransactItems[2].Put
+
+		// The change is recorded in the history, by the caller
+		action := historyPut(input).Item["action"].(*types.AttributeValueMemberS)
+		actor := historyPut(input).Item["actor"].(*types.AttributeValueMemberS)
+		if action.Value != string(models.ActionTimeEntry) || actor.Value != "user-1" {
+			return false
+		}
+
+		// The task's summary gains the half hour the entry adds
+		summary := input.TransactItems[5].Update
+		summaryHours, ok := summary.ExpressionAttributeValues[":a0"].(*types.AttributeValueMemberN)
+		return aws.ToString(put.ConditionExpression) == "attribute_not_exists(PK)" &&
+			hours.Value == "1.5" && expectedVersion.Value == "3" &&
+			summary.ExpressionAttributeNames["#a0"] == "actualHours" && ok && summaryHours.Value == "0.5"
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	entry := models.NewTimeEntry(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID, "tech-1", 3600)
+	entry.Stop(5400, 0)
+
+	updated, err := service.SaveTimeEntry(context.Background(), entry, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "1.50", updated.ActualHours.StringFixed(2))
+	assert.Equal(t, int64(4), updated.Version)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_SaveTimeEntry_RecalculatesHourlyCost(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newTestLaborLine()
+	laborLine.RateType = models.RateTypeHourly
+	laborLine.RatePerHour = models.MustParseDecimal("90")
+	timeEntryFixture(t, client, laborLine)
+
+	client.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		update := input.TransactItems[0].Update
+		cost, ok := update.ExpressionAttributeValues[":laborCost"].(*types.AttributeValueMemberN)
+		return ok && cost.Value == "67.5"
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	entry := models.NewTimeEntry(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID, "tech-1", 0)
+	entry.Stop(2700, 0)
+
+	updated, err := service.SaveTimeEntry(context.Background(), entry, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "67.50", updated.LaborCost.StringFixed(2))
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_SaveTimeEntry_ReadOnly(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newTestLaborLine()
+	laborLine.Status = models.StatusCompleted
+	timeEntryFixture(t, client, laborLine)
+
+	entry := models.NewTimeEntry(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID, "tech-1", 0)
+
+	_, err := service.SaveTimeEntry(context.Background(), entry, "user-1")
+	assert.ErrorIs(t, err, ErrLaborLineReadOnly)
+	client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_SaveTimeEntry_StopsRunningTimerOnCompletedLine(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newTestLaborLine()
+	laborLine.Status = models.StatusCompleted
+	running := models.NewTimeEntry(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID, "tech-1", 0)
+	timeEntryFixture(t, client, laborLine, running)
+
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	running.Stop(3600, 0)
+	updated, err := service.SaveTimeEntry(context.Background(), running, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "1.00", updated.ActualHours.StringFixed(2))
+}
+
+func TestDynamoDBService_SaveTimeEntry_Overlap(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newTestLaborLine()
+	running := models.NewTimeEntry(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID, "tech-1", 1000)
+	otherTech := models.NewTimeEntry(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID, "tech-2", 1000)
+	timeEntryFixture(t, client, laborLine, running, otherTech)
+
+	// A second timer for the same technician overlaps the running one
+	entry := models.NewTimeEntry(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID, "tech-1", 2000)
+
+	_, err := service.SaveTimeEntry(context.Background(), entry, "user-1")
+	assert.ErrorIs(t, err, ErrTimeEntryOverlap)
+	client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_SaveTimeEntry_OverlapOnAnotherLaborLine(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newTestLaborLine()
+	otherLine := models.NewTimeEntry(laborLine.AccountID, laborLine.TaskID, uuid.New().String(), "tech-1", 1000)
+	otherLine.Stop(4600, 0)
+	timeEntryFixture(t, client, laborLine, otherLine)
+
+	// The technician already logged this time on another labor line
+	entry := models.NewTimeEntry(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID, "tech-1", 2000)
+	entry.Stop(3000, 0)
+
+	_, err := service.SaveTimeEntry(context.Background(), entry, "user-1")
+	assert.ErrorIs(t, err, ErrTimeEntryOverlap)
+	client.AssertNotCalled(t, "TransactWriteItems", mock.Anything, mock.Anything)
+}
+
+func TestDynamoDBService_SaveTimeEntry_WritesTimeSlot(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newTestLaborLine()
+	timeEntryFixture(t, client, laborLine)
+
+	entry := models.NewTimeEntry(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID, "tech-1", 1000)
+
+	client.On("TransactWriteItems", mock.Anything, mock.MatchedBy(func(input *dynamodb.TransactWriteItemsInput) bool {
+		slot := input.TransactItems[3].Put
+		clock := input.TransactItems[4].Update
+		return slot.Item["PK"].(*types.AttributeValueMemberS).Value == models.TechnicianTimePK(laborLine.AccountID, "tech-1") &&
+			slot.Item["SK"].(*types.AttributeValueMemberS).Value == models.TimeSlotSK(1000, entry.TimeEntryID) &&
+			clock.Key["SK"].(*types.AttributeValueMemberS).Value == models.TechnicianClockSK &&
+			aws.ToString(clock.ConditionExpression) == "attribute_not_exists(PK)"
+	})).Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+	_, err := service.SaveTimeEntry(context.Background(), entry, "user-1")
+	require.NoError(t, err)
+
+	client.AssertExpectations(t)
+}
+
+func TestDynamoDBService_SaveTimeEntry_RetriesWhenTechnicianTimeChanges(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newTestLaborLine()
+	timeEntryFixture(t, client, laborLine)
+
+	// The technician's clock moved between the read and the write
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).Return((*dynamodb.TransactWriteItemsOutput)(nil), &types.TransactionCanceledException{
+		CancellationReasons: []types.CancellationReason{
+			{Code: aws.String("None")},
+			{Code: aws.String("None")},
+			{Code: aws.String("None")},
+			{Code: aws.String("None")},
+			{Code: aws.String("ConditionalCheckFailed")},
+		},
+	}).Once()
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).Return(&dynamodb.TransactWriteItemsOutput{}, nil).Once()
+
+	entry := models.NewTimeEntry(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID, "tech-1", 1000)
+	_, err := service.SaveTimeEntry(context.Background(), entry, "user-1")
+	require.NoError(t, err)
+
+	client.AssertNumberOfCalls(t, "TransactWriteItems", 2)
+}
+
+func TestDynamoDBService_SaveTimeEntry_AlreadyStopped(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	laborLine := newTestLaborLine()
+	running := models.NewTimeEntry(laborLine.AccountID, laborLine.TaskID, laborLine.LaborLineID, "tech-1", 1000)
+	timeEntryFixture(t, client, laborLine, running)
+
+	client.On("TransactWriteItems", mock.Anything, mock.Anything).Return((*dynamodb.TransactWriteItemsOutput)(nil), &types.TransactionCanceledException{
+		CancellationReasons: []types.CancellationReason{
+			{Code: aws.String("None")},
+			{Code: aws.String("None")},
+			{Code: aws.String("ConditionalCheckFailed")},
+		},
+	})
+
+	running.Stop(4600, 0)
+	_, err := service.SaveTimeEntry(context.Background(), running, "user-1")
+	assert.ErrorIs(t, err, ErrTimerNotRunning)
+}
+
+func TestDynamoDBService_SaveTimeEntry_LaborLineNotFound(t *testing.T) {
+	client := &MockDynamoDBClient{}
+	service := NewDynamoDBService(client, "test-table")
+
+	client.On("GetItem", mock.Anything, mock.Anything).Return(&dynamodb.GetItemOutput{}, nil)
+
+	entry := models.NewTimeEntry(uuid.New().String(), uuid.New().String(), uuid.New().String(), "tech-1", 1000)
+	_, err := service.SaveTimeEntry(context.Background(), entry, "user-1")
+	assert.ErrorIs(t, err, ErrLaborLineNotFound)
+}
@@ -1,24 +1,83 @@
 package services
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"sync"
 
 	"github.com/google/uuid"
 	"github.com/xeipuuv/gojsonschema"
 
 	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/schemas"
+)
+
+// Sub-schema references to definitions within the labor line schema.
+const (
+	timeEntrySchemaRef        = `{"$ref": "https://example.com/schemas/labor-line.schema.json#/definitions/timeEntry"}`
+	rateCardSchemaRef         = `{"$ref": "https://example.com/schemas/labor-line.schema.json#/definitions/rateCard"}`
+	statusTransitionSchemaRef = `{"$ref": "https://example.com/schemas/labor-line.schema.json#/definitions/statusTransition"}`
+	customFieldSchemaRef      = `{"$ref": "https://example.com/schemas/labor-line.schema.json#/definitions/customFieldDefinition"}`
+	noteAdditionSchemaRef     = `{"$ref": "https://example.com/schemas/labor-line.schema.json#/definitions/noteAddition"}`
+	noteEditSchemaRef         = `{"$ref": "https://example.com/schemas/labor-line.schema.json#/definitions/noteEdit"}`
+	noteDeletionSchemaRef     = `{"$ref": "https://example.com/schemas/labor-line.schema.json#/definitions/noteDeletion"}`
+	assignmentSchemaRef       = `{"$ref": "https://example.com/schemas/labor-line.schema.json#/definitions/assignment"}`
+	laborOperationSchemaRef   = `{"$ref": "https://example.com/schemas/labor-line.schema.json#/definitions/laborOperation"}`
+	operationUpdateSchemaRef  = `{"$ref": "https://example.com/schemas/labor-line.schema.json#/definitions/laborOperationUpdate"}`
 )
 
 // ValidationService defines the interface for validation operations.
 type ValidationService interface {
-	ValidateCreateInput(input models.CreateLaborLineInput) error
-	ValidateUpdateInput(input models.UpdateLaborLineInput) error
+	ValidateCreateInput(input models.CreateLaborLineInput, customFields []*models.CustomFieldDefinition) error
+	ValidateUpdateInput(input models.UpdateLaborLineInput, customFields []*models.CustomFieldDefinition) error
+	ValidateStartTimerInput(input models.StartLaborTimerInput) error
+	ValidateStopTimerInput(input models.StopLaborTimerInput) error
+	ValidateAddTimeEntryInput(input models.AddTimeEntryInput) error
+	ValidateRateCardInput(input models.UpdateRateCardInput) error
+	ValidateTransitionStatusInput(input models.TransitionLaborLineStatusInput) error
+	ValidateDefineCustomFieldInput(input models.DefineCustomFieldInput) error
+	ValidateAddNoteInput(input models.AddLaborLineNoteInput) error
+	ValidateEditNoteInput(input models.EditLaborLineNoteInput) error
+	ValidateDeleteNoteInput(input models.DeleteLaborLineNoteInput) error
+	ValidateAssignmentInput(input models.LaborLineAssignmentInput) error
+	ValidateCreateLaborOperationInput(input models.CreateLaborOperationInput) error
+	ValidateUpdateLaborOperationInput(input models.UpdateLaborOperationInput) error
+	LaborLineValidator
+}
+
+// LaborLineValidator validates labor lines read back from the table.
+type LaborLineValidator interface {
+	ValidateLaborLine(laborLine *models.LaborLine) error
 }
 
 // validationService implements ValidationService.
 type validationService struct {
+	// schema is the current labor line schema, which inputs are validated against
 	schema *gojsonschema.Schema
+	// laborLineSchemas holds every loaded labor line schema by version
+	laborLineSchemas   map[int]*gojsonschema.Schema
+	timeEntrySchema    *gojsonschema.Schema
+	rateCardSchema     *gojsonschema.Schema
+	statusSchema       *gojsonschema.Schema
+	customFieldSchema  *gojsonschema.Schema
+	noteAdditionSchema *gojsonschema.Schema
+	noteEditSchema     *gojsonschema.Schema
+	noteDeletionSchema *gojsonschema.Schema
+	assignmentSchema   *gojsonschema.Schema
+	operationSchema    *gojsonschema.Schema
+	operationUpdate    *gojsonschema.Schema
+
+	// document is the current labor line schema, which is composed with an
+	// account's custom field definitions to validate its labor lines
+	document map[string]interface{}
+
+	// composedSchemas caches the compiled schema of each set of custom field
+	// definitions, keyed by the customFields sub-schema
+	composedMu      sync.Mutex
+	composedSchemas map[string]*gojsonschema.Schema
 }
 
 // NewValidationService creates a new validation service instance.
@@ -30,88 +89,137 @@ func NewValidationService(schemaPath string) (ValidationService, error) {
 	}
 
 	// Load schema
-	schemaLoader := gojsonschema.NewBytesLoader(schemaBytes)
-	schema, err := gojsonschema.NewSchema(schemaLoader)
+	// A schema read from a file is used as the current version
+	service, err := newValidationService(map[int]gojsonschema.JSONLoader{
+		schemas.CurrentVersion: gojsonschema.NewBytesLoader(schemaBytes),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("loading JSON schema: %w", err)
 	}
 
-	return &validationService{
-		schema: schema,
-	}, nil
+	return service, nil
 }
 
-// NewValidationServiceWithEmbeddedSchema creates a validation service with embedded schema.
-// This is useful for deployment where we don't want to read files at runtime.
+// NewValidationServiceWithEmbeddedSchema creates a validation service from the
+// schemas built into the function, loading every schema version so that
+// labor lines are validated against the version they were written with.
 func NewValidationServiceWithEmbeddedSchema() (ValidationService, error) {
-	// Embedded schema JSON - matches the labor-line.schema.json file
-	schemaJSON := `{
-		"$schema": "http://json-schema.org/draft-07/schema#",
-		"$id": "https://example.com/schemas/labor-line.schema.json",
-		"title": "Labor Line",
-		"description": "A labor line for maintenance work order tasks",
-		"type": "object",
-		"properties": {
-			"laborLineId": {
-				"type": "string",
-				"format": "uuid",
-				"description": "Unique identifier for the labor line"
-			},
-			"accountId": {
-				"type": "string",
-				"format": "uuid",
-				"description": "Account identifier (used as DynamoDB partition key)"
-			},
-			"taskId": {
-				"type": "string",
-				"format": "uuid",
-				"description": "Task identifier (used in DynamoDB sort key)"
-			},
-			"partId": {
-				"type": "array",
-				"items": {
-					"type": "string",
-					"format": "uuid"
-				},
-				"description": "Optional list of part identifiers required for the work",
-				"uniqueItems": true
-			},
-			"notes": {
-				"type": "array",
-				"items": {
-					"type": "string",
-					"minLength": 1,
-					"maxLength": 1000
-				},
-				"description": "Optional notes describing the work to be performed"
-			},
-			"description": {
-				"type": "string",
-				"maxLength": 1000,
-				"description": "Optional description of the labor line work"
-			}
-		},
-		"required": [
-			"laborLineId",
-			"accountId",
-			"taskId"
-		],
-		"additionalProperties": false
-	}`
-
-	schemaLoader := gojsonschema.NewStringLoader(schemaJSON)
-	schema, err := gojsonschema.NewSchema(schemaLoader)
+	loaders := make(map[int]gojsonschema.JSONLoader)
+	for _, version := range schemas.Versions() {
+		document, err := schemas.LaborLine(version)
+		if err != nil {
+			return nil, err
+		}
+		loaders[version] = gojsonschema.NewBytesLoader(document)
+	}
+
+	service, err := newValidationService(loaders)
 	if err != nil {
 		return nil, fmt.Errorf("loading embedded JSON schema: %w", err)
 	}
 
+	return service, nil
+}
+
+// newValidationService compiles the labor line schema of each version and the
+// sub-schemas defined within the current version, which inputs are validated
+// against.
+func newValidationService(schemaLoaders map[int]gojsonschema.JSONLoader) (*validationService, error) {
+	laborLineSchemas := make(map[int]*gojsonschema.Schema, len(schemaLoaders))
+	for version, schemaLoader := range schemaLoaders {
+		schema, err := gojsonschema.NewSchema(schemaLoader)
+		if err != nil {
+			return nil, fmt.Errorf("compiling schema version %d: %w", version, err)
+		}
+		laborLineSchemas[version] = schema
+	}
+
+	schemaLoader, ok := schemaLoaders[schemas.CurrentVersion]
+	if !ok {
+		return nil, fmt.Errorf("schema version %d: %w", schemas.CurrentVersion, ErrUnknownSchemaVersion)
+	}
+
+	timeEntrySchema, err := compileDefinition(schemaLoader, timeEntrySchemaRef)
+	if err != nil {
+		return nil, fmt.Errorf("compiling time entry schema: %w", err)
+	}
+	rateCardSchema, err := compileDefinition(schemaLoader, rateCardSchemaRef)
+	if err != nil {
+		return nil, fmt.Errorf("compiling rate card schema: %w", err)
+	}
+	statusSchema, err := compileDefinition(schemaLoader, statusTransitionSchemaRef)
+	if err != nil {
+		return nil, fmt.Errorf("compiling status transition schema: %w", err)
+	}
+	customFieldSchema, err := compileDefinition(schemaLoader, customFieldSchemaRef)
+	if err != nil {
+		return nil, fmt.Errorf("compiling custom field schema: %w", err)
+	}
+	noteAdditionSchema, err := compileDefinition(schemaLoader, noteAdditionSchemaRef)
+	if err != nil {
+		return nil, fmt.Errorf("compiling note addition schema: %w", err)
+	}
+	noteEditSchema, err := compileDefinition(schemaLoader, noteEditSchemaRef)
+	if err != nil {
+		return nil, fmt.Errorf("compiling note edit schema: %w", err)
+	}
+	noteDeletionSchema, err := compileDefinition(schemaLoader, noteDeletionSchemaRef)
+	if err != nil {
+		return nil, fmt.Errorf("compiling note deletion schema: %w", err)
+	}
+	assignmentSchema, err := compileDefinition(schemaLoader, assignmentSchemaRef)
+	if err != nil {
+		return nil, fmt.Errorf("compiling assignment schema: %w", err)
+	}
+	operationSchema, err := compileDefinition(schemaLoader, laborOperationSchemaRef)
+	if err != nil {
+		return nil, fmt.Errorf("compiling labor operation schema: %w", err)
+	}
+	operationUpdate, err := compileDefinition(schemaLoader, operationUpdateSchemaRef)
+	if err != nil {
+		return nil, fmt.Errorf("compiling labor operation update schema: %w", err)
+	}
+
+	loaded, err := schemaLoader.LoadJSON()
+	if err != nil {
+		return nil, fmt.Errorf("loading schema version %d: %w", schemas.CurrentVersion, err)
+	}
+	document, ok := loaded.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema version %d is not a JSON object", schemas.CurrentVersion)
+	}
+
 	return &validationService{
-		schema: schema,
+		schema:             laborLineSchemas[schemas.CurrentVersion],
+		laborLineSchemas:   laborLineSchemas,
+		timeEntrySchema:    timeEntrySchema,
+		rateCardSchema:     rateCardSchema,
+		statusSchema:       statusSchema,
+		customFieldSchema:  customFieldSchema,
+		noteAdditionSchema: noteAdditionSchema,
+		noteEditSchema:     noteEditSchema,
+		noteDeletionSchema: noteDeletionSchema,
+		assignmentSchema:   assignmentSchema,
+		operationSchema:    operationSchema,
+		operationUpdate:    operationUpdate,
+		document:           document,
+		composedSchemas:    map[string]*gojsonschema.Schema{},
 	}, nil
 }
 
-// ValidateCreateInput validates a CreateLaborLineInput against the JSON schema.
-func (s *validationService) ValidateCreateInput(input models.CreateLaborLineInput) error {
+// compileDefinition compiles a reference to a definition within the labor line
+// schema. Each compilation needs its own schema loader.
+func compileDefinition(schemaLoader gojsonschema.JSONLoader, ref string) (*gojsonschema.Schema, error) {
+	refLoader := gojsonschema.NewSchemaLoader()
+	if err := refLoader.AddSchemas(schemaLoader); err != nil {
+		return nil, err
+	}
+	return refLoader.Compile(gojsonschema.NewStringLoader(ref))
+}
+
+// ValidateCreateInput validates a CreateLaborLineInput against the JSON schema
+// composed with the account's custom field definitions.
+func (s *validationService) ValidateCreateInput(input models.CreateLaborLineInput, customFields []*models.CustomFieldDefinition) error {
 	// Convert to a map that includes a generated laborLineId for validation
 	validationData := map[string]interface{}{
 		"laborLineId": uuid.New().String(), // Temporary ID for validation
@@ -119,8 +227,8 @@ func (s *validationService) ValidateCreateInput(input models.CreateLaborLineInpu
 		"taskId":      input.TaskID,
 	}
 
-	if input.PartID != nil {
-		validationData["partId"] = input.PartID
+	if input.Parts != nil {
+		validationData["parts"] = input.Parts
 	}
 	if input.Notes != nil {
 		validationData["notes"] = input.Notes
@@ -128,80 +236,564 @@ func (s *validationService) ValidateCreateInput(input models.CreateLaborLineInpu
 	if input.Description != "" {
 		validationData["description"] = input.Description
 	}
+	if input.OperationCode != "" {
+		validationData["operationCode"] = input.OperationCode
+	}
+	if input.CustomFields != nil {
+		validationData["customFields"] = input.CustomFields
+	}
+	addRateFields(validationData, input.EstimatedHours, input.RateType, input.RatePerHour)
 
-	return s.validateData(validationData)
+	schema, err := s.composedSchema(customFields)
+	if err != nil {
+		return err
+	}
+	return s.validateData(schema, validationData)
 }
 
-// ValidateUpdateInput validates an UpdateLaborLineInput against the JSON schema.
-func (s *validationService) ValidateUpdateInput(input models.UpdateLaborLineInput) error {
+// ValidateUpdateInput validates the fields an UpdateLaborLineInput sets
+// against the JSON schema. Custom fields are checked against the account's
+// custom field definitions only when the input replaces them.
+func (s *validationService) ValidateUpdateInput(input models.UpdateLaborLineInput, customFields []*models.CustomFieldDefinition) error {
 	validationData := map[string]interface{}{
 		"laborLineId": input.LaborLineID,
 		"accountId":   input.AccountID,
 		"taskId":      input.TaskID,
 	}
 
-	if input.PartID != nil {
-		validationData["partId"] = input.PartID
+	if input.Parts.HasValue() {
+		validationData["parts"] = input.Parts.Value
 	}
-	if input.Notes != nil {
-		validationData["notes"] = input.Notes
+	if input.Description.HasValue() {
+		validationData["description"] = input.Description.Value
+	}
+	if input.CustomFields.HasValue() {
+		validationData["customFields"] = input.CustomFields.Value
+	}
+	if input.EstimatedHours.HasValue() {
+		validationData["estimatedHours"] = input.EstimatedHours.Value
+	}
+	if input.RateType.HasValue() {
+		validationData["rateType"] = input.RateType.Value
+	}
+	if input.RatePerHour.HasValue() {
+		validationData["ratePerHour"] = input.RatePerHour.Value
+	}
+
+	schema := s.schema
+	if input.CustomFields.Set {
+		var err error
+		if schema, err = s.composedSchema(customFields); err != nil {
+			return err
+		}
+	}
+
+	return s.validateData(schema, validationData)
+}
+
+// composedSchema returns the current labor line schema with its customFields
+// property replaced by one allowing exactly the given custom fields. Compiled
+// schemas are cached, as accounts' definitions rarely change.
+func (s *validationService) composedSchema(customFields []*models.CustomFieldDefinition) (*gojsonschema.Schema, error) {
+	properties := make(map[string]interface{}, len(customFields))
+	required := []interface{}{}
+	for _, field := range customFields {
+		property := map[string]interface{}{"type": field.Type.JSONType()}
+		if len(field.Enum) > 0 {
+			property["enum"] = field.Enum
+		}
+		if field.Pattern != "" {
+			property["pattern"] = field.Pattern
+		}
+		properties[field.Name] = property
+		if field.Required {
+			required = append(required, field.Name)
+		}
+	}
+	customFieldsSchema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		customFieldsSchema["required"] = required
+	}
+
+	key, err := json.Marshal(customFieldsSchema)
+	if err != nil {
+		return nil, fmt.Errorf("encoding custom fields schema: %w", err)
+	}
+
+	s.composedMu.Lock()
+	defer s.composedMu.Unlock()
+	if schema, ok := s.composedSchemas[string(key)]; ok {
+		return schema, nil
+	}
+
+	// The document is shared, so only the maps being changed are copied
+	document := make(map[string]interface{}, len(s.document))
+	for k, v := range s.document {
+		document[k] = v
+	}
+	baseProperties, _ := s.document["properties"].(map[string]interface{})
+	documentProperties := make(map[string]interface{}, len(baseProperties)+1)
+	for k, v := range baseProperties {
+		documentProperties[k] = v
+	}
+	documentProperties["customFields"] = customFieldsSchema
+	document["properties"] = documentProperties
+	if len(required) > 0 {
+		baseRequired, _ := s.document["required"].([]interface{})
+		document["required"] = append(append([]interface{}{}, baseRequired...), "customFields")
+	}
+
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(document))
+	if err != nil {
+		return nil, fmt.Errorf("compiling custom fields schema: %w", err)
+	}
+	s.composedSchemas[string(key)] = schema
+	return schema, nil
+}
+
+// addRateFields adds the optional hours and rate fields of a create or update
+// input to the validation data.
+func addRateFields(validationData map[string]interface{}, estimatedHours *models.Decimal, rateType models.RateType, ratePerHour *models.Decimal) {
+	if estimatedHours != nil {
+		validationData["estimatedHours"] = *estimatedHours
+	}
+	if rateType != "" {
+		validationData["rateType"] = rateType
+	}
+	if ratePerHour != nil {
+		validationData["ratePerHour"] = *ratePerHour
+	}
+}
+
+// ValidateLaborLine validates a stored labor line against the schema version
+// it was written with.
+func (s *validationService) ValidateLaborLine(laborLine *models.LaborLine) error {
+	version := laborLine.SchemaVersion
+	if version == 0 {
+		version = schemas.UnversionedVersion
+	}
+	schema, ok := s.laborLineSchemas[version]
+	if !ok {
+		return fmt.Errorf("schema version %d: %w", version, ErrUnknownSchemaVersion)
+	}
+
+	validationData := map[string]interface{}{
+		"laborLineId": laborLine.LaborLineID,
+		"accountId":   laborLine.AccountID,
+		"taskId":      laborLine.TaskID,
+		"actualHours": laborLine.ActualHours,
+		"laborCost":   laborLine.LaborCost,
+	}
+
+	if laborLine.Parts != nil {
+		validationData["parts"] = laborLine.Parts
+		if version < 3 {
+			// Versions 1 and 2 stored part IDs, which are read as one requested unit of each part
+			partIDs := make([]string, len(laborLine.Parts))
+			for i, part := range laborLine.Parts {
+				partIDs[i] = part.PartID
+			}
+			delete(validationData, "parts")
+			validationData["partId"] = partIDs
+		}
+	}
+	if laborLine.Notes != nil {
+		validationData["notes"] = laborLine.Notes
+		if version < 2 {
+			// Version 1 stored notes as plain strings, which are read as note bodies
+			bodies := make([]string, len(laborLine.Notes))
+			for i, note := range laborLine.Notes {
+				bodies[i] = note.Body
+			}
+			validationData["notes"] = bodies
+		}
+	}
+	if laborLine.Description != "" {
+		validationData["description"] = laborLine.Description
+	}
+	if laborLine.OperationCode != "" {
+		validationData["operationCode"] = laborLine.OperationCode
+	}
+	if laborLine.Status != "" {
+		validationData["status"] = laborLine.Status
+	}
+	if laborLine.CreatedBy != "" {
+		validationData["createdBy"] = laborLine.CreatedBy
+	}
+	if laborLine.AssignedTechnicianIDs != nil {
+		validationData["assignedTechnicianIds"] = laborLine.AssignedTechnicianIDs
+	}
+	if laborLine.CustomFields != nil {
+		validationData["customFields"] = laborLine.CustomFields
+	}
+	addRateFields(validationData, &laborLine.EstimatedHours, laborLine.RateType, &laborLine.RatePerHour)
+
+	if err := s.validateData(schema, validationData); err != nil {
+		return fmt.Errorf("schema version %d: %w", version, err)
+	}
+	return nil
+}
+
+// ValidateStartTimerInput validates a StartLaborTimerInput against the time entry schema.
+func (s *validationService) ValidateStartTimerInput(input models.StartLaborTimerInput) error {
+	validationData := map[string]interface{}{
+		"laborLineId":  input.LaborLineID,
+		"accountId":    input.AccountID,
+		"taskId":       input.TaskID,
+		"technicianId": input.TechnicianID,
+	}
+
+	if input.StartTime != nil {
+		validationData["startTime"] = *input.StartTime
+	}
+
+	return s.validateData(s.timeEntrySchema, validationData)
+}
+
+// ValidateStopTimerInput validates a StopLaborTimerInput against the time entry schema.
+func (s *validationService) ValidateStopTimerInput(input models.StopLaborTimerInput) error {
+	validationData := map[string]interface{}{
+		"laborLineId":  input.LaborLineID,
+		"accountId":    input.AccountID,
+		"taskId":       input.TaskID,
+		"technicianId": input.TechnicianID,
+		"breakMinutes": input.BreakMinutes,
+	}
+
+	if input.EndTime != nil {
+		validationData["endTime"] = *input.EndTime
+	}
+
+	return s.validateData(s.timeEntrySchema, validationData)
+}
+
+// ValidateAddTimeEntryInput validates an AddTimeEntryInput against the time entry schema.
+func (s *validationService) ValidateAddTimeEntryInput(input models.AddTimeEntryInput) error {
+	validationData := map[string]interface{}{
+		"laborLineId":  input.LaborLineID,
+		"accountId":    input.AccountID,
+		"taskId":       input.TaskID,
+		"technicianId": input.TechnicianID,
+		"startTime":    input.StartTime,
+		"endTime":      input.EndTime,
+		"breakMinutes": input.BreakMinutes,
+	}
+
+	if err := s.validateData(s.timeEntrySchema, validationData); err != nil {
+		return err
+	}
+
+	return ValidateTimeRange(input.StartTime, input.EndTime, input.BreakMinutes)
+}
+
+// ValidateRateCardInput validates an UpdateRateCardInput against the rate card
+// schema and checks that each rate type appears at most once.
+func (s *validationService) ValidateRateCardInput(input models.UpdateRateCardInput) error {
+	rates := make([]interface{}, 0, len(input.Rates))
+	seen := make(map[models.RateType]bool, len(input.Rates))
+	for i, entry := range input.Rates {
+		if seen[entry.RateType] {
+			return newValidationError(fmt.Sprintf("rates.%d.rateType", i), "unique", fmt.Sprintf("duplicate rate for rate type %s", entry.RateType), entry.RateType)
+		}
+		seen[entry.RateType] = true
+		rates = append(rates, entry)
+	}
+
+	validationData := map[string]interface{}{
+		"accountId": input.AccountID,
+		"rates":     rates,
+	}
+	if input.DefaultRateType != "" {
+		validationData["defaultRateType"] = input.DefaultRateType
+	}
+
+	return s.validateData(s.rateCardSchema, validationData)
+}
+
+// ValidateTransitionStatusInput validates a TransitionLaborLineStatusInput
+// against the status transition schema.
+func (s *validationService) ValidateTransitionStatusInput(input models.TransitionLaborLineStatusInput) error {
+	validationData := map[string]interface{}{
+		"laborLineId": input.LaborLineID,
+		"accountId":   input.AccountID,
+		"taskId":      input.TaskID,
+		"status":      input.Status,
+	}
+
+	if input.Reason != "" {
+		validationData["reason"] = input.Reason
+	}
+
+	return s.validateData(s.statusSchema, validationData)
+}
+
+// ValidateDefineCustomFieldInput validates a DefineCustomFieldInput against the
+// custom field schema and checks that an enum or pattern is only given for a
+// STRING field and that the pattern is a valid regular expression.
+func (s *validationService) ValidateDefineCustomFieldInput(input models.DefineCustomFieldInput) error {
+	validationData := map[string]interface{}{
+		"accountId": input.AccountID,
+		"name":      input.Name,
+		"type":      input.Type,
+		"required":  input.Required,
+	}
+	if input.Enum != nil {
+		validationData["enum"] = input.Enum
+	}
+	if input.Pattern != "" {
+		validationData["pattern"] = input.Pattern
 	}
 	if input.Description != "" {
 		validationData["description"] = input.Description
 	}
 
-	return s.validateData(validationData)
+	if err := s.validateData(s.customFieldSchema, validationData); err != nil {
+		return err
+	}
+
+	if input.Type != models.CustomFieldTypeString {
+		if input.Enum != nil {
+			return newValidationError("enum", "type", "enum is only allowed for STRING fields", input.Enum)
+		}
+		if input.Pattern != "" {
+			return newValidationError("pattern", "type", "pattern is only allowed for STRING fields", input.Pattern)
+		}
+	}
+	if input.Pattern != "" {
+		if _, err := regexp.Compile(input.Pattern); err != nil {
+			return newValidationError("pattern", "format", fmt.Sprintf("invalid regular expression: %v", err), input.Pattern)
+		}
+	}
+
+	return nil
 }
 
-// validateData validates the given data against the JSON schema.
-func (s *validationService) validateData(data map[string]interface{}) error {
+// ValidateAddNoteInput validates an AddLaborLineNoteInput against the note addition schema.
+func (s *validationService) ValidateAddNoteInput(input models.AddLaborLineNoteInput) error {
+	validationData := map[string]interface{}{
+		"laborLineId": input.LaborLineID,
+		"accountId":   input.AccountID,
+		"taskId":      input.TaskID,
+		"body":        input.Body,
+	}
+	if input.Visibility != "" {
+		validationData["visibility"] = input.Visibility
+	}
+
+	return s.validateData(s.noteAdditionSchema, validationData)
+}
+
+// ValidateEditNoteInput validates an EditLaborLineNoteInput against the note
+// edit schema, which requires a new body or visibility.
+func (s *validationService) ValidateEditNoteInput(input models.EditLaborLineNoteInput) error {
+	validationData := map[string]interface{}{
+		"laborLineId": input.LaborLineID,
+		"accountId":   input.AccountID,
+		"taskId":      input.TaskID,
+		"noteId":      input.NoteID,
+	}
+	if input.Body != "" {
+		validationData["body"] = input.Body
+	}
+	if input.Visibility != "" {
+		validationData["visibility"] = input.Visibility
+	}
+
+	return s.validateData(s.noteEditSchema, validationData)
+}
+
+// ValidateDeleteNoteInput validates a DeleteLaborLineNoteInput against the note deletion schema.
+func (s *validationService) ValidateDeleteNoteInput(input models.DeleteLaborLineNoteInput) error {
+	validationData := map[string]interface{}{
+		"laborLineId": input.LaborLineID,
+		"accountId":   input.AccountID,
+		"taskId":      input.TaskID,
+		"noteId":      input.NoteID,
+	}
+
+	return s.validateData(s.noteDeletionSchema, validationData)
+}
+
+// ValidateAssignmentInput validates a LaborLineAssignmentInput against the assignment schema.
+func (s *validationService) ValidateAssignmentInput(input models.LaborLineAssignmentInput) error {
+	validationData := map[string]interface{}{
+		"laborLineId":  input.LaborLineID,
+		"accountId":    input.AccountID,
+		"taskId":       input.TaskID,
+		"technicianId": input.TechnicianID,
+	}
+
+	return s.validateData(s.assignmentSchema, validationData)
+}
+
+// ValidateCreateLaborOperationInput validates a CreateLaborOperationInput against the labor operation schema.
+func (s *validationService) ValidateCreateLaborOperationInput(input models.CreateLaborOperationInput) error {
+	validationData := map[string]interface{}{
+		"accountId":   input.AccountID,
+		"code":        input.Code,
+		"description": input.Description,
+		"bookHours":   input.BookHours,
+	}
+	if input.RateType != "" {
+		validationData["rateType"] = input.RateType
+	}
+	if input.RatePerHour != nil {
+		validationData["ratePerHour"] = *input.RatePerHour
+	}
+	if input.Active != nil {
+		validationData["active"] = *input.Active
+	}
+
+	return s.validateData(s.operationSchema, validationData)
+}
+
+// ValidateUpdateLaborOperationInput validates the fields an
+// UpdateLaborOperationInput sets against the labor operation update schema.
+// Only the rate type and rate per hour may be cleared, and at least one field
+// must change.
+func (s *validationService) ValidateUpdateLaborOperationInput(input models.UpdateLaborOperationInput) error {
+	if !input.HasChanges() {
+		return newValidationError("(root)", "required", "at least one field to change is required", nil)
+	}
+	switch {
+	case input.Description.Null:
+		return newValidationError("description", "required", "description cannot be cleared", nil)
+	case input.BookHours.Null:
+		return newValidationError("bookHours", "required", "bookHours cannot be cleared", nil)
+	case input.Active.Null:
+		return newValidationError("active", "required", "active cannot be cleared", nil)
+	}
+
+	validationData := map[string]interface{}{
+		"accountId": input.AccountID,
+		"code":      input.Code,
+	}
+	if input.Description.HasValue() {
+		validationData["description"] = input.Description.Value
+	}
+	if input.BookHours.HasValue() {
+		validationData["bookHours"] = input.BookHours.Value
+	}
+	if input.RateType.HasValue() {
+		validationData["rateType"] = input.RateType.Value
+	}
+	if input.RatePerHour.HasValue() {
+		validationData["ratePerHour"] = input.RatePerHour.Value
+	}
+	if input.Active.HasValue() {
+		validationData["active"] = input.Active.Value
+	}
+
+	return s.validateData(s.operationUpdate, validationData)
+}
+
+// ValidateTimeRange checks that a time entry ends after it starts and that the
+// break does not exceed the elapsed time.
+func ValidateTimeRange(startTime, endTime, breakMinutes int64) error {
+	if endTime <= startTime {
+		return newValidationError("endTime", "after", "endTime must be after startTime", endTime)
+	}
+	if breakMinutes*60 > endTime-startTime {
+		return newValidationError("breakMinutes", "maximum", "breakMinutes exceeds the elapsed time", breakMinutes)
+	}
+	return nil
+}
+
+// validateData validates the given data against a JSON schema. Every invalid
+// field is reported in the returned ValidationError.
+func (s *validationService) validateData(schema *gojsonschema.Schema, data map[string]interface{}) error {
+	var fields []FieldError
+
 	// Additional UUID validation
-	if err := s.validateUUIDs(data); err != nil {
-		return err
+	var uuidErr *ValidationError
+	if errors.As(s.validateUUIDs(data), &uuidErr) {
+		fields = append(fields, uuidErr.Fields...)
 	}
 
 	// Validate against JSON schema
 	dataLoader := gojsonschema.NewGoLoader(data)
-	result, err := s.schema.Validate(dataLoader)
+	result, err := schema.Validate(dataLoader)
 	if err != nil {
 		return fmt.Errorf("schema validation error: %w", err)
 	}
 
-	if !result.Valid() {
-		var errors []string
-		for _, desc := range result.Errors() {
-			errors = append(errors, desc.String())
+	for _, desc := range result.Errors() {
+		field := schemaErrorField(desc)
+		if hasFieldError(fields, field) {
+			// A field's UUID error already covers the schema's format error
+			continue
 		}
-		return fmt.Errorf("validation failed: %v", errors)
+		fields = append(fields, FieldError{
+			Field:   field,
+			Rule:    desc.Type(),
+			Message: desc.Description(),
+			Value:   desc.Value(),
+		})
 	}
 
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
 	return nil
 }
 
+// schemaErrorField returns the path of the field a schema error is about.
+// Errors about a missing or unexpected property are reported by the schema
+// against the enclosing object, so the property is appended.
+func schemaErrorField(desc gojsonschema.ResultError) string {
+	field := desc.Field()
+	property, ok := desc.Details()["property"].(string)
+	if !ok {
+		return field
+	}
+	if field == gojsonschema.STRING_CONTEXT_ROOT {
+		return property
+	}
+	return field + "." + property
+}
+
+// hasFieldError reports whether fields already holds an error for field.
+func hasFieldError(fields []FieldError, field string) bool {
+	for _, f := range fields {
+		if f.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
 // validateUUIDs validates that all UUID fields are properly formatted.
 func (s *validationService) validateUUIDs(data map[string]interface{}) error {
-	uuidFields := []string{"laborLineId", "accountId", "taskId"}
+	var fields []FieldError
+	uuidFields := []string{"laborLineId", "accountId", "taskId", "noteId"}
 
 	for _, field := range uuidFields {
 		if value, exists := data[field]; exists {
 			if strValue, ok := value.(string); ok {
 				if _, err := uuid.Parse(strValue); err != nil {
-					return fmt.Errorf("invalid UUID format for field %s: %s", field, strValue)
+					fields = append(fields, FieldError{Field: field, Rule: "format", Message: "invalid UUID format", Value: strValue})
 				}
 			}
 		}
 	}
 
-	// Validate partId array if present
-	if partID, exists := data["partId"]; exists {
-		if partArray, ok := partID.([]string); ok {
+	// Validate the part IDs of part line items if present
+	if parts, exists := data["parts"]; exists {
+		if partArray, ok := parts.([]models.PartLineItem); ok {
 			for i, part := range partArray {
-				if _, err := uuid.Parse(part); err != nil {
-					return fmt.Errorf("invalid UUID format for partId[%d]: %s", i, part)
+				if _, err := uuid.Parse(part.PartID); err != nil {
+					fields = append(fields, FieldError{Field: fmt.Sprintf("parts.%d.partId", i), Rule: "format", Message: "invalid UUID format", Value: part.PartID})
 				}
 			}
 		}
 	}
 
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
 	return nil
 }
@@ -1,13 +1,17 @@
 package services
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
 
 	"steverhoton-labor-lines/lambda/models"
+	"steverhoton-labor-lines/lambda/schemas"
 )
 
 func TestNewValidationServiceWithEmbeddedSchema(t *testing.T) {
@@ -29,10 +33,13 @@ func TestValidationService_ValidateCreateInput(t *testing.T) {
 		{
 			name: "Valid input with all fields",
 			input: models.CreateLaborLineInput{
-				AccountID:   uuid.New().String(),
-				TaskID:      uuid.New().String(),
-				PartID:      []string{uuid.New().String(), uuid.New().String()},
-				Notes:       []string{"Valid note", "Another valid note"},
+				AccountID: uuid.New().String(),
+				TaskID:    uuid.New().String(),
+				Parts: []models.PartLineItem{
+					{PartID: uuid.New().String(), Quantity: models.NewDecimalFromInt(2), UnitCost: func() *models.Decimal { d := models.MustParseDecimal("42.50"); return &d }()},
+					{PartID: uuid.New().String(), Quantity: models.MustParseDecimal("0.5"), UnitOfMeasure: "QT", Status: models.PartStatusIssued},
+				},
+				Notes:       []models.NoteInput{{Body: "Valid note"}, {Visibility: models.NoteVisibilityCustomer, Body: "Another valid note"}},
 				Description: "Complete brake system maintenance and inspection",
 			},
 			wantError: false,
@@ -78,11 +85,11 @@ func TestValidationService_ValidateCreateInput(t *testing.T) {
 			errorMsg:  "invalid UUID format",
 		},
 		{
-			name: "Invalid partId UUID",
+			name: "Invalid part ID UUID",
 			input: models.CreateLaborLineInput{
 				AccountID: uuid.New().String(),
 				TaskID:    uuid.New().String(),
-				PartID:    []string{"invalid-uuid"},
+				Parts:     []models.PartLineItem{{PartID: "invalid-uuid", Quantity: models.NewDecimalFromInt(1)}},
 			},
 			wantError: true,
 			errorMsg:  "invalid UUID format",
@@ -92,7 +99,7 @@ func TestValidationService_ValidateCreateInput(t *testing.T) {
 			input: models.CreateLaborLineInput{
 				AccountID: uuid.New().String(),
 				TaskID:    uuid.New().String(),
-				Notes:     []string{""},
+				Notes:     []models.NoteInput{{Body: ""}},
 			},
 			wantError: true,
 		},
@@ -101,7 +108,16 @@ func TestValidationService_ValidateCreateInput(t *testing.T) {
 			input: models.CreateLaborLineInput{
 				AccountID: uuid.New().String(),
 				TaskID:    uuid.New().String(),
-				Notes:     []string{generateLongString(1001)},
+				Notes:     []models.NoteInput{{Body: generateLongString(1001)}},
+			},
+			wantError: true,
+		},
+		{
+			name: "Unknown note visibility",
+			input: models.CreateLaborLineInput{
+				AccountID: uuid.New().String(),
+				TaskID:    uuid.New().String(),
+				Notes:     []models.NoteInput{{Visibility: "PUBLIC", Body: "Replace worn brake pads"}},
 			},
 			wantError: true,
 		},
@@ -145,7 +161,7 @@ func TestValidationService_ValidateCreateInput(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validationService.ValidateCreateInput(tt.input)
+			err := validationService.ValidateCreateInput(tt.input, nil)
 
 			if tt.wantError {
 				assert.Error(t, err)
@@ -175,9 +191,8 @@ func TestValidationService_ValidateUpdateInput(t *testing.T) {
 				LaborLineID: uuid.New().String(),
 				AccountID:   uuid.New().String(),
 				TaskID:      uuid.New().String(),
-				PartID:      []string{uuid.New().String()},
-				Notes:       []string{"Updated note"},
-				Description: "Updated maintenance description",
+				Parts:       models.NewNullable([]models.PartLineItem{{PartID: uuid.New().String(), Quantity: models.NewDecimalFromInt(1)}}),
+				Description: models.NewNullable("Updated maintenance description"),
 			},
 			wantError: false,
 		},
@@ -214,17 +229,18 @@ func TestValidationService_ValidateUpdateInput(t *testing.T) {
 				LaborLineID: uuid.New().String(),
 				AccountID:   uuid.New().String(),
 				TaskID:      uuid.New().String(),
-				Description: "Updated description for maintenance work",
+				Description: models.NewNullable("Updated description for maintenance work"),
 			},
 			wantError: false,
 		},
 		{
-			name: "Valid update with empty description",
+			name: "Valid update clearing fields",
 			input: models.UpdateLaborLineInput{
 				LaborLineID: uuid.New().String(),
 				AccountID:   uuid.New().String(),
 				TaskID:      uuid.New().String(),
-				Description: "",
+				Description: models.NullValue[string](),
+				Parts:       models.NullValue[[]models.PartLineItem](),
 			},
 			wantError: false,
 		},
@@ -234,15 +250,154 @@ func TestValidationService_ValidateUpdateInput(t *testing.T) {
 				LaborLineID: uuid.New().String(),
 				AccountID:   uuid.New().String(),
 				TaskID:      uuid.New().String(),
-				Description: generateLongString(1001),
+				Description: models.NewNullable(generateLongString(1001)),
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validationService.ValidateUpdateInput(tt.input, nil)
+
+			if tt.wantError {
+				assert.Error(t, err)
+				if tt.errorMsg != "" {
+					assert.Contains(t, err.Error(), tt.errorMsg)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidationService_ValidateTimeEntryInputs(t *testing.T) {
+	validationService, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+	startTime := int64(1700000000)
+
+	tests := []struct {
+		name      string
+		validate  func() error
+		wantError bool
+		errorMsg  string
+	}{
+		{
+			name: "Valid start timer",
+			validate: func() error {
+				return validationService.ValidateStartTimerInput(models.StartLaborTimerInput{
+					AccountID:    accountID,
+					TaskID:       taskID,
+					LaborLineID:  laborLineID,
+					TechnicianID: "tech-1",
+					StartTime:    &startTime,
+				})
+			},
+		},
+		{
+			name: "Start timer without technician",
+			validate: func() error {
+				return validationService.ValidateStartTimerInput(models.StartLaborTimerInput{
+					AccountID:   accountID,
+					TaskID:      taskID,
+					LaborLineID: laborLineID,
+				})
+			},
+			wantError: true,
+		},
+		{
+			name: "Start timer with invalid labor line UUID",
+			validate: func() error {
+				return validationService.ValidateStartTimerInput(models.StartLaborTimerInput{
+					AccountID:    accountID,
+					TaskID:       taskID,
+					LaborLineID:  "invalid-uuid",
+					TechnicianID: "tech-1",
+				})
 			},
 			wantError: true,
+			errorMsg:  "invalid UUID format",
+		},
+		{
+			name: "Valid stop timer",
+			validate: func() error {
+				return validationService.ValidateStopTimerInput(models.StopLaborTimerInput{
+					AccountID:    accountID,
+					TaskID:       taskID,
+					LaborLineID:  laborLineID,
+					TechnicianID: "tech-1",
+					BreakMinutes: 15,
+				})
+			},
+		},
+		{
+			name: "Stop timer with negative break",
+			validate: func() error {
+				return validationService.ValidateStopTimerInput(models.StopLaborTimerInput{
+					AccountID:    accountID,
+					TaskID:       taskID,
+					LaborLineID:  laborLineID,
+					TechnicianID: "tech-1",
+					BreakMinutes: -5,
+				})
+			},
+			wantError: true,
+		},
+		{
+			name: "Valid time entry",
+			validate: func() error {
+				return validationService.ValidateAddTimeEntryInput(models.AddTimeEntryInput{
+					AccountID:    accountID,
+					TaskID:       taskID,
+					LaborLineID:  laborLineID,
+					TechnicianID: "tech-1",
+					StartTime:    startTime,
+					EndTime:      startTime + 3600,
+					BreakMinutes: 10,
+				})
+			},
+		},
+		{
+			name: "Time entry ending before it starts",
+			validate: func() error {
+				return validationService.ValidateAddTimeEntryInput(models.AddTimeEntryInput{
+					AccountID:    accountID,
+					TaskID:       taskID,
+					LaborLineID:  laborLineID,
+					TechnicianID: "tech-1",
+					StartTime:    startTime,
+					EndTime:      startTime - 60,
+				})
+			},
+			wantError: true,
+			errorMsg:  "endTime must be after startTime",
+		},
+		{
+			name: "Time entry with break longer than the entry",
+			validate: func() error {
+				return validationService.ValidateAddTimeEntryInput(models.AddTimeEntryInput{
+					AccountID:    accountID,
+					TaskID:       taskID,
+					LaborLineID:  laborLineID,
+					TechnicianID: "tech-1",
+					StartTime:    startTime,
+					EndTime:      startTime + 600,
+					BreakMinutes: 11,
+				})
+			},
+			wantError: true,
+			errorMsg:  "breakMinutes exceeds",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validationService.ValidateUpdateInput(tt.input)
+			err := tt.validate()
 
 			if tt.wantError {
 				assert.Error(t, err)
@@ -256,6 +411,284 @@ func TestValidationService_ValidateUpdateInput(t *testing.T) {
 	}
 }
 
+func TestValidationService_ValidateRateInputs(t *testing.T) {
+	validationService, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+
+	decimal := func(s string) *models.Decimal {
+		d := models.MustParseDecimal(s)
+		return &d
+	}
+	accountID := uuid.New().String()
+
+	tests := []struct {
+		name      string
+		validate  func() error
+		wantError bool
+		errorMsg  string
+	}{
+		{
+			name: "Create with hours and rate",
+			validate: func() error {
+				return validationService.ValidateCreateInput(models.CreateLaborLineInput{
+					AccountID:      accountID,
+					TaskID:         uuid.New().String(),
+					EstimatedHours: decimal("1.25"),
+					RateType:       models.RateTypeWarranty,
+					RatePerHour:    decimal("89.95"),
+				}, nil)
+			},
+		},
+		{
+			name: "Create with negative estimated hours",
+			validate: func() error {
+				return validationService.ValidateCreateInput(models.CreateLaborLineInput{
+					AccountID:      accountID,
+					TaskID:         uuid.New().String(),
+					EstimatedHours: decimal("-1"),
+				}, nil)
+			},
+			wantError: true,
+		},
+		{
+			name: "Create with unknown rate type",
+			validate: func() error {
+				return validationService.ValidateCreateInput(models.CreateLaborLineInput{
+					AccountID: accountID,
+					TaskID:    uuid.New().String(),
+					RateType:  "OVERTIME",
+				}, nil)
+			},
+			wantError: true,
+		},
+		{
+			name: "Update with negative rate",
+			validate: func() error {
+				return validationService.ValidateUpdateInput(models.UpdateLaborLineInput{
+					LaborLineID: uuid.New().String(),
+					AccountID:   accountID,
+					TaskID:      uuid.New().String(),
+					RatePerHour: models.NewNullable(*decimal("-0.01")),
+				}, nil)
+			},
+			wantError: true,
+		},
+		{
+			name: "Valid rate card",
+			validate: func() error {
+				return validationService.ValidateRateCardInput(models.UpdateRateCardInput{
+					AccountID:       accountID,
+					DefaultRateType: models.RateTypeHourly,
+					Rates: []models.RateCardEntry{
+						{RateType: models.RateTypeHourly, RatePerHour: models.MustParseDecimal("95")},
+						{RateType: models.RateTypeInternal, RatePerHour: models.MustParseDecimal("0")},
+					},
+				})
+			},
+		},
+		{
+			name: "Rate card with duplicate rate type",
+			validate: func() error {
+				return validationService.ValidateRateCardInput(models.UpdateRateCardInput{
+					AccountID: accountID,
+					Rates: []models.RateCardEntry{
+						{RateType: models.RateTypeHourly, RatePerHour: models.MustParseDecimal("95")},
+						{RateType: models.RateTypeHourly, RatePerHour: models.MustParseDecimal("100")},
+					},
+				})
+			},
+			wantError: true,
+			errorMsg:  "duplicate rate",
+		},
+		{
+			name: "Rate card with unknown rate type",
+			validate: func() error {
+				return validationService.ValidateRateCardInput(models.UpdateRateCardInput{
+					AccountID: accountID,
+					Rates: []models.RateCardEntry{
+						{RateType: "OVERTIME", RatePerHour: models.MustParseDecimal("150")},
+					},
+				})
+			},
+			wantError: true,
+		},
+		{
+			name: "Rate card with invalid account",
+			validate: func() error {
+				return validationService.ValidateRateCardInput(models.UpdateRateCardInput{
+					AccountID: "not-a-uuid",
+					Rates:     []models.RateCardEntry{},
+				})
+			},
+			wantError: true,
+			errorMsg:  "invalid UUID format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validate()
+
+			if tt.wantError {
+				assert.Error(t, err)
+				if tt.errorMsg != "" {
+					assert.Contains(t, err.Error(), tt.errorMsg)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidationService_ValidateTransitionStatusInput(t *testing.T) {
+	validationService, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		input     models.TransitionLaborLineStatusInput
+		wantError bool
+		errorMsg  string
+	}{
+		{
+			name: "Valid transition",
+			input: models.TransitionLaborLineStatusInput{
+				AccountID:   uuid.New().String(),
+				TaskID:      uuid.New().String(),
+				LaborLineID: uuid.New().String(),
+				Status:      models.StatusCompleted,
+				Reason:      "Work finished",
+			},
+		},
+		{
+			name: "Unknown status",
+			input: models.TransitionLaborLineStatusInput{
+				AccountID:   uuid.New().String(),
+				TaskID:      uuid.New().String(),
+				LaborLineID: uuid.New().String(),
+				Status:      "CANCELLED",
+			},
+			wantError: true,
+		},
+		{
+			name: "Missing status",
+			input: models.TransitionLaborLineStatusInput{
+				AccountID:   uuid.New().String(),
+				TaskID:      uuid.New().String(),
+				LaborLineID: uuid.New().String(),
+			},
+			wantError: true,
+		},
+		{
+			name: "Reason too long",
+			input: models.TransitionLaborLineStatusInput{
+				AccountID:   uuid.New().String(),
+				TaskID:      uuid.New().String(),
+				LaborLineID: uuid.New().String(),
+				Status:      models.StatusOnHold,
+				Reason:      strings.Repeat("a", 501),
+			},
+			wantError: true,
+		},
+		{
+			name: "Invalid labor line UUID",
+			input: models.TransitionLaborLineStatusInput{
+				AccountID:   uuid.New().String(),
+				TaskID:      uuid.New().String(),
+				LaborLineID: "not-a-uuid",
+				Status:      models.StatusOnHold,
+			},
+			wantError: true,
+			errorMsg:  "invalid UUID format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validationService.ValidateTransitionStatusInput(tt.input)
+
+			if tt.wantError {
+				assert.Error(t, err)
+				if tt.errorMsg != "" {
+					assert.Contains(t, err.Error(), tt.errorMsg)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidationService_ValidateCreateInput_FieldErrors(t *testing.T) {
+	validationService, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+
+	err = validationService.ValidateCreateInput(models.CreateLaborLineInput{
+		AccountID: "invalid-uuid",
+		Parts: []models.PartLineItem{
+			{PartID: uuid.New().String(), Quantity: models.NewDecimalFromInt(1)},
+			{PartID: "bad-part", Quantity: models.NewDecimalFromInt(1)},
+		},
+		Notes: []models.NoteInput{{Body: ""}, {Body: generateLongString(1001)}},
+	}, nil)
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+
+	// Every invalid field is reported, once, with the rule it breaks
+	byField := map[string]FieldError{}
+	for _, field := range validationErr.Fields {
+		_, duplicate := byField[field.Field]
+		assert.False(t, duplicate, "field %s reported twice", field.Field)
+		byField[field.Field] = field
+	}
+	assert.Len(t, byField, 5)
+	assert.Equal(t, FieldError{Field: "accountId", Rule: "format", Message: "invalid UUID format", Value: "invalid-uuid"}, byField["accountId"])
+	assert.Equal(t, FieldError{Field: "parts.1.partId", Rule: "format", Message: "invalid UUID format", Value: "bad-part"}, byField["parts.1.partId"])
+	assert.Equal(t, FieldError{Field: "taskId", Rule: "format", Message: "invalid UUID format", Value: ""}, byField["taskId"])
+	assert.Equal(t, "string_gte", byField["notes.0.body"].Rule)
+	assert.Equal(t, "string_lte", byField["notes.1.body"].Rule)
+	assert.NotEmpty(t, byField["notes.1.body"].Message)
+}
+
+func TestValidationService_validateData_RequiredField(t *testing.T) {
+	vs, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+	validationService := vs.(*validationService)
+
+	err = validationService.validateData(validationService.schema, map[string]interface{}{
+		"laborLineId": uuid.New().String(),
+		"accountId":   uuid.New().String(),
+	})
+
+	// The missing property is named, not the object that lacks it
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Len(t, validationErr.Fields, 1)
+	assert.Equal(t, "taskId", validationErr.Fields[0].Field)
+	assert.Equal(t, "required", validationErr.Fields[0].Rule)
+}
+
+func TestValidationService_ValidateRateCardInput_DuplicateRateField(t *testing.T) {
+	validationService, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+
+	err = validationService.ValidateRateCardInput(models.UpdateRateCardInput{
+		AccountID: uuid.New().String(),
+		Rates: []models.RateCardEntry{
+			{RateType: models.RateTypeHourly, RatePerHour: models.MustParseDecimal("90")},
+			{RateType: models.RateTypeHourly, RatePerHour: models.MustParseDecimal("95")},
+		},
+	})
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Len(t, validationErr.Fields, 1)
+	assert.Equal(t, "rates.1.rateType", validationErr.Fields[0].Field)
+	assert.Equal(t, "unique", validationErr.Fields[0].Rule)
+}
+
 func TestValidationService_validateUUIDs(t *testing.T) {
 	vs, err := NewValidationServiceWithEmbeddedSchema()
 	require.NoError(t, err)
@@ -273,7 +706,7 @@ func TestValidationService_validateUUIDs(t *testing.T) {
 				"laborLineId": uuid.New().String(),
 				"accountId":   uuid.New().String(),
 				"taskId":      uuid.New().String(),
-				"partId":      []string{uuid.New().String(), uuid.New().String()},
+				"parts":       []models.PartLineItem{{PartID: uuid.New().String()}, {PartID: uuid.New().String()}},
 			},
 			wantError: false,
 		},
@@ -283,15 +716,15 @@ func TestValidationService_validateUUIDs(t *testing.T) {
 				"laborLineId": "invalid-uuid",
 			},
 			wantError: true,
-			errorMsg:  "invalid UUID format for field laborLineId",
+			errorMsg:  "laborLineId: invalid UUID format",
 		},
 		{
-			name: "Invalid partId UUID",
+			name: "Invalid part ID UUID",
 			data: map[string]interface{}{
-				"partId": []string{"invalid-uuid"},
+				"parts": []models.PartLineItem{{PartID: "invalid-uuid"}},
 			},
 			wantError: true,
-			errorMsg:  "invalid UUID format for partId[0]",
+			errorMsg:  "parts.0.partId: invalid UUID format",
 		},
 	}
 
@@ -317,3 +750,473 @@ func generateLongString(length int) string {
 	}
 	return string(result)
 }
+
+func TestValidationService_ValidateLaborLine(t *testing.T) {
+	validationService, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+
+	newLaborLine := func(schemaVersion int) *models.LaborLine {
+		return &models.LaborLine{
+			LaborLineID:    uuid.New().String(),
+			AccountID:      uuid.New().String(),
+			TaskID:         uuid.New().String(),
+			Description:    "Replace brake pads",
+			EstimatedHours: models.NewDecimalFromInt(2),
+			RateType:       models.RateTypeHourly,
+			RatePerHour:    models.NewDecimalFromInt(150),
+			LaborCost:      models.NewDecimalFromInt(300),
+			Status:         models.StatusPending,
+			CreatedBy:      "user-123",
+			SchemaVersion:  schemaVersion,
+		}
+	}
+
+	t.Run("Current version", func(t *testing.T) {
+		assert.NoError(t, validationService.ValidateLaborLine(newLaborLine(schemas.CurrentVersion)))
+	})
+
+	t.Run("Written before versions were recorded", func(t *testing.T) {
+		assert.NoError(t, validationService.ValidateLaborLine(newLaborLine(0)))
+	})
+
+	t.Run("Part IDs read as part line items", func(t *testing.T) {
+		laborLine := newLaborLine(2)
+		laborLine.LegacyPartIDs = []string{uuid.New().String()}
+		laborLine.MigrateParts()
+		assert.NoError(t, validationService.ValidateLaborLine(laborLine))
+	})
+
+	t.Run("Part line items", func(t *testing.T) {
+		laborLine := newLaborLine(schemas.CurrentVersion)
+		laborLine.Parts = []models.PartLineItem{{PartID: uuid.New().String(), Quantity: models.NewDecimalFromInt(2), UnitOfMeasure: "EA", Status: models.PartStatusIssued}}
+		assert.NoError(t, validationService.ValidateLaborLine(laborLine))
+
+		laborLine.Parts[0].Quantity = models.Decimal{}
+		var validationErr *ValidationError
+		require.ErrorAs(t, validationService.ValidateLaborLine(laborLine), &validationErr)
+		assert.Equal(t, "parts.0.quantity", validationErr.Fields[0].Field)
+	})
+
+	t.Run("Unknown version", func(t *testing.T) {
+		err := validationService.ValidateLaborLine(newLaborLine(99))
+		assert.ErrorIs(t, err, ErrUnknownSchemaVersion)
+	})
+
+	t.Run("Invalid stored value", func(t *testing.T) {
+		laborLine := newLaborLine(schemas.CurrentVersion)
+		laborLine.Status = "DONE"
+
+		var validationErr *ValidationError
+		require.ErrorAs(t, validationService.ValidateLaborLine(laborLine), &validationErr)
+		assert.Equal(t, "status", validationErr.Fields[0].Field)
+	})
+}
+
+func TestValidationService_ValidateLaborLine_SideBySideVersions(t *testing.T) {
+	document, err := schemas.LaborLine(schemas.CurrentVersion)
+	require.NoError(t, err)
+
+	// A later version that also requires a description
+	var next map[string]interface{}
+	require.NoError(t, json.Unmarshal(document, &next))
+	next["required"] = append(next["required"].([]interface{}), "description")
+	nextDocument, err := json.Marshal(next)
+	require.NoError(t, err)
+
+	validationService, err := newValidationService(map[int]gojsonschema.JSONLoader{
+		schemas.CurrentVersion:     gojsonschema.NewBytesLoader(document),
+		schemas.CurrentVersion + 1: gojsonschema.NewBytesLoader(nextDocument),
+	})
+	require.NoError(t, err)
+
+	laborLine := &models.LaborLine{
+		LaborLineID:   uuid.New().String(),
+		AccountID:     uuid.New().String(),
+		TaskID:        uuid.New().String(),
+		SchemaVersion: schemas.CurrentVersion,
+	}
+	assert.NoError(t, validationService.ValidateLaborLine(laborLine))
+
+	laborLine.SchemaVersion = schemas.CurrentVersion + 1
+	var validationErr *ValidationError
+	require.ErrorAs(t, validationService.ValidateLaborLine(laborLine), &validationErr)
+	assert.Equal(t, "description", validationErr.Fields[0].Field)
+}
+
+func TestValidationService_ValidateCreateInput_CustomFields(t *testing.T) {
+	validationService, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+
+	accountID := uuid.New().String()
+	definitions := []*models.CustomFieldDefinition{
+		{AccountID: accountID, Name: "bayNumber", Type: models.CustomFieldTypeInteger, Required: true},
+		{AccountID: accountID, Name: "vmrsCode", Type: models.CustomFieldTypeString, Pattern: "^[0-9]{3}-[0-9]{3}-[0-9]{3}$"},
+		{AccountID: accountID, Name: "shift", Type: models.CustomFieldTypeString, Enum: []string{"DAY", "NIGHT"}},
+	}
+
+	tests := []struct {
+		name          string
+		customFields  map[string]interface{}
+		definitions   []*models.CustomFieldDefinition
+		expectedField string
+		expectedRule  string
+	}{
+		{
+			name:         "Valid custom fields",
+			customFields: map[string]interface{}{"bayNumber": float64(4), "vmrsCode": "013-001-001", "shift": "DAY"},
+			definitions:  definitions,
+		},
+		{
+			name:          "Missing required field",
+			customFields:  map[string]interface{}{"shift": "NIGHT"},
+			definitions:   definitions,
+			expectedField: "customFields.bayNumber",
+			expectedRule:  "required",
+		},
+		{
+			name:          "No custom fields when one is required",
+			definitions:   definitions,
+			expectedField: "customFields",
+			expectedRule:  "required",
+		},
+		{
+			name:          "Wrong type",
+			customFields:  map[string]interface{}{"bayNumber": "four"},
+			definitions:   definitions,
+			expectedField: "customFields.bayNumber",
+			expectedRule:  "invalid_type",
+		},
+		{
+			name:          "Pattern mismatch",
+			customFields:  map[string]interface{}{"bayNumber": float64(4), "vmrsCode": "13-1-1"},
+			definitions:   definitions,
+			expectedField: "customFields.vmrsCode",
+			expectedRule:  "pattern",
+		},
+		{
+			name:          "Value outside enum",
+			customFields:  map[string]interface{}{"bayNumber": float64(4), "shift": "SWING"},
+			definitions:   definitions,
+			expectedField: "customFields.shift",
+			expectedRule:  "enum",
+		},
+		{
+			name:          "Undefined field",
+			customFields:  map[string]interface{}{"odometer": float64(120000)},
+			expectedField: "customFields.odometer",
+			expectedRule:  "additional_property_not_allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validationService.ValidateCreateInput(models.CreateLaborLineInput{
+				AccountID:    accountID,
+				TaskID:       uuid.New().String(),
+				CustomFields: tt.customFields,
+			}, tt.definitions)
+
+			if tt.expectedField == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			require.Len(t, validationErr.Fields, 1)
+			assert.Equal(t, tt.expectedField, validationErr.Fields[0].Field)
+			assert.Equal(t, tt.expectedRule, validationErr.Fields[0].Rule)
+		})
+	}
+
+	// The base schema is left unchanged by composition
+	assert.NoError(t, validationService.ValidateCreateInput(models.CreateLaborLineInput{
+		AccountID: accountID,
+		TaskID:    uuid.New().String(),
+	}, nil))
+}
+
+func TestValidationService_ValidateUpdateInput_CustomFields(t *testing.T) {
+	validationService, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+
+	accountID := uuid.New().String()
+	definitions := []*models.CustomFieldDefinition{
+		{AccountID: accountID, Name: "bayNumber", Type: models.CustomFieldTypeInteger, Required: true},
+	}
+	input := models.UpdateLaborLineInput{
+		LaborLineID: uuid.New().String(),
+		AccountID:   accountID,
+		TaskID:      uuid.New().String(),
+	}
+
+	// Custom fields left out of an update are not checked
+	assert.NoError(t, validationService.ValidateUpdateInput(input, definitions))
+
+	input.CustomFields = models.NewNullable(map[string]interface{}{"bayNumber": float64(4)})
+	assert.NoError(t, validationService.ValidateUpdateInput(input, definitions))
+
+	// Clearing them is rejected while one is required
+	input.CustomFields = models.NullValue[map[string]interface{}]()
+	err = validationService.ValidateUpdateInput(input, definitions)
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "customFields", validationErr.Fields[0].Field)
+	assert.Equal(t, "required", validationErr.Fields[0].Rule)
+}
+
+func TestValidationService_ValidateDefineCustomFieldInput(t *testing.T) {
+	validationService, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+
+	accountID := uuid.New().String()
+
+	tests := []struct {
+		name          string
+		input         models.DefineCustomFieldInput
+		expectedField string
+	}{
+		{
+			name:  "Valid string field",
+			input: models.DefineCustomFieldInput{AccountID: accountID, Name: "vmrsCode", Type: models.CustomFieldTypeString, Pattern: "^[0-9]{3}$"},
+		},
+		{
+			name:  "Valid required number",
+			input: models.DefineCustomFieldInput{AccountID: accountID, Name: "odometerAtStart", Type: models.CustomFieldTypeNumber, Required: true},
+		},
+		{
+			name:          "Name with spaces",
+			input:         models.DefineCustomFieldInput{AccountID: accountID, Name: "bay number", Type: models.CustomFieldTypeString},
+			expectedField: "name",
+		},
+		{
+			name:          "Unknown type",
+			input:         models.DefineCustomFieldInput{AccountID: accountID, Name: "bayNumber", Type: "DATE"},
+			expectedField: "type",
+		},
+		{
+			name:          "Enum on a number",
+			input:         models.DefineCustomFieldInput{AccountID: accountID, Name: "bayNumber", Type: models.CustomFieldTypeInteger, Enum: []string{"1", "2"}},
+			expectedField: "enum",
+		},
+		{
+			name:          "Invalid regular expression",
+			input:         models.DefineCustomFieldInput{AccountID: accountID, Name: "vmrsCode", Type: models.CustomFieldTypeString, Pattern: "([0-9]"},
+			expectedField: "pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validationService.ValidateDefineCustomFieldInput(tt.input)
+			if tt.expectedField == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.expectedField, validationErr.Fields[0].Field)
+		})
+	}
+}
+
+func TestValidationService_ValidateNoteInputs(t *testing.T) {
+	validationService, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+
+	accountID := uuid.New().String()
+	taskID := uuid.New().String()
+	laborLineID := uuid.New().String()
+
+	tests := []struct {
+		name          string
+		validate      func() error
+		expectedField string
+	}{
+		{
+			name: "Valid note",
+			validate: func() error {
+				return validationService.ValidateAddNoteInput(models.AddLaborLineNoteInput{
+					AccountID: accountID, TaskID: taskID, LaborLineID: laborLineID,
+					Visibility: models.NoteVisibilityCustomer, Body: "Replace worn brake pads",
+				})
+			},
+		},
+		{
+			name: "Note without a body",
+			validate: func() error {
+				return validationService.ValidateAddNoteInput(models.AddLaborLineNoteInput{
+					AccountID: accountID, TaskID: taskID, LaborLineID: laborLineID,
+				})
+			},
+			expectedField: "body",
+		},
+		{
+			name: "Valid edit",
+			validate: func() error {
+				return validationService.ValidateEditNoteInput(models.EditLaborLineNoteInput{
+					AccountID: accountID, TaskID: taskID, LaborLineID: laborLineID,
+					NoteID: uuid.New().String(), Visibility: models.NoteVisibilityInternal,
+				})
+			},
+		},
+		{
+			name: "Edit with an unknown visibility",
+			validate: func() error {
+				return validationService.ValidateEditNoteInput(models.EditLaborLineNoteInput{
+					AccountID: accountID, TaskID: taskID, LaborLineID: laborLineID,
+					NoteID: uuid.New().String(), Visibility: "PUBLIC",
+				})
+			},
+			expectedField: "visibility",
+		},
+		{
+			name: "Edit that changes nothing",
+			validate: func() error {
+				return validationService.ValidateEditNoteInput(models.EditLaborLineNoteInput{
+					AccountID: accountID, TaskID: taskID, LaborLineID: laborLineID,
+					NoteID: uuid.New().String(),
+				})
+			},
+			expectedField: "(root)",
+		},
+		{
+			name: "Delete with an invalid note ID",
+			validate: func() error {
+				return validationService.ValidateDeleteNoteInput(models.DeleteLaborLineNoteInput{
+					AccountID: accountID, TaskID: taskID, LaborLineID: laborLineID,
+					NoteID: "not-a-uuid",
+				})
+			},
+			expectedField: "noteId",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validate()
+			if tt.expectedField == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.expectedField, validationErr.Fields[0].Field)
+		})
+	}
+}
+
+func TestValidationService_ValidateAssignmentInput(t *testing.T) {
+	validationService, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+
+	valid := models.LaborLineAssignmentInput{
+		AccountID:    uuid.New().String(),
+		TaskID:       uuid.New().String(),
+		LaborLineID:  uuid.New().String(),
+		TechnicianID: "tech-1",
+	}
+	assert.NoError(t, validationService.ValidateAssignmentInput(valid))
+
+	tests := []struct {
+		name          string
+		modify        func(input *models.LaborLineAssignmentInput)
+		expectedField string
+	}{
+		{"Missing technician", func(input *models.LaborLineAssignmentInput) { input.TechnicianID = "" }, "technicianId"},
+		{"Invalid labor line ID", func(input *models.LaborLineAssignmentInput) { input.LaborLineID = "not-a-uuid" }, "laborLineId"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := valid
+			tt.modify(&input)
+
+			var validationErr *ValidationError
+			require.ErrorAs(t, validationService.ValidateAssignmentInput(input), &validationErr)
+			assert.Equal(t, tt.expectedField, validationErr.Fields[0].Field)
+		})
+	}
+}
+
+func TestValidationService_ValidateLaborOperationInputs(t *testing.T) {
+	validationService, err := NewValidationServiceWithEmbeddedSchema()
+	require.NoError(t, err)
+
+	accountID := uuid.New().String()
+
+	tests := []struct {
+		name          string
+		validate      func() error
+		expectedField string
+	}{
+		{
+			name: "Valid operation",
+			validate: func() error {
+				return validationService.ValidateCreateLaborOperationInput(models.CreateLaborOperationInput{
+					AccountID: accountID, Code: "013-001-001", Description: "Replace front brake pads",
+					BookHours: models.MustParseDecimal("1.5"), RateType: models.RateTypeFlatRate,
+				})
+			},
+		},
+		{
+			name: "Code with spaces",
+			validate: func() error {
+				return validationService.ValidateCreateLaborOperationInput(models.CreateLaborOperationInput{
+					AccountID: accountID, Code: "013 001", Description: "Replace front brake pads",
+				})
+			},
+			expectedField: "code",
+		},
+		{
+			name: "Operation without a description",
+			validate: func() error {
+				return validationService.ValidateCreateLaborOperationInput(models.CreateLaborOperationInput{
+					AccountID: accountID, Code: "013-001-001",
+				})
+			},
+			expectedField: "description",
+		},
+		{
+			name: "Valid update clearing the rate",
+			validate: func() error {
+				return validationService.ValidateUpdateLaborOperationInput(models.UpdateLaborOperationInput{
+					AccountID: accountID, Code: "013-001-001",
+					RateType: models.NullValue[models.RateType](), RatePerHour: models.NullValue[models.Decimal](),
+				})
+			},
+		},
+		{
+			name: "Update clearing the book hours",
+			validate: func() error {
+				return validationService.ValidateUpdateLaborOperationInput(models.UpdateLaborOperationInput{
+					AccountID: accountID, Code: "013-001-001", BookHours: models.NullValue[models.Decimal](),
+				})
+			},
+			expectedField: "bookHours",
+		},
+		{
+			name: "Update that changes nothing",
+			validate: func() error {
+				return validationService.ValidateUpdateLaborOperationInput(models.UpdateLaborOperationInput{
+					AccountID: accountID, Code: "013-001-001",
+				})
+			},
+			expectedField: "(root)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validate()
+			if tt.expectedField == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.expectedField, validationErr.Fields[0].Field)
+		})
+	}
+}
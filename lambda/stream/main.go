@@ -0,0 +1,73 @@
+// Package main contains the entry point for the Lambda function that consumes
+// the labor lines table's DynamoDB stream.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+
+	"steverhoton-labor-lines/lambda/handler"
+	"steverhoton-labor-lines/lambda/services"
+)
+
+// StreamLambdaHandler is the stream Lambda function handler. Records it cannot
+// process are reported as batch item failures rather than failing the whole
+// invocation; only a configuration error does that.
+func StreamLambdaHandler(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+	streamHandler, err := newStreamHandler(ctx)
+	if err != nil {
+		return events.DynamoDBEventResponse{}, err
+	}
+
+	return streamHandler.HandleDynamoDBEvent(ctx, event), nil
+}
+
+// newStreamHandler creates the stream handler from the environment.
+func newStreamHandler(ctx context.Context) (*handler.StreamHandler, error) {
+	tableName := os.Getenv("DYNAMODB_TABLE_NAME")
+	if tableName == "" {
+		return nil, errors.New("DYNAMODB_TABLE_NAME environment variable not set")
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	// The stream only writes aggregates, never labor lines, so its service has
+	// no labor line events to publish
+	client := dynamodb.NewFromConfig(cfg)
+	dynamoDBService := services.NewDynamoDBService(client, tableName)
+
+	// Changes to task totals are announced when an event bus is configured
+	var handlerOpts []handler.StreamHandlerOption
+	if eventBusName := os.Getenv("EVENT_BUS_NAME"); eventBusName != "" {
+		publisher := services.NewEventBridgePublisher(eventbridge.NewFromConfig(cfg), eventBusName)
+		handlerOpts = append(handlerOpts, handler.WithTaskTotalsPublisher(publisher))
+	}
+
+	// Parts of expired labor lines are released from the reservations the API
+	// function keeps in the table when it checks parts against a catalog file
+	if path := os.Getenv("PARTS_CATALOG_FILE"); path != "" {
+		parts, err := services.LoadMemoryPartsCatalog(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load parts catalog: %w", err)
+		}
+		catalog := services.NewDynamoDBPartsCatalog(client, tableName, parts)
+		handlerOpts = append(handlerOpts, handler.WithStreamPartsCatalog(catalog))
+	}
+
+	return handler.NewStreamHandler(dynamoDBService, handlerOpts...), nil
+}
+
+func main() {
+	lambda.Start(StreamLambdaHandler)
+}
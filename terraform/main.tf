@@ -8,6 +8,19 @@ locals {
   lambda_source_dir      = "${path.module}/../lambda"
   lambda_binary_path     = "${path.module}/bootstrap"
   lambda_deployment_path = "${path.module}/lambda-deployment.zip"
+
+  stream_function_name   = "${local.name_prefix}-labor-lines-stream"
+  stream_log_group_name  = "/aws/lambda/${local.stream_function_name}"
+  stream_binary_path     = "${path.module}/stream/bootstrap"
+  stream_deployment_path = "${path.module}/stream-deployment.zip"
+
+  importer_function_name   = "${local.name_prefix}-labor-lines-importer"
+  importer_log_group_name  = "/aws/lambda/${local.importer_function_name}"
+  importer_binary_path     = "${path.module}/importer/bootstrap"
+  importer_deployment_path = "${path.module}/importer-deployment.zip"
+
+  export_bucket_name = "${local.name_prefix}-labor-line-exports-${data.aws_caller_identity.current.account_id}"
+  import_bucket_name = "${local.name_prefix}-labor-line-imports-${data.aws_caller_identity.current.account_id}"
 }
 
 # Data sources
@@ -24,6 +37,24 @@ resource "aws_cloudwatch_log_group" "lambda_log_group" {
   }
 }
 
+resource "aws_cloudwatch_log_group" "stream_log_group" {
+  name              = local.stream_log_group_name
+  retention_in_days = var.log_retention_days
+
+  tags = {
+    Name = local.stream_log_group_name
+  }
+}
+
+resource "aws_cloudwatch_log_group" "importer_log_group" {
+  name              = local.importer_log_group_name
+  retention_in_days = var.log_retention_days
+
+  tags = {
+    Name = local.importer_log_group_name
+  }
+}
+
 # DynamoDB Table
 resource "aws_dynamodb_table" "labor_lines" {
   name           = local.dynamodb_table_name
@@ -33,6 +64,10 @@ resource "aws_dynamodb_table" "labor_lines" {
   hash_key       = "PK"
   range_key      = "SK"
 
+  # Consumed by the stream function that maintains labor line aggregates
+  stream_enabled   = true
+  stream_view_type = "NEW_AND_OLD_IMAGES"
+
   attribute {
     name = "PK"
     type = "S"
@@ -57,6 +92,54 @@ resource "aws_dynamodb_table" "labor_lines" {
     write_capacity  = var.dynamodb_billing_mode == "PROVISIONED" ? var.dynamodb_write_capacity : null
   }
 
+  attribute {
+    name = "technicianKey"
+    type = "S"
+  }
+
+  attribute {
+    name = "assignedAt"
+    type = "N"
+  }
+
+  attribute {
+    name = "outboxStatus"
+    type = "S"
+  }
+
+  attribute {
+    name = "createdAt"
+    type = "N"
+  }
+
+  # Sparse: only technician assignment items have a technicianKey
+  global_secondary_index {
+    name      = "TechnicianIndex"
+    hash_key  = "technicianKey"
+    range_key = "assignedAt"
+
+    projection_type = "ALL"
+    read_capacity   = var.dynamodb_billing_mode == "PROVISIONED" ? var.dynamodb_read_capacity : null
+    write_capacity  = var.dynamodb_billing_mode == "PROVISIONED" ? var.dynamodb_write_capacity : null
+  }
+
+  # Sparse: only outbox entries awaiting publication have an outboxStatus
+  global_secondary_index {
+    name      = "OutboxIndex"
+    hash_key  = "outboxStatus"
+    range_key = "createdAt"
+
+    projection_type = "ALL"
+    read_capacity   = var.dynamodb_billing_mode == "PROVISIONED" ? var.dynamodb_read_capacity : null
+    write_capacity  = var.dynamodb_billing_mode == "PROVISIONED" ? var.dynamodb_write_capacity : null
+  }
+
+  # Soft-deleted labor lines expire once their retention period has passed
+  ttl {
+    attribute_name = "expiresAt"
+    enabled        = true
+  }
+
   point_in_time_recovery {
     enabled = true
   }
@@ -70,6 +153,111 @@ resource "aws_dynamodb_table" "labor_lines" {
   }
 }
 
+# S3 bucket labor line exports are written to and downloaded from
+resource "aws_s3_bucket" "exports" {
+  bucket = local.export_bucket_name
+
+  tags = {
+    Name = local.export_bucket_name
+  }
+}
+
+resource "aws_s3_bucket_public_access_block" "exports" {
+  bucket = aws_s3_bucket.exports.id
+
+  block_public_acls       = true
+  block_public_policy     = true
+  ignore_public_acls      = true
+  restrict_public_buckets = true
+}
+
+resource "aws_s3_bucket_server_side_encryption_configuration" "exports" {
+  bucket = aws_s3_bucket.exports.id
+
+  rule {
+    apply_server_side_encryption_by_default {
+      sse_algorithm = "AES256"
+    }
+  }
+}
+
+# Exports are only downloaded through short-lived presigned URLs, so they need not be kept long
+resource "aws_s3_bucket_lifecycle_configuration" "exports" {
+  bucket = aws_s3_bucket.exports.id
+
+  rule {
+    id     = "expire-exports"
+    status = "Enabled"
+
+    filter {
+      prefix = "exports/"
+    }
+
+    expiration {
+      days = var.export_retention_days
+    }
+  }
+}
+
+# S3 bucket labor line import files are uploaded to and their reports written to
+resource "aws_s3_bucket" "imports" {
+  bucket = local.import_bucket_name
+
+  tags = {
+    Name = local.import_bucket_name
+  }
+}
+
+resource "aws_s3_bucket_public_access_block" "imports" {
+  bucket = aws_s3_bucket.imports.id
+
+  block_public_acls       = true
+  block_public_policy     = true
+  ignore_public_acls      = true
+  restrict_public_buckets = true
+}
+
+resource "aws_s3_bucket_server_side_encryption_configuration" "imports" {
+  bucket = aws_s3_bucket.imports.id
+
+  rule {
+    apply_server_side_encryption_by_default {
+      sse_algorithm = "AES256"
+    }
+  }
+}
+
+# Import files and their reports are removed once the import has been reviewed
+resource "aws_s3_bucket_lifecycle_configuration" "imports" {
+  bucket = aws_s3_bucket.imports.id
+
+  rule {
+    id     = "expire-imports"
+    status = "Enabled"
+
+    filter {
+      prefix = "imports/"
+    }
+
+    expiration {
+      days = var.import_retention_days
+    }
+  }
+
+  rule {
+    id     = "expire-import-reports"
+    status = "Enabled"
+
+    filter {
+      prefix = "reports/"
+    }
+
+    expiration {
+      days = var.import_retention_days
+    }
+  }
+}
+
 # IAM Role for Lambda Execution
 resource "aws_iam_role" "lambda_execution_role" {
   name = local.iam_role_name
@@ -109,7 +297,11 @@ resource "aws_iam_role_policy" "lambda_logging" {
         ]
         Resource = [
           aws_cloudwatch_log_group.lambda_log_group.arn,
-          "${aws_cloudwatch_log_group.lambda_log_group.arn}:*"
+          "${aws_cloudwatch_log_group.lambda_log_group.arn}:*",
+          aws_cloudwatch_log_group.stream_log_group.arn,
+          "${aws_cloudwatch_log_group.stream_log_group.arn}:*",
+          aws_cloudwatch_log_group.importer_log_group.arn,
+          "${aws_cloudwatch_log_group.importer_log_group.arn}:*"
         ]
       }
     ]
@@ -131,29 +323,129 @@ resource "aws_iam_role_policy" "lambda_dynamodb" {
           "dynamodb:PutItem",
           "dynamodb:UpdateItem",
           "dynamodb:DeleteItem",
+          "dynamodb:BatchGetItem",
+          "dynamodb:BatchWriteItem",
           "dynamodb:Query",
           "dynamodb:Scan"
         ]
         Resource = [
           aws_dynamodb_table.labor_lines.arn,
-          "${aws_dynamodb_table.labor_lines.arn}/index/*"
+          "${aws_dynamodb_table.labor_lines.arn}/index/TaskIndex",
+          "${aws_dynamodb_table.labor_lines.arn}/index/TechnicianIndex",
+          "${aws_dynamodb_table.labor_lines.arn}/index/OutboxIndex"
+        ]
+      }
+    ]
+  })
+}
+
+# IAM Policy for reading the table's stream
+resource "aws_iam_role_policy" "lambda_dynamodb_stream" {
+  name = "${local.iam_role_name}-dynamodb-stream"
+  role = aws_iam_role.lambda_execution_role.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect = "Allow"
+        Action = [
+          "dynamodb:DescribeStream",
+          "dynamodb:GetRecords",
+          "dynamodb:GetShardIterator",
+          "dynamodb:ListStreams"
+        ]
+        Resource = aws_dynamodb_table.labor_lines.stream_arn
+      }
+    ]
+  })
+}
+
+# IAM Policy for publishing labor line events
+resource "aws_iam_role_policy" "lambda_events" {
+  name = "${local.iam_role_name}-events"
+  role = aws_iam_role.lambda_execution_role.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect   = "Allow"
+        Action   = "events:PutEvents"
+        Resource = data.aws_cloudwatch_event_bus.labor_line_events.arn
+      }
+    ]
+  })
+}
+
+# IAM Policy for writing labor line exports and presigning their downloads
+resource "aws_iam_role_policy" "lambda_exports" {
+  name = "${local.iam_role_name}-exports"
+  role = aws_iam_role.lambda_execution_role.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect = "Allow"
+        Action = [
+          "s3:PutObject",
+          "s3:GetObject"
         ]
+        Resource = "${aws_s3_bucket.exports.arn}/exports/*"
+      }
+    ]
+  })
+}
+
+# IAM Policy for reading labor line import files and writing their reports
+resource "aws_iam_role_policy" "lambda_imports" {
+  name = "${local.iam_role_name}-imports"
+  role = aws_iam_role.lambda_execution_role.id
+
+  policy = jsonencode({
+    Version = "2012-10-17"
+    Statement = [
+      {
+        Effect   = "Allow"
+        Action   = "s3:GetObject"
+        Resource = "${aws_s3_bucket.imports.arn}/imports/*"
+      },
+      {
+        Effect   = "Allow"
+        Action   = "s3:PutObject"
+        Resource = "${aws_s3_bucket.imports.arn}/reports/*"
       }
     ]
   })
 }
 
+# Event bus labor line events are published to
+data "aws_cloudwatch_event_bus" "labor_line_events" {
+  name = var.event_bus_name
+}
+
+# Secret used to sign pagination tokens returned by list operations
+resource "random_password" "pagination_token_secret" {
+  length  = 64
+  special = false
+}
+
 # Build Go binary using null_resource
 resource "null_resource" "build_lambda" {
   triggers = {
-    source_hash = filemd5("${local.lambda_source_dir}/main.go")
-    go_mod_hash = filemd5("${local.lambda_source_dir}/go.mod")
+    source_hash   = filemd5("${local.lambda_source_dir}/main.go")
+    stream_hash   = filemd5("${local.lambda_source_dir}/stream/main.go")
+    importer_hash = filemd5("${local.lambda_source_dir}/importer/main.go")
+    go_mod_hash   = filemd5("${local.lambda_source_dir}/go.mod")
   }
 
   provisioner "local-exec" {
     command = <<-EOT
       cd ${local.lambda_source_dir}
       GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -ldflags="-s -w" -o ../terraform/bootstrap .
+      GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -ldflags="-s -w" -o ../terraform/stream/bootstrap ./stream
+      GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -ldflags="-s -w" -o ../terraform/importer/bootstrap ./importer
     EOT
   }
 }
@@ -180,13 +472,21 @@ resource "aws_lambda_function" "labor_lines_handler" {
 
   environment {
     variables = {
-      DYNAMODB_TABLE_NAME = aws_dynamodb_table.labor_lines.name
+      DYNAMODB_TABLE_NAME     = aws_dynamodb_table.labor_lines.name
+      PAGINATION_TOKEN_SECRET = random_password.pagination_token_secret.result
+      DELETED_RETENTION_DAYS  = tostring(var.deleted_retention_days)
+      ACCOUNT_IDS_CLAIM       = var.account_ids_claim
+      TRUSTED_IAM_ROLE_ARNS   = join(",", var.trusted_iam_role_arns)
+      EVENT_BUS_NAME          = var.event_bus_name
+      EXPORT_BUCKET_NAME      = aws_s3_bucket.exports.id
     }
   }
 
   depends_on = [
     aws_iam_role_policy.lambda_logging,
     aws_iam_role_policy.lambda_dynamodb,
+    aws_iam_role_policy.lambda_events,
+    aws_iam_role_policy.lambda_exports,
     aws_cloudwatch_log_group.lambda_log_group,
     null_resource.build_lambda,
     data.archive_file.lambda_zip
@@ -195,4 +495,146 @@ resource "aws_lambda_function" "labor_lines_handler" {
   tags = {
     Name = local.lambda_function_name
   }
-}
\ No newline at end of file
+}
+
+# Scheduled relay of labor line events whose publication failed when they were written
+resource "aws_cloudwatch_event_rule" "outbox_relay" {
+  name                = "${local.name_prefix}-labor-lines-outbox-relay"
+  description         = "Publishes labor line events left in the outbox"
+  schedule_expression = var.outbox_relay_schedule
+
+  tags = {
+    Name = "${local.name_prefix}-labor-lines-outbox-relay"
+  }
+}
+
+resource "aws_cloudwatch_event_target" "outbox_relay" {
+  rule = aws_cloudwatch_event_rule.outbox_relay.name
+  arn  = aws_lambda_function.labor_lines_handler.arn
+}
+
+resource "aws_lambda_permission" "outbox_relay" {
+  statement_id  = "AllowOutboxRelaySchedule"
+  action        = "lambda:InvokeFunction"
+  function_name = aws_lambda_function.labor_lines_handler.function_name
+  principal     = "events.amazonaws.com"
+  source_arn    = aws_cloudwatch_event_rule.outbox_relay.arn
+}
+
+# Stream deployment package
+data "archive_file" "stream_zip" {
+  type        = "zip"
+  source_file = local.stream_binary_path
+  output_path = local.stream_deployment_path
+
+  depends_on = [null_resource.build_lambda]
+}
+
+# Lambda Function maintaining task totals and status counts from the table's stream
+resource "aws_lambda_function" "labor_lines_stream" {
+  filename         = data.archive_file.stream_zip.output_path
+  function_name    = local.stream_function_name
+  role             = aws_iam_role.lambda_execution_role.arn
+  handler          = "main"
+  runtime          = "provided.al2"
+  timeout          = var.lambda_timeout
+  memory_size      = var.lambda_memory_size
+  source_code_hash = data.archive_file.stream_zip.output_base64sha256
+
+  environment {
+    variables = {
+      DYNAMODB_TABLE_NAME = aws_dynamodb_table.labor_lines.name
+      EVENT_BUS_NAME      = var.event_bus_name
+    }
+  }
+
+  depends_on = [
+    aws_iam_role_policy.lambda_logging,
+    aws_iam_role_policy.lambda_dynamodb,
+    aws_iam_role_policy.lambda_dynamodb_stream,
+    aws_iam_role_policy.lambda_events,
+    aws_cloudwatch_log_group.stream_log_group,
+    null_resource.build_lambda,
+    data.archive_file.stream_zip
+  ]
+
+  tags = {
+    Name = local.stream_function_name
+  }
+}
+
+# Failed records are retried from the first failure so each labor line's changes apply in order
+resource "aws_lambda_event_source_mapping" "labor_lines_stream" {
+  event_source_arn                   = aws_dynamodb_table.labor_lines.stream_arn
+  function_name                      = aws_lambda_function.labor_lines_stream.arn
+  starting_position                  = "TRIM_HORIZON"
+  batch_size                         = var.stream_batch_size
+  maximum_batching_window_in_seconds = var.stream_batching_window_seconds
+  maximum_retry_attempts             = var.stream_maximum_retry_attempts
+  bisect_batch_on_function_error     = true
+  function_response_types            = ["ReportBatchItemFailures"]
+}
+
+# Importer deployment package
+data "archive_file" "importer_zip" {
+  type        = "zip"
+  source_file = local.importer_binary_path
+  output_path = local.importer_deployment_path
+
+  depends_on = [null_resource.build_lambda]
+}
+
+# Lambda Function importing the labor line files uploaded to the imports bucket
+resource "aws_lambda_function" "labor_lines_importer" {
+  filename         = data.archive_file.importer_zip.output_path
+  function_name    = local.importer_function_name
+  role             = aws_iam_role.lambda_execution_role.arn
+  handler          = "main"
+  runtime          = "provided.al2"
+  timeout          = var.import_timeout
+  memory_size      = var.lambda_memory_size
+  source_code_hash = data.archive_file.importer_zip.output_base64sha256
+
+  environment {
+    variables = {
+      DYNAMODB_TABLE_NAME = aws_dynamodb_table.labor_lines.name
+      EVENT_BUS_NAME      = var.event_bus_name
+    }
+  }
+
+  depends_on = [
+    aws_iam_role_policy.lambda_logging,
+    aws_iam_role_policy.lambda_dynamodb,
+    aws_iam_role_policy.lambda_events,
+    aws_iam_role_policy.lambda_imports,
+    aws_cloudwatch_log_group.importer_log_group,
+    null_resource.build_lambda,
+    data.archive_file.importer_zip
+  ]
+
+  tags = {
+    Name = local.importer_function_name
+  }
+}
+
+resource "aws_lambda_permission" "imports_bucket" {
+  statement_id   = "AllowImportsBucket"
+  action         = "lambda:InvokeFunction"
+  function_name  = aws_lambda_function.labor_lines_importer.function_name
+  principal      = "s3.amazonaws.com"
+  source_arn     = aws_s3_bucket.imports.arn
+  source_account = data.aws_caller_identity.current.account_id
+}
+
+# Only uploads under imports/ trigger an import, so writing a report does not
+resource "aws_s3_bucket_notification" "imports" {
+  bucket = aws_s3_bucket.imports.id
+
+  lambda_function {
+    lambda_function_arn = aws_lambda_function.labor_lines_importer.arn
+    events              = ["s3:ObjectCreated:*"]
+    filter_prefix       = "imports/"
+  }
+
+  depends_on = [aws_lambda_permission.imports_bucket]
+}
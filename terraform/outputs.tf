@@ -14,6 +14,16 @@ output "lambda_invoke_arn" {
   value       = aws_lambda_function.labor_lines_handler.invoke_arn
 }
 
+output "stream_function_name" {
+  description = "Name of the Lambda function consuming the table's stream"
+  value       = aws_lambda_function.labor_lines_stream.function_name
+}
+
+output "importer_function_name" {
+  description = "Name of the Lambda function importing labor line files"
+  value       = aws_lambda_function.labor_lines_importer.function_name
+}
+
 # DynamoDB Table Outputs
 output "dynamodb_table_name" {
   description = "Name of the DynamoDB table"
@@ -25,6 +35,17 @@ output "dynamodb_table_arn" {
   value       = aws_dynamodb_table.labor_lines.arn
 }
 
+# S3 Bucket Outputs
+output "export_bucket_name" {
+  description = "Name of the S3 bucket labor line exports are written to"
+  value       = aws_s3_bucket.exports.id
+}
+
+output "import_bucket_name" {
+  description = "Name of the S3 bucket labor line import files are uploaded to"
+  value       = aws_s3_bucket.imports.id
+}
+
 # IAM Role Outputs
 output "lambda_execution_role_arn" {
   description = "ARN of the Lambda execution role"
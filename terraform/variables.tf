@@ -97,4 +97,120 @@ variable "log_retention_days" {
     ], var.log_retention_days)
     error_message = "Log retention days must be a valid CloudWatch retention period."
   }
-}
\ No newline at end of file
+}
+
+variable "deleted_retention_days" {
+  description = "Days to keep soft-deleted labor lines before DynamoDB TTL removes them (0 keeps them until purged)"
+  type        = number
+  default     = 0
+
+  validation {
+    condition     = var.deleted_retention_days >= 0 && floor(var.deleted_retention_days) == var.deleted_retention_days
+    error_message = "Deleted retention days must be a non-negative whole number."
+  }
+}
+
+variable "account_ids_claim" {
+  description = "Identity claim (or Lambda authorizer resolver context key) listing the accounts a caller may access"
+  type        = string
+  default     = "custom:accountIds"
+
+  validation {
+    condition     = length(var.account_ids_claim) > 0
+    error_message = "Account IDs claim must not be empty."
+  }
+}
+
+variable "trusted_iam_role_arns" {
+  description = "ARNs of the IAM roles whose callers may access every account and perform every operation, such as back-end services; other IAM callers are refused"
+  type        = list(string)
+  default     = []
+
+  validation {
+    condition     = alltrue([for arn in var.trusted_iam_role_arns : can(regex("^arn:[^:]+:iam::[0-9]{12}:role/.+$", arn))])
+    error_message = "Trusted IAM role ARNs must be IAM role ARNs."
+  }
+}
+
+variable "event_bus_name" {
+  description = "Name of the EventBridge event bus labor line events are published to"
+  type        = string
+  default     = "default"
+
+  validation {
+    condition     = length(var.event_bus_name) > 0
+    error_message = "Event bus name must not be empty."
+  }
+}
+
+variable "export_retention_days" {
+  description = "Days to keep labor line exports in the export bucket"
+  type        = number
+  default     = 7
+
+  validation {
+    condition     = var.export_retention_days >= 1 && floor(var.export_retention_days) == var.export_retention_days
+    error_message = "Export retention days must be a positive whole number."
+  }
+}
+
+variable "import_retention_days" {
+  description = "Days to keep labor line import files and their reports in the import bucket"
+  type        = number
+  default     = 30
+
+  validation {
+    condition     = var.import_retention_days >= 1 && floor(var.import_retention_days) == var.import_retention_days
+    error_message = "Import retention days must be a positive whole number."
+  }
+}
+
+variable "import_timeout" {
+  description = "Lambda function timeout in seconds for importing a labor line file"
+  type        = number
+  default     = 900
+
+  validation {
+    condition     = var.import_timeout >= 1 && var.import_timeout <= 900
+    error_message = "Import timeout must be between 1 and 900 seconds."
+  }
+}
+
+variable "outbox_relay_schedule" {
+  description = "Schedule expression for relaying labor line events whose publication failed"
+  type        = string
+  default     = "rate(5 minutes)"
+}
+
+variable "stream_batch_size" {
+  description = "Maximum number of stream records delivered to the stream function at once"
+  type        = number
+  default     = 100
+
+  validation {
+    condition     = var.stream_batch_size >= 1 && var.stream_batch_size <= 10000
+    error_message = "Stream batch size must be between 1 and 10000."
+  }
+}
+
+variable "stream_batching_window_seconds" {
+  description = "Seconds to gather stream records before invoking the stream function"
+  type        = number
+  default     = 0
+
+  validation {
+    condition     = var.stream_batching_window_seconds >= 0 && var.stream_batching_window_seconds <= 300
+    error_message = "Stream batching window must be between 0 and 300 seconds."
+  }
+}
+
+variable "stream_maximum_retry_attempts" {
+  description = "Times a failed stream record is retried before it is skipped (-1 retries until it expires)"
+  type        = number
+  default     = -1
+
+  validation {
+    condition     = var.stream_maximum_retry_attempts >= -1 && var.stream_maximum_retry_attempts <= 10000
+    error_message = "Stream maximum retry attempts must be between -1 and 10000."
+  }
+}